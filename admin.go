@@ -0,0 +1,905 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+	"slack-leaves-ai-agent/repository"
+
+	"github.com/slack-go/slack"
+)
+
+const adminTimeLayout = "2006-01-02T15:04"
+
+func (a *App) isAdmin(userID string) bool {
+	for _, id := range a.config.AdminUserIDs {
+		if id == userID {
+			return true
+		}
+	}
+
+	ok, err := a.roleRepo.HasRole(userID, models.RoleAdmin)
+	if err != nil {
+		logger.Error("Failed to check roles for %s: %v", userID, err)
+		return false
+	}
+	return ok
+}
+
+// auditEntry is the audit log entry for one change, with before and after
+// stored as JSON.
+func auditEntry(actor, action string, leaveID int64, before, after interface{}) *models.AuditEntry {
+	entry := &models.AuditEntry{
+		Actor:   actor,
+		Action:  action,
+		LeaveID: leaveID,
+	}
+	if before != nil {
+		data, _ := json.Marshal(before)
+		entry.Before = string(data)
+	}
+	if after != nil {
+		data, _ := json.Marshal(after)
+		entry.After = string(data)
+	}
+	return entry
+}
+
+func (a *App) audit(actor, action string, leaveID int64, before, after interface{}) {
+	if err := a.auditRepo.Record(auditEntry(actor, action, leaveID, before, after)); err != nil {
+		logger.Error("Failed to write audit entry (%s %s %d): %v", actor, action, leaveID, err)
+	}
+}
+
+// lockedAction applies the payroll lock to an admin change: without override
+// a change touching a closed period is refused, with it the audit action is
+// marked as an override.
+func (a *App) lockedAction(action string, override bool, leaves ...*models.Leave) (string, error) {
+	if err := a.checkPeriodLock(leaves...); err != nil {
+		if !override {
+			return "", err
+		}
+		return action + "_override", nil
+	}
+	return action, nil
+}
+
+func (a *App) adminCreateLeave(actor string, leave *models.Leave, override bool) error {
+	if err := validateAdminLeave(leave); err != nil {
+		return err
+	}
+	action, err := a.lockedAction("admin_create", override, leave)
+	if err != nil {
+		return err
+	}
+	if leave.OriginalText == "" {
+		leave.OriginalText = "created by admin " + actor
+	}
+	leave.Duration = models.FormatDuration(leave.StartTime, leave.EndTime)
+
+	err = a.leaveRepo.Audited(func(leaves *repository.LeaveRepository) ([]*models.AuditEntry, error) {
+		if err := leaves.Create(leave); err != nil {
+			return nil, err
+		}
+		return []*models.AuditEntry{auditEntry(actor, action, leave.ID, nil, leave)}, nil
+	})
+	if err != nil {
+		return fmt.Errorf("error saving leave: %v", err)
+	}
+
+	a.recordLedgerChange(actor, action, nil, leave)
+	a.syncTeamCalendar(context.Background(), nil, leave)
+	return nil
+}
+
+// adminUpdateLeave loads the record, lets apply mutate it and persists the
+// result, auditing the before and after state.
+func (a *App) adminUpdateLeave(actor string, id int64, override bool, apply func(*models.Leave) error) (*models.Leave, error) {
+	leave, err := a.leaveRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	before := *leave
+
+	if err := apply(leave); err != nil {
+		return nil, err
+	}
+	leave.ID = id
+	if err := validateAdminLeave(leave); err != nil {
+		return nil, err
+	}
+	action, err := a.lockedAction("admin_edit", override, &before, leave)
+	if err != nil {
+		return nil, err
+	}
+	leave.Duration = models.FormatDuration(leave.StartTime, leave.EndTime)
+
+	err = a.leaveRepo.Audited(func(leaves *repository.LeaveRepository) ([]*models.AuditEntry, error) {
+		if err := leaves.Update(leave); err != nil {
+			return nil, err
+		}
+		return []*models.AuditEntry{auditEntry(actor, action, id, before, leave)}, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error updating leave: %v", err)
+	}
+
+	a.recordLedgerChange(actor, action, &before, leave)
+	a.syncTeamCalendar(context.Background(), &before, leave)
+	return leave, nil
+}
+
+func (a *App) adminMergeLeaves(actor string, keepID, mergeID int64, override bool) (*models.Leave, error) {
+	if keepID == mergeID {
+		return nil, fmt.Errorf("cannot merge a record with itself")
+	}
+
+	before, err := a.leaveRepo.GetByID(keepID)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := a.leaveRepo.GetByID(mergeID)
+	if err != nil {
+		return nil, err
+	}
+	action, err := a.lockedAction("admin_merge", override, before, merged)
+	if err != nil {
+		return nil, err
+	}
+
+	var leave *models.Leave
+	err = a.leaveRepo.Audited(func(leaves *repository.LeaveRepository) ([]*models.AuditEntry, error) {
+		if leave, err = leaves.Merge(keepID, mergeID); err != nil {
+			return nil, err
+		}
+		return []*models.AuditEntry{
+			auditEntry(actor, action, keepID, before, leave),
+			auditEntry(actor, action+"_delete", mergeID, merged, nil),
+		}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	a.recordLedgerChange(actor, action, before, leave)
+	a.recordLedgerChange(actor, action+"_delete", merged, nil)
+	a.syncTeamCalendar(context.Background(), before, leave)
+	a.syncTeamCalendar(context.Background(), merged, nil)
+	return leave, nil
+}
+
+func (a *App) adminDeleteLeave(actor string, id int64, override bool) error {
+	leave, err := a.leaveRepo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	action, err := a.lockedAction("admin_delete", override, leave)
+	if err != nil {
+		return err
+	}
+
+	err = a.leaveRepo.Audited(func(leaves *repository.LeaveRepository) ([]*models.AuditEntry, error) {
+		if err := leaves.Delete(id); err != nil {
+			return nil, err
+		}
+		return []*models.AuditEntry{auditEntry(actor, action, id, leave, nil)}, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	a.recordLedgerChange(actor, action, leave, nil)
+	a.syncTeamCalendar(context.Background(), leave, nil)
+	return nil
+}
+
+func validateAdminLeave(leave *models.Leave) error {
+	if leave.Username == "" {
+		return fmt.Errorf("username is required")
+	}
+	if !isKnownLeaveType(leave.LeaveType) {
+		return fmt.Errorf("unknown leave type %q", leave.LeaveType)
+	}
+	if leave.StartTime.IsZero() || leave.EndTime.IsZero() {
+		return fmt.Errorf("start and end time are required")
+	}
+	if !leave.EndTime.After(leave.StartTime) {
+		return fmt.Errorf("end time must be after start time")
+	}
+	return nil
+}
+
+// knownLeaveTypes are the names of models.LeaveTypes.
+var knownLeaveTypes = models.LeaveTypeNames(nil)
+
+func isKnownLeaveType(leaveType string) bool {
+	_, ok := models.FindLeaveType(leaveType)
+	return ok
+}
+
+// isDayOff reports whether a record keeps its owner away for whole days.
+func isDayOff(leaveType string) bool {
+	info, _ := models.FindLeaveType(leaveType)
+	return info.DayOff
+}
+
+// parseAdminTime accepts either a date ("2006-01-02"), which resolves to the
+// start or end of the default work day, or a date and time in loc.
+func parseAdminTime(value string, endOfDay bool, loc *time.Location) (time.Time, error) {
+	if t, err := time.ParseInLocation(adminTimeLayout, value, loc); err == nil {
+		return t, nil
+	}
+
+	day, err := time.ParseInLocation("2006-01-02", value, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q (use YYYY-MM-DD or YYYY-MM-DDTHH:MM)", value)
+	}
+	if endOfDay {
+		return day.Add(18 * time.Hour), nil
+	}
+	return day.Add(9 * time.Hour), nil
+}
+
+// splitArgs splits command text on whitespace, keeping double-quoted
+// sections together.
+func splitArgs(text string) []string {
+	var args []string
+	var current strings.Builder
+	inQuotes := false
+
+	for _, r := range text {
+		switch {
+		case r == '"' || r == '“' || r == '”':
+			inQuotes = !inQuotes
+		case (r == ' ' || r == '\t') && !inQuotes:
+			if current.Len() > 0 {
+				args = append(args, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		args = append(args, current.String())
+	}
+
+	return args
+}
+
+// resolveUserArg turns a Slack mention ("<@U123|name>", "<@U123>") or a plain
+// "@name" into the username leaves are stored under.
+func (a *App) resolveUserArg(arg string) (string, error) {
+	if strings.HasPrefix(arg, "<@") && strings.HasSuffix(arg, ">") {
+		id := strings.TrimSuffix(strings.TrimPrefix(arg, "<@"), ">")
+		if i := strings.Index(id, "|"); i >= 0 {
+			id = id[:i]
+		}
+		user, err := a.slackClient.GetUserInfo(id)
+		if err != nil {
+			return "", fmt.Errorf("unknown user %s: %v", arg, err)
+		}
+		return user.Name, nil
+	}
+
+	username := strings.TrimPrefix(arg, "@")
+	if username == "" {
+		return "", fmt.Errorf("missing user")
+	}
+	return username, nil
+}
+
+// resolveUserIDArg is like resolveUserArg but returns the Slack user ID, which
+// roles are keyed on.
+func resolveUserIDArg(arg string) (string, error) {
+	if strings.HasPrefix(arg, "<@") && strings.HasSuffix(arg, ">") {
+		id := strings.TrimSuffix(strings.TrimPrefix(arg, "<@"), ">")
+		if i := strings.Index(id, "|"); i >= 0 {
+			id = id[:i]
+		}
+		return id, nil
+	}
+	if strings.HasPrefix(arg, "U") || strings.HasPrefix(arg, "W") {
+		return arg, nil
+	}
+	return "", fmt.Errorf("expected a user mention, got %q", arg)
+}
+
+const adminLeaveUsage = "Usage:\n" +
+	"• `/admin-leave list @user`\n" +
+	"• `/admin-leave create @user TYPE START END [reason]`\n" +
+	"• `/admin-leave edit ID field=value ...` (fields: user, type, start, end, reason, private_reason)\n" +
+	"• `/admin-leave merge KEEP_ID MERGE_ID`\n" +
+	"• `/admin-leave delete ID`\n" +
+	"• `/admin-leave grant|revoke @user ROLE`\n" +
+	"• `/admin-leave employment @user EMPLOYEE|CONTRACTOR`\n" +
+	"• `/admin-leave manager @user @manager`\n" +
+	"• `/admin-leave coverage list`\n" +
+	"• `/admin-leave coverage add|remove @user ROLE`\n" +
+	"• `/admin-leave coverage rule ROLE MIN` (0 removes the rule)\n" +
+	"• `/admin-leave office list`\n" +
+	"• `/admin-leave office add CODE TIMEZONE \"Name\" [annual_days=N] [max_advance_days=N] [min_office_days=N]`\n" +
+	"• `/admin-leave office assign @user CODE`\n" +
+	"• `/admin-leave holiday list CODE [YEAR]`\n" +
+	"• `/admin-leave holiday add CODE YYYY-MM-DD Name`\n" +
+	"• `/admin-leave holiday remove CODE YYYY-MM-DD`\n" +
+	"• `/admin-leave audit verify`\n" +
+	"• `/admin-leave viewer create START END DAYS [label]` (read-only API token for an auditor)\n" +
+	"• `/admin-leave viewer list`\n" +
+	"• `/admin-leave viewer revoke ID`\n" +
+	"• `/admin-leave recurring list [@user]`\n" +
+	"• `/admin-leave recurring cancel ID` (also removes its upcoming records)\n" +
+	"• `/admin-leave balance @user [YEAR]` (ledger of accruals, grants and debits)\n" +
+	"• `/admin-leave balance grant|adjust @user DAYS [CATEGORY] reason` (adjust takes negative days too; also `/adjust-balance`)\n" +
+	"• `/admin-leave approvals` (records waiting for a manager's sign-off)\n" +
+	"• `/admin-leave approve|reject ID`\n" +
+	"• `/admin-leave backups`\n" +
+	"• `/admin-leave backup @approver @backup|off` (approves for them while they're on leave)\n" +
+	"• `/admin-leave deadletters [list]` (messages that failed to process)\n" +
+	"• `/admin-leave deadletters replay|discard ID`\n" +
+	"• `/admin-leave setup` (announcement channel and fallback approver)\n" +
+	"• `/admin-leave digest list`\n" +
+	"• `/admin-leave digest preview [#channel]` (today's digest, for the channel's members if given)\n" +
+	"• `/admin-leave digest add #channel [\"CRON\"] [tz=ZONE] [members]` (daily availability digest, default `0 9 * * 1-5`)\n" +
+	"• `/admin-leave digest remove #channel`\n" +
+	"• `/admin-leave usage [WEEKS]` (adoption by feature and department)\n" +
+	"• `/admin-leave flags`\n" +
+	"• `/admin-leave flag NAME on|off|default`\n" +
+	"Times are `YYYY-MM-DD` or `YYYY-MM-DDTHH:MM` in the user's office timezone. " +
+	"Add `--override` to change records in a period closed for payroll."
+
+func handleAdminLeaveCommand(app *App, cmd slack.SlashCommand) {
+	reply := func(text string) {
+		_, err := app.slackClient.PostEphemeral(cmd.ChannelID, cmd.UserID, slack.MsgOptionText(text, false))
+		if err != nil {
+			logger.Error("Failed to post admin reply: %v", err)
+		}
+	}
+
+	if !app.isAdmin(cmd.UserID) {
+		reply("❌ You are not allowed to use this command.")
+		return
+	}
+
+	args := splitArgs(cmd.Text)
+	if len(args) == 0 {
+		reply(adminLeaveUsage)
+		return
+	}
+
+	actor := "slack:" + cmd.UserID
+	text, err := app.runAdminLeaveCommand(actor, args)
+	if err != nil {
+		logger.Error("Admin command %q failed: %v", cmd.Text, err)
+		reply("❌ " + err.Error())
+		return
+	}
+	reply(text)
+}
+
+func (a *App) runAdminLeaveCommand(actor string, args []string) (string, error) {
+	override := false
+	filtered := args[:0:0]
+	for _, arg := range args {
+		if arg == "--override" {
+			override = true
+			continue
+		}
+		filtered = append(filtered, arg)
+	}
+	args = filtered
+	if len(args) == 0 {
+		return adminLeaveUsage, nil
+	}
+
+	switch args[0] {
+	case "list":
+		if len(args) != 2 {
+			return adminLeaveUsage, nil
+		}
+		username, err := a.resolveUserArg(args[1])
+		if err != nil {
+			return "", err
+		}
+		leaves, err := a.leaveRepo.ListByUsername(username, 20)
+		if err != nil {
+			return "", fmt.Errorf("error listing leaves: %v", err)
+		}
+		if len(leaves) == 0 {
+			return fmt.Sprintf("No leave records found for *%s*.", username), nil
+		}
+		var b strings.Builder
+		fmt.Fprintf(&b, "*Recent records for %s*\n", username)
+		for _, leave := range leaves {
+			b.WriteString(formatLeaveLine(&leave))
+			if u := a.leaveURL(leave.ID); u != "" {
+				b.WriteString(" <" + u + "|view>")
+			}
+			b.WriteString("\n")
+		}
+		return b.String(), nil
+
+	case "create":
+		if len(args) < 5 {
+			return adminLeaveUsage, nil
+		}
+		username, err := a.resolveUserArg(args[1])
+		if err != nil {
+			return "", err
+		}
+		loc := a.regionFor(username).Timezone
+		start, err := parseAdminTime(args[3], false, loc)
+		if err != nil {
+			return "", err
+		}
+		end, err := parseAdminTime(args[4], true, loc)
+		if err != nil {
+			return "", err
+		}
+		leave := &models.Leave{
+			Username:  username,
+			LeaveType: strings.ToUpper(args[2]),
+			StartTime: start,
+			EndTime:   end,
+			Reason:    strings.Join(args[5:], " "),
+		}
+		if err := a.adminCreateLeave(actor, leave, override); err != nil {
+			return "", err
+		}
+		return "✅ Created " + formatLeaveLine(leave), nil
+
+	case "edit":
+		if len(args) < 3 {
+			return adminLeaveUsage, nil
+		}
+		id, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid record id %q", args[1])
+		}
+		leave, err := a.adminUpdateLeave(actor, id, override, func(leave *models.Leave) error {
+			return a.applyLeaveEdits(leave, args[2:])
+		})
+		if err != nil {
+			return "", err
+		}
+		return "✏️ Updated " + formatLeaveLine(leave), nil
+
+	case "merge":
+		if len(args) != 3 {
+			return adminLeaveUsage, nil
+		}
+		keepID, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid record id %q", args[1])
+		}
+		mergeID, err := strconv.ParseInt(args[2], 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid record id %q", args[2])
+		}
+		leave, err := a.adminMergeLeaves(actor, keepID, mergeID, override)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("🔗 Merged #%d into %s", mergeID, formatLeaveLine(leave)), nil
+
+	case "delete":
+		if len(args) != 2 {
+			return adminLeaveUsage, nil
+		}
+		id, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid record id %q", args[1])
+		}
+		if err := a.adminDeleteLeave(actor, id, override); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("🗑️ Deleted record #%d", id), nil
+
+	case "employment":
+		if len(args) != 3 {
+			return adminLeaveUsage, nil
+		}
+		username, err := a.resolveUserArg(args[1])
+		if err != nil {
+			return "", err
+		}
+		userID, _ := resolveUserIDArg(args[1])
+		employmentType := strings.ToUpper(args[2])
+		if !isKnownEmploymentType(employmentType) {
+			return "", fmt.Errorf("unknown employment type %q", args[2])
+		}
+		if err := a.employeeRepo.SetEmploymentType(username, userID, employmentType); err != nil {
+			return "", fmt.Errorf("error updating employee: %v", err)
+		}
+		a.audit(actor, "set_employment_type", 0, nil, map[string]string{"username": username, "employment_type": employmentType})
+		return fmt.Sprintf("✅ *%s* is now recorded as %s", username, employmentType), nil
+
+	case "manager":
+		if len(args) != 3 {
+			return adminLeaveUsage, nil
+		}
+		username, err := a.resolveUserArg(args[1])
+		if err != nil {
+			return "", err
+		}
+		userID, _ := resolveUserIDArg(args[1])
+		managerID, err := resolveUserIDArg(args[2])
+		if err != nil {
+			return "", err
+		}
+		if err := a.employeeRepo.SetManager(username, userID, managerID); err != nil {
+			return "", fmt.Errorf("error updating employee: %v", err)
+		}
+		a.audit(actor, "set_manager", 0, nil, map[string]string{"username": username, "manager_id": managerID})
+		return fmt.Sprintf("✅ *%s* now reports to <@%s>", username, managerID), nil
+
+	case "audit":
+		if len(args) != 2 || args[1] != "verify" {
+			return adminLeaveUsage, nil
+		}
+		status, err := a.auditRepo.VerifyChain()
+		if err != nil {
+			return "", fmt.Errorf("error verifying audit log: %v", err)
+		}
+		return auditChainText(status), nil
+
+	case "viewer":
+		return a.runViewerCommand(actor, args[1:])
+
+	case "recurring":
+		return a.runRecurringCommand(actor, args[1:])
+
+	case "balance":
+		return a.runBalanceCommand(actor, args[1:])
+
+	case "coverage":
+		return a.runCoverageCommand(actor, args[1:])
+
+	case "office":
+		return a.runOfficeCommand(actor, args[1:])
+
+	case "holiday":
+		return a.runHolidayCommand(actor, args[1:])
+
+	case "approvals", "approve", "reject":
+		return a.runApprovalCommand(actor, args)
+
+	case "backup", "backups":
+		return a.runBackupCommand(actor, args)
+
+	case "deadletters":
+		return a.runDeadLetterCommand(actor, args[1:])
+
+	case "setup":
+		return a.runSetupCommand(actor, args[1:])
+
+	case "digest":
+		return a.runDigestCommand(actor, args[1:])
+
+	case "usage":
+		return a.runUsageCommand(args[1:])
+
+	case "flags", "flag":
+		return a.runFeatureFlagCommand(actor, args)
+
+	case "grant", "revoke":
+		if len(args) != 3 {
+			return adminLeaveUsage, nil
+		}
+		userID, err := resolveUserIDArg(args[1])
+		if err != nil {
+			return "", err
+		}
+		role := strings.ToLower(args[2])
+		if role != models.RoleAdmin && role != models.RoleHR {
+			return "", fmt.Errorf("unknown role %q", role)
+		}
+		verb := "Granted"
+		if args[0] == "grant" {
+			err = a.roleRepo.Grant(userID, role)
+		} else {
+			verb = "Revoked"
+			err = a.roleRepo.Revoke(userID, role)
+		}
+		if err != nil {
+			return "", fmt.Errorf("error updating roles: %v", err)
+		}
+		a.audit(actor, args[0]+"_role", 0, nil, map[string]string{"user_id": userID, "role": role})
+		return fmt.Sprintf("✅ %s role `%s` for <@%s>", verb, role, userID), nil
+	}
+
+	return adminLeaveUsage, nil
+}
+
+func (a *App) applyLeaveEdits(leave *models.Leave, edits []string) error {
+	for _, edit := range edits {
+		field, value, ok := strings.Cut(edit, "=")
+		if !ok {
+			return fmt.Errorf("invalid edit %q (expected field=value)", edit)
+		}
+
+		switch field {
+		case "user":
+			username, err := a.resolveUserArg(value)
+			if err != nil {
+				return err
+			}
+			leave.Username = username
+		case "type":
+			leave.LeaveType = strings.ToUpper(value)
+		case "start":
+			t, err := parseAdminTime(value, false, a.regionFor(leave.Username).Timezone)
+			if err != nil {
+				return err
+			}
+			leave.StartTime = t
+		case "end":
+			t, err := parseAdminTime(value, true, a.regionFor(leave.Username).Timezone)
+			if err != nil {
+				return err
+			}
+			leave.EndTime = t
+		case "reason":
+			leave.Reason = value
+		case "private_reason":
+			leave.PrivateReason = value
+		default:
+			return fmt.Errorf("unknown field %q", field)
+		}
+	}
+	return nil
+}
+
+func formatLeaveLine(leave *models.Leave) string {
+	return fmt.Sprintf("#%d *%s* %s %s → %s (%s)",
+		leave.ID,
+		leave.Username,
+		leave.LeaveType,
+		leave.StartTime.Format("Jan 2, 2006 3:04 PM"),
+		leave.EndTime.Format("Jan 2, 2006 3:04 PM"),
+		leave.Reason,
+	)
+}
+
+func auditChainText(status *models.AuditChainStatus) string {
+	if !status.Valid {
+		return fmt.Sprintf("🚨 *Audit log verification failed* at entry #%d: %s.\n"+
+			"%d entries before it verified; the last good hash is `%s`.",
+			status.BrokenAt, status.Problem, status.Entries, status.HeadHash)
+	}
+	text := fmt.Sprintf("✅ *Audit log intact*: %d chained entries verified.", status.Entries)
+	if status.HeadID != 0 {
+		text += fmt.Sprintf("\nLatest entry #%d has hash `%s`. Keep a copy of it: "+
+			"a later check must reach the same hash through this entry.", status.HeadID, status.HeadHash)
+	}
+	if status.Legacy > 0 {
+		text += fmt.Sprintf("\n%d older entries were written before chaining and can't be verified.", status.Legacy)
+	}
+	return text
+}
+
+// handleAuditVerify serves /api/admin/audit/verify, reporting whether the
+// audit log's hash chain is intact and its current head hash.
+func (a *App) handleAuditVerify(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	status, err := a.auditRepo.VerifyChain()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// handleParseMetrics serves /api/admin/metrics/parse, reporting how often the
+// model's JSON replies needed repairing and how many messages the rule
+// parser read without the model.
+func (a *App) handleParseMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	stats := a.openAI.RepairStats()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"json_replies": stats,
+		"repair_rate":  stats.RepairRate(),
+		"parsers":      a.openAI.ParserStats(),
+	})
+}
+
+// requireAdminKey guards the admin HTTP API with the ADMIN_API_KEY shared
+// secret. The API is disabled entirely when no key is configured.
+func (a *App) requireAdminKey(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if a.config.AdminAPIKey == "" {
+			http.Error(w, "Admin API disabled", http.StatusForbidden)
+			return
+		}
+
+		key := r.Header.Get("X-Admin-Key")
+		if subtle.ConstantTimeCompare([]byte(key), []byte(a.config.AdminAPIKey)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func adminActor(r *http.Request) string {
+	if name := r.Header.Get("X-Admin-Actor"); name != "" {
+		return "api:" + name
+	}
+	return "api"
+}
+
+// adminOverride reports whether the request asks to bypass the payroll lock
+// with ?override=true.
+func adminOverride(r *http.Request) bool {
+	return r.URL.Query().Get("override") == "true"
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// handleAdminLeaves serves /api/admin/leaves: GET lists a user's records,
+// POST creates a record.
+func (a *App) handleAdminLeaves(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		username := r.URL.Query().Get("username")
+		if username == "" {
+			http.Error(w, "username is required", http.StatusBadRequest)
+			return
+		}
+		leaves, err := a.leaveRepo.ListByUsername(username, 100)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, leaves)
+
+	case http.MethodPost:
+		var leave models.Leave
+		if err := json.NewDecoder(r.Body).Decode(&leave); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		leave.ID = 0
+		if err := a.adminCreateLeave(adminActor(r), &leave, adminOverride(r)); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, http.StatusCreated, leave)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleAdminLeave serves /api/admin/leaves/{id}: GET, PUT (partial update
+// with the fields present in the body) and DELETE, plus POST
+// /api/admin/leaves/{id}/cancel, which cancels the record the way its owner
+// would.
+func (a *App) handleAdminLeave(w http.ResponseWriter, r *http.Request) {
+	path, cancel := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/api/admin/leaves/"), "/cancel")
+	id, err := strconv.ParseInt(path, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid record id", http.StatusBadRequest)
+		return
+	}
+
+	if cancel {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		leave, err := a.leaveRepo.GetByID(id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err := a.cancelLeave(r.Context(), adminActor(r), leave, ""); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		leave, err := a.leaveRepo.GetByID(id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, leave)
+
+	case http.MethodPut, http.MethodPatch:
+		leave, err := a.adminUpdateLeave(adminActor(r), id, adminOverride(r), func(leave *models.Leave) error {
+			if err := json.NewDecoder(r.Body).Decode(leave); err != nil {
+				return fmt.Errorf("invalid request body: %v", err)
+			}
+			return nil
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, http.StatusOK, leave)
+
+	case http.MethodDelete:
+		if err := a.adminDeleteLeave(adminActor(r), id, adminOverride(r)); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleAdminCommand serves POST /api/admin/command, which runs an
+// `/admin-leave` subcommand given as {"args": [...]} and returns its reply
+// as {"output": "..."}. It's how latebotctl reaches everything the slash
+// command can do.
+func (a *App) handleAdminCommand(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Args []string `json:"args"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	output, err := a.runAdminLeaveCommand(adminActor(r), req.Args)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"output": output})
+}
+
+func (a *App) handleAdminMerge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		KeepID  int64 `json:"keep_id"`
+		MergeID int64 `json:"merge_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	leave, err := a.adminMergeLeaves(adminActor(r), req.KeepID, req.MergeID, adminOverride(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, leave)
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"slack-leaves-ai-agent/models"
+
+	"github.com/slack-go/slack"
+)
+
+// Action IDs of the approver's buttons. The button value is
+// "LEAVE_ID:REQUESTER_ID".
+const (
+	approveLeaveActionID = "leave_approve"
+	rejectLeaveActionID  = "leave_reject"
+)
+
+// maxPendingApprovals is how many pending records the admin list shows.
+const maxPendingApprovals = 30
+
+// approverFor returns who must sign off a new record, or "" when it doesn't
+// need approval: see needsApproval, or approvals are flagged off, there's no
+// manager or fallback approver to ask, or the approver can't be sent a DM.
+func (a *App) approverFor(leave *models.Leave) string {
+	if !needsApproval(leave, a.config.ApprovalLeaveTypes, a.userPolicy(leave.Username)) {
+		return ""
+	}
+	if !a.featureEnabled(flagApprovals) {
+		return ""
+	}
+	if !a.hasScope(scopeIMWrite) {
+		logger.Debug("Skipping approval of %s's %s: missing %s scope", leave.Username, leave.LeaveType, scopeIMWrite)
+		return ""
+	}
+	return a.approverOf(leave.Username)
+}
+
+// needsApproval reports whether a record's kind needs sign-off: its type is
+// in approvalTypes, it doesn't start a series and it isn't a sick day kept
+// private by the author's policy. Series are approved as they are, since
+// only their first occurrence would wait; private sick days are recorded
+// without anyone being asked.
+func needsApproval(leave *models.Leave, approvalTypes []string, policy LeavePolicy) bool {
+	if leave.Recurrence != "" || !containsString(approvalTypes, leave.LeaveType) {
+		return false
+	}
+	return !(leave.Sick && policy.SickNoQuestions)
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// requestApproval sends the approver a DM with Approve and Reject buttons
+// for a pending record, and who else on the requester's team is out then.
+func (a *App) requestApproval(ctx context.Context, leave *models.Leave, requesterID, approverID string) {
+	channel, _, _, err := a.slackClient.OpenConversationContext(ctx, &slack.OpenConversationParameters{Users: []string{approverID}})
+	if err != nil {
+		logger.Error("Failed to open DM with approver %s: %v", approverID, err)
+		return
+	}
+
+	text := fmt.Sprintf("📝 <@%s> asked for *%s* from %s to %s (%s)\nReason: %s",
+		requesterID, leave.LeaveType,
+		leave.StartTime.Format("Mon Jan 2, 3:04 PM"), leave.EndTime.Format("Mon Jan 2, 3:04 PM"),
+		leave.Duration, publicReason(leave))
+	value := fmt.Sprintf("%d:%s", leave.ID, requesterID)
+	approve := slack.NewButtonBlockElement(approveLeaveActionID, value,
+		slack.NewTextBlockObject("plain_text", "Approve", false, false)).WithStyle(slack.StylePrimary)
+	reject := slack.NewButtonBlockElement(rejectLeaveActionID, value,
+		slack.NewTextBlockObject("plain_text", "Reject", false, false)).WithStyle(slack.StyleDanger)
+
+	blocks := []slack.Block{slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", text, false, false), nil, nil)}
+	if overlap := a.teamOverlapText(leave); overlap != "" {
+		blocks = append(blocks, slack.NewContextBlock("", slack.NewTextBlockObject("mrkdwn", overlap, false, false)))
+	}
+	blocks = append(blocks, slack.NewActionBlock("approval_"+strconv.FormatInt(leave.ID, 10), approve, reject))
+
+	_, _, err = a.slackClient.PostMessageContext(ctx, channel.ID,
+		slack.MsgOptionText(text, false),
+		slack.MsgOptionBlocks(blocks...),
+	)
+	if err != nil {
+		logger.Error("Failed to ask %s to approve leave %d: %v", approverID, leave.ID, err)
+	}
+}
+
+// resubmitForApproval runs after an author changes their own record. When
+// the record needs sign-off it goes back to pending, if it was approved, and
+// the approver is asked again, so an approved day can't be moved or
+// stretched without them. It reports whether approval was requested.
+func (a *App) resubmitForApproval(ctx context.Context, actor string, leave *models.Leave, requesterID string) bool {
+	approverID := a.approverFor(leave)
+	if approverID == "" || leave.Status == models.LeaveStatusRejected {
+		return false
+	}
+	if leave.Status == models.LeaveStatusApproved {
+		ok, err := a.leaveRepo.Resubmit(leave.ID)
+		if err != nil {
+			logger.Error("Error sending leave %d back for approval: %v", leave.ID, err)
+			return false
+		}
+		if !ok {
+			return false
+		}
+		before := *leave
+		leave.Status = models.LeaveStatusPending
+		a.audit(actor, "resubmitted", leave.ID, before, leave)
+	}
+	a.requestApproval(ctx, leave, requesterID, approverID)
+	return true
+}
+
+func approvalInteraction(callback slack.InteractionCallback) bool {
+	for _, action := range callback.ActionCallback.BlockActions {
+		if action.ActionID == approveLeaveActionID || action.ActionID == rejectLeaveActionID {
+			return true
+		}
+	}
+	return false
+}
+
+// handleApprovalAction records the approver's decision and replaces the
+// buttons with it. Only the record's approver, the backup covering for
+// them, or an admin can decide.
+func (a *App) handleApprovalAction(ctx context.Context, callback slack.InteractionCallback) {
+	for _, action := range callback.ActionCallback.BlockActions {
+		if action.ActionID != approveLeaveActionID && action.ActionID != rejectLeaveActionID {
+			continue
+		}
+
+		idText, requesterID, _ := strings.Cut(action.Value, ":")
+		id, err := strconv.ParseInt(idText, 10, 64)
+		if err != nil {
+			logger.Error("Invalid approval button value %q", action.Value)
+			continue
+		}
+		leave, err := a.leaveRepo.GetByID(id)
+		if err != nil {
+			a.replyFeedback(callback, "That record no longer exists.")
+			continue
+		}
+		if !a.isAdmin(callback.User.ID) && callback.User.ID != a.approverOf(leave.Username) &&
+			callback.User.ID != a.directApproverOf(leave.Username) {
+			a.replyFeedback(callback, "❌ Only the approver can decide on this request.")
+			continue
+		}
+
+		text, err := a.decideLeave(ctx, "slack:"+callback.User.ID, leave, requesterID,
+			action.ActionID == approveLeaveActionID)
+		if err != nil {
+			a.replyFeedback(callback, "❌ "+err.Error())
+			continue
+		}
+
+		_, _, err = a.slackClient.PostMessage(callback.Channel.ID,
+			slack.MsgOptionReplaceOriginal(callback.ResponseURL),
+			slack.MsgOptionText(text, false))
+		if err != nil {
+			logger.Error("Failed to update approval request: %v", err)
+		}
+	}
+}
+
+// approverOf is who currently approves username's records, whether or not
+// any of their types need it: the backup of an approver who's on leave.
+func (a *App) approverOf(username string) string {
+	approverID := a.directApproverOf(username)
+	if backupID := a.activeBackup(approverID); backupID != "" {
+		return backupID
+	}
+	return approverID
+}
+
+// decideLeave approves or rejects a pending record and tells the requester.
+// A rejected record gives back the balance it took; it stays in the table
+// for the audit trail but no longer shows up anywhere. requesterID may be
+// empty, in which case the requester is looked up in the employees table.
+func (a *App) decideLeave(ctx context.Context, actor string, leave *models.Leave, requesterID string, approve bool) (string, error) {
+	status, verb, emoji := models.LeaveStatusRejected, "rejected", "❌"
+	if approve {
+		status, verb, emoji = models.LeaveStatusApproved, "approved", "✅"
+	}
+
+	ok, err := a.leaveRepo.SetStatus(leave.ID, models.LeaveStatusPending, status, actor)
+	if err != nil {
+		return "", fmt.Errorf("error updating leave %d: %v", leave.ID, err)
+	}
+	if !ok {
+		return "", fmt.Errorf("leave #%d isn't waiting for approval (it's %s)", leave.ID, strings.ToLower(leave.Status))
+	}
+	before := *leave
+	leave.Status = status
+	a.audit(actor, "approval_"+verb, leave.ID, before, leave)
+	if !approve {
+		a.recordLedgerChange(actor, "reject", &before, nil)
+	}
+	a.syncTeamCalendar(ctx, &before, leave)
+
+	var email string
+	if employee, err := a.employeeRepo.Get(leave.Username); err == nil {
+		email = employee.Email
+		if requesterID == "" {
+			requesterID = employee.SlackUserID
+		}
+	}
+	if approve {
+		a.syncDeskBooking(ctx, leave, email)
+	}
+	span := fmt.Sprintf("%s from %s to %s", leave.LeaveType,
+		leave.StartTime.Format("Mon Jan 2"), leave.EndTime.Format("Mon Jan 2"))
+	if requesterID != "" {
+		a.notifyUser(ctx, requesterID, "Leave "+verb,
+			fmt.Sprintf("%s Your %s was %s by <@%s>.", emoji, span, verb, strings.TrimPrefix(strings.TrimPrefix(actor, "slack:"), "email:")))
+	} else {
+		logger.Error("Couldn't tell %s their leave %d was %s: no Slack ID on file", leave.Username, leave.ID, verb)
+	}
+
+	return fmt.Sprintf("%s %s's %s: %s.", emoji, leave.Username, span, verb), nil
+}
+
+// runApprovalCommand handles `/admin-leave approvals`, `approve ID` and
+// `reject ID`.
+func (a *App) runApprovalCommand(actor string, args []string) (string, error) {
+	if args[0] == "approvals" {
+		if len(args) != 1 {
+			return adminLeaveUsage, nil
+		}
+		leaves, err := a.leaveRepo.ListByStatus(models.LeaveStatusPending, maxPendingApprovals)
+		if err != nil {
+			return "", fmt.Errorf("error loading pending leaves: %v", err)
+		}
+		if len(leaves) == 0 {
+			return "Nothing is waiting for approval.", nil
+		}
+		lines := []string{"⏳ *Waiting for approval*"}
+		for _, leave := range leaves {
+			approver := "no approver"
+			if id := a.approverOf(leave.Username); id != "" {
+				approver = "<@" + id + ">"
+			}
+			lines = append(lines, fmt.Sprintf("• #%d *%s* %s from %s to %s, asked %s, %s", leave.ID, leave.Username, leave.LeaveType,
+				leave.StartTime.Format("Jan 2"), leave.EndTime.Format("Jan 2"), leave.CreatedAt.Format("Jan 2"), approver))
+		}
+		return strings.Join(lines, "\n"), nil
+	}
+
+	if len(args) != 2 {
+		return adminLeaveUsage, nil
+	}
+	id, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid leave ID %q", args[1])
+	}
+	leave, err := a.leaveRepo.GetByID(id)
+	if err != nil {
+		return "", err
+	}
+	return a.decideLeave(context.Background(), actor, leave, "", args[0] == "approve")
+}
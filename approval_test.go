@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+
+	"slack-leaves-ai-agent/models"
+)
+
+func TestNeedsApproval(t *testing.T) {
+	types := []string{"FULL_DAY", "WFH"}
+	private := LeavePolicy{SickNoQuestions: true}
+
+	tests := []struct {
+		name   string
+		leave  models.Leave
+		policy LeavePolicy
+		want   bool
+	}{
+		{"approval type", models.Leave{LeaveType: "FULL_DAY"}, LeavePolicy{}, true},
+		{"other type", models.Leave{LeaveType: "LATE_ARRIVAL"}, LeavePolicy{}, false},
+		{"series", models.Leave{LeaveType: "WFH", Recurrence: "FREQ=WEEKLY;BYDAY=FR"}, LeavePolicy{}, false},
+		{"sick day, no-questions policy", models.Leave{LeaveType: "FULL_DAY", Sick: true}, private, false},
+		{"sick day, usual policy", models.Leave{LeaveType: "FULL_DAY", Sick: true}, LeavePolicy{}, true},
+		{"not sick, no-questions policy", models.Leave{LeaveType: "FULL_DAY"}, private, true},
+	}
+	for _, tt := range tests {
+		if got := needsApproval(&tt.leave, types, tt.policy); got != tt.want {
+			t.Errorf("%s: needsApproval = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
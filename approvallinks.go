@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+	"slack-leaves-ai-agent/services"
+)
+
+// approvalLinkPath is where the approve and reject links in approval emails
+// point.
+const approvalLinkPath = "/approvals/email"
+
+// approvalLinkTTL is how long an emailed approval link works.
+const approvalLinkTTL = 7 * 24 * time.Hour
+
+// approvalEmailSchedule is how often pending records are checked for
+// approvers to email.
+const approvalEmailSchedule = "*/15 * * * *"
+
+// maxApprovalEmails bounds how many approval emails one check sends.
+const maxApprovalEmails = 100
+
+// approvalEmailsEnabled reports whether approvers who don't answer in Slack
+// are emailed: that needs APPROVAL_LINK_SECRET to sign the links,
+// PUBLIC_BASE_URL for them to point at and SMTP to send them.
+func (a *App) approvalEmailsEnabled() bool {
+	return a.config.ApprovalLinkSecret != "" && a.config.PublicBaseURL != "" && a.approvalMailer != nil
+}
+
+// approvalLink is what a signed approval link carries.
+type approvalLink struct {
+	LeaveID    int64
+	Approve    bool
+	ApproverID string
+	Expires    time.Time
+}
+
+func (l approvalLink) decision() string {
+	if l.Approve {
+		return "approve"
+	}
+	return "reject"
+}
+
+func (l approvalLink) label() string {
+	if l.Approve {
+		return "Approve"
+	}
+	return "Reject"
+}
+
+func (l approvalLink) payload() string {
+	return fmt.Sprintf("%d.%s.%s.%d", l.LeaveID, l.decision(), l.ApproverID, l.Expires.Unix())
+}
+
+// signApprovalLink returns the token of link: its fields and an HMAC of
+// them keyed with APPROVAL_LINK_SECRET.
+func (a *App) signApprovalLink(link approvalLink) string {
+	mac := hmac.New(sha256.New, []byte(a.config.ApprovalLinkSecret))
+	mac.Write([]byte(link.payload()))
+	return link.payload() + "." + hex.EncodeToString(mac.Sum(nil))
+}
+
+var errBadApprovalLink = errors.New("this approval link is invalid")
+
+// verifyApprovalLink checks a token's signature and expiry.
+func (a *App) verifyApprovalLink(token string, now time.Time) (approvalLink, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 5 || (parts[1] != "approve" && parts[1] != "reject") {
+		return approvalLink{}, errBadApprovalLink
+	}
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return approvalLink{}, errBadApprovalLink
+	}
+	expires, err := strconv.ParseInt(parts[3], 10, 64)
+	if err != nil {
+		return approvalLink{}, errBadApprovalLink
+	}
+	link := approvalLink{LeaveID: id, Approve: parts[1] == "approve", ApproverID: parts[2], Expires: time.Unix(expires, 0)}
+
+	want := a.signApprovalLink(link)
+	if !hmac.Equal([]byte(token), []byte(want)) {
+		return approvalLink{}, errBadApprovalLink
+	}
+	if now.After(link.Expires) {
+		return approvalLink{}, errors.New("this approval link has expired; decide in Slack instead")
+	}
+	return link, nil
+}
+
+func (a *App) approvalLinkURL(link approvalLink) string {
+	return strings.TrimSuffix(a.config.PublicBaseURL, "/") + approvalLinkPath + "?token=" + url.QueryEscape(a.signApprovalLink(link))
+}
+
+// approvalEmailJob emails the approvers of records that have waited
+// APPROVAL_EMAIL_AFTER_HOURS without a decision.
+func (a *App) approvalEmailJob() (scheduledJob, error) {
+	schedule, err := services.ParseCron(approvalEmailSchedule)
+	if err != nil {
+		return scheduledJob{}, err
+	}
+	return scheduledJob{
+		name:     "approvalEmails",
+		schedule: schedule,
+		loc:      a.config.Timezone,
+		run: func(ctx context.Context, now time.Time) {
+			a.sendApprovalEmails(ctx, now)
+		},
+	}, nil
+}
+
+func (a *App) sendApprovalEmails(ctx context.Context, now time.Time) {
+	if !a.approvalEmailsEnabled() || !a.featureEnabled(flagApprovals) {
+		return
+	}
+	// created_at is in the server's time, not the schedule's
+	leaves, err := a.leaveRepo.ListAwaitingApprovalEmail(time.Now().Add(-a.config.ApprovalEmailAfter), maxApprovalEmails)
+	if err != nil {
+		logger.Error("Failed to load records awaiting approval: %v", err)
+		return
+	}
+	for i := range leaves {
+		a.emailApprover(ctx, &leaves[i], now)
+	}
+}
+
+// emailApprover sends the current approver of a pending record signed
+// links to approve or reject it. A record is only emailed about once, even
+// if the approver has no email address on file.
+func (a *App) emailApprover(ctx context.Context, leave *models.Leave, now time.Time) {
+	approverID := a.approverOf(leave.Username)
+	if approverID == "" {
+		return
+	}
+
+	expires := now.Add(approvalLinkTTL)
+	approve := approvalLink{LeaveID: leave.ID, Approve: true, ApproverID: approverID, Expires: expires}
+	reject := approve
+	reject.Approve = false
+
+	text := fmt.Sprintf("%s asked for %s from %s to %s (%s).\nReason: %s\n\n"+
+		"It has been waiting for your approval since %s.\n\n"+
+		"Approve: %s\n\nReject: %s\n\n"+
+		"Each link opens a page to confirm and works until %s. You can also decide in Slack.",
+		leave.Username, leave.LeaveType,
+		leave.StartTime.Format("Mon Jan 2, 3:04 PM"), leave.EndTime.Format("Mon Jan 2, 3:04 PM"),
+		leave.Duration, publicReason(leave), leave.CreatedAt.Format("Mon Jan 2, 3:04 PM"),
+		a.approvalLinkURL(approve), a.approvalLinkURL(reject), expires.In(a.config.Timezone).Format("Mon Jan 2"))
+
+	err := a.notifyVia(ctx, a.approvalMailer, approverID, "Leave request waiting: "+leave.Username, text)
+	if errors.Is(err, services.ErrNoAddress) {
+		logger.Debug("No email for approver %s of leave %d", approverID, leave.ID)
+	} else if err != nil {
+		logger.Error("Failed to email approver %s about leave %d: %v", approverID, leave.ID, err)
+		return
+	}
+	if err := a.leaveRepo.MarkApprovalEmailed(leave.ID); err != nil {
+		logger.Error("Failed to mark leave %d as emailed: %v", leave.ID, err)
+	}
+}
+
+var approvalPage = template.Must(template.New("approval").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><meta name="viewport" content="width=device-width"><title>Leave approval</title></head>
+<body style="font-family: sans-serif; max-width: 32em; margin: 3em auto">
+<p>{{.Message}}</p>
+{{if .Token}}<form method="post"><input type="hidden" name="token" value="{{.Token}}"><button type="submit">{{.Button}}</button></form>{{end}}
+</body></html>
+`))
+
+type approvalPageData struct {
+	Message string
+	Token   string // set to ask for confirmation
+	Button  string
+}
+
+// handleApprovalLink serves the links in approval emails. Opening one only
+// shows what it would do, since mail scanners open links too; the decision
+// is taken when the approver confirms. The link must still belong to the
+// record's approver, or the backup covering for them.
+func (a *App) handleApprovalLink(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	page := func(status int, data approvalPageData) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Referrer-Policy", "no-referrer")
+		w.WriteHeader(status)
+		if err := approvalPage.Execute(w, data); err != nil {
+			logger.Error("Failed to write approval page: %v", err)
+		}
+	}
+
+	if a.config.ApprovalLinkSecret == "" {
+		http.NotFound(w, r)
+		return
+	}
+	token := r.URL.Query().Get("token")
+	if r.Method == http.MethodPost {
+		token = r.PostFormValue("token")
+	}
+	link, err := a.verifyApprovalLink(token, time.Now())
+	if err != nil {
+		page(http.StatusForbidden, approvalPageData{Message: err.Error() + "."})
+		return
+	}
+	leave, err := a.leaveRepo.GetByID(link.LeaveID)
+	if err != nil {
+		page(http.StatusNotFound, approvalPageData{Message: "That record no longer exists."})
+		return
+	}
+	if link.ApproverID != a.approverOf(leave.Username) && link.ApproverID != a.directApproverOf(leave.Username) {
+		page(http.StatusForbidden, approvalPageData{Message: "You're no longer the approver of this request."})
+		return
+	}
+	if leave.Status != models.LeaveStatusPending {
+		page(http.StatusOK, approvalPageData{Message: fmt.Sprintf("This request was already %s.", strings.ToLower(leave.Status))})
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		page(http.StatusOK, approvalPageData{
+			Message: fmt.Sprintf("%s %s's %s from %s to %s (%s)?", link.label(), leave.Username, leave.LeaveType,
+				leave.StartTime.Format("Mon Jan 2"), leave.EndTime.Format("Mon Jan 2"), leave.Duration),
+			Token:  token,
+			Button: link.label(),
+		})
+		return
+	}
+
+	text, err := a.decideLeave(r.Context(), "email:"+link.ApproverID, leave, "", link.Approve)
+	if err != nil {
+		page(http.StatusConflict, approvalPageData{Message: err.Error() + "."})
+		return
+	}
+	page(http.StatusOK, approvalPageData{Message: text})
+}
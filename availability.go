@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// availabilityBlocks answers "who's out on day?", listing each record that
+// keeps someone away for all or part of it with its exact window.
+func (a *App) availabilityBlocks(day time.Time) ([]slack.Block, error) {
+	// Stored times are office wall-clock times, so the date is used as-is
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	leaves, err := a.leaveRepo.ListBetween(start, start.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load leave: %v", err)
+	}
+	inactive, err := a.employeeRepo.GetInactive()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load departed employees: %v", err)
+	}
+
+	var lines []string
+	for i := range leaves {
+		leave := &leaves[i]
+		if leave.LeaveType == "IN_OFFICE" || inactive[leave.Username] {
+			continue
+		}
+		lines = append(lines, availabilityLine(leave.Username, leave.LeaveType, leave.StartTime, leave.EndTime, leave.Reason))
+	}
+
+	text := fmt.Sprintf("*Who's away on %s*\n", start.Format("Monday, Jan 2"))
+	if len(lines) == 0 {
+		text += "Everyone's around 🎉"
+	} else {
+		text += strings.Join(lines, "\n")
+	}
+	return []slack.Block{
+		slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", text, false, false), nil, nil),
+	}, nil
+}
+
+// availabilityLine describes one record in an availability answer. Partial
+// days show their window to the minute.
+func availabilityLine(username, leaveType string, start, end time.Time, reason string) string {
+	window := start.Format("3:04 PM") + "–" + end.Format("3:04 PM")
+	switch leaveType {
+	case "FULL_DAY", "LOSS_OF_PAY":
+		if end.Format("2006-01-02") > start.Format("2006-01-02") {
+			return fmt.Sprintf("🌴 *%s* – out until %s", username, end.Format("Jan 2"))
+		}
+		return fmt.Sprintf("🌴 *%s* – out all day", username)
+	case "PARENTAL":
+		return fmt.Sprintf("👶 *%s* – on parental leave until %s", username, end.Format("Jan 2"))
+	case "HALF_DAY":
+		return fmt.Sprintf("🌓 *%s* – out %s", username, window)
+	case "APPOINTMENT":
+		return fmt.Sprintf("🩺 *%s* – away %s%s", username, window, reasonSuffix(reason))
+	case "LATE_ARRIVAL":
+		return fmt.Sprintf("⏰ *%s* – in from %s", username, end.Format("3:04 PM"))
+	case "EARLY_DEPARTURE":
+		return fmt.Sprintf("🏃 *%s* – leaving at %s", username, start.Format("3:04 PM"))
+	case "WFH":
+		return fmt.Sprintf("🏠 *%s* – working from home", username)
+	}
+	return fmt.Sprintf("*%s* – %s %s", username, leaveType, window)
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"slack-leaves-ai-agent/db/migrations"
+	"slack-leaves-ai-agent/models"
+)
+
+// bootstrapMu keeps two provisioning runs from migrating at the same time.
+var bootstrapMu sync.Mutex
+
+// bootstrapConfig is the body of POST /api/bootstrap. Every field is
+// optional. Leave types are built in, so leave_types is only checked: a
+// pipeline that expects a type this version doesn't know fails early.
+type bootstrapConfig struct {
+	LeaveTypes []string           `json:"leave_types"`
+	Offices    []models.Location  `json:"offices"`
+	Holidays   []bootstrapHoliday `json:"holidays"`
+}
+
+type bootstrapHoliday struct {
+	Office string `json:"office"`
+	Date   string `json:"date"` // YYYY-MM-DD
+	Name   string `json:"name"`
+}
+
+// bootstrapResult reports what a bootstrap run did and whether the instance
+// is ready to take traffic.
+type bootstrapResult struct {
+	SchemaBefore int               `json:"schema_before"`
+	Schema       int               `json:"schema"`
+	Offices      int               `json:"offices"`
+	Holidays     int               `json:"holidays"`
+	Ready        bool              `json:"ready"`
+	Checks       map[string]string `json:"checks"`
+}
+
+// handleBootstrap serves POST /api/bootstrap: it applies migrations, seeds
+// offices and holidays from the body, and reports readiness. Offices and
+// holidays are upserted, so the same config can be applied on every deploy.
+// It answers 200 when the instance is ready and 503 when a check failed.
+func (a *App) handleBootstrap(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var config bootstrapConfig
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+			http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if err := config.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	bootstrapMu.Lock()
+	defer bootstrapMu.Unlock()
+
+	result := &bootstrapResult{Checks: make(map[string]string)}
+	var err error
+	if result.SchemaBefore, err = migrations.CurrentVersion(a.db); err != nil {
+		http.Error(w, fmt.Sprintf("error reading schema version: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if err := migrations.Upgrade(a.db); err != nil {
+		logger.Error("Bootstrap migration failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	actor := adminActor(r)
+	if err := a.seedBootstrap(actor, &config, result); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	a.checkReadiness(r, result)
+	logger.Info("Bootstrap by %s: schema %d → %d, %d offices, %d holidays, ready=%t",
+		actor, result.SchemaBefore, result.Schema, result.Offices, result.Holidays, result.Ready)
+
+	status := http.StatusOK
+	if !result.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, result)
+}
+
+// validate checks the whole config before anything is written, so a typo
+// doesn't leave an instance half seeded.
+func (c *bootstrapConfig) validate() error {
+	for _, leaveType := range c.LeaveTypes {
+		if !isKnownLeaveType(leaveType) {
+			return fmt.Errorf("unknown leave type %q", leaveType)
+		}
+	}
+
+	for i := range c.Offices {
+		office := &c.Offices[i]
+		office.Code = strings.ToUpper(office.Code)
+		if office.Code == "" || office.Name == "" {
+			return fmt.Errorf("office %d needs a code and a name", i+1)
+		}
+		if _, err := time.LoadLocation(office.Timezone); err != nil {
+			return fmt.Errorf("office %s: unknown timezone %q", office.Code, office.Timezone)
+		}
+	}
+
+	for i := range c.Holidays {
+		holiday := &c.Holidays[i]
+		holiday.Office = strings.ToUpper(holiday.Office)
+		if _, err := time.Parse("2006-01-02", holiday.Date); err != nil {
+			return fmt.Errorf("holiday %d: invalid date %q (use YYYY-MM-DD)", i+1, holiday.Date)
+		}
+		if holiday.Office == "" || holiday.Name == "" {
+			return fmt.Errorf("holiday %d needs an office and a name", i+1)
+		}
+	}
+	return nil
+}
+
+// seedBootstrap upserts the config's offices, then its holidays. A holiday
+// may name an office that already exists rather than one in the config.
+func (a *App) seedBootstrap(actor string, config *bootstrapConfig, result *bootstrapResult) error {
+	for i := range config.Offices {
+		office := &config.Offices[i]
+		if err := a.locationRepo.Upsert(office); err != nil {
+			return fmt.Errorf("error saving office %s: %v", office.Code, err)
+		}
+		a.audit(actor, "bootstrap_office", 0, nil, office)
+		result.Offices++
+	}
+
+	for _, h := range config.Holidays {
+		if _, err := a.locationRepo.Get(h.Office); err != nil {
+			return fmt.Errorf("holiday %s on %s: %v", h.Name, h.Date, err)
+		}
+		date, _ := time.Parse("2006-01-02", h.Date)
+		holiday := &models.Holiday{LocationCode: h.Office, Date: date, Name: h.Name}
+		if err := a.locationRepo.AddHoliday(holiday); err != nil {
+			return fmt.Errorf("error saving holiday %s on %s: %v", h.Name, h.Date, err)
+		}
+		result.Holidays++
+	}
+	if result.Holidays > 0 {
+		a.audit(actor, "bootstrap_holidays", 0, nil, config.Holidays)
+	}
+	return nil
+}
+
+// checkReadiness fills in the checks a pipeline waits on before sending
+// traffic: the database answers, its schema is current, and the Slack token
+// works.
+func (a *App) checkReadiness(r *http.Request, result *bootstrapResult) {
+	result.Ready = true
+	fail := func(check, detail string) {
+		result.Checks[check] = detail
+		result.Ready = false
+	}
+
+	if err := a.db.PingContext(r.Context()); err != nil {
+		fail("database", err.Error())
+	} else {
+		result.Checks["database"] = "ok"
+	}
+
+	version, err := migrations.CurrentVersion(a.db)
+	result.Schema = version
+	switch {
+	case err != nil:
+		fail("schema", err.Error())
+	case version != migrations.Version():
+		fail("schema", fmt.Sprintf("at version %d, want %d", version, migrations.Version()))
+	default:
+		result.Checks["schema"] = "ok"
+	}
+
+	if _, err := a.slackClient.AuthTestContext(r.Context()); err != nil {
+		fail("slack", err.Error())
+	} else {
+		result.Checks["slack"] = "ok"
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"github.com/slack-go/slack"
+)
+
+// ignoredSender reports whether ev comes from one of IGNORED_SENDERS, which
+// lists user, bot and app IDs. Messages carrying a bot ID are skipped
+// anyway; this is for integrations that post through a user account, such
+// as standup bots posting answers on someone's behalf.
+func (a *App) ignoredSender(ev *slack.MessageEvent) bool {
+	ids := []string{ev.User, ev.BotID}
+	if ev.BotProfile != nil {
+		ids = append(ids, ev.BotProfile.ID, ev.BotProfile.AppID)
+	}
+	for _, id := range ids {
+		if id != "" && containsString(a.config.IgnoredSenders, id) {
+			return true
+		}
+	}
+	return false
+}
+
+// ignoredMessage reports whether text matches IGNORED_MESSAGE_PATTERN, e.g.
+// the template of a workflow or standup post.
+func (a *App) ignoredMessage(text string) bool {
+	return a.config.IgnoredMessagePattern != nil && a.config.IgnoredMessagePattern.MatchString(text)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+	"slack-leaves-ai-agent/services/calendar"
+)
+
+// buildTeamCalendar returns the Google Calendar client, or nil when
+// GOOGLE_CALENDAR_ID or the OAuth credentials aren't set.
+func buildTeamCalendar(config *Config) *calendar.Client {
+	if config.GoogleCalendarID == "" {
+		return nil
+	}
+	if config.GoogleClientID == "" || config.GoogleClientSecret == "" || config.GoogleRefreshToken == "" {
+		logger.Error("Google Calendar sync disabled: GOOGLE_CLIENT_ID, GOOGLE_CLIENT_SECRET and GOOGLE_REFRESH_TOKEN are required")
+		return nil
+	}
+	return calendar.NewClient(calendar.Config{
+		ClientID:     config.GoogleClientID,
+		ClientSecret: config.GoogleClientSecret,
+		RefreshToken: config.GoogleRefreshToken,
+		CalendarID:   config.GoogleCalendarID,
+	})
+}
+
+// onTeamCalendar reports whether a record belongs on the team calendar:
+// approved absences and remote days. Office days aren't news.
+func onTeamCalendar(leave *models.Leave) bool {
+	return leave != nil && leave.DeletedAt == nil && leave.LeaveType != "IN_OFFICE" &&
+		(leave.Status == "" || leave.Status == models.LeaveStatusApproved)
+}
+
+// leaveEventSummary titles a record's calendar event, e.g. "alice: Working
+// from home".
+func leaveEventSummary(leave *models.Leave) string {
+	if info, ok := models.FindLeaveType(leave.LeaveType); ok {
+		return fmt.Sprintf("%s: %s", leave.Username, info.Description)
+	}
+	return fmt.Sprintf("%s: %s", leave.Username, leave.LeaveType)
+}
+
+// calendarEvent is a record's event. Stored times are wall-clock times in
+// the owner's office timezone, loc. Only the public reason goes on it;
+// private details never leave the bot.
+func calendarEvent(leave *models.Leave, loc *time.Location) calendar.Event {
+	return calendar.Event{
+		Summary:     leaveEventSummary(leave),
+		Description: leave.Reason,
+		Start:       wallClock(leave.StartTime, loc),
+		End:         wallClock(leave.EndTime, loc),
+		AllDay:      isDayOff(leave.LeaveType),
+	}
+}
+
+// syncTeamCalendar brings the team calendar in line with a change from
+// before to after, either of which is nil for a record created or removed.
+// The event ID is kept on the record. Failures are logged; the record stands
+// whatever the calendar says.
+func (a *App) syncTeamCalendar(ctx context.Context, before, after *models.Leave) {
+	if a.teamCalendar == nil {
+		return
+	}
+
+	var eventID string
+	var leaveID int64
+	if before != nil {
+		eventID, leaveID = before.CalendarEventID, before.ID
+	}
+	if after != nil {
+		leaveID = after.ID
+		if after.CalendarEventID != "" {
+			eventID = after.CalendarEventID
+		}
+	}
+
+	switch {
+	case onTeamCalendar(after) && eventID != "":
+		err := a.teamCalendar.Update(ctx, eventID, calendarEvent(after, a.regionFor(after.Username).Timezone))
+		if err == nil {
+			return
+		}
+		if !calendar.IsNotFound(err) {
+			logger.Error("Failed to update calendar event of leave %d: %v", leaveID, err)
+			return
+		}
+		// Someone deleted the event on the calendar; put it back
+		fallthrough
+
+	case onTeamCalendar(after):
+		id, err := a.teamCalendar.Insert(ctx, calendarEvent(after, a.regionFor(after.Username).Timezone))
+		if err != nil {
+			logger.Error("Failed to add leave %d to the calendar: %v", leaveID, err)
+			return
+		}
+		a.setCalendarEventID(after, id)
+
+	case eventID != "":
+		if err := a.teamCalendar.Delete(ctx, eventID); err != nil {
+			logger.Error("Failed to remove calendar event of leave %d: %v", leaveID, err)
+			return
+		}
+		if after != nil {
+			a.setCalendarEventID(after, "")
+		} else {
+			a.setCalendarEventID(before, "")
+		}
+	}
+}
+
+func (a *App) setCalendarEventID(leave *models.Leave, eventID string) {
+	if err := a.leaveRepo.SetCalendarEventID(leave.ID, eventID); err != nil {
+		logger.Error("Failed to store calendar event of leave %d: %v", leave.ID, err)
+		return
+	}
+	leave.CalendarEventID = eventID
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+	"slack-leaves-ai-agent/services"
+
+	"github.com/slack-go/slack"
+)
+
+// maxUpcomingLeaves is how many records `/leave list` shows.
+const maxUpcomingLeaves = 20
+
+const leaveCommandUsage = "Usage: `/leave list` (your upcoming leave, with IDs), `/leave cancel ID` or `/leave edit ID FIRST [LAST]` (dates as YYYY-MM-DD)"
+
+// cancelLeave soft-deletes a record on behalf of actor and gives back the
+// balance and desk it took. It fails with *ErrPeriodLocked when the record
+// falls in a locked payroll period.
+func (a *App) cancelLeave(ctx context.Context, actor string, leave *models.Leave, email string) error {
+	if err := a.checkPeriodLock(leave); err != nil {
+		return err
+	}
+	if err := a.leaveRepo.Cancel(leave.ID, actor); err != nil {
+		return err
+	}
+	a.audit(actor, "cancel", leave.ID, leave, nil)
+	a.recordLedgerChange(actor, "cancel", leave, nil)
+	a.syncTeamCalendar(ctx, leave, nil)
+	a.releaseDesk(ctx, leave, email)
+	return nil
+}
+
+// runLeaveCommand handles `/leave list`, `/leave cancel ID` and `/leave edit
+// ID FIRST [LAST]`, which only ever touch the caller's own records. ok is
+// false for anything else, which gets the help.
+func (a *App) runLeaveCommand(ctx context.Context, cmd slack.SlashCommand) (reply string, ok bool) {
+	args := strings.Fields(cmd.Text)
+	if len(args) == 0 || (args[0] != "list" && args[0] != "cancel" && args[0] != "edit") {
+		return "", false
+	}
+
+	user, err := a.slackClient.GetUserInfoContext(ctx, cmd.UserID)
+	if err != nil {
+		logger.Error("Error getting user info: %v", err)
+		return "❌ Failed to look you up, please try again.", true
+	}
+
+	if args[0] == "list" {
+		if len(args) != 1 {
+			return leaveCommandUsage, true
+		}
+		return a.upcomingLeavesText(user.Name), true
+	}
+
+	if (args[0] == "cancel" && len(args) != 2) || (args[0] == "edit" && len(args) != 3 && len(args) != 4) {
+		return leaveCommandUsage, true
+	}
+	id, err := strconv.ParseInt(strings.TrimPrefix(args[1], "#"), 10, 64)
+	if err != nil {
+		return fmt.Sprintf("❌ Invalid leave ID %q\n%s", args[1], leaveCommandUsage), true
+	}
+	// Someone else's record is reported as missing, same as a wrong ID
+	leave, err := a.leaveRepo.GetByID(id)
+	if err != nil || leave.Username != user.Name {
+		return fmt.Sprintf("❌ You have no leave #%d. `/leave list` shows your upcoming records.", id), true
+	}
+	if args[0] == "edit" {
+		return a.editOwnLeave(ctx, cmd.UserID, leave, args[2:], user.Profile.Email), true
+	}
+
+	if err := a.cancelLeave(ctx, "slack:"+cmd.UserID, leave, user.Profile.Email); err != nil {
+		var locked *ErrPeriodLocked
+		if errors.As(err, &locked) {
+			return fmt.Sprintf("🔒 Leave #%d can't be cancelled: %v. Please contact HR.", id, locked), true
+		}
+		logger.Error("Error cancelling leave %d: %v", id, err)
+		return "❌ Failed to cancel the leave, please try again.", true
+	}
+	return fmt.Sprintf("🗑️ Cancelled #%d, %s on %s.", id, leave.LeaveType, leave.StartTime.Format("Jan 2, 2006")), true
+}
+
+// editOwnLeave moves leave to the days in dates (first, and optionally last),
+// keeping its times of day, and returns the reply for `/leave edit`. The new
+// days get the checks a new request gets: the office's booking rules, the
+// policy engine and, when the type needs it, approval again.
+func (a *App) editOwnLeave(ctx context.Context, userID string, leave *models.Leave, dates []string, email string) string {
+	actor := "slack:" + userID
+	region := a.regionFor(leave.Username)
+	loc := region.Timezone
+	days := make([]time.Time, 0, 2)
+	for _, date := range dates {
+		day, err := time.ParseInLocation("2006-01-02", date, loc)
+		if err != nil {
+			return fmt.Sprintf("❌ Invalid date %q, use YYYY-MM-DD\n%s", date, leaveCommandUsage)
+		}
+		days = append(days, day)
+	}
+	first, last := days[0], days[len(days)-1]
+	if len(days) == 1 {
+		// Keep the record's length in days
+		last = first.AddDate(0, 0, int(leave.EndTime.Sub(leave.StartTime).Hours()/24))
+	}
+	before := *leave
+
+	// Stored times are office wall-clock times; keep the times of day
+	start, end := leave.StartTime, leave.EndTime
+	leave.StartTime = time.Date(first.Year(), first.Month(), first.Day(),
+		start.Hour(), start.Minute(), start.Second(), 0, start.Location())
+	leave.EndTime = time.Date(last.Year(), last.Month(), last.Day(),
+		end.Hour(), end.Minute(), end.Second(), 0, end.Location())
+	if !leave.EndTime.After(leave.StartTime) {
+		return fmt.Sprintf("❌ Leave #%d would end before it starts.", leave.ID)
+	}
+	if leave.StartTime.Equal(before.StartTime) && leave.EndTime.Equal(before.EndTime) {
+		return fmt.Sprintf("👍 Leave #%d is already on those days.", leave.ID)
+	}
+
+	maxAdvanceDays := region.ForLeaveType(leave.LeaveType).MaxAdvanceDays
+	if maxAdvanceDays <= 0 {
+		maxAdvanceDays = services.DefaultMaxAdvanceDays
+	}
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	if first.Before(today) {
+		return "❌ Leave can't be moved into the past."
+	}
+	if maxDate := today.AddDate(0, 0, maxAdvanceDays); last.After(maxDate) {
+		return fmt.Sprintf("❌ Leave can't be booked more than %d days in advance (maximum allowed date is %s).",
+			maxAdvanceDays, maxDate.Format("January 2, 2006"))
+	}
+	if reason := region.ForLeaveType(leave.LeaveType).Validate(first, last, now); reason != "" {
+		return "❌ " + reason
+	}
+	if reason := region.CheckSpan(leave.LeaveType, leave.StartTime, leave.EndTime); reason != "" {
+		return "❌ " + reason
+	}
+
+	if err := a.checkPeriodLock(&before, leave); err != nil {
+		return fmt.Sprintf("🔒 Leave #%d can't be changed: %v. Please contact HR.", leave.ID, err)
+	}
+	leave.Duration = models.FormatDuration(leave.StartTime, leave.EndTime)
+	if err := a.leaveRepo.Update(leave); err != nil {
+		logger.Error("Error editing leave %d: %v", leave.ID, err)
+		return "❌ Failed to change the leave, please try again."
+	}
+	a.audit(actor, "edit", leave.ID, before, leave)
+	a.recordLedgerChange(actor, "edit", &before, leave)
+	resubmitted := a.resubmitForApproval(ctx, actor, leave, userID)
+	a.syncTeamCalendar(ctx, &before, leave)
+	if !leave.StartTime.Equal(before.StartTime) {
+		a.releaseDesk(ctx, &before, email)
+		a.syncDeskBooking(ctx, leave, email)
+	}
+	violations := a.evaluateLeave(leave)
+	a.escalateToHR(ctx, leave, violations)
+
+	reply := fmt.Sprintf("✏️ Moved #%d, %s, to %s.", leave.ID, leave.LeaveType, formatLeaveSpan(leave))
+	if resubmitted {
+		reply += " ⏳ It's waiting for approval again."
+	}
+	if warning := policyWarningText(violations); warning != "" {
+		reply += "\n" + warning
+	}
+	return reply
+}
+
+// upcomingLeavesText lists username's records from today on, with the IDs
+// `/leave cancel` and `/leave edit` take.
+func (a *App) upcomingLeavesText(username string) string {
+	loc := a.regionFor(username).Timezone
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	leaves, err := a.leaveRepo.ListStartingFrom(username, today)
+	if err != nil {
+		logger.Error("Error loading upcoming leaves of %s: %v", username, err)
+		return "❌ Failed to load your leave."
+	}
+	if len(leaves) == 0 {
+		return "You have no upcoming leave."
+	}
+
+	lines := []string{"🗓️ *Your upcoming leave*"}
+	for i, leave := range leaves {
+		if i == maxUpcomingLeaves {
+			lines = append(lines, fmt.Sprintf("…and %d more", len(leaves)-i))
+			break
+		}
+		line := fmt.Sprintf("• #%d *%s* %s to %s", leave.ID, leave.LeaveType,
+			leave.StartTime.Format("Mon Jan 2, 3:04 PM"), leave.EndTime.Format("Mon Jan 2, 3:04 PM"))
+		if leave.Status == models.LeaveStatusPending {
+			line += " ⏳"
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n") + "\nCancel one with `/leave cancel ID`, or move it with `/leave edit ID FIRST [LAST]`."
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"slack-leaves-ai-agent/db/migrations"
+	"slack-leaves-ai-agent/models"
+	"slack-leaves-ai-agent/repository"
+	"slack-leaves-ai-agent/services"
+
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+)
+
+// historicalMessage is one line of the backfill input file (JSON lines), e.g.
+// an export of a Slack channel's history.
+type historicalMessage struct {
+	Username string    `json:"username"`
+	Text     string    `json:"text"`
+	PostedAt time.Time `json:"posted_at"`
+}
+
+func main() {
+	file := flag.String("file", "", "JSON lines file of {username, text, posted_at} messages")
+	batchSize := flag.Int("batch-size", 20, "messages per OpenAI call")
+	dryRun := flag.Bool("dry-run", false, "parse and print results without saving")
+	flag.Parse()
+
+	if *file == "" {
+		log.Fatal("-file is required")
+	}
+	if *batchSize <= 0 {
+		log.Fatal("-batch-size must be at least 1")
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	messages, err := readMessages(*file)
+	if err != nil {
+		log.Fatalf("Error reading %s: %v", *file, err)
+	}
+	log.Printf("Loaded %d messages", len(messages))
+
+	schema, err := migrations.SchemaFor(os.Getenv("APP_ENV"), os.Getenv("DB_SCHEMA"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	connStr := fmt.Sprintf(
+		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		os.Getenv("DB_HOST"),
+		os.Getenv("DB_PORT"),
+		os.Getenv("DB_USER"),
+		os.Getenv("DB_PASSWORD"),
+		os.Getenv("DB_NAME"),
+	) + migrations.SearchPath(schema)
+
+	db, err := sql.Open("postgres", connStr)
+	if err != nil {
+		log.Fatalf("Error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	leaveRepo := repository.NewLeaveRepository(db)
+	locationRepo := repository.NewLocationRepository(db)
+	openAI := services.NewOpenAIService(os.Getenv("OPENAI_API_KEY"), 2*time.Minute)
+	ctx := context.Background()
+
+	// Resolve each author's office timezone once
+	timezones := make(map[string]*time.Location)
+	timezoneFor := func(username string) *time.Location {
+		if tz, ok := timezones[username]; ok {
+			return tz
+		}
+		var tz *time.Location
+		location, err := locationRepo.GetForUser(username)
+		if err != nil {
+			log.Printf("Error loading location for %s: %v", username, err)
+		} else if location != nil {
+			if tz, err = time.LoadLocation(location.Timezone); err != nil {
+				log.Printf("Invalid timezone %q for %s: %v", location.Timezone, username, err)
+				tz = nil
+			}
+		}
+		timezones[username] = tz
+		return tz
+	}
+
+	var saved, skipped, failed int
+	for start := 0; start < len(messages); start += *batchSize {
+		end := start + *batchSize
+		if end > len(messages) {
+			end = len(messages)
+		}
+
+		batch := make([]services.BatchMessage, 0, end-start)
+		for i := start; i < end; i++ {
+			batch = append(batch, services.BatchMessage{
+				ID:       strconv.Itoa(i),
+				Text:     messages[i].Text,
+				PostedAt: messages[i].PostedAt,
+				Location: timezoneFor(messages[i].Username),
+			})
+		}
+
+		results, err := openAI.ParseLeaveRequestBatch(ctx, batch)
+		if err != nil {
+			log.Printf("Batch %d-%d failed: %v", start, end-1, err)
+			failed += len(batch)
+			continue
+		}
+
+		// Give anything the model dropped from the batch a second chance on its own
+		for _, item := range batch {
+			if _, ok := results[item.ID]; ok {
+				continue
+			}
+			retry, err := openAI.ParseLeaveRequestBatch(ctx, []services.BatchMessage{item})
+			if err != nil {
+				log.Printf("Retry of message %s failed: %v", item.ID, err)
+				continue
+			}
+			if resp, ok := retry[item.ID]; ok {
+				results[item.ID] = resp
+			}
+		}
+
+		for _, item := range batch {
+			i, _ := strconv.Atoi(item.ID)
+			msg := messages[i]
+
+			resp, ok := results[item.ID]
+			if !ok {
+				log.Printf("No result for message %d from %s", i, msg.Username)
+				failed++
+				continue
+			}
+			if !resp.IsValid {
+				skipped++
+				continue
+			}
+
+			leave := &models.Leave{
+				Username:     msg.Username,
+				OriginalText: msg.Text,
+				StartTime:    resp.StartTime,
+				EndTime:      resp.EndTime,
+				Duration:     resp.Duration,
+				Reason:       resp.Reason,
+				LeaveType:    resp.LeaveType,
+			}
+
+			if *dryRun {
+				log.Printf("[dry-run] %s %s %s → %s", leave.Username, leave.LeaveType,
+					leave.StartTime.Format(time.RFC3339), leave.EndTime.Format(time.RFC3339))
+				saved++
+				continue
+			}
+
+			if err := leaveRepo.Create(leave); err != nil {
+				log.Printf("Error saving message %d: %v", i, err)
+				failed++
+				continue
+			}
+			saved++
+		}
+
+		log.Printf("Processed %d/%d messages", end, len(messages))
+	}
+
+	log.Printf("Backfill completed: %d saved, %d not attendance related, %d failed", saved, skipped, failed)
+}
+
+func readMessages(path string) ([]historicalMessage, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var messages []historicalMessage
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var msg historicalMessage
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		messages = append(messages, msg)
+	}
+
+	return messages, scanner.Err()
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"slack-leaves-ai-agent/db/migrations"
+	"slack-leaves-ai-agent/services"
+
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+	"github.com/sashabaranov/go-openai"
+	"github.com/slack-go/slack"
+)
+
+// requiredScopes are the bot token scopes the app calls need.
+var requiredScopes = []string{
+	"channels:history", // message events in public channels
+	"chat:write",
+	"commands",
+	"files:write", // CSV exports
+	"im:write",    // DMs to managers and employees
+	"links:write", // viewer link unfurls
+	"users:read",  // names and presence
+	"users:read.email",
+}
+
+// checklist prints one line per check and remembers whether any failed.
+type checklist struct {
+	failed bool
+}
+
+func (c *checklist) pass(name, detail string) {
+	fmt.Printf("✅ %s: %s\n", name, detail)
+}
+
+func (c *checklist) warn(name, detail string) {
+	fmt.Printf("⚠️  %s: %s\n", name, detail)
+}
+
+func (c *checklist) fail(name, detail string) {
+	c.failed = true
+	fmt.Printf("❌ %s: %s\n", name, detail)
+}
+
+// doctor checks the deployment's configuration against the services it
+// talks to, so a bad token or a missed migration shows up here rather than
+// as a runtime error.
+func main() {
+	if err := godotenv.Load(); err != nil {
+		fmt.Println("⚠️  .env: not found, using the environment")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	c := &checklist{}
+	checkEnv(c)
+	checkDatabase(ctx, c)
+	botID := checkSlack(ctx, c)
+	if botID != "" {
+		checkChannels(ctx, c, botID)
+	}
+	checkOpenAI(ctx, c)
+
+	if c.failed {
+		fmt.Println("\nSome checks failed.")
+		os.Exit(1)
+	}
+	fmt.Println("\nAll checks passed.")
+}
+
+func checkEnv(c *checklist) {
+	// HTTP mode is signed with the signing secret instead of using an app token
+	slackKey := "SLACK_APP_TOKEN"
+	if strings.EqualFold(os.Getenv("SLACK_MODE"), "http") {
+		slackKey = "SLACK_SIGNING_SECRET"
+	}
+	var missing []string
+	for _, key := range []string{"SLACK_BOT_TOKEN", slackKey, "OPENAI_API_KEY", "DB_HOST", "DB_PORT", "DB_USER", "DB_NAME"} {
+		if os.Getenv(key) == "" {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		c.fail("environment", "missing "+strings.Join(missing, ", "))
+		return
+	}
+	c.pass("environment", "required variables set")
+
+	if tz := os.Getenv("WORKSPACE_TIMEZONE"); tz != "" {
+		if _, err := time.LoadLocation(tz); err != nil {
+			c.fail("environment", fmt.Sprintf("WORKSPACE_TIMEZONE %q: %v", tz, err))
+		}
+	}
+}
+
+func checkDatabase(ctx context.Context, c *checklist) {
+	schema, err := migrations.SchemaFor(os.Getenv("APP_ENV"), os.Getenv("DB_SCHEMA"))
+	if err != nil {
+		c.fail("database", err.Error())
+		return
+	}
+	connStr := fmt.Sprintf(
+		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		os.Getenv("DB_HOST"),
+		os.Getenv("DB_PORT"),
+		os.Getenv("DB_USER"),
+		os.Getenv("DB_PASSWORD"),
+		os.Getenv("DB_NAME"),
+	) + migrations.SearchPath(schema)
+
+	db, err := sql.Open("postgres", connStr)
+	if err != nil {
+		c.fail("database", err.Error())
+		return
+	}
+	defer db.Close()
+	if err := db.PingContext(ctx); err != nil {
+		c.fail("database", "can't connect: "+err.Error())
+		return
+	}
+	c.pass("database", "connected to "+os.Getenv("DB_NAME"))
+
+	version, err := migrations.CurrentVersion(db)
+	switch {
+	case err != nil:
+		c.fail("schema", err.Error())
+	case version == 0:
+		c.fail("schema", "no schema version recorded; run cmd/migrate")
+	case version < migrations.Version():
+		c.fail("schema", fmt.Sprintf("at version %d, this build needs %d; run cmd/migrate", version, migrations.Version()))
+	case version > migrations.Version():
+		c.warn("schema", fmt.Sprintf("at version %d, newer than this build (%d)", version, migrations.Version()))
+	default:
+		c.pass("schema", fmt.Sprintf("at version %d", version))
+	}
+}
+
+// checkSlack verifies both tokens and the bot token's scopes, returning the
+// bot's user ID when the bot token works.
+func checkSlack(ctx context.Context, c *checklist) string {
+	botToken := os.Getenv("SLACK_BOT_TOKEN")
+	if botToken == "" {
+		return ""
+	}
+	client := slack.New(botToken, slack.OptionAppLevelToken(os.Getenv("SLACK_APP_TOKEN")))
+
+	auth, err := client.AuthTestContext(ctx)
+	if err != nil {
+		c.fail("slack bot token", err.Error())
+		return ""
+	}
+	c.pass("slack bot token", fmt.Sprintf("%s in %s", auth.User, auth.Team))
+
+	// auth.test only reports the token's scopes in a response header
+	scopes, err := services.SlackScopes(ctx, botToken)
+	if err != nil {
+		c.fail("slack scopes", err.Error())
+	} else {
+		var missing []string
+		for _, scope := range requiredScopes {
+			if !scopes[scope] {
+				missing = append(missing, scope)
+			}
+		}
+		if len(missing) > 0 {
+			c.fail("slack scopes", "missing "+strings.Join(missing, ", "))
+		} else {
+			c.pass("slack scopes", fmt.Sprintf("all %d required scopes granted", len(requiredScopes)))
+		}
+	}
+
+	if os.Getenv("SLACK_APP_TOKEN") != "" && !strings.EqualFold(os.Getenv("SLACK_MODE"), "http") {
+		// Asks for a socket mode URL without connecting to it
+		if _, _, err := client.StartSocketModeContext(ctx); err != nil {
+			c.fail("slack app token", err.Error())
+		} else {
+			c.pass("slack app token", "socket mode available")
+		}
+	}
+	return auth.UserID
+}
+
+// checkChannels makes sure the bot is in every channel it's configured to
+// read or post in.
+func checkChannels(ctx context.Context, c *checklist, botID string) {
+	channels := make(map[string]string)
+	if id := os.Getenv("ADMIN_CHANNEL_ID"); id != "" {
+		channels[id] = "ADMIN_CHANNEL_ID"
+	}
+	for _, id := range splitList(os.Getenv("NOTIFY_CHANNELS")) {
+		channels[id] = "NOTIFY_CHANNELS"
+	}
+	for _, entry := range splitList(os.Getenv("CHANNEL_TRIGGERS")) {
+		if id, _, ok := strings.Cut(entry, ":"); ok {
+			channels[strings.TrimSpace(id)] = "CHANNEL_TRIGGERS"
+		}
+	}
+	if len(channels) == 0 {
+		c.warn("channels", "none configured")
+		return
+	}
+
+	ids := make([]string, 0, len(channels))
+	for id := range channels {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	client := slack.New(os.Getenv("SLACK_BOT_TOKEN"))
+	for _, id := range ids {
+		name := fmt.Sprintf("channel %s (%s)", id, channels[id])
+		channel, err := client.GetConversationInfoContext(ctx, &slack.GetConversationInfoInput{ChannelID: id})
+		switch {
+		case err != nil:
+			c.fail(name, err.Error())
+		case !channel.IsMember:
+			c.fail(name, fmt.Sprintf("#%s: the bot isn't a member; invite <@%s>", channel.Name, botID))
+		default:
+			c.pass(name, "#"+channel.Name)
+		}
+	}
+}
+
+func checkOpenAI(ctx context.Context, c *checklist) {
+	key := os.Getenv("OPENAI_API_KEY")
+	if key == "" {
+		return
+	}
+	client := openai.NewClient(key)
+	for _, model := range []string{"gpt-4o-mini", services.EmbeddingModel} {
+		if _, err := client.GetModel(ctx, model); err != nil {
+			c.fail("openai", fmt.Sprintf("%s: %v", model, err))
+			return
+		}
+	}
+	c.pass("openai", "key works and models are available")
+}
+
+// splitList parses a comma-separated env value, dropping empty entries.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+
+	"github.com/joho/godotenv"
+)
+
+const usage = `Usage: latebotctl COMMAND [ARGS...] [--override]
+
+  leaves list USER                         a user's records
+  leaves get ID                            one record, as JSON
+  leaves create USER TYPE START END [REASON...]
+  leaves cancel ID                         cancel a record, as its owner would
+  leaves delete ID                         delete a record outright
+  reports encashment|office|compliance|usage|lop [KEY=VALUE...]
+                                           e.g. year=2024, weeks=4, month=2024-03
+  holiday list|add|remove ...              as /admin-leave holiday
+  config export                            print the configuration as YAML
+  config import FILE                       apply a snapshot from config export
+  balance ...                              as /admin-leave balance
+  run ARGS...                              any /admin-leave subcommand
+
+Times are YYYY-MM-DD or YYYY-MM-DDTHH:MM in the user's office timezone.
+--override changes records in a period closed for payroll.
+
+Environment (or .env):
+  LATEBOT_URL        the bot's HTTP address (default http://localhost:$PORT)
+  ADMIN_API_KEY      the admin API key
+  LATEBOT_ACTOR      who to record in the audit log (default $USER)`
+
+// client calls the bot's admin API.
+type client struct {
+	baseURL  string
+	key      string
+	actor    string
+	override bool
+	http     *http.Client
+}
+
+// latebotctl runs admin operations against the bot's HTTP API, for admins
+// who'd rather use a terminal or need to script corrections.
+func main() {
+	godotenv.Load()
+
+	args := os.Args[1:]
+	c := &client{http: &http.Client{Timeout: time.Minute}}
+	filtered := args[:0:0]
+	for _, arg := range args {
+		if arg == "--override" {
+			c.override = true
+			continue
+		}
+		filtered = append(filtered, arg)
+	}
+	args = filtered
+
+	c.baseURL = strings.TrimSuffix(os.Getenv("LATEBOT_URL"), "/")
+	if c.baseURL == "" {
+		port := os.Getenv("PORT")
+		if port == "" {
+			port = "8080"
+		}
+		c.baseURL = "http://localhost:" + port
+	}
+	c.key = os.Getenv("ADMIN_API_KEY")
+	c.actor = os.Getenv("LATEBOT_ACTOR")
+	if c.actor == "" {
+		c.actor = os.Getenv("USER")
+	}
+
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		fmt.Println(usage)
+		return
+	}
+	if c.key == "" {
+		fail(fmt.Errorf("ADMIN_API_KEY is not set"))
+	}
+
+	if err := run(c, args); err != nil {
+		fail(err)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "❌", err)
+	os.Exit(1)
+}
+
+func run(c *client, args []string) error {
+	switch args[0] {
+	case "leaves":
+		if len(args) < 2 {
+			return fmt.Errorf("missing leaves subcommand\n%s", usage)
+		}
+		return runLeaves(c, args[1], args[2:])
+
+	case "reports":
+		if len(args) < 2 {
+			return fmt.Errorf("missing report name\n%s", usage)
+		}
+		query := url.Values{}
+		for _, param := range args[2:] {
+			key, value, ok := strings.Cut(param, "=")
+			if !ok {
+				return fmt.Errorf("invalid report parameter %q, want KEY=VALUE", param)
+			}
+			query.Set(key, value)
+		}
+		body, err := c.do(http.MethodGet, "/api/admin/reports/"+args[1], query, nil)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(body)
+		return err
+
+	case "config":
+		return runConfig(c, args[1:])
+
+	case "holiday", "balance":
+		return c.command(args)
+
+	case "run":
+		return c.command(args[1:])
+	}
+	return fmt.Errorf("unknown command %q\n%s", args[0], usage)
+}
+
+func runLeaves(c *client, sub string, args []string) error {
+	switch sub {
+	case "list":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: leaves list USER")
+		}
+		body, err := c.do(http.MethodGet, "/api/admin/leaves", url.Values{"username": {strings.TrimPrefix(args[0], "@")}}, nil)
+		if err != nil {
+			return err
+		}
+		var leaves []models.Leave
+		if err := json.Unmarshal(body, &leaves); err != nil {
+			return fmt.Errorf("invalid response: %v", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tTYPE\tSTART\tEND\tSTATUS\tREASON")
+		for _, leave := range leaves {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", leave.ID, leave.LeaveType,
+				leave.StartTime.Format("2006-01-02 15:04"), leave.EndTime.Format("2006-01-02 15:04"), leave.Status, leave.Reason)
+		}
+		return w.Flush()
+
+	case "get":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: leaves get ID")
+		}
+		body, err := c.do(http.MethodGet, "/api/admin/leaves/"+args[0], nil, nil)
+		if err != nil {
+			return err
+		}
+		var pretty bytes.Buffer
+		if err := json.Indent(&pretty, body, "", "  "); err != nil {
+			return fmt.Errorf("invalid response: %v", err)
+		}
+		fmt.Println(pretty.String())
+		return nil
+
+	case "create":
+		if len(args) < 4 {
+			return fmt.Errorf("usage: leaves create USER TYPE START END [REASON...]")
+		}
+		// The bot resolves the times in the user's office timezone
+		return c.command(append([]string{"create", "@" + strings.TrimPrefix(args[0], "@")}, args[1:]...))
+
+	case "cancel":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: leaves cancel ID")
+		}
+		if _, err := c.do(http.MethodPost, "/api/admin/leaves/"+args[0]+"/cancel", nil, nil); err != nil {
+			return err
+		}
+		fmt.Printf("🗑️ Cancelled #%s\n", args[0])
+		return nil
+
+	case "delete":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: leaves delete ID")
+		}
+		if _, err := c.do(http.MethodDelete, "/api/admin/leaves/"+args[0], nil, nil); err != nil {
+			return err
+		}
+		fmt.Printf("🗑️ Deleted #%s\n", args[0])
+		return nil
+	}
+	return fmt.Errorf("unknown leaves subcommand %q\n%s", sub, usage)
+}
+
+// runConfig exports the configuration to stdout or imports a snapshot, for
+// promoting staging's configuration to production or restoring it.
+func runConfig(c *client, args []string) error {
+	switch {
+	case len(args) == 1 && args[0] == "export":
+		body, err := c.do(http.MethodGet, "/api/admin/config", nil, nil)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(body)
+		return err
+
+	case len(args) == 2 && args[0] == "import":
+		snapshot, err := os.ReadFile(args[1])
+		if err != nil {
+			return err
+		}
+		body, err := c.do(http.MethodPost, "/api/admin/config", nil, snapshot)
+		if err != nil {
+			return err
+		}
+		var result map[string]int
+		if err := json.Unmarshal(body, &result); err != nil {
+			return fmt.Errorf("invalid response: %v", err)
+		}
+		fmt.Printf("✅ Imported %d offices, %d holidays, %d settings, %d feature flags, %d coverage rules, %d coverage members and %d digests\n",
+			result["offices"], result["holidays"], result["settings"], result["feature_flags"], result["coverage_rules"], result["coverage_members"], result["digests"])
+		return nil
+	}
+	return fmt.Errorf("usage: config export | config import FILE")
+}
+
+// command runs an /admin-leave subcommand and prints its reply.
+func (c *client) command(args []string) error {
+	if c.override {
+		args = append(args, "--override")
+	}
+	payload, err := json.Marshal(map[string][]string{"args": args})
+	if err != nil {
+		return err
+	}
+	body, err := c.do(http.MethodPost, "/api/admin/command", nil, payload)
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		Output string `json:"output"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return fmt.Errorf("invalid response: %v", err)
+	}
+	fmt.Println(resp.Output)
+	return nil
+}
+
+// do sends an authenticated request and returns the body of a successful
+// response. Error responses are returned as errors with the server's
+// message.
+func (c *client) do(method, path string, query url.Values, payload []byte) ([]byte, error) {
+	if c.override {
+		if query == nil {
+			query = url.Values{}
+		}
+		query.Set("override", "true")
+	}
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequest(method, target, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Admin-Key", c.key)
+	if c.actor != "" {
+		req.Header.Set("X-Admin-Actor", c.actor)
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
@@ -2,19 +2,32 @@ package main
 
 import (
 	"database/sql"
+	"flag"
 	"fmt"
 	"log"
 	"os"
 
+	"slack-leaves-ai-agent/db/migrations"
+
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
 
 func main() {
+	recreate := flag.Bool("recreate-leaves", false, "drop and recreate the leaves table, losing every record in it")
+	flag.Parse()
+
 	if err := godotenv.Load(); err != nil {
 		log.Fatal("Error loading .env file")
 	}
 
+	// A staging instance has its own schema, which must never be confused
+	// with production's, least of all with -recreate-leaves
+	schema, err := migrations.SchemaFor(os.Getenv("APP_ENV"), os.Getenv("DB_SCHEMA"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	connStr := fmt.Sprintf(
 		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
 		os.Getenv("DB_HOST"),
@@ -22,7 +35,7 @@ func main() {
 		os.Getenv("DB_USER"),
 		os.Getenv("DB_PASSWORD"),
 		os.Getenv("DB_NAME"),
-	)
+	) + migrations.SearchPath(schema)
 
 	db, err := sql.Open("postgres", connStr)
 	if err != nil {
@@ -30,26 +43,17 @@ func main() {
 	}
 	defer db.Close()
 
-	query := `
-		DROP TABLE IF EXISTS leaves;
-		CREATE TABLE leaves (
-			id SERIAL PRIMARY KEY,
-			username VARCHAR(255) NOT NULL,
-			original_text TEXT NOT NULL,
-			start_time TIMESTAMP NOT NULL,
-			end_time TIMESTAMP NOT NULL,
-			duration VARCHAR(255) NOT NULL,
-			reason TEXT NOT NULL,
-			leave_type VARCHAR(50) NOT NULL,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
-		);
-	`
-
-	_, err = db.Exec(query)
-	if err != nil {
-		log.Fatalf("Error creating table: %v", err)
+	if err := migrations.CreateSchema(db, schema); err != nil {
+		log.Fatalf("Error creating schema %s: %v", schema, err)
+	}
+	migrate := migrations.Upgrade
+	if *recreate {
+		log.Printf("Recreating the leaves table in schema %s: every record in it is lost", schema)
+		migrate = migrations.Run
+	}
+	if err := migrate(db); err != nil {
+		log.Fatalf("Migration failed: %v", err)
 	}
 
 	log.Println("Migration completed successfully!")
-} 
\ No newline at end of file
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"slack-leaves-ai-agent/repository"
+	"slack-leaves-ai-agent/services"
+
+	"github.com/slack-go/slack"
+)
+
+// compareSubject is one side of a comparison: a person, or a team made of
+// the active employees of a department.
+type compareSubject struct {
+	Label   string
+	Members []string
+	Team    bool
+}
+
+type compareRow struct {
+	Label string
+	A, B  float64
+}
+
+// comparisonBlocks answers "how does X compare to Y" with the two sides'
+// stats over the query's period next to each other.
+func (a *App) comparisonBlocks(queryResp *services.QueryResponse) ([]slack.Block, error) {
+	if !a.featureEnabled(flagAnalytics) {
+		return nil, errAnalyticsOff
+	}
+	if len(queryResp.Subjects) != 2 {
+		return nil, fmt.Errorf("name two people or teams to compare, e.g. `how does priya compare to rahul this month?`")
+	}
+	startDate, endDate, err := queryPeriod(queryResp, a.today())
+	if err != nil {
+		return nil, err
+	}
+
+	subjects := make([]*compareSubject, 0, 2)
+	usage := make([][]repository.TypeUsage, 0, 2)
+	for _, name := range queryResp.Subjects {
+		subject, err := a.resolveCompareSubject(name)
+		if err != nil {
+			return nil, err
+		}
+		u, err := a.leaveRepo.GetGroupUsageByType(subject.Members, startDate, endDate.AddDate(0, 0, 1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get stats for %s: %v", subject.Label, err)
+		}
+		subjects = append(subjects, subject)
+		usage = append(usage, u)
+	}
+
+	rows := compareRows(subjects[0], subjects[1], usage[0], usage[1])
+	title := fmt.Sprintf("⚖️ *%s vs %s, %s to %s*", subjects[0].Label, subjects[1].Label,
+		startDate.Format("Jan 2, 2006"), endDate.Format("Jan 2, 2006"))
+	blocks := []slack.Block{
+		slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn",
+			title+"\n"+compareTable(subjects[0].Label, subjects[1].Label, rows), false, false), nil, nil),
+	}
+	return withDetails(blocks, repository.LeaveFilter{
+		Start:     startDate,
+		End:       endDate.AddDate(0, 0, 1),
+		Usernames: append(append([]string{}, subjects[0].Members...), subjects[1].Members...),
+	}), nil
+}
+
+// resolveCompareSubject reads a name as a department if one matches,
+// ignoring case and a trailing "team", and as a username otherwise.
+func (a *App) resolveCompareSubject(name string) (*compareSubject, error) {
+	name = strings.TrimSpace(name)
+	department := strings.TrimSpace(strings.TrimSuffix(strings.ToLower(name), " team"))
+
+	employees, err := a.employeeRepo.List(true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load employees: %v", err)
+	}
+	team := &compareSubject{Team: true}
+	for _, employee := range employees {
+		if employee.Department != "" && strings.ToLower(employee.Department) == department {
+			team.Label = employee.Department
+			team.Members = append(team.Members, employee.Username)
+		}
+	}
+	if len(team.Members) > 0 {
+		return team, nil
+	}
+
+	username, err := a.resolveUserArg(name)
+	if err != nil {
+		return nil, err
+	}
+	return &compareSubject{Label: username, Members: []string{username}}, nil
+}
+
+// compareRows lays out the two sides' usage. Office days aren't leave, so
+// they only show as their own row.
+func compareRows(a, b *compareSubject, usageA, usageB []repository.TypeUsage) []compareRow {
+	sum := func(usage []repository.TypeUsage, records bool, leaveTypes ...string) float64 {
+		total := 0.0
+		for _, u := range usage {
+			for _, leaveType := range leaveTypes {
+				if u.LeaveType != leaveType {
+					continue
+				}
+				if records {
+					total += float64(u.Records)
+				} else {
+					total += u.Days
+				}
+			}
+		}
+		return total
+	}
+	leaveTypes := []string{"FULL_DAY", "HALF_DAY", "PARENTAL", "LOSS_OF_PAY", "WFH", "LATE_ARRIVAL", "EARLY_DEPARTURE", "APPOINTMENT"}
+
+	rows := []compareRow{
+		{"Records", sum(usageA, true, leaveTypes...), sum(usageB, true, leaveTypes...)},
+		{"Days off", sum(usageA, false, "FULL_DAY", "HALF_DAY", "PARENTAL", "LOSS_OF_PAY"), sum(usageB, false, "FULL_DAY", "HALF_DAY", "PARENTAL", "LOSS_OF_PAY")},
+	}
+	if a.Team || b.Team {
+		rows = append([]compareRow{{"People", float64(len(a.Members)), float64(len(b.Members))}}, rows...)
+		rows = append(rows, compareRow{"Days off/person",
+			rows[2].A / float64(len(a.Members)), rows[2].B / float64(len(b.Members))})
+	}
+	return append(rows,
+		compareRow{"WFH days", sum(usageA, false, "WFH"), sum(usageB, false, "WFH")},
+		compareRow{"Office days", sum(usageA, false, "IN_OFFICE"), sum(usageB, false, "IN_OFFICE")},
+		compareRow{"Late arrivals", sum(usageA, true, "LATE_ARRIVAL"), sum(usageB, true, "LATE_ARRIVAL")},
+		compareRow{"Early departures", sum(usageA, true, "EARLY_DEPARTURE"), sum(usageB, true, "EARLY_DEPARTURE")},
+		compareRow{"Appointments", sum(usageA, true, "APPOINTMENT"), sum(usageB, true, "APPOINTMENT")},
+	)
+}
+
+// compareTable renders the rows as a fixed-width table, with the first side
+// minus the second as the delta.
+func compareTable(labelA, labelB string, rows []compareRow) string {
+	width := func(label string) int { return max(len(label), 6) }
+
+	var b strings.Builder
+	b.WriteString("```\n")
+	fmt.Fprintf(&b, "%-16s %*s %*s %7s\n", "", width(labelA), labelA, width(labelB), labelB, "Δ")
+	for _, row := range rows {
+		fmt.Fprintf(&b, "%-16s %*s %*s %7s\n", row.Label,
+			width(labelA), formatCompareValue(row.A), width(labelB), formatCompareValue(row.B),
+			formatCompareDelta(row.A-row.B))
+	}
+	b.WriteString("```")
+	return b.String()
+}
+
+func formatCompareValue(v float64) string {
+	if v == float64(int(v)) {
+		return fmt.Sprintf("%d", int(v))
+	}
+	return fmt.Sprintf("%.1f", v)
+}
+
+func formatCompareDelta(v float64) string {
+	switch {
+	case v > 0:
+		return "+" + formatCompareValue(v)
+	case v < 0:
+		return "-" + formatCompareValue(-v)
+	}
+	return "0"
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+
+	"github.com/slack-go/slack"
+)
+
+// complianceTrendMonths is how far back a non-compliance streak is traced.
+const complianceTrendMonths = 3
+
+// userAttendance holds the days a user logged, keyed "2006-01-02".
+type userAttendance struct {
+	office map[string]bool
+	wfh    map[string]bool
+	off    map[string]bool
+}
+
+func newUserAttendance() *userAttendance {
+	return &userAttendance{
+		office: make(map[string]bool),
+		wfh:    make(map[string]bool),
+		off:    make(map[string]bool),
+	}
+}
+
+// monthWeeks returns the Mondays of the weeks that belong to month. A week
+// belongs to the month its Monday falls in, so every week is counted once.
+func monthWeeks(month time.Time) []time.Time {
+	day := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for day.Weekday() != time.Monday {
+		day = day.AddDate(0, 0, 1)
+	}
+
+	var weeks []time.Time
+	for ; day.Month() == month.Month(); day = day.AddDate(0, 0, 7) {
+		weeks = append(weeks, day)
+	}
+	return weeks
+}
+
+// monthCompliance checks each user's office days in month against their
+// hybrid policy. Holidays and full days of leave reduce the days required
+// that week. A user is non-compliant when they fell short in more than half
+// of the month's weeks. Users who logged no office or WFH days at all are
+// left out, since there's nothing to judge them on, as are departed users.
+func (a *App) monthCompliance(month time.Time) (map[string]*models.ComplianceLine, error) {
+	weeks := monthWeeks(month)
+	rangeStart := weeks[0]
+	rangeEnd := weeks[len(weeks)-1].AddDate(0, 0, 7)
+
+	// Stored times are office wall-clock times, so dates are compared as-is
+	leaves, err := a.leaveRepo.ListBetween(rangeStart, rangeEnd)
+	if err != nil {
+		return nil, fmt.Errorf("error loading attendance: %v", err)
+	}
+
+	attendance := make(map[string]*userAttendance)
+	for _, leave := range leaves {
+		days := attendance[leave.Username]
+		if days == nil {
+			days = newUserAttendance()
+			attendance[leave.Username] = days
+		}
+		var covered map[string]bool
+		switch {
+		case leave.LeaveType == "IN_OFFICE":
+			covered = days.office
+		case leave.LeaveType == "WFH":
+			covered = days.wfh
+		case isDayOff(leave.LeaveType):
+			covered = days.off
+		default:
+			continue
+		}
+		// Every day of a multi-day record counts; weekends and holidays are
+		// skipped when the weeks are added up
+		last := leave.EndTime.Format("2006-01-02")
+		for d := leave.StartTime; d.Format("2006-01-02") <= last; d = d.AddDate(0, 0, 1) {
+			covered[d.Format("2006-01-02")] = true
+		}
+	}
+
+	inactive, err := a.employeeRepo.GetInactive()
+	if err != nil {
+		return nil, fmt.Errorf("error loading departed employees: %v", err)
+	}
+
+	usernames := make([]string, 0, len(attendance))
+	for username, days := range attendance {
+		if inactive[username] {
+			continue
+		}
+		if len(days.office) > 0 || len(days.wfh) > 0 {
+			usernames = append(usernames, username)
+		}
+	}
+
+	employmentTypes, err := a.employeeRepo.GetEmploymentTypes()
+	if err != nil {
+		return nil, fmt.Errorf("error loading employment types: %v", err)
+	}
+	managers, err := a.employeeRepo.GetManagers()
+	if err != nil {
+		return nil, fmt.Errorf("error loading managers: %v", err)
+	}
+	locations, err := a.locationsByUser(usernames)
+	if err != nil {
+		return nil, fmt.Errorf("error loading locations: %v", err)
+	}
+
+	holidays := make(map[string]map[string]bool)
+	holidaysFor := func(location *models.Location) (map[string]bool, error) {
+		if location == nil {
+			return nil, nil
+		}
+		if days, ok := holidays[location.Code]; ok {
+			return days, nil
+		}
+		list, err := a.locationRepo.ListHolidays(location.Code, rangeStart, rangeEnd)
+		if err != nil {
+			return nil, err
+		}
+		days := make(map[string]bool, len(list))
+		for _, holiday := range list {
+			days[holiday.Date.Format("2006-01-02")] = true
+		}
+		holidays[location.Code] = days
+		return days, nil
+	}
+
+	lines := make(map[string]*models.ComplianceLine, len(usernames))
+	for _, username := range usernames {
+		employmentType := employmentTypes[username]
+		if employmentType == "" {
+			employmentType = models.EmploymentEmployee
+		}
+		location := locations[username]
+		policy := a.policyFor(employmentType, location)
+		if policy.MinOfficeDaysPerWeek <= 0 {
+			continue
+		}
+
+		closed, err := holidaysFor(location)
+		if err != nil {
+			return nil, fmt.Errorf("error loading holidays: %v", err)
+		}
+
+		days := attendance[username]
+		line := &models.ComplianceLine{
+			Username:        username,
+			ManagerID:       managers[username],
+			RequiredPerWeek: policy.MinOfficeDaysPerWeek,
+		}
+		for _, monday := range weeks {
+			working, office := 0, 0
+			for i := 0; i < 5; i++ {
+				key := monday.AddDate(0, 0, i).Format("2006-01-02")
+				if closed[key] || days.off[key] {
+					continue
+				}
+				working++
+				if days.office[key] {
+					office++
+					line.OfficeDays++
+				} else if days.wfh[key] {
+					line.WFHDays++
+				}
+			}
+
+			required := policy.MinOfficeDaysPerWeek
+			if working < required {
+				required = working
+			}
+			if required == 0 {
+				continue
+			}
+			line.Weeks++
+			if office < required {
+				line.WeeksShort++
+			}
+		}
+		line.Compliant = line.WeeksShort*2 <= line.Weeks
+		lines[username] = line
+	}
+
+	return lines, nil
+}
+
+// complianceReport returns every user with an office requirement for month,
+// with MonthsShort traced back over the previous months.
+func (a *App) complianceReport(month time.Time) ([]models.ComplianceLine, error) {
+	current, err := a.monthCompliance(month)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range current {
+		if !line.Compliant {
+			line.MonthsShort = 1
+		}
+	}
+
+	for i := 1; i < complianceTrendMonths; i++ {
+		earlier, err := a.monthCompliance(month.AddDate(0, -i, 0))
+		if err != nil {
+			return nil, err
+		}
+		for username, line := range current {
+			if line.MonthsShort != i {
+				continue
+			}
+			if prev, ok := earlier[username]; ok && !prev.Compliant {
+				line.MonthsShort++
+			}
+		}
+	}
+
+	lines := make([]models.ComplianceLine, 0, len(current))
+	for _, line := range current {
+		lines = append(lines, *line)
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		if lines[i].Compliant != lines[j].Compliant {
+			return !lines[i].Compliant
+		}
+		return lines[i].Username < lines[j].Username
+	})
+	return lines, nil
+}
+
+func formatComplianceLine(line models.ComplianceLine) string {
+	text := fmt.Sprintf("• *%s*: %d office / %d WFH days, short of %d/week in %d of %d weeks",
+		line.Username, line.OfficeDays, line.WFHDays, line.RequiredPerWeek, line.WeeksShort, line.Weeks)
+	if line.MonthsShort > 1 {
+		text += fmt.Sprintf(" — %d months in a row", line.MonthsShort)
+	}
+	return text
+}
+
+// runComplianceReports sends last month's hybrid compliance flags to each
+// manager on the 1st of every month. Managers only hear about their own
+// reports; flagged users without a manager go to the admin channel.
+func (a *App) runComplianceReports(ctx context.Context) {
+	loc := a.config.Timezone
+	lastRun := ""
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			now = now.In(loc)
+			thisMonth := now.Format("2006-01")
+			if lastRun == thisMonth || now.Day() != 1 || now.Hour() < 9 {
+				continue
+			}
+			lastRun = thisMonth
+			a.sendComplianceReports(ctx, now.AddDate(0, -1, 0))
+		}
+	}
+}
+
+func (a *App) sendComplianceReports(ctx context.Context, month time.Time) {
+	lines, err := a.complianceReport(month)
+	if err != nil {
+		logger.Error("Failed to build compliance report: %v", err)
+		return
+	}
+
+	byManager := make(map[string][]string)
+	for _, line := range lines {
+		if line.Compliant {
+			continue
+		}
+		byManager[line.ManagerID] = append(byManager[line.ManagerID], formatComplianceLine(line))
+	}
+
+	title := fmt.Sprintf("Hybrid policy check for %s", month.Format("January 2006"))
+	for managerID, flagged := range byManager {
+		text := fmt.Sprintf("🏢 *%s*\nThese people on your team were below the office-day policy:\n%s",
+			title, strings.Join(flagged, "\n"))
+
+		if managerID != "" {
+			a.notifyUser(ctx, managerID, title, text)
+			continue
+		}
+		adminChannel := a.adminChannel()
+		if adminChannel == "" {
+			logger.Info("Compliance: %d flagged users have no manager", len(flagged))
+			continue
+		}
+		_, _, err := a.slackClient.PostMessage(adminChannel, slack.MsgOptionText(
+			fmt.Sprintf("🏢 *%s*\nFlagged users with no manager on record:\n%s", title, strings.Join(flagged, "\n")),
+			false,
+		))
+		if err != nil {
+			logger.Error("Failed to post compliance report: %v", err)
+		}
+	}
+}
+
+// parseReportMonth parses "YYYY-MM", defaulting to the month before today's.
+func parseReportMonth(value string, today time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Date(today.Year(), today.Month()-1, 1, 0, 0, 0, 0, time.UTC), nil
+	}
+	month, err := time.Parse("2006-01", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid month %q (use YYYY-MM)", value)
+	}
+	return month, nil
+}
+
+func (a *App) postComplianceReport(month time.Time, reply func(string)) {
+	lines, err := a.complianceReport(month)
+	if err != nil {
+		logger.Error("Failed to build compliance report: %v", err)
+		reply("❌ " + err.Error())
+		return
+	}
+	if len(lines) == 0 {
+		reply(fmt.Sprintf("No one with an office-day requirement logged office or WFH days in %s.", month.Format("January 2006")))
+		return
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "🏢 *Hybrid policy check for %s*\n", month.Format("January 2006"))
+	compliant := 0
+	for _, line := range lines {
+		if line.Compliant {
+			compliant++
+			continue
+		}
+		b.WriteString(formatComplianceLine(line) + "\n")
+	}
+	fmt.Fprintf(&b, "%d of %d people met the policy.", compliant, len(lines))
+	reply(b.String())
+}
+
+// handleComplianceReport serves /api/admin/reports/compliance?month=YYYY-MM
+func (a *App) handleComplianceReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	month, err := parseReportMonth(r.URL.Query().Get("month"), a.today())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	lines, err := a.complianceReport(month)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, lines)
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+	"slack-leaves-ai-agent/services"
+
+	"github.com/slack-go/slack"
+)
+
+// Action IDs of the buttons asking the author to confirm a parse. The button
+// value is the pending parse's key.
+const (
+	confirmParseActionID = "parse_confirm"
+	editParseActionID    = "parse_edit"
+	rejectParseActionID  = "parse_reject"
+)
+
+// Callback and block IDs of the modal for fixing a parse before it's saved.
+// Block IDs get the item's index appended.
+const (
+	editParseCallbackID   = "parse_edit"
+	editParseTypeBlockID  = "parse_type"
+	editParseStartBlockID = "parse_start"
+	editParseEndBlockID   = "parse_end"
+	editParseInputID      = "value"
+)
+
+// pendingParseTTL is how long an unsure parse waits for its author.
+const pendingParseTTL = 24 * time.Hour
+
+type pendingParse struct {
+	ev       *slack.MessageEvent
+	userInfo *slack.User
+	leaves   []*models.Leave
+	at       time.Time
+
+	// responseURL replaces the confirmation message once the author saves
+	// the parse from the edit modal.
+	responseURL string
+}
+
+// pendingParses holds parses until their author confirms, edits or rejects
+// them. They're kept in memory only, so a
+// restart drops them and the author is asked to post again.
+type pendingParses struct {
+	mu    sync.Mutex
+	items map[string]pendingParse
+}
+
+func newPendingParses() *pendingParses {
+	return &pendingParses{items: make(map[string]pendingParse)}
+}
+
+func (p *pendingParses) put(key string, parse pendingParse) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for k, item := range p.items {
+		if parse.at.Sub(item.at) > pendingParseTTL {
+			delete(p.items, k)
+		}
+	}
+	p.items[key] = parse
+}
+
+// take removes and returns the pending parse, so each can be answered once.
+func (p *pendingParses) take(key string) (pendingParse, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	parse, ok := p.items[key]
+	delete(p.items, key)
+	if ok && time.Since(parse.at) > pendingParseTTL {
+		return parse, false
+	}
+	return parse, ok
+}
+
+// askToConfirmParse shows the author what their message came out as and
+// records it only once they confirm, or fix it and save. Items parsed from
+// the same message are confirmed together. unsure words the question for a
+// parse below the confidence threshold.
+func (a *App) askToConfirmParse(ev *slack.MessageEvent, userInfo *slack.User, leaves []*models.Leave, unsure bool) {
+	key := ev.Channel + ":" + ev.Timestamp
+	a.pendingParses.put(key, pendingParse{ev: ev, userInfo: userInfo, leaves: leaves, at: time.Now()})
+	logger.Info("Asking %s to confirm the parse of %s (unsure: %v)", userInfo.Name, ev.Timestamp, unsure)
+
+	lines := make([]string, 0, len(leaves))
+	for _, leave := range leaves {
+		line := fmt.Sprintf("• *%s* from %s to %s%s",
+			leave.LeaveType,
+			leave.StartTime.Format("Mon Jan 2, 3:04 PM"),
+			leave.EndTime.Format("Mon Jan 2, 3:04 PM"),
+			reasonSuffix(leave.Reason))
+		if rule, err := services.ParseRRule(leave.Recurrence); leave.Recurrence != "" && err == nil {
+			line += ", repeating " + rule.Describe()
+		}
+		lines = append(lines, line)
+	}
+	question := "📋 Here's what I understood. Should I record it?\n"
+	if unsure {
+		question = "🤔 I'm not sure I got that right. Should I record this?\n"
+	}
+	text := question + strings.Join(lines, "\n")
+	confirm := slack.NewButtonBlockElement(confirmParseActionID, key,
+		slack.NewTextBlockObject("plain_text", "Confirm", false, false)).WithStyle(slack.StylePrimary)
+	edit := slack.NewButtonBlockElement(editParseActionID, key,
+		slack.NewTextBlockObject("plain_text", "Edit", false, false))
+	reject := slack.NewButtonBlockElement(rejectParseActionID, key,
+		slack.NewTextBlockObject("plain_text", "Cancel", false, false))
+
+	_, err := a.slackClient.PostEphemeral(ev.Channel, ev.User,
+		slack.MsgOptionText(text, false),
+		slack.MsgOptionBlocks(
+			slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", text, false, false), nil, nil),
+			slack.NewActionBlock("confirm_parse", confirm, edit, reject),
+		),
+	)
+	if err != nil {
+		logger.Error("Failed to ask for parse confirmation: %v", err)
+	}
+}
+
+func reasonSuffix(reason string) string {
+	if reason == "" {
+		return ""
+	}
+	return " (" + reason + ")"
+}
+
+func confirmParseInteraction(callback slack.InteractionCallback) bool {
+	for _, action := range callback.ActionCallback.BlockActions {
+		switch action.ActionID {
+		case confirmParseActionID, editParseActionID, rejectParseActionID:
+			return true
+		}
+	}
+	return false
+}
+
+// handleConfirmParseAction records or drops a pending parse, or opens the
+// modal to fix it. Rejections are audited too, so parses can be checked for
+// accuracy later.
+func (a *App) handleConfirmParseAction(ctx context.Context, callback slack.InteractionCallback) {
+	for _, action := range callback.ActionCallback.BlockActions {
+		if action.ActionID == editParseActionID {
+			a.openParseEditModal(callback, action.Value)
+			continue
+		}
+		if action.ActionID != confirmParseActionID && action.ActionID != rejectParseActionID {
+			continue
+		}
+
+		parse, ok := a.pendingParses.take(action.Value)
+		if ok && parse.ev.User != callback.User.ID {
+			// Ephemeral messages only reach the author, but be sure
+			a.pendingParses.put(action.Value, parse)
+			continue
+		}
+
+		var text string
+		switch {
+		case !ok:
+			text = "⌛ That request has expired. Please post it again."
+		case action.ActionID == confirmParseActionID:
+			for _, leave := range parse.leaves {
+				leave.Trace.AddStep("you confirmed it")
+			}
+			a.saveMessageLeaves(ctx, "confirmed_create", parse.ev, parse.userInfo, parse.leaves)
+			text = "✅ Recorded."
+		default:
+			for _, leave := range parse.leaves {
+				a.audit("slack:"+callback.User.ID, "parse_rejected", 0, leave, nil)
+			}
+			text = "👍 Nothing recorded. Try posting it again with the dates spelled out, e.g. `on leave Friday Mar 8`."
+		}
+
+		_, _, err := a.slackClient.PostMessage(callback.Channel.ID,
+			slack.MsgOptionReplaceOriginal(callback.ResponseURL),
+			slack.MsgOptionText(text, false))
+		if err != nil {
+			logger.Error("Failed to update parse confirmation: %v", err)
+		}
+	}
+}
+
+// openParseEditModal lets the author fix a pending parse. The parse stays
+// pending until the modal is saved.
+func (a *App) openParseEditModal(callback slack.InteractionCallback, key string) {
+	parse, ok := a.pendingParses.take(key)
+	if !ok {
+		_, _, err := a.slackClient.PostMessage(callback.Channel.ID,
+			slack.MsgOptionReplaceOriginal(callback.ResponseURL),
+			slack.MsgOptionText("⌛ That request has expired. Please post it again.", false))
+		if err != nil {
+			logger.Error("Failed to update parse confirmation: %v", err)
+		}
+		return
+	}
+	if parse.ev.User == callback.User.ID {
+		parse.responseURL = callback.ResponseURL
+	}
+	a.pendingParses.put(key, parse)
+	if parse.ev.User != callback.User.ID {
+		return
+	}
+
+	if _, err := a.slackClient.OpenView(callback.TriggerID, parseEditModal(key, parse)); err != nil {
+		logger.Error("Failed to open parse edit modal: %v", err)
+	}
+}
+
+func parseEditModal(key string, parse pendingParse) slack.ModalViewRequest {
+	typeOptions := make([]*slack.OptionBlockObject, 0, len(models.LeaveTypes))
+	for _, info := range models.LeaveTypes {
+		typeOptions = append(typeOptions,
+			slack.NewOptionBlockObject(info.Name, slack.NewTextBlockObject("plain_text", info.Name, false, false), nil))
+	}
+	timeHint := slack.NewTextBlockObject("plain_text", "YYYY-MM-DD or YYYY-MM-DDTHH:MM", false, false)
+	textInput := func(value string) *slack.PlainTextInputBlockElement {
+		input := slack.NewPlainTextInputBlockElement(nil, editParseInputID)
+		input.InitialValue = value
+		return input
+	}
+
+	blocks := []slack.Block{
+		slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn",
+			">"+strings.ReplaceAll(parse.ev.Text, "\n", "\n>"), false, false), nil, nil),
+	}
+	for i, leave := range parse.leaves {
+		typeSelect := slack.NewOptionsSelectBlockElement(slack.OptTypeStatic, nil, editParseInputID, typeOptions...)
+		for _, option := range typeOptions {
+			if option.Value == leave.LeaveType {
+				typeSelect.InitialOption = option
+			}
+		}
+		if len(parse.leaves) > 1 {
+			blocks = append(blocks, slack.NewHeaderBlock(
+				slack.NewTextBlockObject("plain_text", fmt.Sprintf("Item %d", i+1), false, false)))
+		}
+		blocks = append(blocks,
+			slack.NewInputBlock(fmt.Sprintf("%s_%d", editParseTypeBlockID, i),
+				slack.NewTextBlockObject("plain_text", "Type", false, false), nil, typeSelect),
+			slack.NewInputBlock(fmt.Sprintf("%s_%d", editParseStartBlockID, i),
+				slack.NewTextBlockObject("plain_text", "Start", false, false), timeHint,
+				textInput(leave.StartTime.Format(adminTimeLayout))),
+			slack.NewInputBlock(fmt.Sprintf("%s_%d", editParseEndBlockID, i),
+				slack.NewTextBlockObject("plain_text", "End", false, false), timeHint,
+				textInput(leave.EndTime.Format(adminTimeLayout))),
+		)
+	}
+
+	return slack.ModalViewRequest{
+		Type:            slack.VTModal,
+		CallbackID:      editParseCallbackID,
+		PrivateMetadata: key,
+		Title:           slack.NewTextBlockObject("plain_text", "Edit before saving", false, false),
+		Submit:          slack.NewTextBlockObject("plain_text", "Save", false, false),
+		Close:           slack.NewTextBlockObject("plain_text", "Back", false, false),
+		Blocks:          slack.Blocks{BlockSet: blocks},
+	}
+}
+
+// submitParseEdit applies the author's fixes to a pending parse and records
+// it. The edits must pass the same parse policy and booking rules as the
+// message, and the save goes through approval like any other. It runs
+// inline because validation errors go back in the acknowledgement; the save
+// itself is queued.
+func (a *App) submitParseEdit(callback slack.InteractionCallback) *slack.ViewSubmissionResponse {
+	key := callback.View.PrivateMetadata
+	firstBlock := fmt.Sprintf("%s_0", editParseTypeBlockID)
+	parse, ok := a.pendingParses.take(key)
+	if !ok {
+		return slack.NewErrorsViewSubmissionResponse(map[string]string{
+			firstBlock: "This request has expired. Please post it again.",
+		})
+	}
+	if parse.ev.User != callback.User.ID {
+		a.pendingParses.put(key, parse)
+		return slack.NewErrorsViewSubmissionResponse(map[string]string{
+			firstBlock: "Only the author can edit this request.",
+		})
+	}
+
+	region := a.regionFor(parse.userInfo.Name)
+	loc := region.Timezone
+	now := time.Now()
+	values := callback.View.State.Values
+	errs := map[string]string{}
+	edited := make([]*models.Leave, 0, len(parse.leaves))
+	for i, original := range parse.leaves {
+		typeBlock := fmt.Sprintf("%s_%d", editParseTypeBlockID, i)
+		startBlock := fmt.Sprintf("%s_%d", editParseStartBlockID, i)
+		endBlock := fmt.Sprintf("%s_%d", editParseEndBlockID, i)
+
+		leave := *original
+		leave.LeaveType = values[typeBlock][editParseInputID].SelectedOption.Value
+		start, err := parseAdminTime(strings.TrimSpace(values[startBlock][editParseInputID].Value), false, loc)
+		if err != nil {
+			errs[startBlock] = err.Error()
+		}
+		end, err := parseAdminTime(strings.TrimSpace(values[endBlock][editParseInputID].Value), true, loc)
+		if err != nil {
+			errs[endBlock] = err.Error()
+		}
+		if _, failed := errs[startBlock]; failed {
+			continue
+		}
+		if _, failed := errs[endBlock]; failed {
+			continue
+		}
+		leave.StartTime, leave.EndTime = start, end
+		leave.Duration = models.FormatDuration(start, end)
+		if original.Trace != nil {
+			trace := *original.Trace
+			trace.Steps = append(append([]string(nil), trace.Steps...), "you edited it before it was saved")
+			leave.Trace = &trace
+		}
+		if err := validateAdminLeave(&leave); err != nil {
+			errs[endBlock] = err.Error()
+			continue
+		}
+		// The author gets no more leeway than the message itself would have
+		check := &services.LeaveResponse{IsValid: true, LeaveType: leave.LeaveType, StartTime: start, EndTime: end}
+		if reason := services.CheckEditedParse(check, parse.ev.Text, region, now); reason != "" {
+			errs[startBlock] = reason
+			continue
+		}
+		edited = append(edited, &leave)
+	}
+	if len(errs) > 0 {
+		a.pendingParses.put(key, parse)
+		return slack.NewErrorsViewSubmissionResponse(errs)
+	}
+
+	a.queue.Submit("saveEditedParse", func(ctx context.Context) {
+		a.saveMessageLeaves(ctx, "edited_create", parse.ev, parse.userInfo, edited)
+		if parse.responseURL == "" {
+			return
+		}
+		_, _, err := a.slackClient.PostMessage(parse.ev.Channel,
+			slack.MsgOptionReplaceOriginal(parse.responseURL),
+			slack.MsgOptionText("✅ Recorded with your changes.", false))
+		if err != nil {
+			logger.Error("Failed to update parse confirmation: %v", err)
+		}
+	})
+	return nil
+}
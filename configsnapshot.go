@@ -0,0 +1,318 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+	"slack-leaves-ai-agent/services"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configSnapshotVersion is bumped when the snapshot format changes in a way
+// older instances can't import.
+const configSnapshotVersion = 1
+
+// maxConfigSnapshotSize bounds an imported snapshot.
+const maxConfigSnapshotSize = 10 << 20
+
+// configSnapshot is a workspace's configuration, as opposed to its records:
+// settings, feature flags, offices with their leave policies and holidays,
+// coverage teams and channel digests. It is written as YAML, and imports
+// take YAML or JSON. Leave types are built in; they're listed so an import
+// into an instance that lacks one fails.
+type configSnapshot struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	bootstrapConfig
+	Settings     map[string]string      `json:"settings,omitempty"`
+	FeatureFlags map[string]bool        `json:"feature_flags,omitempty"`
+	Coverage     coverageSnapshot       `json:"coverage"`
+	Digests      []models.ChannelDigest `json:"digests,omitempty"`
+}
+
+// coverageSnapshot holds coverage roles: the minimum available per role and
+// who fills each.
+type coverageSnapshot struct {
+	Rules   map[string]int      `json:"rules,omitempty"`
+	Members map[string][]string `json:"members,omitempty"`
+}
+
+// configImportResult counts what an import wrote.
+type configImportResult struct {
+	Offices         int `json:"offices"`
+	Holidays        int `json:"holidays"`
+	Settings        int `json:"settings"`
+	FeatureFlags    int `json:"feature_flags"`
+	CoverageRules   int `json:"coverage_rules"`
+	CoverageMembers int `json:"coverage_members"`
+	Digests         int `json:"digests"`
+}
+
+// exportConfig reads the current configuration.
+func (a *App) exportConfig() (*configSnapshot, error) {
+	snapshot := &configSnapshot{
+		Version:    configSnapshotVersion,
+		ExportedAt: time.Now().UTC(),
+	}
+	snapshot.LeaveTypes = knownLeaveTypes
+
+	var err error
+	if snapshot.Offices, err = a.locationRepo.List(); err != nil {
+		return nil, fmt.Errorf("error loading offices: %v", err)
+	}
+	holidays, err := a.locationRepo.ListAllHolidays()
+	if err != nil {
+		return nil, fmt.Errorf("error loading holidays: %v", err)
+	}
+	for _, holiday := range holidays {
+		snapshot.Holidays = append(snapshot.Holidays, bootstrapHoliday{
+			Office: holiday.LocationCode,
+			Date:   holiday.Date.Format("2006-01-02"),
+			Name:   holiday.Name,
+		})
+	}
+
+	if snapshot.Settings, err = a.settingsRepo.List(); err != nil {
+		return nil, fmt.Errorf("error loading settings: %v", err)
+	}
+	if teamID := a.teamID(); teamID != "" {
+		flags, err := a.featureFlagRepo.List(teamID)
+		if err != nil {
+			return nil, fmt.Errorf("error loading feature flags: %v", err)
+		}
+		snapshot.FeatureFlags = make(map[string]bool, len(flags))
+		for _, flag := range flags {
+			snapshot.FeatureFlags[flag.Flag] = flag.Enabled
+		}
+	}
+
+	if snapshot.Coverage.Rules, err = a.coverageRepo.GetRules(); err != nil {
+		return nil, fmt.Errorf("error loading coverage rules: %v", err)
+	}
+	if snapshot.Coverage.Members, err = a.coverageRepo.ListMembers(); err != nil {
+		return nil, fmt.Errorf("error loading coverage members: %v", err)
+	}
+	if snapshot.Digests, err = a.digestRepo.List(); err != nil {
+		return nil, fmt.Errorf("error loading digests: %v", err)
+	}
+	return snapshot, nil
+}
+
+// validate checks the whole snapshot before anything is imported.
+func (s *configSnapshot) validate() error {
+	if s.Version != configSnapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d, want %d", s.Version, configSnapshotVersion)
+	}
+	if err := s.bootstrapConfig.validate(); err != nil {
+		return err
+	}
+	for flag := range s.FeatureFlags {
+		if !isKnownFeatureFlag(flag) {
+			return fmt.Errorf("unknown feature flag %q", flag)
+		}
+	}
+	for role, minAvailable := range s.Coverage.Rules {
+		if minAvailable < 0 {
+			return fmt.Errorf("coverage rule %s: minimum can't be negative", role)
+		}
+	}
+	for _, digest := range s.Digests {
+		if _, err := services.ParseCron(digest.Schedule); err != nil {
+			return fmt.Errorf("digest for %s: %v", digest.ChannelID, err)
+		}
+		if _, err := time.LoadLocation(digest.Timezone); err != nil {
+			return fmt.Errorf("digest for %s: unknown timezone %q", digest.ChannelID, digest.Timezone)
+		}
+	}
+	return nil
+}
+
+// importConfig applies a snapshot on top of the current configuration.
+// Everything in it is created or overwritten; nothing missing from it is
+// removed, so importing the same snapshot twice changes nothing.
+func (a *App) importConfig(actor string, snapshot *configSnapshot) (*configImportResult, error) {
+	if err := snapshot.validate(); err != nil {
+		return nil, err
+	}
+	teamID := a.teamID()
+	if len(snapshot.FeatureFlags) > 0 && teamID == "" {
+		return nil, fmt.Errorf("couldn't look up this workspace's ID to import feature flags")
+	}
+
+	seeded := &bootstrapResult{}
+	if err := a.seedBootstrap(actor, &snapshot.bootstrapConfig, seeded); err != nil {
+		return nil, err
+	}
+	result := &configImportResult{Offices: seeded.Offices, Holidays: seeded.Holidays}
+
+	keys := make([]string, 0, len(snapshot.Settings))
+	for key := range snapshot.Settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := a.settingsRepo.Set(key, snapshot.Settings[key], actor); err != nil {
+			return result, fmt.Errorf("error saving setting %s: %v", key, err)
+		}
+		result.Settings++
+	}
+
+	for flag, enabled := range snapshot.FeatureFlags {
+		err := a.featureFlagRepo.Set(&models.FeatureFlag{TeamID: teamID, Flag: flag, Enabled: enabled, UpdatedBy: actor})
+		if err != nil {
+			return result, fmt.Errorf("error saving feature flag %s: %v", flag, err)
+		}
+		result.FeatureFlags++
+	}
+
+	for role, minAvailable := range snapshot.Coverage.Rules {
+		if err := a.coverageRepo.SetRule(role, minAvailable); err != nil {
+			return result, fmt.Errorf("error saving coverage rule %s: %v", role, err)
+		}
+		result.CoverageRules++
+	}
+	for role, usernames := range snapshot.Coverage.Members {
+		for _, username := range usernames {
+			if err := a.coverageRepo.AddMember(username, role); err != nil {
+				return result, fmt.Errorf("error adding %s to %s: %v", username, role, err)
+			}
+			result.CoverageMembers++
+		}
+	}
+
+	for i := range snapshot.Digests {
+		digest := &snapshot.Digests[i]
+		digest.UpdatedBy = actor
+		if err := a.digestRepo.Upsert(digest); err != nil {
+			return result, fmt.Errorf("error saving digest for %s: %v", digest.ChannelID, err)
+		}
+		result.Digests++
+	}
+
+	a.audit(actor, "config_import", 0, nil, result)
+	return result, nil
+}
+
+// handleConfigSnapshot serves /api/admin/config: GET exports the
+// configuration as a YAML snapshot and POST imports one.
+func (a *App) handleConfigSnapshot(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		snapshot, err := a.exportConfig()
+		if err != nil {
+			logger.Error("Failed to export config: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		body, err := marshalConfigSnapshot(snapshot)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Header().Set("Content-Disposition", `attachment; filename="latebot-config.yaml"`)
+		w.Write(body)
+
+	case http.MethodPost:
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxConfigSnapshotSize))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var snapshot configSnapshot
+		if err := unmarshalConfigSnapshot(body, &snapshot); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid snapshot (expected the YAML written by export): %v", err), http.StatusBadRequest)
+			return
+		}
+		if err := snapshot.validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		actor := adminActor(r)
+		result, err := a.importConfig(actor, &snapshot)
+		if err != nil {
+			logger.Error("Config import by %s failed: %v", actor, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		logger.Info("Config import by %s: %+v", actor, *result)
+		writeJSON(w, http.StatusOK, result)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// marshalConfigSnapshot writes a snapshot as block-style YAML. It goes
+// through JSON so the keys and formats are the json tags' ones, shared with
+// the bootstrap config and the models.
+func marshalConfigSnapshot(snapshot *configSnapshot) ([]byte, error) {
+	body, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	// JSON is YAML, so it parses into a node tree in field order
+	var doc yaml.Node
+	if err := yaml.Unmarshal(body, &doc); err != nil {
+		return nil, err
+	}
+	blockStyle(&doc)
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// blockStyle drops the flow style and quoting a node tree parsed from JSON
+// has. Strings that would read as another type stay quoted.
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+// unmarshalConfigSnapshot reads a snapshot in any YAML style, JSON included,
+// so exports edited by hand import as well.
+func unmarshalConfigSnapshot(body []byte, snapshot *configSnapshot) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(body, &doc); err != nil {
+		return err
+	}
+	unquotedDatesAsStrings(&doc)
+	var value interface{}
+	if err := doc.Decode(&value); err != nil {
+		return err
+	}
+	body, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, snapshot)
+}
+
+// unquotedDatesAsStrings keeps dates such as a holiday's 2024-01-26 as they
+// were written. YAML would read them as timestamps, which come back out of
+// JSON with a time added.
+func unquotedDatesAsStrings(node *yaml.Node) {
+	if node.Kind == yaml.ScalarNode && node.ShortTag() == "!!timestamp" {
+		node.Tag = "!!str"
+	}
+	for _, child := range node.Content {
+		unquotedDatesAsStrings(child)
+	}
+}
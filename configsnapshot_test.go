@@ -0,0 +1,102 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+)
+
+func TestConfigSnapshotYAML(t *testing.T) {
+	days := 24.0
+	snapshot := &configSnapshot{
+		Version:    configSnapshotVersion,
+		ExportedAt: time.Date(2024, time.March, 4, 9, 30, 0, 0, time.UTC),
+		bootstrapConfig: bootstrapConfig{
+			LeaveTypes: []string{"FULL_DAY", "WFH"},
+			Offices:    []models.Location{{Code: "blr", Name: "Bangalore", Timezone: "Asia/Kolkata", AnnualLeaveDays: &days}},
+			Holidays:   []bootstrapHoliday{{Office: "blr", Date: "2024-01-26", Name: "Republic Day"}},
+		},
+		Settings:     map[string]string{"max_days": "30", "strict": "true", "note": "line one\nline two"},
+		FeatureFlags: map[string]bool{"digests": true},
+		Coverage: coverageSnapshot{
+			Rules:   map[string]int{"oncall": 1},
+			Members: map[string][]string{"oncall": {"alice", "bob"}},
+		},
+	}
+
+	body, err := marshalConfigSnapshot(snapshot)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if strings.HasPrefix(strings.TrimSpace(string(body)), "{") {
+		t.Errorf("export is in flow style:\n%s", body)
+	}
+	var roundTrip configSnapshot
+	if err := unmarshalConfigSnapshot(body, &roundTrip); err != nil {
+		t.Fatalf("unmarshal export: %v\n%s", err, body)
+	}
+	if !reflect.DeepEqual(&roundTrip, snapshot) {
+		t.Errorf("round trip = %+v, want %+v\n%s", roundTrip, *snapshot, body)
+	}
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{
+			name: "hand-written block style",
+			body: `
+version: 1
+exported_at: 2024-03-04T09:30:00Z
+leave_types:
+  - FULL_DAY
+  - WFH
+offices:
+  - code: blr
+    name: Bangalore
+    timezone: Asia/Kolkata
+    annual_leave_days: 24
+holidays:
+  - office: blr
+    date: 2024-01-26
+    name: Republic Day
+settings:
+  max_days: "30"
+  strict: "true"
+  note: |-
+    line one
+    line two
+feature_flags:
+  digests: true
+coverage:
+  rules:
+    oncall: 1
+  members:
+    oncall: [alice, bob]
+`,
+		},
+		{
+			name: "JSON",
+			body: `{"version": 1, "exported_at": "2024-03-04T09:30:00Z",
+				"leave_types": ["FULL_DAY", "WFH"],
+				"offices": [{"code": "blr", "name": "Bangalore", "timezone": "Asia/Kolkata", "annual_leave_days": 24}],
+				"holidays": [{"office": "blr", "date": "2024-01-26", "name": "Republic Day"}],
+				"settings": {"max_days": "30", "strict": "true", "note": "line one\nline two"},
+				"feature_flags": {"digests": true},
+				"coverage": {"rules": {"oncall": 1}, "members": {"oncall": ["alice", "bob"]}}}`,
+		},
+	}
+	for _, tt := range tests {
+		var got configSnapshot
+		if err := unmarshalConfigSnapshot([]byte(tt.body), &got); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(&got, snapshot) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, *snapshot)
+		}
+	}
+}
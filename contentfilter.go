@@ -0,0 +1,67 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"slack-leaves-ai-agent/models"
+)
+
+// defaultBlockedWords are masked in reasons in every workspace;
+// REASON_BLOCKED_WORDS adds to them.
+var defaultBlockedWords = []string{
+	"fuck", "fucking", "shit", "shitty", "bullshit", "crap", "damn", "bastard", "asshole", "bitch", "wtf",
+}
+
+// healthTerms are the health details masked from reasons when the user's
+// policy keeps sick days private.
+var healthTerms = []string{
+	`fever`, `flu`, `cold`, `cough`, `covid(-?19)?`, `migraine`, `headache`, `stomach( ache| bug)?`,
+	`food poisoning`, `diarrh?o?ea`, `vomiting`, `infection`, `surgery`, `operation`, `hospital(i[sz]ed)?`,
+	`pregnan(t|cy)`, `miscarriage`, `ivf`, `therapy`, `therapist`, `psychiatrist`, `depression`, `anxiety`,
+	`panic attack`, `burn-?out`, `injur(y|ed)`, `fracture`, `dental`, `dentist`, `root canal`, `chemo(therapy)?`,
+	`cancer`, `diabetes`, `asthma`, `allerg(y|ies|ic)`, `blood test`, `scan`, `mri`, `x-?ray`,
+}
+
+// contentFilter masks words that shouldn't be stored or posted with a
+// reason.
+type contentFilter struct {
+	blocked *regexp.Regexp
+	health  *regexp.Regexp
+}
+
+func newContentFilter(extraBlocked []string) *contentFilter {
+	words := make([]string, 0, len(defaultBlockedWords)+len(extraBlocked))
+	for _, word := range defaultBlockedWords {
+		words = append(words, regexp.QuoteMeta(word))
+	}
+	for _, word := range extraBlocked {
+		words = append(words, regexp.QuoteMeta(strings.ToLower(word)))
+	}
+	return &contentFilter{
+		blocked: regexp.MustCompile(`(?i)\b(` + strings.Join(words, "|") + `)\b`),
+		health:  regexp.MustCompile(`(?i)\b(` + strings.Join(healthTerms, "|") + `)\b`),
+	}
+}
+
+// Mask replaces blocked words with asterisks and, when maskHealth is set,
+// health details with "[health]".
+func (f *contentFilter) Mask(text string, maskHealth bool) string {
+	text = f.blocked.ReplaceAllStringFunc(text, func(word string) string {
+		return strings.Repeat("*", len([]rune(word)))
+	})
+	if maskHealth {
+		text = f.health.ReplaceAllString(text, "[health]")
+	}
+	return text
+}
+
+// applyReasonFilter masks the reason of a new record before it's stored and
+// echoed in the channel confirmation. Health details are only masked for
+// users whose policy keeps sick days private.
+func (a *App) applyReasonFilter(leave *models.Leave) {
+	if leave.Reason == "" {
+		return
+	}
+	leave.Reason = a.reasonFilter.Mask(leave.Reason, a.userPolicy(leave.Username).SickNoQuestions)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// runCoverageCommand handles `/admin-leave coverage ...`.
+func (a *App) runCoverageCommand(actor string, args []string) (string, error) {
+	if len(args) == 0 {
+		return adminLeaveUsage, nil
+	}
+
+	switch args[0] {
+	case "list":
+		rules, err := a.coverageRepo.GetRules()
+		if err != nil {
+			return "", fmt.Errorf("error listing coverage rules: %v", err)
+		}
+		if len(rules) == 0 {
+			return "No coverage rules configured yet.", nil
+		}
+		roles := make([]string, 0, len(rules))
+		for role := range rules {
+			roles = append(roles, role)
+		}
+		sort.Strings(roles)
+
+		var b strings.Builder
+		b.WriteString("*Coverage rules*\n")
+		for _, role := range roles {
+			members, err := a.coverageRepo.Members(role)
+			if err != nil {
+				return "", fmt.Errorf("error listing members: %v", err)
+			}
+			fmt.Fprintf(&b, "• `%s`: at least %d of %d available (%s)\n",
+				role, rules[role], len(members), strings.Join(members, ", "))
+		}
+		return b.String(), nil
+
+	case "add", "remove":
+		if len(args) != 3 {
+			return adminLeaveUsage, nil
+		}
+		username, err := a.resolveUserArg(args[1])
+		if err != nil {
+			return "", err
+		}
+		role := strings.ToLower(args[2])
+		if args[0] == "add" {
+			err = a.coverageRepo.AddMember(username, role)
+		} else {
+			err = a.coverageRepo.RemoveMember(username, role)
+		}
+		if err != nil {
+			return "", fmt.Errorf("error updating coverage: %v", err)
+		}
+		a.audit(actor, "coverage_"+args[0], 0, nil, map[string]string{"username": username, "role": role})
+		if args[0] == "add" {
+			return fmt.Sprintf("✅ *%s* now covers `%s`", username, role), nil
+		}
+		return fmt.Sprintf("✅ *%s* no longer covers `%s`", username, role), nil
+
+	case "rule":
+		if len(args) != 3 {
+			return adminLeaveUsage, nil
+		}
+		role := strings.ToLower(args[1])
+		minAvailable, err := strconv.Atoi(args[2])
+		if err != nil || minAvailable < 0 {
+			return "", fmt.Errorf("invalid minimum %q", args[2])
+		}
+		if err := a.coverageRepo.SetRule(role, minAvailable); err != nil {
+			return "", fmt.Errorf("error saving coverage rule: %v", err)
+		}
+		a.audit(actor, "coverage_rule", 0, nil, map[string]interface{}{"role": role, "min_available": minAvailable})
+		if minAvailable == 0 {
+			return fmt.Sprintf("🗑️ Removed the coverage rule for `%s`", role), nil
+		}
+		return fmt.Sprintf("✅ At least %d `%s` must now be available on working days", minAvailable, role), nil
+	}
+
+	return adminLeaveUsage, nil
+}
@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"database/sql"
+)
+
+func CreateUserRolesTable(db *sql.DB) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS user_roles (
+			user_id VARCHAR(255) NOT NULL,
+			role VARCHAR(50) NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+			PRIMARY KEY (user_id, role)
+		);
+	`
+
+	_, err := db.Exec(query)
+	return err
+}
+
+func CreateAuditLogTable(db *sql.DB) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS audit_log (
+			id SERIAL PRIMARY KEY,
+			actor VARCHAR(255) NOT NULL,
+			action VARCHAR(50) NOT NULL,
+			leave_id INTEGER,
+			before_data TEXT,
+			after_data TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_audit_log_leave_id ON audit_log (leave_id);
+	`
+
+	_, err := db.Exec(query)
+	return err
+}
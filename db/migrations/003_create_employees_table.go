@@ -0,0 +1,20 @@
+package migrations
+
+import (
+	"database/sql"
+)
+
+func CreateEmployeesTable(db *sql.DB) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS employees (
+			username VARCHAR(255) PRIMARY KEY,
+			slack_user_id VARCHAR(255),
+			employment_type VARCHAR(20) DEFAULT 'EMPLOYEE' NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+		);
+	`
+
+	_, err := db.Exec(query)
+	return err
+}
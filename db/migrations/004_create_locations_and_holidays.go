@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"database/sql"
+)
+
+func CreateLocationsTables(db *sql.DB) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS locations (
+			code VARCHAR(50) PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			timezone VARCHAR(64) NOT NULL,
+			annual_leave_days NUMERIC(5, 1),
+			max_advance_days INTEGER,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS holidays (
+			location_code VARCHAR(50) NOT NULL REFERENCES locations (code) ON DELETE CASCADE,
+			holiday_date DATE NOT NULL,
+			name VARCHAR(255) NOT NULL,
+			PRIMARY KEY (location_code, holiday_date)
+		);
+
+		ALTER TABLE employees ADD COLUMN IF NOT EXISTS location_code VARCHAR(50) REFERENCES locations (code);
+	`
+
+	_, err := db.Exec(query)
+	return err
+}
@@ -0,0 +1,15 @@
+package migrations
+
+import (
+	"database/sql"
+)
+
+func AddManagersAndHybridPolicy(db *sql.DB) error {
+	query := `
+		ALTER TABLE employees ADD COLUMN IF NOT EXISTS manager_slack_id VARCHAR(255);
+		ALTER TABLE locations ADD COLUMN IF NOT EXISTS min_office_days INTEGER;
+	`
+
+	_, err := db.Exec(query)
+	return err
+}
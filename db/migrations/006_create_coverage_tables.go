@@ -0,0 +1,25 @@
+package migrations
+
+import (
+	"database/sql"
+)
+
+func CreateCoverageTables(db *sql.DB) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS coverage_members (
+			username VARCHAR(255) NOT NULL,
+			role VARCHAR(100) NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+			PRIMARY KEY (username, role)
+		);
+
+		CREATE TABLE IF NOT EXISTS coverage_rules (
+			role VARCHAR(100) PRIMARY KEY,
+			min_available INTEGER NOT NULL CHECK (min_available > 0),
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+		);
+	`
+
+	_, err := db.Exec(query)
+	return err
+}
@@ -0,0 +1,22 @@
+package migrations
+
+import (
+	"database/sql"
+)
+
+func AddEmployeeDirectoryFields(db *sql.DB) error {
+	query := `
+		ALTER TABLE employees ADD COLUMN IF NOT EXISTS external_id VARCHAR(255);
+		ALTER TABLE employees ADD COLUMN IF NOT EXISTS email VARCHAR(255);
+		ALTER TABLE employees ADD COLUMN IF NOT EXISTS full_name VARCHAR(255);
+		ALTER TABLE employees ADD COLUMN IF NOT EXISTS department VARCHAR(255);
+		ALTER TABLE employees ADD COLUMN IF NOT EXISTS active BOOLEAN DEFAULT TRUE NOT NULL;
+		ALTER TABLE employees ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMP;
+
+		CREATE INDEX IF NOT EXISTS idx_employees_slack_user_id ON employees(slack_user_id);
+		CREATE INDEX IF NOT EXISTS idx_employees_email ON employees(LOWER(email));
+	`
+
+	_, err := db.Exec(query)
+	return err
+}
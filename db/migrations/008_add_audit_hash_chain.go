@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	"database/sql"
+)
+
+func AddAuditHashChain(db *sql.DB) error {
+	query := `
+		ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS prev_hash VARCHAR(64);
+		ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS hash VARCHAR(64);
+
+		CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+		BEGIN
+			RAISE EXCEPTION 'audit_log is append-only';
+		END;
+		$$ LANGUAGE plpgsql;
+
+		DROP TRIGGER IF EXISTS audit_log_append_only ON audit_log;
+		CREATE TRIGGER audit_log_append_only
+			BEFORE UPDATE OR DELETE ON audit_log
+			FOR EACH ROW EXECUTE PROCEDURE audit_log_append_only();
+	`
+
+	_, err := db.Exec(query)
+	return err
+}
@@ -0,0 +1,25 @@
+package migrations
+
+import (
+	"database/sql"
+)
+
+func CreateViewerTokensTable(db *sql.DB) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS viewer_tokens (
+			id SERIAL PRIMARY KEY,
+			token_hash VARCHAR(64) NOT NULL UNIQUE,
+			label VARCHAR(255) NOT NULL,
+			scope_start DATE NOT NULL,
+			scope_end DATE NOT NULL,
+			expires_at TIMESTAMP NOT NULL,
+			created_by VARCHAR(255) NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+			revoked_at TIMESTAMP,
+			CHECK (scope_end >= scope_start)
+		);
+	`
+
+	_, err := db.Exec(query)
+	return err
+}
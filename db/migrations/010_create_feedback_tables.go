@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"database/sql"
+)
+
+func CreateFeedbackTables(db *sql.DB) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS feedback (
+			id SERIAL PRIMARY KEY,
+			kind VARCHAR(20) NOT NULL,
+			target VARCHAR(255) NOT NULL,
+			leave_id INTEGER,
+			query_text TEXT,
+			original_text TEXT,
+			parsed_data TEXT,
+			user_id VARCHAR(255) NOT NULL,
+			rating SMALLINT NOT NULL CHECK (rating IN (-1, 1)),
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+			UNIQUE (target, user_id)
+		);
+		CREATE INDEX IF NOT EXISTS idx_feedback_leave_id ON feedback (leave_id);
+
+		CREATE TABLE IF NOT EXISTS parse_examples (
+			id SERIAL PRIMARY KEY,
+			message TEXT NOT NULL,
+			posted_at TIMESTAMP NOT NULL,
+			leave_type VARCHAR(50) NOT NULL,
+			start_time TIMESTAMP NOT NULL,
+			end_time TIMESTAMP NOT NULL,
+			reason TEXT NOT NULL,
+			source VARCHAR(20) NOT NULL,
+			leave_id INTEGER,
+			created_by VARCHAR(255) NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+		);
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_parse_examples_leave_id ON parse_examples (leave_id);
+	`
+
+	_, err := db.Exec(query)
+	return err
+}
@@ -0,0 +1,18 @@
+package migrations
+
+import (
+	"database/sql"
+)
+
+func AddFeedbackReview(db *sql.DB) error {
+	query := `
+		ALTER TABLE feedback ADD COLUMN IF NOT EXISTS reviewed_at TIMESTAMP;
+		ALTER TABLE feedback ADD COLUMN IF NOT EXISTS reviewed_by VARCHAR(255);
+		ALTER TABLE feedback ADD COLUMN IF NOT EXISTS review_outcome VARCHAR(20);
+		CREATE INDEX IF NOT EXISTS idx_feedback_review_queue ON feedback (leave_id)
+			WHERE kind = 'parse' AND rating < 0 AND reviewed_at IS NULL;
+	`
+
+	_, err := db.Exec(query)
+	return err
+}
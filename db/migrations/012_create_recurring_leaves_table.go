@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"database/sql"
+)
+
+func CreateRecurringLeavesTable(db *sql.DB) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS recurring_leaves (
+			id SERIAL PRIMARY KEY,
+			username VARCHAR(255) NOT NULL,
+			original_text TEXT NOT NULL,
+			leave_type VARCHAR(50) NOT NULL,
+			reason TEXT NOT NULL,
+			rrule TEXT NOT NULL,
+			dtstart TIMESTAMP NOT NULL,
+			duration_minutes INTEGER NOT NULL,
+			materialized_until TIMESTAMP NOT NULL,
+			created_by VARCHAR(255) NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+			cancelled_at TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_recurring_leaves_username ON recurring_leaves (username);
+
+		ALTER TABLE leaves ADD COLUMN IF NOT EXISTS recurrence_id INTEGER REFERENCES recurring_leaves (id);
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_leaves_recurrence_start ON leaves (recurrence_id, start_time);
+	`
+
+	_, err := db.Exec(query)
+	return err
+}
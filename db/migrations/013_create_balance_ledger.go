@@ -0,0 +1,48 @@
+package migrations
+
+import (
+	"database/sql"
+)
+
+func CreateBalanceLedger(db *sql.DB) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS balance_ledger (
+			id SERIAL PRIMARY KEY,
+			username VARCHAR(255) NOT NULL,
+			entry_type VARCHAR(20) NOT NULL,
+			days NUMERIC(6,2) NOT NULL,
+			effective_date DATE NOT NULL,
+			reason TEXT NOT NULL,
+			leave_id INTEGER,
+			created_by VARCHAR(255) NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_balance_ledger_username ON balance_ledger (username, effective_date);
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_balance_ledger_accrual ON balance_ledger (username, effective_date)
+			WHERE entry_type = 'ACCRUAL';
+
+		CREATE OR REPLACE FUNCTION balance_ledger_append_only() RETURNS trigger AS $$
+		BEGIN
+			RAISE EXCEPTION 'balance_ledger is append-only';
+		END;
+		$$ LANGUAGE plpgsql;
+
+		DROP TRIGGER IF EXISTS balance_ledger_append_only ON balance_ledger;
+		CREATE TRIGGER balance_ledger_append_only
+			BEFORE UPDATE OR DELETE ON balance_ledger
+			FOR EACH ROW EXECUTE PROCEDURE balance_ledger_append_only();
+
+		INSERT INTO balance_ledger (username, entry_type, days, effective_date, reason, leave_id, created_by)
+		SELECT l.username, 'DEBIT',
+			-(CASE l.leave_type WHEN 'FULL_DAY' THEN (l.end_time::date - l.start_time::date + 1) ELSE 0.5 END),
+			l.start_time::date,
+			'Leave #' || l.id || ' (' || l.leave_type || ') recorded before the ledger',
+			l.id, 'system:migration'
+		FROM leaves l
+		WHERE l.leave_type IN ('FULL_DAY', 'HALF_DAY')
+			AND NOT EXISTS (SELECT 1 FROM balance_ledger b WHERE b.leave_id = l.id);
+	`
+
+	_, err := db.Exec(query)
+	return err
+}
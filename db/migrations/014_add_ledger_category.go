@@ -0,0 +1,14 @@
+package migrations
+
+import (
+	"database/sql"
+)
+
+func AddLedgerCategory(db *sql.DB) error {
+	query := `
+		ALTER TABLE balance_ledger ADD COLUMN IF NOT EXISTS category VARCHAR(50) NOT NULL DEFAULT 'ANNUAL';
+	`
+
+	_, err := db.Exec(query)
+	return err
+}
@@ -0,0 +1,15 @@
+package migrations
+
+import (
+	"database/sql"
+)
+
+func AddPrivateReason(db *sql.DB) error {
+	query := `
+		ALTER TABLE leaves ALTER COLUMN reason DROP NOT NULL;
+		ALTER TABLE leaves ADD COLUMN IF NOT EXISTS private_reason TEXT;
+	`
+
+	_, err := db.Exec(query)
+	return err
+}
@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"database/sql"
+)
+
+// CreateWorkingDaysFunction adds working_days(username, start, end, type),
+// the working-day equivalent of one record: half a day for a half day, the
+// share of a 9-hour day for late arrivals, early departures and
+// appointments, and otherwise the weekdays spanned that aren't holidays at
+// the user's office.
+func CreateWorkingDaysFunction(db *sql.DB) error {
+	query := `
+		CREATE OR REPLACE FUNCTION working_days(p_username TEXT, p_start TIMESTAMP, p_end TIMESTAMP, p_type TEXT)
+		RETURNS NUMERIC AS $$
+			SELECT CASE
+				WHEN p_type = 'HALF_DAY' THEN 0.5
+				WHEN p_type IN ('LATE_ARRIVAL', 'EARLY_DEPARTURE', 'APPOINTMENT') THEN
+					LEAST(EXTRACT(EPOCH FROM (p_end - p_start)) / 3600 / 9, 1)
+				ELSE (
+					SELECT COUNT(*)
+					FROM generate_series(p_start::date, p_end::date, INTERVAL '1 day') AS d
+					WHERE EXTRACT(ISODOW FROM d) < 6
+						AND NOT EXISTS (
+							SELECT 1
+							FROM holidays h
+							JOIN employees e ON e.location_code = h.location_code
+							WHERE e.username = p_username AND h.holiday_date = d::date
+						)
+				)
+			END::NUMERIC
+		$$ LANGUAGE SQL STABLE;
+	`
+
+	_, err := db.Exec(query)
+	return err
+}
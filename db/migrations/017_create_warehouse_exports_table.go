@@ -0,0 +1,24 @@
+package migrations
+
+import (
+	"database/sql"
+)
+
+// CreateWarehouseExportsTable records each warehouse export run and how far
+// it got, so the next run only exports what changed since.
+func CreateWarehouseExportsTable(db *sql.DB) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS warehouse_exports (
+			id SERIAL PRIMARY KEY,
+			started_at TIMESTAMP NOT NULL,
+			leaves_through TIMESTAMP NOT NULL,
+			audit_through BIGINT NOT NULL,
+			leave_rows INT NOT NULL,
+			audit_rows INT NOT NULL,
+			manifest_key TEXT NOT NULL
+		);
+	`
+
+	_, err := db.Exec(query)
+	return err
+}
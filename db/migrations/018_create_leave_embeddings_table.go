@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"database/sql"
+)
+
+// CreateLeaveEmbeddingsTable stores an embedding of each record's reason and
+// message for semantic search. It needs the pgvector extension; where that
+// isn't installed the table isn't created and search stays off. There's no
+// foreign key, as the leaves table is recreated by cmd/migrate; searches
+// join on leaves, so embeddings of deleted records are never returned.
+func CreateLeaveEmbeddingsTable(db *sql.DB) error {
+	query := `
+		DO $$
+		BEGIN
+			IF EXISTS (SELECT 1 FROM pg_available_extensions WHERE name = 'vector') THEN
+				CREATE EXTENSION IF NOT EXISTS vector;
+				EXECUTE '
+					CREATE TABLE IF NOT EXISTS leave_embeddings (
+						leave_id INT PRIMARY KEY,
+						model VARCHAR(100) NOT NULL,
+						embedding vector(1536) NOT NULL,
+						embedded_at TIMESTAMP NOT NULL
+					)
+				';
+			END IF;
+		END
+		$$;
+	`
+
+	_, err := db.Exec(query)
+	return err
+}
@@ -0,0 +1,27 @@
+package migrations
+
+import (
+	"database/sql"
+)
+
+// CreatePipelineEventsTable stores each step of processing a Slack message,
+// for /latebot-log.
+func CreatePipelineEventsTable(db *sql.DB) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS pipeline_events (
+			id BIGSERIAL PRIMARY KEY,
+			channel VARCHAR(50) NOT NULL,
+			message_ts VARCHAR(50) NOT NULL,
+			user_id VARCHAR(50) NOT NULL,
+			stage VARCHAR(20) NOT NULL,
+			detail TEXT NOT NULL DEFAULT '',
+			leave_id BIGINT,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_pipeline_events_user ON pipeline_events (user_id, created_at);
+		CREATE INDEX IF NOT EXISTS idx_pipeline_events_created ON pipeline_events (created_at);
+	`
+
+	_, err := db.Exec(query)
+	return err
+}
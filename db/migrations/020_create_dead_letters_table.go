@@ -0,0 +1,29 @@
+package migrations
+
+import (
+	"database/sql"
+)
+
+// CreateDeadLettersTable keeps messages that failed to process, so they can
+// be replayed instead of being lost.
+func CreateDeadLettersTable(db *sql.DB) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS dead_letters (
+			id SERIAL PRIMARY KEY,
+			channel VARCHAR(50) NOT NULL,
+			message_ts VARCHAR(50) NOT NULL,
+			user_id VARCHAR(50) NOT NULL,
+			text TEXT NOT NULL,
+			stage VARCHAR(20) NOT NULL,
+			error TEXT NOT NULL,
+			status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			resolved_at TIMESTAMP,
+			resolved_by VARCHAR(255)
+		);
+		CREATE INDEX IF NOT EXISTS idx_dead_letters_status ON dead_letters (status, id);
+	`
+
+	_, err := db.Exec(query)
+	return err
+}
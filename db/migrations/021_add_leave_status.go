@@ -0,0 +1,19 @@
+package migrations
+
+import (
+	"database/sql"
+)
+
+// AddLeaveStatus adds the approval status of a record and who decided it.
+// Existing records predate approvals and count as approved.
+func AddLeaveStatus(db *sql.DB) error {
+	query := `
+		ALTER TABLE leaves ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'APPROVED';
+		ALTER TABLE leaves ADD COLUMN IF NOT EXISTS decided_by VARCHAR(255);
+		ALTER TABLE leaves ADD COLUMN IF NOT EXISTS decided_at TIMESTAMP;
+		CREATE INDEX IF NOT EXISTS idx_leaves_pending ON leaves (created_at) WHERE status = 'PENDING';
+	`
+
+	_, err := db.Exec(query)
+	return err
+}
@@ -0,0 +1,17 @@
+package migrations
+
+import (
+	"database/sql"
+)
+
+// AddLeaveSoftDelete lets records be cancelled without losing them: a
+// cancelled record keeps its row, with who cancelled it and when.
+func AddLeaveSoftDelete(db *sql.DB) error {
+	query := `
+		ALTER TABLE leaves ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+		ALTER TABLE leaves ADD COLUMN IF NOT EXISTS deleted_by VARCHAR(255);
+	`
+
+	_, err := db.Exec(query)
+	return err
+}
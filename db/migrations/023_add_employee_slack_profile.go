@@ -0,0 +1,17 @@
+package migrations
+
+import (
+	"database/sql"
+)
+
+// AddEmployeeSlackProfile adds what the Slack directory sync keeps of each
+// profile beyond what the roster provides.
+func AddEmployeeSlackProfile(db *sql.DB) error {
+	query := `
+		ALTER TABLE employees ADD COLUMN IF NOT EXISTS timezone VARCHAR(64);
+		ALTER TABLE employees ADD COLUMN IF NOT EXISTS slack_synced_at TIMESTAMP;
+	`
+
+	_, err := db.Exec(query)
+	return err
+}
@@ -0,0 +1,21 @@
+package migrations
+
+import (
+	"database/sql"
+)
+
+// CreateSettingsTable creates the settings store: configuration set from
+// Slack, such as by the first-run setup, rather than in the environment.
+func CreateSettingsTable(db *sql.DB) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS settings (
+			key VARCHAR(100) PRIMARY KEY,
+			value TEXT NOT NULL,
+			updated_by VARCHAR(255) NOT NULL,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+		);
+	`
+
+	_, err := db.Exec(query)
+	return err
+}
@@ -0,0 +1,23 @@
+package migrations
+
+import (
+	"database/sql"
+)
+
+// CreateFeatureFlagsTable creates the per-workspace overrides of feature
+// flag defaults.
+func CreateFeatureFlagsTable(db *sql.DB) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS feature_flags (
+			team_id VARCHAR(50) NOT NULL,
+			flag VARCHAR(50) NOT NULL,
+			enabled BOOLEAN NOT NULL,
+			updated_by VARCHAR(255) NOT NULL,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+			PRIMARY KEY (team_id, flag)
+		);
+	`
+
+	_, err := db.Exec(query)
+	return err
+}
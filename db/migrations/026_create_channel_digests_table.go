@@ -0,0 +1,23 @@
+package migrations
+
+import (
+	"database/sql"
+)
+
+// CreateChannelDigestsTable creates the per-channel settings of the daily
+// availability digest.
+func CreateChannelDigestsTable(db *sql.DB) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS channel_digests (
+			channel_id VARCHAR(50) PRIMARY KEY,
+			schedule VARCHAR(100) NOT NULL,
+			timezone VARCHAR(64) NOT NULL DEFAULT '',
+			members_only BOOLEAN NOT NULL DEFAULT FALSE,
+			updated_by VARCHAR(255) NOT NULL,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+		);
+	`
+
+	_, err := db.Exec(query)
+	return err
+}
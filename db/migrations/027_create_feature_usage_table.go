@@ -0,0 +1,22 @@
+package migrations
+
+import (
+	"database/sql"
+)
+
+// CreateFeatureUsageTable creates the weekly per-user counts of feature use
+// behind the adoption report.
+func CreateFeatureUsageTable(db *sql.DB) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS feature_usage (
+			week DATE NOT NULL,
+			feature VARCHAR(50) NOT NULL,
+			user_id VARCHAR(50) NOT NULL,
+			uses INT NOT NULL DEFAULT 0,
+			PRIMARY KEY (week, feature, user_id)
+		);
+	`
+
+	_, err := db.Exec(query)
+	return err
+}
@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"database/sql"
+)
+
+// CreateUnparsedMessagesTable keeps messages classified as attendance that
+// the parser got nothing out of, for the weekly review of phrasings it
+// misses.
+func CreateUnparsedMessagesTable(db *sql.DB) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS unparsed_messages (
+			id SERIAL PRIMARY KEY,
+			channel VARCHAR(50) NOT NULL,
+			message_ts VARCHAR(50) NOT NULL,
+			user_id VARCHAR(50) NOT NULL,
+			text TEXT NOT NULL,
+			reason TEXT NOT NULL,
+			status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			resolved_at TIMESTAMP,
+			resolved_by VARCHAR(255),
+			UNIQUE (channel, message_ts)
+		);
+		CREATE INDEX IF NOT EXISTS idx_unparsed_messages_status ON unparsed_messages (status, created_at);
+	`
+
+	_, err := db.Exec(query)
+	return err
+}
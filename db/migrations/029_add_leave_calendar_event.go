@@ -0,0 +1,16 @@
+package migrations
+
+import (
+	"database/sql"
+)
+
+// AddLeaveCalendarEvent links a record to its event on the team's Google
+// Calendar, so edits and cancellations reach the event.
+func AddLeaveCalendarEvent(db *sql.DB) error {
+	query := `
+		ALTER TABLE leaves ADD COLUMN IF NOT EXISTS calendar_event_id VARCHAR(1024);
+	`
+
+	_, err := db.Exec(query)
+	return err
+}
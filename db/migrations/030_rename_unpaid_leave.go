@@ -0,0 +1,16 @@
+package migrations
+
+import (
+	"database/sql"
+)
+
+// RenameUnpaidLeave moves records of the short-lived UNPAID type to
+// LOSS_OF_PAY, which payroll reports flag for deduction.
+func RenameUnpaidLeave(db *sql.DB) error {
+	query := `
+		UPDATE leaves SET leave_type = 'LOSS_OF_PAY' WHERE leave_type = 'UNPAID';
+	`
+
+	_, err := db.Exec(query)
+	return err
+}
@@ -0,0 +1,23 @@
+package migrations
+
+import (
+	"database/sql"
+)
+
+// CreateApprovalDelegatesTable creates the backup approvers who take over an
+// approver's requests while they're on leave. active_until is set while
+// requests are being routed to the backup.
+func CreateApprovalDelegatesTable(db *sql.DB) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS approval_delegates (
+			approver_id VARCHAR(50) PRIMARY KEY,
+			backup_id VARCHAR(50) NOT NULL,
+			active_until DATE,
+			updated_by VARCHAR(255) NOT NULL,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+		);
+	`
+
+	_, err := db.Exec(query)
+	return err
+}
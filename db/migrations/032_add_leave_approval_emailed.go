@@ -0,0 +1,16 @@
+package migrations
+
+import (
+	"database/sql"
+)
+
+// AddLeaveApprovalEmailed records when the approver of a pending record was
+// emailed about it, so they're emailed once.
+func AddLeaveApprovalEmailed(db *sql.DB) error {
+	query := `
+		ALTER TABLE leaves ADD COLUMN IF NOT EXISTS approval_emailed_at TIMESTAMP;
+	`
+
+	_, err := db.Exec(query)
+	return err
+}
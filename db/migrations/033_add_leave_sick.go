@@ -0,0 +1,18 @@
+package migrations
+
+import (
+	"database/sql"
+)
+
+// AddLeaveSick stores whether a record is a sick day, so sick days can be
+// counted. Records kept private under the sick-day policy have "sick" for
+// their reason and are marked from that.
+func AddLeaveSick(db *sql.DB) error {
+	query := `
+		ALTER TABLE leaves ADD COLUMN IF NOT EXISTS sick BOOLEAN NOT NULL DEFAULT FALSE;
+		UPDATE leaves SET sick = TRUE WHERE reason = 'sick' AND original_text = 'sick';
+	`
+
+	_, err := db.Exec(query)
+	return err
+}
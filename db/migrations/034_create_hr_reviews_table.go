@@ -0,0 +1,27 @@
+package migrations
+
+import (
+	"database/sql"
+)
+
+// CreateHRReviewsTable creates HR's review queue: records a policy rule
+// escalated to HR, and why.
+func CreateHRReviewsTable(db *sql.DB) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS hr_reviews (
+			id BIGSERIAL PRIMARY KEY,
+			leave_id BIGINT NOT NULL REFERENCES leaves (id) ON DELETE CASCADE,
+			username VARCHAR(255) NOT NULL,
+			reasons TEXT[] NOT NULL,
+			status VARCHAR(20) NOT NULL DEFAULT 'OPEN',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+			resolved_by VARCHAR(255),
+			resolved_at TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_hr_reviews_open ON hr_reviews(created_at) WHERE status = 'OPEN';
+	`
+
+	_, err := db.Exec(query)
+	return err
+}
@@ -0,0 +1,160 @@
+package migrations
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Step is one named migration. Steps run in order and are safe to re-run.
+type Step struct {
+	Name string
+	Run  func(*sql.DB) error
+}
+
+// Steps lists the migrations applied after the leaves table is created.
+// New migrations go at the end.
+var Steps = []Step{
+	{"user_roles", CreateUserRolesTable},
+	{"audit_log", CreateAuditLogTable},
+	{"employees", CreateEmployeesTable},
+	{"locations", CreateLocationsTables},
+	{"managers_and_hybrid_policy", AddManagersAndHybridPolicy},
+	{"coverage", CreateCoverageTables},
+	{"employee_directory_fields", AddEmployeeDirectoryFields},
+	{"audit_hash_chain", AddAuditHashChain},
+	{"viewer_tokens", CreateViewerTokensTable},
+	{"feedback", CreateFeedbackTables},
+	{"feedback_review", AddFeedbackReview},
+	{"recurring_leaves", CreateRecurringLeavesTable},
+	{"balance_ledger", CreateBalanceLedger},
+	{"ledger_category", AddLedgerCategory},
+	{"private_reason", AddPrivateReason},
+	{"working_days", CreateWorkingDaysFunction},
+	{"warehouse_exports", CreateWarehouseExportsTable},
+	{"leave_embeddings", CreateLeaveEmbeddingsTable},
+	{"pipeline_events", CreatePipelineEventsTable},
+	{"dead_letters", CreateDeadLettersTable},
+	{"leave_status", AddLeaveStatus},
+	{"leave_soft_delete", AddLeaveSoftDelete},
+	{"employee_slack_profile", AddEmployeeSlackProfile},
+	{"settings", CreateSettingsTable},
+	{"feature_flags", CreateFeatureFlagsTable},
+	{"channel_digests", CreateChannelDigestsTable},
+	{"feature_usage", CreateFeatureUsageTable},
+	{"unparsed_messages", CreateUnparsedMessagesTable},
+	{"leave_calendar_event", AddLeaveCalendarEvent},
+	{"rename_unpaid_leave", RenameUnpaidLeave},
+	{"approval_delegates", CreateApprovalDelegatesTable},
+	{"leave_approval_emailed", AddLeaveApprovalEmailed},
+	{"leave_sick", AddLeaveSick},
+	{"hr_reviews", CreateHRReviewsTable},
+}
+
+// RecreateLeavesTable drops and recreates the leaves table. Everything
+// stored in it is lost, along with other tables' foreign keys into it.
+func RecreateLeavesTable(db *sql.DB) error {
+	query := `
+		DROP TABLE IF EXISTS leaves CASCADE;
+		CREATE TABLE leaves (
+			id SERIAL PRIMARY KEY,
+			username VARCHAR(255) NOT NULL,
+			original_text TEXT NOT NULL,
+			start_time TIMESTAMP NOT NULL,
+			end_time TIMESTAMP NOT NULL,
+			duration VARCHAR(255) NOT NULL,
+			reason TEXT NOT NULL,
+			leave_type VARCHAR(50) NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+		);
+	`
+
+	_, err := db.Exec(query)
+	return err
+}
+
+// Run recreates the leaves table and applies every step, as cmd/migrate
+// -recreate-leaves does.
+func Run(db *sql.DB) error {
+	if err := RecreateLeavesTable(db); err != nil {
+		return fmt.Errorf("error creating table: %v", err)
+	}
+	for _, step := range Steps {
+		if err := step.Run(db); err != nil {
+			return fmt.Errorf("error creating %s table: %v", step.Name, err)
+		}
+	}
+	if err := recordVersion(db); err != nil {
+		return fmt.Errorf("error recording schema version: %v", err)
+	}
+	return nil
+}
+
+// Upgrade brings the database to Version without losing data: an empty
+// database gets Run, anything else only has the steps re-applied. It is safe
+// to call repeatedly.
+func Upgrade(db *sql.DB) error {
+	empty, err := IsEmpty(db)
+	if err != nil {
+		return fmt.Errorf("error checking schema: %v", err)
+	}
+	if empty {
+		return Run(db)
+	}
+	for _, step := range Steps {
+		if err := step.Run(db); err != nil {
+			return fmt.Errorf("error creating %s table: %v", step.Name, err)
+		}
+	}
+	if err := recordVersion(db); err != nil {
+		return fmt.Errorf("error recording schema version: %v", err)
+	}
+	return nil
+}
+
+// Version is the schema version Run brings a database to: the number of
+// steps.
+func Version() int {
+	return len(Steps)
+}
+
+func recordVersion(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_version (
+			version INT NOT NULL,
+			migrated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return err
+	}
+	if _, err := db.Exec(`DELETE FROM schema_version`); err != nil {
+		return err
+	}
+	_, err = db.Exec(`INSERT INTO schema_version (version) VALUES ($1)`, Version())
+	return err
+}
+
+// IsEmpty reports whether the database has never been migrated: it has
+// neither a schema version nor a leaves table. Run is only safe to call
+// unattended on an empty database, since it recreates the leaves table.
+func IsEmpty(db *sql.DB) (bool, error) {
+	var empty bool
+	err := db.QueryRow(`SELECT to_regclass('schema_version') IS NULL AND to_regclass('leaves') IS NULL`).Scan(&empty)
+	return empty, err
+}
+
+// CurrentVersion returns the schema version the database was last migrated
+// to, or 0 if it predates version tracking.
+func CurrentVersion(db *sql.DB) (int, error) {
+	var exists bool
+	if err := db.QueryRow(`SELECT to_regclass('schema_version') IS NOT NULL`).Scan(&exists); err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, nil
+	}
+	var version int
+	err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&version)
+	return version, err
+}
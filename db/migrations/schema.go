@@ -0,0 +1,48 @@
+package migrations
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// StagingSchema is where a staging instance keeps its tables unless
+// DB_SCHEMA says otherwise, so it can share a database with production.
+const StagingSchema = "staging"
+
+var schemaName = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// SchemaFor returns the Postgres schema an instance uses given APP_ENV and
+// DB_SCHEMA, or "" for the default search path.
+func SchemaFor(appEnv, dbSchema string) (string, error) {
+	schema := strings.TrimSpace(dbSchema)
+	if schema == "" && strings.EqualFold(appEnv, "staging") {
+		schema = StagingSchema
+	}
+	if schema != "" && !schemaName.MatchString(schema) {
+		return "", fmt.Errorf("invalid DB_SCHEMA %q (use lowercase letters, digits and underscores)", schema)
+	}
+	return schema, nil
+}
+
+// SearchPath is the connection string option that puts schema first on the
+// search path, or "" when schema is "".
+func SearchPath(schema string) string {
+	if schema == "" {
+		return ""
+	}
+	return " search_path=" + schema
+}
+
+// CreateSchema creates schema if it doesn't exist yet. Connections using it
+// must have it on their search path.
+func CreateSchema(db *sql.DB, schema string) error {
+	if schema == "" {
+		return nil
+	}
+	_, err := db.Exec(`CREATE SCHEMA IF NOT EXISTS ` + pq.QuoteIdentifier(schema))
+	return err
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"slack-leaves-ai-agent/models"
+
+	"github.com/slack-go/slack"
+)
+
+// maxDeadLetters is how many pending dead letters the list shows.
+const maxDeadLetters = 20
+
+// deadLetter keeps a message that failed at stage so an admin can replay it
+// once the cause is fixed, instead of the request being lost.
+func (a *App) deadLetter(ev *slack.MessageEvent, stage string, cause error) {
+	err := a.deadLetterRepo.Record(&models.DeadLetter{
+		Channel:   ev.Channel,
+		MessageTS: ev.Timestamp,
+		UserID:    ev.User,
+		Text:      ev.Text,
+		Stage:     stage,
+		Error:     cause.Error(),
+	})
+	if err != nil {
+		logger.Error("Failed to dead-letter message %s (%s: %v): %v", ev.Timestamp, stage, cause, err)
+	}
+}
+
+// runDeadLetterCommand handles `/admin-leave deadletters ...`.
+func (a *App) runDeadLetterCommand(actor string, args []string) (string, error) {
+	if len(args) == 0 || args[0] == "list" {
+		letters, err := a.deadLetterRepo.ListPending(maxDeadLetters)
+		if err != nil {
+			return "", fmt.Errorf("error loading dead letters: %v", err)
+		}
+		if len(letters) == 0 {
+			return "📭 No failed messages are waiting.", nil
+		}
+		lines := []string{"📬 *Failed messages*"}
+		for _, letter := range letters {
+			lines = append(lines, fmt.Sprintf("• #%d %s <@%s> in <#%s>, failed to %s: %s\n    > %s",
+				letter.ID, letter.CreatedAt.In(a.config.Timezone).Format("Jan 2 3:04 PM"), letter.UserID, letter.Channel,
+				letter.Stage, letter.Error, strings.ReplaceAll(letter.Text, "\n", " ")))
+		}
+		if len(letters) == maxDeadLetters {
+			lines = append(lines, fmt.Sprintf("Showing the oldest %d.", maxDeadLetters))
+		}
+		return strings.Join(lines, "\n"), nil
+	}
+
+	if len(args) != 2 || (args[0] != "replay" && args[0] != "discard") {
+		return adminLeaveUsage, nil
+	}
+	id, err := strconv.ParseInt(strings.TrimPrefix(args[1], "#"), 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid dead letter ID %q", args[1])
+	}
+	letter, err := a.deadLetterRepo.Get(id)
+	if err != nil {
+		return "", fmt.Errorf("error loading dead letter: %v", err)
+	}
+	if letter == nil {
+		return "", fmt.Errorf("dead letter #%d not found", id)
+	}
+
+	status := models.DeadLetterDiscarded
+	if args[0] == "replay" {
+		status = models.DeadLetterReplayed
+	}
+	ok, err := a.deadLetterRepo.Resolve(id, status, actor)
+	if err != nil {
+		return "", fmt.Errorf("error updating dead letter: %v", err)
+	}
+	if !ok {
+		return "", fmt.Errorf("dead letter #%d was already %s", id, letter.Status)
+	}
+	a.audit(actor, "dead_letter_"+args[0], 0, nil, letter)
+
+	if status == models.DeadLetterDiscarded {
+		return fmt.Sprintf("🗑️ Discarded failed message #%d.", id), nil
+	}
+
+	// The message keeps its timestamp, so dates like "tomorrow" still mean
+	// the day after it was posted
+	ev := &slack.MessageEvent{Msg: slack.Msg{
+		Channel:   letter.Channel,
+		User:      letter.UserID,
+		Text:      letter.Text,
+		Timestamp: letter.MessageTS,
+	}}
+	a.queue.Submit("replayDeadLetter", func(ctx context.Context) {
+		a.logEvent(ev, models.StageReceived, "replayed by "+actor, 0)
+		a.processMessage(ctx, ev)
+	})
+	return fmt.Sprintf("🔁 Replaying message #%d from <@%s>. Replies go to <#%s> as usual; "+
+		"if it fails again it shows up here with a new ID.", id, letter.UserID, letter.Channel), nil
+}
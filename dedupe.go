@@ -0,0 +1,57 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// dedupeTTL is how long a delivered event is remembered. Slack gives up
+// retrying well within it.
+const dedupeTTL = time.Hour
+
+// deduper remembers which events have been handled, so a redelivery after a
+// slow ack or a reconnect doesn't record the same leave twice.
+type deduper struct {
+	mu        sync.Mutex
+	seen      map[string]time.Time
+	lastPrune time.Time
+}
+
+func newDeduper() *deduper {
+	return &deduper{seen: make(map[string]time.Time)}
+}
+
+// Seen reports whether key was already seen, and remembers it if not. An
+// empty key is never a duplicate.
+func (d *deduper) Seen(key string, now time.Time) bool {
+	if key == "" {
+		return false
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if now.Sub(d.lastPrune) > dedupeTTL {
+		for k, at := range d.seen {
+			if now.Sub(at) > dedupeTTL {
+				delete(d.seen, k)
+			}
+		}
+		d.lastPrune = now
+	}
+
+	if at, ok := d.seen[key]; ok && now.Sub(at) <= dedupeTTL {
+		return true
+	}
+	d.seen[key] = now
+	return false
+}
+
+// eventKey and messageKey namespace the two kinds of keys. Slack retries
+// reuse the event_id; a message ts is only unique within its channel.
+func eventKey(eventID string) string {
+	return "event:" + eventID
+}
+
+func messageKey(channel, ts string) string {
+	return "message:" + channel + ":" + ts
+}
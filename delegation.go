@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+	"slack-leaves-ai-agent/services"
+)
+
+// approvalDelegationSchedule is how often approvers' absences are checked,
+// to hand their approvals to a backup or take them back.
+const approvalDelegationSchedule = "0 * * * *"
+
+// maxAbsenceDays bounds how far ahead an absence is followed.
+const maxAbsenceDays = 90
+
+// maxRoutedApprovals bounds how many pending records are sent on when an
+// approver leaves or comes back.
+const maxRoutedApprovals = 200
+
+// directApproverOf is who approves username's records when no backup is
+// covering: their manager, or the fallback approver.
+func (a *App) directApproverOf(username string) string {
+	if employee, err := a.employeeRepo.Get(username); err == nil && employee.ManagerID != "" {
+		return employee.ManagerID
+	}
+	return a.fallbackApprover()
+}
+
+// activeBackup returns who is approving for approverID while they're on
+// leave, or "".
+func (a *App) activeBackup(approverID string) string {
+	if approverID == "" {
+		return ""
+	}
+	delegate, err := a.delegateRepo.Get(approverID)
+	if err != nil {
+		logger.Error("Failed to load backup approver of %s: %v", approverID, err)
+		return ""
+	}
+	if delegate == nil || delegate.ActiveUntil == nil {
+		return ""
+	}
+	return delegate.BackupID
+}
+
+// approverAbsence returns the last day of the absence approverID is on
+// today: their approved days off, running on over weekends into the next
+// ones. away is false when they're working today.
+func (a *App) approverAbsence(approverID string, today time.Time) (last time.Time, away bool) {
+	employee, err := a.employeeRepo.GetBySlackID(approverID)
+	if err != nil {
+		return time.Time{}, false
+	}
+	day := today
+	for i := 0; i < maxAbsenceDays; i, day = i+1, day.AddDate(0, 0, 1) {
+		leaves, err := a.leaveRepo.FindByUserAndDate(employee.Username, day)
+		if err != nil {
+			logger.Error("Failed to load leave of approver %s: %v", employee.Username, err)
+			return last, away
+		}
+		off := false
+		for _, leave := range leaves {
+			if isDayOff(leave.LeaveType) && (leave.Status == "" || leave.Status == models.LeaveStatusApproved) {
+				off = true
+				break
+			}
+		}
+		switch {
+		case off:
+			last, away = day, true
+		case away && (day.Weekday() == time.Saturday || day.Weekday() == time.Sunday):
+		default:
+			return last, away
+		}
+	}
+	return last, away
+}
+
+// syncApprovalDelegation hands an approver's pending approvals to their
+// backup when they're away, and back to them once they return. The backup
+// covers until the approver's first working day without leave, so a
+// weekend at the end of an absence doesn't bounce requests back and forth.
+func (a *App) syncApprovalDelegation(ctx context.Context, delegate *models.ApprovalDelegate, today time.Time) {
+	last, away := a.approverAbsence(delegate.ApproverID, today)
+	weekend := today.Weekday() == time.Saturday || today.Weekday() == time.Sunday
+
+	switch {
+	case away && delegate.ActiveUntil == nil:
+		if err := a.delegateRepo.SetActiveUntil(delegate.ApproverID, &last); err != nil {
+			logger.Error("Failed to start backup approval for %s: %v", delegate.ApproverID, err)
+			return
+		}
+		a.audit("system", "approvals_delegated", 0, nil, map[string]string{
+			"approver_id": delegate.ApproverID, "backup_id": delegate.BackupID, "until": last.Format("2006-01-02")})
+		count := a.routePendingApprovals(ctx, delegate.ApproverID, delegate.BackupID)
+		a.notifyUser(ctx, delegate.BackupID, "Covering leave approvals",
+			fmt.Sprintf("🔁 <@%s> is on leave through %s, so their leave approvals come to you until they're back. %s",
+				delegate.ApproverID, last.Format("Mon Jan 2"), routedApprovalsText(count)))
+
+	case away && !last.Equal(*delegate.ActiveUntil):
+		if err := a.delegateRepo.SetActiveUntil(delegate.ApproverID, &last); err != nil {
+			logger.Error("Failed to extend backup approval for %s: %v", delegate.ApproverID, err)
+		}
+
+	case !away && delegate.ActiveUntil != nil && !weekend:
+		if err := a.delegateRepo.SetActiveUntil(delegate.ApproverID, nil); err != nil {
+			logger.Error("Failed to end backup approval for %s: %v", delegate.ApproverID, err)
+			return
+		}
+		a.audit("system", "approvals_restored", 0, nil, map[string]string{
+			"approver_id": delegate.ApproverID, "backup_id": delegate.BackupID})
+		count := a.routePendingApprovals(ctx, delegate.ApproverID, delegate.ApproverID)
+		a.notifyUser(ctx, delegate.ApproverID, "Leave approvals are back with you",
+			fmt.Sprintf("👋 Welcome back. <@%s> covered your leave approvals while you were away; requests come to you again. %s",
+				delegate.BackupID, routedApprovalsText(count)))
+	}
+}
+
+func routedApprovalsText(count int) string {
+	switch count {
+	case 0:
+		return "Nothing is waiting right now."
+	case 1:
+		return "One request is waiting and has been sent to you."
+	default:
+		return fmt.Sprintf("%d requests are waiting and have been sent to you.", count)
+	}
+}
+
+// routePendingApprovals sends the records waiting for approverID's sign-off
+// to to, with fresh Approve and Reject buttons. It returns how many were
+// sent.
+func (a *App) routePendingApprovals(ctx context.Context, approverID, to string) int {
+	leaves, err := a.leaveRepo.ListByStatus(models.LeaveStatusPending, maxRoutedApprovals)
+	if err != nil {
+		logger.Error("Failed to load pending leaves for %s: %v", approverID, err)
+		return 0
+	}
+	count := 0
+	for i := range leaves {
+		leave := &leaves[i]
+		if a.directApproverOf(leave.Username) != approverID {
+			continue
+		}
+		employee, err := a.employeeRepo.Get(leave.Username)
+		if err != nil || employee.SlackUserID == "" {
+			logger.Error("Couldn't route approval of leave %d: no Slack ID on file for %s", leave.ID, leave.Username)
+			continue
+		}
+		a.requestApproval(ctx, leave, employee.SlackUserID, to)
+		count++
+	}
+	return count
+}
+
+// approvalDelegationJob checks every backup approver on
+// approvalDelegationSchedule.
+func (a *App) approvalDelegationJob() (scheduledJob, error) {
+	schedule, err := services.ParseCron(approvalDelegationSchedule)
+	if err != nil {
+		return scheduledJob{}, err
+	}
+	return scheduledJob{
+		name:     "approvalDelegation",
+		schedule: schedule,
+		loc:      a.config.Timezone,
+		run: func(ctx context.Context, now time.Time) {
+			a.syncApprovalDelegations(ctx)
+		},
+	}, nil
+}
+
+func (a *App) syncApprovalDelegations(ctx context.Context) {
+	if !a.featureEnabled(flagApprovals) {
+		return
+	}
+	delegates, err := a.delegateRepo.List()
+	if err != nil {
+		logger.Error("Failed to load backup approvers: %v", err)
+		return
+	}
+	today := a.today()
+	for i := range delegates {
+		a.syncApprovalDelegation(ctx, &delegates[i], today)
+	}
+}
+
+// delegateOnOwnLeave hands an approver's approvals to their backup as soon
+// as they record leave that starts today, instead of at the next scheduled
+// check.
+func (a *App) delegateOnOwnLeave(ctx context.Context, leave *models.Leave) {
+	if !isDayOff(leave.LeaveType) || leave.Status == models.LeaveStatusPending || !a.featureEnabled(flagApprovals) {
+		return
+	}
+	today := a.today()
+	if !leave.StartTime.Before(today.AddDate(0, 0, 1)) || !leave.EndTime.After(today) {
+		return
+	}
+	employee, err := a.employeeRepo.Get(leave.Username)
+	if err != nil || employee.SlackUserID == "" {
+		return
+	}
+	delegate, err := a.delegateRepo.Get(employee.SlackUserID)
+	if err != nil {
+		logger.Error("Failed to load backup approver of %s: %v", leave.Username, err)
+		return
+	}
+	if delegate != nil {
+		a.syncApprovalDelegation(ctx, delegate, today)
+	}
+}
+
+// runBackupCommand handles `/admin-leave backups` and
+// `/admin-leave backup @approver @backup|off`.
+func (a *App) runBackupCommand(actor string, args []string) (string, error) {
+	if args[0] == "backups" {
+		if len(args) != 1 {
+			return adminLeaveUsage, nil
+		}
+		delegates, err := a.delegateRepo.List()
+		if err != nil {
+			return "", fmt.Errorf("error loading backup approvers: %v", err)
+		}
+		if len(delegates) == 0 {
+			return "No one has a backup approver. Add one with `/admin-leave backup @approver @backup`.", nil
+		}
+		lines := []string{"🔁 *Backup approvers*"}
+		for _, delegate := range delegates {
+			line := fmt.Sprintf("• <@%s> → <@%s>", delegate.ApproverID, delegate.BackupID)
+			if delegate.ActiveUntil != nil {
+				line += fmt.Sprintf(", covering through %s", delegate.ActiveUntil.Format("Jan 2"))
+			}
+			lines = append(lines, line)
+		}
+		return strings.Join(lines, "\n"), nil
+	}
+
+	if len(args) != 3 {
+		return adminLeaveUsage, nil
+	}
+	approverID, err := resolveUserIDArg(args[1])
+	if err != nil {
+		return "", err
+	}
+
+	if strings.EqualFold(args[2], "off") {
+		delegate, err := a.delegateRepo.Get(approverID)
+		if err != nil {
+			return "", fmt.Errorf("error loading backup approver: %v", err)
+		}
+		if err := a.delegateRepo.Delete(approverID); err != nil {
+			return "", err
+		}
+		a.audit(actor, "backup_approver_removed", 0, delegate, nil)
+		text := fmt.Sprintf("✅ <@%s> no longer has a backup approver.", approverID)
+		if delegate != nil && delegate.ActiveUntil != nil {
+			count := a.routePendingApprovals(context.Background(), approverID, approverID)
+			text += fmt.Sprintf(" Their requests go to them again (%d sent back).", count)
+		}
+		return text, nil
+	}
+
+	backupID, err := resolveUserIDArg(args[2])
+	if err != nil {
+		return "", err
+	}
+	if backupID == approverID {
+		return "", fmt.Errorf("<@%s> can't be their own backup", approverID)
+	}
+	before, err := a.delegateRepo.Get(approverID)
+	if err != nil {
+		return "", fmt.Errorf("error loading backup approver: %v", err)
+	}
+	delegate := &models.ApprovalDelegate{ApproverID: approverID, BackupID: backupID, UpdatedBy: actor}
+	if err := a.delegateRepo.Upsert(delegate); err != nil {
+		return "", fmt.Errorf("error saving backup approver: %v", err)
+	}
+	a.audit(actor, "backup_approver_set", 0, before, delegate)
+
+	if before != nil && before.ActiveUntil != nil {
+		// The approver is already away: the new backup takes over what's waiting
+		if before.BackupID != backupID {
+			a.routePendingApprovals(context.Background(), approverID, backupID)
+		}
+	} else if saved, err := a.delegateRepo.Get(approverID); err == nil && saved != nil {
+		// Pick up an absence that has already started
+		a.syncApprovalDelegation(context.Background(), saved, a.today())
+	}
+	return fmt.Sprintf("✅ <@%s> approves for <@%s> while they're on leave.", backupID, approverID), nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+
+	"github.com/slack-go/slack"
+)
+
+// parseUserChange picks user_change events out of Events API payloads.
+// Like function_executed, the slack-go version we're on doesn't know this
+// event type and can't decode it.
+func parseUserChange(raw json.RawMessage) (*slack.User, bool) {
+	var payload struct {
+		Event struct {
+			Type string     `json:"type"`
+			User slack.User `json:"user"`
+		} `json:"event"`
+	}
+	if err := json.Unmarshal(raw, &payload); err != nil || payload.Event.Type != "user_change" {
+		return nil, false
+	}
+	return &payload.Event.User, true
+}
+
+// handleUserChange keeps the employees table in step with Slack: profile
+// changes are synced, a deactivated account marks the employee as departed,
+// and a reactivated one brings them back. Bots and guests aren't employees
+// and are ignored.
+func (a *App) handleUserChange(ctx context.Context, user *slack.User) {
+	if user.IsBot || user.IsRestricted || user.IsUltraRestricted || user.Name == "" {
+		return
+	}
+
+	if !user.Deleted {
+		err := a.employeeRepo.SyncSlackProfile(&models.Employee{
+			Username:    user.Name,
+			SlackUserID: user.ID,
+			Email:       user.Profile.Email,
+			FullName:    slackFullName(user),
+			Timezone:    user.TZ,
+		})
+		if err != nil {
+			logger.Error("Failed to sync %s's profile: %v", user.Name, err)
+		}
+	}
+
+	changed, err := a.employeeRepo.SetActive(user.Name, user.ID, !user.Deleted)
+	if err != nil {
+		logger.Error("Failed to update status of %s: %v", user.Name, err)
+		return
+	}
+	if !changed {
+		return
+	}
+
+	if !user.Deleted {
+		logger.Info("%s is active in Slack again", user.Name)
+		return
+	}
+	logger.Info("%s was deactivated in Slack", user.Name)
+	a.cancelFutureLeaves(ctx, user.Name, user.Profile.Email, "slack")
+}
+
+// handleTeamJoin registers people as employees when they join the workspace.
+func (a *App) handleTeamJoin(user *slack.User) {
+	if user == nil || user.IsBot || user.IsRestricted || user.IsUltraRestricted || user.Name == "" {
+		return
+	}
+	if _, err := a.employeeRepo.SetActive(user.Name, user.ID, true); err != nil {
+		logger.Error("Failed to register %s: %v", user.Name, err)
+	}
+}
+
+// cancelFutureLeaves deletes everything a departed employee had booked from
+// tomorrow onwards, releasing any desks, and tells their manager what was
+// cancelled, or the admin channel if they have none. It's safe to call again:
+// once the records are gone there is nothing left to cancel or report.
+func (a *App) cancelFutureLeaves(ctx context.Context, username, email, source string) {
+	var managerID string
+	if employee, err := a.employeeRepo.Get(username); err == nil {
+		managerID = employee.ManagerID
+		if email == "" {
+			email = employee.Email
+		}
+	}
+
+	now := time.Now().In(a.regionFor(username).Timezone)
+	tomorrow := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, 1)
+
+	leaves, err := a.leaveRepo.ListStartingFrom(username, tomorrow)
+	if err != nil {
+		logger.Error("Failed to load future leaves of %s: %v", username, err)
+		return
+	}
+
+	var cancelled []models.Leave
+	for _, leave := range leaves {
+		if err := a.leaveRepo.Cancel(leave.ID, "system:"+source); err != nil {
+			logger.Error("Error cancelling leave %d: %v", leave.ID, err)
+			continue
+		}
+		a.audit("system:"+source, "departure_cancel", leave.ID, leave, nil)
+		a.recordLedgerChange("system:"+source, "departure_cancel", &leave, nil)
+		a.syncTeamCalendar(ctx, &leave, nil)
+		a.releaseDesk(ctx, &leave, email)
+		cancelled = append(cancelled, leave)
+	}
+	if len(cancelled) == 0 {
+		return
+	}
+	logger.Info("Cancelled %d future records of departed employee %s", len(cancelled), username)
+
+	lines := make([]string, 0, len(cancelled))
+	for _, leave := range cancelled {
+		lines = append(lines, fmt.Sprintf("• %s on %s", leave.LeaveType, leave.StartTime.Format("Jan 2, 2006")))
+	}
+	text := fmt.Sprintf("%s has left the company, so their upcoming leave was cancelled:\n%s",
+		username, strings.Join(lines, "\n"))
+
+	if managerID != "" {
+		a.notifyUser(ctx, managerID, "Leave cancelled for departed employee", text)
+		return
+	}
+
+	adminChannel := a.adminChannel()
+	if adminChannel == "" {
+		return
+	}
+	_, _, err = a.slackClient.PostMessage(adminChannel, slack.MsgOptionText("👋 "+text, false))
+	if err != nil {
+		logger.Error("Failed to post departure notice for %s: %v", username, err)
+	}
+}
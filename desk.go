@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+
+	"slack-leaves-ai-agent/models"
+	"slack-leaves-ai-agent/services"
+)
+
+// buildDeskBooking returns the desk-booking client, or nil when
+// DESK_BOOKING_URL isn't set or the templates are invalid.
+func buildDeskBooking(config *Config) *services.DeskBookingClient {
+	if config.DeskBookingURL == "" {
+		return nil
+	}
+	client, err := services.NewDeskBookingClient(
+		config.DeskBookingURL,
+		config.DeskBookingPayload,
+		config.DeskBookingCancelURL,
+		config.DeskBookingCancelPayload,
+		config.DeskBookingAuth,
+	)
+	if err != nil {
+		logger.Error("Desk booking disabled: %v", err)
+		return nil
+	}
+	return client
+}
+
+func (a *App) deskBookingFor(leave *models.Leave, email string) services.DeskBooking {
+	booking := services.DeskBooking{
+		Username: leave.Username,
+		Email:    email,
+		Date:     leave.StartTime.Format("2006-01-02"),
+		LeaveID:  leave.ID,
+	}
+	if location, err := a.locationFor(leave.Username); err == nil && location != nil {
+		booking.Location = location.Code
+	}
+	return booking
+}
+
+// syncDeskBooking runs after a record is saved. An office day reserves a
+// desk. WFH or a full day off on a day that was planned in the office
+// replaces the office record and releases its desk, once it no longer waits
+// for approval; decideLeave syncs it again when it's approved.
+func (a *App) syncDeskBooking(ctx context.Context, leave *models.Leave, email string) {
+	switch {
+	case leave.LeaveType == "IN_OFFICE":
+		if a.deskBooking == nil {
+			return
+		}
+		if err := a.deskBooking.Book(ctx, a.deskBookingFor(leave, email)); err != nil {
+			logger.Error("Failed to book a desk for %s on %s: %v", leave.Username, leave.StartTime.Format("2006-01-02"), err)
+		}
+
+	case (leave.LeaveType == "WFH" || isDayOff(leave.LeaveType)) && leave.Status != models.LeaveStatusPending:
+		last := leave.EndTime.Format("2006-01-02")
+		for day := leave.StartTime; day.Format("2006-01-02") <= last; day = day.AddDate(0, 0, 1) {
+			planned, err := a.leaveRepo.FindByUserAndDate(leave.Username, day)
+			if err != nil {
+				logger.Error("Failed to check office plans for %s: %v", leave.Username, err)
+				return
+			}
+			for i := range planned {
+				office := &planned[i]
+				if office.LeaveType != "IN_OFFICE" || a.checkPeriodLock(office) != nil {
+					continue
+				}
+				if err := a.leaveRepo.Cancel(office.ID, "system"); err != nil {
+					logger.Error("Failed to remove superseded office day %d: %v", office.ID, err)
+					continue
+				}
+				a.audit("system", "superseded", office.ID, office, nil)
+				a.releaseDesk(ctx, office, email)
+			}
+		}
+	}
+}
+
+// releaseDesk cancels the desk reserved for an office day that is no longer
+// happening.
+func (a *App) releaseDesk(ctx context.Context, office *models.Leave, email string) {
+	if a.deskBooking == nil || office.LeaveType != "IN_OFFICE" {
+		return
+	}
+	if err := a.deskBooking.Cancel(ctx, a.deskBookingFor(office, email)); err != nil {
+		logger.Error("Failed to cancel desk for %s on %s: %v", office.Username, office.StartTime.Format("2006-01-02"), err)
+	}
+}
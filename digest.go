@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+	"slack-leaves-ai-agent/services"
+
+	"github.com/slack-go/slack"
+)
+
+// defaultDigestSchedule posts the availability digest at 9 AM on weekdays.
+const defaultDigestSchedule = "0 9 * * 1-5"
+
+// digestGroups orders the availability digest's sections. Types whose
+// Digest is none of these don't appear in it.
+var digestGroups = []struct {
+	title  string
+	digest string
+}{
+	{"🌴 *On leave*", models.DigestOnLeave},
+	{"🏠 *Working from home*", models.DigestRemote},
+	{"⏰ *Arriving late*", models.DigestLate},
+	{"🏃 *Leaving early*", models.DigestEarly},
+}
+
+// postAvailabilityDigest posts the day's availability to a channel, as
+// scheduled by its digest settings.
+func (a *App) postAvailabilityDigest(ctx context.Context, digest *models.ChannelDigest, now time.Time) {
+	if !a.featureEnabled(flagDigests) {
+		return
+	}
+
+	var members map[string]bool
+	if digest.MembersOnly {
+		var err error
+		if members, err = a.channelMemberUsernames(ctx, digest.ChannelID); err != nil {
+			logger.Error("Failed to load members of %s for its digest: %v", digest.ChannelID, err)
+			return
+		}
+	}
+
+	text, err := a.availabilityDigestText(now, members)
+	if err != nil {
+		logger.Error("Failed to build digest for %s: %v", digest.ChannelID, err)
+		return
+	}
+	_, _, err = a.slackClient.PostMessageContext(ctx, digest.ChannelID, slack.MsgOptionText(text, false))
+	if err != nil {
+		logger.Error("Failed to post digest to %s: %v", digest.ChannelID, err)
+	}
+}
+
+// availabilityDigestText lists who's away on day, by kind of absence. When
+// members isn't nil, only the usernames in it are listed.
+func (a *App) availabilityDigestText(day time.Time, members map[string]bool) (string, error) {
+	leaves, err := a.leaveRepo.ListOnDay(day)
+	if err != nil {
+		return "", fmt.Errorf("failed to load leave: %v", err)
+	}
+
+	lines := []string{fmt.Sprintf("☀️ *Who's around, %s*", day.Format("Monday, Jan 2"))}
+	listed := 0
+	for _, group := range digestGroups {
+		var entries []string
+		for i := range leaves {
+			leave := &leaves[i]
+			info, _ := models.FindLeaveType(leave.LeaveType)
+			if info.Digest != group.digest || (members != nil && !members[leave.Username]) {
+				continue
+			}
+			entries = append(entries, availabilityLine(leave.Username, leave.LeaveType, leave.StartTime, leave.EndTime, leave.Reason))
+		}
+		if len(entries) > 0 {
+			lines = append(lines, "", group.title)
+			lines = append(lines, entries...)
+			listed += len(entries)
+		}
+	}
+	if listed == 0 {
+		lines = append(lines, "Everyone's around today 🎉")
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// channelMemberUsernames returns the usernames of a channel's members who
+// are in the employees table.
+func (a *App) channelMemberUsernames(ctx context.Context, channelID string) (map[string]bool, error) {
+	ids := make(map[string]bool)
+	params := &slack.GetUsersInConversationParameters{ChannelID: channelID, Limit: 1000}
+	for {
+		page, cursor, err := a.slackClient.GetUsersInConversationContext(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range page {
+			ids[id] = true
+		}
+		if cursor == "" {
+			break
+		}
+		params.Cursor = cursor
+	}
+
+	employees, err := a.employeeRepo.List(true)
+	if err != nil {
+		return nil, err
+	}
+	members := make(map[string]bool)
+	for _, employee := range employees {
+		if ids[employee.SlackUserID] {
+			members[employee.Username] = true
+		}
+	}
+	return members, nil
+}
+
+// resolveChannelIDArg accepts a channel mention ("<#C123|general>") or a
+// channel ID.
+func resolveChannelIDArg(arg string) (string, error) {
+	if strings.HasPrefix(arg, "<#") && strings.HasSuffix(arg, ">") {
+		id, _, _ := strings.Cut(strings.TrimSuffix(strings.TrimPrefix(arg, "<#"), ">"), "|")
+		return id, nil
+	}
+	if strings.HasPrefix(arg, "C") || strings.HasPrefix(arg, "G") {
+		return arg, nil
+	}
+	return "", fmt.Errorf("expected a channel mention, got %q", arg)
+}
+
+// runDigestCommand handles `/admin-leave digest list`,
+// `digest add #channel ["CRON"] [tz=ZONE] [members]`, `digest remove
+// #channel` and `digest preview [#channel]`.
+func (a *App) runDigestCommand(actor string, args []string) (string, error) {
+	if len(args) == 0 {
+		return adminLeaveUsage, nil
+	}
+
+	switch args[0] {
+	case "list":
+		digests, err := a.digestRepo.List()
+		if err != nil {
+			return "", fmt.Errorf("error loading digests: %v", err)
+		}
+		if len(digests) == 0 {
+			return "No channel gets the daily digest. Add one with `/admin-leave digest add #channel`.", nil
+		}
+		lines := []string{"☀️ *Daily availability digests*"}
+		for _, digest := range digests {
+			line := fmt.Sprintf("• <#%s> `%s`", digest.ChannelID, digest.Schedule)
+			if digest.Timezone != "" {
+				line += " " + digest.Timezone
+			}
+			if digest.MembersOnly {
+				line += ", channel members only"
+			}
+			lines = append(lines, line)
+		}
+		return strings.Join(lines, "\n"), nil
+
+	case "add":
+		if len(args) < 2 {
+			return adminLeaveUsage, nil
+		}
+		channelID, err := resolveChannelIDArg(args[1])
+		if err != nil {
+			return "", err
+		}
+		digest := &models.ChannelDigest{ChannelID: channelID, Schedule: defaultDigestSchedule, UpdatedBy: actor}
+		for _, option := range args[2:] {
+			switch {
+			case option == "members":
+				digest.MembersOnly = true
+			case strings.HasPrefix(option, "tz="):
+				digest.Timezone = strings.TrimPrefix(option, "tz=")
+				if _, err := time.LoadLocation(digest.Timezone); err != nil {
+					return "", fmt.Errorf("unknown timezone %q (use an IANA name like Europe/London)", digest.Timezone)
+				}
+			default:
+				schedule, err := services.ParseCron(option)
+				if err != nil {
+					return "", err
+				}
+				digest.Schedule = schedule.String()
+			}
+		}
+		if !a.channelAllowed(channelID) {
+			return "", fmt.Errorf("<#%s> isn't one of the staging channels", channelID)
+		}
+		if err := a.digestRepo.Upsert(digest); err != nil {
+			return "", fmt.Errorf("error saving digest: %v", err)
+		}
+		a.audit(actor, "digest_set", 0, nil, digest)
+		return fmt.Sprintf("☀️ <#%s> will get the daily digest on schedule `%s`. Make sure the bot is in the channel.",
+			channelID, digest.Schedule), nil
+
+	case "remove":
+		if len(args) != 2 {
+			return adminLeaveUsage, nil
+		}
+		channelID, err := resolveChannelIDArg(args[1])
+		if err != nil {
+			return "", err
+		}
+		if err := a.digestRepo.Delete(channelID); err != nil {
+			return "", err
+		}
+		a.audit(actor, "digest_remove", 0, map[string]string{"channel_id": channelID}, nil)
+		return fmt.Sprintf("🗑️ <#%s> no longer gets the daily digest.", channelID), nil
+
+	case "preview":
+		now := time.Now().In(a.config.Timezone)
+		var members map[string]bool
+		if len(args) == 2 {
+			channelID, err := resolveChannelIDArg(args[1])
+			if err != nil {
+				return "", err
+			}
+			if members, err = a.channelMemberUsernames(context.Background(), channelID); err != nil {
+				return "", fmt.Errorf("error loading channel members: %v", err)
+			}
+		}
+		return a.availabilityDigestText(now, members)
+	}
+	return adminLeaveUsage, nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// slackbotUserID is Slackbot, which users.list returns as a regular member.
+const slackbotUserID = "USLACKBOT"
+
+// runDirectorySync keeps the employees table in step with users.list, once
+// at startup and then every SLACK_SYNC_INTERVAL_HOURS. user_change events
+// cover most changes as they happen; the sync catches the ones missed while
+// the bot was down and fills in profiles of people who've never posted.
+func (a *App) runDirectorySync(ctx context.Context) {
+	if a.config.SlackSyncInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(a.config.SlackSyncInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := a.syncDirectory(ctx); err != nil {
+			logger.Error("Failed to sync the employee directory from Slack: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// syncDirectory runs every Slack account through handleUserChange, which
+// writes the profile to the employees table and deactivates or reactivates
+// the employee to match. Emails are only there with the users:read.email
+// scope.
+func (a *App) syncDirectory(ctx context.Context) error {
+	users, err := a.slackClient.GetUsersContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	synced := 0
+	for i := range users {
+		user := &users[i]
+		if user.IsAppUser || user.ID == slackbotUserID {
+			continue
+		}
+		a.handleUserChange(ctx, user)
+		synced++
+	}
+
+	logger.Info("Synced %d Slack accounts to the employee directory", synced)
+	return nil
+}
+
+func slackFullName(user *slack.User) string {
+	if user.RealName != "" {
+		return user.RealName
+	}
+	return user.Profile.RealName
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"slack-leaves-ai-agent/repository"
+
+	"github.com/slack-go/slack"
+)
+
+// viewDetailsActionID is the "View details" button under a query answer.
+// Its value is the JSON repository.LeaveFilter of the records behind it.
+const viewDetailsActionID = "query_view_details"
+
+// exportCSVActionID is the "Export CSV" button next to it, with the same
+// value.
+const exportCSVActionID = "query_export_csv"
+
+// maxDetailRecords keeps the drill-down within one Slack message.
+const maxDetailRecords = 50
+
+// maxExportRecords caps a CSV export.
+const maxExportRecords = 10000
+
+// maxButtonValueLength is Slack's limit on a button's value.
+const maxButtonValueLength = 2000
+
+// withDetails adds buttons offering the records behind an answer, listed or
+// as a CSV file, unless the filter doesn't fit in a button.
+func withDetails(blocks []slack.Block, filter repository.LeaveFilter) []slack.Block {
+	value, err := json.Marshal(filter)
+	if err != nil || len(value) > maxButtonValueLength {
+		return blocks
+	}
+	details := slack.NewButtonBlockElement(viewDetailsActionID, string(value),
+		slack.NewTextBlockObject("plain_text", "🔍 View details", true, false))
+	export := slack.NewButtonBlockElement(exportCSVActionID, string(value),
+		slack.NewTextBlockObject("plain_text", "📄 Export CSV", true, false))
+	return append(blocks, slack.NewActionBlock("query_details", details, export))
+}
+
+func detailsInteraction(callback slack.InteractionCallback) bool {
+	for _, action := range callback.ActionCallback.BlockActions {
+		if action.ActionID == viewDetailsActionID {
+			return true
+		}
+	}
+	return false
+}
+
+// handleDetailsAction lists the records behind an answer to whoever asked,
+// so the channel isn't flooded.
+func (a *App) handleDetailsAction(callback slack.InteractionCallback) {
+	for _, action := range callback.ActionCallback.BlockActions {
+		if action.ActionID != viewDetailsActionID {
+			continue
+		}
+
+		var filter repository.LeaveFilter
+		if err := json.Unmarshal([]byte(action.Value), &filter); err != nil {
+			logger.Error("Invalid details filter %q: %v", action.Value, err)
+			continue
+		}
+
+		text, err := a.detailsText(filter)
+		if err != nil {
+			logger.Error("Failed to list details: %v", err)
+			text = "❌ Couldn't load the records, please try again."
+		}
+		_, err = a.slackClient.PostEphemeral(callback.Channel.ID, callback.User.ID, slack.MsgOptionText(text, false))
+		if err != nil {
+			logger.Error("Failed to post details: %v", err)
+		}
+	}
+}
+
+func (a *App) detailsText(filter repository.LeaveFilter) (string, error) {
+	leaves, err := a.leaveRepo.ListMatching(filter, maxDetailRecords+1)
+	if err != nil {
+		return "", err
+	}
+	if len(leaves) == 0 {
+		return "No records behind this answer any more.", nil
+	}
+
+	lines := []string{"🔍 *Records behind this answer*"}
+	for i, leave := range leaves {
+		if i == maxDetailRecords {
+			lines = append(lines, fmt.Sprintf("_Showing the first %d; ask a narrower question to see the rest._", maxDetailRecords))
+			break
+		}
+		when := leave.StartTime.Format("Mon Jan 2, 2006")
+		if leave.EndTime.Format("2006-01-02") != leave.StartTime.Format("2006-01-02") {
+			when += " to " + leave.EndTime.Format("Mon Jan 2, 2006")
+		}
+		lines = append(lines, fmt.Sprintf("• *%s* – %s, %s (%s)", leave.Username, leave.LeaveType, when, leave.Duration))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func exportCSVInteraction(callback slack.InteractionCallback) bool {
+	for _, action := range callback.ActionCallback.BlockActions {
+		if action.ActionID == exportCSVActionID {
+			return true
+		}
+	}
+	return false
+}
+
+// handleExportCSVAction uploads the records behind an answer as a CSV file
+// to where the answer was posted, in its thread if it has one.
+func (a *App) handleExportCSVAction(callback slack.InteractionCallback) {
+	for _, action := range callback.ActionCallback.BlockActions {
+		if action.ActionID != exportCSVActionID {
+			continue
+		}
+		if !a.hasScope(scopeFilesWrite) {
+			_, err := a.slackClient.PostEphemeral(callback.Channel.ID, callback.User.ID,
+				slack.MsgOptionText(missingScopeText(scopeFilesWrite), false))
+			if err != nil {
+				logger.Error("Failed to post export error: %v", err)
+			}
+			continue
+		}
+
+		var filter repository.LeaveFilter
+		if err := json.Unmarshal([]byte(action.Value), &filter); err != nil {
+			logger.Error("Invalid export filter %q: %v", action.Value, err)
+			continue
+		}
+
+		if err := a.uploadQueryCSV(callback, filter); err != nil {
+			logger.Error("Failed to export query results: %v", err)
+			_, err = a.slackClient.PostEphemeral(callback.Channel.ID, callback.User.ID,
+				slack.MsgOptionText("❌ Couldn't export the records, please try again.", false))
+			if err != nil {
+				logger.Error("Failed to post export error: %v", err)
+			}
+		}
+	}
+}
+
+func (a *App) uploadQueryCSV(callback slack.InteractionCallback, filter repository.LeaveFilter) error {
+	leaves, err := a.leaveRepo.ListMatching(filter, maxExportRecords+1)
+	if err != nil {
+		return fmt.Errorf("error listing records: %v", err)
+	}
+
+	comment := fmt.Sprintf("📄 <@%s> exported %d records behind this answer", callback.User.ID, len(leaves))
+	if len(leaves) > maxExportRecords {
+		leaves = leaves[:maxExportRecords]
+		comment = fmt.Sprintf("📄 <@%s> exported the first %d records behind this answer; ask a narrower question for the rest",
+			callback.User.ID, maxExportRecords)
+	}
+	if len(leaves) == 0 {
+		_, err := a.slackClient.PostEphemeral(callback.Channel.ID, callback.User.ID,
+			slack.MsgOptionText("No records behind this answer any more.", false))
+		return err
+	}
+
+	data, err := leavesCSV(leaves)
+	if err != nil {
+		return fmt.Errorf("error writing CSV: %v", err)
+	}
+
+	_, err = a.slackClient.UploadFileV2(slack.UploadFileV2Parameters{
+		Filename:        exportFilename(filter),
+		Title:           "Query results",
+		Content:         string(data),
+		FileSize:        len(data),
+		Channel:         callback.Channel.ID,
+		ThreadTimestamp: callback.Message.ThreadTimestamp,
+		InitialComment:  comment,
+	})
+	return err
+}
+
+// exportFilename names an export after the days it covers, when it has a
+// period. filter.End is exclusive.
+func exportFilename(filter repository.LeaveFilter) string {
+	if filter.Start.IsZero() || filter.End.IsZero() {
+		return "leaves.csv"
+	}
+	return fmt.Sprintf("leaves-%s-%s.csv", filter.Start.Format("20060102"), filter.End.AddDate(0, 0, -1).Format("20060102"))
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"slack-leaves-ai-agent/models"
+
+	"github.com/slack-go/slack"
+)
+
+// resolveHRReviewActionID is the action ID of the button on HR's copy of an
+// escalated record. The button value is the review ID.
+const resolveHRReviewActionID = "hr_review_resolve"
+
+// maxHRReviews is how many open reviews `/leave-report hr` lists.
+const maxHRReviews = 30
+
+// hrChannel is where escalated records are posted: HR_CHANNEL_ID, or the
+// admin channel.
+func (a *App) hrChannel() string {
+	if a.config.HRChannelID != "" {
+		return a.config.HRChannelID
+	}
+	return a.adminChannel()
+}
+
+// escalateToHR adds a record to HR's review queue when a policy check says
+// HR should see it, and posts it to the HR channel. The manager's approval
+// goes ahead as usual; HR reviews alongside it.
+func (a *App) escalateToHR(ctx context.Context, leave *models.Leave, violations []PolicyViolation) {
+	var reasons []string
+	for _, v := range violations {
+		if v.HRMessage != "" {
+			reasons = append(reasons, v.HRMessage)
+		}
+	}
+	if len(reasons) == 0 {
+		return
+	}
+
+	review := &models.HRReview{LeaveID: leave.ID, Username: leave.Username, Reasons: reasons}
+	if err := a.hrReviewRepo.Create(review); err != nil {
+		logger.Error("Failed to queue leave %d for HR review: %v", leave.ID, err)
+		return
+	}
+	a.audit("system", "hr_review_opened", leave.ID, nil, review)
+
+	channel := a.hrChannel()
+	if channel == "" {
+		logger.Info("Leave %d of %s is waiting for HR review: %s", leave.ID, leave.Username, strings.Join(reasons, "; "))
+		return
+	}
+	text := hrReviewText(review, leave, a.approverOf(leave.Username))
+	resolve := slack.NewButtonBlockElement(resolveHRReviewActionID, strconv.FormatInt(review.ID, 10),
+		slack.NewTextBlockObject("plain_text", "Mark reviewed", false, false))
+	_, _, err := a.slackClient.PostMessageContext(ctx, channel,
+		slack.MsgOptionText(text, false),
+		slack.MsgOptionBlocks(
+			slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", text, false, false), nil, nil),
+			slack.NewActionBlock("hr_review_"+strconv.FormatInt(review.ID, 10), resolve),
+		),
+	)
+	if err != nil {
+		logger.Error("Failed to post HR review %d: %v", review.ID, err)
+	}
+}
+
+// hrReviewText is HR's copy of an escalated record: why it was escalated
+// and where the manager's approval stands. Unlike the approver's message it
+// says whether the record is a sick day, since that's what HR follows up on.
+func hrReviewText(review *models.HRReview, leave *models.Leave, approverID string) string {
+	kind := strings.ToLower(strings.ReplaceAll(leave.LeaveType, "_", " "))
+	if leave.Sick {
+		kind = "sick leave"
+	}
+	lines := []string{
+		fmt.Sprintf("🗂️ *HR review #%d:* *%s* recorded %s from %s to %s (%s)",
+			review.ID, leave.Username, kind,
+			leave.StartTime.Format("Mon Jan 2"), leave.EndTime.Format("Mon Jan 2"), leave.Duration),
+	}
+	for _, reason := range review.Reasons {
+		lines = append(lines, "• "+reason)
+	}
+	switch {
+	case leave.Status == models.LeaveStatusPending && approverID != "":
+		lines = append(lines, fmt.Sprintf("_Waiting for <@%s> to approve._", approverID))
+	case leave.Status == models.LeaveStatusPending:
+		lines = append(lines, "_Waiting for approval._")
+	default:
+		lines = append(lines, "_No approval needed; the record is already on the calendar._")
+	}
+	return strings.Join(lines, "\n")
+}
+
+func hrReviewInteraction(callback slack.InteractionCallback) bool {
+	for _, action := range callback.ActionCallback.BlockActions {
+		if action.ActionID == resolveHRReviewActionID {
+			return true
+		}
+	}
+	return false
+}
+
+// handleHRReviewAction closes a review from its button and replaces the
+// button with who reviewed it. Only HR and admins can.
+func (a *App) handleHRReviewAction(callback slack.InteractionCallback) {
+	for _, action := range callback.ActionCallback.BlockActions {
+		if action.ActionID != resolveHRReviewActionID {
+			continue
+		}
+		id, err := strconv.ParseInt(action.Value, 10, 64)
+		if err != nil {
+			logger.Error("Invalid HR review button value %q", action.Value)
+			continue
+		}
+		if !a.canLogForOthers(callback.User.ID) {
+			a.replyFeedback(callback, "❌ Only HR and admins can mark a review done.")
+			continue
+		}
+		text, err := a.resolveHRReview("slack:"+callback.User.ID, id)
+		if err != nil {
+			a.replyFeedback(callback, "❌ "+err.Error())
+			continue
+		}
+
+		var blocks []slack.Block
+		if len(callback.Message.Blocks.BlockSet) > 0 {
+			blocks = append(blocks, callback.Message.Blocks.BlockSet[0])
+		}
+		blocks = append(blocks, slack.NewContextBlock("", slack.NewTextBlockObject("mrkdwn", text, false, false)))
+		_, _, err = a.slackClient.PostMessage(callback.Channel.ID,
+			slack.MsgOptionReplaceOriginal(callback.ResponseURL),
+			slack.MsgOptionText(callback.Message.Text, false),
+			slack.MsgOptionBlocks(blocks...))
+		if err != nil {
+			logger.Error("Failed to update HR review %d: %v", id, err)
+		}
+	}
+}
+
+func (a *App) resolveHRReview(actor string, id int64) (string, error) {
+	ok, err := a.hrReviewRepo.Resolve(id, actor)
+	if err != nil {
+		return "", fmt.Errorf("error resolving review: %v", err)
+	}
+	if !ok {
+		return "", fmt.Errorf("review #%d doesn't exist or was already marked reviewed", id)
+	}
+	a.audit(actor, "hr_review_resolved", 0, nil, map[string]int64{"review_id": id})
+	return fmt.Sprintf("✅ Review #%d marked reviewed by <@%s>.", id, strings.TrimPrefix(actor, "slack:")), nil
+}
+
+// runHRReviewReport handles `/leave-report hr` and
+// `/leave-report hr resolve ID`.
+func (a *App) runHRReviewReport(actor string, args []string) (string, error) {
+	if len(args) == 3 && args[1] == "resolve" {
+		id, err := strconv.ParseInt(strings.TrimPrefix(args[2], "#"), 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid review ID %q", args[2])
+		}
+		return a.resolveHRReview(actor, id)
+	}
+	if len(args) != 1 {
+		return leaveReportUsage, nil
+	}
+
+	reviews, err := a.hrReviewRepo.ListOpen(maxHRReviews)
+	if err != nil {
+		return "", fmt.Errorf("error loading HR reviews: %v", err)
+	}
+	if len(reviews) == 0 {
+		return "Nothing is waiting for HR review.", nil
+	}
+	lines := []string{"🗂️ *Waiting for HR review*"}
+	for _, review := range reviews {
+		lines = append(lines, fmt.Sprintf("• #%d *%s* (%s): %s",
+			review.ID, review.Username, review.CreatedAt.Format("Jan 2"), strings.Join(review.Reasons, "; ")))
+	}
+	if len(reviews) == maxHRReviews {
+		lines = append(lines, fmt.Sprintf("_Showing the oldest %d._", maxHRReviews))
+	}
+	lines = append(lines, "Close one with `/leave-report hr resolve ID`.")
+	return strings.Join(lines, "\n"), nil
+}
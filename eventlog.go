@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+
+	"github.com/slack-go/slack"
+)
+
+// pipelineEventRetention is how long pipeline events are kept.
+const pipelineEventRetention = 30 * 24 * time.Hour
+
+// maxLogEvents is how many events /latebot-log looks at.
+const maxLogEvents = 100
+
+const eventLogUsage = "Usage: `/latebot-log` (latest messages), `/latebot-log @user` or `/latebot-log today`"
+
+// logEvent records a step of processing ev. A failure to record is logged
+// and otherwise ignored; it mustn't hold up the message.
+func (a *App) logEvent(ev *slack.MessageEvent, stage, detail string, leaveID int64) {
+	err := a.eventRepo.Record(&models.PipelineEvent{
+		Channel:   ev.Channel,
+		MessageTS: ev.Timestamp,
+		UserID:    ev.User,
+		Stage:     stage,
+		Detail:    detail,
+		LeaveID:   leaveID,
+	})
+	if err != nil {
+		logger.Error("Failed to record %s event for %s: %v", stage, ev.Timestamp, err)
+	}
+}
+
+// handleEventLogCommand shows admins what happened to recent messages, for
+// "the bot ignored my message" complaints.
+func handleEventLogCommand(app *App, cmd slack.SlashCommand) {
+	reply := func(text string) {
+		_, err := app.slackClient.PostEphemeral(cmd.ChannelID, cmd.UserID, slack.MsgOptionText(text, false))
+		if err != nil {
+			logger.Error("Failed to post event log reply: %v", err)
+		}
+	}
+
+	if !app.isAdmin(cmd.UserID) {
+		reply("❌ You are not allowed to use this command.")
+		return
+	}
+
+	var userID string
+	var since time.Time
+	title := "latest messages"
+	switch arg := strings.TrimSpace(cmd.Text); {
+	case arg == "":
+	case isHelpRequest(arg):
+		reply(eventLogUsage)
+		return
+	case strings.EqualFold(arg, "today"):
+		now := time.Now().In(app.config.Timezone)
+		since = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, app.config.Timezone).UTC()
+		title = "today"
+	default:
+		id, err := resolveUserIDArg(arg)
+		if err != nil {
+			reply("❌ " + err.Error() + "\n" + eventLogUsage)
+			return
+		}
+		userID = id
+		title = fmt.Sprintf("<@%s>", id)
+	}
+
+	events, err := app.eventRepo.List(userID, since, maxLogEvents)
+	if err != nil {
+		logger.Error("Failed to load pipeline events: %v", err)
+		reply("❌ Failed to load the event log.")
+		return
+	}
+	if len(events) == 0 {
+		reply(fmt.Sprintf("No messages processed for %s.", title))
+		return
+	}
+	reply(fmt.Sprintf("🔎 *Message pipeline, %s*\n%s", title, eventLogText(events, app.config.Timezone)))
+}
+
+// eventLogText lists events one message per line, newest message first,
+// with its stages in order: "received → parsed (2 items) → saved #12 → ...".
+func eventLogText(events []models.PipelineEvent, loc *time.Location) string {
+	type message struct {
+		first  models.PipelineEvent
+		stages []string
+	}
+	var order []string
+	messages := make(map[string]*message)
+	// Events come newest first; walk them oldest first to keep stage order
+	for i := len(events) - 1; i >= 0; i-- {
+		event := events[i]
+		key := event.Channel + ":" + event.MessageTS
+		m, ok := messages[key]
+		if !ok {
+			m = &message{first: event}
+			messages[key] = m
+			order = append(order, key)
+		}
+
+		stage := "`" + event.Stage + "`"
+		if event.LeaveID != 0 {
+			stage += fmt.Sprintf(" #%d", event.LeaveID)
+		}
+		if event.Detail != "" {
+			stage += " (" + event.Detail + ")"
+		}
+		m.stages = append(m.stages, stage)
+	}
+
+	lines := make([]string, 0, len(order))
+	for i := len(order) - 1; i >= 0; i-- {
+		m := messages[order[i]]
+		lines = append(lines, fmt.Sprintf("• %s <@%s> in <#%s>: %s",
+			m.first.CreatedAt.In(loc).Format("Jan 2 3:04 PM"), m.first.UserID, m.first.Channel, strings.Join(m.stages, " → ")))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// runPipelineEventCleanup deletes pipeline events past their retention once
+// a day.
+func (a *App) runPipelineEventCleanup(ctx context.Context) {
+	ticker := time.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := a.eventRepo.DeleteBefore(time.Now().UTC().Add(-pipelineEventRetention))
+			if err != nil {
+				logger.Error("Failed to delete old pipeline events: %v", err)
+				continue
+			}
+			if deleted > 0 {
+				logger.Info("Deleted %d pipeline events older than %d days", deleted, int(pipelineEventRetention.Hours()/24))
+			}
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+	"slack-leaves-ai-agent/services"
+
+	"github.com/slack-go/slack"
+)
+
+// explainActionID is the "Why?" button under confirmations. Its value is the
+// record's ID.
+const explainActionID = "explain_parse"
+
+func explainButton(id int64) *slack.ButtonBlockElement {
+	return slack.NewButtonBlockElement(explainActionID, strconv.FormatInt(id, 10),
+		slack.NewTextBlockObject("plain_text", "🤔 Why?", true, false))
+}
+
+func explainInteraction(callback slack.InteractionCallback) bool {
+	for _, action := range callback.ActionCallback.BlockActions {
+		if action.ActionID == explainActionID {
+			return true
+		}
+	}
+	return false
+}
+
+// newParseTrace starts the trace of a record parsed from a message.
+func newParseTrace(source string, response *services.LeaveResponse, loc *time.Location) *models.ParseTrace {
+	return &models.ParseTrace{
+		Source:     source,
+		ParsedAt:   time.Now().In(loc),
+		Timezone:   loc.String(),
+		Phrases:    response.Phrases,
+		Defaults:   response.Defaults,
+		Confidence: response.Confidence,
+	}
+}
+
+// handleExplainAction answers "Why?" with how the record was parsed. The
+// confirmation is public, so anyone who can see it may ask.
+func (a *App) handleExplainAction(callback slack.InteractionCallback) {
+	for _, action := range callback.ActionCallback.BlockActions {
+		if action.ActionID != explainActionID {
+			continue
+		}
+		id, err := strconv.ParseInt(action.Value, 10, 64)
+		if err != nil {
+			continue
+		}
+		leave, err := a.leaveRepo.GetByID(id)
+		if err != nil {
+			a.replyFeedback(callback, "❌ That record no longer exists.")
+			continue
+		}
+		parsed, err := a.parsedLeave(id)
+		if err != nil {
+			logger.Error("Failed to load the parse of leave %d: %v", id, err)
+			a.replyFeedback(callback, "❌ Couldn't load the explanation, please try again.")
+			continue
+		}
+		if parsed == nil {
+			a.replyFeedback(callback, fmt.Sprintf("🤷 I have no notes on how #%d was read; it was recorded before I kept them.", id))
+			continue
+		}
+		a.replyFeedback(callback, parseExplanationText(leave, parsed))
+	}
+}
+
+// parsedLeave returns the record as the parser produced it, with its trace,
+// from the audit entry of its creation. It returns nil when no entry has a
+// trace.
+func (a *App) parsedLeave(id int64) (*models.Leave, error) {
+	entries, err := a.auditRepo.ListByLeave(id)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.Before != "" || entry.After == "" {
+			continue
+		}
+		var leave models.Leave
+		if err := json.Unmarshal([]byte(entry.After), &leave); err != nil {
+			continue
+		}
+		if leave.Trace != nil {
+			return &leave, nil
+		}
+	}
+	return nil, nil
+}
+
+// parseExplanationText explains parsed, the record as first recorded, and
+// notes how current differs from it.
+func parseExplanationText(current, parsed *models.Leave) string {
+	trace := parsed.Trace
+	loc, err := time.LoadLocation(trace.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	start, end := parsed.StartTime.In(loc), parsed.EndTime.In(loc)
+
+	lines := []string{fmt.Sprintf("🤔 *Why #%d is %s*", current.ID, parsed.LeaveType)}
+	if info, ok := models.FindLeaveType(parsed.LeaveType); ok {
+		lines[0] += " (" + strings.ToLower(info.Description[:1]) + info.Description[1:] + ")"
+	}
+
+	from := "your message"
+	if trace.Source == "shortcut" {
+		from = "the message logged with the shortcut"
+	}
+	if len(trace.Phrases) > 0 {
+		quoted := make([]string, 0, len(trace.Phrases))
+		for _, phrase := range trace.Phrases {
+			quoted = append(quoted, "“"+phrase+"”")
+		}
+		lines = append(lines, fmt.Sprintf("• I read it from %s: %s", from, strings.Join(quoted, ", ")))
+	} else {
+		lines = append(lines, fmt.Sprintf("• I read it from %s.", from))
+	}
+
+	span := fmt.Sprintf("%s to %s", start.Format("Mon Jan 2, 3:04 PM"), end.Format("Mon Jan 2, 3:04 PM"))
+	if isDayOff(parsed.LeaveType) {
+		span = fmt.Sprintf("%s to %s, whole days", start.Format("Mon Jan 2"), end.Format("Mon Jan 2"))
+	}
+	lines = append(lines, fmt.Sprintf("• Dates: %s (%s), counting from %s when it was read",
+		span, trace.Timezone, trace.ParsedAt.In(loc).Format("Mon Jan 2, 3:04 PM")))
+	for _, assumed := range trace.Defaults {
+		lines = append(lines, "• Assumed: "+assumed)
+	}
+	lines = append(lines, fmt.Sprintf("• Confidence: %.0f%%", 100*trace.Confidence))
+	for _, step := range trace.Steps {
+		lines = append(lines, "• Then: "+step)
+	}
+
+	if current.LeaveType != parsed.LeaveType || !current.StartTime.Equal(parsed.StartTime) || !current.EndTime.Equal(parsed.EndTime) {
+		lines = append(lines, fmt.Sprintf("✏️ It's been changed since; it's now %s.", formatLeaveLine(current)))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"slack-leaves-ai-agent/models"
+)
+
+// Feature flags admins can toggle per workspace at runtime.
+const (
+	flagApprovals = "approvals"
+	flagBalances  = "balances"
+	flagDigests   = "digests"
+	flagAnalytics = "analytics"
+)
+
+// featureFlags lists every flag with what it covers and its default. A flag
+// only switches a capability off; the env settings that enable it, such as
+// APPROVAL_LEAVE_TYPES or ACCRUAL_STATEMENTS, still apply.
+var featureFlags = []struct {
+	name        string
+	description string
+	enabled     bool
+}{
+	{flagApprovals, "holding configured leave types for a manager's approval", true},
+	{flagBalances, "showing balances to employees: monthly statements and balances in replies", true},
+	{flagDigests, "the weekly parsing digest, managers' weekly one-pagers and daily availability digests", true},
+	{flagAnalytics, "trend and comparison answers to /query", true},
+}
+
+// errAnalyticsOff answers analytics questions while the flag is off.
+var errAnalyticsOff = errors.New("trends and comparisons are turned off in this workspace")
+
+func isKnownFeatureFlag(flag string) bool {
+	for _, f := range featureFlags {
+		if f.name == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// featureEnabled reports whether flag is on in this workspace: its override
+// if an admin set one, otherwise its default. If the override can't be
+// read, the default applies.
+func (a *App) featureEnabled(flag string) bool {
+	def := false
+	for _, f := range featureFlags {
+		if f.name == flag {
+			def = f.enabled
+		}
+	}
+
+	override, err := a.featureFlagRepo.Get(a.teamID(), flag)
+	if err != nil {
+		logger.Error("Failed to load feature flag %s: %v", flag, err)
+		return def
+	}
+	if override == nil {
+		return def
+	}
+	return override.Enabled
+}
+
+// runFeatureFlagCommand handles `/admin-leave flags` and
+// `/admin-leave flag NAME on|off|default`.
+func (a *App) runFeatureFlagCommand(actor string, args []string) (string, error) {
+	teamID := a.teamID()
+	if teamID == "" {
+		return "", fmt.Errorf("couldn't look up this workspace's ID")
+	}
+
+	if args[0] == "flags" {
+		if len(args) != 1 {
+			return adminLeaveUsage, nil
+		}
+		overrides, err := a.featureFlagRepo.List(teamID)
+		if err != nil {
+			return "", fmt.Errorf("error loading feature flags: %v", err)
+		}
+		byFlag := make(map[string]models.FeatureFlag, len(overrides))
+		for _, override := range overrides {
+			byFlag[override.Flag] = override
+		}
+
+		lines := []string{"🚩 *Feature flags*"}
+		for _, f := range featureFlags {
+			enabled, source := f.enabled, "default"
+			if override, ok := byFlag[f.name]; ok {
+				enabled = override.Enabled
+				source = fmt.Sprintf("set by %s on %s", override.UpdatedBy, override.UpdatedAt.Format("Jan 2"))
+			}
+			state := "off"
+			if enabled {
+				state = "on"
+			}
+			lines = append(lines, fmt.Sprintf("• `%s` *%s* (%s): %s", f.name, state, source, f.description))
+		}
+		return strings.Join(lines, "\n"), nil
+	}
+
+	if len(args) != 3 {
+		return adminLeaveUsage, nil
+	}
+	flag, value := strings.ToLower(args[1]), strings.ToLower(args[2])
+	if !isKnownFeatureFlag(flag) {
+		return "", fmt.Errorf("unknown feature flag %q; `/admin-leave flags` lists them", args[1])
+	}
+
+	before := a.featureEnabled(flag)
+	switch value {
+	case "on", "off":
+		err := a.featureFlagRepo.Set(&models.FeatureFlag{TeamID: teamID, Flag: flag, Enabled: value == "on", UpdatedBy: actor})
+		if err != nil {
+			return "", fmt.Errorf("error saving feature flag %s: %v", flag, err)
+		}
+	case "default":
+		if err := a.featureFlagRepo.Clear(teamID, flag); err != nil {
+			return "", fmt.Errorf("error clearing feature flag %s: %v", flag, err)
+		}
+	default:
+		return adminLeaveUsage, nil
+	}
+	after := a.featureEnabled(flag)
+	a.audit(actor, "feature_flag", 0, map[string]bool{flag: before}, map[string]bool{flag: after})
+
+	state := "off"
+	if after {
+		state = "on"
+	}
+	return fmt.Sprintf("🚩 `%s` is now %s.", flag, state), nil
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"slack-leaves-ai-agent/models"
+	"slack-leaves-ai-agent/services"
+
+	"github.com/slack-go/slack"
+)
+
+// Action IDs of the 👍/👎 buttons under confirmations and query answers. The
+// button value says what is being rated: "leave:ID" or "query:TEXT".
+const (
+	feedbackUpActionID   = "feedback_up"
+	feedbackDownActionID = "feedback_down"
+)
+
+// maxParseExamples is how many few-shot examples go into each parse prompt.
+const maxParseExamples = 8
+
+// maxFeedbackQueryLength keeps query text within Slack's button value limit.
+const maxFeedbackQueryLength = 1500
+
+func feedbackBlock(blockID, target string, extra ...slack.BlockElement) slack.Block {
+	up := slack.NewButtonBlockElement(feedbackUpActionID, target,
+		slack.NewTextBlockObject("plain_text", "👍", true, false))
+	down := slack.NewButtonBlockElement(feedbackDownActionID, target,
+		slack.NewTextBlockObject("plain_text", "👎", true, false))
+	return slack.NewActionBlock(blockID, append([]slack.BlockElement{up, down}, extra...)...)
+}
+
+func leaveFeedbackTarget(id int64) string {
+	return "leave:" + strconv.FormatInt(id, 10)
+}
+
+func queryFeedbackTarget(text string) string {
+	text = strings.TrimSpace(text)
+	if runes := []rune(text); len(runes) > maxFeedbackQueryLength {
+		text = string(runes[:maxFeedbackQueryLength])
+	}
+	return "query:" + text
+}
+
+// confirmationOptions renders the confirmation for recorded leaves, each with
+// its own feedback, private details and "Why?" buttons, keeping the plain
+// text as the notification fallback.
+func confirmationOptions(leaves ...*models.Leave) []slack.MsgOption {
+	texts := make([]string, 0, len(leaves))
+	blocks := make([]slack.Block, 0, 2*len(leaves))
+	for _, leave := range leaves {
+		text := confirmationText(leave)
+		texts = append(texts, text)
+		blocks = append(blocks,
+			slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", text, false, false), nil, nil),
+			feedbackBlock("feedback_"+strconv.FormatInt(leave.ID, 10), leaveFeedbackTarget(leave.ID),
+				privateReasonButton(leave.ID), explainButton(leave.ID)),
+		)
+	}
+	return []slack.MsgOption{
+		slack.MsgOptionText(strings.Join(texts, "\n"), false),
+		slack.MsgOptionBlocks(blocks...),
+	}
+}
+
+func feedbackInteraction(callback slack.InteractionCallback) bool {
+	for _, action := range callback.ActionCallback.BlockActions {
+		if action.ActionID == feedbackUpActionID || action.ActionID == feedbackDownActionID {
+			return true
+		}
+	}
+	return false
+}
+
+// handleFeedbackAction stores a 👍/👎. A 👍 from the author of a recorded
+// leave also adds the message and its parse to the few-shot example store,
+// since they've confirmed the parse was right.
+func (a *App) handleFeedbackAction(callback slack.InteractionCallback) {
+	for _, action := range callback.ActionCallback.BlockActions {
+		rating := 0
+		switch action.ActionID {
+		case feedbackUpActionID:
+			rating = 1
+		case feedbackDownActionID:
+			rating = -1
+		default:
+			continue
+		}
+
+		feedback := &models.Feedback{
+			Target: action.Value,
+			UserID: callback.User.ID,
+			Rating: rating,
+		}
+
+		var leave *models.Leave
+		kind, value, _ := strings.Cut(action.Value, ":")
+		switch kind {
+		case "leave":
+			id, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				continue
+			}
+			leave, err = a.leaveRepo.GetByID(id)
+			if err != nil {
+				a.replyFeedback(callback, "❌ That record no longer exists.")
+				continue
+			}
+			parsed, _ := json.Marshal(map[string]string{
+				"leave_type": leave.LeaveType,
+				"start_time": leave.StartTime.Format(adminTimeLayout),
+				"end_time":   leave.EndTime.Format(adminTimeLayout),
+				"reason":     leave.Reason,
+			})
+			feedback.Kind = models.FeedbackParse
+			feedback.LeaveID = id
+			feedback.OriginalText = leave.OriginalText
+			feedback.ParsedData = string(parsed)
+		case "query":
+			feedback.Kind = models.FeedbackQuery
+			feedback.QueryText = value
+		default:
+			continue
+		}
+
+		if err := a.feedbackRepo.Record(feedback); err != nil {
+			logger.Error("Failed to record feedback: %v", err)
+			a.replyFeedback(callback, "❌ Couldn't save your feedback, please try again.")
+			continue
+		}
+		logger.Info("Feedback %+d on %s from %s", rating, feedback.Target, callback.User.ID)
+
+		if rating > 0 && leave != nil && a.isLeaveAuthor(callback.User.ID, leave) {
+			example := &models.ParseExample{
+				Message:   leave.OriginalText,
+				PostedAt:  leave.CreatedAt,
+				LeaveType: leave.LeaveType,
+				StartTime: leave.StartTime,
+				EndTime:   leave.EndTime,
+				Reason:    leave.Reason,
+				Source:    models.ExampleConfirmed,
+				LeaveID:   leave.ID,
+				CreatedBy: "slack:" + callback.User.ID,
+			}
+			if err := a.feedbackRepo.AddExample(example); err != nil {
+				logger.Error("Failed to store parse example: %v", err)
+			}
+		}
+
+		switch {
+		case rating > 0:
+			a.replyFeedback(callback, "🙏 Thanks for the feedback!")
+		case leave != nil:
+			a.replyFeedback(callback, "🙏 Thanks, an admin will review this record and correct it if needed.")
+		default:
+			a.replyFeedback(callback, "🙏 Thanks, we'll use this to improve.")
+		}
+	}
+}
+
+func (a *App) isLeaveAuthor(userID string, leave *models.Leave) bool {
+	user, err := a.slackClient.GetUserInfo(userID)
+	if err != nil {
+		logger.Error("Error getting user info: %v", err)
+		return false
+	}
+	return user.Name == leave.Username
+}
+
+func (a *App) replyFeedback(callback slack.InteractionCallback, text string) {
+	_, err := a.slackClient.PostEphemeral(callback.Channel.ID, callback.User.ID, slack.MsgOptionText(text, false))
+	if err != nil {
+		logger.Error("Failed to post feedback reply: %v", err)
+	}
+}
+
+// parseExamples feeds the example store to the parser as few-shot examples.
+func (a *App) parseExamples() []services.Example {
+	stored, err := a.feedbackRepo.ListExamples(maxParseExamples)
+	if err != nil {
+		logger.Error("Failed to load parse examples: %v", err)
+		return nil
+	}
+
+	examples := make([]services.Example, 0, len(stored))
+	for _, e := range stored {
+		examples = append(examples, services.Example{
+			Message:   e.Message,
+			PostedAt:  e.PostedAt,
+			LeaveType: e.LeaveType,
+			StartTime: e.StartTime,
+			EndTime:   e.EndTime,
+			Reason:    e.Reason,
+		})
+	}
+	return examples
+}
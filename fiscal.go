@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"slack-leaves-ai-agent/services"
+)
+
+// Reporting period presets a query may name instead of dates. They follow
+// FISCAL_YEAR_START_MONTH, SPRINT_START and SPRINT_LENGTH_DAYS.
+const (
+	periodFiscalYear = "fiscal_year"
+	periodQuarter    = "quarter" // a quarter of the fiscal year
+	periodSprint     = "sprint"
+)
+
+// today returns the current date in the workspace's timezone, at midnight
+// UTC like the dates reports are given in. Report periods are worked out
+// from it, so "this month" doesn't depend on the server's clock.
+func (a *App) today() time.Time {
+	now := time.Now().In(a.config.Timezone)
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// fiscalYearStart returns the first day of the fiscal year day falls in.
+func (a *App) fiscalYearStart(day time.Time) time.Time {
+	start := time.Date(day.Year(), a.config.FiscalYearStartMonth, 1, 0, 0, 0, 0, time.UTC)
+	if start.After(day) {
+		start = start.AddDate(-1, 0, 0)
+	}
+	return start
+}
+
+// fiscalYearLabel names the fiscal year starting at start: just the year
+// when it follows the calendar, otherwise e.g. "FY2024-25".
+func (a *App) fiscalYearLabel(start time.Time) string {
+	if a.config.FiscalYearStartMonth == time.January {
+		return fmt.Sprintf("%d", start.Year())
+	}
+	return fmt.Sprintf("FY%d-%02d", start.Year(), (start.Year()+1)%100)
+}
+
+// reportingPeriod returns the first and last day of a period preset. offset
+// counts periods from the one today is in, so -1 is the last one. The
+// current period ends today rather than in the future.
+func (a *App) reportingPeriod(period string, offset int, today time.Time) (time.Time, time.Time, error) {
+	var start, next time.Time
+	switch period {
+	case periodFiscalYear:
+		start = a.fiscalYearStart(today).AddDate(offset, 0, 0)
+		next = start.AddDate(1, 0, 0)
+	case periodQuarter:
+		yearStart := a.fiscalYearStart(today)
+		months := (today.Year()-yearStart.Year())*12 + int(today.Month()-yearStart.Month())
+		start = yearStart.AddDate(0, (months/3+offset)*3, 0)
+		next = start.AddDate(0, 3, 0)
+	case periodSprint:
+		if a.config.SprintStart.IsZero() {
+			return time.Time{}, time.Time{}, fmt.Errorf("sprints aren't set up here; ask an admin to set SPRINT_START")
+		}
+		length := a.config.SprintLengthDays
+		days := int(today.Sub(a.config.SprintStart).Hours() / 24)
+		n := days / length
+		if days%length < 0 {
+			n--
+		}
+		start = a.config.SprintStart.AddDate(0, 0, (n+offset)*length)
+		next = start.AddDate(0, 0, length)
+	default:
+		return time.Time{}, time.Time{}, fmt.Errorf("unknown period %q", period)
+	}
+
+	end := next.AddDate(0, 0, -1)
+	if end.After(today) {
+		end = today
+	}
+	return start, end, nil
+}
+
+// applyReportingPeriod replaces a query's dates with those of the preset it
+// names.
+func (a *App) applyReportingPeriod(queryResp *services.QueryResponse) error {
+	start, end, err := a.reportingPeriod(queryResp.Period, queryResp.PeriodOffset, a.today())
+	if err != nil {
+		return err
+	}
+	queryResp.StartDate = start.Format("2006-01-02")
+	queryResp.EndDate = end.Format("2006-01-02")
+	return nil
+}
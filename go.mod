@@ -7,6 +7,7 @@ require (
 	github.com/lib/pq v1.10.9
 	github.com/sashabaranov/go-openai v1.17.9
 	github.com/slack-go/slack v0.12.3
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require github.com/gorilla/websocket v1.4.2 // indirect
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"github.com/slack-go/slack"
+)
+
+// Guest policies decide what happens to messages from guests and from people
+// in other organisations posting in a shared channel.
+const (
+	GuestPolicyIgnore = "ignore" // skip their messages
+	GuestPolicyTag    = "tag"    // record them, flagged in the thread
+	GuestPolicyAllow  = "allow"  // treat them like anyone else
+)
+
+// Kinds of accounts that aren't regular members of the workspace.
+const (
+	accountGuest    = "guest"
+	accountExternal = "external"
+)
+
+func isValidGuestPolicy(policy string) bool {
+	switch policy {
+	case GuestPolicyIgnore, GuestPolicyTag, GuestPolicyAllow:
+		return true
+	}
+	return false
+}
+
+// guestKind returns accountGuest for single- and multi-channel guests,
+// accountExternal for users of another workspace, and "" for members. On
+// Enterprise Grid, members of sibling workspaces count as external too.
+func (a *App) guestKind(user *slack.User) string {
+	if user.IsStranger {
+		return accountExternal
+	}
+	if teamID := a.teamID(); teamID != "" && user.TeamID != "" && user.TeamID != teamID {
+		return accountExternal
+	}
+	if user.IsRestricted || user.IsUltraRestricted {
+		return accountGuest
+	}
+	return ""
+}
+
+func kindArticle(kind string) string {
+	if kind == accountExternal {
+		return "an external"
+	}
+	return "a guest"
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+	"slack-leaves-ai-agent/services"
+
+	"github.com/slack-go/slack"
+)
+
+// handoverLookback is how far back recent messages are considered.
+const handoverLookback = 14 * 24 * time.Hour
+
+// buildOpenItemsSources returns one source per HANDOVER_ITEMS_URLS entry.
+func buildOpenItemsSources(config *Config) []services.OpenItemsSource {
+	var sources []services.OpenItemsSource
+	for _, u := range config.HandoverItemsURLs {
+		sources = append(sources, services.NewHTTPOpenItemsSource(u, config.HandoverItemsAuth))
+	}
+	return sources
+}
+
+// leaveDays is the number of calendar days a record spans.
+func leaveDays(leave *models.Leave) int {
+	start := leave.StartTime
+	end := leave.EndTime
+	first := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	last := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+	return int(last.Sub(first).Hours()/24) + 1
+}
+
+// maybeSendHandover DMs the user a draft handover checklist when they record
+// a full-day leave of at least HANDOVER_MIN_DAYS. It is a private draft; the
+// user decides whether and where to share it.
+func (a *App) maybeSendHandover(ctx context.Context, leave *models.Leave, userID, email string) {
+	if a.config.HandoverMinDays <= 0 || !isDayOff(leave.LeaveType) || leaveDays(leave) < a.config.HandoverMinDays {
+		return
+	}
+	if leave.EndTime.Before(time.Now()) {
+		return
+	}
+	if !a.hasScope(scopeIMWrite) {
+		logger.Debug("Skipping handover checklist for %s: missing %s scope", leave.Username, scopeIMWrite)
+		return
+	}
+
+	input := services.HandoverInput{
+		Username:  leave.Username,
+		StartDate: leave.StartTime,
+		EndDate:   leave.EndTime,
+		Reason:    leave.Reason,
+	}
+	for _, msg := range a.activity.Recent(userID, time.Now().Add(-handoverLookback)) {
+		input.Messages = append(input.Messages, msg.Text)
+	}
+	if email != "" {
+		for _, source := range a.openItems {
+			items, err := source.OpenItems(ctx, email)
+			if err != nil {
+				logger.Error("Failed to load open items from %s: %v", source.Name(), err)
+				continue
+			}
+			input.OpenItems = append(input.OpenItems, items...)
+		}
+	}
+	if len(input.Messages) == 0 && len(input.OpenItems) == 0 {
+		logger.Debug("No recent activity for %s, skipping handover checklist", leave.Username)
+		return
+	}
+
+	checklist, err := a.openAI.GenerateHandoverChecklist(ctx, input)
+	if err != nil {
+		logger.Error("Failed to generate handover checklist: %v", err)
+		return
+	}
+
+	channel, _, _, err := a.slackClient.OpenConversationContext(ctx, &slack.OpenConversationParameters{Users: []string{userID}})
+	if err != nil {
+		logger.Error("Failed to open DM with %s: %v", userID, err)
+		return
+	}
+
+	_, _, err = a.slackClient.PostMessageContext(ctx, channel.ID, slack.MsgOptionText(
+		fmt.Sprintf("📋 *Draft handover checklist for %s – %s*\n"+
+			"_Only you can see this. Edit it and share it with your team before you go._\n\n%s",
+			leave.StartTime.Format("Jan 2"), leave.EndTime.Format("Jan 2"), checklist),
+		false,
+	))
+	if err != nil {
+		logger.Error("Failed to send handover checklist: %v", err)
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"slack-leaves-ai-agent/models"
+	"slack-leaves-ai-agent/repository"
+
+	"github.com/slack-go/slack"
+)
+
+// Action IDs of the select menus in the help messages. Their options are
+// loaded on demand through block_suggestion interactions.
+const (
+	helpPickUserActionID      = "help_pick_user"
+	helpPickLeaveTypeActionID = "help_pick_leave_type"
+)
+
+// maxSuggestions is the most options returned for one options-load request.
+const maxSuggestions = 20
+
+func isHelpRequest(text string) bool {
+	text = strings.ToLower(strings.TrimSpace(text))
+	return text == "" || text == "help" || text == "?"
+}
+
+func externalSelect(actionID, placeholder string) *slack.SelectBlockElement {
+	minQueryLength := 0
+	element := slack.NewOptionsSelectBlockElement(slack.OptTypeExternal,
+		slack.NewTextBlockObject("plain_text", placeholder, false, false), actionID)
+	element.MinQueryLength = &minQueryLength
+	return element
+}
+
+func queryHelpBlocks() []slack.Block {
+	text := "*📊 `/query` answers questions about leave in plain English.*\n" +
+		"Try:\n" +
+		"• `/query who took the most leave?`\n" +
+		"• `/query who's out today?`\n" +
+		"• `/query leave stats for priya`\n" +
+		"• `/query leaves between 2024-01-01 and 2024-03-31`\n" +
+		"• `/query how many contractors were out last month?`\n" +
+		"• `/query who WFH'd the most last quarter?`\n" +
+		"• `/query who took more than 5 leaves this quarter?`\n" +
+		"• `/query who was absent the most last month?` (WFH not counted)\n" +
+		"• `/query leave stats for last fiscal year`\n" +
+		"• `/query who was out the most this sprint?`\n" +
+		"• `/query how does priya compare to rahul this quarter?`\n" +
+		"• `/query WFH trend by month this year`\n" +
+		"• `/query similar: medical appointments last quarter` (HR and admins, searches reasons by meaning)\n" +
+		"• `/query export last month` (uploads the records as CSV; add `xlsx` for Excel)\n\n" +
+		"Reply in the answer's thread to follow up, e.g. _and what about just the backend team?_\n\n" +
+		"Or pick a teammate to see their totals:"
+
+	return []slack.Block{
+		slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", text, false, false), nil, nil),
+		slack.NewActionBlock("help_query", externalSelect(helpPickUserActionID, "Look up a teammate")),
+	}
+}
+
+func (a *App) leaveHelpBlocks() []slack.Block {
+	var how string
+	switch a.config.DefaultTrigger {
+	case TriggerMention:
+		how = "Mention me in a message, e.g. `@bot wfh today`."
+	case TriggerKeywords:
+		how = "Just say it in the channel, e.g. `wfh today` or `sick leave tomorrow`; I pick up messages about attendance."
+	default:
+		how = "Just say it in the channel, e.g. `wfh today` or `sick leave tomorrow`."
+	}
+
+	lines := make([]string, 0, len(models.LeaveTypes))
+	for _, info := range models.LeaveTypes {
+		lines = append(lines, fmt.Sprintf("• *%s* – %s", info.Name, info.Description))
+	}
+
+	text := "*🗓️ Recording leave*\n" + how + " You can also DM me.\n" +
+		"To cancel, say something like `cancel my leave tomorrow`, or find the record with `/leave list` and run `/leave cancel ID`. Move a record to other days with `/leave edit ID FIRST [LAST]`. To change the end of a leave, say `back early, cutting my leave short` or `extending till Wednesday`.\n\n" +
+		"*What I understand*\n" + strings.Join(lines, "\n") + "\n\n" +
+		"*Commands*\n" +
+		"• `/query help` – ask questions about leave\n" +
+		"• `/teamcal [MONTH]` – your team's month at a glance\n" +
+		"• `/leave-report` – HR reports (HR and admins)\n" +
+		"• `/admin-leave` – manage records (admins)\n" +
+		"• `/adjust-balance` – grant or take away leave days (admins)\n\n" +
+		"Pick a type to see an example:"
+
+	return []slack.Block{
+		slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", text, false, false), nil, nil),
+		slack.NewActionBlock("help_leave", externalSelect(helpPickLeaveTypeActionID, "Choose a leave type")),
+	}
+}
+
+func handleLeaveCommand(ctx context.Context, app *App, cmd slack.SlashCommand) {
+	if reply, ok := app.runLeaveCommand(ctx, cmd); ok {
+		_, err := app.slackClient.PostEphemeral(cmd.ChannelID, cmd.UserID, slack.MsgOptionText(reply, false))
+		if err != nil {
+			logger.Error("Failed to post leave command reply: %v", err)
+		}
+		return
+	}
+
+	blocks := app.leaveHelpBlocks()
+	if !isHelpRequest(cmd.Text) {
+		blocks = append([]slack.Block{slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn",
+			"I don't take leave through this command – post it in the channel instead. Here's how:", false, false), nil, nil)},
+			blocks...)
+	}
+	app.postEphemeralBlocks(cmd.ChannelID, cmd.UserID, blocks)
+}
+
+func (a *App) postEphemeralBlocks(channelID, userID string, blocks []slack.Block) {
+	_, err := a.slackClient.PostEphemeral(channelID, userID, slack.MsgOptionBlocks(blocks...))
+	if err != nil {
+		logger.Error("Failed to post help: %v", err)
+	}
+}
+
+// blockSuggestions answers an options-load request from one of the help
+// select menus. It runs inline, since Slack only waits three seconds for
+// the options.
+func (a *App) blockSuggestions(callback slack.InteractionCallback) slack.OptionsResponse {
+	query := strings.TrimSpace(callback.Value)
+	var options []*slack.OptionBlockObject
+
+	switch callback.ActionID {
+	case helpPickUserActionID:
+		usernames, err := a.employeeRepo.SearchUsernames(query, maxSuggestions)
+		if err != nil {
+			logger.Error("Failed to search usernames: %v", err)
+			break
+		}
+		for _, username := range usernames {
+			options = append(options, slack.NewOptionBlockObject(username,
+				slack.NewTextBlockObject("plain_text", username, false, false), nil))
+		}
+
+	case helpPickLeaveTypeActionID:
+		for _, info := range models.LeaveTypes {
+			if query != "" && !strings.Contains(strings.ToLower(info.Name+" "+info.Description), strings.ToLower(query)) {
+				continue
+			}
+			options = append(options, slack.NewOptionBlockObject(info.Name,
+				slack.NewTextBlockObject("plain_text", info.Name, false, false),
+				slack.NewTextBlockObject("plain_text", info.Description, false, false)))
+		}
+	}
+
+	return slack.OptionsResponse{Options: options}
+}
+
+// handleHelpAction responds to a choice made in one of the help menus.
+func (a *App) handleHelpAction(callback slack.InteractionCallback) {
+	for _, action := range callback.ActionCallback.BlockActions {
+		value := action.SelectedOption.Value
+		if value == "" {
+			continue
+		}
+
+		var text string
+		switch action.ActionID {
+		case helpPickUserActionID:
+			stats, err := a.leaveRepo.GetEmployeeStats(value)
+			if err != nil {
+				text = "❌ " + err.Error()
+				break
+			}
+			text = employeeStatsText(stats)
+			if context := a.peerContextText(value); context != "" {
+				text += context + "\n"
+			}
+
+		case helpPickLeaveTypeActionID:
+			info, ok := models.FindLeaveType(value)
+			if !ok {
+				continue
+			}
+			text = fmt.Sprintf("%s *%s*: %s\nFor example: `%s`", getStatusMessage(info.Name), info.Name, info.Description, info.Example)
+
+		default:
+			continue
+		}
+
+		_, err := a.slackClient.PostEphemeral(callback.Channel.ID, callback.User.ID, slack.MsgOptionText(text, false))
+		if err != nil {
+			logger.Error("Failed to post help answer: %v", err)
+		}
+	}
+}
+
+func employeeStatsText(stats []repository.LeaveStats) string {
+	var b strings.Builder
+	for _, stat := range stats {
+		fmt.Fprintf(&b, "📋 *%s*\n• Leave Count: %s\n• Types: %s\n• Total Hours: %.1f (%.1f working days)\n",
+			stat.Username, leaveCountText(stat), stat.LeaveTypes, stat.TotalHours, stat.WorkingDays)
+	}
+	return b.String()
+}
+
+// helpInteraction reports whether the block action came from a help menu.
+func helpInteraction(callback slack.InteractionCallback) bool {
+	for _, action := range callback.ActionCallback.BlockActions {
+		if action.ActionID == helpPickUserActionID || action.ActionID == helpPickLeaveTypeActionID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"slack-leaves-ai-agent/models"
+	"slack-leaves-ai-agent/services"
+)
+
+// hookWebhook is one HOOK_WEBHOOKS entry.
+type hookWebhook struct {
+	Point string
+	URL   string
+}
+
+// parseHookWebhooks parses HOOK_WEBHOOKS, a comma-separated list of
+// POINT=URL, e.g. "post_create=https://hooks.example.com/soc-lead".
+func parseHookWebhooks(value string) ([]hookWebhook, error) {
+	var webhooks []hookWebhook
+	for _, item := range splitList(value) {
+		point, url, ok := strings.Cut(item, "=")
+		point, url = strings.ToLower(strings.TrimSpace(point)), strings.TrimSpace(url)
+		if !ok || url == "" || !services.IsHookPoint(point) {
+			return nil, fmt.Errorf("invalid HOOK_WEBHOOKS entry %q, want POINT=URL with POINT one of %s, %s, %s",
+				item, services.HookPreValidate, services.HookPostCreate, services.HookPreNotify)
+		}
+		webhooks = append(webhooks, hookWebhook{Point: point, URL: url})
+	}
+	return webhooks, nil
+}
+
+// buildHooks registers the webhooks in HOOK_WEBHOOKS and loads the Go
+// plugins in HOOK_PLUGINS. A plugin that fails to load is logged and left
+// out.
+func buildHooks(config *Config) *services.HookRegistry {
+	registry := services.NewHookRegistry()
+	for _, webhook := range config.HookWebhooks {
+		if err := registry.Register(webhook.Point, webhook.URL, services.WebhookHook(webhook.URL)); err != nil {
+			logger.Error("Failed to register hook %s: %v", webhook.URL, err)
+		}
+	}
+	for _, path := range config.HookPlugins {
+		if err := services.LoadHookPlugin(path, registry); err != nil {
+			logger.Error("Failed to load hook plugin %s: %v", path, err)
+		}
+	}
+	return registry
+}
+
+// ErrHookRejected is returned when a pre_validate hook refuses a record.
+type ErrHookRejected struct {
+	Reason string
+}
+
+func (e *ErrHookRejected) Error() string {
+	if e.Reason == "" {
+		return "refused by a custom rule"
+	}
+	return e.Reason
+}
+
+// runPreValidateHooks lets hooks refuse leave before it's saved.
+func (a *App) runPreValidateHooks(ctx context.Context, actor string, leave *models.Leave) error {
+	result := a.hooks.Run(ctx, services.HookEvent{Hook: services.HookPreValidate, Actor: actor, Leave: leave})
+	if result.Reject {
+		return &ErrHookRejected{Reason: result.Reason}
+	}
+	return nil
+}
+
+// runPostCreateHooks tells hooks about a saved record without holding up
+// the reply.
+func (a *App) runPostCreateHooks(actor string, leave *models.Leave) {
+	saved := *leave
+	go a.hooks.Run(context.Background(), services.HookEvent{Hook: services.HookPostCreate, Actor: actor, Leave: &saved})
+}
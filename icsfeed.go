@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+	"slack-leaves-ai-agent/services"
+)
+
+// maxFeedEvents bounds the iCalendar feed.
+const maxFeedEvents = 2000
+
+// feedTokenFromQuery lets calendar apps, which can't send headers, pass a
+// viewer token as ?token=. The token is taken out of the URL so it isn't
+// logged with it.
+func feedTokenFromQuery(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if token := query.Get("token"); token != "" {
+			if r.Header.Get("Authorization") == "" {
+				r.Header.Set("Authorization", "Bearer "+token)
+			}
+			query.Del("token")
+			r.URL.RawQuery = query.Encode()
+		}
+		next(w, r)
+	}
+}
+
+// handleCalendarFeed serves /api/leave/calendar.ics?user=&team=, the upcoming
+// approved leave as an iCalendar feed to subscribe to. user takes usernames
+// and team departments, both comma-separated. A viewer token's feed only
+// covers its scope.
+func (a *App) handleCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	usernames := splitList(r.URL.Query().Get("user"))
+	name := "Team leave"
+	if teams := splitList(r.URL.Query().Get("team")); len(teams) > 0 {
+		members, err := a.departmentMembers(teams)
+		if err != nil {
+			logger.Error("Failed to load team members for the calendar feed: %v", err)
+			http.Error(w, "Internal error", http.StatusInternalServerError)
+			return
+		}
+		if len(usernames) > 0 {
+			members = intersectStrings(members, usernames)
+		}
+		if len(members) == 0 {
+			http.Error(w, "No one matches those filters", http.StatusNotFound)
+			return
+		}
+		usernames = members
+		name = strings.Join(teams, ", ") + " leave"
+	} else if len(usernames) > 0 {
+		name = strings.Join(usernames, ", ") + " leave"
+	}
+
+	token, _ := r.Context().Value(viewerTokenKey{}).(*models.ViewerToken)
+	from, until := feedWindow(a.today(), token)
+	leaves, err := a.leaveRepo.ListUpcoming(from, usernames, maxFeedEvents)
+	if err != nil {
+		logger.Error("Failed to load upcoming leave for the calendar feed: %v", err)
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+
+	locations := make(map[string]*time.Location)
+	events := make([]services.ICalEvent, 0, len(leaves))
+	for i := range leaves {
+		leave := &leaves[i]
+		if !until.IsZero() && !leave.StartTime.Before(until) {
+			continue
+		}
+		loc, ok := locations[leave.Username]
+		if !ok {
+			loc = a.regionFor(leave.Username).Timezone
+			locations[leave.Username] = loc
+		}
+		events = append(events, services.ICalEvent{
+			UID:         fmt.Sprintf("leave-%d@latebot", leave.ID),
+			Summary:     leaveEventSummary(leave),
+			Description: leave.Reason,
+			Start:       wallClock(leave.StartTime, loc),
+			End:         wallClock(leave.EndTime, loc),
+			AllDay:      isDayOff(leave.LeaveType),
+			Updated:     leave.UpdatedAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="leave.ics"`)
+	if r.Method == http.MethodHead {
+		return
+	}
+	if err := services.WriteICal(w, name, events); err != nil {
+		logger.Error("Failed to write the calendar feed: %v", err)
+	}
+}
+
+// feedWindow is what a calendar feed covers: records that haven't ended by
+// from and start before until, which is zero for no end. That's from today
+// on, kept within a viewer token's scope. Stored times are office
+// wall-clock times, so records ending later today are kept.
+func feedWindow(today time.Time, token *models.ViewerToken) (from, until time.Time) {
+	from = today
+	if token == nil {
+		return from, time.Time{}
+	}
+	if token.ScopeStart.After(from) {
+		from = token.ScopeStart
+	}
+	return from, token.ScopeEnd.AddDate(0, 0, 1)
+}
+
+// departmentMembers returns the usernames of active employees in any of
+// departments, ignoring case.
+func (a *App) departmentMembers(departments []string) ([]string, error) {
+	employees, err := a.employeeRepo.List(true)
+	if err != nil {
+		return nil, err
+	}
+	var usernames []string
+	for _, employee := range employees {
+		for _, department := range departments {
+			if strings.EqualFold(employee.Department, department) {
+				usernames = append(usernames, employee.Username)
+				break
+			}
+		}
+	}
+	return usernames, nil
+}
+
+func intersectStrings(a, b []string) []string {
+	var both []string
+	for _, s := range a {
+		if containsString(b, s) {
+			both = append(both, s)
+		}
+	}
+	return both
+}
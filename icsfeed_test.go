@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+)
+
+func TestFeedWindow(t *testing.T) {
+	day := func(month time.Month, d int) time.Time { return time.Date(2024, month, d, 0, 0, 0, 0, time.UTC) }
+	today := day(time.March, 4)
+
+	tests := []struct {
+		name      string
+		token     *models.ViewerToken
+		from      time.Time
+		until     time.Time
+		unbounded bool
+	}{
+		{name: "no token", from: today, unbounded: true},
+		{
+			name:  "scope already started",
+			token: &models.ViewerToken{ScopeStart: day(time.January, 1), ScopeEnd: day(time.March, 31)},
+			from:  today,
+			until: day(time.April, 1),
+		},
+		{
+			// Leave between today and the scope must stay out of the feed
+			name:  "scope starts in the future",
+			token: &models.ViewerToken{ScopeStart: day(time.June, 1), ScopeEnd: day(time.June, 30)},
+			from:  day(time.June, 1),
+			until: day(time.July, 1),
+		},
+	}
+	for _, tt := range tests {
+		from, until := feedWindow(today, tt.token)
+		if !from.Equal(tt.from) {
+			t.Errorf("%s: from = %s, want %s", tt.name, from, tt.from)
+		}
+		if tt.unbounded != until.IsZero() || !tt.unbounded && !until.Equal(tt.until) {
+			t.Errorf("%s: until = %s, want %s", tt.name, until, tt.until)
+		}
+	}
+}
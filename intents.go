@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+	"slack-leaves-ai-agent/services"
+
+	"github.com/slack-go/slack"
+)
+
+// handleCancellation cancels the author's leave on the day their message
+// refers to.
+func (a *App) handleCancellation(ctx context.Context, ev *slack.MessageEvent, userInfo *slack.User) {
+	loc := a.regionFor(userInfo.Name).Timezone
+	cancelResp, err := a.openAI.ParseCancellation(ctx, ev.Text, loc)
+	if err != nil {
+		logger.Error("Error parsing cancellation: %v", err)
+		a.notifyIfTimeout(err, ev.Channel, ev.User)
+		return
+	}
+
+	if !cancelResp.IsValid {
+		a.replyInThread(ev, fmt.Sprintf("❌ Unable to process cancellation: %s", cancelResp.Error))
+		return
+	}
+
+	day, err := time.ParseInLocation("2006-01-02", cancelResp.Date, loc)
+	if err != nil {
+		logger.Error("Invalid cancellation date %q: %v", cancelResp.Date, err)
+		return
+	}
+
+	leaves, err := a.leaveRepo.FindByUserAndDate(userInfo.Name, day)
+	if err != nil {
+		logger.Error("Error finding leaves to cancel: %v", err)
+		return
+	}
+
+	var cancelled []models.Leave
+	for _, leave := range leaves {
+		if cancelResp.LeaveType != "" && leave.LeaveType != cancelResp.LeaveType {
+			continue
+		}
+		if err := a.cancelLeave(ctx, "slack:"+ev.User, &leave, userInfo.Profile.Email); err != nil {
+			var locked *ErrPeriodLocked
+			if errors.As(err, &locked) {
+				a.replyInThread(ev, fmt.Sprintf("🔒 Your %s on %s can't be cancelled: %v. Please contact HR.",
+					leave.LeaveType, leave.StartTime.Format("Jan 2, 2006"), err))
+			} else {
+				logger.Error("Error cancelling leave %d: %v", leave.ID, err)
+			}
+			continue
+		}
+		cancelled = append(cancelled, leave)
+	}
+
+	if len(cancelled) == 0 {
+		a.replyInThread(ev, fmt.Sprintf("🤔 I couldn't find anything recorded for you on %s.", day.Format("Jan 2, 2006")))
+		return
+	}
+
+	var lines []string
+	for _, leave := range cancelled {
+		lines = append(lines, fmt.Sprintf("• %s on %s", leave.LeaveType, leave.StartTime.Format("Jan 2, 2006")))
+	}
+	a.replyInThread(ev, "🗑️ Cancelled:\n"+strings.Join(lines, "\n"))
+}
+
+// handleAdjustment moves the end of the author's leave when they come back
+// early or extend it, then reports their updated balance and lets their
+// manager know. An extension of leave that needs approval goes back to the
+// approver, as a new request would.
+func (a *App) handleAdjustment(ctx context.Context, ev *slack.MessageEvent, userInfo *slack.User) {
+	region := a.regionFor(userInfo.Name)
+	loc := region.Timezone
+	adjustResp, err := a.openAI.ParseAdjustment(ctx, ev.Text, loc)
+	if err != nil {
+		logger.Error("Error parsing adjustment: %v", err)
+		a.notifyIfTimeout(err, ev.Channel, ev.User)
+		return
+	}
+
+	if !adjustResp.IsValid {
+		a.replyInThread(ev, fmt.Sprintf("❌ Unable to process the change: %s", adjustResp.Error))
+		return
+	}
+
+	day, err := time.ParseInLocation("2006-01-02", adjustResp.Date, loc)
+	if err != nil {
+		logger.Error("Invalid adjustment date %q: %v", adjustResp.Date, err)
+		return
+	}
+	newLastDay, err := time.ParseInLocation("2006-01-02", adjustResp.NewEndDate, loc)
+	if err != nil {
+		logger.Error("Invalid adjustment end date %q: %v", adjustResp.NewEndDate, err)
+		return
+	}
+
+	leaves, err := a.leaveRepo.FindByUserAndDate(userInfo.Name, day)
+	if err != nil {
+		logger.Error("Error finding leave to adjust: %v", err)
+		return
+	}
+
+	// Only day-based records have an end date worth moving
+	var leave *models.Leave
+	for i := range leaves {
+		if !isDayOff(leaves[i].LeaveType) && leaves[i].LeaveType != "WFH" {
+			continue
+		}
+		if adjustResp.LeaveType != "" && leaves[i].LeaveType != adjustResp.LeaveType {
+			continue
+		}
+		leave = &leaves[i]
+		break
+	}
+	if leave == nil {
+		a.replyInThread(ev, fmt.Sprintf("🤔 I couldn't find a leave or WFH of yours covering %s to change.", day.Format("Jan 2, 2006")))
+		return
+	}
+	before := *leave
+
+	// Stored times are office wall-clock times; keep the end's time of day
+	end := leave.EndTime
+	leave.EndTime = time.Date(newLastDay.Year(), newLastDay.Month(), newLastDay.Day(),
+		end.Hour(), end.Minute(), end.Second(), 0, end.Location())
+	if !leave.EndTime.After(leave.StartTime) {
+		a.replyInThread(ev, fmt.Sprintf("🤔 Your %s starts on %s, so ending it on %s would remove it entirely. Say you're cancelling it instead.",
+			leave.LeaveType, leave.StartTime.Format("Jan 2"), newLastDay.Format("Jan 2")))
+		return
+	}
+	if leave.EndTime.Equal(before.EndTime) {
+		a.replyInThread(ev, fmt.Sprintf("👍 Your %s already ends on %s.", leave.LeaveType, before.EndTime.Format("Jan 2, 2006")))
+		return
+	}
+
+	maxAdvanceDays := region.ForLeaveType(leave.LeaveType).MaxAdvanceDays
+	if maxAdvanceDays <= 0 {
+		maxAdvanceDays = services.DefaultMaxAdvanceDays
+	}
+	now := time.Now().In(loc)
+	if maxDate := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc).AddDate(0, 0, maxAdvanceDays); newLastDay.After(maxDate) {
+		a.replyInThread(ev, fmt.Sprintf("❌ Leave can't be booked more than %d days in advance (maximum allowed date is %s).",
+			maxAdvanceDays, maxDate.Format("January 2, 2006")))
+		return
+	}
+
+	if err := a.checkPeriodLock(&before, leave); err != nil {
+		a.replyInThread(ev, fmt.Sprintf("🔒 Your %s from %s can't be changed: %v. Please contact HR.",
+			leave.LeaveType, leave.StartTime.Format("Jan 2, 2006"), err))
+		return
+	}
+
+	leave.Duration = models.FormatDuration(leave.StartTime, leave.EndTime)
+	if err := a.leaveRepo.Update(leave); err != nil {
+		logger.Error("Error adjusting leave %d: %v", leave.ID, err)
+		a.replyInThread(ev, "❌ Sorry, I couldn't update your leave. Please try again.")
+		return
+	}
+	action := "extend"
+	if leave.EndTime.Before(before.EndTime) {
+		action = "shorten"
+	}
+	a.audit("slack:"+ev.User, action, leave.ID, before, leave)
+	a.recordLedgerChange("slack:"+ev.User, action, &before, leave)
+	resubmitted := action == "extend" && a.resubmitForApproval(ctx, "slack:"+ev.User, leave, ev.User)
+	a.syncTeamCalendar(ctx, &before, leave)
+
+	change := fmt.Sprintf("%s from %s now ends on %s instead of %s",
+		leave.LeaveType, leave.StartTime.Format("Jan 2"), leave.EndTime.Format("Mon Jan 2"), before.EndTime.Format("Mon Jan 2"))
+	reply := "✏️ Updated: your " + change + "."
+	if resubmitted {
+		reply += "\n⏳ It's waiting for approval again."
+	}
+	if leave.LeaveType == "FULL_DAY" && a.featureEnabled(flagBalances) {
+		if balance, err := a.leaveBalance(userInfo.Name, leave.StartTime.Year()); err != nil {
+			logger.Error("Failed to compute leave balance of %s: %v", userInfo.Name, err)
+		} else if balance.Entitlement > 0 {
+			reply += fmt.Sprintf("\nYou've used %s days this year and have %s days available.",
+				formatDays(balance.Used), formatDays(balance.Available))
+		}
+	}
+	a.replyInThread(ev, reply)
+
+	// The approver's request already says what changed
+	if resubmitted {
+		return
+	}
+	if employee, err := a.employeeRepo.Get(userInfo.Name); err == nil && employee.ManagerID != "" {
+		a.notifyUser(ctx, employee.ManagerID, "Leave "+action+"ed",
+			fmt.Sprintf("✏️ <@%s>'s %s.", ev.User, change))
+	}
+}
+
+func (a *App) replyInThread(ev *slack.MessageEvent, text string) {
+	_, _, err := a.slackClient.PostMessage(
+		ev.Channel,
+		slack.MsgOptionText(text, false),
+		slack.MsgOptionTS(threadRoot(ev)),
+	)
+	if err != nil {
+		logger.Error("Error replying in thread: %v", err)
+	}
+}
+
+// handleQueryMessage answers a question asked in a channel or DM through the
+// same pipeline as /query, replying in a thread under the question.
+func (a *App) handleQueryMessage(ctx context.Context, ev *slack.MessageEvent) {
+	text := ev.Text
+	if botID := a.botUserID(); botID != "" {
+		text = strings.TrimSpace(strings.ReplaceAll(text, "<@"+botID+">", ""))
+	}
+
+	blocks, queryResp, err := a.buildQueryBlocks(ctx, text, nil)
+	if errors.Is(err, services.ErrTimeout) {
+		a.notifyIfTimeout(err, ev.Channel, ev.User)
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to run query from message: %v", err)
+		a.replyInThread(ev, "❌ "+err.Error())
+		return
+	}
+
+	_, _, err = a.slackClient.PostMessage(
+		ev.Channel,
+		slack.MsgOptionBlocks(blocks...),
+		slack.MsgOptionTS(ev.Timestamp),
+	)
+	if err != nil {
+		logger.Error("Failed to post query response: %v", err)
+		return
+	}
+	// Further questions in the thread follow up on this one
+	a.queryThreads.Add(ev.Channel, ev.Timestamp, services.QueryTurn{Query: text, Parsed: queryResp}, time.Now())
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+	"slack-leaves-ai-agent/services"
+	"slack-leaves-ai-agent/services/export"
+
+	"github.com/slack-go/slack"
+)
+
+// exportPrefix switches /query to uploading the records of a period as a
+// file, e.g. `/query export last month` or `/query export xlsx last month`.
+const exportPrefix = "export"
+
+// exportPageSize is how many records an export reads from the database at
+// a time.
+const exportPageSize = 500
+
+// exportRequest returns what a `/query export` asks for: the file format,
+// csv unless it names xlsx or excel, and the rest of the text, its period.
+func exportRequest(text string) (format, period string, ok bool) {
+	words := strings.Fields(text)
+	if len(words) == 0 || !strings.EqualFold(words[0], exportPrefix) {
+		return "", "", false
+	}
+	format = export.FormatCSV
+	var rest []string
+	for _, word := range words[1:] {
+		switch strings.ToLower(word) {
+		case "csv":
+			format = export.FormatCSV
+		case "xlsx", "excel":
+			format = export.FormatXLSX
+		default:
+			rest = append(rest, word)
+		}
+	}
+	return format, strings.Join(rest, " "), true
+}
+
+// writeLeaveExport writes the records overlapping the dates start through
+// end to w, a page at a time, stopping after limit records when limit is
+// positive. It returns how many were written and whether more were left.
+func (a *App) writeLeaveExport(w io.Writer, format string, start, end time.Time, limit int) (int, bool, error) {
+	out, err := export.NewWriter(w, format)
+	if err != nil {
+		return 0, false, err
+	}
+	if err := out.WriteRow(leaveExportHeader); err != nil {
+		return 0, false, err
+	}
+
+	// Stored times are office wall-clock times, so the dates are used as-is
+	var last *models.Leave
+	count, more := 0, false
+	for {
+		leaves, err := a.leaveRepo.ListBetweenPage(start, end.AddDate(0, 0, 1), last, exportPageSize)
+		if err != nil {
+			return count, false, fmt.Errorf("error listing records: %v", err)
+		}
+		for i := range leaves {
+			if limit > 0 && count == limit {
+				more = true
+				break
+			}
+			if err := out.WriteRow(leaveExportRow(&leaves[i])); err != nil {
+				return count, false, err
+			}
+			count++
+		}
+		if more || len(leaves) < exportPageSize {
+			break
+		}
+		last = &leaves[len(leaves)-1]
+	}
+	return count, more, out.Close()
+}
+
+// handleLeaveFileExport serves /api/leave/export?format=csv|xlsx&from=&to=,
+// streaming every record overlapping the dates, this month by default.
+func (a *App) handleLeaveFileExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = export.FormatCSV
+	}
+	if format != export.FormatCSV && format != export.FormatXLSX {
+		http.Error(w, "format must be csv or xlsx", http.StatusBadRequest)
+		return
+	}
+
+	today := a.today()
+	thisMonth := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+	start, end, err := requestRange(r, r.URL.Query().Get("from"), r.URL.Query().Get("to"),
+		thisMonth, thisMonth.AddDate(0, 1, -1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	w.Header().Set("Content-Type", export.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", leaveExportFilename(format, start, end)))
+	// The headers are sent with the first page, so a later failure can only
+	// cut the file short
+	if _, _, err := a.writeLeaveExport(w, format, start, end, 0); err != nil {
+		logger.Error("Failed to export records %s to %s: %v", start.Format("2006-01-02"), end.Format("2006-01-02"), err)
+	}
+}
+
+func leaveExportFilename(format string, start, end time.Time) string {
+	return fmt.Sprintf("leaves-%s-%s.%s", start.Format("20060102"), end.Format("20060102"), format)
+}
+
+// handleQueryExport uploads the records of the period text names, the month
+// so far by default, to the channel /query was used in.
+func (a *App) handleQueryExport(ctx context.Context, cmd slack.SlashCommand, format, text string) {
+	reply := func(text string) {
+		_, err := a.slackClient.PostEphemeral(cmd.ChannelID, cmd.UserID, slack.MsgOptionText(text, false))
+		if err != nil {
+			logger.Error("Failed to post export reply: %v", err)
+		}
+	}
+
+	if !a.hasScope(scopeFilesWrite) {
+		reply(missingScopeText(scopeFilesWrite))
+		return
+	}
+
+	start, end, err := a.exportPeriod(ctx, text)
+	if errors.Is(err, services.ErrTimeout) {
+		a.notifyIfTimeout(err, cmd.ChannelID, cmd.UserID)
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to read export period %q: %v", text, err)
+		reply("❌ " + err.Error())
+		return
+	}
+
+	var buf bytes.Buffer
+	count, more, err := a.writeLeaveExport(&buf, format, start, end, maxExportRecords)
+	if err != nil {
+		logger.Error("Failed to export records: %v", err)
+		reply("❌ Couldn't export the records, please try again.")
+		return
+	}
+	if count == 0 {
+		reply(fmt.Sprintf("No leave records from %s to %s.", start.Format("Jan 2, 2006"), end.Format("Jan 2, 2006")))
+		return
+	}
+
+	period := fmt.Sprintf("%s to %s", start.Format("Jan 2, 2006"), end.Format("Jan 2, 2006"))
+	comment := fmt.Sprintf("📄 <@%s> exported %d records from %s", cmd.UserID, count, period)
+	if more {
+		comment = fmt.Sprintf("📄 <@%s> exported the first %d records from %s; export a shorter period for the rest",
+			cmd.UserID, count, period)
+	}
+	_, err = a.slackClient.UploadFileV2(slack.UploadFileV2Parameters{
+		Filename:       leaveExportFilename(format, start, end),
+		Title:          "Leave records " + period,
+		Reader:         &buf,
+		FileSize:       buf.Len(),
+		Channel:        cmd.ChannelID,
+		InitialComment: comment,
+	})
+	if err != nil {
+		logger.Error("Failed to upload export: %v", err)
+		reply("❌ Failed to upload the file.")
+	}
+}
+
+// exportPeriod returns the first and last day of the period text names.
+func (a *App) exportPeriod(ctx context.Context, text string) (time.Time, time.Time, error) {
+	if text == "" {
+		return queryPeriod(&services.QueryResponse{}, a.today())
+	}
+	queryResp, err := a.openAI.ParseQuery(ctx, text, nil, a.config.Timezone)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if queryResp.Period != "" {
+		if err := a.applyReportingPeriod(queryResp); err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+	return queryPeriod(queryResp, a.today())
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+	"slack-leaves-ai-agent/services"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackutilsx"
+)
+
+// maxLeaveTurns is how many questions the bot asks about one leave before
+// giving up on the thread.
+const maxLeaveTurns = 3
+
+// leaveThreadTTL is how long after its question a thread still takes the
+// author's answer.
+const leaveThreadTTL = 6 * time.Hour
+
+// leaveThreads remembers the threads where the bot asked the author of a
+// leave message for something the message left out, like its date, so
+// their reply is parsed together with what they said before. Threads are
+// keyed by channel and thread_ts. Nothing here is persisted.
+type leaveThreads struct {
+	mu      sync.Mutex
+	threads map[string]*leaveThread
+}
+
+type leaveThread struct {
+	user     string
+	turns    []services.LeaveTurn
+	lastUsed time.Time
+}
+
+func newLeaveThreads() *leaveThreads {
+	return &leaveThreads{threads: make(map[string]*leaveThread)}
+}
+
+// Ask records the question asked about a message in a thread, and forgets
+// threads that have gone quiet.
+func (l *leaveThreads) Ask(channel, threadTS, user string, turn services.LeaveTurn, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for key, thread := range l.threads {
+		if now.Sub(thread.lastUsed) > leaveThreadTTL {
+			delete(l.threads, key)
+		}
+	}
+
+	key := queryThreadKey(channel, threadTS)
+	thread, ok := l.threads[key]
+	if !ok || thread.user != user {
+		thread = &leaveThread{user: user}
+		l.threads[key] = thread
+	}
+	thread.turns = append(thread.turns, turn)
+	thread.lastUsed = now
+}
+
+// Waiting returns the earlier turns of a thread where user owes the bot an
+// answer, oldest first, or nil when there's no such thread.
+func (l *leaveThreads) Waiting(channel, threadTS, user string, now time.Time) []services.LeaveTurn {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	thread, ok := l.threads[queryThreadKey(channel, threadTS)]
+	if !ok || thread.user != user || now.Sub(thread.lastUsed) > leaveThreadTTL {
+		return nil
+	}
+	return append([]services.LeaveTurn(nil), thread.turns...)
+}
+
+// Done forgets a thread once its leave is recorded or given up on.
+func (l *leaveThreads) Done(channel, threadTS string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.threads, queryThreadKey(channel, threadTS))
+}
+
+// threadRoot is the thread_ts to reply to ev under: its thread, or ev
+// itself when it isn't in one.
+func threadRoot(ev *slack.MessageEvent) string {
+	if ev.ThreadTimestamp != "" {
+		return ev.ThreadTimestamp
+	}
+	return ev.Timestamp
+}
+
+// leaveConversationText is what a leave parsed from a thread was recorded
+// from: the earlier messages and the answer, one per line.
+func leaveConversationText(history []services.LeaveTurn, text string) string {
+	if len(history) == 0 {
+		return text
+	}
+	lines := make([]string, 0, len(history)+1)
+	for _, turn := range history {
+		lines = append(lines, turn.Message)
+	}
+	return strings.Join(append(lines, text), "\n")
+}
+
+// followUpLeaveThread asks the author, in the message's thread, about the
+// items the parser found something missing from. Once nothing is left to
+// ask the thread is forgotten, and after maxLeaveTurns questions the bot
+// gives up.
+func (a *App) followUpLeaveThread(ev *slack.MessageEvent, history []services.LeaveTurn, questions []string) {
+	root := threadRoot(ev)
+	if len(questions) == 0 {
+		if len(history) > 0 {
+			a.leaveThreads.Done(ev.Channel, root)
+		}
+		return
+	}
+	if len(history) >= maxLeaveTurns {
+		a.leaveThreads.Done(ev.Channel, root)
+		a.replyInThread(ev, "🤔 I still can't tell what to record. Please post your leave again with the type and dates, e.g. `WFH tomorrow`.")
+		return
+	}
+
+	question := strings.Join(questions, " ")
+	a.leaveThreads.Ask(ev.Channel, root, ev.User, services.LeaveTurn{Message: ev.Text, Question: question}, time.Now())
+	a.logEvent(ev, models.StageHeld, "asked the author for details", 0)
+	// The question comes from the model, so it mustn't be able to mention anyone
+	a.replyInThread(ev, "❓ "+slackutilsx.EscapeMessage(question)+" _Reply in this thread._")
+}
+
+// handleLeaveFollowUp parses the author's answer to a question about their
+// leave together with the thread's earlier messages. An answer like
+// "tomorrow" wouldn't match the channel trigger or read as a leave request
+// on its own, so those checks are skipped.
+func (a *App) handleLeaveFollowUp(ctx context.Context, ev *slack.MessageEvent) {
+	if a.dedupe.Seen(messageKey(ev.Channel, ev.Timestamp), time.Now()) {
+		logger.Debug("Skipping duplicate message: %s", ev.Timestamp)
+		return
+	}
+	history := a.leaveThreads.Waiting(ev.Channel, ev.ThreadTimestamp, ev.User, time.Now())
+	if len(history) == 0 {
+		return
+	}
+	a.logEvent(ev, models.StageReceived, "", 0)
+
+	allowed, firstExceeded := a.rateLimiter.Allow(ev.User, time.Now())
+	if firstExceeded {
+		a.notifyRateLimited(ev.User)
+	}
+	if !allowed {
+		logger.Debug("Throttling follow-up from %s", ev.User)
+		a.logEvent(ev, models.StageSkipped, "rate limited", 0)
+		return
+	}
+
+	userInfo, err := a.slackClient.GetUserInfo(ev.User)
+	if err != nil {
+		logger.Error("Error getting user info: %v", err)
+		a.logEvent(ev, models.StageFailed, "couldn't look up the author: "+err.Error(), 0)
+		return
+	}
+	if botID := a.botUserID(); botID != "" {
+		ev.Text = strings.TrimSpace(strings.ReplaceAll(ev.Text, "<@"+botID+">", ""))
+	}
+
+	a.trackUsage(usageMessageParse, ev.User)
+	a.parseMessageLeaves(ctx, ev, userInfo, history, nil)
+}
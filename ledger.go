@@ -0,0 +1,381 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+
+	"github.com/slack-go/slack"
+)
+
+// quotaDays is how much of the annual entitlement a record uses: each
+// working day of a full-day leave in its owner's office and half a day for a
+// half day, matching GetLeaveDaysUsed.
+func (a *App) quotaDays(leave *models.Leave) float64 {
+	switch leave.LeaveType {
+	case "FULL_DAY":
+		region := a.regionSpanning(leave.Username, leave.StartTime, leave.EndTime)
+		return float64(len(region.WorkingDays(leave.StartTime, leave.EndTime)))
+	case "HALF_DAY":
+		return 0.5
+	}
+	return 0
+}
+
+// recordLedgerChange posts the balance effect of a change to a record: a
+// debit when one is created, a reversal when one is removed, and a reversal
+// plus a new debit when an edit changes what it uses. before or after is nil
+// for creations and removals.
+func (a *App) recordLedgerChange(actor, action string, before, after *models.Leave) {
+	var beforeDays, afterDays float64
+	if before != nil {
+		beforeDays = a.quotaDays(before)
+	}
+	if after != nil {
+		afterDays = a.quotaDays(after)
+	}
+	if before != nil && after != nil && beforeDays == afterDays &&
+		before.StartTime.Format("2006-01-02") == after.StartTime.Format("2006-01-02") {
+		return
+	}
+
+	if beforeDays > 0 {
+		a.appendLedger(&models.LedgerEntry{
+			Username:      before.Username,
+			EntryType:     models.LedgerDebit,
+			Category:      models.LedgerAnnual,
+			Days:          beforeDays,
+			EffectiveDate: before.StartTime,
+			Reason:        fmt.Sprintf("Reversal of leave #%d (%s, %s) on %s", before.ID, before.LeaveType, formatLeaveSpan(before), action),
+			LeaveID:       before.ID,
+			CreatedBy:     actor,
+		})
+	}
+	if afterDays > 0 {
+		a.appendLedger(&models.LedgerEntry{
+			Username:      after.Username,
+			EntryType:     models.LedgerDebit,
+			Category:      models.LedgerAnnual,
+			Days:          -afterDays,
+			EffectiveDate: after.StartTime,
+			Reason:        fmt.Sprintf("Leave #%d (%s, %s) on %s", after.ID, after.LeaveType, formatLeaveSpan(after), action),
+			LeaveID:       after.ID,
+			CreatedBy:     actor,
+		})
+	}
+}
+
+func formatLeaveSpan(leave *models.Leave) string {
+	start := leave.StartTime.Format("Jan 2")
+	end := leave.EndTime.Format("Jan 2")
+	if start == end {
+		return start
+	}
+	return start + "–" + end
+}
+
+func (a *App) appendLedger(entry *models.LedgerEntry) {
+	if _, err := a.ledgerRepo.Append(entry); err != nil {
+		logger.Error("Failed to post %s of %s days for %s to the ledger: %v", entry.EntryType, formatDays(entry.Days), entry.Username, err)
+	}
+}
+
+// postAccruals credits every active employee whose policy accrues leave with
+// a twelfth of their entitlement for each month of the year so far, on the
+// 1st of the month. Months already credited are skipped, so it is safe to
+// run repeatedly.
+func (a *App) postAccruals(now time.Time) {
+	employees, err := a.employeeRepo.List(true)
+	if err != nil {
+		logger.Error("Failed to load employees for accruals: %v", err)
+		return
+	}
+
+	posted := 0
+	for _, employee := range employees {
+		policy := a.userPolicy(employee.Username)
+		if !policy.AccruesLeave || policy.AnnualLeaveDays <= 0 {
+			continue
+		}
+		for month := time.January; month <= now.Month(); month++ {
+			// Round the running total rather than each month so a year adds up
+			// to the entitlement exactly
+			days := roundDays(policy.AnnualLeaveDays*float64(month)/12) - roundDays(policy.AnnualLeaveDays*float64(month-1)/12)
+			ok, err := a.ledgerRepo.Append(&models.LedgerEntry{
+				Username:      employee.Username,
+				EntryType:     models.LedgerAccrual,
+				Category:      models.LedgerAnnual,
+				Days:          days,
+				EffectiveDate: time.Date(now.Year(), month, 1, 0, 0, 0, 0, time.UTC),
+				Reason:        fmt.Sprintf("Accrual for %s %d (%s days a year)", month, now.Year(), formatDays(policy.AnnualLeaveDays)),
+				CreatedBy:     "system:accrual",
+			})
+			if err != nil {
+				logger.Error("Failed to post accrual for %s: %v", employee.Username, err)
+				break
+			}
+			if ok {
+				posted++
+			}
+		}
+	}
+	if posted > 0 {
+		logger.Info("Posted %d leave accruals", posted)
+	}
+}
+
+func roundDays(days float64) float64 {
+	return math.Round(days*100) / 100
+}
+
+// runAccruals posts accruals at startup and then once a day, so each month's
+// credit appears on the 1st.
+func (a *App) runAccruals(ctx context.Context) {
+	loc := a.config.Timezone
+	a.postAccruals(time.Now().In(loc))
+
+	lastRun := time.Now().In(loc).Format("2006-01-02")
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			now = now.In(loc)
+			today := now.Format("2006-01-02")
+			if lastRun == today {
+				continue
+			}
+			lastRun = today
+			a.postAccruals(now)
+		}
+	}
+}
+
+// leaveBalance sums the user's annual-leave ledger for the year.
+func (a *App) leaveBalance(username string, year int) (*models.Balance, error) {
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	totals, err := a.ledgerRepo.Totals(username, models.LedgerAnnual, start, start.AddDate(1, 0, 0))
+	if err != nil {
+		return nil, fmt.Errorf("error loading balance ledger: %v", err)
+	}
+	return balanceFromTotals(username, year, a.userPolicy(username).AnnualLeaveDays, totals), nil
+}
+
+// categoryBalance sums the user's ledger for the year in a category other
+// than the annual one, e.g. COMP_OFF.
+func (a *App) categoryBalance(username, category string, year int) (float64, error) {
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	totals, err := a.ledgerRepo.Totals(username, category, start, start.AddDate(1, 0, 0))
+	if err != nil {
+		return 0, fmt.Errorf("error loading balance ledger: %v", err)
+	}
+	var days float64
+	for _, total := range totals {
+		days += total
+	}
+	return roundDays(days), nil
+}
+
+func balanceFromTotals(username string, year int, entitlement float64, totals map[string]float64) *models.Balance {
+	balance := &models.Balance{
+		Username:    username,
+		Year:        year,
+		Entitlement: entitlement,
+		Accrued:     totals[models.LedgerAccrual],
+		Granted:     totals[models.LedgerGrant] + totals[models.LedgerAdjustment],
+		Used:        -totals[models.LedgerDebit],
+	}
+	balance.Available = roundDays(balance.Accrued + balance.Granted - balance.Used)
+	return balance
+}
+
+func formatLedgerEntry(entry *models.LedgerEntry) string {
+	sign := ""
+	if entry.Days > 0 {
+		sign = "+"
+	}
+	category := ""
+	if entry.Category != "" && entry.Category != models.LedgerAnnual {
+		category = " " + entry.Category
+	}
+	return fmt.Sprintf("`%s` %s%s%s %s — %s", entry.EffectiveDate.Format("Jan 2"), sign, formatDays(entry.Days),
+		category, strings.ToLower(entry.EntryType), entry.Reason)
+}
+
+// balanceCategoryPattern is what a balance category may look like, e.g.
+// ANNUAL, CASUAL or COMP_OFF.
+var balanceCategoryPattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]{1,49}$`)
+
+// adjustBalance posts a manual grant or adjustment, with the admin and
+// their reason, to the user's ledger and lets the user know.
+func (a *App) adjustBalance(actor, username, entryType, category string, days float64, reason string) (*models.LedgerEntry, error) {
+	if days == 0 {
+		return nil, fmt.Errorf("the number of days can't be zero")
+	}
+	if entryType == models.LedgerGrant && days < 0 {
+		return nil, fmt.Errorf("grants must be positive; use an adjustment to take days away")
+	}
+	if !balanceCategoryPattern.MatchString(category) {
+		return nil, fmt.Errorf("invalid balance category %q", category)
+	}
+	if strings.TrimSpace(reason) == "" {
+		return nil, fmt.Errorf("a reason is required")
+	}
+
+	entry := &models.LedgerEntry{
+		Username:      username,
+		EntryType:     entryType,
+		Category:      category,
+		Days:          roundDays(days),
+		EffectiveDate: time.Now().In(a.regionFor(username).Timezone),
+		Reason:        strings.TrimSpace(reason),
+		CreatedBy:     actor,
+	}
+	if _, err := a.ledgerRepo.Append(entry); err != nil {
+		return nil, fmt.Errorf("error updating balance: %v", err)
+	}
+	a.audit(actor, "balance_"+strings.ToLower(entryType), 0, nil, entry)
+	logger.Info("%s posted %s %s days %s for %s", actor, strings.ToLower(entryType), formatDays(entry.Days), category, username)
+
+	if employee, err := a.employeeRepo.Get(username); err == nil && employee.SlackUserID != "" {
+		a.notifyUser(context.Background(), employee.SlackUserID, "Leave balance updated",
+			"🧾 Your leave balance was updated: "+formatLedgerEntry(entry))
+	}
+	return entry, nil
+}
+
+// parseBalanceAdjustment reads `@user DAYS [CATEGORY] reason...`. The reason
+// may be quoted; without a category the annual balance is adjusted.
+func (a *App) parseBalanceAdjustment(args []string) (username, category string, days float64, reason string, err error) {
+	if len(args) < 3 {
+		return "", "", 0, "", fmt.Errorf("expected @user DAYS [CATEGORY] reason")
+	}
+	if username, err = a.resolveUserArg(args[0]); err != nil {
+		return "", "", 0, "", err
+	}
+	if days, err = strconv.ParseFloat(args[1], 64); err != nil {
+		return "", "", 0, "", fmt.Errorf("invalid number of days %q", args[1])
+	}
+
+	category = models.LedgerAnnual
+	rest := args[2:]
+	if len(rest) > 1 && balanceCategoryPattern.MatchString(rest[0]) {
+		category = rest[0]
+		rest = rest[1:]
+	}
+	return username, category, days, strings.Join(rest, " "), nil
+}
+
+// runBalanceCommand handles `/admin-leave balance`.
+func (a *App) runBalanceCommand(actor string, args []string) (string, error) {
+	if len(args) == 0 {
+		return adminLeaveUsage, nil
+	}
+
+	switch args[0] {
+	case "grant", "adjust":
+		username, category, days, reason, err := a.parseBalanceAdjustment(args[1:])
+		if err != nil {
+			return "", err
+		}
+		entryType := models.LedgerAdjustment
+		if args[0] == "grant" {
+			entryType = models.LedgerGrant
+		}
+		entry, err := a.adjustBalance(actor, username, entryType, category, days, reason)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("✅ %s: %s", username, formatLedgerEntry(entry)), nil
+
+	default:
+		username, err := a.resolveUserArg(args[0])
+		if err != nil {
+			return "", err
+		}
+		year := time.Now().Year()
+		if len(args) > 1 {
+			if year, err = strconv.Atoi(args[1]); err != nil {
+				return "", fmt.Errorf("invalid year %q", args[1])
+			}
+		}
+
+		start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+		entries, err := a.ledgerRepo.List(username, start, start.AddDate(1, 0, 0))
+		if err != nil {
+			return "", fmt.Errorf("error loading balance ledger: %v", err)
+		}
+		totals := make(map[string]float64)
+		other := make(map[string]float64)
+		var otherCategories []string
+		for _, entry := range entries {
+			if entry.Category == models.LedgerAnnual {
+				totals[entry.EntryType] += entry.Days
+				continue
+			}
+			if _, ok := other[entry.Category]; !ok {
+				otherCategories = append(otherCategories, entry.Category)
+			}
+			other[entry.Category] += entry.Days
+		}
+		balance := balanceFromTotals(username, year, a.userPolicy(username).AnnualLeaveDays, totals)
+
+		var b strings.Builder
+		fmt.Fprintf(&b, "*Leave balance of %s for %d*: %s days available\n", username, year, formatDays(balance.Available))
+		fmt.Fprintf(&b, "Accrued %s · granted %s · used %s (entitlement %s a year)\n",
+			formatDays(balance.Accrued), formatDays(balance.Granted), formatDays(balance.Used), formatDays(balance.Entitlement))
+		for _, category := range otherCategories {
+			fmt.Fprintf(&b, "%s: %s days\n", category, formatDays(roundDays(other[category])))
+		}
+		for i := range entries {
+			b.WriteString("• " + formatLedgerEntry(&entries[i]) + "\n")
+		}
+		return b.String(), nil
+	}
+}
+
+const adjustBalanceUsage = "Usage: `/adjust-balance @user +DAYS|-DAYS [CATEGORY] \"reason\"`\n" +
+	"e.g. `/adjust-balance @asha +2 CASUAL \"granted for weekend release work\"`. " +
+	"Without a category the annual balance is adjusted."
+
+// handleAdjustBalanceCommand lets admins post a manual balance adjustment,
+// for the policy exceptions HR grants.
+func handleAdjustBalanceCommand(app *App, cmd slack.SlashCommand) {
+	reply := func(text string) {
+		_, err := app.slackClient.PostEphemeral(cmd.ChannelID, cmd.UserID, slack.MsgOptionText(text, false))
+		if err != nil {
+			logger.Error("Failed to post balance reply: %v", err)
+		}
+	}
+
+	if !app.isAdmin(cmd.UserID) {
+		reply("❌ You are not allowed to use this command.")
+		return
+	}
+
+	args := splitArgs(cmd.Text)
+	if len(args) < 3 {
+		reply(adjustBalanceUsage)
+		return
+	}
+	username, category, days, reason, err := app.parseBalanceAdjustment(args)
+	if err != nil {
+		reply("❌ " + err.Error() + "\n" + adjustBalanceUsage)
+		return
+	}
+	entry, err := app.adjustBalance("slack:"+cmd.UserID, username, models.LedgerAdjustment, category, days, reason)
+	if err != nil {
+		reply("❌ " + err.Error())
+		return
+	}
+	reply(fmt.Sprintf("✅ %s: %s", username, formatLedgerEntry(entry)))
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// shutdown stops the bot once socket mode has disconnected, so no new
+// events arrive: it stops the HTTP server, lets queued and running jobs
+// finish, then closes the database. Everything shares one deadline of
+// timeout. It reports whether everything stopped in time.
+func (a *App) shutdown(server *http.Server, timeout time.Duration) bool {
+	logger.Info("Shutting down, waiting up to %s for work in progress", timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	clean := true
+	if err := server.Shutdown(ctx); err != nil {
+		logger.Error("Failed to stop the HTTP server cleanly: %v", err)
+		clean = false
+	}
+	if !a.queue.Shutdown(ctx) {
+		logger.Error("Gave up waiting for queued Slack events after %s", timeout)
+		clean = false
+	}
+	if err := a.db.Close(); err != nil {
+		logger.Error("Failed to close the database: %v", err)
+		clean = false
+	}
+
+	if clean {
+		logger.Info("Shut down cleanly 👋")
+	}
+	return clean
+}
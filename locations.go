@@ -0,0 +1,294 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+	"slack-leaves-ai-agent/services"
+)
+
+// locationFor returns the office a user belongs to, falling back to
+// DEFAULT_LOCATION for users who haven't been assigned one. A nil location
+// means the built-in default region applies.
+func (a *App) locationFor(username string) (*models.Location, error) {
+	location, err := a.locationRepo.GetForUser(username)
+	if err != nil || location != nil {
+		return location, err
+	}
+	if a.config.DefaultLocation == "" {
+		return nil, nil
+	}
+	return a.locationRepo.Get(a.config.DefaultLocation)
+}
+
+// regionFor resolves the user's office into the timezone, booking window and
+// holidays their leave requests are validated against. People with no
+// office get the default region in their Slack timezone.
+func (a *App) regionFor(username string) services.Region {
+	return a.regionSpanning(username, time.Time{}, time.Time{})
+}
+
+// regionSpanning is regionFor with the office's holidays from start's date
+// to end's date as well, for counting the working days of a record that
+// falls outside the booking window. A zero start adds none.
+func (a *App) regionSpanning(username string, start, end time.Time) services.Region {
+	region := services.DefaultRegion()
+	region.LongLeaveTypes = make(map[string]bool, len(a.config.LongLeaveTypes))
+	for _, leaveType := range a.config.LongLeaveTypes {
+		region.LongLeaveTypes[leaveType] = true
+	}
+	region.LongLeaveAdvanceDays = a.config.LongLeaveAdvanceDays
+	region.WorkStartHour, region.WorkEndHour = a.config.WorkStartHour, a.config.WorkEndHour
+
+	location, err := a.locationFor(username)
+	if err != nil {
+		logger.Error("Failed to load location for %s: %v", username, err)
+		return region
+	}
+	if location == nil {
+		// Without an office, go by the timezone set in their Slack profile
+		if employee, err := a.employeeRepo.Get(username); err == nil && employee.Timezone != "" {
+			if tz, err := time.LoadLocation(employee.Timezone); err == nil {
+				region.Timezone = tz
+			}
+		}
+		return region
+	}
+
+	if tz, err := time.LoadLocation(location.Timezone); err == nil {
+		region.Timezone = tz
+	} else {
+		logger.Error("Invalid timezone %q for location %s: %v", location.Timezone, location.Code, err)
+	}
+	if location.MaxAdvanceDays != nil {
+		region.MaxAdvanceDays = *location.MaxAdvanceDays
+	}
+
+	today := time.Now().In(region.Timezone)
+	from, to := today, today.AddDate(0, 0, region.MaxAdvanceDays+1)
+	if !start.IsZero() {
+		if start.Before(from) {
+			from = start
+		}
+		if end.After(to) {
+			to = end
+		}
+	}
+	holidays, err := a.locationRepo.ListHolidays(location.Code, from, to)
+	if err != nil {
+		logger.Error("Failed to load holidays for %s: %v", location.Code, err)
+		return region
+	}
+	region.Holidays = make(map[string]string, len(holidays))
+	for _, holiday := range holidays {
+		region.Holidays[holiday.Date.Format("2006-01-02")] = holiday.Name
+	}
+
+	return region
+}
+
+// locationsByUser maps every known user to their office, applying
+// DEFAULT_LOCATION to anyone unassigned. Users with no office at all are
+// absent from the map.
+func (a *App) locationsByUser(usernames []string) (map[string]*models.Location, error) {
+	locations, err := a.locationRepo.List()
+	if err != nil {
+		return nil, err
+	}
+	byCode := make(map[string]*models.Location, len(locations))
+	for i := range locations {
+		byCode[locations[i].Code] = &locations[i]
+	}
+
+	assignments, err := a.locationRepo.GetAssignments()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]*models.Location, len(usernames))
+	for _, username := range usernames {
+		code, ok := assignments[username]
+		if !ok {
+			code = a.config.DefaultLocation
+		}
+		if location, ok := byCode[code]; ok {
+			result[username] = location
+		}
+	}
+	return result, nil
+}
+
+// runOfficeCommand handles `/admin-leave office ...`.
+func (a *App) runOfficeCommand(actor string, args []string) (string, error) {
+	if len(args) == 0 {
+		return adminLeaveUsage, nil
+	}
+
+	switch args[0] {
+	case "list":
+		locations, err := a.locationRepo.List()
+		if err != nil {
+			return "", fmt.Errorf("error listing offices: %v", err)
+		}
+		if len(locations) == 0 {
+			return "No offices configured yet.", nil
+		}
+		var b strings.Builder
+		b.WriteString("*Offices*\n")
+		for _, location := range locations {
+			fmt.Fprintf(&b, "• `%s` %s (%s)", location.Code, location.Name, location.Timezone)
+			if location.AnnualLeaveDays != nil {
+				fmt.Fprintf(&b, ", %s days/year", formatDays(*location.AnnualLeaveDays))
+			}
+			if location.MaxAdvanceDays != nil {
+				fmt.Fprintf(&b, ", book up to %d days ahead", *location.MaxAdvanceDays)
+			}
+			if location.MinOfficeDays != nil {
+				fmt.Fprintf(&b, ", %d office days/week", *location.MinOfficeDays)
+			}
+			if location.Code == a.config.DefaultLocation {
+				b.WriteString(" _(default)_")
+			}
+			b.WriteString("\n")
+		}
+		return b.String(), nil
+
+	case "add":
+		if len(args) < 4 {
+			return adminLeaveUsage, nil
+		}
+		location := &models.Location{
+			Code:     strings.ToUpper(args[1]),
+			Timezone: args[2],
+			Name:     args[3],
+		}
+		if _, err := time.LoadLocation(location.Timezone); err != nil {
+			return "", fmt.Errorf("unknown timezone %q (use an IANA name like Europe/London)", location.Timezone)
+		}
+		for _, option := range args[4:] {
+			key, value, ok := strings.Cut(option, "=")
+			if !ok {
+				return "", fmt.Errorf("invalid option %q (expected key=value)", option)
+			}
+			switch key {
+			case "annual_days":
+				days, err := strconv.ParseFloat(value, 64)
+				if err != nil || days < 0 {
+					return "", fmt.Errorf("invalid annual_days %q", value)
+				}
+				location.AnnualLeaveDays = &days
+			case "max_advance_days":
+				days, err := strconv.Atoi(value)
+				if err != nil || days <= 0 {
+					return "", fmt.Errorf("invalid max_advance_days %q", value)
+				}
+				location.MaxAdvanceDays = &days
+			case "min_office_days":
+				days, err := strconv.Atoi(value)
+				if err != nil || days < 0 || days > 5 {
+					return "", fmt.Errorf("invalid min_office_days %q", value)
+				}
+				location.MinOfficeDays = &days
+			default:
+				return "", fmt.Errorf("unknown option %q", key)
+			}
+		}
+		if err := a.locationRepo.Upsert(location); err != nil {
+			return "", fmt.Errorf("error saving office: %v", err)
+		}
+		a.audit(actor, "upsert_location", 0, nil, location)
+		return fmt.Sprintf("✅ Saved office `%s` (%s, %s)", location.Code, location.Name, location.Timezone), nil
+
+	case "assign":
+		if len(args) != 3 {
+			return adminLeaveUsage, nil
+		}
+		username, err := a.resolveUserArg(args[1])
+		if err != nil {
+			return "", err
+		}
+		userID, _ := resolveUserIDArg(args[1])
+		location, err := a.locationRepo.Get(strings.ToUpper(args[2]))
+		if err != nil {
+			return "", err
+		}
+		if err := a.locationRepo.AssignUser(username, userID, location.Code); err != nil {
+			return "", fmt.Errorf("error assigning office: %v", err)
+		}
+		a.audit(actor, "assign_location", 0, nil, map[string]string{"username": username, "location": location.Code})
+		return fmt.Sprintf("✅ *%s* now works from `%s` (%s)", username, location.Code, location.Name), nil
+	}
+
+	return adminLeaveUsage, nil
+}
+
+// runHolidayCommand handles `/admin-leave holiday ...`.
+func (a *App) runHolidayCommand(actor string, args []string) (string, error) {
+	if len(args) < 2 {
+		return adminLeaveUsage, nil
+	}
+	code := strings.ToUpper(args[1])
+
+	switch args[0] {
+	case "list":
+		year := time.Now().Year()
+		if len(args) > 2 {
+			y, err := strconv.Atoi(args[2])
+			if err != nil {
+				return "", fmt.Errorf("invalid year %q", args[2])
+			}
+			year = y
+		}
+		from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+		holidays, err := a.locationRepo.ListHolidays(code, from, from.AddDate(1, 0, -1))
+		if err != nil {
+			return "", fmt.Errorf("error listing holidays: %v", err)
+		}
+		if len(holidays) == 0 {
+			return fmt.Sprintf("No holidays recorded for `%s` in %d.", code, year), nil
+		}
+		var b strings.Builder
+		fmt.Fprintf(&b, "*Holidays for %s in %d*\n", code, year)
+		for _, holiday := range holidays {
+			fmt.Fprintf(&b, "• %s — %s\n", holiday.Date.Format("Mon Jan 2"), holiday.Name)
+		}
+		return b.String(), nil
+
+	case "add":
+		if len(args) < 4 {
+			return adminLeaveUsage, nil
+		}
+		date, err := time.Parse("2006-01-02", args[2])
+		if err != nil {
+			return "", fmt.Errorf("invalid date %q (use YYYY-MM-DD)", args[2])
+		}
+		if _, err := a.locationRepo.Get(code); err != nil {
+			return "", err
+		}
+		holiday := &models.Holiday{LocationCode: code, Date: date, Name: strings.Join(args[3:], " ")}
+		if err := a.locationRepo.AddHoliday(holiday); err != nil {
+			return "", fmt.Errorf("error saving holiday: %v", err)
+		}
+		a.audit(actor, "add_holiday", 0, nil, holiday)
+		return fmt.Sprintf("🎉 Added %s on %s for `%s`", holiday.Name, date.Format("Jan 2, 2006"), code), nil
+
+	case "remove":
+		if len(args) != 3 {
+			return adminLeaveUsage, nil
+		}
+		date, err := time.Parse("2006-01-02", args[2])
+		if err != nil {
+			return "", fmt.Errorf("invalid date %q (use YYYY-MM-DD)", args[2])
+		}
+		if err := a.locationRepo.RemoveHoliday(code, date); err != nil {
+			return "", err
+		}
+		a.audit(actor, "remove_holiday", 0, map[string]string{"location": code, "date": args[2]}, nil)
+		return fmt.Sprintf("🗑️ Removed the %s holiday for `%s`", date.Format("Jan 2, 2006"), code), nil
+	}
+
+	return adminLeaveUsage, nil
+}
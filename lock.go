@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+)
+
+// ErrPeriodLocked is returned when a change touches a payroll period that has
+// already been closed.
+type ErrPeriodLocked struct {
+	Period string
+}
+
+func (e *ErrPeriodLocked) Error() string {
+	return fmt.Sprintf("%s is closed for payroll; changes need an admin override", e.Period)
+}
+
+// periodLockedAt returns when the payroll month containing t closes: on
+// PAYROLL_LOCK_DAY of the following month. A zero time means locking is off.
+func (a *App) periodLockedAt(t time.Time) time.Time {
+	if a.config.PayrollLockDay <= 0 {
+		return time.Time{}
+	}
+	loc := a.config.Timezone
+	t = t.In(loc)
+	nextMonth := time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+	return nextMonth.AddDate(0, 0, a.config.PayrollLockDay-1)
+}
+
+func (a *App) isLocked(t time.Time) bool {
+	lockedAt := a.periodLockedAt(t)
+	return !lockedAt.IsZero() && !time.Now().Before(lockedAt)
+}
+
+// checkPeriodLock returns an ErrPeriodLocked if any of the records fall in a
+// closed payroll period. Nil records are ignored so callers can pass the
+// before and after state of a change.
+func (a *App) checkPeriodLock(leaves ...*models.Leave) error {
+	for _, leave := range leaves {
+		if leave == nil {
+			continue
+		}
+		if a.isLocked(leave.StartTime) {
+			return &ErrPeriodLocked{Period: leave.StartTime.Format("January 2006")}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+)
+
+// longLeaveMilestoneMinDays is how long a long-type leave must be before it
+// gets halfway and return-prep reminders; shorter ones only get the start.
+const longLeaveMilestoneMinDays = 14
+
+// runLongLeaveMilestones sends the reminders for long leaves, such as
+// parental leave, once a day at 9:00 IST: when the leave starts, at its
+// halfway point and LONG_LEAVE_RETURN_PREP_DAYS before the person is back.
+// Milestones are worked out from the record each day, so a leave that is
+// shortened or extended moves its reminders with it.
+func (a *App) runLongLeaveMilestones(ctx context.Context) {
+	if len(a.config.LongLeaveTypes) == 0 {
+		return
+	}
+
+	loc := a.config.Timezone
+	lastRun := ""
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			now = now.In(loc)
+			today := now.Format("2006-01-02")
+			if lastRun == today || now.Hour() < 9 {
+				continue
+			}
+			lastRun = today
+			a.sendLongLeaveMilestones(ctx, now)
+		}
+	}
+}
+
+func (a *App) sendLongLeaveMilestones(ctx context.Context, now time.Time) {
+	// Stored times are office wall-clock times, so the date is used as-is
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	leaves, err := a.leaveRepo.ListBetween(today, today.AddDate(0, 0, a.config.LongLeaveReturnPrepDays+1))
+	if err != nil {
+		logger.Error("Failed to load long leaves: %v", err)
+		return
+	}
+	inactive, err := a.employeeRepo.GetInactive()
+	if err != nil {
+		logger.Error("Failed to load departed employees: %v", err)
+		return
+	}
+
+	longTypes := make(map[string]bool, len(a.config.LongLeaveTypes))
+	for _, leaveType := range a.config.LongLeaveTypes {
+		longTypes[leaveType] = true
+	}
+
+	for i := range leaves {
+		leave := &leaves[i]
+		if !longTypes[leave.LeaveType] || inactive[leave.Username] {
+			continue
+		}
+		milestone := a.longLeaveMilestone(leave, today)
+		if milestone == "" {
+			continue
+		}
+
+		employee, err := a.employeeRepo.Get(leave.Username)
+		if err != nil {
+			logger.Error("Failed to load employee %s for leave milestone: %v", leave.Username, err)
+			continue
+		}
+		logger.Info("Sending %s reminder for leave %d of %s", milestone, leave.ID, leave.Username)
+		a.notifyLongLeaveMilestone(ctx, milestone, leave, employee)
+	}
+}
+
+// longLeaveMilestone returns which reminder, if any, is due for the leave on
+// day: "start", "halfway" or "return_prep".
+func (a *App) longLeaveMilestone(leave *models.Leave, day time.Time) string {
+	date := day.Format("2006-01-02")
+	if leave.StartTime.Format("2006-01-02") == date {
+		return "start"
+	}
+
+	days := leaveDays(leave)
+	if days < longLeaveMilestoneMinDays {
+		return ""
+	}
+	if leave.StartTime.AddDate(0, 0, days/2).Format("2006-01-02") == date {
+		return "halfway"
+	}
+	if a.config.LongLeaveReturnPrepDays > 0 &&
+		leave.EndTime.AddDate(0, 0, -a.config.LongLeaveReturnPrepDays).Format("2006-01-02") == date {
+		return "return_prep"
+	}
+	return ""
+}
+
+// notifyLongLeaveMilestone tells the person on leave and their manager about
+// a milestone.
+func (a *App) notifyLongLeaveMilestone(ctx context.Context, milestone string, leave *models.Leave, employee *models.Employee) {
+	back := returnDay(leave.EndTime)
+	kind := "leave"
+	if leave.LeaveType == "PARENTAL" {
+		kind = "parental leave"
+	}
+
+	var subject, toUser, toManager string
+	switch milestone {
+	case "start":
+		subject = "Leave starting"
+		toUser = fmt.Sprintf("👋 Your %s starts today. You're down as back on %s. Enjoy the time off!",
+			kind, back.Format("Monday, Jan 2"))
+		toManager = fmt.Sprintf("👋 <@%s>'s %s starts today; they're due back on %s.",
+			employee.SlackUserID, kind, back.Format("Monday, Jan 2"))
+	case "halfway":
+		subject = "Leave halfway"
+		toUser = fmt.Sprintf("⏳ You're halfway through your %s. You're down as back on %s; let me know if that changes by saying e.g. `extending till %s`.",
+			kind, back.Format("Monday, Jan 2"), leave.EndTime.AddDate(0, 0, 7).Format("Jan 2"))
+		toManager = fmt.Sprintf("⏳ <@%s> is halfway through their %s and due back on %s.",
+			employee.SlackUserID, kind, back.Format("Monday, Jan 2"))
+	case "return_prep":
+		subject = "Return from leave"
+		toUser = fmt.Sprintf("🔙 You're due back from %s on %s. If the date has changed, just tell me, e.g. `extending till %s`.",
+			kind, back.Format("Monday, Jan 2"), leave.EndTime.AddDate(0, 0, 7).Format("Jan 2"))
+		toManager = fmt.Sprintf("🔙 <@%s> is due back from %s on %s. Now is a good time to plan their return: access, onboarding catch-up and a first-week check-in.",
+			employee.SlackUserID, kind, back.Format("Monday, Jan 2"))
+	default:
+		return
+	}
+
+	if employee.SlackUserID != "" {
+		a.notifyUser(ctx, employee.SlackUserID, subject, toUser)
+	}
+	if employee.ManagerID != "" {
+		a.notifyUser(ctx, employee.ManagerID, subject, toManager)
+	}
+}
+
+// returnDay is the first weekday after a leave's last day.
+func returnDay(end time.Time) time.Time {
+	day := end.AddDate(0, 0, 1)
+	for day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day
+}
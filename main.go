@@ -1,17 +1,28 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
+	"slack-leaves-ai-agent/db/migrations"
 	"slack-leaves-ai-agent/models"
 	"slack-leaves-ai-agent/repository"
 	"slack-leaves-ai-agent/services"
+	"slack-leaves-ai-agent/services/calendar"
+	"slack-leaves-ai-agent/warehouse"
 
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
@@ -21,16 +32,95 @@ import (
 )
 
 type Config struct {
-	Port               string
-	SlackBotToken      string
-	SlackAppToken      string
-	SlackSigningSecret string
-	DBHost             string
-	DBPort             string
-	DBUser             string
-	DBPassword         string
-	DBName             string
-	OpenAIKey          string
+	Port                     string
+	SlackBotToken            string
+	SlackAppToken            string
+	SlackSigningSecret       string
+	SlackMode                string
+	DBHost                   string
+	DBPort                   string
+	DBUser                   string
+	DBPassword               string
+	DBName                   string
+	OpenAIKey                string
+	AdminUserIDs             []string
+	AdminAPIKey              string
+	AdminChannelID           string
+	OpenAITimeout            time.Duration
+	RateLimitPerDay          int
+	ChannelTriggers          map[string]string
+	DefaultTrigger           string
+	NotifyChannels           []string
+	SMTPHost                 string
+	SMTPPort                 string
+	SMTPUsername             string
+	SMTPPassword             string
+	SMTPFrom                 string
+	NotifyWebhookURL         string
+	WellnessChecks           map[string]string
+	WellnessCheckTime        string
+	PublicBaseURL            string
+	DashboardURL             string
+	PayrollLockDay           int
+	AnnualLeaveDays          float64
+	EncashmentMaxDays        float64
+	DefaultLocation          string
+	HybridMinOfficeDays      int
+	DeskBookingURL           string
+	DeskBookingPayload       string
+	DeskBookingCancelURL     string
+	DeskBookingCancelPayload string
+	DeskBookingAuth          string
+	GoogleCalendarID         string
+	GoogleClientID           string
+	GoogleClientSecret       string
+	GoogleRefreshToken       string
+	HandoverMinDays          int
+	HandoverItemsURLs        []string
+	HandoverItemsAuth        string
+	SCIMToken                string
+	ParseConfidenceThreshold float64
+	SickNoQuestions          bool
+	LongLeaveTypes           []string
+	LongLeaveAdvanceDays     int
+	WorkStartHour            int
+	WorkEndHour              int
+	LongLeaveReturnPrepDays  int
+	AccrualStatements        bool
+	ManagerWeeklyDigest      bool
+	StatsMinGroupSize        int
+	ReasonBlockedWords       []string
+	FiscalYearStartMonth     time.Month
+	SprintStart              time.Time
+	SprintLengthDays         int
+	Timezone                 *time.Location
+	WarehouseURL             string
+	WarehouseRegion          string
+	WarehouseAccessKey       string
+	WarehouseSecretKey       string
+	EmbeddingsEnabled        bool
+	MetricsToken             string
+	ApprovalLeaveTypes       []string
+	ApproverID               string
+	ApprovalEmailAfter       time.Duration
+	ApprovalLinkSecret       string
+	HREscalationDays         int
+	HREscalationSickCount    int
+	HRChannelID              string
+	EventWorkers             int
+	EventTimeout             time.Duration
+	ConfirmBeforeSave        bool
+	TriggerPrefix            string
+	GuestPolicy              string
+	IgnoredSenders           []string
+	IgnoredMessagePattern    *regexp.Regexp
+	SlackSyncInterval        time.Duration
+	ShutdownTimeout          time.Duration
+	HookWebhooks             []hookWebhook
+	HookPlugins              []string
+	Environment              string
+	StagingChannels          []string
+	DBSchema                 string
 }
 
 func loadConfig() (*Config, error) {
@@ -38,20 +128,292 @@ func loadConfig() (*Config, error) {
 		return nil, fmt.Errorf("error loading .env file: %v", err)
 	}
 
+	channelTriggers, err := parseChannelTriggers(os.Getenv("CHANNEL_TRIGGERS"))
+	if err != nil {
+		return nil, err
+	}
+
+	wellnessChecks, err := parseWellnessChecks(os.Getenv("WELLNESS_CHECK_USERS"))
+	if err != nil {
+		return nil, err
+	}
+
+	wellnessCheckTime := os.Getenv("WELLNESS_CHECK_TIME")
+	if wellnessCheckTime == "" {
+		wellnessCheckTime = "11:00"
+	}
+
+	longLeaveTypes := splitList(strings.ToUpper(os.Getenv("LONG_LEAVE_TYPES")))
+	if len(longLeaveTypes) == 0 {
+		longLeaveTypes = []string{"PARENTAL"}
+	}
+	for _, leaveType := range longLeaveTypes {
+		if !isKnownLeaveType(leaveType) {
+			return nil, fmt.Errorf("invalid LONG_LEAVE_TYPES entry %q", leaveType)
+		}
+	}
+
+	approvalLeaveTypes := splitList(strings.ToUpper(os.Getenv("APPROVAL_LEAVE_TYPES")))
+	for _, leaveType := range approvalLeaveTypes {
+		if !isKnownLeaveType(leaveType) {
+			return nil, fmt.Errorf("invalid APPROVAL_LEAVE_TYPES entry %q", leaveType)
+		}
+	}
+
+	approvalEmailHours := getEnvInt("APPROVAL_EMAIL_AFTER_HOURS", 4)
+	if approvalEmailHours < 0 {
+		return nil, fmt.Errorf("invalid APPROVAL_EMAIL_AFTER_HOURS %d", approvalEmailHours)
+	}
+	if os.Getenv("APPROVAL_LINK_SECRET") != "" && (os.Getenv("PUBLIC_BASE_URL") == "" || os.Getenv("SMTP_HOST") == "") {
+		return nil, fmt.Errorf("APPROVAL_LINK_SECRET needs PUBLIC_BASE_URL and SMTP_HOST to email approval links")
+	}
+
+	hrEscalationDays := getEnvInt("HR_ESCALATION_DAYS", 10)
+	if hrEscalationDays < 0 {
+		return nil, fmt.Errorf("invalid HR_ESCALATION_DAYS %d", hrEscalationDays)
+	}
+	hrEscalationSickCount := getEnvInt("HR_ESCALATION_SICK_COUNT", 3)
+	if hrEscalationSickCount < 0 {
+		return nil, fmt.Errorf("invalid HR_ESCALATION_SICK_COUNT %d", hrEscalationSickCount)
+	}
+
+	eventWorkers := getEnvInt("EVENT_WORKERS", 8)
+	if eventWorkers <= 0 {
+		return nil, fmt.Errorf("invalid EVENT_WORKERS %d", eventWorkers)
+	}
+
+	fiscalYearStartMonth := getEnvInt("FISCAL_YEAR_START_MONTH", 1)
+	if fiscalYearStartMonth < 1 || fiscalYearStartMonth > 12 {
+		return nil, fmt.Errorf("invalid FISCAL_YEAR_START_MONTH %d", fiscalYearStartMonth)
+	}
+
+	var sprintStart time.Time
+	if value := os.Getenv("SPRINT_START"); value != "" {
+		if sprintStart, err = time.Parse("2006-01-02", value); err != nil {
+			return nil, fmt.Errorf("invalid SPRINT_START %q: %v", value, err)
+		}
+	}
+	sprintLengthDays := getEnvInt("SPRINT_LENGTH_DAYS", 14)
+	if sprintLengthDays <= 0 {
+		return nil, fmt.Errorf("invalid SPRINT_LENGTH_DAYS %d", sprintLengthDays)
+	}
+
+	timezoneName := os.Getenv("WORKSPACE_TIMEZONE")
+	if timezoneName == "" {
+		timezoneName = "Asia/Kolkata"
+	}
+	timezone, err := time.LoadLocation(timezoneName)
+	if err != nil {
+		return nil, fmt.Errorf("invalid WORKSPACE_TIMEZONE %q: %v", timezoneName, err)
+	}
+
+	triggerPrefix := strings.TrimSpace(os.Getenv("TRIGGER_PREFIX"))
+	if triggerPrefix == "" {
+		triggerPrefix = "latebot"
+	}
+
+	guestPolicy := strings.ToLower(os.Getenv("GUEST_POLICY"))
+	if guestPolicy == "" {
+		guestPolicy = GuestPolicyIgnore
+	}
+	if !isValidGuestPolicy(guestPolicy) {
+		return nil, fmt.Errorf("invalid GUEST_POLICY %q", guestPolicy)
+	}
+
+	var ignoredMessagePattern *regexp.Regexp
+	if value := os.Getenv("IGNORED_MESSAGE_PATTERN"); value != "" {
+		if ignoredMessagePattern, err = regexp.Compile(value); err != nil {
+			return nil, fmt.Errorf("invalid IGNORED_MESSAGE_PATTERN %q: %v", value, err)
+		}
+	}
+
+	hookWebhooks, err := parseHookWebhooks(os.Getenv("HOOK_WEBHOOKS"))
+	if err != nil {
+		return nil, err
+	}
+
+	environment := strings.ToLower(os.Getenv("APP_ENV"))
+	if environment == "" {
+		environment = envProduction
+	}
+	if environment != envProduction && environment != envStaging {
+		return nil, fmt.Errorf("invalid APP_ENV %q (use %s or %s)", environment, envProduction, envStaging)
+	}
+	stagingChannels := splitList(os.Getenv("STAGING_CHANNELS"))
+	if environment == envStaging && len(stagingChannels) == 0 {
+		return nil, fmt.Errorf("STAGING_CHANNELS must list the channels a staging instance may use")
+	}
+	dbSchema, err := migrations.SchemaFor(environment, os.Getenv("DB_SCHEMA"))
+	if err != nil {
+		return nil, err
+	}
+
+	slackMode := strings.ToLower(os.Getenv("SLACK_MODE"))
+	if slackMode == "" {
+		slackMode = slackModeSocket
+	}
+	if slackMode != slackModeSocket && slackMode != slackModeHTTP {
+		return nil, fmt.Errorf("invalid SLACK_MODE %q (use %s or %s)", slackMode, slackModeSocket, slackModeHTTP)
+	}
+	if slackMode == slackModeHTTP && os.Getenv("SLACK_SIGNING_SECRET") == "" {
+		return nil, fmt.Errorf("SLACK_MODE=%s needs SLACK_SIGNING_SECRET to verify requests", slackModeHTTP)
+	}
+
+	defaultTrigger := strings.ToLower(os.Getenv("DEFAULT_CHANNEL_TRIGGER"))
+	if defaultTrigger == "" {
+		defaultTrigger = TriggerKeywords
+	}
+	if !isValidTriggerMode(defaultTrigger) {
+		return nil, fmt.Errorf("invalid DEFAULT_CHANNEL_TRIGGER %q", defaultTrigger)
+	}
+
 	return &Config{
-		Port:               os.Getenv("PORT"),
-		SlackBotToken:      os.Getenv("SLACK_BOT_TOKEN"),
-		SlackAppToken:      os.Getenv("SLACK_APP_TOKEN"),
-		SlackSigningSecret: os.Getenv("SLACK_SIGNING_SECRET"),
-		DBHost:             os.Getenv("DB_HOST"),
-		DBPort:             os.Getenv("DB_PORT"),
-		DBUser:             os.Getenv("DB_USER"),
-		DBPassword:         os.Getenv("DB_PASSWORD"),
-		DBName:             os.Getenv("DB_NAME"),
-		OpenAIKey:          os.Getenv("OPENAI_API_KEY"),
+		Port:                     os.Getenv("PORT"),
+		SlackBotToken:            os.Getenv("SLACK_BOT_TOKEN"),
+		SlackAppToken:            os.Getenv("SLACK_APP_TOKEN"),
+		SlackSigningSecret:       os.Getenv("SLACK_SIGNING_SECRET"),
+		SlackMode:                slackMode,
+		DBHost:                   os.Getenv("DB_HOST"),
+		DBPort:                   os.Getenv("DB_PORT"),
+		DBUser:                   os.Getenv("DB_USER"),
+		DBPassword:               os.Getenv("DB_PASSWORD"),
+		DBName:                   os.Getenv("DB_NAME"),
+		OpenAIKey:                os.Getenv("OPENAI_API_KEY"),
+		AdminUserIDs:             splitList(os.Getenv("ADMIN_USER_IDS")),
+		AdminAPIKey:              os.Getenv("ADMIN_API_KEY"),
+		AdminChannelID:           os.Getenv("ADMIN_CHANNEL_ID"),
+		OpenAITimeout:            time.Duration(getEnvInt("OPENAI_TIMEOUT_SECONDS", 20)) * time.Second,
+		RateLimitPerDay:          getEnvInt("RATE_LIMIT_PER_DAY", 20),
+		ChannelTriggers:          channelTriggers,
+		DefaultTrigger:           defaultTrigger,
+		NotifyChannels:           splitList(os.Getenv("NOTIFY_CHANNELS")),
+		SMTPHost:                 os.Getenv("SMTP_HOST"),
+		SMTPPort:                 os.Getenv("SMTP_PORT"),
+		SMTPUsername:             os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:             os.Getenv("SMTP_PASSWORD"),
+		SMTPFrom:                 os.Getenv("SMTP_FROM"),
+		NotifyWebhookURL:         os.Getenv("NOTIFY_WEBHOOK_URL"),
+		WellnessChecks:           wellnessChecks,
+		WellnessCheckTime:        wellnessCheckTime,
+		PublicBaseURL:            os.Getenv("PUBLIC_BASE_URL"),
+		DashboardURL:             os.Getenv("DASHBOARD_URL"),
+		PayrollLockDay:           getEnvInt("PAYROLL_LOCK_DAY", 0),
+		AnnualLeaveDays:          getEnvFloat("ANNUAL_LEAVE_DAYS", 18),
+		EncashmentMaxDays:        getEnvFloat("ENCASHMENT_MAX_DAYS", 10),
+		DefaultLocation:          strings.ToUpper(os.Getenv("DEFAULT_LOCATION")),
+		HybridMinOfficeDays:      getEnvInt("HYBRID_MIN_OFFICE_DAYS", 0),
+		DeskBookingURL:           os.Getenv("DESK_BOOKING_URL"),
+		DeskBookingPayload:       os.Getenv("DESK_BOOKING_PAYLOAD"),
+		DeskBookingCancelURL:     os.Getenv("DESK_BOOKING_CANCEL_URL"),
+		DeskBookingCancelPayload: os.Getenv("DESK_BOOKING_CANCEL_PAYLOAD"),
+		DeskBookingAuth:          os.Getenv("DESK_BOOKING_AUTH"),
+		GoogleCalendarID:         os.Getenv("GOOGLE_CALENDAR_ID"),
+		GoogleClientID:           os.Getenv("GOOGLE_CLIENT_ID"),
+		GoogleClientSecret:       os.Getenv("GOOGLE_CLIENT_SECRET"),
+		GoogleRefreshToken:       os.Getenv("GOOGLE_REFRESH_TOKEN"),
+		HandoverMinDays:          getEnvInt("HANDOVER_MIN_DAYS", 3),
+		HandoverItemsURLs:        splitList(os.Getenv("HANDOVER_ITEMS_URLS")),
+		HandoverItemsAuth:        os.Getenv("HANDOVER_ITEMS_AUTH"),
+		SCIMToken:                os.Getenv("SCIM_TOKEN"),
+		ParseConfidenceThreshold: getEnvFloat("PARSE_CONFIDENCE_THRESHOLD", 0.7),
+		SickNoQuestions:          getEnvBool("SICK_NO_QUESTIONS", false),
+		LongLeaveTypes:           longLeaveTypes,
+		LongLeaveAdvanceDays:     getEnvInt("LONG_LEAVE_ADVANCE_DAYS", 365),
+		WorkStartHour:            getEnvInt("WORK_START_HOUR", 9),
+		WorkEndHour:              getEnvInt("WORK_END_HOUR", 18),
+		LongLeaveReturnPrepDays:  getEnvInt("LONG_LEAVE_RETURN_PREP_DAYS", 7),
+		AccrualStatements:        getEnvBool("ACCRUAL_STATEMENTS", false),
+		ManagerWeeklyDigest:      getEnvBool("MANAGER_WEEKLY_DIGEST", false),
+		StatsMinGroupSize:        getEnvInt("STATS_MIN_GROUP_SIZE", 5),
+		ReasonBlockedWords:       splitList(os.Getenv("REASON_BLOCKED_WORDS")),
+		FiscalYearStartMonth:     time.Month(fiscalYearStartMonth),
+		SprintStart:              sprintStart,
+		SprintLengthDays:         sprintLengthDays,
+		Timezone:                 timezone,
+		WarehouseURL:             os.Getenv("WAREHOUSE_EXPORT_URL"),
+		WarehouseRegion:          os.Getenv("WAREHOUSE_REGION"),
+		WarehouseAccessKey:       os.Getenv("WAREHOUSE_ACCESS_KEY_ID"),
+		WarehouseSecretKey:       os.Getenv("WAREHOUSE_SECRET_ACCESS_KEY"),
+		EmbeddingsEnabled:        getEnvBool("EMBEDDINGS_ENABLED", false),
+		MetricsToken:             os.Getenv("METRICS_TOKEN"),
+		ApprovalLeaveTypes:       approvalLeaveTypes,
+		ApproverID:               os.Getenv("APPROVER_ID"),
+		ApprovalEmailAfter:       time.Duration(approvalEmailHours) * time.Hour,
+		ApprovalLinkSecret:       os.Getenv("APPROVAL_LINK_SECRET"),
+		HREscalationDays:         hrEscalationDays,
+		HREscalationSickCount:    hrEscalationSickCount,
+		HRChannelID:              os.Getenv("HR_CHANNEL_ID"),
+		EventWorkers:             eventWorkers,
+		ConfirmBeforeSave:        getEnvBool("CONFIRM_BEFORE_SAVE", true),
+		TriggerPrefix:            triggerPrefix,
+		GuestPolicy:              guestPolicy,
+		IgnoredSenders:           splitList(os.Getenv("IGNORED_SENDERS")),
+		IgnoredMessagePattern:    ignoredMessagePattern,
+		SlackSyncInterval:        time.Duration(getEnvInt("SLACK_SYNC_INTERVAL_HOURS", 24)) * time.Hour,
+		ShutdownTimeout:          time.Duration(getEnvInt("SHUTDOWN_TIMEOUT_SECONDS", 30)) * time.Second,
+		HookWebhooks:             hookWebhooks,
+		HookPlugins:              splitList(os.Getenv("HOOK_PLUGINS")),
+		Environment:              environment,
+		StagingChannels:          stagingChannels,
+		DBSchema:                 dbSchema,
+		EventTimeout:             time.Duration(getEnvInt("EVENT_TIMEOUT_SECONDS", 120)) * time.Second,
 	}, nil
 }
 
+// splitList parses a comma-separated env value, dropping empty entries.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// getEnvInt reads an integer env value, falling back to def when it is unset
+// or malformed.
+func getEnvInt(key string, def int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid %s=%q, using default %d", key, value, def)
+		return def
+	}
+	return n
+}
+
+// getEnvFloat is getEnvInt for fractional values such as day counts.
+func getEnvFloat(key string, def float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("Invalid %s=%q, using default %v", key, value, def)
+		return def
+	}
+	return n
+}
+
+// getEnvBool is getEnvInt for on/off switches ("true", "1", "false", ...).
+func getEnvBool(key string, def bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid %s=%q, using default %v", key, value, def)
+		return def
+	}
+	return b
+}
+
 func initDB(config *Config) (*sql.DB, error) {
 	connStr := fmt.Sprintf(
 		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
@@ -60,7 +422,7 @@ func initDB(config *Config) (*sql.DB, error) {
 		config.DBUser,
 		config.DBPassword,
 		config.DBName,
-	)
+	) + migrations.SearchPath(config.DBSchema)
 
 	db, err := sql.Open("postgres", connStr)
 	if err != nil {
@@ -70,37 +432,121 @@ func initDB(config *Config) (*sql.DB, error) {
 	if err = db.Ping(); err != nil {
 		return nil, fmt.Errorf("error pinging database: %v", err)
 	}
+	if err := migrations.CreateSchema(db, config.DBSchema); err != nil {
+		return nil, fmt.Errorf("error creating schema %s: %v", config.DBSchema, err)
+	}
 
 	log.Println("Connected to PostgreSQL database")
 	return db, nil
 }
 
 type App struct {
-	config        *Config
-	db            *sql.DB
-	openAI        *services.OpenAIService
-	leaveRepo     *repository.LeaveRepository
-	slackClient   *slack.Client
-	processedMsgs map[string]bool
+	config          *Config
+	db              *sql.DB
+	openAI          *services.OpenAIService
+	leaveRepo       *repository.LeaveRepository
+	roleRepo        *repository.RoleRepository
+	auditRepo       *repository.AuditRepository
+	employeeRepo    *repository.EmployeeRepository
+	locationRepo    *repository.LocationRepository
+	coverageRepo    *repository.CoverageRepository
+	viewerTokenRepo *repository.ViewerTokenRepository
+	feedbackRepo    *repository.FeedbackRepository
+	recurringRepo   *repository.RecurringLeaveRepository
+	ledgerRepo      *repository.BalanceLedgerRepository
+	warehouseRepo   *repository.WarehouseExportRepository
+	embeddingRepo   *repository.EmbeddingRepository
+	pendingParses   *pendingParses
+	slackClient     *slack.Client
+	notifier        services.Notifier
+	deskBooking     *services.DeskBookingClient
+	teamCalendar    *calendar.Client
+	warehouse       *warehouse.Bucket
+	openItems       []services.OpenItemsSource
+	dedupe          *deduper
+	queue           *jobQueue
+	rateLimiter     *userRateLimiter
+	activity        *activityTracker
+	queryThreads    *queryThreads
+	leaveThreads    *leaveThreads
+	scopes          map[string]bool // bot token scopes, nil if unknown
+	parseMetrics    *parseMetrics
+	eventRepo       *repository.PipelineEventRepository
+	deadLetterRepo  *repository.DeadLetterRepository
+	settingsRepo    *repository.SettingsRepository
+	featureFlagRepo *repository.FeatureFlagRepository
+	digestRepo      *repository.ChannelDigestRepository
+	usageRepo       *repository.UsageRepository
+	unparsedRepo    *repository.UnparsedRepository
+	delegateRepo    *repository.ApprovalDelegateRepository
+	approvalMailer  services.Notifier // nil without SMTP
+	hrReviewRepo    *repository.HRReviewRepository
+	hooks           *services.HookRegistry
+	reasonFilter    *contentFilter
+	botIDMu         sync.Mutex
+	botID           string
+	botTeamID       string
 }
 
 func NewApp(config *Config, db *sql.DB) *App {
-	return &App{
-		config:        config,
-		db:            db,
-		openAI:        services.NewOpenAIService(config.OpenAIKey),
-		leaveRepo:     repository.NewLeaveRepository(db),
-		slackClient:   slack.New(config.SlackBotToken, slack.OptionAppLevelToken(config.SlackAppToken)),
-		processedMsgs: make(map[string]bool),
+	slackOptions := []slack.Option{slack.OptionAppLevelToken(config.SlackAppToken)}
+	if config.Environment == envStaging {
+		slackOptions = append(slackOptions, slack.OptionHTTPClient(newStagingHTTPClient(config.StagingChannels)))
+	}
+	slackClient := slack.New(config.SlackBotToken, slackOptions...)
+
+	app := &App{
+		config:          config,
+		db:              db,
+		openAI:          services.NewOpenAIService(config.OpenAIKey, config.OpenAITimeout),
+		leaveRepo:       repository.NewLeaveRepository(db),
+		roleRepo:        repository.NewRoleRepository(db),
+		auditRepo:       repository.NewAuditRepository(db),
+		employeeRepo:    repository.NewEmployeeRepository(db),
+		locationRepo:    repository.NewLocationRepository(db),
+		coverageRepo:    repository.NewCoverageRepository(db),
+		viewerTokenRepo: repository.NewViewerTokenRepository(db),
+		feedbackRepo:    repository.NewFeedbackRepository(db),
+		recurringRepo:   repository.NewRecurringLeaveRepository(db),
+		ledgerRepo:      repository.NewBalanceLedgerRepository(db),
+		warehouseRepo:   repository.NewWarehouseExportRepository(db),
+		embeddingRepo:   repository.NewEmbeddingRepository(db),
+		eventRepo:       repository.NewPipelineEventRepository(db),
+		deadLetterRepo:  repository.NewDeadLetterRepository(db),
+		settingsRepo:    repository.NewSettingsRepository(db),
+		featureFlagRepo: repository.NewFeatureFlagRepository(db),
+		digestRepo:      repository.NewChannelDigestRepository(db),
+		usageRepo:       repository.NewUsageRepository(db),
+		unparsedRepo:    repository.NewUnparsedRepository(db),
+		delegateRepo:    repository.NewApprovalDelegateRepository(db),
+		hooks:           buildHooks(config),
+		pendingParses:   newPendingParses(),
+		slackClient:     slackClient,
+		notifier:        buildNotifier(config, slackClient),
+		approvalMailer:  buildApprovalMailer(config),
+		hrReviewRepo:    repository.NewHRReviewRepository(db),
+		deskBooking:     buildDeskBooking(config),
+		teamCalendar:    buildTeamCalendar(config),
+		warehouse:       buildWarehouseBucket(config),
+		openItems:       buildOpenItemsSources(config),
+		dedupe:          newDeduper(),
+		queue:           newJobQueue(config.EventWorkers, eventQueueSize, config.EventTimeout),
+		rateLimiter:     newUserRateLimiter(config.RateLimitPerDay),
+		activity:        newActivityTracker(),
+		queryThreads:    newQueryThreads(),
+		leaveThreads:    newLeaveThreads(),
+		parseMetrics:    newParseMetrics(),
+		reasonFilter:    newContentFilter(config.ReasonBlockedWords),
 	}
+	app.openAI.SetExampleSource(app.parseExamples)
+	return app
 }
 
-func (a *App) handleMessage(ev *slack.MessageEvent) {
-	if a.processedMsgs[ev.Timestamp] {
+func (a *App) handleMessage(ctx context.Context, ev *slack.MessageEvent) {
+	if a.dedupe.Seen(messageKey(ev.Channel, ev.Timestamp), time.Now()) {
 		logger.Debug("Skipping duplicate message: %s", ev.Timestamp)
 		return
 	}
-	a.processedMsgs[ev.Timestamp] = true
 
 	// Skip bot messages and system messages
 	if ev.SubType != "" || ev.BotID != "" {
@@ -109,112 +555,318 @@ func (a *App) handleMessage(ev *slack.MessageEvent) {
 	}
 
 	// Skip our own messages
-	authTest, err := a.slackClient.AuthTest()
-	if err == nil && ev.User == authTest.UserID {
+	if ev.User == a.botUserID() {
 		logger.Debug("Skipping our own message")
 		return
 	}
 
+	a.logEvent(ev, models.StageReceived, "", 0)
+
+	// Standup bots and workflow posts share channels with people
+	if a.ignoredSender(ev) {
+		logger.Debug("Skipping message from ignored sender: %s", ev.Timestamp)
+		a.logEvent(ev, models.StageSkipped, "sender is in IGNORED_SENDERS", 0)
+		return
+	}
+	if a.ignoredMessage(ev.Text) {
+		logger.Debug("Skipping message matching IGNORED_MESSAGE_PATTERN: %s", ev.Timestamp)
+		a.logEvent(ev, models.StageSkipped, "matches IGNORED_MESSAGE_PATTERN", 0)
+		return
+	}
+
+	// Don't spend an LLM call on chatter the channel isn't configured to parse
+	if !a.shouldParse(ev.Channel, ev.Text) {
+		logger.Debug("Skipping message not matching channel trigger: %s", ev.Timestamp)
+		a.logEvent(ev, models.StageSkipped, "doesn't match the channel's "+a.triggerModeFor(ev.Channel)+" trigger", 0)
+		return
+	}
+	ev.Text = a.triggerText(ev.Channel, ev.Text)
+
+	allowed, firstExceeded := a.rateLimiter.Allow(ev.User, time.Now())
+	if firstExceeded {
+		a.notifyRateLimited(ev.User)
+	}
+	if !allowed {
+		logger.Debug("Throttling message from %s", ev.User)
+		a.logEvent(ev, models.StageSkipped, "rate limited", 0)
+		return
+	}
+
+	a.processMessage(ctx, ev)
+}
+
+// processMessage classifies, parses and records a message that passed
+// handleMessage's checks. Dead-lettered messages are replayed through here.
+func (a *App) processMessage(ctx context.Context, ev *slack.MessageEvent) {
 	// Get user info
 	userInfo, err := a.slackClient.GetUserInfo(ev.User)
 	if err != nil {
 		log.Printf("Error getting user info: %v", err)
+		a.logEvent(ev, models.StageFailed, "couldn't look up the author: "+err.Error(), 0)
+		a.deadLetter(ev, "user", err)
+		return
+	}
+	if kind := a.guestKind(userInfo); kind != "" && a.config.GuestPolicy == GuestPolicyIgnore {
+		logger.Debug("Skipping message from %s account %s", kind, userInfo.Name)
+		a.logEvent(ev, models.StageSkipped, kind+" account", 0)
+		return
+	}
+
+	// A message the rule parser can read is a leave request; there's no need
+	// to ask the model what it is
+	intent := services.IntentLeaveRequest
+	ruled := services.ParseLeaveRules(ev.Text, time.Now(), a.regionFor(userInfo.Name))
+	if ruled == nil {
+		intent, err = a.openAI.ClassifyIntent(ctx, ev.Text)
+		if err != nil {
+			log.Printf("Error classifying message: %v", err)
+			a.logEvent(ev, models.StageFailed, "couldn't classify: "+err.Error(), 0)
+			a.deadLetter(ev, "classify", err)
+			a.notifyIfTimeout(err, ev.Channel, ev.User)
+			return
+		}
+	}
+	if intent != services.IntentLeaveRequest {
+		a.logEvent(ev, models.StageParsed, strings.ToLower(intent), 0)
+	}
+
+	switch intent {
+	case services.IntentCancellation:
+		a.handleCancellation(ctx, ev, userInfo)
+		return
+	case services.IntentAdjustment:
+		a.handleAdjustment(ctx, ev, userInfo)
+		return
+	case services.IntentQuery:
+		a.trackUsage(usageQuery, ev.User)
+		a.handleQueryMessage(ctx, ev)
+		return
+	case services.IntentUnrelated:
+		logger.Debug("Skipping message classified as unrelated: %s", ev.Timestamp)
+		a.parseMetrics.Add(services.OutcomeUnrelated, "")
 		return
 	}
 
-	response, err := a.openAI.ParseLeaveRequest(ev.Text, ev.Timestamp)
+	a.trackUsage(usageMessageParse, ev.User)
+	a.parseMessageLeaves(ctx, ev, userInfo, nil, ruled)
+}
+
+// parseMessageLeaves parses the leaves in a message and records them,
+// asking the author in a thread about items missing something. history is
+// the earlier messages of the thread when ev answers such a question, and
+// ruled the rule parser's reading of ev, if it had one.
+func (a *App) parseMessageLeaves(ctx context.Context, ev *slack.MessageEvent, userInfo *slack.User, history []services.LeaveTurn, ruled []*services.LeaveResponse) {
+	region := a.regionFor(userInfo.Name)
+	responses, err := a.openAI.ParseLeaveRequests(ctx, ev.Text, ev.Timestamp, history, region, ruled)
 	if err != nil {
 		log.Printf("Error parsing message: %v", err)
+		if errors.Is(err, services.ErrInvalidJSON) {
+			a.parseMetrics.Add(services.OutcomeJSONError, "")
+			a.recordUnparsed(ev, "the parser's reply wasn't valid JSON")
+		}
+		a.logEvent(ev, models.StageFailed, "couldn't parse: "+err.Error(), 0)
+		a.deadLetter(ev, "parse", err)
+		a.notifyIfTimeout(err, ev.Channel, ev.User)
 		return
 	}
-
-	if !response.IsValid {
-		// If there's a validation error, inform the user
-		if response.Error != "" {
-			_, _, err = a.slackClient.PostMessage(ev.Channel, slack.MsgOptionText(
-				fmt.Sprintf("❌ Unable to process leave request: %s", response.Error),
-				false,
-			))
-			if err != nil {
-				log.Printf("Error sending error message: %v", err)
+	a.countParses(responses)
+	a.logEvent(ev, models.StageParsed, fmt.Sprintf("%d items", len(responses)), 0)
+
+	// A message can hold several items; record the valid ones and explain
+	// what was wrong with the rest
+	var leaves []*models.Leave
+	var questions []string
+	unsure := false
+	for _, response := range responses {
+		if !response.IsValid {
+			a.logEvent(ev, models.StageRejected, strings.TrimSpace(response.LeaveType+" "+response.Outcome+": "+response.Error), 0)
+			if response.Outcome == services.OutcomeIncomplete {
+				questions = append(questions, response.Question)
+				continue
+			}
+			// If there's a validation error, inform the user, in the thread
+			// when answering a question there
+			if response.Error != "" && len(history) > 0 {
+				a.replyInThread(ev, fmt.Sprintf("❌ Unable to process leave request: %s", response.Error))
+			} else if response.Error != "" {
+				_, _, err = a.slackClient.PostMessage(ev.Channel, slack.MsgOptionText(
+					fmt.Sprintf("❌ Unable to process leave request: %s", response.Error),
+					false,
+				))
+				if err != nil {
+					log.Printf("Error sending error message: %v", err)
+				}
 			}
+			continue
+		}
+
+		leaves = append(leaves, &models.Leave{
+			Username:     userInfo.Name,
+			OriginalText: leaveConversationText(history, ev.Text),
+			StartTime:    response.StartTime,
+			EndTime:      response.EndTime,
+			Duration:     response.Duration,
+			Reason:       response.Reason,
+			LeaveType:    response.LeaveType,
+			Recurrence:   response.RRule,
+
+			ParseConfidence: response.Confidence,
+			Trace:           newParseTrace("message", response, region.Timezone),
+			Sick:            response.Sick,
+		})
+		a.logEvent(ev, models.StageValidated, fmt.Sprintf("%s, confidence %.2f", response.LeaveType, response.Confidence), 0)
+		if response.Suspicious || (response.Confidence < a.config.ParseConfidenceThreshold && !(response.Sick && a.userPolicy(userInfo.Name).SickNoQuestions)) {
+			unsure = true
+		}
+	}
+	a.followUpLeaveThread(ev, history, questions)
+	if len(leaves) == 0 {
+		if reason := unparsedReason(responses); reason != "" {
+			a.recordUnparsed(ev, reason)
 		}
 		return
 	}
 
-	leave := &models.Leave{
-		Username:     userInfo.Name,
-		OriginalText: ev.Text,
-		StartTime:    response.StartTime,
-		EndTime:      response.EndTime,
-		Duration:     response.Duration,
-		Reason:       response.Reason,
-		LeaveType:    response.LeaveType,
+	// Check with the author rather than silently record the parse
+	if unsure || a.config.ConfirmBeforeSave {
+		a.logEvent(ev, models.StageHeld, "asked the author to confirm", 0)
+		for _, leave := range leaves {
+			if unsure {
+				leave.Trace.AddStep("I wasn't sure I had it right, so I asked you to confirm")
+			} else {
+				leave.Trace.AddStep("I asked you to confirm, as every parse here is checked first")
+			}
+		}
+		a.askToConfirmParse(ev, userInfo, leaves, unsure)
+		return
 	}
 
-	if err := a.leaveRepo.Create(leave); err != nil {
-		log.Printf("Error saving leave: %v", err)
+	a.saveMessageLeaves(ctx, "create", ev, userInfo, leaves)
+}
+
+// saveMessageLeaves records the leaves parsed from a channel message and
+// answers the message with one confirmation listing them all, any policy
+// warnings and the handover.
+func (a *App) saveMessageLeaves(ctx context.Context, action string, ev *slack.MessageEvent, userInfo *slack.User, leaves []*models.Leave) {
+	var recorded []*models.Leave
+	var violations []PolicyViolation
+	var saveErr error
+	for _, leave := range leaves {
+		record := a.recordLeave
+		if leave.Recurrence != "" {
+			record = a.startRecurringLeave
+		}
+		approverID := a.approverFor(leave)
+		if approverID != "" {
+			leave.Status = models.LeaveStatusPending
+			leave.Trace.AddStep(fmt.Sprintf("it went to <@%s> for approval", approverID))
+		}
+		leaveViolations, err := record(ctx, "slack:"+ev.User, action, leave, userInfo.Profile.Email)
+		if err != nil {
+			log.Printf("Error saving leave: %v", err)
+			a.logEvent(ev, models.StageFailed, "couldn't save "+leave.LeaveType+": "+err.Error(), 0)
+			var locked *ErrPeriodLocked
+			var rejected *ErrHookRejected
+			switch {
+			case errors.As(err, &rejected):
+				a.replyInThread(ev, fmt.Sprintf("🚫 Your %s wasn't recorded: %v", leave.LeaveType, rejected))
+			case !errors.As(err, &locked):
+				saveErr = err
+			}
+			continue
+		}
+		a.logEvent(ev, models.StageSaved, leave.LeaveType, leave.ID)
+		if approverID != "" {
+			a.requestApproval(ctx, leave, ev.User, approverID)
+		}
+		recorded = append(recorded, leave)
+		violations = append(violations, leaveViolations...)
+	}
+	if len(recorded) == 0 {
+		// Only when nothing was saved, so a replay can't record items twice.
+		// A locked period or a hook's refusal isn't a failure a fix would
+		// change.
+		if saveErr != nil {
+			a.deadLetter(ev, "save", saveErr)
+		}
 		return
 	}
 
 	// Send confirmation message
-	var emoji, messageType string
-	switch response.LeaveType {
-	case "WFH":
-		emoji = "🏠"
-		messageType = "WFH"
-	case "FULL_DAY":
-		emoji = "🌴"
-		messageType = "full day leave"
-	case "HALF_DAY":
-		emoji = "🌓"
-		messageType = "half day leave"
-	case "LATE_ARRIVAL":
-		emoji = "⏰"
-		messageType = "late arrival"
-	case "EARLY_DEPARTURE":
-		emoji = "🏃"
-		messageType = "early departure"
-	default:
-		emoji = "✅"
-		messageType = "request"
-	}
-
-	_, _, err = a.slackClient.PostMessage(ev.Channel, slack.MsgOptionText(
-		fmt.Sprintf("%s Your %s has been recorded!\n"+
-			"📅 From: %s\n"+
-			"📅 To: %s\n"+
-			"📝 Reason: %s\n\n"+
-			"Status: %s\n"+
-			"Have a great day! 🌟",
-			emoji,
-			messageType,
-			leave.StartTime.Format("Jan 2, 2006 3:04 PM"),
-			leave.EndTime.Format("Jan 2, 2006 3:04 PM"),
-			leave.Reason,
-			getStatusMessage(response.LeaveType),
-		), false))
+	_, _, err := a.slackClient.PostMessage(ev.Channel, confirmationOptions(recorded...)...)
 
 	if err != nil {
 		log.Printf("Error sending confirmation: %v", err)
+		a.logEvent(ev, models.StageFailed, "couldn't post the confirmation: "+err.Error(), 0)
+	} else {
+		a.logEvent(ev, models.StageConfirmed, "", 0)
+	}
+
+	if warning := policyWarningText(violations); warning != "" {
+		a.replyInThread(ev, warning)
+	}
+	if kind := a.guestKind(userInfo); kind != "" && a.config.GuestPolicy == GuestPolicyTag {
+		a.replyInThread(ev, fmt.Sprintf("👤 <@%s> posts from %s account. Admins may want to check this record.", ev.User, kindArticle(kind)))
+	}
+
+	for _, leave := range recorded {
+		a.maybeSendHandover(ctx, leave, ev.User, userInfo.Profile.Email)
+	}
+}
+
+// confirmationText is the message posted once a leave has been recorded.
+func confirmationText(leave *models.Leave) string {
+	emoji, messageType := "✅", "request"
+	if info, ok := models.FindLeaveType(leave.LeaveType); ok {
+		emoji, messageType = info.Emoji, info.Label
+	}
+
+	var repeats string
+	if rule, err := services.ParseRRule(leave.Recurrence); leave.Recurrence != "" && err == nil {
+		repeats = "🔁 Repeats " + rule.Describe() + "\n"
+	}
+	if leave.Status == models.LeaveStatusPending {
+		repeats += "⏳ Waiting for approval\n"
+	}
+
+	return fmt.Sprintf("%s Your %s has been recorded!\n"+
+		"📅 From: %s\n"+
+		"📅 To: %s\n"+
+		"%s"+
+		"📝 Reason: %s\n\n"+
+		"Status: %s\n"+
+		"Have a great day! 🌟",
+		emoji,
+		messageType,
+		leave.StartTime.Format("Jan 2, 2006 3:04 PM"),
+		leave.EndTime.Format("Jan 2, 2006 3:04 PM"),
+		repeats,
+		publicReason(leave),
+		getStatusMessage(leave.LeaveType),
+	)
+}
+
+// notifyIfTimeout tells the user privately when OpenAI was too slow to answer,
+// so they know to retry instead of waiting for a reply that won't come.
+func (a *App) notifyIfTimeout(err error, channelID, userID string) {
+	if !errors.Is(err, services.ErrTimeout) {
+		return
+	}
+
+	_, postErr := a.slackClient.PostEphemeral(channelID, userID, slack.MsgOptionText(
+		"⏳ This is taking longer than usual, please try again in a minute.",
+		false,
+	))
+	if postErr != nil {
+		logger.Error("Failed to send timeout notice: %v", postErr)
 	}
 }
 
 func getStatusMessage(leaveType string) string {
-	switch leaveType {
-	case "WFH":
-		return "🏠 Working remotely"
-	case "FULL_DAY":
-		return "🌴 Out of office"
-	case "HALF_DAY":
-		return "🌓 Partially available"
-	case "LATE_ARRIVAL":
-		return "⏰ Arriving late"
-	case "EARLY_DEPARTURE":
-		return "🏃 Leaving early"
-	default:
-		return "✅ Recorded"
+	if info, ok := models.FindLeaveType(leaveType); ok {
+		return info.Status
 	}
+	return "✅ Recorded"
 }
 
 type PrettyLogger struct {
@@ -249,7 +901,9 @@ func (l *PrettyLogger) Event(format string, v ...interface{}) {
 
 var logger = NewPrettyLogger()
 
-func setupSocketModeHandler(app *App, config *Config) error {
+// setupSocketModeHandler connects to Slack and handles events until ctx
+// ends or the connection fails for good.
+func setupSocketModeHandler(ctx context.Context, app *App, config *Config) error {
 	slackClient := slack.New(
 		config.SlackBotToken,
 		slack.OptionAppLevelToken(config.SlackAppToken),
@@ -263,7 +917,7 @@ func setupSocketModeHandler(app *App, config *Config) error {
 	go handleSocketModeEvents(socketClient, app)
 
 	logger.Info("Starting Slack bot with Socket Mode...")
-	return socketClient.Run()
+	return socketClient.RunContext(ctx)
 }
 
 func handleSocketModeEvents(client *socketmode.Client, app *App) {
@@ -283,41 +937,10 @@ func handleSocketModeEvents(client *socketmode.Client, app *App) {
 			}
 
 			client.Ack(*evt.Request)
-			logger.Event("Received event: Type=%s", eventsAPIEvent.Type)
-
-			if eventsAPIEvent.Type == slackevents.CallbackEvent {
-				innerEvent := eventsAPIEvent.InnerEvent
-				switch ev := innerEvent.Data.(type) {
-				case *slackevents.MessageEvent:
-					// Skip non-user messages
-					if ev.SubType != "" || ev.BotID != "" || ev.ThreadTimeStamp != "" {
-						logger.Debug("Skipping non-user message")
-						continue
-					}
-
-					// Skip our own messages
-					authTest, err := app.slackClient.AuthTest()
-					if err == nil && ev.User == authTest.UserID {
-						logger.Debug("Skipping our own message")
-						continue
-					}
-
-					logger.Debug("Message from %s: %s", ev.User, ev.Text)
-					messageEvent := &slack.MessageEvent{
-						Msg: slack.Msg{
-							Text:      ev.Text,
-							User:      ev.User,
-							Channel:   ev.Channel,
-							Timestamp: ev.TimeStamp,
-						},
-					}
-					go app.handleMessage(messageEvent)
-				default:
-					logger.Debug("Unhandled callback event type: %T", ev)
-				}
-			} else {
-				logger.Debug("Unhandled event type: %s", eventsAPIEvent.Type)
+			if evt.Request.RetryAttempt > 0 {
+				logger.Debug("Slack redelivered an event (attempt %d, %s)", evt.Request.RetryAttempt, evt.Request.RetryReason)
 			}
+			app.dispatchEvent(eventsAPIEvent)
 		case socketmode.EventTypeSlashCommand:
 			cmd, ok := evt.Data.(slack.SlashCommand)
 			if !ok {
@@ -325,44 +948,364 @@ func handleSocketModeEvents(client *socketmode.Client, app *App) {
 				continue
 			}
 
-			client.Ack(*evt.Request)
+			if resp := app.dispatchSlashCommand(cmd); resp != nil {
+				client.Ack(*evt.Request, resp)
+			} else {
+				client.Ack(*evt.Request)
+			}
+		case socketmode.EventTypeInteractive:
+			callback, ok := evt.Data.(slack.InteractionCallback)
+			if !ok {
+				logger.Debug("Failed to cast interaction callback")
+				continue
+			}
 
-			switch cmd.Command {
-			case "/query":
-				go handleQueryCommand(app, cmd)
+			if resp := app.dispatchInteraction(callback); resp != nil {
+				client.Ack(*evt.Request, resp)
+			} else {
+				client.Ack(*evt.Request)
+			}
+		case socketmode.EventTypeErrorBadMessage:
+			bad, ok := evt.Data.(*socketmode.ErrorBadMessage)
+			if !ok {
+				continue
 			}
+			var req socketmode.Request
+			if err := json.Unmarshal(bad.Message, &req); err == nil && req.Type == socketmode.RequestTypeEventsAPI &&
+				app.dispatchUndecodedEvent(req.Payload) {
+				client.Ack(req)
+				continue
+			}
+			logger.Debug("Bad socket mode message: %v", bad.Cause)
 		default:
 			logger.Debug("Unhandled event type: %v", evt.Type)
 		}
 	}
 }
 
-func handleQueryCommand(app *App, cmd slack.SlashCommand) {
-	// Parse the query using OpenAI
-	queryResp, err := app.openAI.ParseQuery(cmd.Text)
-	if err != nil {
-		logger.Error("Failed to parse query: %v", err)
+// dispatchEvent routes an Events API event, from socket mode or HTTP, to its
+// handler once it has been acknowledged.
+func (a *App) dispatchEvent(eventsAPIEvent slackevents.EventsAPIEvent) {
+	logger.Event("Received event: Type=%s", eventsAPIEvent.Type)
+
+	// Checked here rather than in the handlers, which run concurrently and
+	// would race each other
+	if callback, ok := eventsAPIEvent.Data.(*slackevents.EventsAPICallbackEvent); ok &&
+		a.dedupe.Seen(eventKey(callback.EventID), time.Now()) {
+		logger.Debug("Skipping duplicate event: %s", callback.EventID)
 		return
 	}
 
-	if queryResp.Error != "" {
+	if eventsAPIEvent.Type == slackevents.CallbackEvent {
+		innerEvent := eventsAPIEvent.InnerEvent
+		switch ev := innerEvent.Data.(type) {
+		case *slackevents.MessageEvent:
+			if !a.channelAllowed(ev.Channel) {
+				return
+			}
+			a.activity.Touch(ev.User, time.Now())
+			// Only handover checklists read what people wrote
+			if a.config.HandoverMinDays > 0 && ev.SubType == "" && ev.BotID == "" {
+				a.activity.Remember(ev.User, ev.Text, time.Now())
+			}
+
+			// Replies under a query answer are follow-up questions
+			if ev.SubType == "" && ev.BotID == "" && ev.ThreadTimeStamp != "" && ev.User != a.botUserID() &&
+				a.queryThreads.Turns(ev.Channel, ev.ThreadTimeStamp, time.Now()) != nil {
+				followUp := &slack.MessageEvent{
+					Msg: slack.Msg{
+						Text:            ev.Text,
+						User:            ev.User,
+						Channel:         ev.Channel,
+						Timestamp:       ev.TimeStamp,
+						ThreadTimestamp: ev.ThreadTimeStamp,
+					},
+				}
+				a.queue.Submit("handleQueryFollowUp", func(ctx context.Context) { a.handleQueryFollowUp(ctx, followUp) })
+				return
+			}
+
+			// Replies to a question about a leave message continue its parse
+			if ev.SubType == "" && ev.BotID == "" && ev.ThreadTimeStamp != "" &&
+				a.leaveThreads.Waiting(ev.Channel, ev.ThreadTimeStamp, ev.User, time.Now()) != nil {
+				followUp := &slack.MessageEvent{
+					Msg: slack.Msg{
+						Text:            ev.Text,
+						User:            ev.User,
+						Channel:         ev.Channel,
+						Timestamp:       ev.TimeStamp,
+						ThreadTimestamp: ev.ThreadTimeStamp,
+					},
+				}
+				a.queue.Submit("handleLeaveFollowUp", func(ctx context.Context) { a.handleLeaveFollowUp(ctx, followUp) })
+				return
+			}
+
+			// Skip non-user messages
+			if ev.SubType != "" || ev.BotID != "" || ev.ThreadTimeStamp != "" {
+				logger.Debug("Skipping non-user message")
+				return
+			}
+
+			// Skip our own messages
+			if ev.User == a.botUserID() {
+				logger.Debug("Skipping our own message")
+				return
+			}
+
+			logger.Debug("Message from %s: %s", ev.User, ev.Text)
+			messageEvent := &slack.MessageEvent{
+				Msg: slack.Msg{
+					Text:      ev.Text,
+					User:      ev.User,
+					Channel:   ev.Channel,
+					Timestamp: ev.TimeStamp,
+				},
+			}
+			a.queue.Submit("handleMessage", func(ctx context.Context) { a.handleMessage(ctx, messageEvent) })
+		case *slackevents.LinkSharedEvent:
+			if !a.channelAllowed(ev.Channel) {
+				return
+			}
+			a.queue.Submit("handleLinkShared", func(ctx context.Context) { a.handleLinkShared(ctx, ev) })
+		case *slackevents.TeamJoinEvent:
+			a.queue.Submit("handleTeamJoin", func(context.Context) { a.handleTeamJoin(ev.User) })
+		case *slackevents.AppHomeOpenedEvent:
+			a.queue.Submit("handleAppHomeOpened", func(context.Context) {
+				a.trackUsage(usageAppHome, ev.User)
+				a.handleAppHomeOpened(ev)
+			})
+		default:
+			logger.Debug("Unhandled callback event type: %T", ev)
+		}
+	} else {
+		logger.Debug("Unhandled event type: %s", eventsAPIEvent.Type)
+	}
+}
+
+// dispatchUndecodedEvent handles the Events API payloads slack-go can't
+// decode, reporting whether it knew the event.
+func (a *App) dispatchUndecodedEvent(payload json.RawMessage) bool {
+	if fn, ok := parseFunctionExecuted(payload); ok {
+		logger.Event("Received workflow function: %s", fn.Function.CallbackID)
+		a.queue.Submit("handleFunctionExecuted", func(ctx context.Context) { a.handleFunctionExecuted(ctx, fn) })
+		return true
+	}
+	if user, ok := parseUserChange(payload); ok {
+		logger.Event("Received user change: %s", user.Name)
+		a.queue.Submit("handleUserChange", func(ctx context.Context) { a.handleUserChange(ctx, user) })
+		return true
+	}
+	return false
+}
+
+// dispatchSlashCommand routes a slash command to its handler. It returns
+// what to answer Slack with, or nil for a plain acknowledgement.
+func (a *App) dispatchSlashCommand(cmd slack.SlashCommand) interface{} {
+	if !a.channelAllowed(cmd.ChannelID) {
+		return map[string]string{"response_type": "ephemeral", "text": a.stagingNotice()}
+	}
+
+	switch cmd.Command {
+	case "/query":
+		a.queue.Submit("handleQueryCommand", func(ctx context.Context) {
+			a.trackUsage(usageQuery, cmd.UserID)
+			handleQueryCommand(ctx, a, cmd)
+		})
+	case "/admin-leave":
+		a.queue.Submit("handleAdminLeaveCommand", func(context.Context) { handleAdminLeaveCommand(a, cmd) })
+	case "/leave-report":
+		a.queue.Submit("handleLeaveReportCommand", func(context.Context) { handleLeaveReportCommand(a, cmd) })
+	case "/teamcal":
+		a.queue.Submit("handleTeamCalCommand", func(context.Context) { handleTeamCalCommand(a, cmd) })
+	case "/leave":
+		a.queue.Submit("handleLeaveCommand", func(ctx context.Context) {
+			a.trackUsage(usageLeaveCommand, cmd.UserID)
+			handleLeaveCommand(ctx, a, cmd)
+		})
+	case "/adjust-balance":
+		a.queue.Submit("handleAdjustBalanceCommand", func(context.Context) { handleAdjustBalanceCommand(a, cmd) })
+	case "/latebot-test":
+		a.queue.Submit("handleTestCommand", func(ctx context.Context) { handleTestCommand(ctx, a, cmd) })
+	case "/latebot-log":
+		a.queue.Submit("handleEventLogCommand", func(context.Context) { handleEventLogCommand(a, cmd) })
+	}
+	return nil
+}
+
+// dispatchInteraction routes an interaction to its handler. It returns what
+// to answer Slack with, such as options or a modal's validation errors, or
+// nil for a plain acknowledgement.
+func (a *App) dispatchInteraction(callback slack.InteractionCallback) interface{} {
+	// Options are returned in the acknowledgement itself
+	if callback.Type == slack.InteractionTypeBlockSuggestion {
+		return a.blockSuggestions(callback)
+	}
+	if callback.Type == slack.InteractionTypeViewSubmission {
+		userID := callback.User.ID
+		a.queue.Submit("trackUsage", func(context.Context) { a.trackUsage(usageModal, userID) })
+	}
+	// So are validation errors of modal submissions
+	if callback.Type == slack.InteractionTypeViewSubmission {
+		switch callback.View.CallbackID {
+		case reviewModalCallbackID:
+			return viewSubmissionAck(a.submitReviewCorrection(callback))
+		case editParseCallbackID:
+			return viewSubmissionAck(a.submitParseEdit(callback))
+		case unparsedModalCallbackID:
+			return viewSubmissionAck(a.submitUnparsedExample(callback))
+		case privateReasonModalCallbackID:
+			return viewSubmissionAck(a.submitPrivateReason(callback))
+		}
+	}
+
+	logger.Event("Received interaction: Type=%s CallbackID=%s", callback.Type, callback.CallbackID)
+
+	switch callback.Type {
+	case slack.InteractionTypeMessageAction:
+		switch callback.CallbackID {
+		case logAsLeaveCallbackID:
+			a.queue.Submit("handleLogAsLeaveShortcut", func(ctx context.Context) {
+				a.trackUsage(usageShortcut, callback.User.ID)
+				a.handleLogAsLeaveShortcut(ctx, callback)
+			})
+		}
+	case slack.InteractionTypeBlockActions:
+		if helpInteraction(callback) {
+			a.queue.Submit("handleHelpAction", func(context.Context) { a.handleHelpAction(callback) })
+		}
+		if feedbackInteraction(callback) {
+			a.queue.Submit("handleFeedbackAction", func(context.Context) { a.handleFeedbackAction(callback) })
+		}
+		if reviewInteraction(callback) {
+			a.queue.Submit("handleReviewAction", func(context.Context) { a.handleReviewAction(callback) })
+		}
+		if unparsedInteraction(callback) {
+			a.queue.Submit("handleUnparsedAction", func(context.Context) { a.handleUnparsedAction(callback) })
+		}
+		if confirmParseInteraction(callback) {
+			a.queue.Submit("handleConfirmParseAction", func(ctx context.Context) { a.handleConfirmParseAction(ctx, callback) })
+		}
+		if detailsInteraction(callback) {
+			a.queue.Submit("handleDetailsAction", func(context.Context) { a.handleDetailsAction(callback) })
+		}
+		if exportCSVInteraction(callback) {
+			a.queue.Submit("handleExportCSVAction", func(context.Context) { a.handleExportCSVAction(callback) })
+		}
+		if explainInteraction(callback) {
+			a.queue.Submit("handleExplainAction", func(context.Context) { a.handleExplainAction(callback) })
+		}
+		if privateReasonInteraction(callback) {
+			a.queue.Submit("handlePrivateReasonAction", func(context.Context) { a.handlePrivateReasonAction(callback) })
+		}
+		if approvalInteraction(callback) {
+			a.queue.Submit("handleApprovalAction", func(ctx context.Context) { a.handleApprovalAction(ctx, callback) })
+		}
+		if hrReviewInteraction(callback) {
+			a.queue.Submit("handleHRReviewAction", func(context.Context) { a.handleHRReviewAction(callback) })
+		}
+		if setupInteraction(callback) {
+			a.queue.Submit("handleSetupAction", func(context.Context) { a.handleSetupAction(callback) })
+		}
+	}
+	return nil
+}
+
+// viewSubmissionAck answers a modal submission with resp, or with a plain
+// acknowledgement when it's nil.
+func viewSubmissionAck(resp *slack.ViewSubmissionResponse) interface{} {
+	if resp == nil {
+		return nil
+	}
+	return resp
+}
+
+func handleQueryCommand(ctx context.Context, app *App, cmd slack.SlashCommand) {
+	if isHelpRequest(cmd.Text) {
+		app.postEphemeralBlocks(cmd.ChannelID, cmd.UserID, queryHelpBlocks())
+		return
+	}
+	if topic, ok := similarTopic(cmd.Text); ok {
+		app.handleSimilarQuery(ctx, cmd, topic)
+		return
+	}
+	if format, period, ok := exportRequest(cmd.Text); ok {
+		app.handleQueryExport(ctx, cmd, format, period)
+		return
+	}
+
+	blocks, queryResp, err := app.buildQueryBlocks(ctx, cmd.Text, nil)
+	if err != nil {
+		logger.Error("Failed to run query: %v", err)
+		if errors.Is(err, services.ErrTimeout) {
+			app.notifyIfTimeout(err, cmd.ChannelID, cmd.UserID)
+			return
+		}
 		app.slackClient.PostEphemeral(
 			cmd.ChannelID,
 			cmd.UserID,
-			slack.MsgOptionText("❌ "+queryResp.Error, false),
+			slack.MsgOptionText("❌ "+err.Error(), false),
 		)
 		return
 	}
 
+	// Post the message
+	_, ts, err := app.slackClient.PostMessage(
+		cmd.ChannelID,
+		slack.MsgOptionBlocks(blocks...),
+	)
+	if err == nil {
+		// Replies in the answer's thread follow up on this question
+		app.queryThreads.Add(cmd.ChannelID, ts, services.QueryTurn{Query: cmd.Text, Parsed: queryResp}, time.Now())
+	}
+
+	if err != nil {
+		logger.Error("Failed to post query response: %v", err)
+		app.slackClient.PostEphemeral(
+			cmd.ChannelID,
+			cmd.UserID,
+			slack.MsgOptionText("❌ Failed to get leave statistics", false),
+		)
+	}
+}
+
+// buildQueryBlocks parses a natural-language question with OpenAI, runs the
+// matching repository query and renders the report as Slack blocks. history
+// holds the earlier questions of the thread, for follow-ups. It also returns
+// the question as understood, for the next follow-up.
+func (a *App) buildQueryBlocks(ctx context.Context, text string, history []services.QueryTurn) ([]slack.Block, *services.QueryResponse, error) {
+	// Parse the query using OpenAI
+	queryResp, err := a.openAI.ParseQuery(ctx, text, history, a.config.Timezone)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if queryResp.Error != "" {
+		return nil, nil, errors.New(queryResp.Error)
+	}
+
+	// Presets are worked out here rather than by the model, which doesn't
+	// know the company's fiscal year or sprints
+	if queryResp.Period != "" {
+		if err := a.applyReportingPeriod(queryResp); err != nil {
+			return nil, nil, err
+		}
+	}
+
 	var blocks []slack.Block
 	blocks = append(blocks, slack.NewHeaderBlock(
 		slack.NewTextBlockObject("plain_text", "📊 Leave Statistics Report", false, false),
 	))
 
+	// "Who WFH'd the most" is a ranking of one type, not of all leave
+	if queryResp.QueryType == "top_employee" && len(queryResp.LeaveTypes) > 0 {
+		queryResp.QueryType = "type_ranking"
+	}
+
 	switch queryResp.QueryType {
 	case "top_employee":
 		// Get employee with highest leaves
-		stat, err := app.leaveRepo.GetTopLeaveEmployee()
+		stat, err := a.leaveRepo.GetTopLeaveEmployee()
 		if err != nil {
 			logger.Error("Failed to get top leave employee: %v", err)
 			blocks = append(blocks, slack.NewSectionBlock(
@@ -374,21 +1317,22 @@ func handleQueryCommand(app *App, cmd slack.SlashCommand) {
 				slack.NewTextBlockObject("mrkdwn",
 					fmt.Sprintf("👑 *Employee with Most Leaves*\n\n"+
 						"*%s*\n"+
-						"• Leave Count: %d\n"+
+						"• Leave Count: %s\n"+
 						"• Types: %s\n"+
-						"• Total Hours: %.1f",
+						"• Total Hours: %.1f (%.1f working days)",
 						stat.Username,
-						stat.LeaveCount,
+						leaveCountText(*stat),
 						stat.LeaveTypes,
-						stat.TotalHours),
+						stat.TotalHours, stat.WorkingDays),
 					false, false),
 				nil, nil,
 			))
+			blocks = withDetails(blocks, repository.LeaveFilter{Usernames: []string{stat.Username}})
 		}
 
 	case "employee_stats":
 		// Get stats for specific employee
-		stats, err := app.leaveRepo.GetEmployeeStats(queryResp.Username)
+		stats, err := a.leaveRepo.GetEmployeeStats(queryResp.Username)
 		if err != nil {
 			logger.Error("Failed to get employee stats: %v", err)
 			blocks = append(blocks, slack.NewSectionBlock(
@@ -404,10 +1348,91 @@ func handleQueryCommand(app *App, cmd slack.SlashCommand) {
 					nil, nil,
 				))
 			} else {
-				// Format employee stats
-				// ... format blocks for employee stats ...
+				blocks = append(blocks, slack.NewSectionBlock(
+					slack.NewTextBlockObject("mrkdwn", employeeStatsText(stats), false, false),
+					nil, nil,
+				))
+				if context := a.peerContextText(queryResp.Username); context != "" {
+					blocks = append(blocks, slack.NewContextBlock("",
+						slack.NewTextBlockObject("mrkdwn", context, false, false)))
+				}
+				blocks = withDetails(blocks, repository.LeaveFilter{Usernames: []string{queryResp.Username}})
+			}
+		}
+
+	case "comparison":
+		comparison, err := a.comparisonBlocks(queryResp)
+		if err != nil {
+			return nil, nil, err
+		}
+		blocks = append(blocks, comparison...)
+
+	case "trend":
+		trend, err := a.trendBlocks(queryResp)
+		if err != nil {
+			return nil, nil, err
+		}
+		blocks = append(blocks, trend...)
+
+	case "type_ranking":
+		leaveTypes, err := queryLeaveTypes(queryResp)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(leaveTypes) == 0 {
+			return nil, nil, fmt.Errorf("which kind of leave should I rank by, e.g. WFH or LATE_ARRIVAL?")
+		}
+		startDate, endDate, err := queryPeriod(queryResp, a.today())
+		if err != nil {
+			return nil, nil, err
+		}
+		limit := queryResp.Limit
+		if limit <= 0 {
+			limit = defaultRankingLimit
+		}
+
+		stats, err := a.leaveRepo.GetLeaveTypeRanking(startDate, endDate.AddDate(0, 0, 1), leaveTypes, limit)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get leave ranking: %v", err)
+		}
+
+		text := fmt.Sprintf("🏆 *Most %s, %s to %s*\n",
+			strings.Join(leaveTypes, ", "), startDate.Format("Jan 2, 2006"), endDate.Format("Jan 2, 2006"))
+		if len(stats) == 0 {
+			text += "\nNo records found for this period."
+		}
+		usernames := make([]string, 0, len(stats))
+		for i, stat := range stats {
+			text += fmt.Sprintf("\n%d. *%s* – %d records, %.1f hours (%.1f working days)",
+				i+1, stat.Username, stat.LeaveCount, stat.TotalHours, stat.WorkingDays)
+			usernames = append(usernames, stat.Username)
+		}
+		blocks = append(blocks, slack.NewSectionBlock(
+			slack.NewTextBlockObject("mrkdwn", text, false, false),
+			nil, nil,
+		))
+		if len(stats) > 0 {
+			blocks = withDetails(blocks, repository.LeaveFilter{
+				Start:      startDate,
+				End:        endDate.AddDate(0, 0, 1),
+				Usernames:  usernames,
+				LeaveTypes: leaveTypes,
+			})
+		}
+
+	case "availability":
+		day := a.today()
+		if queryResp.StartDate != "" {
+			day, err = time.Parse("2006-01-02", queryResp.StartDate)
+			if err != nil {
+				return nil, nil, fmt.Errorf("error parsing date: %v", err)
 			}
 		}
+		availability, err := a.availabilityBlocks(day)
+		if err != nil {
+			return nil, nil, err
+		}
+		blocks = append(blocks, availability...)
 
 	case "period_stats":
 		// Use the dates from query response
@@ -417,21 +1442,12 @@ func handleQueryCommand(app *App, cmd slack.SlashCommand) {
 		// Parse the string dates back to time.Time
 		startDateParsed, err := time.Parse("2006-01-02", startDate)
 		if err != nil {
-			fmt.Printf("Error parsing start date: %v\n", err)
-			return
+			return nil, nil, fmt.Errorf("error parsing start date: %v", err)
 		}
 
 		endDateParsed, err := time.Parse("2006-01-02", endDate)
 		if err != nil {
-			fmt.Printf("Error parsing end date: %v\n", err)
-			return
-		}
-
-		var stats []repository.LeaveStats
-		stats, err = app.leaveRepo.GetLeaveStatsByPeriod(startDateParsed, endDateParsed)
-		if err != nil {
-			logger.Error("Failed to get leave stats: %v", err)
-			return
+			return nil, nil, fmt.Errorf("error parsing end date: %v", err)
 		}
 
 		blocks = append(blocks, slack.NewSectionBlock(
@@ -443,43 +1459,174 @@ func handleQueryCommand(app *App, cmd slack.SlashCommand) {
 			nil, nil,
 		))
 
+		if isTrendBucket(queryResp.GroupBy) {
+			trend, err := a.trendBlocks(queryResp)
+			if err != nil {
+				return nil, nil, err
+			}
+			blocks = append(blocks, trend...)
+			break
+		}
+
+		if queryResp.GroupBy == "employment_type" {
+			typeStats, err := a.leaveRepo.GetLeaveStatsByEmploymentType(startDateParsed, endDateParsed)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to get leave stats: %v", err)
+			}
+			for _, stat := range typeStats {
+				blocks = append(blocks, slack.NewSectionBlock(
+					slack.NewTextBlockObject("mrkdwn",
+						fmt.Sprintf("*%s*\n"+
+							"• People: %d\n"+
+							"• Leave Count: %d\n"+
+							"• Total Hours: %.1f (%.1f working days)",
+							stat.EmploymentType,
+							stat.UserCount,
+							stat.LeaveCount,
+							stat.TotalHours, stat.WorkingDays),
+						false, false),
+					nil, nil,
+				))
+			}
+			blocks = withDetails(blocks, repository.LeaveFilter{Start: startDateParsed, End: endDateParsed})
+			break
+		}
+
+		var comparison *repository.Comparison
+		if queryResp.ComparisonType != "" {
+			comparison = &repository.Comparison{Type: queryResp.ComparisonType, Value: queryResp.ComparisonValue}
+			blocks = append(blocks, slack.NewContextBlock("", slack.NewTextBlockObject("mrkdwn",
+				fmt.Sprintf("Only people with %s %d records", comparisonPhrase(comparison.Type), comparison.Value),
+				false, false)))
+		}
+
+		if queryResp.ExcludeWFH {
+			blocks = append(blocks, slack.NewContextBlock("", slack.NewTextBlockObject("mrkdwn",
+				"Working from home isn't counted", false, false)))
+		}
+
+		var stats []repository.LeaveStats
+		stats, err = a.leaveRepo.GetLeaveStatsByPeriod(startDateParsed, endDateParsed, strings.ToUpper(queryResp.EmploymentType),
+			!queryResp.ExcludeWFH, comparison)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get leave stats: %v", err)
+		}
+		if len(stats) == 0 {
+			blocks = append(blocks, slack.NewSectionBlock(
+				slack.NewTextBlockObject("mrkdwn", "No one matches.", false, false),
+				nil, nil,
+			))
+		}
+
+		details := repository.LeaveFilter{
+			Start:          startDateParsed,
+			End:            endDateParsed,
+			EmploymentType: strings.ToUpper(queryResp.EmploymentType),
+		}
+		if queryResp.ExcludeWFH {
+			details.LeaveTypes = models.LeaveTypeNames(func(t models.LeaveType) bool { return t.Away })
+		}
 		for _, stat := range stats {
 			blocks = append(blocks, slack.NewSectionBlock(
 				slack.NewTextBlockObject("mrkdwn",
 					fmt.Sprintf("*%s*\n"+
-						"• Leave Count: %d\n"+
+						"• Leave Count: %s\n"+
 						"• Types: %s\n"+
-						"• Total Hours: %.1f",
+						"• Total Hours: %.1f (%.1f working days)",
 						stat.Username,
-						stat.LeaveCount,
+						leaveCountText(stat),
 						stat.LeaveTypes,
-						stat.TotalHours),
+						stat.TotalHours, stat.WorkingDays),
 					false, false),
 				nil, nil,
 			))
+			// A filtered list names everyone in it, so the details can too
+			if comparison != nil {
+				details.Usernames = append(details.Usernames, stat.Username)
+			}
+		}
+		if len(stats) > 0 {
+			blocks = withDetails(blocks, details)
 		}
 	}
 
-	// Post the message
-	_, _, err = app.slackClient.PostMessage(
-		cmd.ChannelID,
-		slack.MsgOptionBlocks(blocks...),
-	)
+	blocks = append(blocks, feedbackBlock("feedback", queryFeedbackTarget(text)))
+	return blocks, queryResp, nil
+}
 
-	if err != nil {
-		logger.Error("Failed to post query response: %v", err)
-		app.slackClient.PostEphemeral(
-			cmd.ChannelID,
-			cmd.UserID,
-			slack.MsgOptionText("❌ Failed to get leave statistics", false),
-		)
+// leaveCountText is a record count split into time off and WFH, e.g.
+// "5 (3 absences, 2 remote days)".
+func leaveCountText(stat repository.LeaveStats) string {
+	return fmt.Sprintf("%d (%d absences, %d remote days)", stat.LeaveCount, stat.Absences, stat.RemoteDays)
+}
+
+// defaultRankingLimit is how many people a ranking lists unless the query
+// asks for a number.
+const defaultRankingLimit = 5
+
+// comparisonPhrase words a comparison type for the report, e.g. "more than".
+func comparisonPhrase(comparisonType string) string {
+	switch comparisonType {
+	case "greater_than":
+		return "more than"
+	case "less_than":
+		return "fewer than"
+	case "at_least":
+		return "at least"
+	case "at_most":
+		return "at most"
+	case "not_equal":
+		return "other than"
+	default:
+		return "exactly"
+	}
+}
+
+// queryLeaveTypes returns the kinds of leave the query is about, checked
+// and upper-cased.
+func queryLeaveTypes(queryResp *services.QueryResponse) ([]string, error) {
+	leaveTypes := make([]string, 0, len(queryResp.LeaveTypes))
+	for _, leaveType := range queryResp.LeaveTypes {
+		leaveType = strings.ToUpper(strings.TrimSpace(leaveType))
+		if !isKnownLeaveType(leaveType) {
+			return nil, fmt.Errorf("unknown leave type %q", leaveType)
+		}
+		leaveTypes = append(leaveTypes, leaveType)
+	}
+	return leaveTypes, nil
+}
+
+// queryPeriod returns the query's start and end dates, inclusive, defaulting
+// to the month so far.
+func queryPeriod(queryResp *services.QueryResponse, today time.Time) (time.Time, time.Time, error) {
+	startDate := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+	endDate := today
+
+	var err error
+	if queryResp.StartDate != "" {
+		if startDate, err = time.Parse("2006-01-02", queryResp.StartDate); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("error parsing start date: %v", err)
+		}
+	}
+	if queryResp.EndDate != "" {
+		if endDate, err = time.Parse("2006-01-02", queryResp.EndDate); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("error parsing end date: %v", err)
+		}
 	}
+	if endDate.Before(startDate) {
+		return time.Time{}, time.Time{}, fmt.Errorf("the period ends before it starts")
+	}
+	return startDate, endDate, nil
 }
 
 type LeaveRequest struct {
-	Message string `json:"message"`
+	Message  string `json:"message"`
+	Username string `json:"username,omitempty"` // validates against this user's office
 }
 
+// handleLeaveRequest serves POST /api/leave, parsing a message the way the
+// bot would without saving anything. It spends an OpenAI call per request
+// and isn't limited to a date range, so it needs the admin key.
 func (a *App) handleLeaveRequest(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -492,7 +1639,16 @@ func (a *App) handleLeaveRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response, err := a.openAI.ParseLeaveRequest(req.Message, fmt.Sprintf("%d", time.Now().Unix()))
+	region := services.DefaultRegion()
+	if req.Username != "" {
+		region = a.regionFor(req.Username)
+	}
+
+	response, err := a.openAI.ParseLeaveRequest(r.Context(), req.Message, fmt.Sprintf("%d", time.Now().Unix()), region)
+	if errors.Is(err, services.ErrTimeout) {
+		http.Error(w, err.Error(), http.StatusGatewayTimeout)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -509,23 +1665,38 @@ func (a *App) handleLeaveQuery(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		Query string `json:"query"`
+		Query        string `json:"query"`
+		Start        string `json:"start"`
+		End          string `json:"end"`
+		Period       string `json:"period"`        // "fiscal_year", "quarter" or "sprint", instead of start and end
+		PeriodOffset int    `json:"period_offset"` // -1 for the last period
+		ExcludeWFH   bool   `json:"exclude_wfh"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
-
-	// Get the current time in IST
-	loc, _ := time.LoadLocation("Asia/Kolkata")
-	now := time.Now().In(loc)
+	if req.Period != "" {
+		period := services.QueryResponse{Period: req.Period, PeriodOffset: req.PeriodOffset}
+		if err := a.applyReportingPeriod(&period); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req.Start, req.End = period.StartDate, period.EndDate
+	}
 
 	// Default to last month
-	startDate := time.Date(now.Year(), now.Month()-1, 1, 0, 0, 0, 0, loc)
-	endDate := startDate.AddDate(0, 1, 0).Add(-time.Second)
+	today := a.today()
+	lastMonth := time.Date(today.Year(), today.Month()-1, 1, 0, 0, 0, 0, time.UTC)
+	startDate, endDate, err := requestRange(r, req.Start, req.End, lastMonth, lastMonth.AddDate(0, 1, -1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	endDate = endDate.AddDate(0, 0, 1).Add(-time.Second)
 
 	// Get leave statistics
-	stats, err := a.leaveRepo.GetLeaveStatsByPeriod(startDate, endDate)
+	stats, err := a.leaveRepo.GetLeaveStatsByPeriod(startDate, endDate, "", !req.ExcludeWFH, nil)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -567,18 +1738,95 @@ func main() {
 		logger.Error("Failed to initialize database: %v", err)
 		os.Exit(1)
 	}
-	defer db.Close()
 	logger.Info("Database connected successfully 🗄️")
+	if config.Environment == envStaging {
+		logger.Info("Running as staging: schema %s, channels %s", config.DBSchema, strings.Join(config.StagingChannels, ", "))
+	}
+
+	// A fresh install gets its tables here rather than through cmd/migrate
+	firstRun, err := migrations.IsEmpty(db)
+	if err != nil {
+		logger.Error("Failed to check the database schema: %v", err)
+		os.Exit(1)
+	}
+	if firstRun {
+		if err := migrations.Run(db); err != nil {
+			logger.Error("Failed to create tables: %v", err)
+			os.Exit(1)
+		}
+		logger.Info("Created tables in the empty database")
+	}
+
+	// ctx ends on SIGINT or SIGTERM, which starts the shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	app := NewApp(config, db)
+	app.loadSlackScopes(ctx)
+	go app.runFirstRunSetup(ctx, firstRun)
 
 	// Add HTTP endpoints
-	http.HandleFunc("/api/leave", app.handleLeaveRequest)
-	http.HandleFunc("/api/leave/query", app.handleLeaveQuery)
-	go http.ListenAndServe(":"+config.Port, nil)
+	http.HandleFunc("/api/leave", app.requireAdminKey(app.handleLeaveRequest))
+	http.HandleFunc("/api/leave/query", app.requireViewerAccess(app.handleLeaveQuery))
+	http.HandleFunc("/api/export/leaves", app.requireViewerAccess(app.handleLeaveExport))
+	http.HandleFunc("/api/leave/export", app.requireViewerAccess(app.handleLeaveFileExport))
+	http.HandleFunc("/api/leave/calendar.ics", feedTokenFromQuery(app.requireViewerAccess(app.handleCalendarFeed)))
+	http.HandleFunc("/api/admin/leaves", app.requireAdminKey(app.handleAdminLeaves))
+	http.HandleFunc("/api/admin/leaves/", app.requireAdminKey(app.handleAdminLeave))
+	http.HandleFunc("/api/admin/leaves/merge", app.requireAdminKey(app.handleAdminMerge))
+	http.HandleFunc("/api/admin/command", app.requireAdminKey(app.handleAdminCommand))
+	http.HandleFunc("/api/admin/config", app.requireAdminKey(app.handleConfigSnapshot))
+	http.HandleFunc("/api/bootstrap", app.requireAdminKey(app.handleBootstrap))
+	http.HandleFunc("/api/admin/reports/encashment", app.requireAdminKey(app.handleEncashmentReport))
+	http.HandleFunc("/api/admin/reports/office", app.requireAdminKey(app.handleOfficeReport))
+	http.HandleFunc("/api/admin/reports/compliance", app.requireAdminKey(app.handleComplianceReport))
+	http.HandleFunc("/api/admin/reports/lop", app.requireAdminKey(app.handleLossOfPayReport))
+	http.HandleFunc("/api/admin/reports/usage", app.requireAdminKey(app.handleUsageReport))
+	http.HandleFunc("/api/admin/roster", app.requireAdminKey(app.handleRosterImport))
+	http.HandleFunc("/api/admin/audit/verify", app.requireAdminKey(app.handleAuditVerify))
+	http.HandleFunc("/api/admin/metrics/parse", app.requireAdminKey(app.handleParseMetrics))
+	http.HandleFunc("/metrics", app.handleMetrics)
+	http.HandleFunc(approvalLinkPath, app.handleApprovalLink)
+	http.HandleFunc("/scim/v2/Users", app.requireSCIMToken(app.handleSCIMUsers))
+	http.HandleFunc("/scim/v2/Users/", app.requireSCIMToken(app.handleSCIMUser))
+	if config.SlackMode == slackModeHTTP {
+		http.HandleFunc("/slack/events", app.handleSlackEvents)
+	}
+	server := &http.Server{Addr: ":" + config.Port}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Error("HTTP server error: %v", err)
+			stop()
+		}
+	}()
+
+	go app.runWellnessChecks(ctx)
+	go app.runComplianceReports(ctx)
+	go app.runRecurringLeaves(ctx)
+	go app.runLongLeaveMilestones(ctx)
+	go app.runAccruals(ctx)
+	go app.runLeaveStatements(ctx)
+	go app.runManagerOnePagers(ctx)
+	go app.runWarehouseExport(ctx)
+	go app.runEmbeddings(ctx)
+	go app.runOpsDigest(ctx)
+	go app.runPipelineEventCleanup(ctx)
+	go app.runRateLimitPruning(ctx)
+	go app.runDirectorySync(ctx)
+	go app.runScheduler(ctx)
+
+	if config.SlackMode == slackModeHTTP {
+		logger.Info("Receiving Slack events over HTTP at /slack/events")
+		<-ctx.Done()
+	} else {
+		err = setupSocketModeHandler(ctx, app, config)
+		if ctx.Err() == nil {
+			logger.Error("Socket mode error: %v", err)
+		}
+	}
+	stop()
 
-	if err := setupSocketModeHandler(app, config); err != nil {
-		logger.Error("Socket mode error: %v", err)
+	if !app.shutdown(server, config.ShutdownTimeout) {
 		os.Exit(1)
 	}
 }
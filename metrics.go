@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"slack-leaves-ai-agent/services"
+
+	"github.com/slack-go/slack"
+)
+
+// opsDigestHour is when, on Mondays in the workspace's timezone, the weekly
+// parsing digest goes to the admin channel.
+const opsDigestHour = 9
+
+// parseKey is one counter of parseMetrics. LeaveType is empty when the
+// outcome has none, e.g. an unrelated message.
+type parseKey struct {
+	Outcome   string
+	LeaveType string
+}
+
+// parseMetrics counts how attendance messages were parsed since start, by
+// outcome and leave type. Nothing here is persisted.
+type parseMetrics struct {
+	mu     sync.Mutex
+	counts map[parseKey]int64
+}
+
+func newParseMetrics() *parseMetrics {
+	return &parseMetrics{counts: make(map[parseKey]int64)}
+}
+
+func (m *parseMetrics) Add(outcome, leaveType string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counts[parseKey{outcome, leaveType}]++
+}
+
+// Snapshot returns a copy of the counters.
+func (m *parseMetrics) Snapshot() map[parseKey]int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	counts := make(map[parseKey]int64, len(m.counts))
+	for key, n := range m.counts {
+		counts[key] = n
+	}
+	return counts
+}
+
+// countParses records the outcome of every item parsed from a message.
+func (a *App) countParses(responses []*services.LeaveResponse) {
+	for _, response := range responses {
+		leaveType := response.LeaveType
+		if response.Outcome == services.OutcomeUnrelated {
+			leaveType = ""
+		}
+		a.parseMetrics.Add(response.Outcome, leaveType)
+	}
+}
+
+// handleMetrics serves /metrics in the Prometheus text format. When
+// METRICS_TOKEN is set, scrapers must send it as a bearer token.
+func (a *App) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if a.config.MetricsToken != "" {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(a.config.MetricsToken)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("# HELP latebot_parses_total Attendance items parsed from messages, by outcome and leave type.\n")
+	b.WriteString("# TYPE latebot_parses_total counter\n")
+	counts := a.parseMetrics.Snapshot()
+	keys := make([]parseKey, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Outcome != keys[j].Outcome {
+			return keys[i].Outcome < keys[j].Outcome
+		}
+		return keys[i].LeaveType < keys[j].LeaveType
+	})
+	for _, key := range keys {
+		fmt.Fprintf(&b, "latebot_parses_total{outcome=\"%s\",leave_type=\"%s\"} %d\n",
+			promLabel(key.Outcome), promLabel(key.LeaveType), counts[key])
+	}
+
+	repairs := a.openAI.RepairStats()
+	b.WriteString("# HELP latebot_json_replies_total Model JSON replies, by how they were decoded.\n")
+	b.WriteString("# TYPE latebot_json_replies_total counter\n")
+	for _, c := range []struct {
+		result string
+		n      int64
+	}{{"valid", repairs.Valid}, {"repaired", repairs.Repaired}, {"reasked", repairs.Reasked}, {"failed", repairs.Failed}} {
+		fmt.Fprintf(&b, "latebot_json_replies_total{result=\"%s\"} %d\n", c.result, c.n)
+	}
+
+	parsers := a.openAI.ParserStats()
+	b.WriteString("# HELP latebot_leave_parses_total Leave messages parsed, by the rule parser or the model.\n")
+	b.WriteString("# TYPE latebot_leave_parses_total counter\n")
+	fmt.Fprintf(&b, "latebot_leave_parses_total{parser=\"rules\"} %d\n", parsers.Rules)
+	fmt.Fprintf(&b, "latebot_leave_parses_total{parser=\"model\"} %d\n", parsers.Model)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write([]byte(b.String()))
+}
+
+// promLabel escapes a Prometheus label value.
+func promLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// runOpsDigest posts the week's parse outcomes to the admin channel every
+// Monday morning. The counters live in memory, so after a restart the digest
+// only covers the time since.
+func (a *App) runOpsDigest(ctx context.Context) {
+	loc := a.config.Timezone
+	lastRun := ""
+	last := a.parseMetrics.Snapshot()
+	lastAt := time.Now()
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			now = now.In(loc)
+			today := now.Format("2006-01-02")
+			if lastRun == today || now.Weekday() != time.Monday || now.Hour() < opsDigestHour {
+				continue
+			}
+			lastRun = today
+
+			current := a.parseMetrics.Snapshot()
+			week := make(map[parseKey]int64, len(current))
+			for key, n := range current {
+				if n -= last[key]; n > 0 {
+					week[key] = n
+				}
+			}
+			from := lastAt.In(loc)
+			last, lastAt = current, now
+
+			adminChannel := a.adminChannel()
+			if len(week) == 0 || adminChannel == "" || !a.featureEnabled(flagDigests) {
+				continue
+			}
+			_, _, err := a.slackClient.PostMessageContext(ctx, adminChannel,
+				slack.MsgOptionText(opsDigestText(week, from, now), false))
+			if err != nil {
+				logger.Error("Failed to post ops digest: %v", err)
+			}
+		}
+	}
+}
+
+// opsDigestText summarizes a week of parse outcomes.
+func opsDigestText(counts map[parseKey]int64, from, to time.Time) string {
+	var total int64
+	byOutcome := make(map[string]int64)
+	validByType := make(map[string]int64)
+	for key, n := range counts {
+		total += n
+		byOutcome[key.Outcome] += n
+		if key.Outcome == services.OutcomeValid {
+			validByType[key.LeaveType] += n
+		}
+	}
+
+	lines := []string{
+		fmt.Sprintf("📈 *Weekly parsing digest*, %s to %s", from.Format("Jan 2"), to.Format("Jan 2")),
+		fmt.Sprintf("Items parsed from messages: %d", total),
+	}
+	for _, o := range []struct{ outcome, label string }{
+		{services.OutcomeValid, "✅ Understood"},
+		{services.OutcomeIncomplete, "❓ Asked the author for details"},
+		{services.OutcomeInvalidPastDate, "📅 Refused, date in the past"},
+		{services.OutcomeInvalidTooFar, "🔭 Refused, too far ahead"},
+		{services.OutcomeInvalid, "❌ Refused, other reasons"},
+		{services.OutcomeJSONError, "🧩 Model reply unreadable"},
+		{services.OutcomeUnrelated, "💤 Not about attendance"},
+	} {
+		if n := byOutcome[o.outcome]; n > 0 {
+			lines = append(lines, fmt.Sprintf("• %s: %d (%.0f%%)", o.label, n, 100*float64(n)/float64(total)))
+		}
+	}
+
+	if len(validByType) > 0 {
+		types := make([]string, 0, len(validByType))
+		for leaveType := range validByType {
+			types = append(types, leaveType)
+		}
+		sort.Slice(types, func(i, j int) bool { return validByType[types[i]] > validByType[types[j]] })
+		parts := make([]string, 0, len(types))
+		for _, leaveType := range types {
+			parts = append(parts, fmt.Sprintf("%s %d", leaveType, validByType[leaveType]))
+		}
+		lines = append(lines, "Understood by type: "+strings.Join(parts, " · "))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// ApprovalDelegate is who approves for an approver while they're on leave.
+type ApprovalDelegate struct {
+	ApproverID  string     `json:"approver_id"`
+	BackupID    string     `json:"backup_id"`
+	ActiveUntil *time.Time `json:"active_until,omitempty"` // last day of the absence the backup is covering, nil when not covering
+	UpdatedBy   string     `json:"updated_by"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
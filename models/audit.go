@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+const (
+	RoleAdmin = "admin"
+	RoleHR    = "hr"
+)
+
+type AuditEntry struct {
+	ID        int64     `json:"id"`
+	Actor     string    `json:"actor"`
+	Action    string    `json:"action"`
+	LeaveID   int64     `json:"leave_id,omitempty"`
+	Before    string    `json:"before,omitempty"`
+	After     string    `json:"after,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	PrevHash  string    `json:"prev_hash,omitempty"`
+	Hash      string    `json:"hash,omitempty"`
+}
+
+// AuditChainStatus is the result of checking the audit log's hash chain.
+type AuditChainStatus struct {
+	Valid    bool   `json:"valid"`
+	Entries  int    `json:"entries"`           // chained entries checked
+	Legacy   int    `json:"legacy"`            // entries written before chaining was enabled
+	HeadID   int64  `json:"head_id,omitempty"` // latest chained entry
+	HeadHash string `json:"head_hash,omitempty"`
+	BrokenAt int64  `json:"broken_at,omitempty"` // first entry that fails verification
+	Problem  string `json:"problem,omitempty"`
+}
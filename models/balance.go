@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Balance ledger entry types. Accruals and grants add to a balance, debits
+// take leave out of it (and put it back when the leave is cancelled or
+// shortened), and adjustments are manual corrections either way.
+const (
+	LedgerGrant      = "GRANT"
+	LedgerAccrual    = "ACCRUAL"
+	LedgerDebit      = "DEBIT"
+	LedgerAdjustment = "ADJUSTMENT"
+)
+
+// LedgerAnnual is the balance category of the annual entitlement, which
+// accruals and leave debits post to. Other categories (e.g. CASUAL or
+// COMP_OFF) only hold what admins grant.
+const LedgerAnnual = "ANNUAL"
+
+// LedgerCompOff is the balance category of compensatory days off, granted
+// for working weekends or holidays.
+const LedgerCompOff = "COMP_OFF"
+
+// LedgerEntry is one line of a user's leave balance ledger. Entries are never
+// changed or removed; a balance is the sum of its entries, and each says why
+// it was made.
+type LedgerEntry struct {
+	ID            int64     `json:"id"`
+	Username      string    `json:"username"`
+	EntryType     string    `json:"entry_type"`
+	Category      string    `json:"category"`
+	Days          float64   `json:"days"` // positive adds to the balance
+	EffectiveDate time.Time `json:"effective_date"`
+	Reason        string    `json:"reason"`
+	LeaveID       int64     `json:"leave_id,omitempty"`
+	CreatedBy     string    `json:"created_by"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Balance sums a user's annual-leave ledger for a year.
+type Balance struct {
+	Username    string  `json:"username"`
+	Year        int     `json:"year"`
+	Entitlement float64 `json:"entitlement"` // days per year under the user's policy
+	Accrued     float64 `json:"accrued"`
+	Granted     float64 `json:"granted"` // grants and adjustments
+	Used        float64 `json:"used"`    // net debits, as a positive number
+	Available   float64 `json:"available"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// ChannelDigest is where and when the daily availability digest is posted.
+type ChannelDigest struct {
+	ChannelID   string    `json:"channel_id"`
+	Schedule    string    `json:"schedule"`           // cron expression, e.g. "0 9 * * 1-5"
+	Timezone    string    `json:"timezone,omitempty"` // IANA name; empty means the company timezone
+	MembersOnly bool      `json:"members_only"`       // only list the channel's members
+	UpdatedBy   string    `json:"updated_by"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
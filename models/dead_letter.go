@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Dead letter statuses.
+const (
+	DeadLetterPending   = "pending"
+	DeadLetterReplayed  = "replayed"
+	DeadLetterDiscarded = "discarded"
+)
+
+// DeadLetter is a Slack message that failed to process, kept with its error
+// so an admin can replay it once the cause is fixed.
+type DeadLetter struct {
+	ID         int64      `json:"id"`
+	Channel    string     `json:"channel"`
+	MessageTS  string     `json:"message_ts"`
+	UserID     string     `json:"user_id"`
+	Text       string     `json:"text"`
+	Stage      string     `json:"stage"` // where it failed, e.g. "parse"
+	Error      string     `json:"error"`
+	Status     string     `json:"status"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	ResolvedBy string     `json:"resolved_by,omitempty"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// FeatureFlag turns a capability on or off in one workspace, overriding its
+// default.
+type FeatureFlag struct {
+	TeamID    string    `json:"team_id"`
+	Flag      string    `json:"flag"`
+	Enabled   bool      `json:"enabled"`
+	UpdatedBy string    `json:"updated_by"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+const (
+	FeedbackParse = "parse" // a confirmation of a recorded leave
+	FeedbackQuery = "query" // a /query answer
+)
+
+// Feedback is a 👍 (Rating 1) or 👎 (Rating -1) on a bot response. Parse
+// feedback keeps a snapshot of the message and what it was parsed into, so
+// later edits to the record don't change what was rated.
+type Feedback struct {
+	ID           int64     `json:"id"`
+	Kind         string    `json:"kind"`
+	Target       string    `json:"target"`
+	LeaveID      int64     `json:"leave_id,omitempty"`
+	QueryText    string    `json:"query_text,omitempty"`
+	OriginalText string    `json:"original_text,omitempty"`
+	ParsedData   string    `json:"parsed_data,omitempty"`
+	UserID       string    `json:"user_id"`
+	Rating       int       `json:"rating"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ParseExample is a message and its correct parse, shown to the parser as a
+// few-shot example.
+type ParseExample struct {
+	ID        int64     `json:"id"`
+	Message   string    `json:"message"`
+	PostedAt  time.Time `json:"posted_at"`
+	LeaveType string    `json:"leave_type"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	Reason    string    `json:"reason"`
+	Source    string    `json:"source"`
+	LeaveID   int64     `json:"leave_id,omitempty"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+const (
+	ExampleConfirmed  = "confirmed"  // the author gave the parse a 👍
+	ExampleCorrection = "correction" // an admin corrected a misparse
+	ExampleUnparsed   = "unparsed"   // an admin parsed a message the bot missed
+)
+
+const (
+	ReviewCorrected = "corrected" // an admin fixed the record
+	ReviewDismissed = "dismissed" // the record was right after all
+)
+
+// MisparseReport is a recorded leave that users flagged with a 👎, as it
+// waits in the admin review queue.
+type MisparseReport struct {
+	LeaveID        int64     `json:"leave_id"`
+	OriginalText   string    `json:"original_text"`
+	ParsedData     string    `json:"parsed_data"`
+	FlaggedBy      []string  `json:"flagged_by"`
+	FirstFlaggedAt time.Time `json:"first_flagged_at"`
+}
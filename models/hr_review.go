@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// HR review statuses.
+const (
+	HRReviewOpen     = "OPEN"
+	HRReviewResolved = "RESOLVED"
+)
+
+// HRReview is a record in HR's review queue, sent there by the policy rules
+// it broke. It's reviewed in addition to any manager approval.
+type HRReview struct {
+	ID         int64      `json:"id"`
+	LeaveID    int64      `json:"leave_id"`
+	Username   string     `json:"username"`
+	Reasons    []string   `json:"reasons"`
+	Status     string     `json:"status"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	ResolvedBy string     `json:"resolved_by,omitempty"`
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"encoding/json"
+	"fmt"
 	"time"
 )
 
@@ -16,6 +17,67 @@ type Leave struct {
 	LeaveType    string    `json:"leave_type"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
+	RecurrenceID int64     `json:"recurrence_id,omitempty"`
+
+	// PrivateReason holds details behind a public reason such as "personal
+	// reasons". Only admins see it; it's never posted in a channel.
+	PrivateReason string `json:"private_reason,omitempty"`
+
+	// Recurrence is the RRULE of the series a freshly parsed leave starts.
+	// The series is stored as a RecurringLeave, not on the record.
+	Recurrence string `json:"recurrence,omitempty"`
+
+	// ParseConfidence is the parser's confidence in a record it just
+	// produced. It isn't stored with the record, only in its audit entry.
+	ParseConfidence float64 `json:"parse_confidence,omitempty"`
+
+	// Trace explains how a freshly parsed leave was read. Like the
+	// confidence, it's only stored in the record's audit entry.
+	Trace *ParseTrace `json:"trace,omitempty"`
+
+	// Sick marks a freshly parsed leave taken because the author is unwell.
+	// It's stored so sick days can be counted, but isn't read back with the
+	// record; under the sick-day privacy policy the reason and message are
+	// replaced with "sick" as well.
+	Sick bool `json:"sick,omitempty"`
+
+	// Status is where the record is in the approval workflow. Records of
+	// types that don't need approval are created APPROVED.
+	Status string `json:"status,omitempty"`
+
+	// CalendarEventID is the record's event on the team's Google Calendar,
+	// empty when it has none.
+	CalendarEventID string `json:"calendar_event_id,omitempty"`
+
+	// DeletedAt is set once the record is cancelled. Cancelled records are
+	// only returned by ListUpdatedBetween, for exports.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// Leave approval statuses. Rejected records are kept for the audit trail
+// but left out of every listing and report.
+const (
+	LeaveStatusPending  = "PENDING"
+	LeaveStatusApproved = "APPROVED"
+	LeaveStatusRejected = "REJECTED"
+)
+
+// FormatDuration renders the span between start and end the same way the
+// parser does ("9 hours", "2.5 hours", "3 days").
+func FormatDuration(start, end time.Time) string {
+	d := end.Sub(start)
+	if d >= 24*time.Hour {
+		days := int(d.Hours()/24 + 0.5)
+		if days == 1 {
+			return "1 day"
+		}
+		return fmt.Sprintf("%d days", days)
+	}
+	hours := d.Hours()
+	if hours == float64(int(hours)) {
+		return fmt.Sprintf("%d hours", int(hours))
+	}
+	return fmt.Sprintf("%.1f hours", hours)
 }
 
 type EmployeeLeaveStats struct {
@@ -23,11 +85,87 @@ type EmployeeLeaveStats struct {
 	LeaveCount int     `json:"leave_count"`
 	LeaveTypes string  `json:"leave_types"`
 	TotalHours float64 `json:"total_hours"`
+
+	// WorkingDays is TotalHours as working days, skipping weekends and
+	// office holidays.
+	WorkingDays float64 `json:"working_days"`
 }
 
+type EncashmentLine struct {
+	Username       string  `json:"username"`
+	EmploymentType string  `json:"employment_type"`
+	Entitlement    float64 `json:"entitlement"`
+	Used           float64 `json:"used"`
+	Remaining      float64 `json:"remaining"`
+	Encashable     float64 `json:"encashable"`
+}
+
+// LeaveStatement is a user's monthly leave statement: their annual leave
+// accrued, used and remaining for the year so far from the balance ledger,
+// the month's ledger entries, and what they logged per category.
+type LeaveStatement struct {
+	Username      string              `json:"username"`
+	Month         time.Time           `json:"month"`
+	Entitlement   float64             `json:"entitlement"`     // days per year
+	Accrued       float64             `json:"accrued"`         // earned by the end of Month
+	Granted       float64             `json:"granted"`         // grants and adjustments by the end of Month
+	Used          float64             `json:"used"`            // year to date
+	UsedThisMonth float64             `json:"used_this_month"` // during Month
+	Available     float64             `json:"available"`       // accrued and granted less used
+	Remaining     float64             `json:"remaining"`       // entitlement and granted less used
+	LossOfPay     float64             `json:"loss_of_pay"`     // working days of unpaid leave during Month
+	Entries       []LedgerEntry       `json:"entries"`         // ledger entries effective during Month
+	Categories    []StatementCategory `json:"categories"`
+}
+
+type StatementCategory struct {
+	LeaveType        string  `json:"leave_type"`
+	Records          int     `json:"records"`
+	Days             float64 `json:"days"`
+	RecordsThisMonth int     `json:"records_this_month"`
+	DaysThisMonth    float64 `json:"days_this_month"`
+}
+
+// ComplianceLine is one user's standing against the hybrid office-day policy
+// for a month.
+type ComplianceLine struct {
+	Username        string `json:"username"`
+	ManagerID       string `json:"manager_id,omitempty"`
+	RequiredPerWeek int    `json:"required_per_week"`
+	Weeks           int    `json:"weeks"`       // weeks with at least one required office day
+	WeeksShort      int    `json:"weeks_short"` // weeks below the requirement
+	OfficeDays      int    `json:"office_days"`
+	WFHDays         int    `json:"wfh_days"`
+	Compliant       bool   `json:"compliant"`
+	MonthsShort     int    `json:"months_short"` // consecutive non-compliant months ending this one
+}
+
+type OfficeUtilizationLine struct {
+	Weekday     string  `json:"weekday"`
+	Days        int     `json:"days"`          // how many of this weekday the period covered
+	AvgInOffice float64 `json:"avg_in_office"` // people in the office on an average such day
+	AvgWFH      float64 `json:"avg_wfh"`
+	OfficeShare float64 `json:"office_share"` // in-office share of logged office/WFH days, 0-1
+	OfficeUsers int     `json:"office_users"`
+}
+
+const (
+	EmploymentEmployee   = "EMPLOYEE"
+	EmploymentContractor = "CONTRACTOR"
+)
+
 type Employee struct {
-	Username string `json:"username"`
-	// Add other relevant fields as necessary
+	Username       string `json:"username"`
+	SlackUserID    string `json:"slack_user_id,omitempty"`
+	EmploymentType string `json:"employment_type,omitempty"`
+	ExternalID     string `json:"external_id,omitempty"`
+	Email          string `json:"email,omitempty"`
+	FullName       string `json:"full_name,omitempty"`
+	Department     string `json:"department,omitempty"`
+	ManagerID      string `json:"manager_slack_id,omitempty"`
+	LocationCode   string `json:"location_code,omitempty"`
+	Timezone       string `json:"timezone,omitempty"`
+	Active         bool   `json:"active"`
 }
 
 type LeaveResponse struct {
@@ -0,0 +1,170 @@
+package models
+
+// Sections of the availability digest a leave type is listed under.
+const (
+	DigestOnLeave = "on_leave"
+	DigestRemote  = "remote"
+	DigestLate    = "late"
+	DigestEarly   = "early"
+)
+
+// LeaveType describes one value of Leave.LeaveType. Adding a type means
+// adding it to LeaveTypes; everything that lists or labels types reads it
+// from there.
+type LeaveType struct {
+	Name        string // stored in leave_type, e.g. "FULL_DAY"
+	Label       string // what confirmations call it, e.g. "full day leave"
+	Emoji       string
+	Status      string // the status line of its confirmation
+	Description string // what /leave-help says it's for
+	Example     string // a message /leave-help gives as an example
+
+	// DayOff types keep their owner away for whole days; Away types take
+	// them away from work for at least part of a day.
+	DayOff bool
+	Away   bool
+
+	// CalendarSymbol is its cell in the team calendar. When a user has
+	// several records on one day, the highest CalendarRank is shown.
+	CalendarSymbol string
+	CalendarRank   int
+
+	// Digest is the availability digest section it's listed under, or ""
+	// when it isn't listed.
+	Digest string
+}
+
+// LeaveTypes are the leave types records can have. They are built in, not
+// configured.
+var LeaveTypes = []LeaveType{
+	{
+		Name:           "FULL_DAY",
+		Label:          "full day leave",
+		Emoji:          "🌴",
+		Status:         "🌴 Out of office",
+		Description:    "Out for one or more whole days",
+		Example:        "on leave tomorrow and Friday",
+		DayOff:         true,
+		Away:           true,
+		CalendarSymbol: "O",
+		CalendarRank:   5,
+		Digest:         DigestOnLeave,
+	},
+	{
+		Name:           "HALF_DAY",
+		Label:          "half day leave",
+		Emoji:          "🌓",
+		Status:         "🌓 Partially available",
+		Description:    "Out for the first or second half of the day",
+		Example:        "taking the afternoon off",
+		Away:           true,
+		CalendarSymbol: "h",
+		CalendarRank:   4,
+		Digest:         DigestOnLeave,
+	},
+	{
+		Name:           "WFH",
+		Label:          "WFH",
+		Emoji:          "🏠",
+		Status:         "🏠 Working remotely",
+		Description:    "Working from home",
+		Example:        "wfh today",
+		CalendarSymbol: "W",
+		CalendarRank:   3,
+		Digest:         DigestRemote,
+	},
+	{
+		Name:           "IN_OFFICE",
+		Label:          "office day",
+		Emoji:          "🏢",
+		Status:         "🏢 In the office",
+		Description:    "Coming in to the office",
+		Example:        "wfo on Thursday",
+		CalendarSymbol: "I",
+		CalendarRank:   1,
+	},
+	{
+		Name:           "LATE_ARRIVAL",
+		Label:          "late arrival",
+		Emoji:          "⏰",
+		Status:         "⏰ Arriving late",
+		Description:    "Starting later than usual",
+		Example:        "running late, in by 11",
+		Away:           true,
+		CalendarSymbol: "L",
+		CalendarRank:   2,
+		Digest:         DigestLate,
+	},
+	{
+		Name:           "EARLY_DEPARTURE",
+		Label:          "early departure",
+		Emoji:          "🏃",
+		Status:         "🏃 Leaving early",
+		Description:    "Leaving before the end of the day",
+		Example:        "need to leave at 4 today",
+		Away:           true,
+		CalendarSymbol: "L",
+		CalendarRank:   2,
+		Digest:         DigestEarly,
+	},
+	{
+		Name:           "APPOINTMENT",
+		Label:          "appointment",
+		Emoji:          "🩺",
+		Status:         "🩺 Away for an appointment",
+		Description:    "Away for part of the day, to the minute",
+		Example:        "out from 2:30 to 4 for a dentist appointment",
+		Away:           true,
+		CalendarSymbol: "a",
+		CalendarRank:   2,
+		Digest:         DigestOnLeave,
+	},
+	{
+		Name:           "PARENTAL",
+		Label:          "parental leave",
+		Emoji:          "👶",
+		Status:         "👶 On parental leave",
+		Description:    "Parental leave, which can be booked further ahead and run for months",
+		Example:        "on parental leave from June 3 to August 30",
+		DayOff:         true,
+		Away:           true,
+		CalendarSymbol: "O",
+		CalendarRank:   5,
+		Digest:         DigestOnLeave,
+	},
+	{
+		Name:           "LOSS_OF_PAY",
+		Label:          "loss-of-pay leave",
+		Emoji:          "🌴",
+		Status:         "🌴 Out of office",
+		Description:    "Unpaid leave (loss of pay), deducted from pay rather than your leave balance",
+		Example:        "unpaid leave on Friday",
+		DayOff:         true,
+		Away:           true,
+		CalendarSymbol: "O",
+		CalendarRank:   5,
+		Digest:         DigestOnLeave,
+	},
+}
+
+// FindLeaveType returns the leave type called name.
+func FindLeaveType(name string) (LeaveType, bool) {
+	for _, leaveType := range LeaveTypes {
+		if leaveType.Name == name {
+			return leaveType, true
+		}
+	}
+	return LeaveType{}, false
+}
+
+// LeaveTypeNames returns the names of the leave types matching keep, or of
+// all of them when keep is nil, in table order.
+func LeaveTypeNames(keep func(LeaveType) bool) []string {
+	var names []string
+	for _, leaveType := range LeaveTypes {
+		if keep == nil || keep(leaveType) {
+			names = append(names, leaveType.Name)
+		}
+	}
+	return names
+}
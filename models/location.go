@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+type Location struct {
+	Code            string   `json:"code"`
+	Name            string   `json:"name"`
+	Timezone        string   `json:"timezone"`
+	AnnualLeaveDays *float64 `json:"annual_leave_days,omitempty"` // overrides the company default
+	MaxAdvanceDays  *int     `json:"max_advance_days,omitempty"`  // overrides the 30-day booking window
+	MinOfficeDays   *int     `json:"min_office_days,omitempty"`   // overrides HYBRID_MIN_OFFICE_DAYS
+}
+
+type Holiday struct {
+	LocationCode string    `json:"location_code"`
+	Date         time.Time `json:"date"`
+	Name         string    `json:"name"`
+}
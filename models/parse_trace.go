@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// ParseTrace follows a parsed record through the pipeline, so the bot can
+// answer "why did you record this?". It's kept in the record's audit entry,
+// not on the record.
+type ParseTrace struct {
+	Source     string    `json:"source"`    // "message" or "shortcut"
+	ParsedAt   time.Time `json:"parsed_at"` // relative dates were read from this moment
+	Timezone   string    `json:"timezone"`
+	Phrases    []string  `json:"phrases,omitempty"`
+	Defaults   []string  `json:"defaults,omitempty"`
+	Confidence float64   `json:"confidence"`
+
+	// Steps are what happened to the parse before it was saved, e.g. being
+	// held for the author's confirmation.
+	Steps []string `json:"steps,omitempty"`
+}
+
+// AddStep notes a step, doing nothing on a nil trace so callers needn't
+// check whether the record was parsed.
+func (t *ParseTrace) AddStep(step string) {
+	if t != nil {
+		t.Steps = append(t.Steps, step)
+	}
+}
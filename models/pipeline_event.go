@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Pipeline stages a message goes through, in order. A message stops at
+// "skipped" when it isn't processed further.
+const (
+	StageReceived  = "received"
+	StageSkipped   = "skipped"
+	StageParsed    = "parsed"
+	StageRejected  = "rejected"
+	StageValidated = "validated"
+	StageHeld      = "held" // waiting for the author to confirm a shaky parse
+	StageSaved     = "saved"
+	StageFailed    = "failed"
+	StageConfirmed = "confirmed"
+)
+
+// PipelineEvent is one step of processing a Slack message, kept for
+// debugging "the bot ignored my message".
+type PipelineEvent struct {
+	ID        int64     `json:"id"`
+	Channel   string    `json:"channel"`
+	MessageTS string    `json:"message_ts"`
+	UserID    string    `json:"user_id"`
+	Stage     string    `json:"stage"`
+	Detail    string    `json:"detail,omitempty"`
+	LeaveID   int64     `json:"leave_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// RecurringLeave is a repeating leave ("WFH every other Friday"). Its
+// occurrences are created as ordinary leave records, a few weeks at a time,
+// up to MaterializedUntil.
+type RecurringLeave struct {
+	ID                int64      `json:"id"`
+	Username          string     `json:"username"`
+	OriginalText      string     `json:"original_text"`
+	LeaveType         string     `json:"leave_type"`
+	Reason            string     `json:"reason"`
+	RRule             string     `json:"rrule"`
+	DTStart           time.Time  `json:"dtstart"`
+	DurationMinutes   int        `json:"duration_minutes"`
+	MaterializedUntil time.Time  `json:"materialized_until"`
+	CreatedBy         string     `json:"created_by"`
+	CreatedAt         time.Time  `json:"created_at"`
+	CancelledAt       *time.Time `json:"cancelled_at,omitempty"`
+}
+
+// Occurrence builds the leave record of the occurrence starting at start.
+func (r *RecurringLeave) Occurrence(start time.Time) *Leave {
+	end := start.Add(time.Duration(r.DurationMinutes) * time.Minute)
+	return &Leave{
+		Username:     r.Username,
+		OriginalText: r.OriginalText,
+		StartTime:    start,
+		EndTime:      end,
+		Duration:     FormatDuration(start, end),
+		Reason:       r.Reason,
+		LeaveType:    r.LeaveType,
+		RecurrenceID: r.ID,
+	}
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Unparsed message statuses.
+const (
+	UnparsedPending   = "pending"
+	UnparsedExample   = "example"   // an admin added it to the parse examples
+	UnparsedDismissed = "dismissed" // an admin found nothing to learn from it
+)
+
+// UnparsedMessage is a message classified as attendance that the parser got
+// nothing out of. Admins review them weekly for phrasings the bot misses.
+type UnparsedMessage struct {
+	ID         int64      `json:"id"`
+	Channel    string     `json:"channel"`
+	MessageTS  string     `json:"message_ts"`
+	UserID     string     `json:"user_id"`
+	Text       string     `json:"text"`
+	Reason     string     `json:"reason"`
+	Status     string     `json:"status"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	ResolvedBy string     `json:"resolved_by,omitempty"`
+}
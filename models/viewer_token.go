@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// ViewerToken grants an external auditor read-only API access to records in
+// [ScopeStart, ScopeEnd] until ExpiresAt. Only a hash of the token itself is
+// stored.
+type ViewerToken struct {
+	ID         int64      `json:"id"`
+	Label      string     `json:"label"`
+	ScopeStart time.Time  `json:"scope_start"`
+	ScopeEnd   time.Time  `json:"scope_end"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	CreatedBy  string     `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Usable reports whether the token can still be used at now.
+func (t *ViewerToken) Usable(now time.Time) bool {
+	return t.RevokedAt == nil && now.Before(t.ExpiresAt)
+}
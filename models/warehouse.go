@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// WarehouseExport is one run of the nightly warehouse export. The next run
+// picks up leaves updated after LeavesThrough and audit entries after
+// AuditThrough.
+type WarehouseExport struct {
+	ID            int64     `json:"id"`
+	StartedAt     time.Time `json:"started_at"`
+	LeavesThrough time.Time `json:"leaves_through"`
+	AuditThrough  int64     `json:"audit_through"`
+	LeaveRows     int       `json:"leave_rows"`
+	AuditRows     int       `json:"audit_rows"`
+	ManifestKey   string    `json:"manifest_key"`
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+
+	"slack-leaves-ai-agent/services"
+
+	"github.com/slack-go/slack"
+)
+
+// buildNotifier assembles the notification channels listed in NOTIFY_CHANNELS
+// (default: slack only).
+func buildNotifier(config *Config, slackClient *slack.Client) *services.MultiNotifier {
+	channels := config.NotifyChannels
+	if len(channels) == 0 {
+		channels = []string{"slack"}
+	}
+
+	var notifiers []services.Notifier
+	for _, channel := range channels {
+		switch channel {
+		case "slack":
+			notifiers = append(notifiers, services.NewSlackNotifier(slackClient))
+		case "email":
+			if config.SMTPHost == "" {
+				logger.Error("Email notifications enabled but SMTP_HOST is not set")
+				continue
+			}
+			notifiers = append(notifiers, services.NewEmailNotifier(
+				config.SMTPHost,
+				config.SMTPPort,
+				config.SMTPUsername,
+				config.SMTPPassword,
+				config.SMTPFrom,
+			))
+		case "webhook":
+			if config.NotifyWebhookURL == "" {
+				logger.Error("Webhook notifications enabled but NOTIFY_WEBHOOK_URL is not set")
+				continue
+			}
+			notifiers = append(notifiers, services.NewWebhookNotifier(config.NotifyWebhookURL))
+		default:
+			logger.Error("Unknown notification channel %q", channel)
+		}
+	}
+
+	return services.NewMultiNotifier(notifiers...)
+}
+
+// buildApprovalMailer returns the email channel approvers are reached on
+// when they don't answer in Slack, whether or not NOTIFY_CHANNELS lists
+// email. It's nil when SMTP_HOST isn't set.
+func buildApprovalMailer(config *Config) services.Notifier {
+	if config.SMTPHost == "" {
+		return nil
+	}
+	return services.NewEmailNotifier(config.SMTPHost, config.SMTPPort, config.SMTPUsername, config.SMTPPassword, config.SMTPFrom)
+}
+
+// notifyUser sends a notification to a Slack user over every configured
+// channel, looking up their email for the channels that need it. pre_notify
+// hooks can drop it or change its text first.
+func (a *App) notifyUser(ctx context.Context, slackUserID, subject, text string) {
+	if err := a.notifyVia(ctx, a.notifier, slackUserID, subject, text); err != nil {
+		logger.Error("Failed to notify %s: %v", slackUserID, err)
+	}
+}
+
+// notifyVia is notifyUser over the given notifier only.
+func (a *App) notifyVia(ctx context.Context, notifier services.Notifier, slackUserID, subject, text string) error {
+	to := services.Recipient{SlackID: slackUserID}
+	if user, err := a.slackClient.GetUserInfoContext(ctx, slackUserID); err == nil {
+		to.Name = user.Name
+		to.Email = user.Profile.Email
+	}
+
+	n := services.Notification{
+		To:      to,
+		Subject: subject,
+		Text:    text,
+	}
+	result := a.hooks.Run(ctx, services.HookEvent{Hook: services.HookPreNotify, Notification: &n})
+	if result.Suppress {
+		logger.Debug("Notification to %s suppressed by a hook", slackUserID)
+		return nil
+	}
+	if result.Text != "" {
+		n.Text = result.Text
+	}
+
+	return notifier.Notify(ctx, n)
+}
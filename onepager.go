@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// runManagerOnePagers DMs every manager a summary of their team's next week
+// on Fridays at 16:00 IST, when MANAGER_WEEKLY_DIGEST is on.
+func (a *App) runManagerOnePagers(ctx context.Context) {
+	if !a.config.ManagerWeeklyDigest {
+		return
+	}
+
+	loc := a.config.Timezone
+	lastRun := ""
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			now = now.In(loc)
+			today := now.Format("2006-01-02")
+			if lastRun == today || now.Weekday() != time.Friday || now.Hour() < 16 {
+				continue
+			}
+			lastRun = today
+			if !a.featureEnabled(flagDigests) {
+				continue
+			}
+			a.sendManagerOnePagers(ctx, now)
+		}
+	}
+}
+
+func (a *App) sendManagerOnePagers(ctx context.Context, now time.Time) {
+	managers, err := a.employeeRepo.GetManagers()
+	if err != nil {
+		logger.Error("Failed to load managers: %v", err)
+		return
+	}
+	inactive, err := a.employeeRepo.GetInactive()
+	if err != nil {
+		logger.Error("Failed to load departed employees: %v", err)
+		return
+	}
+
+	teams := make(map[string][]string)
+	for username, managerID := range managers {
+		if managerID == "" || inactive[username] {
+			continue
+		}
+		teams[managerID] = append(teams[managerID], username)
+	}
+
+	// Stored times are office wall-clock times, so the dates are used as-is
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	monday := today.AddDate(0, 0, (8-int(today.Weekday()))%7)
+	if monday.Equal(today) {
+		monday = monday.AddDate(0, 0, 7)
+	}
+
+	for managerID, team := range teams {
+		sort.Strings(team)
+		blocks, err := a.managerOnePager(monday, team)
+		if err != nil {
+			logger.Error("Failed to build one-pager for %s: %v", managerID, err)
+			continue
+		}
+		_, _, err = a.slackClient.PostMessageContext(ctx, managerID,
+			slack.MsgOptionText("📋 Your team's week of "+monday.Format("Jan 2"), false),
+			slack.MsgOptionBlocks(blocks...),
+		)
+		if err != nil {
+			logger.Error("Failed to send one-pager to %s: %v", managerID, err)
+		}
+	}
+	logger.Info("Sent %d manager one-pagers for the week of %s", len(teams), monday.Format("2006-01-02"))
+}
+
+// managerOnePager summarises a team's working week starting on monday: who
+// is out, pending approvals, policy flags and the share of the team
+// available each day.
+func (a *App) managerOnePager(monday time.Time, team []string) ([]slack.Block, error) {
+	saturday := monday.AddDate(0, 0, 5)
+	onTeam := make(map[string]bool, len(team))
+	for _, username := range team {
+		onTeam[username] = true
+	}
+
+	leaves, err := a.leaveRepo.ListBetween(monday, saturday)
+	if err != nil {
+		return nil, fmt.Errorf("error loading leave: %v", err)
+	}
+	statuses, err := a.leaveRepo.GetDailyStatuses(monday, saturday)
+	if err != nil {
+		return nil, fmt.Errorf("error loading daily statuses: %v", err)
+	}
+
+	var out []string
+	var flags []string
+	seenFlags := make(map[string]bool)
+	for i := range leaves {
+		leave := &leaves[i]
+		if !onTeam[leave.Username] || (!isDayOff(leave.LeaveType) && leave.LeaveType != "HALF_DAY") {
+			continue
+		}
+		out = append(out, fmt.Sprintf("• *%s* – %s, %s", leave.Username, strings.ToLower(strings.ReplaceAll(leave.LeaveType, "_", " ")), formatLeaveSpan(leave)))
+		for _, v := range a.evaluateLeave(leave) {
+			if v.Message == "" || v.Day.Before(monday) || !v.Day.Before(saturday) || seenFlags[v.Message] {
+				continue
+			}
+			seenFlags[v.Message] = true
+			flags = append(flags, "• "+v.Message)
+		}
+	}
+
+	// A day off takes a whole person out of the team's capacity, a half day half
+	away := make(map[string]map[string]float64)
+	for _, status := range statuses {
+		if !onTeam[status.Username] {
+			continue
+		}
+		day := status.Day.Format("2006-01-02")
+		if away[day] == nil {
+			away[day] = make(map[string]float64)
+		}
+		switch {
+		case isDayOff(status.LeaveType):
+			away[day][status.Username] = 1
+		case status.LeaveType == "HALF_DAY" && away[day][status.Username] < 0.5:
+			away[day][status.Username] = 0.5
+		}
+	}
+	var capacity []string
+	for day := monday; day.Before(saturday); day = day.AddDate(0, 0, 1) {
+		var absent float64
+		for _, share := range away[day.Format("2006-01-02")] {
+			absent += share
+		}
+		percent := 100 * (float64(len(team)) - absent) / float64(len(team))
+		capacity = append(capacity, fmt.Sprintf("%s *%.0f%%*", day.Format("Mon"), percent))
+	}
+
+	section := func(title string, lines []string, empty string) slack.Block {
+		text := "*" + title + "*\n"
+		if len(lines) == 0 {
+			text += empty
+		} else {
+			text += strings.Join(lines, "\n")
+		}
+		return slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", text, false, false), nil, nil)
+	}
+
+	return []slack.Block{
+		slack.NewHeaderBlock(slack.NewTextBlockObject("plain_text",
+			fmt.Sprintf("📋 Your team, week of %s", monday.Format("Jan 2")), true, false)),
+		slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn",
+			fmt.Sprintf("*Capacity* (%d people)\n%s", len(team), strings.Join(capacity, " · ")), false, false), nil, nil),
+		section("🌴 Out", out, "Nobody on your team is out."),
+		section("⏳ Pending approvals", nil, "Nothing waiting for you."),
+		section("⚠️ Policy flags", flags, "No flags."),
+		slack.NewContextBlock("", slack.NewTextBlockObject("mrkdwn",
+			"Capacity counts full days and parental leave as out and half days as half; WFH counts as available. Use `/teamcal` for the month.",
+			false, false)),
+	}, nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+)
+
+// maxOverlapDays bounds how many working days the approval card's team
+// calendar lists.
+const maxOverlapDays = 10
+
+// teamOverlapText is the mini-calendar on an approval card: for each working
+// day of the request, who else on the requester's team (everyone with the
+// same manager) is already out. It's "" when the requester has no team on
+// file or the request isn't a day off.
+func (a *App) teamOverlapText(leave *models.Leave) string {
+	if !isDayOff(leave.LeaveType) && leave.LeaveType != "HALF_DAY" {
+		return ""
+	}
+	employee, err := a.employeeRepo.Get(leave.Username)
+	if err != nil || employee.ManagerID == "" {
+		return ""
+	}
+	reports, err := a.employeeRepo.GetReports(employee.ManagerID)
+	if err != nil {
+		logger.Error("Failed to load team of %s: %v", leave.Username, err)
+		return ""
+	}
+	inactive, err := a.employeeRepo.GetInactive()
+	if err != nil {
+		logger.Error("Failed to load departed employees: %v", err)
+		return ""
+	}
+	teamSize := 0
+	for _, username := range reports {
+		if username != leave.Username && !inactive[username] {
+			teamSize++
+		}
+	}
+	if teamSize == 0 {
+		return ""
+	}
+
+	// Stored times are office wall-clock times, so compare calendar dates
+	first := time.Date(leave.StartTime.Year(), leave.StartTime.Month(), leave.StartTime.Day(), 0, 0, 0, 0, time.UTC)
+	last := time.Date(leave.EndTime.Year(), leave.EndTime.Month(), leave.EndTime.Day(), 0, 0, 0, 0, time.UTC)
+	overlap, err := a.leaveRepo.GetTeamOverlap(employee.ManagerID, leave.Username, first, last.AddDate(0, 0, 1))
+	if err != nil {
+		logger.Error("Failed to load team overlap for leave %d: %v", leave.ID, err)
+		return ""
+	}
+	out := make(map[string][]string)
+	for _, day := range overlap {
+		if !isDayOff(day.LeaveType) && day.LeaveType != "HALF_DAY" {
+			continue
+		}
+		entry := day.Username
+		if day.LeaveType == "HALF_DAY" {
+			entry += " (½)"
+		}
+		if day.Pending {
+			entry += " (pending)"
+		}
+		key := day.Day.Format("2006-01-02")
+		out[key] = append(out[key], entry)
+	}
+
+	lines := []string{fmt.Sprintf("👥 *Team on these days* (%d others)", teamSize)}
+	listed, more := 0, 0
+	for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+		if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+			continue
+		}
+		if listed == maxOverlapDays {
+			more++
+			continue
+		}
+		listed++
+		away := out[day.Format("2006-01-02")]
+		if len(away) == 0 {
+			lines = append(lines, fmt.Sprintf("`%s` 🟢 everyone else in", day.Format("Mon Jan 02")))
+			continue
+		}
+		marker := "🟡"
+		if len(away)*2 >= teamSize {
+			marker = "🔴"
+		}
+		lines = append(lines, fmt.Sprintf("`%s` %s %d out: %s", day.Format("Mon Jan 02"), marker, len(away), strings.Join(away, ", ")))
+	}
+	if listed == 0 {
+		return ""
+	}
+	if more > 0 {
+		lines = append(lines, fmt.Sprintf("_…and %d more working days_", more))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"slack-leaves-ai-agent/repository"
+)
+
+// isLossOfPay reports whether a record is unpaid leave, which payroll
+// deducts instead of the leave balance.
+func isLossOfPay(leaveType string) bool {
+	return leaveType == "LOSS_OF_PAY"
+}
+
+// lossOfPayReport returns each user's loss-of-pay working days in month, for
+// finance to deduct. Stored times are office wall-clock times, so the month
+// is taken as calendar dates.
+func (a *App) lossOfPayReport(month time.Time) ([]repository.LossOfPayDays, error) {
+	start := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	days, err := a.leaveRepo.GetLossOfPayDays(start, start.AddDate(0, 1, 0), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error loading loss of pay: %v", err)
+	}
+	return days, nil
+}
+
+func lossOfPayCSV(lines []repository.LossOfPayDays) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"username", "records", "working_days"})
+	for _, line := range lines {
+		w.Write([]string{line.Username, strconv.Itoa(line.Records), formatDays(line.WorkingDays)})
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+func (a *App) postLossOfPayReport(month time.Time, reply func(string)) {
+	lines, err := a.lossOfPayReport(month)
+	if err != nil {
+		logger.Error("Failed to build loss of pay report: %v", err)
+		reply("❌ " + err.Error())
+		return
+	}
+	if len(lines) == 0 {
+		reply(fmt.Sprintf("No approved loss-of-pay leave in %s.", month.Format("January 2006")))
+		return
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "💸 *Loss of pay for %s* (working days to deduct)\n", month.Format("January 2006"))
+	var total float64
+	for _, line := range lines {
+		fmt.Fprintf(&b, "• *%s*: %s days (%d records)\n", line.Username, formatDays(line.WorkingDays), line.Records)
+		total += line.WorkingDays
+	}
+	fmt.Fprintf(&b, "%s days across %d people.", formatDays(total), len(lines))
+	reply(b.String())
+}
+
+// handleLossOfPayReport serves /api/admin/reports/lop?month=YYYY-MM&format=csv|json
+func (a *App) handleLossOfPayReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	month, err := parseReportMonth(r.URL.Query().Get("month"), a.today())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	lines, err := a.lossOfPayReport(month)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "json" {
+		writeJSON(w, http.StatusOK, lines)
+		return
+	}
+
+	data, err := lossOfPayCSV(lines)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=loss-of-pay-%s.csv", month.Format("2006-01")))
+	w.Write(data)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// peerContextText puts a person's leave count for the fiscal year so far next to
+// their team's and the company's, e.g. "You: 4 leaves · team avg 3.2". A
+// figure is only shown when its group has at least StatsMinGroupSize people,
+// so an average can't be used to work out a colleague's count. It returns ""
+// when nothing may be shown or the numbers can't be loaded.
+func (a *App) peerContextText(username string) string {
+	start := a.fiscalYearStart(a.today())
+	counts, err := a.leaveRepo.GetLeaveCountsByUser(start, a.today().AddDate(0, 0, 1))
+	if err != nil {
+		logger.Error("Error getting leave counts for peer context: %v", err)
+		return ""
+	}
+	own, ok := counts[username]
+	if !ok {
+		return ""
+	}
+
+	var parts []string
+	if team, label := a.peerTeam(username); len(team) >= a.config.StatsMinGroupSize {
+		parts = append(parts, fmt.Sprintf("%s avg %.1f", label, peerAverage(counts, team)))
+	}
+	if len(counts) >= a.config.StatsMinGroupSize {
+		everyone := make([]string, 0, len(counts))
+		for user := range counts {
+			everyone = append(everyone, user)
+		}
+		parts = append(parts,
+			fmt.Sprintf("company avg %.1f", peerAverage(counts, everyone)),
+			fmt.Sprintf("more than %d%% of people", peerPercentile(counts, own)))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("_%s so far: %d leaves · %s_", a.fiscalYearLabel(start), own, strings.Join(parts, " · "))
+}
+
+// peerTeam returns the people sharing username's manager, or failing that
+// their department, including username. Only those with a count are peers.
+func (a *App) peerTeam(username string) ([]string, string) {
+	employee, err := a.employeeRepo.Get(username)
+	if err != nil || (employee.ManagerID == "" && employee.Department == "") {
+		return nil, ""
+	}
+	employees, err := a.employeeRepo.List(true)
+	if err != nil {
+		logger.Error("Error listing employees for peer context: %v", err)
+		return nil, ""
+	}
+
+	var team []string
+	label := "team"
+	for _, e := range employees {
+		if employee.ManagerID != "" {
+			if e.ManagerID == employee.ManagerID {
+				team = append(team, e.Username)
+			}
+		} else if strings.EqualFold(e.Department, employee.Department) {
+			team = append(team, e.Username)
+			label = employee.Department
+		}
+	}
+	return team, label
+}
+
+func peerAverage(counts map[string]int, users []string) float64 {
+	total, n := 0, 0
+	for _, user := range users {
+		if count, ok := counts[user]; ok {
+			total += count
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return float64(total) / float64(n)
+}
+
+// peerPercentile is the share of people with fewer leaves than count.
+func peerPercentile(counts map[string]int, count int) int {
+	fewer := 0
+	for _, c := range counts {
+		if c < count {
+			fewer++
+		}
+	}
+	return fewer * 100 / len(counts)
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+)
+
+// LeavePolicy is the set of leave rules applied to one employment type.
+type LeavePolicy struct {
+	AccruesLeave    bool    // whether the user earns paid leave at all
+	AnnualLeaveDays float64 // yearly paid leave entitlement
+	Encashable      bool    // whether unused leave can be paid out
+
+	// SickNoQuestions keeps sick days private: they're recorded without
+	// asking the author anything and with "sick" in place of the reason
+	// and message. It's a workspace-wide setting (SICK_NO_QUESTIONS).
+	SickNoQuestions bool
+
+	MinOfficeDaysPerWeek int // hybrid policy; 0 means no office requirement
+}
+
+// policyFor returns the policy for an employment type at an office.
+// Contractors don't accrue paid leave or have office-day requirements but
+// still log availability (WFH, out, late) like everyone else. An office may
+// override the annual entitlement and hybrid requirement; a nil location uses
+// the company defaults.
+func (a *App) policyFor(employmentType string, location *models.Location) LeavePolicy {
+	switch employmentType {
+	case models.EmploymentContractor:
+		return LeavePolicy{SickNoQuestions: a.config.SickNoQuestions}
+	default:
+		policy := LeavePolicy{
+			AccruesLeave:    true,
+			AnnualLeaveDays: a.config.AnnualLeaveDays,
+			Encashable:      true,
+			SickNoQuestions: a.config.SickNoQuestions,
+
+			MinOfficeDaysPerWeek: a.config.HybridMinOfficeDays,
+		}
+		if location != nil && location.AnnualLeaveDays != nil {
+			policy.AnnualLeaveDays = *location.AnnualLeaveDays
+		}
+		if location != nil && location.MinOfficeDays != nil {
+			policy.MinOfficeDaysPerWeek = *location.MinOfficeDays
+		}
+		return policy
+	}
+}
+
+// userPolicy returns the policy that applies to a user, from their
+// employment type and office.
+func (a *App) userPolicy(username string) LeavePolicy {
+	employmentType := models.EmploymentEmployee
+	if employee, err := a.employeeRepo.Get(username); err == nil && employee.EmploymentType != "" {
+		employmentType = employee.EmploymentType
+	}
+	location, err := a.locationFor(username)
+	if err != nil {
+		logger.Error("Failed to load location for %s: %v", username, err)
+	}
+	return a.policyFor(employmentType, location)
+}
+
+// applySickPrivacy replaces the reason and message of a sick day with "sick"
+// when the user's policy keeps sick days private, so no medical detail is
+// stored or echoed back.
+func (a *App) applySickPrivacy(leave *models.Leave) {
+	if !leave.Sick || !a.userPolicy(leave.Username).SickNoQuestions {
+		return
+	}
+	leave.Reason = "sick"
+	leave.OriginalText = "sick"
+	if leave.Trace != nil {
+		leave.Trace.Phrases, leave.Trace.Defaults = nil, nil
+		leave.Trace.AddStep("the details were left out under the sick-day privacy policy")
+	}
+}
+
+func isKnownEmploymentType(employmentType string) bool {
+	return employmentType == models.EmploymentEmployee || employmentType == models.EmploymentContractor
+}
+
+// PolicyViolation is a rule a record breaks. Violations are warnings; the
+// record is still saved. A violation with an HRMessage also sends the record
+// to HR's review queue, with that text; its Message, what the author is
+// told, may then be empty.
+type PolicyViolation struct {
+	Rule      string
+	Day       time.Time
+	Message   string
+	HRMessage string
+}
+
+// leaveCheck is one rule of the policy engine.
+type leaveCheck func(a *App, leave *models.Leave) ([]PolicyViolation, error)
+
+// leaveChecks run, in order, against every new record.
+var leaveChecks = []leaveCheck{
+	checkCoverage,
+	checkBalance,
+	checkLongAbsence,
+	checkSickFrequency,
+}
+
+// evaluateLeave runs the policy engine against a record. A check that fails
+// to run is logged and skipped rather than blocking the others.
+func (a *App) evaluateLeave(leave *models.Leave) []PolicyViolation {
+	var violations []PolicyViolation
+	for _, check := range leaveChecks {
+		found, err := check(a, leave)
+		if err != nil {
+			logger.Error("Policy check failed for %s: %v", leave.Username, err)
+			continue
+		}
+		violations = append(violations, found...)
+	}
+	return violations
+}
+
+// checkCoverage warns when a full day off would leave a coverage role (e.g.
+// release manager) with fewer available members than its rule requires on
+// any working day of the leave. Half days and WFH don't count as absent.
+func checkCoverage(a *App, leave *models.Leave) ([]PolicyViolation, error) {
+	if !isDayOff(leave.LeaveType) {
+		return nil, nil
+	}
+
+	roles, err := a.coverageRepo.RolesFor(leave.Username)
+	if err != nil || len(roles) == 0 {
+		return nil, err
+	}
+	rules, err := a.coverageRepo.GetRules()
+	if err != nil {
+		return nil, err
+	}
+
+	// Stored times are office wall-clock times, so compare calendar dates
+	first := time.Date(leave.StartTime.Year(), leave.StartTime.Month(), leave.StartTime.Day(), 0, 0, 0, 0, time.UTC)
+	last := time.Date(leave.EndTime.Year(), leave.EndTime.Month(), leave.EndTime.Day(), 0, 0, 0, 0, time.UTC)
+
+	statuses, err := a.leaveRepo.GetDailyStatuses(first, last.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	absent := make(map[string]map[string]bool)
+	for _, status := range statuses {
+		if !isDayOff(status.LeaveType) {
+			continue
+		}
+		day := status.Day.Format("2006-01-02")
+		if absent[day] == nil {
+			absent[day] = make(map[string]bool)
+		}
+		absent[day][status.Username] = true
+	}
+
+	var violations []PolicyViolation
+	for _, role := range roles {
+		minAvailable, ok := rules[role]
+		if !ok {
+			continue
+		}
+		members, err := a.coverageRepo.Members(role)
+		if err != nil {
+			return nil, err
+		}
+
+		for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+			if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+				continue
+			}
+			var available []string
+			for _, member := range members {
+				if member != leave.Username && !absent[day.Format("2006-01-02")][member] {
+					available = append(available, member)
+				}
+			}
+			if len(available) >= minAvailable {
+				continue
+			}
+
+			message := fmt.Sprintf("only %d of %d %s would be available on %s (at least %d needed)",
+				len(available), len(members), role, day.Format("Mon Jan 2"), minAvailable)
+			if len(available) > 0 {
+				message += ": " + strings.Join(available, ", ")
+			}
+			violations = append(violations, PolicyViolation{Rule: "coverage:" + role, Day: day, Message: message})
+		}
+	}
+
+	return violations, nil
+}
+
+// checkBalance warns when a record takes the last of its owner's annual
+// leave, or more, and suggests what to take instead: comp-off days they've
+// been granted, or loss of pay. Checks run once the record's debit is on the
+// ledger, so the balance already counts it.
+func checkBalance(a *App, leave *models.Leave) ([]PolicyViolation, error) {
+	days := a.quotaDays(leave)
+	if days == 0 || !a.featureEnabled(flagBalances) || !a.userPolicy(leave.Username).AccruesLeave {
+		return nil, nil
+	}
+	year := leave.StartTime.Year()
+	balance, err := a.leaveBalance(leave.Username, year)
+	if err != nil {
+		return nil, err
+	}
+	if balance.Entitlement <= 0 || balance.Available > 0 {
+		return nil, nil
+	}
+
+	var message string
+	switch {
+	case balance.Available == 0:
+		message = fmt.Sprintf("this uses the last of your annual leave for %d", year)
+	case balance.Available+days > 0:
+		message = fmt.Sprintf("this takes you %s days over your annual leave for %d", formatDays(-balance.Available), year)
+	default:
+		message = fmt.Sprintf("your annual leave for %d was already used up; this takes you %s days over",
+			year, formatDays(-balance.Available))
+	}
+
+	compOff, err := a.categoryBalance(leave.Username, models.LedgerCompOff, year)
+	if err != nil {
+		return nil, err
+	}
+	if compOff > 0 {
+		message += fmt.Sprintf("\n   ◦ You have %s comp-off days: ask an admin to take it from those instead", formatDays(compOff))
+	}
+	if leave.LeaveType == "FULL_DAY" {
+		span := "on " + leave.StartTime.Format("Mon Jan 2")
+		if leave.EndTime.Format("2006-01-02") != leave.StartTime.Format("2006-01-02") {
+			span = fmt.Sprintf("from %s to %s", leave.StartTime.Format("Mon Jan 2"), leave.EndTime.Format("Mon Jan 2"))
+		}
+		message += fmt.Sprintf("\n   ◦ Or take it as loss of pay so your balance isn't used: cancel this one and say e.g. _loss of pay %s_", span)
+	}
+	return []PolicyViolation{{Rule: "balance", Day: leave.StartTime, Message: message}}, nil
+}
+
+// checkLongAbsence sends days off longer than HR_ESCALATION_DAYS calendar
+// days to HR as well as the approver, since absences that long often need
+// paperwork or a plan for the author's work.
+func checkLongAbsence(a *App, leave *models.Leave) ([]PolicyViolation, error) {
+	limit := a.config.HREscalationDays
+	days := leaveDays(leave)
+	if limit == 0 || !isDayOff(leave.LeaveType) || days <= limit {
+		return nil, nil
+	}
+	return []PolicyViolation{{
+		Rule:    "long_absence",
+		Day:     leave.StartTime,
+		Message: fmt.Sprintf("leave longer than %d days is also reviewed by HR; they may reach out about it", limit),
+		HRMessage: fmt.Sprintf("%d consecutive days off, %s to %s (the threshold is %d)",
+			days, leave.StartTime.Format("Mon Jan 2"), leave.EndTime.Format("Mon Jan 2"), limit),
+	}}, nil
+}
+
+// checkSickFrequency sends a sick day to HR once it's the author's
+// HR_ESCALATION_SICK_COUNT-th or later in a month. The author isn't told,
+// since a reminder of how often they've been unwell helps no one.
+func checkSickFrequency(a *App, leave *models.Leave) ([]PolicyViolation, error) {
+	limit := a.config.HREscalationSickCount
+	if limit == 0 || !leave.Sick {
+		return nil, nil
+	}
+	start := time.Date(leave.StartTime.Year(), leave.StartTime.Month(), 1, 0, 0, 0, 0, leave.StartTime.Location())
+	count, err := a.leaveRepo.CountSick(leave.Username, start, start.AddDate(0, 1, 0))
+	if err != nil || count < limit {
+		return nil, err
+	}
+	return []PolicyViolation{{
+		Rule: "sick_frequency",
+		Day:  leave.StartTime,
+		HRMessage: fmt.Sprintf("sick leave %d times in %s (the threshold is %d)",
+			count, start.Format("January"), limit),
+	}}, nil
+}
+
+// policyWarningText formats violations for a reply, or "" if there are none
+// the author should see.
+func policyWarningText(violations []PolicyViolation) string {
+	if len(violations) == 0 {
+		return ""
+	}
+	lines := make([]string, 0, len(violations))
+	for _, v := range violations {
+		if v.Message != "" {
+			lines = append(lines, "• "+v.Message)
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return "⚠️ *Heads up:*\n" + strings.Join(lines, "\n")
+}
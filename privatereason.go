@@ -0,0 +1,127 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"slack-leaves-ai-agent/models"
+
+	"github.com/slack-go/slack"
+)
+
+// Action, callback and block IDs of the "Private details" button under a
+// confirmation and the modal it opens. The button value is the record ID.
+const (
+	privateReasonActionID        = "private_reason"
+	privateReasonModalCallbackID = "private_reason"
+	privateReasonBlockID         = "private_reason"
+	privateReasonInputActionID   = "value"
+)
+
+// maxPrivateReasonLength keeps the details to a few sentences.
+const maxPrivateReasonLength = 1000
+
+func privateReasonButton(id int64) *slack.ButtonBlockElement {
+	return slack.NewButtonBlockElement(privateReasonActionID, strconv.FormatInt(id, 10),
+		slack.NewTextBlockObject("plain_text", "🔒 Private details", true, false))
+}
+
+func privateReasonInteraction(callback slack.InteractionCallback) bool {
+	for _, action := range callback.ActionCallback.BlockActions {
+		if action.ActionID == privateReasonActionID {
+			return true
+		}
+	}
+	return false
+}
+
+// handlePrivateReasonAction opens the private details modal for the author
+// of the record.
+func (a *App) handlePrivateReasonAction(callback slack.InteractionCallback) {
+	for _, action := range callback.ActionCallback.BlockActions {
+		if action.ActionID != privateReasonActionID {
+			continue
+		}
+		id, err := strconv.ParseInt(action.Value, 10, 64)
+		if err != nil {
+			continue
+		}
+		leave, err := a.leaveRepo.GetByID(id)
+		if err != nil {
+			a.replyFeedback(callback, "❌ That record no longer exists.")
+			continue
+		}
+		if !a.isLeaveAuthor(callback.User.ID, leave) {
+			a.replyFeedback(callback, "🔒 Only "+leave.Username+" can add private details to this record.")
+			continue
+		}
+		if _, err := a.slackClient.OpenView(callback.TriggerID, privateReasonModal(leave)); err != nil {
+			logger.Error("Failed to open private details modal: %v", err)
+		}
+	}
+}
+
+func privateReasonModal(leave *models.Leave) slack.ModalViewRequest {
+	input := slack.NewPlainTextInputBlockElement(nil, privateReasonInputActionID)
+	input.Multiline = true
+	input.MaxLength = maxPrivateReasonLength
+	input.InitialValue = leave.PrivateReason
+
+	block := slack.NewInputBlock(privateReasonBlockID, slack.NewTextBlockObject("plain_text", "Details", false, false),
+		slack.NewTextBlockObject("plain_text", "Leave empty to remove them", false, false), input)
+	block.Optional = true
+
+	return slack.ModalViewRequest{
+		Type:            slack.VTModal,
+		CallbackID:      privateReasonModalCallbackID,
+		PrivateMetadata: strconv.FormatInt(leave.ID, 10),
+		Title:           slack.NewTextBlockObject("plain_text", "Private details", false, false),
+		Submit:          slack.NewTextBlockObject("plain_text", "Save", false, false),
+		Close:           slack.NewTextBlockObject("plain_text", "Cancel", false, false),
+		Blocks: slack.Blocks{BlockSet: []slack.Block{
+			slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn",
+				"Only HR and admins see these. Your public reason stays as it is: *"+publicReason(leave)+"*",
+				false, false), nil, nil),
+			block,
+		}},
+	}
+}
+
+// submitPrivateReason stores the details from the modal. It runs inline so
+// an error can go back in the acknowledgement; a nil response closes the
+// modal.
+func (a *App) submitPrivateReason(callback slack.InteractionCallback) *slack.ViewSubmissionResponse {
+	id, err := strconv.ParseInt(callback.View.PrivateMetadata, 10, 64)
+	if err != nil {
+		logger.Error("Invalid private details modal metadata %q", callback.View.PrivateMetadata)
+		return nil
+	}
+	fail := func(message string) *slack.ViewSubmissionResponse {
+		return slack.NewErrorsViewSubmissionResponse(map[string]string{privateReasonBlockID: message})
+	}
+
+	leave, err := a.leaveRepo.GetByID(id)
+	if err != nil {
+		return fail("That record no longer exists.")
+	}
+	if !a.isLeaveAuthor(callback.User.ID, leave) {
+		return fail("Only " + leave.Username + " can add private details to this record.")
+	}
+	before := *leave
+
+	leave.PrivateReason = strings.TrimSpace(callback.View.State.Values[privateReasonBlockID][privateReasonInputActionID].Value)
+	if err := a.leaveRepo.SetPrivateReason(id, leave.PrivateReason); err != nil {
+		logger.Error("Failed to save private details of leave %d: %v", id, err)
+		return fail("Couldn't save the details, please try again.")
+	}
+	a.audit("slack:"+callback.User.ID, "private_reason", id, before, leave)
+	return nil
+}
+
+// publicReason is the reason as shown in channels.
+func publicReason(leave *models.Leave) string {
+	if leave.Reason == "" {
+		return "none given"
+	}
+	return leave.Reason
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"slack-leaves-ai-agent/services"
+
+	"github.com/slack-go/slack"
+)
+
+// maxQueryTurns is how many earlier questions of a thread are passed on to
+// the parser.
+const maxQueryTurns = 5
+
+// queryThreadTTL is how long after its last question a thread still takes
+// follow-ups.
+const queryThreadTTL = time.Hour
+
+// queryThreads remembers the questions asked in each thread with a query
+// answer, so a reply like "and what about just the backend team?" is
+// understood as a follow-up. Nothing here is persisted.
+type queryThreads struct {
+	mu      sync.Mutex
+	threads map[string]*queryThread
+}
+
+type queryThread struct {
+	turns    []services.QueryTurn
+	lastUsed time.Time
+}
+
+func newQueryThreads() *queryThreads {
+	return &queryThreads{threads: make(map[string]*queryThread)}
+}
+
+func queryThreadKey(channel, threadTS string) string {
+	return channel + ":" + threadTS
+}
+
+// Add records a question asked in a thread, dropping the oldest once
+// maxQueryTurns is reached, and forgets threads that have gone quiet.
+func (q *queryThreads) Add(channel, threadTS string, turn services.QueryTurn, now time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for key, thread := range q.threads {
+		if now.Sub(thread.lastUsed) > queryThreadTTL {
+			delete(q.threads, key)
+		}
+	}
+
+	key := queryThreadKey(channel, threadTS)
+	thread, ok := q.threads[key]
+	if !ok {
+		thread = &queryThread{}
+		q.threads[key] = thread
+	}
+	thread.turns = append(thread.turns, turn)
+	if len(thread.turns) > maxQueryTurns {
+		thread.turns = thread.turns[len(thread.turns)-maxQueryTurns:]
+	}
+	thread.lastUsed = now
+}
+
+// Turns returns the questions asked in a thread, oldest first, or nil when
+// it isn't a query thread or has gone quiet.
+func (q *queryThreads) Turns(channel, threadTS string, now time.Time) []services.QueryTurn {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	thread, ok := q.threads[queryThreadKey(channel, threadTS)]
+	if !ok || now.Sub(thread.lastUsed) > queryThreadTTL {
+		return nil
+	}
+	return append([]services.QueryTurn(nil), thread.turns...)
+}
+
+// handleQueryFollowUp answers a question asked in the thread of an earlier
+// query answer, with the thread's earlier questions as context.
+func (a *App) handleQueryFollowUp(ctx context.Context, ev *slack.MessageEvent) {
+	history := a.queryThreads.Turns(ev.Channel, ev.ThreadTimestamp, time.Now())
+	if len(history) == 0 {
+		return
+	}
+
+	allowed, firstExceeded := a.rateLimiter.Allow(ev.User, time.Now())
+	if firstExceeded {
+		a.notifyRateLimited(ev.User)
+	}
+	if !allowed {
+		logger.Debug("Throttling follow-up from %s", ev.User)
+		return
+	}
+
+	text := ev.Text
+	if botID := a.botUserID(); botID != "" {
+		text = strings.TrimSpace(strings.ReplaceAll(text, "<@"+botID+">", ""))
+	}
+
+	// People chat in these threads too; only questions get an answer
+	intent, err := a.openAI.ClassifyIntent(ctx, text)
+	if err != nil {
+		logger.Error("Failed to classify follow-up: %v", err)
+		return
+	}
+	if intent != services.IntentQuery {
+		logger.Debug("Skipping thread reply classified as %s: %s", intent, ev.Timestamp)
+		return
+	}
+
+	blocks, queryResp, err := a.buildQueryBlocks(ctx, text, history)
+	if errors.Is(err, services.ErrTimeout) {
+		a.notifyIfTimeout(err, ev.Channel, ev.User)
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to run follow-up query: %v", err)
+		a.replyInQueryThread(ev, slack.MsgOptionText("❌ "+err.Error(), false))
+		return
+	}
+
+	a.replyInQueryThread(ev, slack.MsgOptionBlocks(blocks...))
+	a.queryThreads.Add(ev.Channel, ev.ThreadTimestamp, services.QueryTurn{Query: text, Parsed: queryResp}, time.Now())
+}
+
+func (a *App) replyInQueryThread(ev *slack.MessageEvent, content slack.MsgOption) {
+	_, _, err := a.slackClient.PostMessage(ev.Channel, content, slack.MsgOptionTS(ev.ThreadTimestamp))
+	if err != nil {
+		logger.Error("Failed to post follow-up query response: %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// eventQueueSize is how many jobs can wait for a worker.
+const eventQueueSize = 500
+
+// job is one piece of work handed off by the socket mode loop.
+type job struct {
+	name string
+	run  func(ctx context.Context)
+}
+
+// jobQueue runs the work behind Slack events on a fixed pool of workers, so
+// the socket mode loop only acks and enqueues and is never held up by a slow
+// OpenAI call. Each job gets a context that ends after timeout; a job that
+// overruns it is abandoned by its worker, so one stuck call can't take a
+// worker with it, but Shutdown still waits for it.
+type jobQueue struct {
+	jobs    chan job
+	timeout time.Duration
+	workers sync.WaitGroup
+	running sync.WaitGroup // every job's goroutine, abandoned ones included
+
+	mu     sync.RWMutex // held for reading while submitting
+	closed bool
+}
+
+func newJobQueue(workers, size int, timeout time.Duration) *jobQueue {
+	q := &jobQueue{jobs: make(chan job, size), timeout: timeout}
+	q.workers.Add(workers)
+	for i := 0; i < workers; i++ {
+		go q.work()
+	}
+	return q
+}
+
+// Submit queues run. When the queue is full it waits for room, which holds
+// up the next event's ack, so the queue should be sized well above a burst.
+// Once the queue is shutting down, run is dropped.
+func (q *jobQueue) Submit(name string, run func(ctx context.Context)) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		logger.Error("Shutting down, dropping %s", name)
+		return
+	}
+
+	select {
+	case q.jobs <- job{name: name, run: run}:
+	default:
+		logger.Error("Job queue full, waiting to queue %s", name)
+		q.jobs <- job{name: name, run: run}
+	}
+}
+
+// Shutdown stops taking jobs and waits until the queued and running ones,
+// including any abandoned after their timeout, are done or ctx ends,
+// whichever comes first. It reports whether the queue drained.
+func (q *jobQueue) Shutdown(ctx context.Context) bool {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.jobs)
+	}
+	q.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		q.workers.Wait()
+		q.running.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (q *jobQueue) work() {
+	defer q.workers.Done()
+	for j := range q.jobs {
+		q.runJob(j)
+	}
+}
+
+func (q *jobQueue) runJob(j job) {
+	ctx, cancel := context.WithTimeout(context.Background(), q.timeout)
+	defer cancel()
+
+	done := make(chan struct{})
+	q.running.Add(1)
+	go func() {
+		defer q.running.Done()
+		defer close(done)
+		defer func() {
+			if r := recover(); r != nil {
+				logger.Error("Job %s panicked: %v\n%s", j.name, r, debug.Stack())
+			}
+		}()
+		j.run(ctx)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		logger.Error("Job %s still running after %s, moving on", j.name, q.timeout)
+	}
+}
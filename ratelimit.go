@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+const throttledInterval = 10 * time.Minute
+
+// rateLimitPruneInterval is how often counters from previous days are
+// dropped.
+const rateLimitPruneInterval = time.Hour
+
+// userRateLimiter counts how many messages each user has sent to the parser
+// today. Once a user goes past the daily limit their messages are throttled to
+// one every throttledInterval rather than dropped outright, so a genuine
+// request still gets through eventually.
+type userRateLimiter struct {
+	mu       sync.Mutex
+	limit    int
+	counters map[string]*userCounter
+}
+
+type userCounter struct {
+	day      string
+	count    int
+	lastSeen time.Time
+	notified bool
+}
+
+func newUserRateLimiter(limit int) *userRateLimiter {
+	return &userRateLimiter{
+		limit:    limit,
+		counters: make(map[string]*userCounter),
+	}
+}
+
+// Allow records a message from userID and reports whether it should be
+// processed, and whether this is the first time today the user crossed the
+// limit (so the caller can alert an admin exactly once).
+func (l *userRateLimiter) Allow(userID string, now time.Time) (allowed bool, firstExceeded bool) {
+	if l.limit <= 0 {
+		return true, false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	day := now.Format("2006-01-02")
+	c, ok := l.counters[userID]
+	if !ok || c.day != day {
+		c = &userCounter{day: day}
+		l.counters[userID] = c
+	}
+
+	c.count++
+	if c.count <= l.limit {
+		c.lastSeen = now
+		return true, false
+	}
+
+	firstExceeded = !c.notified
+	c.notified = true
+
+	if now.Sub(c.lastSeen) < throttledInterval {
+		return false, firstExceeded
+	}
+	c.lastSeen = now
+	return true, firstExceeded
+}
+
+// Prune drops the counters of users who haven't sent anything on now's day.
+// Allow would start them afresh anyway, so they only hold memory.
+func (l *userRateLimiter) Prune(now time.Time) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	day := now.Format("2006-01-02")
+	pruned := 0
+	for userID, c := range l.counters {
+		if c.day != day {
+			delete(l.counters, userID)
+			pruned++
+		}
+	}
+	return pruned
+}
+
+// runRateLimitPruning prunes the rate limiter's idle counters until ctx is
+// cancelled, so it doesn't keep one for every user it has ever seen.
+func (a *App) runRateLimitPruning(ctx context.Context) {
+	ticker := time.NewTicker(rateLimitPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if pruned := a.rateLimiter.Prune(time.Now()); pruned > 0 {
+				logger.Info("Pruned %d idle rate limit counters", pruned)
+			}
+		}
+	}
+}
+
+func (a *App) notifyRateLimited(userID string) {
+	logger.Info("User %s exceeded %d requests today, throttling", userID, a.config.RateLimitPerDay)
+
+	adminChannel := a.adminChannel()
+	if adminChannel == "" {
+		return
+	}
+
+	_, _, err := a.slackClient.PostMessage(adminChannel, slack.MsgOptionText(
+		fmt.Sprintf("⚠️ <@%s> has sent more than %d attendance messages today. "+
+			"Further messages are throttled to one every %d minutes for the rest of the day.",
+			userID, a.config.RateLimitPerDay, int(throttledInterval.Minutes())),
+		false,
+	))
+	if err != nil {
+		logger.Error("Failed to notify admin channel: %v", err)
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUserRateLimiterPrune(t *testing.T) {
+	limiter := newUserRateLimiter(5)
+	monday := time.Date(2024, time.March, 4, 23, 0, 0, 0, time.UTC)
+	tuesday := monday.Add(2 * time.Hour)
+
+	limiter.Allow("U1", monday)
+	limiter.Allow("U2", monday)
+	limiter.Allow("U2", tuesday)
+
+	if pruned := limiter.Prune(tuesday); pruned != 1 {
+		t.Errorf("Prune = %d, want 1", pruned)
+	}
+	if _, ok := limiter.counters["U1"]; ok {
+		t.Error("yesterday's counter was kept")
+	}
+	if c := limiter.counters["U2"]; c == nil || c.count != 1 {
+		t.Errorf("today's counter = %+v, want a count of 1", c)
+	}
+	if pruned := limiter.Prune(tuesday); pruned != 0 {
+		t.Errorf("second Prune = %d, want 0", pruned)
+	}
+}
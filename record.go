@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"slack-leaves-ai-agent/models"
+)
+
+// recordLeave is the standard write path for a new record, shared by every
+// way a leave can come in: pre_validate hooks, payroll lock, save,
+// post_create hooks, audit, desk booking, the team calendar, backup
+// approvers, then the policy engine, whose escalations go to HR. Violations
+// are warnings for the caller to surface; the record is saved regardless.
+func (a *App) recordLeave(ctx context.Context, actor, action string, leave *models.Leave, email string) ([]PolicyViolation, error) {
+	a.applySickPrivacy(leave)
+	a.applyReasonFilter(leave)
+	if err := a.runPreValidateHooks(ctx, actor, leave); err != nil {
+		return nil, err
+	}
+	if err := a.checkPeriodLock(leave); err != nil {
+		return nil, err
+	}
+	if err := a.leaveRepo.Create(leave); err != nil {
+		return nil, fmt.Errorf("error saving leave: %v", err)
+	}
+	a.runPostCreateHooks(actor, leave)
+	a.audit(actor, action, leave.ID, nil, leave)
+	a.recordLedgerChange(actor, action, nil, leave)
+	a.syncDeskBooking(ctx, leave, email)
+	a.syncTeamCalendar(ctx, nil, leave)
+	a.delegateOnOwnLeave(ctx, leave)
+	violations := a.evaluateLeave(leave)
+	a.escalateToHR(ctx, leave, violations)
+	return violations, nil
+}
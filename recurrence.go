@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+	"slack-leaves-ai-agent/services"
+)
+
+// startRecurringLeave stores the series a parsed leave starts, records the
+// leave as its first occurrence and creates the occurrences that fall inside
+// the booking window. Later ones are created by runRecurringLeaves as the
+// window moves on.
+func (a *App) startRecurringLeave(ctx context.Context, actor, action string, leave *models.Leave, email string) ([]PolicyViolation, error) {
+	rule, err := services.ParseRRule(leave.Recurrence)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recurrence %q: %v", leave.Recurrence, err)
+	}
+	if err := a.checkPeriodLock(leave); err != nil {
+		return nil, err
+	}
+	a.applySickPrivacy(leave)
+	a.applyReasonFilter(leave)
+
+	rec := &models.RecurringLeave{
+		Username:          leave.Username,
+		OriginalText:      leave.OriginalText,
+		LeaveType:         leave.LeaveType,
+		Reason:            leave.Reason,
+		RRule:             rule.String(),
+		DTStart:           leave.StartTime,
+		DurationMinutes:   int(leave.EndTime.Sub(leave.StartTime).Minutes()),
+		MaterializedUntil: leave.StartTime,
+		CreatedBy:         actor,
+	}
+	if err := a.recurringRepo.Create(rec); err != nil {
+		return nil, fmt.Errorf("error saving recurring leave: %v", err)
+	}
+	a.audit(actor, "recurring_create", 0, nil, rec)
+
+	leave.RecurrenceID = rec.ID
+	violations, err := a.recordLeave(ctx, actor, action, leave, email)
+	if err != nil {
+		return nil, err
+	}
+
+	a.materializeRecurringLeave(ctx, rec)
+	return violations, nil
+}
+
+// materializeRecurringLeave creates the series' occurrences from where it
+// left off up to the end of the user's booking window. Occurrences that the
+// booking rules refuse, such as public holidays, are skipped.
+func (a *App) materializeRecurringLeave(ctx context.Context, rec *models.RecurringLeave) {
+	rule, err := services.ParseRRule(rec.RRule)
+	if err != nil {
+		logger.Error("Invalid rule on recurring leave %d: %v", rec.ID, err)
+		return
+	}
+
+	region := a.regionFor(rec.Username)
+	loc := region.Timezone
+	maxAdvanceDays := region.MaxAdvanceDays
+	if maxAdvanceDays <= 0 {
+		maxAdvanceDays = services.DefaultMaxAdvanceDays
+	}
+
+	// Stored times are office wall-clock times
+	dtstart := wallClock(rec.DTStart, loc)
+	from := wallClock(rec.MaterializedUntil, loc)
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	if today.After(from) {
+		from = today.Add(-time.Nanosecond)
+	}
+	horizon := today.AddDate(0, 0, maxAdvanceDays+1).Add(-time.Nanosecond)
+	if !horizon.After(from) {
+		return
+	}
+
+	var email string
+	if employee, err := a.employeeRepo.Get(rec.Username); err == nil {
+		email = employee.Email
+	}
+
+	created := 0
+	for _, start := range rule.Between(dtstart, from, horizon) {
+		leave := rec.Occurrence(start)
+		if reason := region.Validate(leave.StartTime, leave.EndTime, now); reason != "" {
+			logger.Debug("Skipping occurrence of recurring leave %d on %s: %s", rec.ID, start.Format("2006-01-02"), reason)
+			continue
+		}
+		if _, err := a.recordLeave(ctx, "system:recurrence", "recurring_create", leave, email); err != nil {
+			logger.Error("Failed to create occurrence of recurring leave %d on %s: %v", rec.ID, start.Format("2006-01-02"), err)
+			continue
+		}
+		created++
+	}
+
+	if err := a.recurringRepo.SetMaterializedUntil(rec.ID, horizon); err != nil {
+		logger.Error("Failed to update recurring leave %d: %v", rec.ID, err)
+		return
+	}
+	if created > 0 {
+		logger.Info("Created %d occurrences of recurring leave %d for %s", created, rec.ID, rec.Username)
+	}
+}
+
+// wallClock reads a stored wall-clock time as a time in loc.
+func wallClock(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+}
+
+// runRecurringLeaves tops up every series once an hour, so occurrences appear
+// as they come inside the booking window.
+func (a *App) runRecurringLeaves(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			recs, err := a.recurringRepo.List("")
+			if err != nil {
+				logger.Error("Failed to load recurring leaves: %v", err)
+				continue
+			}
+			for i := range recs {
+				a.materializeRecurringLeave(ctx, &recs[i])
+			}
+		}
+	}
+}
+
+// runRecurringCommand handles `/admin-leave recurring list|cancel`.
+func (a *App) runRecurringCommand(actor string, args []string) (string, error) {
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+
+	switch args[0] {
+	case "list":
+		var username string
+		if len(args) > 1 {
+			var err error
+			if username, err = a.resolveUserArg(args[1]); err != nil {
+				return "", err
+			}
+		}
+		recs, err := a.recurringRepo.List(username)
+		if err != nil {
+			return "", err
+		}
+		if len(recs) == 0 {
+			return "No recurring leave.", nil
+		}
+		lines := make([]string, 0, len(recs))
+		for _, rec := range recs {
+			lines = append(lines, recurringLeaveLine(&rec))
+		}
+		return strings.Join(lines, "\n"), nil
+
+	case "cancel":
+		if len(args) != 2 {
+			return adminLeaveUsage, nil
+		}
+		id, err := strconv.ParseInt(strings.TrimPrefix(args[1], "#"), 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid recurring leave id %q", args[1])
+		}
+		rec, err := a.recurringRepo.GetByID(id)
+		if err != nil {
+			return "", err
+		}
+		if err := a.recurringRepo.Cancel(id); err != nil {
+			return "", err
+		}
+		a.audit(actor, "recurring_cancel", 0, rec, nil)
+
+		// Drop the occurrences that haven't happened yet
+		ctx := context.Background()
+		leaves, err := a.leaveRepo.ListStartingFrom(rec.Username, time.Now().In(a.regionFor(rec.Username).Timezone))
+		if err != nil {
+			return "", err
+		}
+		removed := 0
+		for _, leave := range leaves {
+			if leave.RecurrenceID != id {
+				continue
+			}
+			if err := a.leaveRepo.Cancel(leave.ID, actor); err != nil {
+				logger.Error("Error cancelling leave %d: %v", leave.ID, err)
+				continue
+			}
+			a.audit(actor, "recurring_cancel", leave.ID, leave, nil)
+			a.recordLedgerChange(actor, "recurring_cancel", &leave, nil)
+			a.syncTeamCalendar(ctx, &leave, nil)
+			a.releaseDesk(ctx, &leave, "")
+			removed++
+		}
+		return fmt.Sprintf("🚫 Cancelled recurring leave #%d and %d upcoming records", id, removed), nil
+
+	default:
+		return adminLeaveUsage, nil
+	}
+}
+
+func recurringLeaveLine(rec *models.RecurringLeave) string {
+	describe := rec.RRule
+	if rule, err := services.ParseRRule(rec.RRule); err == nil {
+		describe = rule.Describe()
+	}
+	return fmt.Sprintf("#%d *%s* %s %s, from %s (`%s`)",
+		rec.ID, rec.Username, rec.LeaveType, describe, rec.DTStart.Format("Jan 2, 2006 3:04 PM"), rec.RRule)
+}
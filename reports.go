@@ -0,0 +1,365 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+	"slack-leaves-ai-agent/repository"
+
+	"github.com/slack-go/slack"
+)
+
+// encashmentReport computes each user's unused leave for the year and how
+// much of it can be encashed under the ENCASHMENT_MAX_DAYS cap. Only users
+// with at least one record in the leaves table and an encashable policy are
+// included.
+func (a *App) encashmentReport(year int) ([]models.EncashmentLine, error) {
+	loc := a.config.Timezone
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+	end := start.AddDate(1, 0, 0)
+
+	used, err := a.leaveRepo.GetLeaveDaysUsed(start, end)
+	if err != nil {
+		return nil, fmt.Errorf("error loading leave usage: %v", err)
+	}
+
+	employmentTypes, err := a.employeeRepo.GetEmploymentTypes()
+	if err != nil {
+		return nil, fmt.Errorf("error loading employment types: %v", err)
+	}
+
+	usernames := make([]string, 0, len(used))
+	for _, u := range used {
+		usernames = append(usernames, u.Username)
+	}
+	locations, err := a.locationsByUser(usernames)
+	if err != nil {
+		return nil, fmt.Errorf("error loading locations: %v", err)
+	}
+
+	lines := make([]models.EncashmentLine, 0, len(used))
+	for _, u := range used {
+		employmentType := employmentTypes[u.Username]
+		if employmentType == "" {
+			employmentType = models.EmploymentEmployee
+		}
+		policy := a.policyFor(employmentType, locations[u.Username])
+		if !policy.Encashable {
+			continue
+		}
+
+		remaining := math.Max(policy.AnnualLeaveDays-u.DaysUsed, 0)
+		lines = append(lines, models.EncashmentLine{
+			Username:       u.Username,
+			EmploymentType: employmentType,
+			Entitlement:    policy.AnnualLeaveDays,
+			Used:           u.DaysUsed,
+			Remaining:      remaining,
+			Encashable:     math.Min(remaining, a.config.EncashmentMaxDays),
+		})
+	}
+
+	return lines, nil
+}
+
+// officeUtilizationReport summarises office attendance per working day of
+// the week over the last `weeks` weeks up to and including today.
+func (a *App) officeUtilizationReport(weeks int) ([]models.OfficeUtilizationLine, time.Time, time.Time, error) {
+	loc := a.config.Timezone
+	now := time.Now().In(loc)
+	end := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc).AddDate(0, 0, 1)
+	start := end.AddDate(0, 0, -7*weeks)
+
+	attendance, err := a.leaveRepo.GetOfficeAttendanceByWeekday(start, end)
+	if err != nil {
+		return nil, start, end, fmt.Errorf("error loading office attendance: %v", err)
+	}
+	byWeekday := make(map[time.Weekday]repository.WeekdayAttendance, len(attendance))
+	for _, day := range attendance {
+		byWeekday[day.Weekday] = day
+	}
+
+	lines := make([]models.OfficeUtilizationLine, 0, 5)
+	for weekday := time.Monday; weekday <= time.Friday; weekday++ {
+		day := byWeekday[weekday]
+		line := models.OfficeUtilizationLine{
+			Weekday:     weekday.String(),
+			Days:        weeks,
+			AvgInOffice: float64(day.InOffice) / float64(weeks),
+			AvgWFH:      float64(day.WFH) / float64(weeks),
+			OfficeUsers: day.OfficeUsers,
+		}
+		if total := day.InOffice + day.WFH; total > 0 {
+			line.OfficeShare = float64(day.InOffice) / float64(total)
+		}
+		lines = append(lines, line)
+	}
+
+	return lines, start, end, nil
+}
+
+func encashmentCSV(lines []models.EncashmentLine) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"username", "employment_type", "entitlement", "used", "remaining", "encashable"})
+	for _, line := range lines {
+		w.Write([]string{
+			line.Username,
+			line.EmploymentType,
+			formatDays(line.Entitlement),
+			formatDays(line.Used),
+			formatDays(line.Remaining),
+			formatDays(line.Encashable),
+		})
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+func formatDays(days float64) string {
+	return strconv.FormatFloat(days, 'f', -1, 64)
+}
+
+// canViewReports reports whether userID may see company-wide HR reports.
+func (a *App) canViewReports(userID string) bool {
+	return a.canLogForOthers(userID)
+}
+
+const leaveReportUsage = "Usage:\n" +
+	"• `/leave-report encashment [YEAR]`\n" +
+	"• `/leave-report office [WEEKS]`\n" +
+	"• `/leave-report compliance [YYYY-MM]`\n" +
+	"• `/leave-report lop [YYYY-MM]` (loss of pay to deduct)\n" +
+	"• `/leave-report hr [resolve ID]` (records escalated to HR)"
+
+func handleLeaveReportCommand(app *App, cmd slack.SlashCommand) {
+	reply := func(text string) {
+		_, err := app.slackClient.PostEphemeral(cmd.ChannelID, cmd.UserID, slack.MsgOptionText(text, false))
+		if err != nil {
+			logger.Error("Failed to post report reply: %v", err)
+		}
+	}
+
+	if !app.canViewReports(cmd.UserID) {
+		reply("❌ Reports are only available to HR and admins.")
+		return
+	}
+
+	args := strings.Fields(cmd.Text)
+	if len(args) == 0 {
+		reply(leaveReportUsage)
+		return
+	}
+
+	switch args[0] {
+	case "encashment":
+		year := app.today().Year()
+		if len(args) > 1 {
+			y, err := strconv.Atoi(args[1])
+			if err != nil {
+				reply(fmt.Sprintf("❌ Invalid year %q", args[1]))
+				return
+			}
+			year = y
+		}
+		app.postEncashmentReport(cmd, year, reply)
+	case "office":
+		weeks := 4
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n <= 0 || n > 52 {
+				reply(fmt.Sprintf("❌ Invalid number of weeks %q", args[1]))
+				return
+			}
+			weeks = n
+		}
+		app.postOfficeUtilizationReport(weeks, reply)
+	case "compliance":
+		value := ""
+		if len(args) > 1 {
+			value = args[1]
+		}
+		month, err := parseReportMonth(value, app.today())
+		if err != nil {
+			reply("❌ " + err.Error())
+			return
+		}
+		app.postComplianceReport(month, reply)
+	case "lop":
+		value := ""
+		if len(args) > 1 {
+			value = args[1]
+		}
+		month, err := parseReportMonth(value, app.today())
+		if err != nil {
+			reply("❌ " + err.Error())
+			return
+		}
+		app.postLossOfPayReport(month, reply)
+	case "hr":
+		text, err := app.runHRReviewReport("slack:"+cmd.UserID, args)
+		if err != nil {
+			reply("❌ " + err.Error())
+			return
+		}
+		reply(text)
+	default:
+		reply(leaveReportUsage)
+	}
+}
+
+// postEncashmentReport shows a summary to the requester and sends the full
+// CSV to their DMs rather than the channel, since it contains everyone's
+// balances.
+func (a *App) postEncashmentReport(cmd slack.SlashCommand, year int, reply func(string)) {
+	for _, scope := range []string{scopeFilesWrite, scopeIMWrite} {
+		if !a.hasScope(scope) {
+			reply(missingScopeText(scope))
+			return
+		}
+	}
+
+	lines, err := a.encashmentReport(year)
+	if err != nil {
+		logger.Error("Failed to build encashment report: %v", err)
+		reply("❌ " + err.Error())
+		return
+	}
+	if len(lines) == 0 {
+		reply(fmt.Sprintf("No leave records found for %d.", year))
+		return
+	}
+
+	var total float64
+	for _, line := range lines {
+		total += line.Encashable
+	}
+
+	data, err := encashmentCSV(lines)
+	if err != nil {
+		logger.Error("Failed to write encashment CSV: %v", err)
+		reply("❌ Failed to generate the CSV file.")
+		return
+	}
+
+	channel, _, _, err := a.slackClient.OpenConversation(&slack.OpenConversationParameters{Users: []string{cmd.UserID}})
+	if err != nil {
+		logger.Error("Failed to open DM with %s: %v", cmd.UserID, err)
+		reply("❌ Couldn't send you the report in a DM.")
+		return
+	}
+
+	_, err = a.slackClient.UploadFileV2(slack.UploadFileV2Parameters{
+		Filename: fmt.Sprintf("encashment-%d.csv", year),
+		Title:    fmt.Sprintf("Leave encashment %d", year),
+		Content:  string(data),
+		FileSize: len(data),
+		Channel:  channel.ID,
+		InitialComment: fmt.Sprintf("💰 *Leave encashment report %d*\n"+
+			"• Employees: %d\n"+
+			"• Annual entitlement (employees): %s days\n"+
+			"• Encashment cap: %s days\n"+
+			"• Total encashable: %s days",
+			year, len(lines), formatDays(a.config.AnnualLeaveDays), formatDays(a.config.EncashmentMaxDays), formatDays(total)),
+	})
+	if err != nil {
+		logger.Error("Failed to upload encashment report: %v", err)
+		reply("❌ Failed to upload the report.")
+		return
+	}
+
+	reply("📬 The encashment report has been sent to you in a DM.")
+}
+
+func (a *App) postOfficeUtilizationReport(weeks int, reply func(string)) {
+	lines, start, end, err := a.officeUtilizationReport(weeks)
+	if err != nil {
+		logger.Error("Failed to build office utilization report: %v", err)
+		reply("❌ " + err.Error())
+		return
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "🏢 *Office utilization %s – %s*\n",
+		start.Format("Jan 2"), end.AddDate(0, 0, -1).Format("Jan 2, 2006"))
+	for _, line := range lines {
+		fmt.Fprintf(&b, "• *%s*: %.1f in office, %.1f WFH on average (%.0f%% in office, %d people)\n",
+			line.Weekday, line.AvgInOffice, line.AvgWFH, line.OfficeShare*100, line.OfficeUsers)
+	}
+	reply(b.String())
+}
+
+// handleEncashmentReport serves /api/admin/reports/encashment?year=&format=csv|json
+func (a *App) handleEncashmentReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	year := a.today().Year()
+	if y := r.URL.Query().Get("year"); y != "" {
+		n, err := strconv.Atoi(y)
+		if err != nil {
+			http.Error(w, "Invalid year", http.StatusBadRequest)
+			return
+		}
+		year = n
+	}
+
+	lines, err := a.encashmentReport(year)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "json" {
+		writeJSON(w, http.StatusOK, lines)
+		return
+	}
+
+	data, err := encashmentCSV(lines)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=encashment-%d.csv", year))
+	w.Write(data)
+}
+
+// handleOfficeReport serves /api/admin/reports/office?weeks=
+func (a *App) handleOfficeReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	weeks := 4
+	if v := r.URL.Query().Get("weeks"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > 52 {
+			http.Error(w, "Invalid weeks", http.StatusBadRequest)
+			return
+		}
+		weeks = n
+	}
+
+	lines, start, end, err := a.officeUtilizationReport(weeks)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"start":    start.Format("2006-01-02"),
+		"end":      end.AddDate(0, 0, -1).Format("2006-01-02"),
+		"weekdays": lines,
+	})
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+)
+
+type ApprovalDelegateRepository struct {
+	db *sql.DB
+}
+
+func NewApprovalDelegateRepository(db *sql.DB) *ApprovalDelegateRepository {
+	return &ApprovalDelegateRepository{db: db}
+}
+
+const approvalDelegateColumns = `approver_id, backup_id, active_until, updated_by, updated_at`
+
+func scanApprovalDelegate(row rowScanner) (*models.ApprovalDelegate, error) {
+	var delegate models.ApprovalDelegate
+	var activeUntil sql.NullTime
+	err := row.Scan(&delegate.ApproverID, &delegate.BackupID, &activeUntil, &delegate.UpdatedBy, &delegate.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if activeUntil.Valid {
+		delegate.ActiveUntil = &activeUntil.Time
+	}
+	return &delegate, nil
+}
+
+// Upsert sets an approver's backup, replacing any earlier one. Whether the
+// backup is currently covering is left as it was.
+func (r *ApprovalDelegateRepository) Upsert(delegate *models.ApprovalDelegate) error {
+	query := `
+		INSERT INTO approval_delegates (approver_id, backup_id, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (approver_id) DO UPDATE
+		SET backup_id = EXCLUDED.backup_id,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	delegate.UpdatedAt = time.Now()
+	_, err := r.db.Exec(query, delegate.ApproverID, delegate.BackupID, delegate.UpdatedBy, delegate.UpdatedAt)
+	return err
+}
+
+// Get returns an approver's backup, or nil if they have none.
+func (r *ApprovalDelegateRepository) Get(approverID string) (*models.ApprovalDelegate, error) {
+	query := `SELECT ` + approvalDelegateColumns + ` FROM approval_delegates WHERE approver_id = $1`
+
+	delegate, err := scanApprovalDelegate(r.db.QueryRow(query, approverID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return delegate, err
+}
+
+// List returns every approver's backup, by approver.
+func (r *ApprovalDelegateRepository) List() ([]models.ApprovalDelegate, error) {
+	rows, err := r.db.Query(`SELECT ` + approvalDelegateColumns + ` FROM approval_delegates ORDER BY approver_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var delegates []models.ApprovalDelegate
+	for rows.Next() {
+		delegate, err := scanApprovalDelegate(rows)
+		if err != nil {
+			return nil, err
+		}
+		delegates = append(delegates, *delegate)
+	}
+	return delegates, rows.Err()
+}
+
+// SetActiveUntil records that the backup covers for the approver through
+// the date until, or, with nil, that the approver is back.
+func (r *ApprovalDelegateRepository) SetActiveUntil(approverID string, until *time.Time) error {
+	_, err := r.db.Exec(`UPDATE approval_delegates SET active_until = $2 WHERE approver_id = $1`, approverID, until)
+	return err
+}
+
+// Delete removes an approver's backup.
+func (r *ApprovalDelegateRepository) Delete(approverID string) error {
+	result, err := r.db.Exec(`DELETE FROM approval_delegates WHERE approver_id = $1`, approverID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("<@%s> has no backup approver", approverID)
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+)
+
+type AuditRepository struct {
+	db *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// Record appends an entry to the audit log, chaining it to the previous
+// entry: each entry stores the hash of the one before it and a hash over its
+// own contents plus that link, so altering or removing any entry breaks
+// every hash after it. Writers are serialised so the chain never forks.
+func (r *AuditRepository) Record(entry *models.AuditEntry) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := recordAudit(tx, entry); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// recordAudit appends entry to the audit log inside tx. The table lock is
+// held until tx ends.
+func recordAudit(tx *sql.Tx, entry *models.AuditEntry) error {
+	if _, err := tx.Exec(`LOCK TABLE audit_log IN EXCLUSIVE MODE`); err != nil {
+		return err
+	}
+
+	var prevHash sql.NullString
+	err := tx.QueryRow(`SELECT hash FROM audit_log WHERE hash IS NOT NULL ORDER BY id DESC LIMIT 1`).Scan(&prevHash)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	// TIMESTAMP keeps microseconds, so hash exactly what will be read back
+	entry.CreatedAt = time.Now().Truncate(time.Microsecond)
+	entry.PrevHash = prevHash.String
+	entry.Hash = auditEntryHash(entry)
+
+	var leaveID sql.NullInt64
+	if entry.LeaveID != 0 {
+		leaveID = sql.NullInt64{Int64: entry.LeaveID, Valid: true}
+	}
+
+	query := `
+		INSERT INTO audit_log (actor, action, leave_id, before_data, after_data, created_at, prev_hash, hash)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8)
+		RETURNING id
+	`
+	return tx.QueryRow(
+		query,
+		entry.Actor,
+		entry.Action,
+		leaveID,
+		entry.Before,
+		entry.After,
+		entry.CreatedAt,
+		entry.PrevHash,
+		entry.Hash,
+	).Scan(&entry.ID)
+}
+
+// auditEntryHash is the SHA-256 of the entry's contents and the previous
+// entry's hash. The fields are JSON-encoded as a list so no two different
+// entries can serialise the same way.
+func auditEntryHash(entry *models.AuditEntry) string {
+	data, _ := json.Marshal([]interface{}{
+		entry.PrevHash,
+		entry.Actor,
+		entry.Action,
+		entry.LeaveID,
+		entry.Before,
+		entry.After,
+		entry.CreatedAt.Format("2006-01-02 15:04:05.000000"),
+	})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyChain walks the whole audit log in order, recomputing every hash and
+// checking each entry links to the one before it. Entries written before the
+// chain was introduced have no hash and are counted separately; a missing
+// hash after the chain has started is a failure.
+func (r *AuditRepository) VerifyChain() (*models.AuditChainStatus, error) {
+	rows, err := r.db.Query(`
+		SELECT id, actor, action, COALESCE(leave_id, 0), COALESCE(before_data, ''), COALESCE(after_data, ''),
+			created_at, COALESCE(prev_hash, ''), COALESCE(hash, '')
+		FROM audit_log
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	status := &models.AuditChainStatus{Valid: true}
+	for rows.Next() {
+		var entry models.AuditEntry
+		err := rows.Scan(&entry.ID, &entry.Actor, &entry.Action, &entry.LeaveID, &entry.Before, &entry.After,
+			&entry.CreatedAt, &entry.PrevHash, &entry.Hash)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case entry.Hash == "" && status.Entries == 0:
+			status.Legacy++
+			continue
+		case entry.Hash == "":
+			status.Problem = "entry has no hash"
+		case entry.PrevHash != status.HeadHash:
+			status.Problem = "entry doesn't link to the previous entry; an entry before it was removed or altered"
+		case auditEntryHash(&entry) != entry.Hash:
+			status.Problem = "entry contents don't match its hash"
+		}
+		if status.Problem != "" {
+			status.Valid = false
+			status.BrokenAt = entry.ID
+			break
+		}
+
+		status.Entries++
+		status.HeadID = entry.ID
+		status.HeadHash = entry.Hash
+	}
+
+	return status, rows.Err()
+}
+
+func (r *AuditRepository) ListByLeave(leaveID int64) ([]models.AuditEntry, error) {
+	query := `
+		SELECT id, actor, action, COALESCE(leave_id, 0), COALESCE(before_data, ''), COALESCE(after_data, ''), created_at
+		FROM audit_log
+		WHERE leave_id = $1
+		ORDER BY id
+	`
+
+	rows, err := r.db.Query(query, leaveID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.AuditEntry
+	for rows.Next() {
+		var entry models.AuditEntry
+		err := rows.Scan(&entry.ID, &entry.Actor, &entry.Action, &entry.LeaveID, &entry.Before, &entry.After, &entry.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// ListAfter returns the entries after afterID in order, for incremental
+// exports. Writers are serialised, so IDs are committed in order and none
+// are skipped.
+func (r *AuditRepository) ListAfter(afterID int64) ([]models.AuditEntry, error) {
+	query := `
+		SELECT id, actor, action, COALESCE(leave_id, 0), COALESCE(before_data, ''), COALESCE(after_data, ''), created_at,
+			COALESCE(prev_hash, ''), COALESCE(hash, '')
+		FROM audit_log
+		WHERE id > $1
+		ORDER BY id
+	`
+
+	rows, err := r.db.Query(query, afterID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.AuditEntry
+	for rows.Next() {
+		var entry models.AuditEntry
+		err := rows.Scan(&entry.ID, &entry.Actor, &entry.Action, &entry.LeaveID, &entry.Before, &entry.After, &entry.CreatedAt,
+			&entry.PrevHash, &entry.Hash)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+)
+
+type BalanceLedgerRepository struct {
+	db *sql.DB
+}
+
+func NewBalanceLedgerRepository(db *sql.DB) *BalanceLedgerRepository {
+	return &BalanceLedgerRepository{db: db}
+}
+
+const ledgerColumns = `id, username, entry_type, category, days, effective_date, reason, COALESCE(leave_id, 0), created_by, created_at`
+
+func scanLedgerEntry(row rowScanner) (*models.LedgerEntry, error) {
+	var entry models.LedgerEntry
+	err := row.Scan(
+		&entry.ID,
+		&entry.Username,
+		&entry.EntryType,
+		&entry.Category,
+		&entry.Days,
+		&entry.EffectiveDate,
+		&entry.Reason,
+		&entry.LeaveID,
+		&entry.CreatedBy,
+		&entry.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Append adds an entry to the ledger. A user gets at most one accrual per
+// effective date, so posting an accrual again is a no-op that returns false.
+// Entries without a category go to the annual balance.
+func (r *BalanceLedgerRepository) Append(entry *models.LedgerEntry) (bool, error) {
+	if entry.Category == "" {
+		entry.Category = models.LedgerAnnual
+	}
+	entry.CreatedAt = time.Now()
+	err := r.db.QueryRow(`
+		INSERT INTO balance_ledger (username, entry_type, category, days, effective_date, reason, leave_id, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, 0), $8, $9)
+		ON CONFLICT (username, effective_date) WHERE entry_type = 'ACCRUAL' DO NOTHING
+		RETURNING id
+	`,
+		entry.Username,
+		entry.EntryType,
+		entry.Category,
+		entry.Days,
+		entry.EffectiveDate,
+		entry.Reason,
+		entry.LeaveID,
+		entry.CreatedBy,
+		entry.CreatedAt,
+	).Scan(&entry.ID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// List returns the user's entries in every category effective in
+// [from, to), oldest first.
+func (r *BalanceLedgerRepository) List(username string, from, to time.Time) ([]models.LedgerEntry, error) {
+	rows, err := r.db.Query(`
+		SELECT `+ledgerColumns+`
+		FROM balance_ledger
+		WHERE username = $1 AND effective_date >= $2::date AND effective_date < $3::date
+		ORDER BY effective_date, id
+	`, username, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.LedgerEntry
+	for rows.Next() {
+		entry, err := scanLedgerEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *entry)
+	}
+
+	return entries, nil
+}
+
+// Totals sums the user's entries in a category effective in [from, to) per
+// entry type.
+func (r *BalanceLedgerRepository) Totals(username, category string, from, to time.Time) (map[string]float64, error) {
+	rows, err := r.db.Query(`
+		SELECT entry_type, SUM(days)
+		FROM balance_ledger
+		WHERE username = $1 AND category = $2 AND effective_date >= $3::date AND effective_date < $4::date
+		GROUP BY entry_type
+	`, username, category, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := make(map[string]float64)
+	for rows.Next() {
+		var entryType string
+		var days float64
+		if err := rows.Scan(&entryType, &days); err != nil {
+			return nil, err
+		}
+		totals[entryType] = days
+	}
+
+	return totals, nil
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+)
+
+type ChannelDigestRepository struct {
+	db *sql.DB
+}
+
+func NewChannelDigestRepository(db *sql.DB) *ChannelDigestRepository {
+	return &ChannelDigestRepository{db: db}
+}
+
+const channelDigestColumns = `channel_id, schedule, timezone, members_only, updated_by, updated_at`
+
+func scanChannelDigest(row rowScanner) (*models.ChannelDigest, error) {
+	var digest models.ChannelDigest
+	err := row.Scan(&digest.ChannelID, &digest.Schedule, &digest.Timezone, &digest.MembersOnly, &digest.UpdatedBy, &digest.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &digest, nil
+}
+
+// Upsert stores a channel's digest settings, replacing any earlier ones.
+func (r *ChannelDigestRepository) Upsert(digest *models.ChannelDigest) error {
+	query := `
+		INSERT INTO channel_digests (channel_id, schedule, timezone, members_only, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (channel_id) DO UPDATE
+		SET schedule = EXCLUDED.schedule,
+			timezone = EXCLUDED.timezone,
+			members_only = EXCLUDED.members_only,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	digest.UpdatedAt = time.Now()
+	_, err := r.db.Exec(query, digest.ChannelID, digest.Schedule, digest.Timezone, digest.MembersOnly, digest.UpdatedBy, digest.UpdatedAt)
+	return err
+}
+
+// List returns every channel's digest settings, by channel.
+func (r *ChannelDigestRepository) List() ([]models.ChannelDigest, error) {
+	rows, err := r.db.Query(`SELECT ` + channelDigestColumns + ` FROM channel_digests ORDER BY channel_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var digests []models.ChannelDigest
+	for rows.Next() {
+		digest, err := scanChannelDigest(rows)
+		if err != nil {
+			return nil, err
+		}
+		digests = append(digests, *digest)
+	}
+	return digests, rows.Err()
+}
+
+// Delete stops a channel's digest.
+func (r *ChannelDigestRepository) Delete(channelID string) error {
+	result, err := r.db.Exec(`DELETE FROM channel_digests WHERE channel_id = $1`, channelID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("no digest is posted to %s", channelID)
+	}
+	return nil
+}
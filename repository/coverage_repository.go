@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"database/sql"
+)
+
+// CoverageRepository stores coverage roles (e.g. "release-manager"), the
+// people who fill them, and how many of them must be available on any
+// working day.
+type CoverageRepository struct {
+	db *sql.DB
+}
+
+func NewCoverageRepository(db *sql.DB) *CoverageRepository {
+	return &CoverageRepository{db: db}
+}
+
+func (r *CoverageRepository) AddMember(username, role string) error {
+	query := `
+		INSERT INTO coverage_members (username, role)
+		VALUES ($1, $2)
+		ON CONFLICT (username, role) DO NOTHING
+	`
+
+	_, err := r.db.Exec(query, username, role)
+	return err
+}
+
+func (r *CoverageRepository) RemoveMember(username, role string) error {
+	_, err := r.db.Exec(`DELETE FROM coverage_members WHERE username = $1 AND role = $2`, username, role)
+	return err
+}
+
+// Members returns who fills role. Departed employees don't count towards
+// coverage and are left out.
+func (r *CoverageRepository) Members(role string) ([]string, error) {
+	return r.queryStrings(`
+		SELECT username FROM coverage_members
+		WHERE role = $1 AND username NOT IN (`+departedUsers+`)
+		ORDER BY username
+	`, role)
+}
+
+// ListMembers returns everyone who fills each role, departed or not, keyed
+// by role.
+func (r *CoverageRepository) ListMembers() (map[string][]string, error) {
+	rows, err := r.db.Query(`SELECT role, username FROM coverage_members ORDER BY role, username`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := make(map[string][]string)
+	for rows.Next() {
+		var role, username string
+		if err := rows.Scan(&role, &username); err != nil {
+			return nil, err
+		}
+		members[role] = append(members[role], username)
+	}
+	return members, rows.Err()
+}
+
+// RolesFor returns the coverage roles the user fills.
+func (r *CoverageRepository) RolesFor(username string) ([]string, error) {
+	return r.queryStrings(`SELECT role FROM coverage_members WHERE username = $1 ORDER BY role`, username)
+}
+
+// SetRule requires at least minAvailable members of role to be available. A
+// minAvailable of zero removes the rule.
+func (r *CoverageRepository) SetRule(role string, minAvailable int) error {
+	if minAvailable <= 0 {
+		_, err := r.db.Exec(`DELETE FROM coverage_rules WHERE role = $1`, role)
+		return err
+	}
+
+	query := `
+		INSERT INTO coverage_rules (role, min_available)
+		VALUES ($1, $2)
+		ON CONFLICT (role) DO UPDATE SET min_available = EXCLUDED.min_available
+	`
+
+	_, err := r.db.Exec(query, role, minAvailable)
+	return err
+}
+
+// GetRules returns the minimum available count keyed by role.
+func (r *CoverageRepository) GetRules() (map[string]int, error) {
+	rows, err := r.db.Query(`SELECT role, min_available FROM coverage_rules ORDER BY role`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := make(map[string]int)
+	for rows.Next() {
+		var role string
+		var minAvailable int
+		if err := rows.Scan(&role, &minAvailable); err != nil {
+			return nil, err
+		}
+		rules[role] = minAvailable
+	}
+
+	return rules, nil
+}
+
+func (r *CoverageRepository) queryStrings(query string, args ...interface{}) ([]string, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+
+	return values, nil
+}
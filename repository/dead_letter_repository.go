@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+)
+
+type DeadLetterRepository struct {
+	db *sql.DB
+}
+
+func NewDeadLetterRepository(db *sql.DB) *DeadLetterRepository {
+	return &DeadLetterRepository{db: db}
+}
+
+// Record stores a failed message as pending.
+func (r *DeadLetterRepository) Record(letter *models.DeadLetter) error {
+	query := `
+		INSERT INTO dead_letters (channel, message_ts, user_id, text, stage, error, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`
+
+	letter.Status = models.DeadLetterPending
+	letter.CreatedAt = time.Now()
+	return r.db.QueryRow(
+		query,
+		letter.Channel,
+		letter.MessageTS,
+		letter.UserID,
+		letter.Text,
+		letter.Stage,
+		letter.Error,
+		letter.Status,
+		letter.CreatedAt,
+	).Scan(&letter.ID)
+}
+
+const deadLetterColumns = `id, channel, message_ts, user_id, text, stage, error, status, created_at, resolved_at, COALESCE(resolved_by, '')`
+
+func scanDeadLetter(row rowScanner) (*models.DeadLetter, error) {
+	var letter models.DeadLetter
+	var resolvedAt sql.NullTime
+	err := row.Scan(
+		&letter.ID,
+		&letter.Channel,
+		&letter.MessageTS,
+		&letter.UserID,
+		&letter.Text,
+		&letter.Stage,
+		&letter.Error,
+		&letter.Status,
+		&letter.CreatedAt,
+		&resolvedAt,
+		&letter.ResolvedBy,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if resolvedAt.Valid {
+		letter.ResolvedAt = &resolvedAt.Time
+	}
+	return &letter, nil
+}
+
+// Get returns the dead letter with the given ID, or nil if there is none.
+func (r *DeadLetterRepository) Get(id int64) (*models.DeadLetter, error) {
+	query := `SELECT ` + deadLetterColumns + ` FROM dead_letters WHERE id = $1`
+
+	letter, err := scanDeadLetter(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return letter, err
+}
+
+// ListPending returns up to limit pending dead letters, oldest first.
+func (r *DeadLetterRepository) ListPending(limit int) ([]*models.DeadLetter, error) {
+	query := `SELECT ` + deadLetterColumns + ` FROM dead_letters WHERE status = $1 ORDER BY id LIMIT $2`
+
+	rows, err := r.db.Query(query, models.DeadLetterPending, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var letters []*models.DeadLetter
+	for rows.Next() {
+		letter, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, err
+		}
+		letters = append(letters, letter)
+	}
+	return letters, rows.Err()
+}
+
+// Resolve moves a pending dead letter to status. It reports false when the
+// letter doesn't exist or was already resolved, so two admins can't replay
+// the same message.
+func (r *DeadLetterRepository) Resolve(id int64, status, actor string) (bool, error) {
+	query := `
+		UPDATE dead_letters SET status = $2, resolved_at = $3, resolved_by = $4
+		WHERE id = $1 AND status = $5
+	`
+
+	result, err := r.db.Exec(query, id, status, time.Now(), actor, models.DeadLetterPending)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
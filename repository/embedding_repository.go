@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+)
+
+type EmbeddingRepository struct {
+	db *sql.DB
+}
+
+func NewEmbeddingRepository(db *sql.DB) *EmbeddingRepository {
+	return &EmbeddingRepository{db: db}
+}
+
+// LeaveText is the text of a record that gets embedded: its reason and the
+// message it was parsed from. Private reasons are never embedded.
+type LeaveText struct {
+	LeaveID int64
+	Text    string
+}
+
+// SimilarLeave is a search hit. Similarity is the cosine similarity of the
+// record's embedding to the search's, up to 1.
+type SimilarLeave struct {
+	models.Leave
+	Similarity float64 `json:"similarity"`
+}
+
+// Available reports whether the leave_embeddings table exists. It's only
+// created where pgvector is installed.
+func (r *EmbeddingRepository) Available() (bool, error) {
+	var available bool
+	err := r.db.QueryRow(`SELECT to_regclass('leave_embeddings') IS NOT NULL`).Scan(&available)
+	return available, err
+}
+
+// ListStale returns up to limit records, other than office days, with no
+// embedding or one made before the record last changed.
+func (r *EmbeddingRepository) ListStale(limit int) ([]LeaveText, error) {
+	query := `
+		SELECT l.id, CONCAT_WS(' - ', NULLIF(l.reason, ''), l.original_text)
+		FROM leaves l
+		LEFT JOIN leave_embeddings e ON e.leave_id = l.id
+		WHERE l.leave_type <> 'IN_OFFICE' AND l.deleted_at IS NULL AND (e.leave_id IS NULL OR e.embedded_at < l.updated_at)
+		ORDER BY l.id
+		LIMIT $1
+	`
+
+	rows, err := r.db.Query(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var texts []LeaveText
+	for rows.Next() {
+		var text LeaveText
+		if err := rows.Scan(&text.LeaveID, &text.Text); err != nil {
+			return nil, err
+		}
+		texts = append(texts, text)
+	}
+	return texts, rows.Err()
+}
+
+// Save stores the embedding of a record, replacing any earlier one.
+func (r *EmbeddingRepository) Save(leaveID int64, model string, embedding []float32) error {
+	query := `
+		INSERT INTO leave_embeddings (leave_id, model, embedding, embedded_at)
+		VALUES ($1, $2, $3::vector, $4)
+		ON CONFLICT (leave_id) DO UPDATE
+		SET model = EXCLUDED.model, embedding = EXCLUDED.embedding, embedded_at = EXCLUDED.embedded_at
+	`
+
+	_, err := r.db.Exec(query, leaveID, model, vectorLiteral(embedding), time.Now())
+	return err
+}
+
+// Search returns the limit records of people still here closest in meaning
+// to embedding, most similar first. Zero start or end leaves that side of
+// the period open; end is exclusive.
+func (r *EmbeddingRepository) Search(embedding []float32, start, end time.Time, limit int) ([]SimilarLeave, error) {
+	query := `
+		SELECT ` + leaveColumns + `, 1 - (e.embedding <=> $1::vector)
+		FROM leaves
+		JOIN leave_embeddings e ON e.leave_id = leaves.id
+		WHERE ($2::timestamp IS NULL OR start_time >= $2) AND ($3::timestamp IS NULL OR start_time < $3)
+			AND username NOT IN (` + departedUsers + `) AND deleted_at IS NULL AND status <> 'REJECTED'
+		ORDER BY e.embedding <=> $1::vector
+		LIMIT $4
+	`
+
+	rows, err := r.db.Query(query, vectorLiteral(embedding), nullTime(start), nullTime(end), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []SimilarLeave
+	for rows.Next() {
+		var similarity float64
+		leave, err := scanLeave(extraColumns{rows, []interface{}{&similarity}})
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, SimilarLeave{Leave: *leave, Similarity: similarity})
+	}
+	return results, rows.Err()
+}
+
+// extraColumns scans a record followed by more columns.
+type extraColumns struct {
+	row  rowScanner
+	dest []interface{}
+}
+
+func (e extraColumns) Scan(dest ...interface{}) error {
+	return e.row.Scan(append(dest, e.dest...)...)
+}
+
+// vectorLiteral formats an embedding as pgvector text, e.g. "[0.1,0.2]".
+func vectorLiteral(embedding []float32) string {
+	parts := make([]string, len(embedding))
+	for i, v := range embedding {
+		parts[i] = strconv.FormatFloat(float64(v), 'g', -1, 32)
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
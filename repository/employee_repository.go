@@ -0,0 +1,404 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+
+	"github.com/lib/pq"
+)
+
+type EmployeeRepository struct {
+	db *sql.DB
+}
+
+func NewEmployeeRepository(db *sql.DB) *EmployeeRepository {
+	return &EmployeeRepository{db: db}
+}
+
+// SetEmploymentType records the user's employment type, creating the
+// employee row if it doesn't exist yet.
+func (r *EmployeeRepository) SetEmploymentType(username, slackUserID, employmentType string) error {
+	query := `
+		INSERT INTO employees (username, slack_user_id, employment_type, created_at, updated_at)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $4)
+		ON CONFLICT (username) DO UPDATE
+		SET employment_type = EXCLUDED.employment_type,
+			slack_user_id = COALESCE(EXCLUDED.slack_user_id, employees.slack_user_id),
+			updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := r.db.Exec(query, username, slackUserID, employmentType, time.Now())
+	return err
+}
+
+// GetEmploymentType returns the user's employment type, defaulting to
+// EMPLOYEE for users not in the employees table.
+func (r *EmployeeRepository) GetEmploymentType(username string) (string, error) {
+	var employmentType string
+	err := r.db.QueryRow(`SELECT employment_type FROM employees WHERE username = $1`, username).Scan(&employmentType)
+	if err == sql.ErrNoRows {
+		return models.EmploymentEmployee, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return employmentType, nil
+}
+
+// GetEmploymentTypes returns the employment type of every known employee
+// keyed by username.
+func (r *EmployeeRepository) GetEmploymentTypes() (map[string]string, error) {
+	rows, err := r.db.Query(`SELECT username, employment_type FROM employees`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	types := make(map[string]string)
+	for rows.Next() {
+		var username, employmentType string
+		if err := rows.Scan(&username, &employmentType); err != nil {
+			return nil, err
+		}
+		types[username] = employmentType
+	}
+
+	return types, nil
+}
+
+// SetManager records who the user reports to, creating the employee row if
+// it doesn't exist yet.
+func (r *EmployeeRepository) SetManager(username, slackUserID, managerID string) error {
+	query := `
+		INSERT INTO employees (username, slack_user_id, manager_slack_id, created_at, updated_at)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $4)
+		ON CONFLICT (username) DO UPDATE
+		SET manager_slack_id = EXCLUDED.manager_slack_id,
+			slack_user_id = COALESCE(EXCLUDED.slack_user_id, employees.slack_user_id),
+			updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := r.db.Exec(query, username, slackUserID, managerID, time.Now())
+	return err
+}
+
+// GetManagers returns the manager's Slack user ID for every employee that
+// has one, keyed by username.
+func (r *EmployeeRepository) GetManagers() (map[string]string, error) {
+	rows, err := r.db.Query(`SELECT username, manager_slack_id FROM employees WHERE manager_slack_id IS NOT NULL`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	managers := make(map[string]string)
+	for rows.Next() {
+		var username, managerID string
+		if err := rows.Scan(&username, &managerID); err != nil {
+			return nil, err
+		}
+		managers[username] = managerID
+	}
+
+	return managers, nil
+}
+
+// GetReports returns the usernames of everyone who reports to managerID.
+func (r *EmployeeRepository) GetReports(managerID string) ([]string, error) {
+	rows, err := r.db.Query(`SELECT username FROM employees WHERE manager_slack_id = $1 ORDER BY username`, managerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var usernames []string
+	for rows.Next() {
+		var username string
+		if err := rows.Scan(&username); err != nil {
+			return nil, err
+		}
+		usernames = append(usernames, username)
+	}
+
+	return usernames, nil
+}
+
+const employeeColumns = `username, COALESCE(slack_user_id, ''), employment_type, COALESCE(external_id, ''),
+	COALESCE(email, ''), COALESCE(full_name, ''), COALESCE(department, ''),
+	COALESCE(manager_slack_id, ''), COALESCE(location_code, ''), COALESCE(timezone, ''), active`
+
+func scanEmployee(row rowScanner) (*models.Employee, error) {
+	var employee models.Employee
+	err := row.Scan(
+		&employee.Username,
+		&employee.SlackUserID,
+		&employee.EmploymentType,
+		&employee.ExternalID,
+		&employee.Email,
+		&employee.FullName,
+		&employee.Department,
+		&employee.ManagerID,
+		&employee.LocationCode,
+		&employee.Timezone,
+		&employee.Active,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &employee, nil
+}
+
+// Upsert writes a directory record from the identity provider or HR roster.
+// The roster is authoritative, so every field it carries overwrites what was
+// set by hand and empty optional fields clear the stored value. The office is
+// the exception: it's left alone when not given, since it's usually assigned
+// with /office assign rather than by the roster.
+func (r *EmployeeRepository) Upsert(employee *models.Employee) error {
+	employmentType := employee.EmploymentType
+	if employmentType == "" {
+		employmentType = models.EmploymentEmployee
+	}
+
+	query := `
+		INSERT INTO employees (username, slack_user_id, employment_type, external_id, email, full_name,
+			department, manager_slack_id, location_code, active, deactivated_at, created_at, updated_at)
+		VALUES ($1, NULLIF($2, ''), $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''),
+			NULLIF($7, ''), NULLIF($8, ''), NULLIF($9, ''), $10, CASE WHEN $10 THEN NULL ELSE $11::timestamp END, $11, $11)
+		ON CONFLICT (username) DO UPDATE
+		SET slack_user_id = COALESCE(EXCLUDED.slack_user_id, employees.slack_user_id),
+			employment_type = EXCLUDED.employment_type,
+			external_id = COALESCE(EXCLUDED.external_id, employees.external_id),
+			email = EXCLUDED.email,
+			full_name = EXCLUDED.full_name,
+			department = EXCLUDED.department,
+			manager_slack_id = EXCLUDED.manager_slack_id,
+			location_code = COALESCE(EXCLUDED.location_code, employees.location_code),
+			active = EXCLUDED.active,
+			deactivated_at = CASE
+				WHEN EXCLUDED.active THEN NULL
+				ELSE COALESCE(employees.deactivated_at, EXCLUDED.deactivated_at)
+			END,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := r.db.Exec(query,
+		employee.Username,
+		employee.SlackUserID,
+		employmentType,
+		employee.ExternalID,
+		employee.Email,
+		employee.FullName,
+		employee.Department,
+		employee.ManagerID,
+		employee.LocationCode,
+		employee.Active,
+		time.Now(),
+	)
+	return err
+}
+
+// SyncSlackProfile writes what Slack knows of an active member. Slack only
+// fills in the name and email where the roster hasn't set them, but its
+// user ID and timezone always win. People Slack has that the directory
+// doesn't are added as employees.
+func (r *EmployeeRepository) SyncSlackProfile(employee *models.Employee) error {
+	query := `
+		INSERT INTO employees (username, slack_user_id, email, full_name, timezone, slack_synced_at, created_at, updated_at)
+		VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''), $6, $6, $6)
+		ON CONFLICT (username) DO UPDATE
+		SET slack_user_id = COALESCE(EXCLUDED.slack_user_id, employees.slack_user_id),
+			email = COALESCE(employees.email, EXCLUDED.email),
+			full_name = COALESCE(employees.full_name, EXCLUDED.full_name),
+			timezone = EXCLUDED.timezone,
+			slack_synced_at = EXCLUDED.slack_synced_at
+	`
+
+	_, err := r.db.Exec(query,
+		employee.Username,
+		employee.SlackUserID,
+		employee.Email,
+		employee.FullName,
+		employee.Timezone,
+		time.Now(),
+	)
+	return err
+}
+
+func (r *EmployeeRepository) Get(username string) (*models.Employee, error) {
+	query := `SELECT ` + employeeColumns + ` FROM employees WHERE username = $1`
+
+	employee, err := scanEmployee(r.db.QueryRow(query, username))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("employee %s not found", username)
+	}
+	return employee, err
+}
+
+// GetBySlackID looks an employee up by their Slack user ID, returning nil if
+// there is no such employee.
+func (r *EmployeeRepository) GetBySlackID(slackUserID string) (*models.Employee, error) {
+	query := `SELECT ` + employeeColumns + ` FROM employees WHERE slack_user_id = $1`
+
+	employee, err := scanEmployee(r.db.QueryRow(query, slackUserID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return employee, err
+}
+
+// GetByEmail looks an employee up by email, ignoring case, returning nil if
+// there is no such employee.
+func (r *EmployeeRepository) GetByEmail(email string) (*models.Employee, error) {
+	query := `SELECT ` + employeeColumns + ` FROM employees WHERE LOWER(email) = LOWER($1) LIMIT 1`
+
+	employee, err := scanEmployee(r.db.QueryRow(query, email))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return employee, err
+}
+
+// List returns every employee, optionally only those still active.
+func (r *EmployeeRepository) List(activeOnly bool) ([]models.Employee, error) {
+	query := `SELECT ` + employeeColumns + ` FROM employees WHERE active OR NOT $1 ORDER BY username`
+
+	rows, err := r.db.Query(query, activeOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var employees []models.Employee
+	for rows.Next() {
+		employee, err := scanEmployee(rows)
+		if err != nil {
+			return nil, err
+		}
+		employees = append(employees, *employee)
+	}
+
+	return employees, nil
+}
+
+// Deactivate marks the employee as departed. Their records are kept but they
+// drop out of reports.
+func (r *EmployeeRepository) Deactivate(username string) error {
+	result, err := r.db.Exec(`
+		UPDATE employees
+		SET active = FALSE, deactivated_at = COALESCE(deactivated_at, $2), updated_at = $2
+		WHERE username = $1
+	`, username, time.Now())
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("employee %s not found", username)
+	}
+	return nil
+}
+
+// SetActive marks the user as active or departed, creating the employee row
+// if it doesn't exist yet, and reports whether anything changed.
+func (r *EmployeeRepository) SetActive(username, slackUserID string, active bool) (bool, error) {
+	query := `
+		INSERT INTO employees (username, slack_user_id, active, deactivated_at, created_at, updated_at)
+		VALUES ($1, NULLIF($2, ''), $3, CASE WHEN $3 THEN NULL ELSE $4::timestamp END, $4, $4)
+		ON CONFLICT (username) DO UPDATE
+		SET active = EXCLUDED.active,
+			deactivated_at = EXCLUDED.deactivated_at,
+			slack_user_id = COALESCE(EXCLUDED.slack_user_id, employees.slack_user_id),
+			updated_at = EXCLUDED.updated_at
+		WHERE employees.active <> EXCLUDED.active
+		RETURNING username
+	`
+
+	var updated string
+	err := r.db.QueryRow(query, username, slackUserID, active, time.Now()).Scan(&updated)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// DeactivateMissing deactivates every active employee not in usernames, for
+// full roster imports, and returns who was deactivated.
+func (r *EmployeeRepository) DeactivateMissing(usernames []string) ([]string, error) {
+	rows, err := r.db.Query(`
+		UPDATE employees
+		SET active = FALSE, deactivated_at = $2, updated_at = $2
+		WHERE active AND username <> ALL($1)
+		RETURNING username
+	`, pq.Array(usernames), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deactivated []string
+	for rows.Next() {
+		var username string
+		if err := rows.Scan(&username); err != nil {
+			return nil, err
+		}
+		deactivated = append(deactivated, username)
+	}
+
+	return deactivated, rows.Err()
+}
+
+// GetInactive returns the usernames of departed employees.
+func (r *EmployeeRepository) GetInactive() (map[string]bool, error) {
+	rows, err := r.db.Query(`SELECT username FROM employees WHERE NOT active`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	inactive := make(map[string]bool)
+	for rows.Next() {
+		var username string
+		if err := rows.Scan(&username); err != nil {
+			return nil, err
+		}
+		inactive[username] = true
+	}
+
+	return inactive, nil
+}
+
+// SearchUsernames returns up to limit active usernames containing query,
+// ignoring case. Anyone with a leave record counts, not just people in the
+// employees table.
+func (r *EmployeeRepository) SearchUsernames(query string, limit int) ([]string, error) {
+	rows, err := r.db.Query(`
+		SELECT username FROM (
+			SELECT username FROM employees WHERE active
+			UNION
+			SELECT DISTINCT username FROM leaves WHERE username NOT IN (`+departedUsers+`)
+		) u
+		WHERE position(LOWER($1) in LOWER(username)) > 0
+		ORDER BY username
+		LIMIT $2
+	`, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var usernames []string
+	for rows.Next() {
+		var username string
+		if err := rows.Scan(&username); err != nil {
+			return nil, err
+		}
+		usernames = append(usernames, username)
+	}
+
+	return usernames, nil
+}
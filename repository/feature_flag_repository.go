@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+)
+
+type FeatureFlagRepository struct {
+	db *sql.DB
+}
+
+func NewFeatureFlagRepository(db *sql.DB) *FeatureFlagRepository {
+	return &FeatureFlagRepository{db: db}
+}
+
+const featureFlagColumns = `team_id, flag, enabled, updated_by, updated_at`
+
+func scanFeatureFlag(row rowScanner) (*models.FeatureFlag, error) {
+	var flag models.FeatureFlag
+	err := row.Scan(&flag.TeamID, &flag.Flag, &flag.Enabled, &flag.UpdatedBy, &flag.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &flag, nil
+}
+
+// Get returns a workspace's override of a flag, or nil if it has none.
+func (r *FeatureFlagRepository) Get(teamID, flag string) (*models.FeatureFlag, error) {
+	query := `SELECT ` + featureFlagColumns + ` FROM feature_flags WHERE team_id = $1 AND flag = $2`
+
+	featureFlag, err := scanFeatureFlag(r.db.QueryRow(query, teamID, flag))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return featureFlag, err
+}
+
+// List returns every override a workspace has, by flag.
+func (r *FeatureFlagRepository) List(teamID string) ([]models.FeatureFlag, error) {
+	query := `SELECT ` + featureFlagColumns + ` FROM feature_flags WHERE team_id = $1 ORDER BY flag`
+
+	rows, err := r.db.Query(query, teamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var flags []models.FeatureFlag
+	for rows.Next() {
+		flag, err := scanFeatureFlag(rows)
+		if err != nil {
+			return nil, err
+		}
+		flags = append(flags, *flag)
+	}
+	return flags, rows.Err()
+}
+
+// Set stores a workspace's override of a flag, replacing any earlier one.
+func (r *FeatureFlagRepository) Set(flag *models.FeatureFlag) error {
+	query := `
+		INSERT INTO feature_flags (team_id, flag, enabled, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (team_id, flag) DO UPDATE
+		SET enabled = EXCLUDED.enabled, updated_by = EXCLUDED.updated_by, updated_at = EXCLUDED.updated_at
+	`
+
+	flag.UpdatedAt = time.Now()
+	_, err := r.db.Exec(query, flag.TeamID, flag.Flag, flag.Enabled, flag.UpdatedBy, flag.UpdatedAt)
+	return err
+}
+
+// Clear removes a workspace's override, so the flag goes back to its
+// default.
+func (r *FeatureFlagRepository) Clear(teamID, flag string) error {
+	_, err := r.db.Exec(`DELETE FROM feature_flags WHERE team_id = $1 AND flag = $2`, teamID, flag)
+	return err
+}
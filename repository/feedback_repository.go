@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+
+	"github.com/lib/pq"
+)
+
+type FeedbackRepository struct {
+	db *sql.DB
+}
+
+func NewFeedbackRepository(db *sql.DB) *FeedbackRepository {
+	return &FeedbackRepository{db: db}
+}
+
+// Record stores a user's rating of a response. Rating the same response
+// again replaces the earlier rating and puts it back up for review.
+func (r *FeedbackRepository) Record(feedback *models.Feedback) error {
+	query := `
+		INSERT INTO feedback (kind, target, leave_id, query_text, original_text, parsed_data, user_id, rating, created_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), $7, $8, $9)
+		ON CONFLICT (target, user_id) DO UPDATE
+		SET rating = EXCLUDED.rating,
+			original_text = EXCLUDED.original_text,
+			parsed_data = EXCLUDED.parsed_data,
+			created_at = EXCLUDED.created_at,
+			reviewed_at = NULL,
+			reviewed_by = NULL,
+			review_outcome = NULL
+		RETURNING id
+	`
+
+	var leaveID sql.NullInt64
+	if feedback.LeaveID != 0 {
+		leaveID = sql.NullInt64{Int64: feedback.LeaveID, Valid: true}
+	}
+
+	feedback.CreatedAt = time.Now()
+	return r.db.QueryRow(
+		query,
+		feedback.Kind,
+		feedback.Target,
+		leaveID,
+		feedback.QueryText,
+		feedback.OriginalText,
+		feedback.ParsedData,
+		feedback.UserID,
+		feedback.Rating,
+		feedback.CreatedAt,
+	).Scan(&feedback.ID)
+}
+
+// AddExample stores a parse example. An example for the same record
+// replaces the earlier one, so a correction supersedes a confirmation.
+func (r *FeedbackRepository) AddExample(example *models.ParseExample) error {
+	var leaveID sql.NullInt64
+	if example.LeaveID != 0 {
+		leaveID = sql.NullInt64{Int64: example.LeaveID, Valid: true}
+	}
+
+	example.CreatedAt = time.Now()
+	return r.db.QueryRow(`
+		INSERT INTO parse_examples (message, posted_at, leave_type, start_time, end_time, reason, source, leave_id, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (leave_id) DO UPDATE
+		SET message = EXCLUDED.message,
+			posted_at = EXCLUDED.posted_at,
+			leave_type = EXCLUDED.leave_type,
+			start_time = EXCLUDED.start_time,
+			end_time = EXCLUDED.end_time,
+			reason = EXCLUDED.reason,
+			source = EXCLUDED.source,
+			created_by = EXCLUDED.created_by,
+			created_at = EXCLUDED.created_at
+		RETURNING id
+	`,
+		example.Message,
+		example.PostedAt,
+		example.LeaveType,
+		example.StartTime,
+		example.EndTime,
+		example.Reason,
+		example.Source,
+		leaveID,
+		example.CreatedBy,
+		example.CreatedAt,
+	).Scan(&example.ID)
+}
+
+// ListExamples returns up to limit examples, corrections and messages it
+// missed first since they show the parser what it got wrong, then the most
+// recent.
+func (r *FeedbackRepository) ListExamples(limit int) ([]models.ParseExample, error) {
+	rows, err := r.db.Query(`
+		SELECT id, message, posted_at, leave_type, start_time, end_time, reason, source,
+			COALESCE(leave_id, 0), created_by, created_at
+		FROM parse_examples
+		ORDER BY source IN ('correction', 'unparsed') DESC, created_at DESC
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var examples []models.ParseExample
+	for rows.Next() {
+		var e models.ParseExample
+		err := rows.Scan(&e.ID, &e.Message, &e.PostedAt, &e.LeaveType, &e.StartTime, &e.EndTime, &e.Reason,
+			&e.Source, &e.LeaveID, &e.CreatedBy, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		examples = append(examples, e)
+	}
+
+	return examples, nil
+}
+
+// ReviewQueue returns recorded leaves flagged as misparsed that no admin has
+// reviewed yet, most flagged first. The message and parse are from the
+// earliest flag, i.e. what the parser originally produced.
+func (r *FeedbackRepository) ReviewQueue(limit int) ([]models.MisparseReport, error) {
+	rows, err := r.db.Query(`
+		SELECT leave_id,
+			COALESCE((ARRAY_AGG(original_text ORDER BY created_at))[1], ''),
+			COALESCE((ARRAY_AGG(parsed_data ORDER BY created_at))[1], ''),
+			ARRAY_AGG(user_id ORDER BY created_at),
+			MIN(created_at)
+		FROM feedback
+		WHERE kind = 'parse' AND rating < 0 AND reviewed_at IS NULL AND leave_id IS NOT NULL
+		GROUP BY leave_id
+		ORDER BY COUNT(*) DESC, MIN(created_at)
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reports []models.MisparseReport
+	for rows.Next() {
+		var report models.MisparseReport
+		err := rows.Scan(&report.LeaveID, &report.OriginalText, &report.ParsedData,
+			pq.Array(&report.FlaggedBy), &report.FirstFlaggedAt)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+// ResolveMisparse takes a record out of the review queue, returning how many
+// flags it cleared.
+func (r *FeedbackRepository) ResolveMisparse(leaveID int64, reviewedBy, outcome string) (int64, error) {
+	result, err := r.db.Exec(`
+		UPDATE feedback
+		SET reviewed_at = $1, reviewed_by = $2, review_outcome = $3
+		WHERE kind = 'parse' AND rating < 0 AND reviewed_at IS NULL AND leave_id = $4
+	`, time.Now(), reviewedBy, outcome, leaveID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+
+	"github.com/lib/pq"
+)
+
+type HRReviewRepository struct {
+	db *sql.DB
+}
+
+func NewHRReviewRepository(db *sql.DB) *HRReviewRepository {
+	return &HRReviewRepository{db: db}
+}
+
+// Create adds a record to HR's review queue.
+func (r *HRReviewRepository) Create(review *models.HRReview) error {
+	query := `
+		INSERT INTO hr_reviews (leave_id, username, reasons, status, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`
+
+	review.Status = models.HRReviewOpen
+	review.CreatedAt = time.Now()
+	return r.db.QueryRow(query, review.LeaveID, review.Username, pq.Array(review.Reasons), review.Status, review.CreatedAt).
+		Scan(&review.ID)
+}
+
+const hrReviewColumns = `id, leave_id, username, reasons, status, created_at, resolved_at, COALESCE(resolved_by, '')`
+
+func scanHRReview(row rowScanner) (*models.HRReview, error) {
+	var review models.HRReview
+	var resolvedAt sql.NullTime
+	err := row.Scan(
+		&review.ID,
+		&review.LeaveID,
+		&review.Username,
+		pq.Array(&review.Reasons),
+		&review.Status,
+		&review.CreatedAt,
+		&resolvedAt,
+		&review.ResolvedBy,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if resolvedAt.Valid {
+		review.ResolvedAt = &resolvedAt.Time
+	}
+	return &review, nil
+}
+
+// ListOpen returns up to limit reviews still waiting for HR, oldest first.
+func (r *HRReviewRepository) ListOpen(limit int) ([]models.HRReview, error) {
+	query := `
+		SELECT ` + hrReviewColumns + ` FROM hr_reviews
+		WHERE status = $1
+		ORDER BY created_at, id
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(query, models.HRReviewOpen, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reviews []models.HRReview
+	for rows.Next() {
+		review, err := scanHRReview(rows)
+		if err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, *review)
+	}
+	return reviews, rows.Err()
+}
+
+// Resolve closes an open review. It reports false when the review doesn't
+// exist or was already resolved.
+func (r *HRReviewRepository) Resolve(id int64, actor string) (bool, error) {
+	query := `
+		UPDATE hr_reviews SET status = $2, resolved_at = $3, resolved_by = $4
+		WHERE id = $1 AND status = $5
+	`
+
+	result, err := r.db.Exec(query, id, models.HRReviewResolved, time.Now(), actor, models.HRReviewOpen)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
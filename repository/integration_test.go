@@ -0,0 +1,164 @@
+//go:build integration
+
+// The integration suite runs every repository method against a real
+// Postgres with the full set of migrations applied:
+//
+//	go test -tags integration ./repository/...
+//
+// It starts a throwaway postgres container with the docker CLI, or uses the
+// database in INTEGRATION_DATABASE_URL instead. That database is wiped, so
+// never point it at one that matters.
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"slack-leaves-ai-agent/db/migrations"
+	"slack-leaves-ai-agent/models"
+
+	_ "github.com/lib/pq"
+)
+
+const integrationImage = "postgres:15-alpine"
+
+var testDB *sql.DB
+
+func TestMain(m *testing.M) {
+	os.Exit(runIntegration(m))
+}
+
+func runIntegration(m *testing.M) int {
+	connStr := os.Getenv("INTEGRATION_DATABASE_URL")
+	if connStr == "" {
+		container, err := startPostgres()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error starting postgres: %v\n", err)
+			return 1
+		}
+		defer exec.Command("docker", "rm", "-f", container.id).Run()
+		connStr = container.connStr
+	}
+
+	db, err := openWhenReady(connStr, time.Minute)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error connecting to database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	if err := migrations.Run(db); err != nil {
+		fmt.Fprintf(os.Stderr, "Migration failed: %v\n", err)
+		return 1
+	}
+	testDB = db
+
+	return m.Run()
+}
+
+type postgresContainer struct {
+	id      string
+	connStr string
+}
+
+// startPostgres runs a postgres container on a free local port.
+func startPostgres() (*postgresContainer, error) {
+	out, err := exec.Command("docker", "run", "-d", "--rm",
+		"-e", "POSTGRES_USER=leaves",
+		"-e", "POSTGRES_PASSWORD=leaves",
+		"-e", "POSTGRES_DB=leaves",
+		"-p", "127.0.0.1::5432",
+		integrationImage,
+	).Output()
+	if err != nil {
+		return nil, fmt.Errorf("docker run: %v", err)
+	}
+	id := strings.TrimSpace(string(out))
+
+	out, err = exec.Command("docker", "port", id, "5432/tcp").Output()
+	if err != nil {
+		exec.Command("docker", "rm", "-f", id).Run()
+		return nil, fmt.Errorf("docker port: %v", err)
+	}
+	// e.g. "127.0.0.1:49153", one line per address family
+	addr := strings.TrimSpace(strings.Split(string(out), "\n")[0])
+	host, port, ok := strings.Cut(addr, ":")
+	if !ok {
+		exec.Command("docker", "rm", "-f", id).Run()
+		return nil, fmt.Errorf("unexpected docker port output %q", addr)
+	}
+
+	return &postgresContainer{
+		id:      id,
+		connStr: fmt.Sprintf("host=%s port=%s user=leaves password=leaves dbname=leaves sslmode=disable", host, port),
+	}, nil
+}
+
+// openWhenReady waits for the database to accept connections, since a fresh
+// container takes a few seconds to start.
+func openWhenReady(connStr string, timeout time.Duration) (*sql.DB, error) {
+	db, err := sql.Open("postgres", connStr)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	for {
+		err := db.PingContext(ctx)
+		if err == nil {
+			return db, nil
+		}
+		select {
+		case <-ctx.Done():
+			db.Close()
+			return nil, fmt.Errorf("database not ready after %s: %v", timeout, err)
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+// resetDB empties every table, so each test starts from a migrated but
+// empty database. TRUNCATE doesn't fire the append-only triggers.
+func resetDB(t *testing.T) {
+	t.Helper()
+	_, err := testDB.Exec(`
+		TRUNCATE leaves, recurring_leaves, user_roles, audit_log, employees, locations, holidays,
+			coverage_members, coverage_rules, viewer_tokens, feedback, parse_examples, balance_ledger, warehouse_exports,
+			pipeline_events, dead_letters, settings, feature_flags, channel_digests, feature_usage, unparsed_messages,
+			approval_delegates, hr_reviews
+		RESTART IDENTITY CASCADE
+	`)
+	if err != nil {
+		t.Fatalf("Error resetting database: %v", err)
+	}
+}
+
+// day returns midnight UTC of the given date. TIMESTAMP columns come back
+// in UTC, so times built this way compare equal after a round trip.
+func day(year int, month time.Month, d int) time.Time {
+	return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+// createLeave stores a record of leaveType for username from start to end.
+func createLeave(t *testing.T, repo *LeaveRepository, username, leaveType string, start, end time.Time) *models.Leave {
+	t.Helper()
+	leave := &models.Leave{
+		Username:     username,
+		OriginalText: leaveType + " for " + username,
+		StartTime:    start,
+		EndTime:      end,
+		Duration:     models.FormatDuration(start, end),
+		LeaveType:    leaveType,
+	}
+	if err := repo.Create(leave); err != nil {
+		t.Fatalf("Error creating leave: %v", err)
+	}
+	return leave
+}
@@ -3,59 +3,781 @@ package repository
 import (
 	"database/sql"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"slack-leaves-ai-agent/models"
+
+	"github.com/lib/pq"
 )
 
-type LeaveRepository struct {
-	db *sql.DB
+// querier runs statements on the database, or inside a transaction for a
+// repository handed out by Audited.
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+type LeaveRepository struct {
+	db querier
+}
+
+func NewLeaveRepository(db *sql.DB) *LeaveRepository {
+	return &LeaveRepository{db: db}
+}
+
+func (r *LeaveRepository) Create(leave *models.Leave) error {
+	query := `
+		INSERT INTO leaves (
+			username, original_text, start_time, end_time, 
+			duration, reason, leave_type, created_at, updated_at, recurrence_id, private_reason, status, sick
+		) VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8, $9, NULLIF($10, 0), NULLIF($11, ''), $12, $13)
+		RETURNING id
+	`
+
+	if leave.Status == "" {
+		leave.Status = models.LeaveStatusApproved
+	}
+	now := time.Now()
+	err := r.db.QueryRow(
+		query,
+		leave.Username,
+		leave.OriginalText,
+		leave.StartTime,
+		leave.EndTime,
+		leave.Duration,
+		leave.Reason,
+		leave.LeaveType,
+		now,
+		now,
+		leave.RecurrenceID,
+		leave.PrivateReason,
+		leave.Status,
+		leave.Sick,
+	).Scan(&leave.ID)
+
+	return err
+}
+
+const leaveColumns = `id, username, original_text, start_time, end_time, duration, COALESCE(reason, ''), leave_type,
+	created_at, updated_at, COALESCE(recurrence_id, 0), COALESCE(private_reason, ''), status, deleted_at,
+	COALESCE(calendar_event_id, '')`
+
+// departedUsers selects employees the roster has marked as inactive.
+// Company-wide reports leave them out; their records are kept.
+const departedUsers = `SELECT username FROM employees WHERE NOT active`
+
+// absenceSplit splits a user's records into absences and remote days, so
+// WFH isn't read as time off. Remote days are calendar days of WFH.
+const absenceSplit = `
+	COUNT(*) FILTER (WHERE leave_type <> 'WFH') as absences,
+	COALESCE(SUM(end_time::date - start_time::date + 1) FILTER (WHERE leave_type = 'WFH'), 0) as remote_days`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanLeave(row rowScanner) (*models.Leave, error) {
+	var leave models.Leave
+	var deletedAt sql.NullTime
+	err := row.Scan(
+		&leave.ID,
+		&leave.Username,
+		&leave.OriginalText,
+		&leave.StartTime,
+		&leave.EndTime,
+		&leave.Duration,
+		&leave.Reason,
+		&leave.LeaveType,
+		&leave.CreatedAt,
+		&leave.UpdatedAt,
+		&leave.RecurrenceID,
+		&leave.PrivateReason,
+		&leave.Status,
+		&deletedAt,
+		&leave.CalendarEventID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if deletedAt.Valid {
+		leave.DeletedAt = &deletedAt.Time
+	}
+	return &leave, nil
+}
+
+func (r *LeaveRepository) GetByID(id int64) (*models.Leave, error) {
+	query := `SELECT ` + leaveColumns + ` FROM leaves WHERE id = $1 AND deleted_at IS NULL`
+
+	leave, err := scanLeave(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("leave record %d not found", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return leave, nil
+}
+
+func (r *LeaveRepository) ListByUsername(username string, limit int) ([]models.Leave, error) {
+	query := `
+		SELECT ` + leaveColumns + `
+		FROM leaves
+		WHERE username = $1 AND deleted_at IS NULL AND status <> 'REJECTED'
+		ORDER BY start_time DESC
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(query, username, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var leaves []models.Leave
+	for rows.Next() {
+		leave, err := scanLeave(rows)
+		if err != nil {
+			return nil, err
+		}
+		leaves = append(leaves, *leave)
+	}
+
+	return leaves, nil
+}
+
+// ListStartingFrom returns the user's records starting at or after from,
+// earliest first.
+func (r *LeaveRepository) ListStartingFrom(username string, from time.Time) ([]models.Leave, error) {
+	query := `
+		SELECT ` + leaveColumns + `
+		FROM leaves
+		WHERE username = $1 AND start_time >= $2 AND deleted_at IS NULL AND status <> 'REJECTED'
+		ORDER BY start_time
+	`
+
+	rows, err := r.db.Query(query, username, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var leaves []models.Leave
+	for rows.Next() {
+		leave, err := scanLeave(rows)
+		if err != nil {
+			return nil, err
+		}
+		leaves = append(leaves, *leave)
+	}
+
+	return leaves, nil
+}
+
+// ListUpdatedBetween returns the records created or changed in
+// (after, through], oldest change first, for incremental exports.
+func (r *LeaveRepository) ListUpdatedBetween(after, through time.Time) ([]models.Leave, error) {
+	query := `
+		SELECT ` + leaveColumns + `
+		FROM leaves
+		WHERE updated_at > $1 AND updated_at <= $2
+		ORDER BY updated_at, id
+	`
+
+	rows, err := r.db.Query(query, after, through)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var leaves []models.Leave
+	for rows.Next() {
+		leave, err := scanLeave(rows)
+		if err != nil {
+			return nil, err
+		}
+		leaves = append(leaves, *leave)
+	}
+
+	return leaves, rows.Err()
+}
+
+// FindByUserAndDate returns the user's records overlapping the given day.
+func (r *LeaveRepository) FindByUserAndDate(username string, day time.Time) ([]models.Leave, error) {
+	dayStart := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	dayEnd := dayStart.AddDate(0, 0, 1)
+
+	query := `
+		SELECT ` + leaveColumns + `
+		FROM leaves
+		WHERE username = $1 AND start_time < $3 AND end_time > $2 AND deleted_at IS NULL AND status <> 'REJECTED'
+		ORDER BY start_time
+	`
+
+	rows, err := r.db.Query(query, username, dayStart, dayEnd)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var leaves []models.Leave
+	for rows.Next() {
+		leave, err := scanLeave(rows)
+		if err != nil {
+			return nil, err
+		}
+		leaves = append(leaves, *leave)
+	}
+
+	return leaves, nil
+}
+
+// ListBetween returns every record overlapping [start, end), ordered by
+// user and start time.
+func (r *LeaveRepository) ListBetween(start, end time.Time) ([]models.Leave, error) {
+	query := `
+		SELECT ` + leaveColumns + `
+		FROM leaves
+		WHERE start_time < $2 AND end_time > $1 AND deleted_at IS NULL AND status <> 'REJECTED'
+		ORDER BY username, start_time
+	`
+
+	rows, err := r.db.Query(query, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var leaves []models.Leave
+	for rows.Next() {
+		leave, err := scanLeave(rows)
+		if err != nil {
+			return nil, err
+		}
+		leaves = append(leaves, *leave)
+	}
+
+	return leaves, nil
+}
+
+// ListBetweenPage returns up to limit records overlapping [start, end) in
+// the order of ListBetween, starting after last, the final record of the
+// previous page, or from the first when last is nil.
+func (r *LeaveRepository) ListBetweenPage(start, end time.Time, last *models.Leave, limit int) ([]models.Leave, error) {
+	args := []interface{}{start, end, limit}
+	after := ""
+	if last != nil {
+		after = "AND (username, start_time, id) > ($4, $5, $6)"
+		args = append(args, last.Username, last.StartTime, last.ID)
+	}
+	query := `
+		SELECT ` + leaveColumns + `
+		FROM leaves
+		WHERE start_time < $2 AND end_time > $1 AND deleted_at IS NULL AND status <> 'REJECTED'
+		` + after + `
+		ORDER BY username, start_time, id
+		LIMIT $3
+	`
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var leaves []models.Leave
+	for rows.Next() {
+		leave, err := scanLeave(rows)
+		if err != nil {
+			return nil, err
+		}
+		leaves = append(leaves, *leave)
+	}
+
+	return leaves, nil
+}
+
+// ListOnDay returns the records of people still here that overlap day,
+// a calendar date in office wall-clock time, ordered by type and user.
+// Office days aren't absences and are left out.
+func (r *LeaveRepository) ListOnDay(day time.Time) ([]models.Leave, error) {
+	dayStart := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+
+	query := `
+		SELECT ` + leaveColumns + `
+		FROM leaves
+		WHERE start_time < $2 AND end_time > $1 AND deleted_at IS NULL AND status <> 'REJECTED'
+			AND leave_type <> 'IN_OFFICE' AND username NOT IN (` + departedUsers + `)
+		ORDER BY leave_type, username, start_time
+	`
+
+	rows, err := r.db.Query(query, dayStart, dayStart.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var leaves []models.Leave
+	for rows.Next() {
+		leave, err := scanLeave(rows)
+		if err != nil {
+			return nil, err
+		}
+		leaves = append(leaves, *leave)
+	}
+
+	return leaves, nil
+}
+
+// ListUpcoming returns up to limit approved records of people still here
+// that haven't ended by from, earliest first. When usernames isn't empty,
+// only their records are returned. Office days are left out.
+func (r *LeaveRepository) ListUpcoming(from time.Time, usernames []string, limit int) ([]models.Leave, error) {
+	query := `
+		SELECT ` + leaveColumns + `
+		FROM leaves
+		WHERE end_time > $1 AND deleted_at IS NULL AND status = 'APPROVED' AND leave_type <> 'IN_OFFICE'
+			AND username NOT IN (` + departedUsers + `)
+			AND (cardinality($2::text[]) = 0 OR username = ANY($2))
+		ORDER BY start_time, username
+		LIMIT $3
+	`
+
+	rows, err := r.db.Query(query, from, pq.Array(usernames), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var leaves []models.Leave
+	for rows.Next() {
+		leave, err := scanLeave(rows)
+		if err != nil {
+			return nil, err
+		}
+		leaves = append(leaves, *leave)
+	}
+
+	return leaves, rows.Err()
+}
+
+// LeaveFilter narrows ListMatching to the records behind a report. Zero
+// fields don't filter; office days are only included when LeaveTypes asks
+// for them, as in the reports.
+type LeaveFilter struct {
+	Start          time.Time `json:"start"`
+	End            time.Time `json:"end"`
+	Usernames      []string  `json:"usernames,omitempty"`
+	LeaveTypes     []string  `json:"leave_types,omitempty"`
+	EmploymentType string    `json:"employment_type,omitempty"`
+}
+
+// ListMatching returns up to limit records of people still here that start
+// in [filter.Start, filter.End) and match the filter, earliest first.
+func (r *LeaveRepository) ListMatching(filter LeaveFilter, limit int) ([]models.Leave, error) {
+	var args []interface{}
+	conditions := []string{"username NOT IN (" + departedUsers + ")", "deleted_at IS NULL", "status <> 'REJECTED'"}
+	where := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if !filter.Start.IsZero() {
+		where("start_time >= $%d", filter.Start)
+	}
+	if !filter.End.IsZero() {
+		where("start_time < $%d", filter.End)
+	}
+	if len(filter.Usernames) > 0 {
+		where("username = ANY($%d)", pq.Array(filter.Usernames))
+	}
+	if len(filter.LeaveTypes) > 0 {
+		where("leave_type = ANY($%d)", pq.Array(filter.LeaveTypes))
+	} else {
+		conditions = append(conditions, "leave_type <> 'IN_OFFICE'")
+	}
+	if filter.EmploymentType != "" {
+		where("COALESCE((SELECT employment_type FROM employees e WHERE e.username = leaves.username), 'EMPLOYEE') = $%d", filter.EmploymentType)
+	}
+	args = append(args, limit)
+
+	query := `
+		SELECT ` + leaveColumns + `
+		FROM leaves
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY start_time, username
+		LIMIT $` + strconv.Itoa(len(args))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var leaves []models.Leave
+	for rows.Next() {
+		leave, err := scanLeave(rows)
+		if err != nil {
+			return nil, err
+		}
+		leaves = append(leaves, *leave)
+	}
+
+	return leaves, rows.Err()
+}
+
+func (r *LeaveRepository) Update(leave *models.Leave) error {
+	query := `
+		UPDATE leaves
+		SET username = $1, start_time = $2, end_time = $3, duration = $4,
+			reason = NULLIF($5, ''), leave_type = $6, updated_at = $7, private_reason = NULLIF($9, '')
+		WHERE id = $8 AND deleted_at IS NULL
+	`
+
+	leave.UpdatedAt = time.Now()
+	result, err := r.db.Exec(
+		query,
+		leave.Username,
+		leave.StartTime,
+		leave.EndTime,
+		leave.Duration,
+		leave.Reason,
+		leave.LeaveType,
+		leave.UpdatedAt,
+		leave.ID,
+		leave.PrivateReason,
+	)
+	if err != nil {
+		return err
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("leave record %d not found", leave.ID)
+	}
+	return nil
+}
+
+// SetPrivateReason stores the private details of a record, or clears them
+// when privateReason is empty.
+func (r *LeaveRepository) SetPrivateReason(id int64, privateReason string) error {
+	result, err := r.db.Exec(`
+		UPDATE leaves SET private_reason = NULLIF($1, ''), updated_at = $2 WHERE id = $3
+	`, privateReason, time.Now(), id)
+	if err != nil {
+		return err
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("leave record %d not found", id)
+	}
+	return nil
+}
+
+// SetCalendarEventID links a record to its calendar event, or unlinks it
+// when eventID is empty. It leaves updated_at alone: the record itself
+// hasn't changed.
+func (r *LeaveRepository) SetCalendarEventID(id int64, eventID string) error {
+	_, err := r.db.Exec(`UPDATE leaves SET calendar_event_id = NULLIF($1, '') WHERE id = $2`, eventID, id)
+	return err
+}
+
+// SetStatus moves a record from one approval status to another. It reports
+// false when the record isn't in status from any more, so a decision is only
+// taken once.
+func (r *LeaveRepository) SetStatus(id int64, from, to, decidedBy string) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE leaves SET status = $1, decided_by = $2, decided_at = $3, updated_at = $3
+		WHERE id = $4 AND status = $5
+	`, to, decidedBy, time.Now(), id, from)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// Resubmit sends an approved record back for approval after its author
+// changed it, clearing the earlier decision. It reports false when the
+// record isn't approved.
+func (r *LeaveRepository) Resubmit(id int64) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE leaves SET status = $1, decided_by = NULL, decided_at = NULL, updated_at = $2
+		WHERE id = $3 AND status = $4 AND deleted_at IS NULL
+	`, models.LeaveStatusPending, time.Now(), id, models.LeaveStatusApproved)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// ListByStatus returns up to limit records in the given approval status,
+// oldest first.
+func (r *LeaveRepository) ListByStatus(status string, limit int) ([]models.Leave, error) {
+	query := `
+		SELECT ` + leaveColumns + `
+		FROM leaves
+		WHERE status = $1 AND deleted_at IS NULL
+		ORDER BY created_at, id
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(query, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var leaves []models.Leave
+	for rows.Next() {
+		leave, err := scanLeave(rows)
+		if err != nil {
+			return nil, err
+		}
+		leaves = append(leaves, *leave)
+	}
+
+	return leaves, rows.Err()
+}
+
+// CountSick returns how many of the user's records starting in [start, end)
+// are sick days. Rejected and cancelled records don't count.
+func (r *LeaveRepository) CountSick(username string, start, end time.Time) (int, error) {
+	query := `
+		SELECT COUNT(*) FROM leaves
+		WHERE username = $1 AND sick AND start_time >= $2 AND start_time < $3
+			AND deleted_at IS NULL AND status <> 'REJECTED'
+	`
+
+	var count int
+	err := r.db.QueryRow(query, username, start, end).Scan(&count)
+	return count, err
+}
+
+// ListAwaitingApprovalEmail returns up to limit records that have been
+// waiting for approval since before createdBefore and whose approver hasn't
+// been emailed about them, oldest first.
+func (r *LeaveRepository) ListAwaitingApprovalEmail(createdBefore time.Time, limit int) ([]models.Leave, error) {
+	query := `
+		SELECT ` + leaveColumns + `
+		FROM leaves
+		WHERE status = $1 AND created_at < $2 AND approval_emailed_at IS NULL AND deleted_at IS NULL
+		ORDER BY created_at, id
+		LIMIT $3
+	`
+
+	rows, err := r.db.Query(query, models.LeaveStatusPending, createdBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var leaves []models.Leave
+	for rows.Next() {
+		leave, err := scanLeave(rows)
+		if err != nil {
+			return nil, err
+		}
+		leaves = append(leaves, *leave)
+	}
+
+	return leaves, rows.Err()
+}
+
+// MarkApprovalEmailed records that a record's approver was emailed about it.
+// Like SetCalendarEventID, it leaves updated_at alone.
+func (r *LeaveRepository) MarkApprovalEmailed(id int64) error {
+	_, err := r.db.Exec(`UPDATE leaves SET approval_emailed_at = $1 WHERE id = $2`, time.Now(), id)
+	return err
+}
+
+// Cancel soft-deletes a record: it's kept, with who cancelled it and when,
+// but no longer found by any lookup or report. Exports still see it, so they
+// can pick up the cancellation.
+func (r *LeaveRepository) Cancel(id int64, cancelledBy string) error {
+	result, err := r.db.Exec(`
+		UPDATE leaves SET deleted_at = $1, deleted_by = $2, updated_at = $1
+		WHERE id = $3 AND deleted_at IS NULL
+	`, time.Now(), cancelledBy, id)
+	if err != nil {
+		return err
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("leave record %d not found", id)
+	}
+	return nil
+}
+
+func (r *LeaveRepository) Delete(id int64) error {
+	result, err := r.db.Exec(`DELETE FROM leaves WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("leave record %d not found", id)
+	}
+	return nil
+}
+
+// Merge folds the record mergeID into keepID, widening keepID to cover both
+// time ranges, and removes mergeID. Both records must belong to the same user.
+func (r *LeaveRepository) Merge(keepID, mergeID int64) (*models.Leave, error) {
+	tx, owned, err := r.begin()
+	if err != nil {
+		return nil, err
+	}
+	if owned {
+		defer tx.Rollback()
+	}
+
+	query := `SELECT ` + leaveColumns + ` FROM leaves WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`
+
+	keep, err := scanLeave(tx.QueryRow(query, keepID))
+	if err != nil {
+		return nil, fmt.Errorf("error loading leave record %d: %v", keepID, err)
+	}
+	merge, err := scanLeave(tx.QueryRow(query, mergeID))
+	if err != nil {
+		return nil, fmt.Errorf("error loading leave record %d: %v", mergeID, err)
+	}
+
+	if keep.Username != merge.Username {
+		return nil, fmt.Errorf("cannot merge records of different users (%s, %s)", keep.Username, merge.Username)
+	}
+
+	if merge.StartTime.Before(keep.StartTime) {
+		keep.StartTime = merge.StartTime
+	}
+	if merge.EndTime.After(keep.EndTime) {
+		keep.EndTime = merge.EndTime
+	}
+	if keep.Reason == "" {
+		keep.Reason = merge.Reason
+	}
+	if keep.PrivateReason == "" {
+		keep.PrivateReason = merge.PrivateReason
+	}
+	keep.OriginalText = keep.OriginalText + "\n" + merge.OriginalText
+	keep.Duration = models.FormatDuration(keep.StartTime, keep.EndTime)
+	keep.UpdatedAt = time.Now()
+
+	_, err = tx.Exec(`
+		UPDATE leaves
+		SET original_text = $1, start_time = $2, end_time = $3, duration = $4, reason = NULLIF($5, ''), updated_at = $6,
+			private_reason = NULLIF($8, '')
+		WHERE id = $7
+	`, keep.OriginalText, keep.StartTime, keep.EndTime, keep.Duration, keep.Reason, keep.UpdatedAt, keep.ID, keep.PrivateReason)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM leaves WHERE id = $1`, merge.ID); err != nil {
+		return nil, err
+	}
+
+	if owned {
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+	}
+
+	return keep, nil
 }
 
-func NewLeaveRepository(db *sql.DB) *LeaveRepository {
-	return &LeaveRepository{db: db}
+// begin starts a transaction, or returns the one the repository is bound
+// to. Only a transaction it started (owned) is the caller's to commit.
+func (r *LeaveRepository) begin() (tx *sql.Tx, owned bool, err error) {
+	if tx, ok := r.db.(*sql.Tx); ok {
+		return tx, false, nil
+	}
+	tx, err = r.db.(*sql.DB).Begin()
+	return tx, true, err
 }
 
-func (r *LeaveRepository) Create(leave *models.Leave) error {
-	query := `
-		INSERT INTO leaves (
-			username, original_text, start_time, end_time, 
-			duration, reason, leave_type, created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-		RETURNING id
-	`
+// Audited runs change against a repository bound to a new transaction and
+// appends the audit entries it returns in that same transaction, so a change
+// is saved together with its audit entries or not at all.
+func (r *LeaveRepository) Audited(change func(leaves *LeaveRepository) ([]*models.AuditEntry, error)) error {
+	tx, owned, err := r.begin()
+	if err != nil {
+		return err
+	}
+	if !owned {
+		return fmt.Errorf("already in an audited change")
+	}
+	defer tx.Rollback()
 
-	now := time.Now()
-	err := r.db.QueryRow(
-		query,
-		leave.Username,
-		leave.OriginalText,
-		leave.StartTime,
-		leave.EndTime,
-		leave.Duration,
-		leave.Reason,
-		leave.LeaveType,
-		now,
-		now,
-	).Scan(&leave.ID)
+	entries, err := change(&LeaveRepository{db: tx})
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := recordAudit(tx, entry); err != nil {
+			return fmt.Errorf("error writing audit entry: %v", err)
+		}
+	}
+	return tx.Commit()
+}
 
-	return err
+// Comparison keeps only users whose record count compares to Value as Type
+// says, e.g. {"greater_than", 5} for more than 5 records.
+type Comparison struct {
+	Type  string `json:"type"`
+	Value int    `json:"value"`
+}
+
+// comparisonOperators are the comparison types and their SQL operators.
+// Operators are written into the query, so only these may be used.
+var comparisonOperators = map[string]string{
+	"greater_than": ">",
+	"less_than":    "<",
+	"at_least":     ">=",
+	"at_most":      "<=",
+	"equal":        "=",
+	"not_equal":    "<>",
 }
 
-func (r *LeaveRepository) GetLeaveStatsByPeriod(startDate, endDate time.Time) ([]LeaveStats, error) {
+// GetLeaveStatsByPeriod aggregates records per user. An empty employmentType
+// includes everyone; users missing from the employees table count as
+// EMPLOYEE. WFH is only counted when includeWFH is set. A nil comparison
+// keeps every user.
+func (r *LeaveRepository) GetLeaveStatsByPeriod(startDate, endDate time.Time, employmentType string, includeWFH bool, comparison *Comparison) ([]LeaveStats, error) {
+	args := []interface{}{startDate, endDate, employmentType, includeWFH}
+	having := ""
+	if comparison != nil {
+		operator, ok := comparisonOperators[comparison.Type]
+		if !ok {
+			return nil, fmt.Errorf("unsupported comparison %q", comparison.Type)
+		}
+		having = "HAVING COUNT(*) " + operator + " $5"
+		args = append(args, comparison.Value)
+	}
+
 	query := `
 		SELECT 
-			username,
+			l.username,
 			COUNT(*) as leave_count,
-			STRING_AGG(leave_type, ', ') as leave_types,
-			SUM(EXTRACT(EPOCH FROM (end_time - start_time))/3600) as total_hours
-		FROM leaves 
-		WHERE start_time BETWEEN $1 AND $2
-		GROUP BY username
+			STRING_AGG(l.leave_type, ', ') as leave_types,
+			SUM(EXTRACT(EPOCH FROM (l.end_time - l.start_time))/3600) as total_hours,
+			SUM(working_days(l.username, l.start_time, l.end_time, l.leave_type)) as working_days,` + absenceSplit + `
+		FROM leaves l
+		LEFT JOIN employees e ON e.username = l.username
+		WHERE l.start_time BETWEEN $1 AND $2
+			AND l.leave_type <> 'IN_OFFICE' AND l.deleted_at IS NULL AND l.status <> 'REJECTED'
+			AND ($4 OR l.leave_type <> 'WFH')
+			AND l.username NOT IN (` + departedUsers + `)
+			AND ($3 = '' OR COALESCE(e.employment_type, 'EMPLOYEE') = $3)
+		GROUP BY l.username
+		` + having + `
 		ORDER BY leave_count DESC
 	`
 
-	rows, err := r.db.Query(query, startDate, endDate)
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -64,7 +786,7 @@ func (r *LeaveRepository) GetLeaveStatsByPeriod(startDate, endDate time.Time) ([
 	var stats []LeaveStats
 	for rows.Next() {
 		var stat LeaveStats
-		err := rows.Scan(&stat.Username, &stat.LeaveCount, &stat.LeaveTypes, &stat.TotalHours)
+		err := rows.Scan(&stat.Username, &stat.LeaveCount, &stat.LeaveTypes, &stat.TotalHours, &stat.WorkingDays, &stat.Absences, &stat.RemoteDays)
 		if err != nil {
 			return nil, err
 		}
@@ -74,14 +796,60 @@ func (r *LeaveRepository) GetLeaveStatsByPeriod(startDate, endDate time.Time) ([
 	return stats, nil
 }
 
+// GetLeaveStatsByEmploymentType aggregates records per employment type.
+func (r *LeaveRepository) GetLeaveStatsByEmploymentType(startDate, endDate time.Time) ([]EmploymentTypeStats, error) {
+	query := `
+		SELECT
+			COALESCE(e.employment_type, 'EMPLOYEE') as employment_type,
+			COUNT(DISTINCT l.username) as user_count,
+			COUNT(*) as leave_count,
+			SUM(EXTRACT(EPOCH FROM (l.end_time - l.start_time))/3600) as total_hours,
+			SUM(working_days(l.username, l.start_time, l.end_time, l.leave_type)) as working_days
+		FROM leaves l
+		LEFT JOIN employees e ON e.username = l.username
+		WHERE l.start_time BETWEEN $1 AND $2
+			AND l.leave_type <> 'IN_OFFICE' AND l.deleted_at IS NULL AND l.status <> 'REJECTED'
+			AND l.username NOT IN (` + departedUsers + `)
+		GROUP BY 1
+		ORDER BY 1
+	`
+
+	rows, err := r.db.Query(query, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []EmploymentTypeStats
+	for rows.Next() {
+		var stat EmploymentTypeStats
+		if err := rows.Scan(&stat.EmploymentType, &stat.UserCount, &stat.LeaveCount, &stat.TotalHours, &stat.WorkingDays); err != nil {
+			return nil, err
+		}
+		stats = append(stats, stat)
+	}
+
+	return stats, nil
+}
+
+type EmploymentTypeStats struct {
+	EmploymentType string  `json:"employment_type"`
+	UserCount      int     `json:"user_count"`
+	LeaveCount     int     `json:"leave_count"`
+	TotalHours     float64 `json:"total_hours"`
+	WorkingDays    float64 `json:"working_days"`
+}
+
 func (r *LeaveRepository) GetTopLeaveEmployee() (*LeaveStats, error) {
 	query := `
 		SELECT 
 			username,
 			COUNT(*) as leave_count,
 			STRING_AGG(leave_type, ', ') as leave_types,
-			SUM(EXTRACT(EPOCH FROM (end_time - start_time))/3600) as total_hours
+			SUM(EXTRACT(EPOCH FROM (end_time - start_time))/3600) as total_hours,
+			SUM(working_days(username, start_time, end_time, leave_type)) as working_days,` + absenceSplit + `
 		FROM leaves 
+		WHERE leave_type <> 'IN_OFFICE' AND deleted_at IS NULL AND status <> 'REJECTED' AND username NOT IN (` + departedUsers + `)
 		GROUP BY username
 		ORDER BY leave_count DESC
 		LIMIT 1
@@ -93,6 +861,9 @@ func (r *LeaveRepository) GetTopLeaveEmployee() (*LeaveStats, error) {
 		&stat.LeaveCount,
 		&stat.LeaveTypes,
 		&stat.TotalHours,
+		&stat.WorkingDays,
+		&stat.Absences,
+		&stat.RemoteDays,
 	)
 
 	if err == sql.ErrNoRows {
@@ -111,9 +882,10 @@ func (r *LeaveRepository) GetEmployeeStats(username string) ([]LeaveStats, error
 			username,
 			COUNT(*) as leave_count,
 			STRING_AGG(leave_type, ', ') as leave_types,
-			SUM(EXTRACT(EPOCH FROM (end_time - start_time))/3600) as total_hours
+			SUM(EXTRACT(EPOCH FROM (end_time - start_time))/3600) as total_hours,
+			SUM(working_days(username, start_time, end_time, leave_type)) as working_days,` + absenceSplit + `
 		FROM leaves 
-		WHERE username = $1
+		WHERE username = $1 AND leave_type <> 'IN_OFFICE' AND deleted_at IS NULL AND status <> 'REJECTED'
 		GROUP BY username
 	`
 
@@ -126,7 +898,7 @@ func (r *LeaveRepository) GetEmployeeStats(username string) ([]LeaveStats, error
 	var stats []LeaveStats
 	for rows.Next() {
 		var stat LeaveStats
-		err := rows.Scan(&stat.Username, &stat.LeaveCount, &stat.LeaveTypes, &stat.TotalHours)
+		err := rows.Scan(&stat.Username, &stat.LeaveCount, &stat.LeaveTypes, &stat.TotalHours, &stat.WorkingDays, &stat.Absences, &stat.RemoteDays)
 		if err != nil {
 			return nil, err
 		}
@@ -140,21 +912,25 @@ func (r *LeaveRepository) GetEmployeeStats(username string) ([]LeaveStats, error
 	return stats, nil
 }
 
-func (r *LeaveRepository) GetMostLeavesThisMonth() ([]models.EmployeeLeaveStats, error) {
+// GetMostLeavesThisMonth finds who has the most records starting in today's
+// month. today is the date in the workspace's timezone, not the database's.
+func (r *LeaveRepository) GetMostLeavesThisMonth(today time.Time) ([]models.EmployeeLeaveStats, error) {
 	query := `
 		SELECT 
 			username,
 			COUNT(*) as leave_count,
 			STRING_AGG(leave_type, ', ') as leave_types,
-			SUM(EXTRACT(EPOCH FROM (end_time - start_time))/3600) as total_hours
+			SUM(EXTRACT(EPOCH FROM (end_time - start_time))/3600) as total_hours,
+			SUM(working_days(username, start_time, end_time, leave_type)) as working_days
 		FROM leaves 
-		WHERE start_time >= date_trunc('month', CURRENT_DATE)
+		WHERE start_time >= date_trunc('month', $1::date) AND leave_type <> 'IN_OFFICE' AND deleted_at IS NULL AND status <> 'REJECTED'
+			AND username NOT IN (` + departedUsers + `)
 		GROUP BY username
 		ORDER BY leave_count DESC
 		LIMIT 1
 	`
 
-	rows, err := r.db.Query(query)
+	rows, err := r.db.Query(query, today)
 	if err != nil {
 		return nil, err
 	}
@@ -163,7 +939,7 @@ func (r *LeaveRepository) GetMostLeavesThisMonth() ([]models.EmployeeLeaveStats,
 	var stats []models.EmployeeLeaveStats
 	for rows.Next() {
 		var stat models.EmployeeLeaveStats
-		err := rows.Scan(&stat.Username, &stat.LeaveCount, &stat.LeaveTypes, &stat.TotalHours)
+		err := rows.Scan(&stat.Username, &stat.LeaveCount, &stat.LeaveTypes, &stat.TotalHours, &stat.WorkingDays)
 		if err != nil {
 			return nil, err
 		}
@@ -177,20 +953,188 @@ func (r *LeaveRepository) GetMostLeavesThisMonth() ([]models.EmployeeLeaveStats,
 	return stats, nil
 }
 
-func (r *LeaveRepository) GetTopEmployeesWithMostLeaves(year int, limit int) ([]models.EmployeeLeaveStats, error) {
-	// Implement logic to query the database for the top N employees with the most leaves in a given year
-	return []models.EmployeeLeaveStats{}, nil
+// GetTopEmployeesWithMostLeaves ranks the employees with the most records
+// starting in [start, end), leaving out office days and departed users. Pass
+// the bounds of the fiscal year for an annual ranking.
+func (r *LeaveRepository) GetTopEmployeesWithMostLeaves(start, end time.Time, limit int) ([]models.EmployeeLeaveStats, error) {
+	query := `
+		SELECT
+			username,
+			COUNT(*) as leave_count,
+			STRING_AGG(leave_type, ', ') as leave_types,
+			SUM(EXTRACT(EPOCH FROM (end_time - start_time))/3600) as total_hours,
+			SUM(working_days(username, start_time, end_time, leave_type)) as working_days
+		FROM leaves
+		WHERE start_time >= $1 AND start_time < $2 AND leave_type <> 'IN_OFFICE' AND deleted_at IS NULL AND status <> 'REJECTED'
+			AND username NOT IN (` + departedUsers + `)
+		GROUP BY username
+		ORDER BY leave_count DESC, username
+		LIMIT $3
+	`
+
+	rows, err := r.db.Query(query, start, end, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []models.EmployeeLeaveStats{}
+	for rows.Next() {
+		var stat models.EmployeeLeaveStats
+		err := rows.Scan(&stat.Username, &stat.LeaveCount, &stat.LeaveTypes, &stat.TotalHours, &stat.WorkingDays)
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, stat)
+	}
+	return stats, rows.Err()
+}
+
+// GetLeaveTypeRanking ranks users by how many records of the given types
+// they started in [startDate, endDate), most first, leaving out departed
+// users. Ties go to whoever spent longer away.
+func (r *LeaveRepository) GetLeaveTypeRanking(startDate, endDate time.Time, leaveTypes []string, limit int) ([]LeaveStats, error) {
+	query := `
+		SELECT
+			username,
+			COUNT(*) as leave_count,
+			STRING_AGG(DISTINCT leave_type, ', ') as leave_types,
+			SUM(EXTRACT(EPOCH FROM (end_time - start_time))/3600) as total_hours,
+			SUM(working_days(username, start_time, end_time, leave_type)) as working_days
+		FROM leaves
+		WHERE start_time >= $1 AND start_time < $2
+			AND leave_type = ANY($3) AND deleted_at IS NULL AND status <> 'REJECTED'
+			AND username NOT IN (` + departedUsers + `)
+		GROUP BY username
+		ORDER BY leave_count DESC, total_hours DESC, username
+		LIMIT $4
+	`
+
+	rows, err := r.db.Query(query, startDate, endDate, pq.Array(leaveTypes), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []LeaveStats
+	for rows.Next() {
+		var stat LeaveStats
+		if err := rows.Scan(&stat.Username, &stat.LeaveCount, &stat.LeaveTypes, &stat.TotalHours, &stat.WorkingDays); err != nil {
+			return nil, err
+		}
+		stats = append(stats, stat)
+	}
+
+	return stats, rows.Err()
+}
+
+// GetLeaveTrend buckets the records starting in [startDate, endDate) by
+// day, week (starting Monday) or month, returning every bucket in the
+// period in order, empty ones included. Without leaveTypes office days are
+// left out, as in the other reports; an empty employmentType includes
+// everyone.
+func (r *LeaveRepository) GetLeaveTrend(startDate, endDate time.Time, bucket string, leaveTypes []string, employmentType string) ([]TrendPoint, error) {
+	switch bucket {
+	case "day", "week", "month":
+	default:
+		return nil, fmt.Errorf("can't group by %q, use day, week or month", bucket)
+	}
+
+	query := `
+		SELECT
+			b.bucket,
+			COUNT(l.id) as leave_count,
+			COUNT(DISTINCT l.username) as user_count,
+			COALESCE(SUM(EXTRACT(EPOCH FROM (l.end_time - l.start_time))/3600), 0) as total_hours,
+			COALESCE(SUM(working_days(l.username, l.start_time, l.end_time, l.leave_type)), 0) as working_days
+		FROM generate_series(
+			date_trunc($3, $1::timestamp),
+			$2::timestamp - interval '1 microsecond',
+			('1 ' || $3)::interval
+		) as b(bucket)
+		LEFT JOIN leaves l ON date_trunc($3, l.start_time) = b.bucket
+			AND l.start_time >= $1::timestamp AND l.start_time < $2::timestamp
+			AND (l.leave_type = ANY($4) OR (cardinality($4::text[]) = 0 AND l.leave_type <> 'IN_OFFICE'))
+			AND l.deleted_at IS NULL AND l.status <> 'REJECTED'
+			AND l.username NOT IN (` + departedUsers + `)
+			AND ($5 = '' OR COALESCE((SELECT e.employment_type FROM employees e WHERE e.username = l.username), 'EMPLOYEE') = $5)
+		GROUP BY b.bucket
+		ORDER BY b.bucket
+	`
+
+	rows, err := r.db.Query(query, startDate, endDate, bucket, pq.Array(leaveTypes), employmentType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var points []TrendPoint
+	for rows.Next() {
+		var point TrendPoint
+		if err := rows.Scan(&point.Bucket, &point.LeaveCount, &point.UserCount, &point.TotalHours, &point.WorkingDays); err != nil {
+			return nil, err
+		}
+		points = append(points, point)
+	}
+
+	return points, rows.Err()
+}
+
+type TrendPoint struct {
+	Bucket      time.Time `json:"bucket"` // start of the day, week or month
+	LeaveCount  int       `json:"leave_count"`
+	UserCount   int       `json:"user_count"`
+	TotalHours  float64   `json:"total_hours"`
+	WorkingDays float64   `json:"working_days"`
+}
+
+// GetLeaveCountsByUser counts, for everyone still here, the records other
+// than office days they started in [startDate, endDate). Active employees
+// without records count as zero.
+func (r *LeaveRepository) GetLeaveCountsByUser(startDate, endDate time.Time) (map[string]int, error) {
+	query := `
+		SELECT u.username, COUNT(l.id)
+		FROM (
+			SELECT username FROM employees WHERE active
+			UNION
+			SELECT DISTINCT username FROM leaves
+			WHERE start_time >= $1 AND start_time < $2 AND deleted_at IS NULL AND status <> 'REJECTED' AND username NOT IN (` + departedUsers + `)
+		) u
+		LEFT JOIN leaves l ON l.username = u.username
+			AND l.start_time >= $1 AND l.start_time < $2 AND l.leave_type <> 'IN_OFFICE' AND l.deleted_at IS NULL AND l.status <> 'REJECTED'
+		GROUP BY u.username
+	`
+
+	rows, err := r.db.Query(query, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var username string
+		var count int
+		if err := rows.Scan(&username, &count); err != nil {
+			return nil, err
+		}
+		counts[username] = count
+	}
+
+	return counts, rows.Err()
 }
 
-func (r *LeaveRepository) GetLeaveCountToday() (int, error) {
+// GetLeaveCountToday counts the records other than office days covering
+// today, the date in the workspace's timezone.
+func (r *LeaveRepository) GetLeaveCountToday(today time.Time) (int, error) {
 	query := `
 		SELECT COUNT(*)
 		FROM leaves
-		WHERE start_time <= CURRENT_DATE AND end_time >= CURRENT_DATE
+		WHERE start_time <= $1::date AND end_time >= $1::date AND leave_type <> 'IN_OFFICE' AND deleted_at IS NULL AND status <> 'REJECTED'
 	`
 
 	var count int
-	err := r.db.QueryRow(query).Scan(&count)
+	err := r.db.QueryRow(query, today).Scan(&count)
 	if err != nil {
 		return 0, err
 	}
@@ -198,18 +1142,21 @@ func (r *LeaveRepository) GetLeaveCountToday() (int, error) {
 	return count, nil
 }
 
-func (r *LeaveRepository) GetEmployeesNeverTakenLeaveThisYear() ([]models.Employee, error) {
+// GetEmployeesWithoutLeaveSince lists the active employees with no records
+// other than office days starting on or after since, usually the start of
+// the fiscal year.
+func (r *LeaveRepository) GetEmployeesWithoutLeaveSince(since time.Time) ([]models.Employee, error) {
 	query := `
 		SELECT username
 		FROM employees
-		WHERE username NOT IN (
+		WHERE active AND username NOT IN (
 			SELECT username
 			FROM leaves
-			WHERE EXTRACT(YEAR FROM start_time) = EXTRACT(YEAR FROM CURRENT_DATE)
+			WHERE start_time >= $1 AND leave_type <> 'IN_OFFICE' AND deleted_at IS NULL AND status <> 'REJECTED'
 		)
 	`
 
-	rows, err := r.db.Query(query)
+	rows, err := r.db.Query(query, since)
 	if err != nil {
 		return nil, err
 	}
@@ -227,14 +1174,16 @@ func (r *LeaveRepository) GetEmployeesNeverTakenLeaveThisYear() ([]models.Employ
 	return employees, nil
 }
 
-func (r *LeaveRepository) GetAllEmployeesCurrentlyOnLeave() ([]models.Employee, error) {
+// GetAllEmployeesCurrentlyOnLeave lists who has a record other than an
+// office day covering today, the date in the workspace's timezone.
+func (r *LeaveRepository) GetAllEmployeesCurrentlyOnLeave(today time.Time) ([]models.Employee, error) {
 	query := `
 		SELECT DISTINCT username
 		FROM leaves
-		WHERE start_time <= CURRENT_DATE AND end_time >= CURRENT_DATE
+		WHERE start_time <= $1::date AND end_time >= $1::date AND leave_type <> 'IN_OFFICE' AND deleted_at IS NULL AND status <> 'REJECTED'
 	`
 
-	rows, err := r.db.Query(query)
+	rows, err := r.db.Query(query, today)
 	if err != nil {
 		return nil, err
 	}
@@ -252,9 +1201,280 @@ func (r *LeaveRepository) GetAllEmployeesCurrentlyOnLeave() ([]models.Employee,
 	return employees, nil
 }
 
+// GetLeaveDaysUsed returns, per user, how many days of leave quota were used
+// by records starting in [startDate, endDate). Full days count each working
+// day they span at the user's office, half days count as 0.5; WFH, late
+// arrivals, early departures, appointments and parental leave don't use
+// quota.
+func (r *LeaveRepository) GetLeaveDaysUsed(startDate, endDate time.Time) ([]LeaveDaysUsed, error) {
+	query := `
+		SELECT
+			username,
+			COALESCE(SUM(CASE leave_type
+				WHEN 'FULL_DAY' THEN working_days(username, start_time, end_time, leave_type)
+				WHEN 'HALF_DAY' THEN 0.5
+				ELSE 0
+			END), 0) as days_used
+		FROM leaves
+		WHERE start_time >= $1 AND start_time < $2 AND deleted_at IS NULL AND status <> 'REJECTED'
+			AND username NOT IN (` + departedUsers + `)
+		GROUP BY username
+		ORDER BY username
+	`
+
+	rows, err := r.db.Query(query, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var used []LeaveDaysUsed
+	for rows.Next() {
+		var u LeaveDaysUsed
+		if err := rows.Scan(&u.Username, &u.DaysUsed); err != nil {
+			return nil, err
+		}
+		used = append(used, u)
+	}
+
+	return used, nil
+}
+
+// GetLossOfPayDays returns, per user, the working days of approved
+// loss-of-pay leave falling within [startDate, endDate), the deductions
+// payroll applies for the period, for usernames or everyone if it's empty.
+// Records are cut to the period, and people who have since left are kept for
+// their final settlement.
+func (r *LeaveRepository) GetLossOfPayDays(startDate, endDate time.Time, usernames []string) ([]LossOfPayDays, error) {
+	query := `
+		SELECT
+			username,
+			COUNT(*) as records,
+			COALESCE(SUM(working_days(username, GREATEST(start_time, $1), LEAST(end_time, $2 - INTERVAL '1 second'), leave_type)), 0) as working_days
+		FROM leaves
+		WHERE leave_type = 'LOSS_OF_PAY' AND start_time < $2 AND end_time > $1 AND deleted_at IS NULL AND status = 'APPROVED'
+			AND (cardinality($3::text[]) = 0 OR username = ANY($3))
+		GROUP BY username
+		ORDER BY username
+	`
+
+	rows, err := r.db.Query(query, startDate, endDate, pq.Array(usernames))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var days []LossOfPayDays
+	for rows.Next() {
+		var d LossOfPayDays
+		if err := rows.Scan(&d.Username, &d.Records, &d.WorkingDays); err != nil {
+			return nil, err
+		}
+		days = append(days, d)
+	}
+
+	return days, nil
+}
+
+// GetDailyStatuses expands every record overlapping [startDate, endDate) into
+// one row per calendar day it covers, so callers can lay records out on a
+// calendar without doing date arithmetic themselves.
+func (r *LeaveRepository) GetDailyStatuses(startDate, endDate time.Time) ([]DayStatus, error) {
+	query := `
+		SELECT l.username, d::date as day, l.leave_type
+		FROM leaves l
+		CROSS JOIN LATERAL generate_series(l.start_time::date, l.end_time::date, interval '1 day') as d
+		WHERE l.start_time < $2 AND l.end_time >= $1
+			AND d >= $1::date AND d < $2::date AND l.deleted_at IS NULL AND l.status <> 'REJECTED'
+		ORDER BY l.username, day
+	`
+
+	rows, err := r.db.Query(query, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var statuses []DayStatus
+	for rows.Next() {
+		var status DayStatus
+		if err := rows.Scan(&status.Username, &status.Day, &status.LeaveType); err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+type DayStatus struct {
+	Username  string    `json:"username"`
+	Day       time.Time `json:"day"`
+	LeaveType string    `json:"leave_type"`
+}
+
+// TeamDay is one calendar day of a teammate's record.
+type TeamDay struct {
+	Username  string
+	Day       time.Time
+	LeaveType string
+	Pending   bool
+}
+
+// GetTeamOverlap expands the records of managerID's active reports, other
+// than except, that overlap [startDate, endDate) into one row per calendar
+// day, like GetDailyStatuses. Records still waiting for approval are
+// included and marked pending.
+func (r *LeaveRepository) GetTeamOverlap(managerID, except string, startDate, endDate time.Time) ([]TeamDay, error) {
+	query := `
+		SELECT l.username, d::date as day, l.leave_type, l.status = 'PENDING'
+		FROM leaves l
+		JOIN employees e ON e.username = l.username
+		CROSS JOIN LATERAL generate_series(l.start_time::date, l.end_time::date, interval '1 day') as d
+		WHERE e.manager_slack_id = $3 AND e.active AND l.username <> $4
+			AND l.start_time < $2 AND l.end_time >= $1
+			AND d >= $1::date AND d < $2::date AND l.deleted_at IS NULL AND l.status <> 'REJECTED'
+		ORDER BY day, l.username
+	`
+
+	rows, err := r.db.Query(query, startDate, endDate, managerID, except)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var days []TeamDay
+	for rows.Next() {
+		var day TeamDay
+		if err := rows.Scan(&day.Username, &day.Day, &day.LeaveType, &day.Pending); err != nil {
+			return nil, err
+		}
+		days = append(days, day)
+	}
+	return days, rows.Err()
+}
+
+// GetUsageByType totals one user's records starting in [startDate, endDate)
+// per leave type. Days counts each working day of whole-day records (full
+// days, parental leave, loss of pay, WFH and office days) at the user's
+// office, as GetLeaveDaysUsed does, and 0.5 per half day; the partial-day
+// types only have a record count.
+func (r *LeaveRepository) GetUsageByType(username string, startDate, endDate time.Time) ([]TypeUsage, error) {
+	return r.GetGroupUsageByType([]string{username}, startDate, endDate)
+}
+
+// GetGroupUsageByType is GetUsageByType summed over several users, e.g. a
+// team.
+func (r *LeaveRepository) GetGroupUsageByType(usernames []string, startDate, endDate time.Time) ([]TypeUsage, error) {
+	query := `
+		SELECT
+			leave_type,
+			COUNT(*) as records,
+			COALESCE(SUM(CASE
+				WHEN leave_type IN ('FULL_DAY', 'PARENTAL', 'LOSS_OF_PAY', 'WFH', 'IN_OFFICE') THEN working_days(username, start_time, end_time, leave_type)
+				WHEN leave_type = 'HALF_DAY' THEN 0.5
+				ELSE 0
+			END), 0) as days
+		FROM leaves
+		WHERE username = ANY($1) AND start_time >= $2 AND start_time < $3 AND deleted_at IS NULL AND status <> 'REJECTED'
+		GROUP BY leave_type
+		ORDER BY leave_type
+	`
+
+	rows, err := r.db.Query(query, pq.Array(usernames), startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var usage []TypeUsage
+	for rows.Next() {
+		var u TypeUsage
+		if err := rows.Scan(&u.LeaveType, &u.Records, &u.Days); err != nil {
+			return nil, err
+		}
+		usage = append(usage, u)
+	}
+
+	return usage, nil
+}
+
+type TypeUsage struct {
+	LeaveType string  `json:"leave_type"`
+	Records   int     `json:"records"`
+	Days      float64 `json:"days"`
+}
+
+type LeaveDaysUsed struct {
+	Username string  `json:"username"`
+	DaysUsed float64 `json:"days_used"`
+}
+
+type LossOfPayDays struct {
+	Username    string  `json:"username"`
+	Records     int     `json:"records"`
+	WorkingDays float64 `json:"working_days"`
+}
+
+// GetOfficeAttendanceByWeekday counts, per day of the week, how many
+// person-days in [startDate, endDate) were logged as IN_OFFICE and as WFH.
+// A record covering several days counts once for each of them.
+func (r *LeaveRepository) GetOfficeAttendanceByWeekday(startDate, endDate time.Time) ([]WeekdayAttendance, error) {
+	query := `
+		SELECT
+			EXTRACT(DOW FROM d.day)::int as weekday,
+			COUNT(DISTINCT (l.username, d.day)) FILTER (WHERE l.leave_type = 'IN_OFFICE') as in_office,
+			COUNT(DISTINCT (l.username, d.day)) FILTER (WHERE l.leave_type = 'WFH') as wfh,
+			COUNT(DISTINCT l.username) FILTER (WHERE l.leave_type = 'IN_OFFICE') as office_users
+		FROM leaves l
+		CROSS JOIN LATERAL generate_series(l.start_time::date, l.end_time::date, INTERVAL '1 day') AS d(day)
+		WHERE l.start_time < $2 AND l.end_time >= $1 AND d.day >= $1 AND d.day < $2
+			AND l.leave_type IN ('IN_OFFICE', 'WFH') AND l.deleted_at IS NULL AND l.status <> 'REJECTED'
+			AND l.username NOT IN (` + departedUsers + `)
+		GROUP BY 1
+		ORDER BY 1
+	`
+
+	rows, err := r.db.Query(query, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attendance []WeekdayAttendance
+	for rows.Next() {
+		var a WeekdayAttendance
+		var weekday int
+		if err := rows.Scan(&weekday, &a.InOffice, &a.WFH, &a.OfficeUsers); err != nil {
+			return nil, err
+		}
+		a.Weekday = time.Weekday(weekday)
+		attendance = append(attendance, a)
+	}
+
+	return attendance, nil
+}
+
+type WeekdayAttendance struct {
+	Weekday     time.Weekday `json:"weekday"`
+	InOffice    int          `json:"in_office"`    // person-days in the office
+	WFH         int          `json:"wfh"`          // person-days working from home
+	OfficeUsers int          `json:"office_users"` // distinct people who came in on this weekday
+}
+
 type LeaveStats struct {
 	Username   string  `json:"username"`
 	LeaveCount int     `json:"leave_count"`
 	LeaveTypes string  `json:"leave_types"`
 	TotalHours float64 `json:"total_hours"`
+
+	// WorkingDays is TotalHours as working days: half days count 0.5,
+	// shorter absences their share of a 9-hour day, and longer ones the
+	// weekdays they span that aren't office holidays.
+	WorkingDays float64 `json:"working_days"`
+
+	// Absences and RemoteDays split LeaveCount into time off and WFH. Only
+	// filled by the per-user totals, not the rankings.
+	Absences   int `json:"absences"`
+	RemoteDays int `json:"remote_days"`
 }
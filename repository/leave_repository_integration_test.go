@@ -0,0 +1,635 @@
+//go:build integration
+
+package repository
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+)
+
+func TestLeaveRepositoryCRUD(t *testing.T) {
+	resetDB(t)
+	repo := NewLeaveRepository(testDB)
+
+	monday := day(2024, time.March, 4)
+	first := createLeave(t, repo, "alice", "FULL_DAY", monday.Add(9*time.Hour), monday.Add(18*time.Hour))
+	second := createLeave(t, repo, "alice", "WFH", monday.AddDate(0, 0, 2).Add(9*time.Hour), monday.AddDate(0, 0, 2).Add(18*time.Hour))
+	other := createLeave(t, repo, "bob", "HALF_DAY", monday.Add(9*time.Hour), monday.Add(13*time.Hour))
+
+	got, err := repo.GetByID(first.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if got.Username != "alice" || got.LeaveType != "FULL_DAY" || !got.StartTime.Equal(first.StartTime) ||
+		!got.EndTime.Equal(first.EndTime) || got.RecurrenceID != 0 {
+		t.Errorf("GetByID = %+v, want %+v", got, first)
+	}
+	if _, err := repo.GetByID(first.ID + 100); err == nil {
+		t.Error("GetByID of a missing record succeeded")
+	}
+
+	got.LeaveType = "HALF_DAY"
+	got.Reason = "dentist"
+	got.EndTime = monday.Add(13 * time.Hour)
+	if err := repo.Update(got); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	updated, err := repo.GetByID(first.ID)
+	if err != nil {
+		t.Fatalf("GetByID after Update: %v", err)
+	}
+	if updated.LeaveType != "HALF_DAY" || updated.Reason != "dentist" || !updated.EndTime.Equal(got.EndTime) {
+		t.Errorf("after Update got %+v", updated)
+	}
+	if err := repo.Update(&models.Leave{ID: first.ID + 100}); err == nil {
+		t.Error("Update of a missing record succeeded")
+	}
+
+	if err := repo.SetPrivateReason(second.ID, "looking after my dad"); err != nil {
+		t.Fatalf("SetPrivateReason: %v", err)
+	}
+	private, err := repo.GetByID(second.ID)
+	if err != nil {
+		t.Fatalf("GetByID after SetPrivateReason: %v", err)
+	}
+	if private.PrivateReason != "looking after my dad" || private.Reason != "" {
+		t.Errorf("after SetPrivateReason got reason %q, private reason %q", private.Reason, private.PrivateReason)
+	}
+	if err := repo.SetPrivateReason(second.ID, ""); err != nil {
+		t.Fatalf("SetPrivateReason to clear: %v", err)
+	}
+	if cleared, _ := repo.GetByID(second.ID); cleared == nil || cleared.PrivateReason != "" {
+		t.Errorf("after clearing got %+v", cleared)
+	}
+
+	leaves, err := repo.ListByUsername("alice", 10)
+	if err != nil {
+		t.Fatalf("ListByUsername: %v", err)
+	}
+	if ids := leaveIDs(leaves); !reflect.DeepEqual(ids, []int64{second.ID, first.ID}) {
+		t.Errorf("ListByUsername = %v, want newest first", ids)
+	}
+	if leaves, _ := repo.ListByUsername("alice", 1); len(leaves) != 1 {
+		t.Errorf("ListByUsername with limit 1 returned %d records", len(leaves))
+	}
+
+	leaves, err = repo.ListStartingFrom("alice", monday.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("ListStartingFrom: %v", err)
+	}
+	if ids := leaveIDs(leaves); !reflect.DeepEqual(ids, []int64{second.ID}) {
+		t.Errorf("ListStartingFrom = %v, want [%d]", ids, second.ID)
+	}
+
+	leaves, err = repo.FindByUserAndDate("alice", monday)
+	if err != nil {
+		t.Fatalf("FindByUserAndDate: %v", err)
+	}
+	if ids := leaveIDs(leaves); !reflect.DeepEqual(ids, []int64{first.ID}) {
+		t.Errorf("FindByUserAndDate = %v, want [%d]", ids, first.ID)
+	}
+
+	leaves, err = repo.ListBetween(monday, monday.AddDate(0, 0, 7))
+	if err != nil {
+		t.Fatalf("ListBetween: %v", err)
+	}
+	if ids := leaveIDs(leaves); !reflect.DeepEqual(ids, []int64{first.ID, second.ID, other.ID}) {
+		t.Errorf("ListBetween = %v, want by user then start", ids)
+	}
+
+	if err := repo.Delete(other.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := repo.GetByID(other.ID); err == nil {
+		t.Error("GetByID after Delete succeeded")
+	}
+	if err := repo.Delete(other.ID); err == nil {
+		t.Error("Delete of a missing record succeeded")
+	}
+}
+
+func TestLeaveRepositoryMerge(t *testing.T) {
+	resetDB(t)
+	repo := NewLeaveRepository(testDB)
+
+	monday := day(2024, time.March, 4)
+	keep := createLeave(t, repo, "alice", "FULL_DAY", monday.Add(9*time.Hour), monday.Add(18*time.Hour))
+	merge := createLeave(t, repo, "alice", "FULL_DAY", monday.AddDate(0, 0, 1).Add(9*time.Hour), monday.AddDate(0, 0, 1).Add(18*time.Hour))
+	other := createLeave(t, repo, "bob", "FULL_DAY", monday.Add(9*time.Hour), monday.Add(18*time.Hour))
+
+	if _, err := repo.Merge(keep.ID, other.ID); err == nil {
+		t.Error("Merge of records of different users succeeded")
+	}
+
+	merged, err := repo.Merge(keep.ID, merge.ID)
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	if !merged.StartTime.Equal(keep.StartTime) || !merged.EndTime.Equal(merge.EndTime) {
+		t.Errorf("Merge spans %s to %s", merged.StartTime, merged.EndTime)
+	}
+	if want := keep.OriginalText + "\n" + merge.OriginalText; merged.OriginalText != want {
+		t.Errorf("Merge original text = %q, want %q", merged.OriginalText, want)
+	}
+
+	stored, err := repo.GetByID(keep.ID)
+	if err != nil {
+		t.Fatalf("GetByID after Merge: %v", err)
+	}
+	if !stored.EndTime.Equal(merge.EndTime) || stored.Duration != merged.Duration {
+		t.Errorf("stored merge = %+v, want %+v", stored, merged)
+	}
+	if _, err := repo.GetByID(merge.ID); err == nil {
+		t.Error("merged record still exists")
+	}
+}
+
+func TestLeaveRepositoryAudited(t *testing.T) {
+	resetDB(t)
+	repo := NewLeaveRepository(testDB)
+	audit := NewAuditRepository(testDB)
+	monday := day(2024, time.March, 4)
+	newLeave := func(username string) *models.Leave {
+		return &models.Leave{Username: username, OriginalText: "off", LeaveType: "FULL_DAY",
+			StartTime: monday.Add(9 * time.Hour), EndTime: monday.Add(18 * time.Hour), Duration: "9h"}
+	}
+
+	saved := newLeave("alice")
+	err := repo.Audited(func(leaves *LeaveRepository) ([]*models.AuditEntry, error) {
+		if err := leaves.Create(saved); err != nil {
+			return nil, err
+		}
+		return []*models.AuditEntry{{Actor: "admin:test", Action: "admin_create", LeaveID: saved.ID}}, nil
+	})
+	if err != nil {
+		t.Fatalf("Audited: %v", err)
+	}
+	if _, err := repo.GetByID(saved.ID); err != nil {
+		t.Errorf("audited record wasn't saved: %v", err)
+	}
+	if entries, _ := audit.ListByLeave(saved.ID); len(entries) != 1 || entries[0].Hash == "" {
+		t.Errorf("audit entries = %+v, want one chained entry", entries)
+	}
+
+	tests := []struct {
+		name   string
+		action string
+		fail   error
+	}{
+		{"change fails", "admin_create", fmt.Errorf("refused")},
+		{"audit write fails", strings.Repeat("x", 51), nil}, // longer than the action column
+	}
+	for _, tt := range tests {
+		leave := newLeave("bob")
+		err := repo.Audited(func(leaves *LeaveRepository) ([]*models.AuditEntry, error) {
+			if err := leaves.Create(leave); err != nil {
+				return nil, err
+			}
+			return []*models.AuditEntry{{Actor: "admin:test", Action: tt.action, LeaveID: leave.ID}}, tt.fail
+		})
+		if err == nil {
+			t.Errorf("%s: no error", tt.name)
+		}
+		if _, err := repo.GetByID(leave.ID); err == nil {
+			t.Errorf("%s: record was saved without its audit entry", tt.name)
+		}
+		if entries, _ := audit.ListByLeave(leave.ID); len(entries) != 0 {
+			t.Errorf("%s: audit entries = %+v, want none", tt.name, entries)
+		}
+	}
+
+	keep := createLeave(t, repo, "carol", "FULL_DAY", monday.Add(9*time.Hour), monday.Add(18*time.Hour))
+	merge := createLeave(t, repo, "carol", "FULL_DAY", monday.AddDate(0, 0, 1).Add(9*time.Hour), monday.AddDate(0, 0, 1).Add(18*time.Hour))
+	err = repo.Audited(func(leaves *LeaveRepository) ([]*models.AuditEntry, error) {
+		if _, err := leaves.Merge(keep.ID, merge.ID); err != nil {
+			return nil, err
+		}
+		return []*models.AuditEntry{{Actor: "admin:test", Action: strings.Repeat("x", 51), LeaveID: keep.ID}}, nil
+	})
+	if err == nil {
+		t.Error("merge with a failing audit entry: no error")
+	}
+	if _, err := repo.GetByID(merge.ID); err != nil {
+		t.Errorf("merge was applied without its audit entry: %v", err)
+	}
+}
+
+// A leave over a weekend and a public holiday uses only the working days.
+func TestLeaveDaysUsedCountsWorkingDays(t *testing.T) {
+	resetDB(t)
+	repo := NewLeaveRepository(testDB)
+	locations := NewLocationRepository(testDB)
+	if err := locations.Upsert(&models.Location{Code: "BLR", Name: "Bengaluru", Timezone: "Asia/Kolkata"}); err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+	if err := locations.AssignUser("alice", "U1", "BLR"); err != nil {
+		t.Fatalf("AssignUser: %v", err)
+	}
+	if err := locations.AddHoliday(&models.Holiday{LocationCode: "BLR", Date: day(2024, time.March, 8), Name: "Maha Shivaratri"}); err != nil {
+		t.Fatalf("AddHoliday: %v", err)
+	}
+
+	// Thursday to Tuesday: Friday is a holiday, then the weekend
+	createLeave(t, repo, "alice", "FULL_DAY", day(2024, time.March, 7).Add(9*time.Hour), day(2024, time.March, 12).Add(18*time.Hour))
+	createLeave(t, repo, "bob", "FULL_DAY", day(2024, time.March, 8).Add(9*time.Hour), day(2024, time.March, 11).Add(18*time.Hour))
+
+	used, err := repo.GetLeaveDaysUsed(day(2024, time.March, 1), day(2024, time.April, 1))
+	if err != nil {
+		t.Fatalf("GetLeaveDaysUsed: %v", err)
+	}
+	want := []LeaveDaysUsed{{Username: "alice", DaysUsed: 3}, {Username: "bob", DaysUsed: 2}}
+	if !reflect.DeepEqual(used, want) {
+		t.Errorf("GetLeaveDaysUsed = %+v, want %+v", used, want)
+	}
+
+	usage, err := repo.GetUsageByType("alice", day(2024, time.March, 1), day(2024, time.April, 1))
+	if err != nil {
+		t.Fatalf("GetUsageByType: %v", err)
+	}
+	if wantUsage := []TypeUsage{{LeaveType: "FULL_DAY", Records: 1, Days: 3}}; !reflect.DeepEqual(usage, wantUsage) {
+		t.Errorf("GetUsageByType = %+v, want %+v", usage, wantUsage)
+	}
+}
+
+// A record covering several days counts for each weekday it covers, within
+// the requested range only.
+func TestOfficeAttendanceByWeekdayExpandsRecords(t *testing.T) {
+	resetDB(t)
+	repo := NewLeaveRepository(testDB)
+
+	// Monday to Wednesday at home; Wednesday to Friday in the office, which
+	// runs past the end of the range
+	createLeave(t, repo, "alice", "WFH", day(2024, time.March, 25).Add(9*time.Hour), day(2024, time.March, 27).Add(18*time.Hour))
+	createLeave(t, repo, "bob", "IN_OFFICE", day(2024, time.March, 27).Add(9*time.Hour), day(2024, time.March, 29).Add(18*time.Hour))
+
+	attendance, err := repo.GetOfficeAttendanceByWeekday(day(2024, time.March, 25), day(2024, time.March, 28))
+	if err != nil {
+		t.Fatalf("GetOfficeAttendanceByWeekday: %v", err)
+	}
+	want := []WeekdayAttendance{
+		{Weekday: time.Monday, WFH: 1},
+		{Weekday: time.Tuesday, WFH: 1},
+		{Weekday: time.Wednesday, InOffice: 1, WFH: 1, OfficeUsers: 1},
+	}
+	if !reflect.DeepEqual(attendance, want) {
+		t.Errorf("GetOfficeAttendanceByWeekday = %+v, want %+v", attendance, want)
+	}
+}
+
+// seedLeaveStats stores March 2024 records for alice (employee), carol
+// (contractor) and dave (departed), whose records reports must leave out.
+func seedLeaveStats(t *testing.T, repo *LeaveRepository) {
+	t.Helper()
+	employees := NewEmployeeRepository(testDB)
+	if err := employees.SetEmploymentType("carol", "", models.EmploymentContractor); err != nil {
+		t.Fatalf("SetEmploymentType: %v", err)
+	}
+	if _, err := employees.SetActive("dave", "", false); err != nil {
+		t.Fatalf("SetActive: %v", err)
+	}
+
+	at := func(d int, hour int) time.Time { return day(2024, time.March, d).Add(time.Duration(hour) * time.Hour) }
+	createLeave(t, repo, "alice", "FULL_DAY", at(4, 9), at(4, 18))   // Monday, 1 day
+	createLeave(t, repo, "alice", "IN_OFFICE", at(6, 9), at(6, 18))  // Wednesday
+	createLeave(t, repo, "alice", "FULL_DAY", at(11, 9), at(12, 18)) // Monday and Tuesday, 2 days
+	createLeave(t, repo, "carol", "WFH", at(7, 9), at(7, 18))        // Thursday
+	for d := 18; d <= 20; d++ {
+		createLeave(t, repo, "dave", "FULL_DAY", at(d, 9), at(d, 18))
+	}
+}
+
+func TestLeaveRepositoryStats(t *testing.T) {
+	resetDB(t)
+	repo := NewLeaveRepository(testDB)
+	seedLeaveStats(t, repo)
+
+	start, end := day(2024, time.March, 1), day(2024, time.March, 31)
+
+	stats, err := repo.GetLeaveStatsByPeriod(start, end, "", true, nil)
+	if err != nil {
+		t.Fatalf("GetLeaveStatsByPeriod: %v", err)
+	}
+	if len(stats) != 2 || stats[0].Username != "alice" || stats[0].LeaveCount != 2 ||
+		stats[1].Username != "carol" || stats[1].LeaveCount != 1 {
+		t.Errorf("GetLeaveStatsByPeriod = %+v", stats)
+	}
+	if len(stats) == 2 && (stats[0].Absences != 2 || stats[0].RemoteDays != 0 || stats[1].Absences != 0 || stats[1].RemoteDays != 1) {
+		t.Errorf("GetLeaveStatsByPeriod split = %+v", stats)
+	}
+	stats, err = repo.GetLeaveStatsByPeriod(start, end, "", false, nil)
+	if err != nil {
+		t.Fatalf("GetLeaveStatsByPeriod without WFH: %v", err)
+	}
+	if len(stats) != 1 || stats[0].Username != "alice" {
+		t.Errorf("GetLeaveStatsByPeriod without WFH = %+v, want alice only", stats)
+	}
+	stats, err = repo.GetLeaveStatsByPeriod(start, end, models.EmploymentContractor, true, nil)
+	if err != nil {
+		t.Fatalf("GetLeaveStatsByPeriod for contractors: %v", err)
+	}
+	if len(stats) != 1 || stats[0].Username != "carol" {
+		t.Errorf("GetLeaveStatsByPeriod for contractors = %+v", stats)
+	}
+
+	stats, err = repo.GetLeaveStatsByPeriod(start, end, "", true, &Comparison{Type: "greater_than", Value: 1})
+	if err != nil {
+		t.Fatalf("GetLeaveStatsByPeriod with a comparison: %v", err)
+	}
+	if len(stats) != 1 || stats[0].Username != "alice" {
+		t.Errorf("GetLeaveStatsByPeriod with more than 1 record = %+v, want alice", stats)
+	}
+	if stats, _ := repo.GetLeaveStatsByPeriod(start, end, "", true, &Comparison{Type: "at_most", Value: 1}); len(stats) != 1 || stats[0].Username != "carol" {
+		t.Errorf("GetLeaveStatsByPeriod with at most 1 record = %+v, want carol", stats)
+	}
+	if _, err := repo.GetLeaveStatsByPeriod(start, end, "", true, &Comparison{Type: "> 0 OR TRUE", Value: 1}); err == nil {
+		t.Error("GetLeaveStatsByPeriod accepted an unknown comparison")
+	}
+
+	byType, err := repo.GetLeaveStatsByEmploymentType(start, end)
+	if err != nil {
+		t.Fatalf("GetLeaveStatsByEmploymentType: %v", err)
+	}
+	wantByType := []EmploymentTypeStats{
+		{EmploymentType: models.EmploymentContractor, UserCount: 1, LeaveCount: 1, TotalHours: 9, WorkingDays: 1},
+		{EmploymentType: models.EmploymentEmployee, UserCount: 1, LeaveCount: 2, TotalHours: 9 + 33, WorkingDays: 1 + 2},
+	}
+	if !reflect.DeepEqual(byType, wantByType) {
+		t.Errorf("GetLeaveStatsByEmploymentType = %+v, want %+v", byType, wantByType)
+	}
+
+	top, err := repo.GetTopLeaveEmployee()
+	if err != nil {
+		t.Fatalf("GetTopLeaveEmployee: %v", err)
+	}
+	if top.Username != "alice" || top.LeaveCount != 2 {
+		t.Errorf("GetTopLeaveEmployee = %+v, want alice with 2", top)
+	}
+
+	stats, err = repo.GetEmployeeStats("alice")
+	if err != nil {
+		t.Fatalf("GetEmployeeStats: %v", err)
+	}
+	if len(stats) != 1 || stats[0].LeaveCount != 2 || stats[0].TotalHours != 42 || stats[0].WorkingDays != 3 {
+		t.Errorf("GetEmployeeStats = %+v", stats)
+	}
+	if _, err := repo.GetEmployeeStats("nobody"); err == nil {
+		t.Error("GetEmployeeStats of a user without records succeeded")
+	}
+
+	ranked, err := repo.GetTopEmployeesWithMostLeaves(day(2024, time.January, 1), day(2025, time.January, 1), 10)
+	if err != nil {
+		t.Fatalf("GetTopEmployeesWithMostLeaves: %v", err)
+	}
+	wantRanked := []models.EmployeeLeaveStats{
+		{Username: "alice", LeaveCount: 2, LeaveTypes: "FULL_DAY, FULL_DAY", TotalHours: 42, WorkingDays: 3},
+		{Username: "carol", LeaveCount: 1, LeaveTypes: "WFH", TotalHours: 9, WorkingDays: 1},
+	}
+	if !reflect.DeepEqual(ranked, wantRanked) {
+		t.Errorf("GetTopEmployeesWithMostLeaves = %+v, want %+v", ranked, wantRanked)
+	}
+	if ranked, _ := repo.GetTopEmployeesWithMostLeaves(day(2024, time.January, 1), day(2025, time.January, 1), 1); len(ranked) != 1 || ranked[0].Username != "alice" {
+		t.Errorf("GetTopEmployeesWithMostLeaves with limit 1 = %+v", ranked)
+	}
+	ranked, err = repo.GetTopEmployeesWithMostLeaves(day(2023, time.January, 1), day(2024, time.January, 1), 10)
+	if err != nil || ranked == nil || len(ranked) != 0 {
+		t.Errorf("GetTopEmployeesWithMostLeaves for a year without records = %+v, %v", ranked, err)
+	}
+
+	byLeaveType, err := repo.GetLeaveTypeRanking(start, day(2024, time.April, 1), []string{"FULL_DAY", "IN_OFFICE"}, 10)
+	if err != nil {
+		t.Fatalf("GetLeaveTypeRanking: %v", err)
+	}
+	wantByLeaveType := []LeaveStats{{Username: "alice", LeaveCount: 3, LeaveTypes: "FULL_DAY, IN_OFFICE", TotalHours: 51, WorkingDays: 4}}
+	if !reflect.DeepEqual(byLeaveType, wantByLeaveType) {
+		t.Errorf("GetLeaveTypeRanking = %+v, want %+v", byLeaveType, wantByLeaveType)
+	}
+	if byLeaveType, _ := repo.GetLeaveTypeRanking(start, day(2024, time.April, 1), []string{"WFH"}, 10); len(byLeaveType) != 1 || byLeaveType[0].Username != "carol" {
+		t.Errorf("GetLeaveTypeRanking of WFH = %+v", byLeaveType)
+	}
+
+	trend, err := repo.GetLeaveTrend(start, day(2024, time.April, 1), "week", nil, "")
+	if err != nil {
+		t.Fatalf("GetLeaveTrend: %v", err)
+	}
+	wantTrend := []TrendPoint{
+		{Bucket: day(2024, time.February, 26)},
+		{Bucket: day(2024, time.March, 4), LeaveCount: 2, UserCount: 2, TotalHours: 18, WorkingDays: 2},
+		{Bucket: day(2024, time.March, 11), LeaveCount: 1, UserCount: 1, TotalHours: 33, WorkingDays: 2},
+		{Bucket: day(2024, time.March, 18)},
+		{Bucket: day(2024, time.March, 25)},
+	}
+	if !reflect.DeepEqual(trend, wantTrend) {
+		t.Errorf("GetLeaveTrend = %+v, want %+v", trend, wantTrend)
+	}
+	trend, err = repo.GetLeaveTrend(start, day(2024, time.April, 1), "month", []string{"WFH"}, models.EmploymentContractor)
+	if err != nil {
+		t.Fatalf("GetLeaveTrend of contractors' WFH: %v", err)
+	}
+	if want := []TrendPoint{{Bucket: start, LeaveCount: 1, UserCount: 1, TotalHours: 9, WorkingDays: 1}}; !reflect.DeepEqual(trend, want) {
+		t.Errorf("GetLeaveTrend of contractors' WFH = %+v, want %+v", trend, want)
+	}
+	if _, err := repo.GetLeaveTrend(start, end, "year; DROP TABLE leaves", nil, ""); err == nil {
+		t.Error("GetLeaveTrend accepted an unknown bucket")
+	}
+
+	used, err := repo.GetLeaveDaysUsed(start, day(2024, time.April, 1))
+	if err != nil {
+		t.Fatalf("GetLeaveDaysUsed: %v", err)
+	}
+	wantUsed := []LeaveDaysUsed{{Username: "alice", DaysUsed: 3}, {Username: "carol", DaysUsed: 0}}
+	if !reflect.DeepEqual(used, wantUsed) {
+		t.Errorf("GetLeaveDaysUsed = %+v, want %+v", used, wantUsed)
+	}
+
+	usage, err := repo.GetUsageByType("alice", start, day(2024, time.April, 1))
+	if err != nil {
+		t.Fatalf("GetUsageByType: %v", err)
+	}
+	wantUsage := []TypeUsage{{LeaveType: "FULL_DAY", Records: 2, Days: 3}, {LeaveType: "IN_OFFICE", Records: 1, Days: 1}}
+	if !reflect.DeepEqual(usage, wantUsage) {
+		t.Errorf("GetUsageByType = %+v, want %+v", usage, wantUsage)
+	}
+
+	groupUsage, err := repo.GetGroupUsageByType([]string{"alice", "carol", "dave"}, start, day(2024, time.April, 1))
+	if err != nil {
+		t.Fatalf("GetGroupUsageByType: %v", err)
+	}
+	wantGroupUsage := []TypeUsage{
+		{LeaveType: "FULL_DAY", Records: 5, Days: 6},
+		{LeaveType: "IN_OFFICE", Records: 1, Days: 1},
+		{LeaveType: "WFH", Records: 1, Days: 1},
+	}
+	if !reflect.DeepEqual(groupUsage, wantGroupUsage) {
+		t.Errorf("GetGroupUsageByType = %+v, want %+v", groupUsage, wantGroupUsage)
+	}
+
+	attendance, err := repo.GetOfficeAttendanceByWeekday(start, day(2024, time.April, 1))
+	if err != nil {
+		t.Fatalf("GetOfficeAttendanceByWeekday: %v", err)
+	}
+	wantAttendance := []WeekdayAttendance{
+		{Weekday: time.Wednesday, InOffice: 1, WFH: 0, OfficeUsers: 1},
+		{Weekday: time.Thursday, InOffice: 0, WFH: 1, OfficeUsers: 0},
+	}
+	if !reflect.DeepEqual(attendance, wantAttendance) {
+		t.Errorf("GetOfficeAttendanceByWeekday = %+v, want %+v", attendance, wantAttendance)
+	}
+
+	statuses, err := repo.GetDailyStatuses(day(2024, time.March, 11), day(2024, time.March, 13))
+	if err != nil {
+		t.Fatalf("GetDailyStatuses: %v", err)
+	}
+	if len(statuses) != 2 || statuses[0].Username != "alice" || !statuses[0].Day.Equal(day(2024, time.March, 11)) ||
+		!statuses[1].Day.Equal(day(2024, time.March, 12)) || statuses[1].LeaveType != "FULL_DAY" {
+		t.Errorf("GetDailyStatuses = %+v", statuses)
+	}
+
+	counts, err := repo.GetLeaveCountsByUser(start, day(2024, time.April, 1))
+	if err != nil {
+		t.Fatalf("GetLeaveCountsByUser: %v", err)
+	}
+	if want := map[string]int{"alice": 2, "carol": 1}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("GetLeaveCountsByUser = %v, want %v", counts, want)
+	}
+	counts, err = repo.GetLeaveCountsByUser(day(2024, time.April, 1), day(2024, time.May, 1))
+	if err != nil {
+		t.Fatalf("GetLeaveCountsByUser for April: %v", err)
+	}
+	if want := map[string]int{"carol": 0}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("GetLeaveCountsByUser for April = %v, want %v", counts, want)
+	}
+}
+
+func TestLeaveRepositoryListMatching(t *testing.T) {
+	resetDB(t)
+	repo := NewLeaveRepository(testDB)
+	seedLeaveStats(t, repo)
+
+	march := LeaveFilter{Start: day(2024, time.March, 1), End: day(2024, time.April, 1)}
+	tests := []struct {
+		name   string
+		filter LeaveFilter
+		limit  int
+		want   []string
+	}{
+		{"period", march, 10, []string{"alice", "carol", "alice"}},
+		{"limit", march, 2, []string{"alice", "carol"}},
+		{"office days", LeaveFilter{Start: march.Start, End: march.End, LeaveTypes: []string{"IN_OFFICE"}}, 10, []string{"alice"}},
+		{"user", LeaveFilter{Usernames: []string{"carol"}}, 10, []string{"carol"}},
+		{"contractors", LeaveFilter{Start: march.Start, End: march.End, EmploymentType: models.EmploymentContractor}, 10, []string{"carol"}},
+		{"departed", LeaveFilter{Usernames: []string{"dave"}}, 10, nil},
+	}
+	for _, tt := range tests {
+		leaves, err := repo.ListMatching(tt.filter, tt.limit)
+		if err != nil {
+			t.Fatalf("ListMatching %s: %v", tt.name, err)
+		}
+		var got []string
+		for _, leave := range leaves {
+			got = append(got, leave.Username)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ListMatching %s = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLeaveRepositoryListUpdatedBetween(t *testing.T) {
+	resetDB(t)
+	repo := NewLeaveRepository(testDB)
+
+	monday := day(2024, time.March, 4)
+	var leaves []*models.Leave
+	for i, username := range []string{"alice", "bob", "carol"} {
+		leave := createLeave(t, repo, username, "FULL_DAY", monday, monday.Add(9*time.Hour))
+		if _, err := testDB.Exec(`UPDATE leaves SET updated_at = $1 WHERE id = $2`, day(2024, time.March, i+1), leave.ID); err != nil {
+			t.Fatalf("Error setting updated_at: %v", err)
+		}
+		leaves = append(leaves, leave)
+	}
+
+	changed, err := repo.ListUpdatedBetween(day(2024, time.March, 1), day(2024, time.March, 3))
+	if err != nil {
+		t.Fatalf("ListUpdatedBetween: %v", err)
+	}
+	if len(changed) != 2 || changed[0].ID != leaves[1].ID || changed[1].ID != leaves[2].ID {
+		t.Errorf("ListUpdatedBetween = %+v, want bob's and carol's", changed)
+	}
+	if changed, _ := repo.ListUpdatedBetween(time.Time{}, day(2024, time.March, 1)); len(changed) != 1 || changed[0].Username != "alice" {
+		t.Errorf("ListUpdatedBetween from the start = %+v, want alice's", changed)
+	}
+}
+
+func TestLeaveRepositoryToday(t *testing.T) {
+	resetDB(t)
+	repo := NewLeaveRepository(testDB)
+	now := time.Now().UTC()
+	today := day(now.Year(), now.Month(), now.Day())
+
+	if _, err := repo.GetMostLeavesThisMonth(today); err == nil {
+		t.Error("GetMostLeavesThisMonth without records succeeded")
+	}
+
+	employees := NewEmployeeRepository(testDB)
+	for _, username := range []string{"alice", "erin"} {
+		if _, err := employees.SetActive(username, "", true); err != nil {
+			t.Fatalf("SetActive: %v", err)
+		}
+	}
+
+	// The queries compare against CURRENT_DATE, so the records start at
+	// midnight today in the database's time zone, UTC
+	createLeave(t, repo, "alice", "FULL_DAY", today, today.AddDate(0, 0, 1))
+	createLeave(t, repo, "bob", "IN_OFFICE", today, today.AddDate(0, 0, 1))
+
+	count, err := repo.GetLeaveCountToday(today)
+	if err != nil {
+		t.Fatalf("GetLeaveCountToday: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("GetLeaveCountToday = %d, want 1", count)
+	}
+	if count, _ := repo.GetLeaveCountToday(today.AddDate(0, 0, 2)); count != 0 {
+		t.Errorf("GetLeaveCountToday the day after tomorrow = %d, want 0", count)
+	}
+
+	onLeave, err := repo.GetAllEmployeesCurrentlyOnLeave(today)
+	if err != nil {
+		t.Fatalf("GetAllEmployeesCurrentlyOnLeave: %v", err)
+	}
+	if len(onLeave) != 1 || onLeave[0].Username != "alice" {
+		t.Errorf("GetAllEmployeesCurrentlyOnLeave = %+v, want alice", onLeave)
+	}
+
+	never, err := repo.GetEmployeesWithoutLeaveSince(today.AddDate(0, 0, -30))
+	if err != nil {
+		t.Fatalf("GetEmployeesWithoutLeaveSince: %v", err)
+	}
+	if len(never) != 1 || never[0].Username != "erin" {
+		t.Errorf("GetEmployeesWithoutLeaveSince = %+v, want erin", never)
+	}
+	if later, _ := repo.GetEmployeesWithoutLeaveSince(today.AddDate(0, 0, 1)); len(later) != len(never)+1 {
+		t.Errorf("GetEmployeesWithoutLeaveSince tomorrow = %+v, want alice too", later)
+	}
+
+	most, err := repo.GetMostLeavesThisMonth(today)
+	if err != nil {
+		t.Fatalf("GetMostLeavesThisMonth: %v", err)
+	}
+	if len(most) != 1 || most[0].Username != "alice" || most[0].LeaveCount != 1 {
+		t.Errorf("GetMostLeavesThisMonth = %+v, want alice", most)
+	}
+}
+
+func leaveIDs(leaves []models.Leave) []int64 {
+	ids := make([]int64, 0, len(leaves))
+	for _, leave := range leaves {
+		ids = append(ids, leave.ID)
+	}
+	return ids
+}
@@ -0,0 +1,214 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+)
+
+type LocationRepository struct {
+	db *sql.DB
+}
+
+func NewLocationRepository(db *sql.DB) *LocationRepository {
+	return &LocationRepository{db: db}
+}
+
+func (r *LocationRepository) Upsert(location *models.Location) error {
+	query := `
+		INSERT INTO locations (code, name, timezone, annual_leave_days, max_advance_days, min_office_days)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (code) DO UPDATE
+		SET name = EXCLUDED.name,
+			timezone = EXCLUDED.timezone,
+			annual_leave_days = EXCLUDED.annual_leave_days,
+			max_advance_days = EXCLUDED.max_advance_days,
+			min_office_days = EXCLUDED.min_office_days
+	`
+
+	_, err := r.db.Exec(query, location.Code, location.Name, location.Timezone,
+		location.AnnualLeaveDays, location.MaxAdvanceDays, location.MinOfficeDays)
+	return err
+}
+
+const locationColumns = `code, name, timezone, annual_leave_days, max_advance_days, min_office_days`
+
+func scanLocation(row rowScanner) (*models.Location, error) {
+	var location models.Location
+	var annualLeaveDays sql.NullFloat64
+	var maxAdvanceDays, minOfficeDays sql.NullInt64
+	err := row.Scan(
+		&location.Code,
+		&location.Name,
+		&location.Timezone,
+		&annualLeaveDays,
+		&maxAdvanceDays,
+		&minOfficeDays,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if annualLeaveDays.Valid {
+		location.AnnualLeaveDays = &annualLeaveDays.Float64
+	}
+	if maxAdvanceDays.Valid {
+		days := int(maxAdvanceDays.Int64)
+		location.MaxAdvanceDays = &days
+	}
+	if minOfficeDays.Valid {
+		days := int(minOfficeDays.Int64)
+		location.MinOfficeDays = &days
+	}
+
+	return &location, nil
+}
+
+func (r *LocationRepository) Get(code string) (*models.Location, error) {
+	query := `SELECT ` + locationColumns + ` FROM locations WHERE code = $1`
+
+	location, err := scanLocation(r.db.QueryRow(query, code))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("location %s not found", code)
+	}
+	return location, err
+}
+
+// GetForUser returns the office the user is assigned to, or nil if they
+// have none.
+func (r *LocationRepository) GetForUser(username string) (*models.Location, error) {
+	var code sql.NullString
+	err := r.db.QueryRow(`SELECT location_code FROM employees WHERE username = $1`, username).Scan(&code)
+	if err == sql.ErrNoRows || (err == nil && !code.Valid) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.Get(code.String)
+}
+
+// AssignUser sets the user's office, creating the employee row if needed.
+func (r *LocationRepository) AssignUser(username, slackUserID, code string) error {
+	query := `
+		INSERT INTO employees (username, slack_user_id, location_code, created_at, updated_at)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $4)
+		ON CONFLICT (username) DO UPDATE
+		SET location_code = EXCLUDED.location_code,
+			slack_user_id = COALESCE(EXCLUDED.slack_user_id, employees.slack_user_id),
+			updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := r.db.Exec(query, username, slackUserID, code, time.Now())
+	return err
+}
+
+func (r *LocationRepository) AddHoliday(holiday *models.Holiday) error {
+	query := `
+		INSERT INTO holidays (location_code, holiday_date, name)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (location_code, holiday_date) DO UPDATE SET name = EXCLUDED.name
+	`
+
+	_, err := r.db.Exec(query, holiday.LocationCode, holiday.Date.Format("2006-01-02"), holiday.Name)
+	return err
+}
+
+func (r *LocationRepository) RemoveHoliday(code string, date time.Time) error {
+	result, err := r.db.Exec(
+		`DELETE FROM holidays WHERE location_code = $1 AND holiday_date = $2`,
+		code, date.Format("2006-01-02"),
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("no holiday on %s for %s", date.Format("2006-01-02"), code)
+	}
+	return nil
+}
+
+// ListHolidays returns the location's holidays between from and to inclusive.
+func (r *LocationRepository) ListHolidays(code string, from, to time.Time) ([]models.Holiday, error) {
+	query := `
+		SELECT location_code, holiday_date, name
+		FROM holidays
+		WHERE location_code = $1 AND holiday_date BETWEEN $2 AND $3
+		ORDER BY holiday_date
+	`
+
+	rows, err := r.db.Query(query, code, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var holidays []models.Holiday
+	for rows.Next() {
+		var holiday models.Holiday
+		if err := rows.Scan(&holiday.LocationCode, &holiday.Date, &holiday.Name); err != nil {
+			return nil, err
+		}
+		holidays = append(holidays, holiday)
+	}
+
+	return holidays, nil
+}
+
+// ListAllHolidays returns every office's holidays, by office and date.
+func (r *LocationRepository) ListAllHolidays() ([]models.Holiday, error) {
+	rows, err := r.db.Query(`SELECT location_code, holiday_date, name FROM holidays ORDER BY location_code, holiday_date`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var holidays []models.Holiday
+	for rows.Next() {
+		var holiday models.Holiday
+		if err := rows.Scan(&holiday.LocationCode, &holiday.Date, &holiday.Name); err != nil {
+			return nil, err
+		}
+		holidays = append(holidays, holiday)
+	}
+	return holidays, rows.Err()
+}
+
+func (r *LocationRepository) List() ([]models.Location, error) {
+	rows, err := r.db.Query(`SELECT ` + locationColumns + ` FROM locations ORDER BY code`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var locations []models.Location
+	for rows.Next() {
+		location, err := scanLocation(rows)
+		if err != nil {
+			return nil, err
+		}
+		locations = append(locations, *location)
+	}
+	return locations, nil
+}
+
+// GetAssignments maps each username with an office to its location code.
+func (r *LocationRepository) GetAssignments() (map[string]string, error) {
+	rows, err := r.db.Query(`SELECT username, location_code FROM employees WHERE location_code IS NOT NULL`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	assignments := make(map[string]string)
+	for rows.Next() {
+		var username, code string
+		if err := rows.Scan(&username, &code); err != nil {
+			return nil, err
+		}
+		assignments[username] = code
+	}
+	return assignments, nil
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+)
+
+type PipelineEventRepository struct {
+	db *sql.DB
+}
+
+func NewPipelineEventRepository(db *sql.DB) *PipelineEventRepository {
+	return &PipelineEventRepository{db: db}
+}
+
+// Record stores an event. Times are kept in UTC, so List's since must be
+// too.
+func (r *PipelineEventRepository) Record(event *models.PipelineEvent) error {
+	event.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	var leaveID sql.NullInt64
+	if event.LeaveID != 0 {
+		leaveID = sql.NullInt64{Int64: event.LeaveID, Valid: true}
+	}
+
+	query := `
+		INSERT INTO pipeline_events (channel, message_ts, user_id, stage, detail, leave_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`
+
+	return r.db.QueryRow(
+		query,
+		event.Channel,
+		event.MessageTS,
+		event.UserID,
+		event.Stage,
+		event.Detail,
+		leaveID,
+		event.CreatedAt,
+	).Scan(&event.ID)
+}
+
+// List returns the latest limit events, newest first. An empty userID means
+// everyone's, and a zero since means no lower bound.
+func (r *PipelineEventRepository) List(userID string, since time.Time, limit int) ([]models.PipelineEvent, error) {
+	query := `
+		SELECT id, channel, message_ts, user_id, stage, detail, COALESCE(leave_id, 0), created_at
+		FROM pipeline_events
+		WHERE ($1 = '' OR user_id = $1) AND ($2::timestamp IS NULL OR created_at >= $2)
+		ORDER BY id DESC
+		LIMIT $3
+	`
+
+	rows, err := r.db.Query(query, userID, nullTime(since), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.PipelineEvent
+	for rows.Next() {
+		var event models.PipelineEvent
+		err := rows.Scan(&event.ID, &event.Channel, &event.MessageTS, &event.UserID, &event.Stage,
+			&event.Detail, &event.LeaveID, &event.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// DeleteBefore removes events older than before and returns how many went.
+func (r *PipelineEventRepository) DeleteBefore(before time.Time) (int64, error) {
+	result, err := r.db.Exec(`DELETE FROM pipeline_events WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
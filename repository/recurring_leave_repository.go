@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+)
+
+type RecurringLeaveRepository struct {
+	db *sql.DB
+}
+
+func NewRecurringLeaveRepository(db *sql.DB) *RecurringLeaveRepository {
+	return &RecurringLeaveRepository{db: db}
+}
+
+const recurringLeaveColumns = `id, username, original_text, leave_type, reason, rrule, dtstart, duration_minutes,
+	materialized_until, created_by, created_at, cancelled_at`
+
+func scanRecurringLeave(row rowScanner) (*models.RecurringLeave, error) {
+	var rec models.RecurringLeave
+	var cancelledAt sql.NullTime
+	err := row.Scan(
+		&rec.ID,
+		&rec.Username,
+		&rec.OriginalText,
+		&rec.LeaveType,
+		&rec.Reason,
+		&rec.RRule,
+		&rec.DTStart,
+		&rec.DurationMinutes,
+		&rec.MaterializedUntil,
+		&rec.CreatedBy,
+		&rec.CreatedAt,
+		&cancelledAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if cancelledAt.Valid {
+		rec.CancelledAt = &cancelledAt.Time
+	}
+	return &rec, nil
+}
+
+func (r *RecurringLeaveRepository) Create(rec *models.RecurringLeave) error {
+	rec.CreatedAt = time.Now()
+	return r.db.QueryRow(`
+		INSERT INTO recurring_leaves (username, original_text, leave_type, reason, rrule, dtstart,
+			duration_minutes, materialized_until, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id
+	`,
+		rec.Username,
+		rec.OriginalText,
+		rec.LeaveType,
+		rec.Reason,
+		rec.RRule,
+		rec.DTStart,
+		rec.DurationMinutes,
+		rec.MaterializedUntil,
+		rec.CreatedBy,
+		rec.CreatedAt,
+	).Scan(&rec.ID)
+}
+
+func (r *RecurringLeaveRepository) GetByID(id int64) (*models.RecurringLeave, error) {
+	rec, err := scanRecurringLeave(r.db.QueryRow(`SELECT `+recurringLeaveColumns+` FROM recurring_leaves WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("recurring leave %d not found", id)
+	}
+	return rec, err
+}
+
+// List returns the series that haven't been cancelled, of one user or of
+// everyone when username is empty.
+func (r *RecurringLeaveRepository) List(username string) ([]models.RecurringLeave, error) {
+	rows, err := r.db.Query(`
+		SELECT `+recurringLeaveColumns+`
+		FROM recurring_leaves
+		WHERE cancelled_at IS NULL AND ($1 = '' OR username = $1)
+		ORDER BY username, id
+	`, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recs []models.RecurringLeave
+	for rows.Next() {
+		rec, err := scanRecurringLeave(rows)
+		if err != nil {
+			return nil, err
+		}
+		recs = append(recs, *rec)
+	}
+
+	return recs, nil
+}
+
+// SetMaterializedUntil records that occurrences up to until have been created.
+func (r *RecurringLeaveRepository) SetMaterializedUntil(id int64, until time.Time) error {
+	_, err := r.db.Exec(`UPDATE recurring_leaves SET materialized_until = $1 WHERE id = $2`, until, id)
+	return err
+}
+
+func (r *RecurringLeaveRepository) Cancel(id int64) error {
+	result, err := r.db.Exec(`UPDATE recurring_leaves SET cancelled_at = $1 WHERE id = $2 AND cancelled_at IS NULL`,
+		time.Now(), id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("recurring leave %d not found or already cancelled", id)
+	}
+	return nil
+}
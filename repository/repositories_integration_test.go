@@ -0,0 +1,1464 @@
+//go:build integration
+
+package repository
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+)
+
+func TestRoleRepository(t *testing.T) {
+	resetDB(t)
+	repo := NewRoleRepository(testDB)
+
+	for i := 0; i < 2; i++ {
+		if err := repo.Grant("U1", models.RoleAdmin); err != nil {
+			t.Fatalf("Grant: %v", err)
+		}
+	}
+	if ok, err := repo.HasRole("U1", models.RoleAdmin); err != nil || !ok {
+		t.Errorf("HasRole after Grant = %v, %v", ok, err)
+	}
+	if ok, err := repo.HasRole("U1", models.RoleHR); err != nil || ok {
+		t.Errorf("HasRole of an ungranted role = %v, %v", ok, err)
+	}
+
+	if err := repo.Revoke("U1", models.RoleAdmin); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if ok, err := repo.HasRole("U1", models.RoleAdmin); err != nil || ok {
+		t.Errorf("HasRole after Revoke = %v, %v", ok, err)
+	}
+}
+
+func TestAuditRepository(t *testing.T) {
+	resetDB(t)
+	repo := NewAuditRepository(testDB)
+
+	entries := []*models.AuditEntry{
+		{Actor: "slack:U1", Action: "create", LeaveID: 7, After: `{"id":7}`},
+		{Actor: "slack:U2", Action: "role_grant"},
+		{Actor: "slack:U1", Action: "update", LeaveID: 7, Before: `{"id":7}`, After: `{"id":7,"reason":"x"}`},
+	}
+	for _, entry := range entries {
+		if err := repo.Record(entry); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+	if entries[1].PrevHash != entries[0].Hash || entries[2].PrevHash != entries[1].Hash {
+		t.Error("Record didn't chain the entries")
+	}
+
+	status, err := repo.VerifyChain()
+	if err != nil {
+		t.Fatalf("VerifyChain: %v", err)
+	}
+	if !status.Valid || status.Entries != 3 || status.HeadID != entries[2].ID || status.HeadHash != entries[2].Hash {
+		t.Errorf("VerifyChain = %+v", status)
+	}
+
+	byLeave, err := repo.ListByLeave(7)
+	if err != nil {
+		t.Fatalf("ListByLeave: %v", err)
+	}
+	if len(byLeave) != 2 || byLeave[0].Action != "create" || byLeave[1].Action != "update" {
+		t.Errorf("ListByLeave = %+v", byLeave)
+	}
+
+	after, err := repo.ListAfter(entries[0].ID)
+	if err != nil {
+		t.Fatalf("ListAfter: %v", err)
+	}
+	if len(after) != 2 || after[0].ID != entries[1].ID || after[1].Hash != entries[2].Hash {
+		t.Errorf("ListAfter = %+v", after)
+	}
+	if all, _ := repo.ListAfter(0); len(all) != 3 {
+		t.Errorf("ListAfter(0) returned %d entries, want 3", len(all))
+	}
+
+	if _, err := testDB.Exec(`UPDATE audit_log SET after_data = 'x' WHERE id = $1`, entries[1].ID); err == nil {
+		t.Error("audit_log accepted an update")
+	}
+
+	// Tamper with an entry behind the trigger's back
+	_, err = testDB.Exec(`
+		ALTER TABLE audit_log DISABLE TRIGGER audit_log_append_only;
+		UPDATE audit_log SET after_data = 'tampered' WHERE id = ` + strconv.FormatInt(entries[1].ID, 10) + `;
+		ALTER TABLE audit_log ENABLE TRIGGER audit_log_append_only;
+	`)
+	if err != nil {
+		t.Fatalf("Error tampering with the audit log: %v", err)
+	}
+	status, err = repo.VerifyChain()
+	if err != nil {
+		t.Fatalf("VerifyChain after tampering: %v", err)
+	}
+	if status.Valid || status.BrokenAt != entries[1].ID {
+		t.Errorf("VerifyChain after tampering = %+v, want broken at %d", status, entries[1].ID)
+	}
+}
+
+func TestEmployeeRepository(t *testing.T) {
+	resetDB(t)
+	repo := NewEmployeeRepository(testDB)
+
+	if err := repo.SetEmploymentType("alice", "U1", models.EmploymentContractor); err != nil {
+		t.Fatalf("SetEmploymentType: %v", err)
+	}
+	if got, err := repo.GetEmploymentType("alice"); err != nil || got != models.EmploymentContractor {
+		t.Errorf("GetEmploymentType = %q, %v", got, err)
+	}
+	if got, err := repo.GetEmploymentType("nobody"); err != nil || got != models.EmploymentEmployee {
+		t.Errorf("GetEmploymentType of an unknown user = %q, %v", got, err)
+	}
+
+	if err := repo.SetManager("alice", "", "M1"); err != nil {
+		t.Fatalf("SetManager: %v", err)
+	}
+	if err := repo.SetManager("bob", "U2", "M1"); err != nil {
+		t.Fatalf("SetManager: %v", err)
+	}
+	types, err := repo.GetEmploymentTypes()
+	if err != nil {
+		t.Fatalf("GetEmploymentTypes: %v", err)
+	}
+	wantTypes := map[string]string{"alice": models.EmploymentContractor, "bob": models.EmploymentEmployee}
+	if !reflect.DeepEqual(types, wantTypes) {
+		t.Errorf("GetEmploymentTypes = %v, want %v", types, wantTypes)
+	}
+	managers, err := repo.GetManagers()
+	if err != nil {
+		t.Fatalf("GetManagers: %v", err)
+	}
+	if !reflect.DeepEqual(managers, map[string]string{"alice": "M1", "bob": "M1"}) {
+		t.Errorf("GetManagers = %v", managers)
+	}
+	if reports, err := repo.GetReports("M1"); err != nil || !reflect.DeepEqual(reports, []string{"alice", "bob"}) {
+		t.Errorf("GetReports = %v, %v", reports, err)
+	}
+
+	alice, err := repo.Get("alice")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if alice.SlackUserID != "U1" || !alice.Active {
+		t.Errorf("Get = %+v, want the Slack ID kept and active", alice)
+	}
+	if _, err := repo.Get("nobody"); err == nil {
+		t.Error("Get of an unknown user succeeded")
+	}
+
+	carol := &models.Employee{
+		Username:       "carol",
+		SlackUserID:    "U3",
+		EmploymentType: models.EmploymentEmployee,
+		ExternalID:     "E3",
+		Email:          "Carol@Example.com",
+		FullName:       "Carol C",
+		Department:     "Engineering",
+		ManagerID:      "M2",
+		Active:         true,
+	}
+	if err := repo.Upsert(carol); err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+	if got, err := repo.GetBySlackID("U3"); err != nil || !reflect.DeepEqual(got, carol) {
+		t.Errorf("GetBySlackID = %+v, %v, want %+v", got, err, carol)
+	}
+	if got, err := repo.GetBySlackID("U404"); err != nil || got != nil {
+		t.Errorf("GetBySlackID of an unknown ID = %+v, %v", got, err)
+	}
+	if got, err := repo.GetByEmail("carol@example.com"); err != nil || got == nil || got.Username != "carol" {
+		t.Errorf("GetByEmail = %+v, %v", got, err)
+	}
+	if got, err := repo.GetByEmail("nobody@example.com"); err != nil || got != nil {
+		t.Errorf("GetByEmail of an unknown address = %+v, %v", got, err)
+	}
+
+	if err := repo.Deactivate("bob"); err != nil {
+		t.Fatalf("Deactivate: %v", err)
+	}
+	if err := repo.Deactivate("nobody"); err == nil {
+		t.Error("Deactivate of an unknown user succeeded")
+	}
+	if active, _ := repo.List(true); !reflect.DeepEqual(employeeNames(active), []string{"alice", "carol"}) {
+		t.Errorf("List(true) = %v", employeeNames(active))
+	}
+	if all, _ := repo.List(false); !reflect.DeepEqual(employeeNames(all), []string{"alice", "bob", "carol"}) {
+		t.Errorf("List(false) = %v", employeeNames(all))
+	}
+
+	if changed, err := repo.SetActive("bob", "", true); err != nil || !changed {
+		t.Errorf("SetActive reactivating = %v, %v", changed, err)
+	}
+	if changed, err := repo.SetActive("bob", "", true); err != nil || changed {
+		t.Errorf("SetActive without a change = %v, %v", changed, err)
+	}
+	if changed, err := repo.SetActive("dan", "", false); err != nil || !changed {
+		t.Errorf("SetActive of a new user = %v, %v", changed, err)
+	}
+
+	deactivated, err := repo.DeactivateMissing([]string{"alice", "bob"})
+	if err != nil {
+		t.Fatalf("DeactivateMissing: %v", err)
+	}
+	if !reflect.DeepEqual(deactivated, []string{"carol"}) {
+		t.Errorf("DeactivateMissing = %v, want [carol]", deactivated)
+	}
+	inactive, err := repo.GetInactive()
+	if err != nil {
+		t.Fatalf("GetInactive: %v", err)
+	}
+	if !reflect.DeepEqual(inactive, map[string]bool{"carol": true, "dan": true}) {
+		t.Errorf("GetInactive = %v", inactive)
+	}
+
+	// Anyone with a record is searchable, departed employees aren't
+	leaves := NewLeaveRepository(testDB)
+	createLeave(t, leaves, "alicia", "WFH", day(2024, time.March, 4), day(2024, time.March, 4).Add(9*time.Hour))
+	createLeave(t, leaves, "carol", "WFH", day(2024, time.March, 4), day(2024, time.March, 4).Add(9*time.Hour))
+	if got, err := repo.SearchUsernames("ALI", 10); err != nil || !reflect.DeepEqual(got, []string{"alice", "alicia"}) {
+		t.Errorf("SearchUsernames = %v, %v", got, err)
+	}
+	if got, _ := repo.SearchUsernames("ali", 1); !reflect.DeepEqual(got, []string{"alice"}) {
+		t.Errorf("SearchUsernames with limit 1 = %v", got)
+	}
+	if got, _ := repo.SearchUsernames("car", 10); len(got) != 0 {
+		t.Errorf("SearchUsernames found departed users: %v", got)
+	}
+}
+
+func TestLocationRepository(t *testing.T) {
+	resetDB(t)
+	repo := NewLocationRepository(testDB)
+
+	annual, advance := 20.0, 60
+	blr := &models.Location{Code: "BLR", Name: "Bangalore", Timezone: "Asia/Kolkata", AnnualLeaveDays: &annual}
+	lon := &models.Location{Code: "LON", Name: "London", Timezone: "Europe/London", MaxAdvanceDays: &advance}
+	for _, location := range []*models.Location{blr, lon} {
+		if err := repo.Upsert(location); err != nil {
+			t.Fatalf("Upsert: %v", err)
+		}
+	}
+	blr.Name = "Bengaluru"
+	if err := repo.Upsert(blr); err != nil {
+		t.Fatalf("Upsert of an existing location: %v", err)
+	}
+
+	got, err := repo.Get("BLR")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if !reflect.DeepEqual(got, blr) {
+		t.Errorf("Get = %+v, want %+v", got, blr)
+	}
+	if _, err := repo.Get("NOPE"); err == nil {
+		t.Error("Get of an unknown location succeeded")
+	}
+	if all, err := repo.List(); err != nil || len(all) != 2 || all[0].Code != "BLR" || *all[1].MaxAdvanceDays != 60 {
+		t.Errorf("List = %+v, %v", all, err)
+	}
+
+	if got, err := repo.GetForUser("alice"); err != nil || got != nil {
+		t.Errorf("GetForUser without an office = %+v, %v", got, err)
+	}
+	if err := repo.AssignUser("alice", "U1", "BLR"); err != nil {
+		t.Fatalf("AssignUser: %v", err)
+	}
+	if got, err := repo.GetForUser("alice"); err != nil || got == nil || got.Code != "BLR" {
+		t.Errorf("GetForUser = %+v, %v", got, err)
+	}
+	if got, err := repo.GetAssignments(); err != nil || !reflect.DeepEqual(got, map[string]string{"alice": "BLR"}) {
+		t.Errorf("GetAssignments = %v, %v", got, err)
+	}
+
+	republicDay := day(2024, time.January, 26)
+	for _, name := range []string{"Republic", "Republic Day"} {
+		if err := repo.AddHoliday(&models.Holiday{LocationCode: "BLR", Date: republicDay, Name: name}); err != nil {
+			t.Fatalf("AddHoliday: %v", err)
+		}
+	}
+	holidays, err := repo.ListHolidays("BLR", day(2024, time.January, 1), day(2024, time.December, 31))
+	if err != nil {
+		t.Fatalf("ListHolidays: %v", err)
+	}
+	if len(holidays) != 1 || holidays[0].Name != "Republic Day" || !holidays[0].Date.Equal(republicDay) {
+		t.Errorf("ListHolidays = %+v", holidays)
+	}
+	if err := repo.AddHoliday(&models.Holiday{LocationCode: "LON", Date: day(2023, time.December, 25), Name: "Christmas"}); err != nil {
+		t.Fatalf("AddHoliday: %v", err)
+	}
+	if all, err := repo.ListAllHolidays(); err != nil || len(all) != 2 || all[0].LocationCode != "BLR" || all[1].Name != "Christmas" {
+		t.Errorf("ListAllHolidays = %+v, %v", all, err)
+	}
+	if err := repo.RemoveHoliday("BLR", republicDay); err != nil {
+		t.Fatalf("RemoveHoliday: %v", err)
+	}
+	if err := repo.RemoveHoliday("BLR", republicDay); err == nil {
+		t.Error("RemoveHoliday of a missing holiday succeeded")
+	}
+}
+
+func TestCoverageRepository(t *testing.T) {
+	resetDB(t)
+	repo := NewCoverageRepository(testDB)
+
+	for _, member := range [][2]string{{"alice", "oncall"}, {"bob", "oncall"}, {"alice", "oncall"}, {"alice", "release"}} {
+		if err := repo.AddMember(member[0], member[1]); err != nil {
+			t.Fatalf("AddMember: %v", err)
+		}
+	}
+	if got, err := repo.Members("oncall"); err != nil || !reflect.DeepEqual(got, []string{"alice", "bob"}) {
+		t.Errorf("Members = %v, %v", got, err)
+	}
+	if _, err := NewEmployeeRepository(testDB).SetActive("bob", "", false); err != nil {
+		t.Fatalf("SetActive: %v", err)
+	}
+	if got, _ := repo.Members("oncall"); !reflect.DeepEqual(got, []string{"alice"}) {
+		t.Errorf("Members counted a departed employee: %v", got)
+	}
+	want := map[string][]string{"oncall": {"alice", "bob"}, "release": {"alice"}}
+	if got, err := repo.ListMembers(); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("ListMembers = %v, %v, want %v", got, err, want)
+	}
+
+	if got, err := repo.RolesFor("alice"); err != nil || !reflect.DeepEqual(got, []string{"oncall", "release"}) {
+		t.Errorf("RolesFor = %v, %v", got, err)
+	}
+	if err := repo.RemoveMember("alice", "release"); err != nil {
+		t.Fatalf("RemoveMember: %v", err)
+	}
+	if got, _ := repo.RolesFor("alice"); !reflect.DeepEqual(got, []string{"oncall"}) {
+		t.Errorf("RolesFor after RemoveMember = %v", got)
+	}
+
+	for _, rule := range []struct {
+		role string
+		min  int
+	}{{"oncall", 2}, {"oncall", 1}, {"release", 1}, {"release", 0}} {
+		if err := repo.SetRule(rule.role, rule.min); err != nil {
+			t.Fatalf("SetRule: %v", err)
+		}
+	}
+	if got, err := repo.GetRules(); err != nil || !reflect.DeepEqual(got, map[string]int{"oncall": 1}) {
+		t.Errorf("GetRules = %v, %v", got, err)
+	}
+}
+
+func TestViewerTokenRepository(t *testing.T) {
+	resetDB(t)
+	repo := NewViewerTokenRepository(testDB)
+
+	now := time.Now().UTC()
+	first := &models.ViewerToken{
+		Label:      "auditor",
+		ScopeStart: day(2024, time.January, 1),
+		ScopeEnd:   day(2024, time.December, 31),
+		ExpiresAt:  now.Add(24 * time.Hour),
+		CreatedBy:  "slack:U1",
+	}
+	second := &models.ViewerToken{Label: "finance", ScopeStart: first.ScopeStart, ScopeEnd: first.ScopeEnd,
+		ExpiresAt: now.Add(24 * time.Hour), CreatedBy: "slack:U1"}
+	if err := repo.Create(first, "hash-1"); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := repo.Create(second, "hash-2"); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	got, err := repo.GetByHash("hash-1")
+	if err != nil || got == nil {
+		t.Fatalf("GetByHash = %+v, %v", got, err)
+	}
+	if got.ID != first.ID || got.Label != "auditor" || !got.ScopeEnd.Equal(first.ScopeEnd) || !got.Usable(now) {
+		t.Errorf("GetByHash = %+v, want %+v", got, first)
+	}
+	if got, err := repo.GetByHash("hash-404"); err != nil || got != nil {
+		t.Errorf("GetByHash of an unknown hash = %+v, %v", got, err)
+	}
+
+	tokens, err := repo.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(tokens) != 2 || tokens[0].ID != second.ID || tokens[1].ID != first.ID {
+		t.Errorf("List = %+v, want newest first", tokens)
+	}
+
+	if err := repo.Revoke(first.ID); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if err := repo.Revoke(first.ID); err == nil {
+		t.Error("Revoke of a revoked token succeeded")
+	}
+	if got, _ := repo.GetByHash("hash-1"); got == nil || got.RevokedAt == nil || got.Usable(now) {
+		t.Errorf("GetByHash after Revoke = %+v", got)
+	}
+}
+
+func TestFeedbackRepository(t *testing.T) {
+	resetDB(t)
+	repo := NewFeedbackRepository(testDB)
+
+	flag := func(leaveID int64, userID string, rating int) *models.Feedback {
+		t.Helper()
+		feedback := &models.Feedback{
+			Kind:         models.FeedbackParse,
+			Target:       "leave:" + strconv.FormatInt(leaveID, 10),
+			LeaveID:      leaveID,
+			OriginalText: "off friday",
+			ParsedData:   `{"leave_type":"FULL_DAY"}`,
+			UserID:       userID,
+			Rating:       rating,
+		}
+		if err := repo.Record(feedback); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+		return feedback
+	}
+
+	first := flag(1, "U1", -1)
+	flag(1, "U2", -1)
+	flag(2, "U1", -1)
+	query := &models.Feedback{Kind: models.FeedbackQuery, Target: "query:who is out", QueryText: "who is out", UserID: "U1", Rating: 1}
+	if err := repo.Record(query); err != nil {
+		t.Fatalf("Record of a query rating: %v", err)
+	}
+
+	queue, err := repo.ReviewQueue(10)
+	if err != nil {
+		t.Fatalf("ReviewQueue: %v", err)
+	}
+	if len(queue) != 2 || queue[0].LeaveID != 1 || !reflect.DeepEqual(queue[0].FlaggedBy, []string{"U1", "U2"}) ||
+		queue[0].OriginalText != "off friday" || queue[1].LeaveID != 2 {
+		t.Errorf("ReviewQueue = %+v", queue)
+	}
+
+	// Rating again replaces the earlier rating
+	if again := flag(2, "U1", 1); again.ID == 0 {
+		t.Error("Record of a new rating returned no ID")
+	}
+	if queue, _ := repo.ReviewQueue(10); len(queue) != 1 || queue[0].LeaveID != 1 {
+		t.Errorf("ReviewQueue after a changed rating = %+v", queue)
+	}
+
+	if n, err := repo.ResolveMisparse(1, "slack:U9", models.ReviewDismissed); err != nil || n != 2 {
+		t.Errorf("ResolveMisparse = %d, %v, want 2 flags cleared", n, err)
+	}
+	if n, err := repo.ResolveMisparse(1, "slack:U9", models.ReviewDismissed); err != nil || n != 0 {
+		t.Errorf("ResolveMisparse again = %d, %v", n, err)
+	}
+	if queue, _ := repo.ReviewQueue(10); len(queue) != 0 {
+		t.Errorf("ReviewQueue after ResolveMisparse = %+v", queue)
+	}
+
+	// Flagging again puts the record back up for review
+	if again := flag(1, "U1", -1); again.ID != first.ID {
+		t.Errorf("Record of a repeat rating created #%d, want #%d replaced", again.ID, first.ID)
+	}
+	if queue, _ := repo.ReviewQueue(10); len(queue) != 1 || !reflect.DeepEqual(queue[0].FlaggedBy, []string{"U1"}) {
+		t.Errorf("ReviewQueue after a repeat flag = %+v", queue)
+	}
+
+	example := func(leaveID int64, source string) *models.ParseExample {
+		t.Helper()
+		start := day(2024, time.March, 8).Add(9 * time.Hour)
+		e := &models.ParseExample{
+			Message:   "off friday",
+			PostedAt:  day(2024, time.March, 6),
+			LeaveType: "FULL_DAY",
+			StartTime: start,
+			EndTime:   start.Add(9 * time.Hour),
+			Source:    source,
+			LeaveID:   leaveID,
+			CreatedBy: "slack:U1",
+		}
+		if err := repo.AddExample(e); err != nil {
+			t.Fatalf("AddExample: %v", err)
+		}
+		return e
+	}
+	confirmed := example(1, models.ExampleConfirmed)
+	example(2, models.ExampleCorrection)
+	example(3, models.ExampleConfirmed)
+	if corrected := example(1, models.ExampleCorrection); corrected.ID != confirmed.ID {
+		t.Errorf("AddExample for the same record created #%d, want #%d replaced", corrected.ID, confirmed.ID)
+	}
+
+	examples, err := repo.ListExamples(10)
+	if err != nil {
+		t.Fatalf("ListExamples: %v", err)
+	}
+	var order []int64
+	for _, e := range examples {
+		order = append(order, e.LeaveID)
+	}
+	if !reflect.DeepEqual(order, []int64{1, 2, 3}) {
+		t.Errorf("ListExamples = records %v, want corrections first, newest first", order)
+	}
+	if examples, _ := repo.ListExamples(1); len(examples) != 1 {
+		t.Errorf("ListExamples with limit 1 returned %d", len(examples))
+	}
+}
+
+func TestRecurringLeaveRepository(t *testing.T) {
+	resetDB(t)
+	repo := NewRecurringLeaveRepository(testDB)
+
+	dtstart := day(2024, time.March, 1).Add(9 * time.Hour)
+	series := func(username string) *models.RecurringLeave {
+		t.Helper()
+		rec := &models.RecurringLeave{
+			Username:          username,
+			OriginalText:      "WFH every Friday",
+			LeaveType:         "WFH",
+			RRule:             "FREQ=WEEKLY;BYDAY=FR",
+			DTStart:           dtstart,
+			DurationMinutes:   540,
+			MaterializedUntil: dtstart,
+			CreatedBy:         "slack:U1",
+		}
+		if err := repo.Create(rec); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		return rec
+	}
+	alice, bob := series("alice"), series("bob")
+
+	got, err := repo.GetByID(alice.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if got.Username != "alice" || got.RRule != alice.RRule || !got.DTStart.Equal(dtstart) ||
+		got.DurationMinutes != 540 || got.CancelledAt != nil {
+		t.Errorf("GetByID = %+v, want %+v", got, alice)
+	}
+	if _, err := repo.GetByID(alice.ID + 100); err == nil {
+		t.Error("GetByID of a missing series succeeded")
+	}
+
+	if recs, err := repo.List(""); err != nil || len(recs) != 2 || recs[0].ID != alice.ID || recs[1].ID != bob.ID {
+		t.Errorf("List = %+v, %v", recs, err)
+	}
+	if recs, _ := repo.List("bob"); len(recs) != 1 || recs[0].ID != bob.ID {
+		t.Errorf("List of one user = %+v", recs)
+	}
+
+	until := day(2024, time.April, 1)
+	if err := repo.SetMaterializedUntil(alice.ID, until); err != nil {
+		t.Fatalf("SetMaterializedUntil: %v", err)
+	}
+	if got, _ := repo.GetByID(alice.ID); got == nil || !got.MaterializedUntil.Equal(until) {
+		t.Errorf("GetByID after SetMaterializedUntil = %+v", got)
+	}
+
+	// Each occurrence is stored once
+	leaves := NewLeaveRepository(testDB)
+	occurrence := alice.Occurrence(dtstart)
+	if err := leaves.Create(occurrence); err != nil {
+		t.Fatalf("Error creating occurrence: %v", err)
+	}
+	if stored, err := leaves.GetByID(occurrence.ID); err != nil || stored.RecurrenceID != alice.ID {
+		t.Errorf("occurrence = %+v, %v, want recurrence #%d", stored, err, alice.ID)
+	}
+	if err := leaves.Create(alice.Occurrence(dtstart)); err == nil {
+		t.Error("a duplicate occurrence was stored")
+	}
+
+	if err := repo.Cancel(alice.ID); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	if err := repo.Cancel(alice.ID); err == nil {
+		t.Error("Cancel of a cancelled series succeeded")
+	}
+	if got, _ := repo.GetByID(alice.ID); got == nil || got.CancelledAt == nil {
+		t.Errorf("GetByID after Cancel = %+v", got)
+	}
+	if recs, _ := repo.List(""); len(recs) != 1 || recs[0].ID != bob.ID {
+		t.Errorf("List after Cancel = %+v", recs)
+	}
+}
+
+func TestBalanceLedgerRepository(t *testing.T) {
+	resetDB(t)
+	repo := NewBalanceLedgerRepository(testDB)
+
+	post := func(entry *models.LedgerEntry) bool {
+		t.Helper()
+		entry.Username = "alice"
+		entry.CreatedBy = "system:test"
+		ok, err := repo.Append(entry)
+		if err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+		return ok
+	}
+
+	grant := &models.LedgerEntry{EntryType: models.LedgerGrant, Days: 5, EffectiveDate: day(2024, time.January, 1), Reason: "carry over"}
+	if !post(grant) || grant.Category != models.LedgerAnnual {
+		t.Errorf("Append of a grant = %+v", grant)
+	}
+	accrual := func() *models.LedgerEntry {
+		return &models.LedgerEntry{EntryType: models.LedgerAccrual, Days: 1.67, EffectiveDate: day(2024, time.January, 31), Reason: "January"}
+	}
+	if !post(accrual()) {
+		t.Error("Append of an accrual returned false")
+	}
+	if post(accrual()) {
+		t.Error("Append of a repeat accrual returned true")
+	}
+	post(&models.LedgerEntry{EntryType: models.LedgerDebit, Days: -1.5, EffectiveDate: day(2024, time.February, 5), Reason: "Leave #42", LeaveID: 42})
+	post(&models.LedgerEntry{EntryType: models.LedgerAdjustment, Category: "SICK", Days: 2, EffectiveDate: day(2024, time.February, 10), Reason: "correction"})
+
+	entries, err := repo.List("alice", day(2024, time.January, 1), day(2025, time.January, 1))
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	var types []string
+	for _, entry := range entries {
+		types = append(types, entry.EntryType)
+	}
+	wantTypes := []string{models.LedgerGrant, models.LedgerAccrual, models.LedgerDebit, models.LedgerAdjustment}
+	if !reflect.DeepEqual(types, wantTypes) {
+		t.Errorf("List = %v, want %v", types, wantTypes)
+	}
+	if len(entries) == 4 && (entries[2].LeaveID != 42 || entries[2].Days != -1.5 || entries[3].Category != "SICK" ||
+		!entries[1].EffectiveDate.Equal(day(2024, time.January, 31))) {
+		t.Errorf("List = %+v", entries)
+	}
+	if entries, _ := repo.List("alice", day(2024, time.January, 1), day(2024, time.February, 1)); len(entries) != 2 {
+		t.Errorf("List of January returned %d entries", len(entries))
+	}
+
+	totals, err := repo.Totals("alice", models.LedgerAnnual, day(2024, time.January, 1), day(2025, time.January, 1))
+	if err != nil {
+		t.Fatalf("Totals: %v", err)
+	}
+	wantTotals := map[string]float64{models.LedgerGrant: 5, models.LedgerAccrual: 1.67, models.LedgerDebit: -1.5}
+	if !reflect.DeepEqual(totals, wantTotals) {
+		t.Errorf("Totals = %v, want %v", totals, wantTotals)
+	}
+	if totals, _ := repo.Totals("alice", "SICK", day(2024, time.January, 1), day(2025, time.January, 1)); totals[models.LedgerAdjustment] != 2 {
+		t.Errorf("Totals of SICK = %v", totals)
+	}
+
+	if _, err := testDB.Exec(`DELETE FROM balance_ledger WHERE username = 'alice'`); err == nil {
+		t.Error("balance_ledger accepted a delete")
+	}
+}
+
+func TestWarehouseExportRepository(t *testing.T) {
+	resetDB(t)
+	repo := NewWarehouseExportRepository(testDB)
+
+	if latest, err := repo.Latest(); err != nil || latest != nil {
+		t.Fatalf("Latest before any export = %+v, %v", latest, err)
+	}
+
+	for i, export := range []*models.WarehouseExport{
+		{StartedAt: day(2024, time.March, 1), LeavesThrough: day(2024, time.March, 1), AuditThrough: 4, LeaveRows: 3, AuditRows: 4, ManifestKey: "manifests/1.json"},
+		{StartedAt: day(2024, time.March, 2), LeavesThrough: day(2024, time.March, 2), AuditThrough: 9, LeaveRows: 1, AuditRows: 5, ManifestKey: "manifests/2.json"},
+	} {
+		if err := repo.Record(export); err != nil || export.ID != int64(i+1) {
+			t.Fatalf("Record = %d, %v", export.ID, err)
+		}
+	}
+
+	latest, err := repo.Latest()
+	if err != nil {
+		t.Fatalf("Latest: %v", err)
+	}
+	want := &models.WarehouseExport{ID: 2, StartedAt: day(2024, time.March, 2), LeavesThrough: day(2024, time.March, 2),
+		AuditThrough: 9, LeaveRows: 1, AuditRows: 5, ManifestKey: "manifests/2.json"}
+	if !reflect.DeepEqual(latest, want) {
+		t.Errorf("Latest = %+v, want %+v", latest, want)
+	}
+}
+
+func TestPipelineEventRepository(t *testing.T) {
+	resetDB(t)
+	repo := NewPipelineEventRepository(testDB)
+
+	for _, event := range []*models.PipelineEvent{
+		{Channel: "C1", MessageTS: "1.1", UserID: "U1", Stage: models.StageReceived},
+		{Channel: "C1", MessageTS: "1.1", UserID: "U1", Stage: models.StageSaved, LeaveID: 7},
+		{Channel: "C1", MessageTS: "2.1", UserID: "U2", Stage: models.StageSkipped, Detail: "rate limited"},
+	} {
+		if err := repo.Record(event); err != nil || event.ID == 0 {
+			t.Fatalf("Record = %d, %v", event.ID, err)
+		}
+	}
+
+	events, err := repo.List("", time.Time{}, 10)
+	if err != nil || len(events) != 3 || events[0].Detail != "rate limited" || events[1].LeaveID != 7 {
+		t.Fatalf("List = %+v, %v", events, err)
+	}
+	if events, err := repo.List("U1", time.Time{}, 10); err != nil || len(events) != 2 {
+		t.Errorf("List of U1 = %+v, %v", events, err)
+	}
+	if events, err := repo.List("", time.Now().UTC().Add(time.Hour), 10); err != nil || len(events) != 0 {
+		t.Errorf("List since an hour from now = %+v, %v", events, err)
+	}
+
+	if deleted, err := repo.DeleteBefore(time.Now().UTC().Add(time.Hour)); err != nil || deleted != 3 {
+		t.Errorf("DeleteBefore = %d, %v", deleted, err)
+	}
+}
+
+func TestLeaveApprovalStatus(t *testing.T) {
+	resetDB(t)
+	repo := NewLeaveRepository(testDB)
+
+	approved := createLeave(t, repo, "alice", "FULL_DAY", day(2024, time.March, 4), day(2024, time.March, 4))
+	if approved.Status != models.LeaveStatusApproved {
+		t.Errorf("default Status = %q, want APPROVED", approved.Status)
+	}
+	pending := &models.Leave{
+		Username:     "alice",
+		OriginalText: "off friday",
+		StartTime:    day(2024, time.March, 8),
+		EndTime:      day(2024, time.March, 8),
+		Duration:     "1 day",
+		LeaveType:    "FULL_DAY",
+		Status:       models.LeaveStatusPending,
+	}
+	if err := repo.Create(pending); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	leaves, err := repo.ListByStatus(models.LeaveStatusPending, 10)
+	if err != nil || len(leaves) != 1 || leaves[0].ID != pending.ID {
+		t.Fatalf("ListByStatus = %+v, %v", leaves, err)
+	}
+
+	if ok, err := repo.SetStatus(pending.ID, models.LeaveStatusPending, models.LeaveStatusRejected, "slack:UMGR"); err != nil || !ok {
+		t.Fatalf("SetStatus = %v, %v", ok, err)
+	}
+	if ok, err := repo.SetStatus(pending.ID, models.LeaveStatusPending, models.LeaveStatusApproved, "slack:UMGR"); err != nil || ok {
+		t.Errorf("second SetStatus = %v, %v, want false", ok, err)
+	}
+	if got, err := repo.GetByID(pending.ID); err != nil || got.Status != models.LeaveStatusRejected {
+		t.Errorf("GetByID = %+v, %v", got, err)
+	}
+
+	// Rejected records are left out of listings and reports
+	if leaves, err := repo.ListByUsername("alice", 10); err != nil || len(leaves) != 1 || leaves[0].ID != approved.ID {
+		t.Errorf("ListByUsername = %+v, %v", leaves, err)
+	}
+	used, err := repo.GetLeaveDaysUsed(day(2024, time.March, 1), day(2024, time.April, 1))
+	if err != nil || len(used) != 1 || used[0].DaysUsed != 1 {
+		t.Errorf("GetLeaveDaysUsed = %+v, %v", used, err)
+	}
+}
+
+func TestEmployeeSyncSlackProfile(t *testing.T) {
+	resetDB(t)
+	repo := NewEmployeeRepository(testDB)
+
+	// The roster's name and email win over Slack's
+	err := repo.Upsert(&models.Employee{Username: "alice", Email: "alice@corp.example", FullName: "Alice Roster", Active: true})
+	if err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+	err = repo.SyncSlackProfile(&models.Employee{Username: "alice", SlackUserID: "U1", Email: "alice@other.example",
+		FullName: "Alice Slack", Timezone: "Europe/Berlin"})
+	if err != nil {
+		t.Fatalf("SyncSlackProfile: %v", err)
+	}
+	alice, err := repo.Get("alice")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if alice.SlackUserID != "U1" || alice.Email != "alice@corp.example" || alice.FullName != "Alice Roster" || alice.Timezone != "Europe/Berlin" {
+		t.Errorf("Get = %+v", alice)
+	}
+
+	// People only Slack knows are added
+	if err := repo.SyncSlackProfile(&models.Employee{Username: "bob", SlackUserID: "U2", FullName: "Bob"}); err != nil {
+		t.Fatalf("SyncSlackProfile: %v", err)
+	}
+	if bob, err := repo.Get("bob"); err != nil || bob.FullName != "Bob" || !bob.Active {
+		t.Errorf("Get = %+v, %v", bob, err)
+	}
+}
+
+func TestSettingsRepository(t *testing.T) {
+	resetDB(t)
+	repo := NewSettingsRepository(testDB)
+
+	if value, err := repo.Get("admin_channel"); err != nil || value != "" {
+		t.Errorf("Get of an unset key = %q, %v", value, err)
+	}
+	if err := repo.Set("admin_channel", "C1", "slack:U1"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := repo.Set("admin_channel", "C2", "slack:U2"); err != nil {
+		t.Fatalf("second Set: %v", err)
+	}
+	if value, err := repo.Get("admin_channel"); err != nil || value != "C2" {
+		t.Errorf("Get = %q, %v, want C2", value, err)
+	}
+	if err := repo.Set("approver_id", "U1", "slack:U2"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	want := map[string]string{"admin_channel": "C2", "approver_id": "U1"}
+	if got, err := repo.List(); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("List = %v, %v, want %v", got, err, want)
+	}
+}
+
+func TestFeatureFlagRepository(t *testing.T) {
+	resetDB(t)
+	repo := NewFeatureFlagRepository(testDB)
+
+	if flag, err := repo.Get("T1", "approvals"); err != nil || flag != nil {
+		t.Errorf("Get of an unset flag = %+v, %v", flag, err)
+	}
+	if err := repo.Set(&models.FeatureFlag{TeamID: "T1", Flag: "approvals", Enabled: false, UpdatedBy: "slack:U1"}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := repo.Set(&models.FeatureFlag{TeamID: "T2", Flag: "approvals", Enabled: true, UpdatedBy: "slack:U1"}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if flag, err := repo.Get("T1", "approvals"); err != nil || flag == nil || flag.Enabled {
+		t.Errorf("Get = %+v, %v, want disabled", flag, err)
+	}
+	if flags, err := repo.List("T2"); err != nil || len(flags) != 1 || !flags[0].Enabled {
+		t.Errorf("List = %+v, %v", flags, err)
+	}
+
+	if err := repo.Clear("T1", "approvals"); err != nil {
+		t.Fatalf("Clear: %v", err)
+	}
+	if flag, err := repo.Get("T1", "approvals"); err != nil || flag != nil {
+		t.Errorf("Get after Clear = %+v, %v", flag, err)
+	}
+}
+
+func TestChannelDigestRepository(t *testing.T) {
+	resetDB(t)
+	repo := NewChannelDigestRepository(testDB)
+
+	for _, digest := range []*models.ChannelDigest{
+		{ChannelID: "C1", Schedule: "0 9 * * 1-5", UpdatedBy: "slack:U1"},
+		{ChannelID: "C2", Schedule: "30 8 * * *", Timezone: "Europe/London", UpdatedBy: "slack:U1"},
+		{ChannelID: "C1", Schedule: "0 10 * * 1-5", MembersOnly: true, UpdatedBy: "slack:U2"},
+	} {
+		if err := repo.Upsert(digest); err != nil {
+			t.Fatalf("Upsert: %v", err)
+		}
+	}
+	digests, err := repo.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(digests) != 2 || digests[0].Schedule != "0 10 * * 1-5" || !digests[0].MembersOnly || digests[1].Timezone != "Europe/London" {
+		t.Errorf("List = %+v", digests)
+	}
+
+	if err := repo.Delete("C2"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := repo.Delete("C2"); err == nil {
+		t.Error("second Delete succeeded, want an error")
+	}
+}
+
+func TestLeaveListOnDay(t *testing.T) {
+	resetDB(t)
+	repo := NewLeaveRepository(testDB)
+
+	march4 := day(2024, time.March, 4)
+	createLeave(t, repo, "alice", "WFH", march4.Add(9*time.Hour), march4.Add(18*time.Hour))
+	createLeave(t, repo, "bob", "FULL_DAY", day(2024, time.March, 1), march4.Add(18*time.Hour))
+	createLeave(t, repo, "carol", "IN_OFFICE", march4.Add(9*time.Hour), march4.Add(18*time.Hour))
+	createLeave(t, repo, "dave", "FULL_DAY", day(2024, time.March, 5).Add(9*time.Hour), day(2024, time.March, 5).Add(18*time.Hour))
+	createLeave(t, repo, "erin", "LATE_ARRIVAL", march4.Add(9*time.Hour), march4.Add(11*time.Hour))
+	if _, err := NewEmployeeRepository(testDB).SetActive("erin", "", false); err != nil {
+		t.Fatalf("SetActive: %v", err)
+	}
+
+	leaves, err := repo.ListOnDay(march4)
+	if err != nil {
+		t.Fatalf("ListOnDay: %v", err)
+	}
+	var got []string
+	for _, leave := range leaves {
+		got = append(got, leave.Username)
+	}
+	if want := []string{"bob", "alice"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListOnDay = %v, want %v", got, want)
+	}
+}
+
+func TestLeaveCalendarEventID(t *testing.T) {
+	resetDB(t)
+	repo := NewLeaveRepository(testDB)
+
+	march4 := day(2024, time.March, 4)
+	leave := createLeave(t, repo, "alice", "FULL_DAY", march4.Add(9*time.Hour), march4.Add(18*time.Hour))
+	if err := repo.SetCalendarEventID(leave.ID, "evt123"); err != nil {
+		t.Fatalf("SetCalendarEventID: %v", err)
+	}
+	got, err := repo.GetByID(leave.ID)
+	if err != nil || got.CalendarEventID != "evt123" {
+		t.Errorf("GetByID = %+v, %v, want event evt123", got, err)
+	}
+
+	if err := repo.SetCalendarEventID(leave.ID, ""); err != nil {
+		t.Fatalf("SetCalendarEventID to clear: %v", err)
+	}
+	if got, err := repo.GetByID(leave.ID); err != nil || got.CalendarEventID != "" {
+		t.Errorf("GetByID after clearing = %+v, %v", got, err)
+	}
+}
+
+func TestLeaveGetLossOfPayDays(t *testing.T) {
+	resetDB(t)
+	repo := NewLeaveRepository(testDB)
+
+	march4 := day(2024, time.March, 4)
+	createLeave(t, repo, "alice", "LOSS_OF_PAY", march4.Add(9*time.Hour), day(2024, time.March, 5).Add(18*time.Hour))
+	createLeave(t, repo, "bob", "LOSS_OF_PAY", day(2024, time.February, 28).Add(9*time.Hour), day(2024, time.March, 1).Add(18*time.Hour))
+	createLeave(t, repo, "carol", "FULL_DAY", march4.Add(9*time.Hour), march4.Add(18*time.Hour))
+	pending := &models.Leave{
+		Username:     "dave",
+		OriginalText: "unpaid leave friday",
+		StartTime:    day(2024, time.March, 8).Add(9 * time.Hour),
+		EndTime:      day(2024, time.March, 8).Add(18 * time.Hour),
+		Duration:     "1 day",
+		LeaveType:    "LOSS_OF_PAY",
+		Status:       models.LeaveStatusPending,
+	}
+	if err := repo.Create(pending); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// bob's leave is cut to March, and Saturday and Sunday aren't deducted
+	days, err := repo.GetLossOfPayDays(day(2024, time.March, 1), day(2024, time.April, 1), nil)
+	if err != nil {
+		t.Fatalf("GetLossOfPayDays: %v", err)
+	}
+	want := []LossOfPayDays{{"alice", 1, 2}, {"bob", 1, 1}}
+	if !reflect.DeepEqual(days, want) {
+		t.Errorf("GetLossOfPayDays = %+v, want %+v", days, want)
+	}
+
+	days, err = repo.GetLossOfPayDays(day(2024, time.March, 1), day(2024, time.April, 1), []string{"bob"})
+	if err != nil || len(days) != 1 || days[0].Username != "bob" {
+		t.Errorf("GetLossOfPayDays for bob = %+v, %v", days, err)
+	}
+}
+
+func TestLeaveListBetweenPage(t *testing.T) {
+	resetDB(t)
+	repo := NewLeaveRepository(testDB)
+
+	march4 := day(2024, time.March, 4)
+	createLeave(t, repo, "bob", "FULL_DAY", march4, march4.Add(18*time.Hour))
+	createLeave(t, repo, "alice", "WFH", day(2024, time.March, 5), day(2024, time.March, 5).Add(18*time.Hour))
+	createLeave(t, repo, "alice", "WFH", march4, march4.Add(18*time.Hour))
+	createLeave(t, repo, "carol", "FULL_DAY", day(2024, time.April, 1), day(2024, time.April, 1))
+
+	var got []string
+	var last *models.Leave
+	for {
+		leaves, err := repo.ListBetweenPage(day(2024, time.March, 1), day(2024, time.April, 1), last, 2)
+		if err != nil {
+			t.Fatalf("ListBetweenPage: %v", err)
+		}
+		for _, leave := range leaves {
+			got = append(got, leave.Username+" "+leave.StartTime.Format("Jan 2"))
+		}
+		if len(leaves) < 2 {
+			break
+		}
+		last = &leaves[len(leaves)-1]
+	}
+	if want := []string{"alice Mar 4", "alice Mar 5", "bob Mar 4"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListBetweenPage pages = %v, want %v", got, want)
+	}
+}
+
+func TestLeaveListUpcoming(t *testing.T) {
+	resetDB(t)
+	repo := NewLeaveRepository(testDB)
+
+	march4 := day(2024, time.March, 4)
+	createLeave(t, repo, "alice", "FULL_DAY", day(2024, time.March, 1), day(2024, time.March, 1))
+	createLeave(t, repo, "alice", "WFH", march4.Add(9*time.Hour), march4.Add(18*time.Hour))
+	createLeave(t, repo, "bob", "FULL_DAY", day(2024, time.March, 6), day(2024, time.March, 7))
+	createLeave(t, repo, "carol", "IN_OFFICE", march4.Add(9*time.Hour), march4.Add(18*time.Hour))
+	rejected := createLeave(t, repo, "dave", "FULL_DAY", day(2024, time.March, 5), day(2024, time.March, 5))
+	if _, err := repo.SetStatus(rejected.ID, models.LeaveStatusApproved, models.LeaveStatusRejected, "slack:UMGR"); err != nil {
+		t.Fatalf("SetStatus: %v", err)
+	}
+
+	names := func(leaves []models.Leave) []string {
+		var got []string
+		for _, leave := range leaves {
+			got = append(got, leave.Username)
+		}
+		return got
+	}
+	leaves, err := repo.ListUpcoming(march4, nil, 10)
+	if err != nil {
+		t.Fatalf("ListUpcoming: %v", err)
+	}
+	if want := []string{"alice", "bob"}; !reflect.DeepEqual(names(leaves), want) {
+		t.Errorf("ListUpcoming = %v, want %v", names(leaves), want)
+	}
+
+	leaves, err = repo.ListUpcoming(march4, []string{"bob", "dave"}, 10)
+	if err != nil {
+		t.Fatalf("ListUpcoming for bob and dave: %v", err)
+	}
+	if want := []string{"bob"}; !reflect.DeepEqual(names(leaves), want) {
+		t.Errorf("ListUpcoming for bob and dave = %v, want %v", names(leaves), want)
+	}
+}
+
+func TestUsageRepository(t *testing.T) {
+	resetDB(t)
+	repo := NewUsageRepository(testDB)
+	employees := NewEmployeeRepository(testDB)
+
+	for _, employee := range []*models.Employee{
+		{Username: "alice", SlackUserID: "U1", Department: "Eng", Active: true},
+		{Username: "bob", SlackUserID: "U2", Department: "Eng", Active: true},
+		{Username: "carol", SlackUserID: "U3", Department: "Sales", Active: true},
+	} {
+		if err := employees.Upsert(employee); err != nil {
+			t.Fatalf("Upsert: %v", err)
+		}
+	}
+
+	lastWeek, thisWeek := day(2024, time.March, 4), day(2024, time.March, 11)
+	for _, use := range []struct {
+		week    time.Time
+		feature string
+		userID  string
+	}{
+		{lastWeek, "query", "U1"},
+		{thisWeek, "query", "U1"},
+		{thisWeek, "query", "U1"},
+		{thisWeek, "query", "U2"},
+		{thisWeek, "app_home", "U2"},
+	} {
+		if err := repo.Record(use.week, use.feature, use.userID); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+
+	weekly, err := repo.Weekly(thisWeek)
+	if err != nil {
+		t.Fatalf("Weekly: %v", err)
+	}
+	want := []WeeklyUsage{
+		{Week: thisWeek, Feature: "app_home", Users: 1, Uses: 1},
+		{Week: thisWeek, Feature: "query", Users: 2, Uses: 3},
+	}
+	if len(weekly) != len(want) {
+		t.Fatalf("Weekly = %+v, want %+v", weekly, want)
+	}
+	for i := range want {
+		if !weekly[i].Week.Equal(want[i].Week) || weekly[i].Feature != want[i].Feature ||
+			weekly[i].Users != want[i].Users || weekly[i].Uses != want[i].Uses {
+			t.Errorf("Weekly[%d] = %+v, want %+v", i, weekly[i], want[i])
+		}
+	}
+	if n, err := repo.ActiveUsers(lastWeek); err != nil || n != 2 {
+		t.Errorf("ActiveUsers = %d, %v, want 2", n, err)
+	}
+
+	departments, err := repo.DepartmentAdoption(lastWeek)
+	if err != nil {
+		t.Fatalf("DepartmentAdoption: %v", err)
+	}
+	wantDepartments := []DepartmentAdoption{{"Sales", 1, 0}, {"Eng", 2, 2}}
+	if !reflect.DeepEqual(departments, wantDepartments) {
+		t.Errorf("DepartmentAdoption = %+v, want %+v", departments, wantDepartments)
+	}
+}
+
+func TestLeaveCancel(t *testing.T) {
+	resetDB(t)
+	repo := NewLeaveRepository(testDB)
+
+	kept := createLeave(t, repo, "alice", "FULL_DAY", day(2024, time.March, 4), day(2024, time.March, 4))
+	cancelled := createLeave(t, repo, "alice", "WFH", day(2024, time.March, 5), day(2024, time.March, 5))
+	before := time.Now().Add(-time.Minute)
+
+	if err := repo.Cancel(cancelled.ID, "slack:U1"); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	if err := repo.Cancel(cancelled.ID, "slack:U1"); err == nil {
+		t.Error("second Cancel succeeded, want an error")
+	}
+	if _, err := repo.GetByID(cancelled.ID); err == nil {
+		t.Error("GetByID found a cancelled record")
+	}
+	if leaves, err := repo.FindByUserAndDate("alice", day(2024, time.March, 5)); err != nil || len(leaves) != 0 {
+		t.Errorf("FindByUserAndDate = %+v, %v", leaves, err)
+	}
+	if leaves, err := repo.ListByUsername("alice", 10); err != nil || len(leaves) != 1 || leaves[0].ID != kept.ID {
+		t.Errorf("ListByUsername = %+v, %v", leaves, err)
+	}
+
+	// Exports still see the cancellation
+	leaves, err := repo.ListUpdatedBetween(before, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("ListUpdatedBetween: %v", err)
+	}
+	found := false
+	for _, leave := range leaves {
+		if leave.ID == cancelled.ID {
+			found = leave.DeletedAt != nil
+		}
+	}
+	if !found {
+		t.Errorf("ListUpdatedBetween = %+v, want the cancelled record with DeletedAt set", leaves)
+	}
+}
+
+func TestDeadLetterRepository(t *testing.T) {
+	resetDB(t)
+	repo := NewDeadLetterRepository(testDB)
+
+	first := &models.DeadLetter{Channel: "C1", MessageTS: "1.1", UserID: "U1", Text: "wfh tomorrow", Stage: "parse", Error: "timeout"}
+	second := &models.DeadLetter{Channel: "C1", MessageTS: "2.1", UserID: "U2", Text: "ooo friday", Stage: "save", Error: "connection reset"}
+	for _, letter := range []*models.DeadLetter{first, second} {
+		if err := repo.Record(letter); err != nil || letter.ID == 0 || letter.Status != models.DeadLetterPending {
+			t.Fatalf("Record = %+v, %v", letter, err)
+		}
+	}
+
+	letters, err := repo.ListPending(10)
+	if err != nil || len(letters) != 2 || letters[0].ID != first.ID || letters[1].Error != "connection reset" {
+		t.Fatalf("ListPending = %+v, %v", letters, err)
+	}
+
+	if ok, err := repo.Resolve(first.ID, models.DeadLetterReplayed, "slack:UADMIN"); err != nil || !ok {
+		t.Fatalf("Resolve = %v, %v", ok, err)
+	}
+	if ok, err := repo.Resolve(first.ID, models.DeadLetterDiscarded, "slack:UADMIN"); err != nil || ok {
+		t.Errorf("second Resolve = %v, %v, want false", ok, err)
+	}
+	got, err := repo.Get(first.ID)
+	if err != nil || got.Status != models.DeadLetterReplayed || got.ResolvedAt == nil || got.ResolvedBy != "slack:UADMIN" {
+		t.Errorf("Get = %+v, %v", got, err)
+	}
+	if letters, err := repo.ListPending(10); err != nil || len(letters) != 1 || letters[0].ID != second.ID {
+		t.Errorf("ListPending after replay = %+v, %v", letters, err)
+	}
+	if got, err := repo.Get(999); err != nil || got != nil {
+		t.Errorf("Get of a missing ID = %+v, %v", got, err)
+	}
+}
+
+func TestUnparsedRepository(t *testing.T) {
+	resetDB(t)
+	repo := NewUnparsedRepository(testDB)
+
+	for _, message := range []*models.UnparsedMessage{
+		{Channel: "C1", MessageTS: "1.1", UserID: "U1", Text: "ooo-ish thurs", Reason: "no items found"},
+		{Channel: "C1", MessageTS: "1.1", UserID: "U1", Text: "ooo-ish thurs", Reason: "replayed"},
+		{Channel: "C1", MessageTS: "2.1", UserID: "U2", Text: "dentist then in", Reason: "parsed as unrelated"},
+	} {
+		if err := repo.Record(message); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+
+	messages, err := repo.ListPending(time.Now().Add(-time.Hour), 10)
+	if err != nil || len(messages) != 2 || messages[0].Reason != "no items found" || messages[1].UserID != "U2" {
+		t.Fatalf("ListPending = %+v, %v", messages, err)
+	}
+	if later, err := repo.ListPending(time.Now().Add(time.Hour), 10); err != nil || len(later) != 0 {
+		t.Errorf("ListPending from later = %+v, %v", later, err)
+	}
+
+	first := messages[0]
+	if ok, err := repo.Resolve(first.ID, models.UnparsedExample, "slack:UADMIN"); err != nil || !ok {
+		t.Fatalf("Resolve = %v, %v", ok, err)
+	}
+	if ok, err := repo.Resolve(first.ID, models.UnparsedDismissed, "slack:UADMIN"); err != nil || ok {
+		t.Errorf("second Resolve = %v, %v, want false", ok, err)
+	}
+	got, err := repo.Get(first.ID)
+	if err != nil || got.Status != models.UnparsedExample || got.ResolvedAt == nil || got.ResolvedBy != "slack:UADMIN" {
+		t.Errorf("Get = %+v, %v", got, err)
+	}
+	if messages, err := repo.ListPending(time.Now().Add(-time.Hour), 10); err != nil || len(messages) != 1 || messages[0].MessageTS != "2.1" {
+		t.Errorf("ListPending after Resolve = %+v, %v", messages, err)
+	}
+	if got, err := repo.Get(999); err != nil || got != nil {
+		t.Errorf("Get of a missing ID = %+v, %v", got, err)
+	}
+}
+
+func TestEmbeddingRepository(t *testing.T) {
+	resetDB(t)
+	repo := NewEmbeddingRepository(testDB)
+	if ok, err := repo.Available(); err != nil || !ok {
+		t.Skipf("pgvector isn't installed: %v", err)
+	}
+	if _, err := testDB.Exec(`TRUNCATE leave_embeddings`); err != nil {
+		t.Fatalf("Error clearing embeddings: %v", err)
+	}
+	leaveRepo := NewLeaveRepository(testDB)
+
+	// One-hot vectors: each record is only similar to a search along its axis
+	axis := func(i int) []float32 {
+		v := make([]float32, 1536)
+		v[i] = 1
+		return v
+	}
+	dentist := createLeave(t, leaveRepo, "alice", "FULL_DAY", day(2024, time.March, 4), day(2024, time.March, 4))
+	dentist.Reason = "dentist"
+	if err := leaveRepo.Update(dentist); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	travel := createLeave(t, leaveRepo, "bob", "FULL_DAY", day(2024, time.April, 8), day(2024, time.April, 8))
+	createLeave(t, leaveRepo, "carol", "IN_OFFICE", day(2024, time.April, 8), day(2024, time.April, 8))
+
+	stale, err := repo.ListStale(10)
+	if err != nil || len(stale) != 2 || stale[0].LeaveID != dentist.ID || stale[1].LeaveID != travel.ID {
+		t.Fatalf("ListStale = %+v, %v", stale, err)
+	}
+	if stale[0].Text != "dentist - "+dentist.OriginalText {
+		t.Errorf("ListStale text = %q", stale[0].Text)
+	}
+
+	if err := repo.Save(dentist.ID, "test", axis(0)); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := repo.Save(travel.ID, "test", axis(1)); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if stale, err := repo.ListStale(10); err != nil || len(stale) != 0 {
+		t.Errorf("ListStale after Save = %+v, %v", stale, err)
+	}
+
+	results, err := repo.Search(axis(1), time.Time{}, time.Time{}, 10)
+	if err != nil || len(results) != 2 || results[0].ID != travel.ID || results[0].Similarity < 0.99 {
+		t.Fatalf("Search = %+v, %v", results, err)
+	}
+	results, err = repo.Search(axis(1), day(2024, time.March, 1), day(2024, time.April, 1), 10)
+	if err != nil || len(results) != 1 || results[0].ID != dentist.ID {
+		t.Errorf("Search in March = %+v, %v", results, err)
+	}
+}
+
+func employeeNames(employees []models.Employee) []string {
+	names := make([]string, 0, len(employees))
+	for _, employee := range employees {
+		names = append(names, employee.Username)
+	}
+	return names
+}
+
+func TestApprovalDelegateRepository(t *testing.T) {
+	resetDB(t)
+	repo := NewApprovalDelegateRepository(testDB)
+
+	if delegate, err := repo.Get("U1"); err != nil || delegate != nil {
+		t.Fatalf("Get before Upsert = %+v, %v", delegate, err)
+	}
+	if err := repo.Upsert(&models.ApprovalDelegate{ApproverID: "U1", BackupID: "U2", UpdatedBy: "slack:U9"}); err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+	until := day(2024, time.March, 8)
+	if err := repo.SetActiveUntil("U1", &until); err != nil {
+		t.Fatalf("SetActiveUntil: %v", err)
+	}
+	// A new backup leaves the absence being covered as it was
+	if err := repo.Upsert(&models.ApprovalDelegate{ApproverID: "U1", BackupID: "U3", UpdatedBy: "slack:U9"}); err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+	delegate, err := repo.Get("U1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if delegate.BackupID != "U3" || delegate.ActiveUntil == nil || !delegate.ActiveUntil.Equal(until) {
+		t.Errorf("Get = %+v", delegate)
+	}
+
+	if err := repo.SetActiveUntil("U1", nil); err != nil {
+		t.Fatalf("SetActiveUntil: %v", err)
+	}
+	delegates, err := repo.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(delegates) != 1 || delegates[0].ActiveUntil != nil {
+		t.Errorf("List = %+v", delegates)
+	}
+
+	if err := repo.Delete("U1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := repo.Delete("U1"); err == nil {
+		t.Error("second Delete succeeded, want an error")
+	}
+}
+
+func TestLeaveListAwaitingApprovalEmail(t *testing.T) {
+	resetDB(t)
+	repo := NewLeaveRepository(testDB)
+
+	createLeave(t, repo, "alice", "FULL_DAY", day(2024, time.March, 4), day(2024, time.March, 4))
+	pending := &models.Leave{
+		Username:     "bob",
+		OriginalText: "off friday",
+		StartTime:    day(2024, time.March, 8),
+		EndTime:      day(2024, time.March, 8),
+		Duration:     "1 day",
+		LeaveType:    "FULL_DAY",
+		Status:       models.LeaveStatusPending,
+	}
+	if err := repo.Create(pending); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	if leaves, err := repo.ListAwaitingApprovalEmail(time.Now().Add(-time.Hour), 10); err != nil || len(leaves) != 0 {
+		t.Errorf("ListAwaitingApprovalEmail before the record = %+v, %v", leaves, err)
+	}
+	leaves, err := repo.ListAwaitingApprovalEmail(time.Now().Add(time.Minute), 10)
+	if err != nil || len(leaves) != 1 || leaves[0].ID != pending.ID {
+		t.Fatalf("ListAwaitingApprovalEmail = %+v, %v", leaves, err)
+	}
+
+	if err := repo.MarkApprovalEmailed(pending.ID); err != nil {
+		t.Fatalf("MarkApprovalEmailed: %v", err)
+	}
+	if leaves, err := repo.ListAwaitingApprovalEmail(time.Now().Add(time.Minute), 10); err != nil || len(leaves) != 0 {
+		t.Errorf("ListAwaitingApprovalEmail after MarkApprovalEmailed = %+v, %v", leaves, err)
+	}
+}
+
+func TestLeaveCountSick(t *testing.T) {
+	resetDB(t)
+	repo := NewLeaveRepository(testDB)
+
+	for _, d := range []int{4, 12, 29} {
+		leave := &models.Leave{
+			Username:     "alice",
+			OriginalText: "sick",
+			Reason:       "sick",
+			StartTime:    day(2024, time.March, d),
+			EndTime:      day(2024, time.March, d),
+			Duration:     "1 day",
+			LeaveType:    "FULL_DAY",
+			Sick:         true,
+		}
+		if d == 29 {
+			leave.Status = models.LeaveStatusRejected
+		}
+		if err := repo.Create(leave); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+	createLeave(t, repo, "alice", "FULL_DAY", day(2024, time.March, 6), day(2024, time.March, 6))
+
+	count, err := repo.CountSick("alice", day(2024, time.March, 1), day(2024, time.April, 1))
+	if err != nil || count != 2 {
+		t.Errorf("CountSick = %d, %v; want 2", count, err)
+	}
+	if count, err := repo.CountSick("bob", day(2024, time.March, 1), day(2024, time.April, 1)); err != nil || count != 0 {
+		t.Errorf("CountSick(bob) = %d, %v; want 0", count, err)
+	}
+}
+
+func TestHRReviewRepository(t *testing.T) {
+	resetDB(t)
+	leaves := NewLeaveRepository(testDB)
+	repo := NewHRReviewRepository(testDB)
+
+	leave := createLeave(t, leaves, "alice", "FULL_DAY", day(2024, time.March, 4), day(2024, time.March, 18))
+	review := &models.HRReview{LeaveID: leave.ID, Username: "alice", Reasons: []string{"15 consecutive days off"}}
+	if err := repo.Create(review); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	reviews, err := repo.ListOpen(10)
+	if err != nil || len(reviews) != 1 || reviews[0].ID != review.ID || len(reviews[0].Reasons) != 1 {
+		t.Fatalf("ListOpen = %+v, %v", reviews, err)
+	}
+
+	if ok, err := repo.Resolve(review.ID, "slack:U9"); err != nil || !ok {
+		t.Fatalf("Resolve = %v, %v", ok, err)
+	}
+	if ok, err := repo.Resolve(review.ID, "slack:U9"); err != nil || ok {
+		t.Errorf("Resolve twice = %v, %v; want false", ok, err)
+	}
+	if reviews, err := repo.ListOpen(10); err != nil || len(reviews) != 0 {
+		t.Errorf("ListOpen after Resolve = %+v, %v", reviews, err)
+	}
+	// Deleting an escalated record takes its reviews with it
+	if err := repo.Create(&models.HRReview{LeaveID: leave.ID, Username: "alice", Reasons: []string{"again"}}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := leaves.Delete(leave.ID); err != nil {
+		t.Fatalf("Delete escalated leave: %v", err)
+	}
+	if reviews, err := repo.ListOpen(10); err != nil || len(reviews) != 0 {
+		t.Errorf("ListOpen after Delete = %+v, %v", reviews, err)
+	}
+}
+
+func TestLeaveGetTeamOverlap(t *testing.T) {
+	resetDB(t)
+	employees := NewEmployeeRepository(testDB)
+	repo := NewLeaveRepository(testDB)
+
+	for _, username := range []string{"alice", "bob", "carol", "dave"} {
+		if err := employees.SetManager(username, "", "M1"); err != nil {
+			t.Fatalf("SetManager: %v", err)
+		}
+	}
+	if err := employees.SetManager("erin", "", "M2"); err != nil {
+		t.Fatalf("SetManager: %v", err)
+	}
+	if err := employees.Deactivate("dave"); err != nil {
+		t.Fatalf("Deactivate: %v", err)
+	}
+
+	createLeave(t, repo, "alice", "FULL_DAY", day(2024, time.March, 4), day(2024, time.March, 8))
+	createLeave(t, repo, "bob", "FULL_DAY", day(2024, time.March, 5), day(2024, time.March, 6))
+	createLeave(t, repo, "dave", "FULL_DAY", day(2024, time.March, 5), day(2024, time.March, 5))
+	createLeave(t, repo, "erin", "FULL_DAY", day(2024, time.March, 5), day(2024, time.March, 5))
+	pending := &models.Leave{
+		Username:     "carol",
+		OriginalText: "off wednesday",
+		StartTime:    day(2024, time.March, 6),
+		EndTime:      day(2024, time.March, 6),
+		Duration:     "1 day",
+		LeaveType:    "HALF_DAY",
+		Status:       models.LeaveStatusPending,
+	}
+	if err := repo.Create(pending); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	days, err := repo.GetTeamOverlap("M1", "alice", day(2024, time.March, 4), day(2024, time.March, 9))
+	if err != nil {
+		t.Fatalf("GetTeamOverlap: %v", err)
+	}
+	var got []string
+	for _, d := range days {
+		entry := d.Day.Format("Jan 2") + " " + d.Username
+		if d.Pending {
+			entry += " pending"
+		}
+		got = append(got, entry)
+	}
+	want := []string{"Mar 5 bob", "Mar 6 bob", "Mar 6 carol pending"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetTeamOverlap = %v, want %v", got, want)
+	}
+}
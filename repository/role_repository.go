@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"database/sql"
+)
+
+type RoleRepository struct {
+	db *sql.DB
+}
+
+func NewRoleRepository(db *sql.DB) *RoleRepository {
+	return &RoleRepository{db: db}
+}
+
+func (r *RoleRepository) HasRole(userID, role string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM user_roles WHERE user_id = $1 AND role = $2
+		)
+	`
+
+	var exists bool
+	if err := r.db.QueryRow(query, userID, role).Scan(&exists); err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+func (r *RoleRepository) Grant(userID, role string) error {
+	query := `
+		INSERT INTO user_roles (user_id, role)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id, role) DO NOTHING
+	`
+
+	_, err := r.db.Exec(query, userID, role)
+	return err
+}
+
+func (r *RoleRepository) Revoke(userID, role string) error {
+	_, err := r.db.Exec(`DELETE FROM user_roles WHERE user_id = $1 AND role = $2`, userID, role)
+	return err
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+)
+
+type SettingsRepository struct {
+	db *sql.DB
+}
+
+func NewSettingsRepository(db *sql.DB) *SettingsRepository {
+	return &SettingsRepository{db: db}
+}
+
+// Get returns the value of a setting, or "" if it was never set.
+func (r *SettingsRepository) Get(key string) (string, error) {
+	var value string
+	err := r.db.QueryRow(`SELECT value FROM settings WHERE key = $1`, key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return value, err
+}
+
+// Set stores a setting, replacing any earlier value.
+func (r *SettingsRepository) Set(key, value, updatedBy string) error {
+	query := `
+		INSERT INTO settings (key, value, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (key) DO UPDATE
+		SET value = EXCLUDED.value, updated_by = EXCLUDED.updated_by, updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := r.db.Exec(query, key, value, updatedBy, time.Now())
+	return err
+}
+
+// List returns every setting, keyed by name.
+func (r *SettingsRepository) List() (map[string]string, error) {
+	rows, err := r.db.Query(`SELECT key, value FROM settings ORDER BY key`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	settings := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, err
+		}
+		settings[key] = value
+	}
+	return settings, rows.Err()
+}
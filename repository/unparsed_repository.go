@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+)
+
+type UnparsedRepository struct {
+	db *sql.DB
+}
+
+func NewUnparsedRepository(db *sql.DB) *UnparsedRepository {
+	return &UnparsedRepository{db: db}
+}
+
+// Record stores a message the parser got nothing out of. A message already
+// stored, e.g. by an earlier attempt at a replayed dead letter, is left as
+// it is.
+func (r *UnparsedRepository) Record(message *models.UnparsedMessage) error {
+	query := `
+		INSERT INTO unparsed_messages (channel, message_ts, user_id, text, reason, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (channel, message_ts) DO NOTHING
+	`
+
+	message.Status = models.UnparsedPending
+	message.CreatedAt = time.Now()
+	_, err := r.db.Exec(
+		query,
+		message.Channel,
+		message.MessageTS,
+		message.UserID,
+		message.Text,
+		message.Reason,
+		message.Status,
+		message.CreatedAt,
+	)
+	return err
+}
+
+const unparsedColumns = `id, channel, message_ts, user_id, text, reason, status, created_at, resolved_at, COALESCE(resolved_by, '')`
+
+func scanUnparsed(row rowScanner) (*models.UnparsedMessage, error) {
+	var message models.UnparsedMessage
+	var resolvedAt sql.NullTime
+	err := row.Scan(
+		&message.ID,
+		&message.Channel,
+		&message.MessageTS,
+		&message.UserID,
+		&message.Text,
+		&message.Reason,
+		&message.Status,
+		&message.CreatedAt,
+		&resolvedAt,
+		&message.ResolvedBy,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if resolvedAt.Valid {
+		message.ResolvedAt = &resolvedAt.Time
+	}
+	return &message, nil
+}
+
+// Get returns the unparsed message with the given ID, or nil if there is
+// none.
+func (r *UnparsedRepository) Get(id int64) (*models.UnparsedMessage, error) {
+	query := `SELECT ` + unparsedColumns + ` FROM unparsed_messages WHERE id = $1`
+
+	message, err := scanUnparsed(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return message, err
+}
+
+// ListPending returns up to limit pending messages stored since since,
+// oldest first.
+func (r *UnparsedRepository) ListPending(since time.Time, limit int) ([]*models.UnparsedMessage, error) {
+	query := `
+		SELECT ` + unparsedColumns + ` FROM unparsed_messages
+		WHERE status = $1 AND created_at >= $2
+		ORDER BY created_at, id
+		LIMIT $3
+	`
+
+	rows, err := r.db.Query(query, models.UnparsedPending, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []*models.UnparsedMessage
+	for rows.Next() {
+		message, err := scanUnparsed(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, rows.Err()
+}
+
+// Resolve moves a pending message to status. It reports false when the
+// message doesn't exist or was already resolved.
+func (r *UnparsedRepository) Resolve(id int64, status, actor string) (bool, error) {
+	query := `
+		UPDATE unparsed_messages SET status = $2, resolved_at = $3, resolved_by = $4
+		WHERE id = $1 AND status = $5
+	`
+
+	result, err := r.db.Exec(query, id, status, time.Now(), actor, models.UnparsedPending)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
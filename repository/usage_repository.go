@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+)
+
+// UsageRepository counts how often each user uses each feature, per week.
+type UsageRepository struct {
+	db *sql.DB
+}
+
+func NewUsageRepository(db *sql.DB) *UsageRepository {
+	return &UsageRepository{db: db}
+}
+
+// WeeklyUsage is one feature's use in one week.
+type WeeklyUsage struct {
+	Week    time.Time `json:"week"`
+	Feature string    `json:"feature"`
+	Users   int       `json:"users"` // distinct users
+	Uses    int       `json:"uses"`
+}
+
+// DepartmentAdoption is how many of a department's active employees used
+// any feature.
+type DepartmentAdoption struct {
+	Department  string `json:"department"`
+	Employees   int    `json:"employees"`
+	ActiveUsers int    `json:"active_users"`
+}
+
+// Record counts one use of feature by userID in the week starting week.
+func (r *UsageRepository) Record(week time.Time, feature, userID string) error {
+	query := `
+		INSERT INTO feature_usage (week, feature, user_id, uses)
+		VALUES ($1, $2, $3, 1)
+		ON CONFLICT (week, feature, user_id) DO UPDATE SET uses = feature_usage.uses + 1
+	`
+
+	_, err := r.db.Exec(query, week.Format("2006-01-02"), feature, userID)
+	return err
+}
+
+// Weekly returns each feature's use in every week from since on, by week
+// and feature.
+func (r *UsageRepository) Weekly(since time.Time) ([]WeeklyUsage, error) {
+	query := `
+		SELECT week, feature, COUNT(DISTINCT user_id), SUM(uses)
+		FROM feature_usage
+		WHERE week >= $1
+		GROUP BY week, feature
+		ORDER BY week, feature
+	`
+
+	rows, err := r.db.Query(query, since.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var usage []WeeklyUsage
+	for rows.Next() {
+		var u WeeklyUsage
+		if err := rows.Scan(&u.Week, &u.Feature, &u.Users, &u.Uses); err != nil {
+			return nil, err
+		}
+		usage = append(usage, u)
+	}
+	return usage, rows.Err()
+}
+
+// ActiveUsers returns how many distinct users used any feature from since
+// on.
+func (r *UsageRepository) ActiveUsers(since time.Time) (int, error) {
+	var n int
+	err := r.db.QueryRow(`SELECT COUNT(DISTINCT user_id) FROM feature_usage WHERE week >= $1`,
+		since.Format("2006-01-02")).Scan(&n)
+	return n, err
+}
+
+// DepartmentAdoption returns, for each department of active employees with
+// a Slack ID, how many used any feature from since on, least adopted first.
+// Employees without a department are grouped under "".
+func (r *UsageRepository) DepartmentAdoption(since time.Time) ([]DepartmentAdoption, error) {
+	query := `
+		WITH active AS (
+			SELECT DISTINCT user_id FROM feature_usage WHERE week >= $1
+		)
+		SELECT COALESCE(e.department, ''), COUNT(*), COUNT(a.user_id)
+		FROM employees e
+		LEFT JOIN active a ON a.user_id = e.slack_user_id
+		WHERE e.active AND e.slack_user_id IS NOT NULL
+		GROUP BY 1
+		ORDER BY COUNT(a.user_id)::float / COUNT(*), 1
+	`
+
+	rows, err := r.db.Query(query, since.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var departments []DepartmentAdoption
+	for rows.Next() {
+		var d DepartmentAdoption
+		if err := rows.Scan(&d.Department, &d.Employees, &d.ActiveUsers); err != nil {
+			return nil, err
+		}
+		departments = append(departments, d)
+	}
+	return departments, rows.Err()
+}
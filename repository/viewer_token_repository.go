@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+)
+
+type ViewerTokenRepository struct {
+	db *sql.DB
+}
+
+func NewViewerTokenRepository(db *sql.DB) *ViewerTokenRepository {
+	return &ViewerTokenRepository{db: db}
+}
+
+func (r *ViewerTokenRepository) Create(token *models.ViewerToken, tokenHash string) error {
+	query := `
+		INSERT INTO viewer_tokens (token_hash, label, scope_start, scope_end, expires_at, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`
+
+	token.CreatedAt = time.Now()
+	return r.db.QueryRow(
+		query,
+		tokenHash,
+		token.Label,
+		token.ScopeStart,
+		token.ScopeEnd,
+		token.ExpiresAt,
+		token.CreatedBy,
+		token.CreatedAt,
+	).Scan(&token.ID)
+}
+
+const viewerTokenColumns = `id, label, scope_start, scope_end, expires_at, created_by, created_at, revoked_at`
+
+func scanViewerToken(row rowScanner) (*models.ViewerToken, error) {
+	var token models.ViewerToken
+	var revokedAt sql.NullTime
+	err := row.Scan(
+		&token.ID,
+		&token.Label,
+		&token.ScopeStart,
+		&token.ScopeEnd,
+		&token.ExpiresAt,
+		&token.CreatedBy,
+		&token.CreatedAt,
+		&revokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if revokedAt.Valid {
+		token.RevokedAt = &revokedAt.Time
+	}
+	return &token, nil
+}
+
+// GetByHash returns the token with the given hash, or nil if there is none.
+func (r *ViewerTokenRepository) GetByHash(tokenHash string) (*models.ViewerToken, error) {
+	query := `SELECT ` + viewerTokenColumns + ` FROM viewer_tokens WHERE token_hash = $1`
+
+	token, err := scanViewerToken(r.db.QueryRow(query, tokenHash))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return token, err
+}
+
+// List returns every token, newest first.
+func (r *ViewerTokenRepository) List() ([]models.ViewerToken, error) {
+	rows, err := r.db.Query(`SELECT ` + viewerTokenColumns + ` FROM viewer_tokens ORDER BY id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []models.ViewerToken
+	for rows.Next() {
+		token, err := scanViewerToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, *token)
+	}
+
+	return tokens, nil
+}
+
+func (r *ViewerTokenRepository) Revoke(id int64) error {
+	result, err := r.db.Exec(`UPDATE viewer_tokens SET revoked_at = $2 WHERE id = $1 AND revoked_at IS NULL`, id, time.Now())
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("no active viewer token #%d", id)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"database/sql"
+
+	"slack-leaves-ai-agent/models"
+)
+
+type WarehouseExportRepository struct {
+	db *sql.DB
+}
+
+func NewWarehouseExportRepository(db *sql.DB) *WarehouseExportRepository {
+	return &WarehouseExportRepository{db: db}
+}
+
+func (r *WarehouseExportRepository) Record(export *models.WarehouseExport) error {
+	query := `
+		INSERT INTO warehouse_exports (started_at, leaves_through, audit_through, leave_rows, audit_rows, manifest_key)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`
+
+	return r.db.QueryRow(
+		query,
+		export.StartedAt,
+		export.LeavesThrough,
+		export.AuditThrough,
+		export.LeaveRows,
+		export.AuditRows,
+		export.ManifestKey,
+	).Scan(&export.ID)
+}
+
+// Latest returns the most recent export, or nil before the first one.
+func (r *WarehouseExportRepository) Latest() (*models.WarehouseExport, error) {
+	query := `
+		SELECT id, started_at, leaves_through, audit_through, leave_rows, audit_rows, manifest_key
+		FROM warehouse_exports
+		ORDER BY id DESC
+		LIMIT 1
+	`
+
+	var export models.WarehouseExport
+	err := r.db.QueryRow(query).Scan(
+		&export.ID,
+		&export.StartedAt,
+		&export.LeavesThrough,
+		&export.AuditThrough,
+		&export.LeaveRows,
+		&export.AuditRows,
+		&export.ManifestKey,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
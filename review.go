@@ -0,0 +1,261 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"slack-leaves-ai-agent/models"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+// Action and callback IDs of the misparse review queue on the App Home tab.
+const (
+	reviewCorrectActionID = "review_correct"
+	reviewDismissActionID = "review_dismiss"
+	reviewModalCallbackID = "review_correction"
+)
+
+// Block and action IDs of the correction modal's inputs.
+const (
+	reviewTypeBlockID   = "review_type"
+	reviewStartBlockID  = "review_start"
+	reviewEndBlockID    = "review_end"
+	reviewReasonBlockID = "review_reason"
+	reviewInputActionID = "value"
+)
+
+// maxReviewQueue is how many flagged records the App Home shows at a time.
+const maxReviewQueue = 25
+
+func (a *App) handleAppHomeOpened(ev *slackevents.AppHomeOpenedEvent) {
+	if ev.Tab != "home" {
+		return
+	}
+	a.publishHome(ev.User)
+}
+
+// publishHome renders the App Home tab. Everyone gets a short intro; admins
+// also get the queue of records flagged as misparsed.
+func (a *App) publishHome(userID string) {
+	blocks := []slack.Block{
+		slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn",
+			"*👋 I keep track of who's out and who's in.*\n"+
+				"Post your leave in the channel or DM me, and use `/leave` to see what I understand.",
+			false, false), nil, nil),
+	}
+	if a.isAdmin(userID) {
+		blocks = append(blocks, a.reviewQueueBlocks()...)
+	}
+
+	view := slack.HomeTabViewRequest{
+		Type:   slack.VTHomeTab,
+		Blocks: slack.Blocks{BlockSet: blocks},
+	}
+	if _, err := a.slackClient.PublishView(userID, view, ""); err != nil {
+		logger.Error("Failed to publish App Home for %s: %v", userID, err)
+	}
+}
+
+func (a *App) reviewQueueBlocks() []slack.Block {
+	blocks := []slack.Block{
+		slack.NewDividerBlock(),
+		slack.NewHeaderBlock(slack.NewTextBlockObject("plain_text", "🔍 Misparse review queue", true, false)),
+	}
+
+	reports, err := a.feedbackRepo.ReviewQueue(maxReviewQueue)
+	if err != nil {
+		logger.Error("Failed to load review queue: %v", err)
+		return append(blocks, slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn",
+			"❌ Couldn't load the review queue.", false, false), nil, nil))
+	}
+	if len(reports) == 0 {
+		return append(blocks, slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn",
+			"Nothing to review. Records people flag with 👎 show up here.", false, false), nil, nil))
+	}
+
+	for _, report := range reports {
+		value := strconv.FormatInt(report.LeaveID, 10)
+		flaggedBy := make([]string, 0, len(report.FlaggedBy))
+		for _, id := range report.FlaggedBy {
+			flaggedBy = append(flaggedBy, "<@"+id+">")
+		}
+
+		current := "_The record has since been deleted._"
+		buttons := []slack.BlockElement{
+			slack.NewButtonBlockElement(reviewDismissActionID, value,
+				slack.NewTextBlockObject("plain_text", "Dismiss", false, false)),
+		}
+		if leave, err := a.leaveRepo.GetByID(report.LeaveID); err == nil {
+			current = "Recorded as " + formatLeaveLine(leave)
+			correct := slack.NewButtonBlockElement(reviewCorrectActionID, value,
+				slack.NewTextBlockObject("plain_text", "Correct", false, false)).WithStyle(slack.StylePrimary)
+			buttons = append([]slack.BlockElement{correct}, buttons...)
+		}
+
+		text := fmt.Sprintf("*#%d* flagged by %s on %s\n>%s\n%s",
+			report.LeaveID, strings.Join(flaggedBy, ", "), report.FirstFlaggedAt.Format("Jan 2"),
+			strings.ReplaceAll(report.OriginalText, "\n", "\n>"), current)
+		blocks = append(blocks,
+			slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", text, false, false), nil, nil),
+			slack.NewActionBlock("review_"+value, buttons...),
+		)
+	}
+	return blocks
+}
+
+func reviewInteraction(callback slack.InteractionCallback) bool {
+	for _, action := range callback.ActionCallback.BlockActions {
+		if action.ActionID == reviewCorrectActionID || action.ActionID == reviewDismissActionID {
+			return true
+		}
+	}
+	return false
+}
+
+// handleReviewAction opens the correction modal or dismisses a flag.
+func (a *App) handleReviewAction(callback slack.InteractionCallback) {
+	if !a.isAdmin(callback.User.ID) {
+		return
+	}
+
+	for _, action := range callback.ActionCallback.BlockActions {
+		id, err := strconv.ParseInt(action.Value, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		switch action.ActionID {
+		case reviewCorrectActionID:
+			leave, err := a.leaveRepo.GetByID(id)
+			if err != nil {
+				logger.Error("Failed to load leave %d for review: %v", id, err)
+				a.publishHome(callback.User.ID)
+				continue
+			}
+			if _, err := a.slackClient.OpenView(callback.TriggerID, reviewModal(leave)); err != nil {
+				logger.Error("Failed to open correction modal: %v", err)
+			}
+
+		case reviewDismissActionID:
+			if _, err := a.feedbackRepo.ResolveMisparse(id, "slack:"+callback.User.ID, models.ReviewDismissed); err != nil {
+				logger.Error("Failed to dismiss misparse report %d: %v", id, err)
+			}
+			a.publishHome(callback.User.ID)
+		}
+	}
+}
+
+func reviewModal(leave *models.Leave) slack.ModalViewRequest {
+	typeOptions := make([]*slack.OptionBlockObject, 0, len(models.LeaveTypes))
+	var initialType *slack.OptionBlockObject
+	for _, info := range models.LeaveTypes {
+		option := slack.NewOptionBlockObject(info.Name, slack.NewTextBlockObject("plain_text", info.Name, false, false), nil)
+		if info.Name == leave.LeaveType {
+			initialType = option
+		}
+		typeOptions = append(typeOptions, option)
+	}
+	typeSelect := slack.NewOptionsSelectBlockElement(slack.OptTypeStatic, nil, reviewInputActionID, typeOptions...)
+	typeSelect.InitialOption = initialType
+
+	textInput := func(value string) *slack.PlainTextInputBlockElement {
+		input := slack.NewPlainTextInputBlockElement(nil, reviewInputActionID)
+		input.InitialValue = value
+		return input
+	}
+	timeHint := slack.NewTextBlockObject("plain_text", "YYYY-MM-DD or YYYY-MM-DDTHH:MM, in the employee's office time", false, false)
+
+	return slack.ModalViewRequest{
+		Type:            slack.VTModal,
+		CallbackID:      reviewModalCallbackID,
+		PrivateMetadata: strconv.FormatInt(leave.ID, 10),
+		Title:           slack.NewTextBlockObject("plain_text", "Correct record", false, false),
+		Submit:          slack.NewTextBlockObject("plain_text", "Save", false, false),
+		Close:           slack.NewTextBlockObject("plain_text", "Cancel", false, false),
+		Blocks: slack.Blocks{BlockSet: []slack.Block{
+			slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn",
+				fmt.Sprintf("*%s* wrote:\n>%s", leave.Username, strings.ReplaceAll(leave.OriginalText, "\n", "\n>")),
+				false, false), nil, nil),
+			slack.NewInputBlock(reviewTypeBlockID, slack.NewTextBlockObject("plain_text", "Type", false, false), nil, typeSelect),
+			slack.NewInputBlock(reviewStartBlockID, slack.NewTextBlockObject("plain_text", "Start", false, false), timeHint,
+				textInput(leave.StartTime.Format(adminTimeLayout))),
+			slack.NewInputBlock(reviewEndBlockID, slack.NewTextBlockObject("plain_text", "End", false, false), timeHint,
+				textInput(leave.EndTime.Format(adminTimeLayout))),
+			slack.NewInputBlock(reviewReasonBlockID, slack.NewTextBlockObject("plain_text", "Reason", false, false), nil,
+				textInput(leave.Reason)),
+			slack.NewContextBlock("", slack.NewTextBlockObject("mrkdwn",
+				"Saving also adds the message and the corrected parse to the parser's examples.", false, false)),
+		}},
+	}
+}
+
+// submitReviewCorrection applies a correction from the review modal and
+// promotes it into the example store. It runs inline because validation
+// errors go back in the acknowledgement; a nil response closes the modal.
+func (a *App) submitReviewCorrection(callback slack.InteractionCallback) *slack.ViewSubmissionResponse {
+	if !a.isAdmin(callback.User.ID) {
+		return slack.NewErrorsViewSubmissionResponse(map[string]string{
+			reviewTypeBlockID: "You are not allowed to correct records.",
+		})
+	}
+
+	id, err := strconv.ParseInt(callback.View.PrivateMetadata, 10, 64)
+	if err != nil {
+		logger.Error("Invalid review modal metadata %q", callback.View.PrivateMetadata)
+		return nil
+	}
+	values := callback.View.State.Values
+	actor := "slack:" + callback.User.ID
+
+	errs := map[string]string{}
+	leave, err := a.adminUpdateLeave(actor, id, false, func(leave *models.Leave) error {
+		loc := a.regionFor(leave.Username).Timezone
+		leave.LeaveType = values[reviewTypeBlockID][reviewInputActionID].SelectedOption.Value
+		leave.Reason = strings.TrimSpace(values[reviewReasonBlockID][reviewInputActionID].Value)
+
+		start, err := parseAdminTime(strings.TrimSpace(values[reviewStartBlockID][reviewInputActionID].Value), false, loc)
+		if err != nil {
+			errs[reviewStartBlockID] = err.Error()
+		}
+		end, err := parseAdminTime(strings.TrimSpace(values[reviewEndBlockID][reviewInputActionID].Value), true, loc)
+		if err != nil {
+			errs[reviewEndBlockID] = err.Error()
+		}
+		if len(errs) > 0 {
+			return fmt.Errorf("invalid times")
+		}
+		leave.StartTime, leave.EndTime = start, end
+		return nil
+	})
+	if err != nil {
+		if len(errs) == 0 {
+			errs[reviewEndBlockID] = err.Error()
+		}
+		return slack.NewErrorsViewSubmissionResponse(errs)
+	}
+
+	example := &models.ParseExample{
+		Message:   leave.OriginalText,
+		PostedAt:  leave.CreatedAt,
+		LeaveType: leave.LeaveType,
+		StartTime: leave.StartTime,
+		EndTime:   leave.EndTime,
+		Reason:    leave.Reason,
+		Source:    models.ExampleCorrection,
+		LeaveID:   leave.ID,
+		CreatedBy: actor,
+	}
+	if err := a.feedbackRepo.AddExample(example); err != nil {
+		logger.Error("Failed to store correction example: %v", err)
+	}
+	if _, err := a.feedbackRepo.ResolveMisparse(id, actor, models.ReviewCorrected); err != nil {
+		logger.Error("Failed to resolve misparse report %d: %v", id, err)
+	}
+	logger.Info("%s corrected misparsed leave %d", actor, id)
+
+	go a.publishHome(callback.User.ID)
+	return nil
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"slack-leaves-ai-agent/models"
+)
+
+// provisionEmployee writes a directory record from the HR roster or identity
+// provider. People are matched to Slack by email when their Slack account
+// isn't given, since that's the one identifier both systems share, and
+// managerEmail is resolved to the manager's Slack user ID the same way.
+func (a *App) provisionEmployee(ctx context.Context, employee *models.Employee, managerEmail string) error {
+	if employee.Username == "" || employee.SlackUserID == "" {
+		if employee.Email == "" {
+			return fmt.Errorf("email is required to find the Slack account")
+		}
+		if !a.hasScope(scopeUsersReadEmail) {
+			return fmt.Errorf("can't look up %s in Slack without the %s scope; give slack_user_id and username instead", employee.Email, scopeUsersReadEmail)
+		}
+		user, err := a.slackClient.GetUserByEmailContext(ctx, employee.Email)
+		if err != nil {
+			return fmt.Errorf("no Slack account found for %s: %v", employee.Email, err)
+		}
+		if employee.Username == "" {
+			employee.Username = user.Name
+		}
+		if employee.SlackUserID == "" {
+			employee.SlackUserID = user.ID
+		}
+	}
+
+	if managerEmail != "" {
+		managerID, err := a.slackIDForEmail(ctx, managerEmail)
+		if err != nil {
+			return fmt.Errorf("manager %s: %v", managerEmail, err)
+		}
+		employee.ManagerID = managerID
+	}
+
+	employee.EmploymentType = strings.ToUpper(employee.EmploymentType)
+	switch employee.EmploymentType {
+	case "", models.EmploymentEmployee, models.EmploymentContractor:
+	default:
+		return fmt.Errorf("unknown employment type %q", employee.EmploymentType)
+	}
+
+	employee.LocationCode = strings.ToUpper(employee.LocationCode)
+	if employee.LocationCode != "" {
+		if _, err := a.locationRepo.Get(employee.LocationCode); err != nil {
+			return err
+		}
+	}
+
+	if err := a.employeeRepo.Upsert(employee); err != nil {
+		return fmt.Errorf("error saving employee %s: %v", employee.Username, err)
+	}
+	return nil
+}
+
+// slackIDForEmail finds a Slack user ID by email, checking the employees
+// table before asking Slack.
+func (a *App) slackIDForEmail(ctx context.Context, email string) (string, error) {
+	employee, err := a.employeeRepo.GetByEmail(email)
+	if err != nil {
+		return "", err
+	}
+	if employee != nil && employee.SlackUserID != "" {
+		return employee.SlackUserID, nil
+	}
+	if !a.hasScope(scopeUsersReadEmail) {
+		return "", fmt.Errorf("not in the directory, and looking it up in Slack needs the %s scope", scopeUsersReadEmail)
+	}
+	user, err := a.slackClient.GetUserByEmailContext(ctx, email)
+	if err != nil {
+		return "", fmt.Errorf("no Slack account found: %v", err)
+	}
+	return user.ID, nil
+}
+
+type rosterImportResult struct {
+	Provisioned int                 `json:"provisioned"`
+	Synced      bool                `json:"synced"`
+	Deactivated []string            `json:"deactivated"`
+	Errors      []rosterImportError `json:"errors"`
+}
+
+type rosterImportError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// importRoster provisions every row of an HR roster CSV. The header row
+// names the columns, in any order: email is required; username,
+// slack_user_id, full_name, department, manager_email, employment_type,
+// location and active are optional. With sync set the file is treated as
+// the complete roster and anyone missing from it is deactivated, unless a
+// row failed, since a bad row would otherwise deactivate that person.
+func (a *App) importRoster(ctx context.Context, r io.Reader, sync bool) (*rosterImportResult, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("error reading header: %v", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["email"]; !ok {
+		return nil, fmt.Errorf("the roster must have an email column")
+	}
+
+	result := &rosterImportResult{}
+	var usernames []string
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		employee := &models.Employee{
+			Username:       strings.TrimPrefix(field("username"), "@"),
+			SlackUserID:    field("slack_user_id"),
+			Email:          field("email"),
+			FullName:       field("full_name"),
+			Department:     field("department"),
+			EmploymentType: field("employment_type"),
+			LocationCode:   field("location"),
+			Active:         true,
+		}
+		if value := field("active"); value != "" {
+			active, err := strconv.ParseBool(value)
+			if err != nil {
+				result.Errors = append(result.Errors, rosterImportError{Line: line, Error: fmt.Sprintf("invalid active value %q", value)})
+				continue
+			}
+			employee.Active = active
+		}
+
+		if err := a.provisionEmployee(ctx, employee, field("manager_email")); err != nil {
+			result.Errors = append(result.Errors, rosterImportError{Line: line, Error: err.Error()})
+			continue
+		}
+		result.Provisioned++
+		if employee.Active {
+			usernames = append(usernames, employee.Username)
+		} else {
+			a.cancelFutureLeaves(ctx, employee.Username, employee.Email, "roster")
+		}
+	}
+
+	if sync && len(result.Errors) == 0 {
+		if len(usernames) == 0 {
+			return nil, fmt.Errorf("refusing to sync an empty roster")
+		}
+		result.Deactivated, err = a.employeeRepo.DeactivateMissing(usernames)
+		if err != nil {
+			return nil, fmt.Errorf("error deactivating departed employees: %v", err)
+		}
+		result.Synced = true
+		for _, username := range result.Deactivated {
+			a.cancelFutureLeaves(ctx, username, "", "roster")
+		}
+	}
+
+	return result, nil
+}
+
+// handleRosterImport serves POST /api/admin/roster with a CSV body.
+// ?sync=true deactivates everyone not in the file.
+func (a *App) handleRosterImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	result, err := a.importRoster(r.Context(), r.Body, r.URL.Query().Get("sync") == "true")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.Info("Roster import by %s: %d provisioned, %d deactivated, %d errors",
+		adminActor(r), result.Provisioned, len(result.Deactivated), len(result.Errors))
+	if len(result.Deactivated) > 0 {
+		logger.Info("Deactivated by roster sync: %s", strings.Join(result.Deactivated, ", "))
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
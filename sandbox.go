@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+	"slack-leaves-ai-agent/services"
+
+	"github.com/slack-go/slack"
+)
+
+const testCommandUsage = "Usage: `/latebot-test [as @user] message`, e.g. `/latebot-test as @priya ooo thu-fri, back monday`\n" +
+	"Shows what the message would be recorded as. Nothing is saved or posted."
+
+// handleTestCommand runs `/latebot-test` for admins: the message goes through
+// the same pipeline as one posted in this channel, by the admin or by the
+// user named with "as @user", and the would-be records are shown only to the
+// admin. Nothing is saved, audited or sent to anyone else.
+func handleTestCommand(ctx context.Context, app *App, cmd slack.SlashCommand) {
+	reply := func(text string) {
+		_, err := app.slackClient.PostEphemeral(cmd.ChannelID, cmd.UserID, slack.MsgOptionText(text, false))
+		if err != nil {
+			logger.Error("Failed to post test reply: %v", err)
+		}
+	}
+
+	if !app.isAdmin(cmd.UserID) {
+		reply("❌ You are not allowed to use this command.")
+		return
+	}
+
+	text := strings.TrimSpace(cmd.Text)
+	username := ""
+	if fields := strings.Fields(text); len(fields) >= 3 && strings.EqualFold(fields[0], "as") {
+		name, err := app.resolveUserArg(fields[1])
+		if err != nil {
+			reply("❌ " + err.Error())
+			return
+		}
+		username = name
+		text = strings.TrimSpace(text[len(fields[0]):])
+		text = strings.TrimSpace(text[len(fields[1]):])
+	}
+	if text == "" || isHelpRequest(text) {
+		reply(testCommandUsage)
+		return
+	}
+	if username == "" {
+		userInfo, err := app.slackClient.GetUserInfo(cmd.UserID)
+		if err != nil {
+			logger.Error("Error getting user info: %v", err)
+			reply("❌ Couldn't look you up in Slack.")
+			return
+		}
+		username = userInfo.Name
+	}
+
+	result, err := app.dryRunMessage(ctx, cmd.ChannelID, username, text)
+	if err != nil {
+		logger.Error("Test run of %q failed: %v", text, err)
+		if errors.Is(err, services.ErrTimeout) {
+			app.notifyIfTimeout(err, cmd.ChannelID, cmd.UserID)
+			return
+		}
+		reply("❌ " + err.Error())
+		return
+	}
+	reply(result)
+}
+
+// dryRunMessage follows handleMessage and recordLeave for a message from
+// username without writing anything, and describes each step's outcome.
+func (a *App) dryRunMessage(ctx context.Context, channelID, username, text string) (string, error) {
+	lines := []string{fmt.Sprintf("🧪 *Test run for %s* (nothing saved)", username)}
+	if a.shouldParse(channelID, text) {
+		lines = append(lines, "• Trigger: would be picked up in this channel")
+	} else {
+		lines = append(lines, "• Trigger: would be *ignored* in this channel")
+	}
+	text = a.triggerText(channelID, text)
+
+	intent, err := a.openAI.ClassifyIntent(ctx, text)
+	if err != nil {
+		return "", err
+	}
+	lines = append(lines, "• Intent: "+intent)
+	if intent != services.IntentLeaveRequest {
+		return strings.Join(lines, "\n"), nil
+	}
+
+	region := a.regionFor(username)
+	responses, err := a.openAI.ParseLeaveRequests(ctx, text, fmt.Sprintf("%d", time.Now().Unix()), nil, region,
+		services.ParseLeaveRules(text, time.Now(), region))
+	if err != nil {
+		return "", err
+	}
+	for i, response := range responses {
+		lines = append(lines, fmt.Sprintf("\n*Item %d*", i+1))
+		if !response.IsValid {
+			lines = append(lines, "❌ Rejected: "+response.Error)
+			continue
+		}
+
+		leave := &models.Leave{
+			Username:     username,
+			OriginalText: text,
+			StartTime:    response.StartTime,
+			EndTime:      response.EndTime,
+			Duration:     response.Duration,
+			Reason:       response.Reason,
+			LeaveType:    response.LeaveType,
+			Recurrence:   response.RRule,
+
+			ParseConfidence: response.Confidence,
+			Sick:            response.Sick,
+		}
+		a.applySickPrivacy(leave)
+		a.applyReasonFilter(leave)
+
+		lines = append(lines, fmt.Sprintf("• %s from %s to %s (%s)", leave.LeaveType,
+			leave.StartTime.Format("Mon Jan 2, 2006 3:04 PM"), leave.EndTime.Format("Mon Jan 2, 2006 3:04 PM"), leave.Duration))
+		lines = append(lines, "• Reason: "+publicReason(leave))
+		if rule, err := services.ParseRRule(leave.Recurrence); leave.Recurrence != "" && err == nil {
+			lines = append(lines, "• Repeats "+rule.Describe())
+		}
+
+		confirm := a.config.ConfirmBeforeSave || response.Suspicious ||
+			(response.Confidence < a.config.ParseConfidenceThreshold && !(response.Sick && a.userPolicy(username).SickNoQuestions))
+		note := ""
+		if confirm {
+			note = ", would ask the author to confirm"
+		}
+		if response.Suspicious {
+			note += " (reads like an instruction to the bot)"
+		}
+		lines = append(lines, fmt.Sprintf("• Confidence: %.0f%%%s", response.Confidence*100, note))
+
+		if err := a.checkPeriodLock(leave); err != nil {
+			lines = append(lines, "🔒 Would not be saved: "+err.Error())
+			continue
+		}
+		if warning := policyWarningText(a.evaluateLeave(leave)); warning != "" {
+			lines = append(lines, warning)
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"slack-leaves-ai-agent/services"
+)
+
+// scheduledJob is periodic work run by runScheduler.
+type scheduledJob struct {
+	name     string
+	schedule *services.CronSchedule
+	loc      *time.Location // the schedule is read in this timezone
+	run      func(ctx context.Context, now time.Time)
+}
+
+// runScheduler is the bot's cron. At the start of every minute it lists the
+// scheduled jobs and queues the ones due, so they run on the event workers
+// with the usual timeout. Jobs are listed afresh each minute, so schedules
+// changed from Slack apply at once.
+func (a *App) runScheduler(ctx context.Context) {
+	timer := time.NewTimer(time.Until(time.Now().Truncate(time.Minute).Add(time.Minute)))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-timer.C:
+			now = now.Truncate(time.Minute)
+			a.runDueJobs(now)
+			timer.Reset(time.Until(now.Add(time.Minute)))
+		}
+	}
+}
+
+func (a *App) runDueJobs(now time.Time) {
+	for _, job := range a.scheduledJobs() {
+		local := now.In(job.loc)
+		if !job.schedule.Matches(local) {
+			continue
+		}
+		run := job.run
+		a.queue.Submit(job.name, func(ctx context.Context) { run(ctx, local) })
+	}
+}
+
+// scheduledJobs lists the jobs runScheduler considers: the weekly digest of
+// unparsed messages, the backup approver check, approval emails and one
+// availability digest per configured channel. A digest whose settings can't
+// be read is skipped and logged.
+func (a *App) scheduledJobs() []scheduledJob {
+	var jobs []scheduledJob
+	if job, err := a.unparsedDigestJob(); err != nil {
+		logger.Error("Skipping unparsed message digest: %v", err)
+	} else {
+		jobs = append(jobs, job)
+	}
+	if job, err := a.approvalDelegationJob(); err != nil {
+		logger.Error("Skipping backup approver check: %v", err)
+	} else {
+		jobs = append(jobs, job)
+	}
+	if job, err := a.approvalEmailJob(); err != nil {
+		logger.Error("Skipping approval emails: %v", err)
+	} else {
+		jobs = append(jobs, job)
+	}
+
+	digests, err := a.digestRepo.List()
+	if err != nil {
+		logger.Error("Failed to load channel digests: %v", err)
+		return jobs
+	}
+
+	for _, digest := range digests {
+		schedule, err := services.ParseCron(digest.Schedule)
+		if err != nil {
+			logger.Error("Skipping digest for %s: %v", digest.ChannelID, err)
+			continue
+		}
+		loc := a.config.Timezone
+		if digest.Timezone != "" {
+			if loc, err = time.LoadLocation(digest.Timezone); err != nil {
+				logger.Error("Skipping digest for %s: unknown timezone %q", digest.ChannelID, digest.Timezone)
+				continue
+			}
+		}
+		digest := digest
+		jobs = append(jobs, scheduledJob{
+			name:     "availabilityDigest:" + digest.ChannelID,
+			schedule: schedule,
+			loc:      loc,
+			run: func(ctx context.Context, now time.Time) {
+				a.postAvailabilityDigest(ctx, &digest, now)
+			},
+		})
+	}
+	return jobs
+}
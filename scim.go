@@ -0,0 +1,450 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"slack-leaves-ai-agent/models"
+)
+
+// A minimal SCIM 2.0 Users endpoint (RFC 7643/7644) so the identity provider
+// can provision and deprovision employees directly. A user's SCIM id is their
+// Slack user ID, which never changes, and their manager is referenced by the
+// manager's SCIM id in the enterprise extension. Deleting a user deactivates
+// them rather than removing their records.
+
+const (
+	scimUserSchema       = "urn:ietf:params:scim:schemas:core:2.0:User"
+	scimEnterpriseSchema = "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+	scimListSchema       = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	scimErrorSchema      = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+type scimUser struct {
+	Schemas     []string        `json:"schemas"`
+	ID          string          `json:"id,omitempty"`
+	ExternalID  string          `json:"externalId,omitempty"`
+	UserName    string          `json:"userName"`
+	DisplayName string          `json:"displayName,omitempty"`
+	Name        *scimName       `json:"name,omitempty"`
+	Emails      []scimEmail     `json:"emails,omitempty"`
+	UserType    string          `json:"userType,omitempty"`
+	Active      *bool           `json:"active,omitempty"`
+	Enterprise  *scimEnterprise `json:"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User,omitempty"`
+	Meta        *scimMeta       `json:"meta,omitempty"`
+}
+
+type scimName struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+type scimEmail struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+type scimEnterprise struct {
+	Department string       `json:"department,omitempty"`
+	Manager    *scimManager `json:"manager,omitempty"`
+}
+
+type scimManager struct {
+	Value       string `json:"value"`
+	DisplayName string `json:"displayName,omitempty"`
+}
+
+type scimMeta struct {
+	ResourceType string `json:"resourceType"`
+	Location     string `json:"location,omitempty"`
+}
+
+type scimPatchRequest struct {
+	Schemas    []string `json:"schemas"`
+	Operations []struct {
+		Op    string          `json:"op"`
+		Path  string          `json:"path"`
+		Value json.RawMessage `json:"value"`
+	} `json:"Operations"`
+}
+
+// requireSCIMToken guards the SCIM endpoint with the SCIM_TOKEN bearer
+// token configured in the identity provider. Provisioning is disabled when
+// no token is set.
+func (a *App) requireSCIMToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if a.config.SCIMToken == "" {
+			scimError(w, http.StatusForbidden, "SCIM provisioning is disabled")
+			return
+		}
+
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(a.config.SCIMToken)) != 1 {
+			scimError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func scimError(w http.ResponseWriter, status int, detail string) {
+	w.Header().Set("Content-Type", "application/scim+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"schemas": []string{scimErrorSchema},
+		"status":  strconv.Itoa(status),
+		"detail":  detail,
+	})
+}
+
+func writeSCIM(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/scim+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func toSCIMUser(employee *models.Employee) scimUser {
+	active := employee.Active
+	user := scimUser{
+		Schemas:     []string{scimUserSchema, scimEnterpriseSchema},
+		ID:          employee.SlackUserID,
+		ExternalID:  employee.ExternalID,
+		UserName:    employee.Email,
+		DisplayName: employee.FullName,
+		UserType:    employee.EmploymentType,
+		Active:      &active,
+		Meta:        &scimMeta{ResourceType: "User", Location: "/scim/v2/Users/" + employee.SlackUserID},
+	}
+	if user.UserName == "" {
+		user.UserName = employee.Username
+	}
+	if employee.FullName != "" {
+		user.Name = &scimName{Formatted: employee.FullName}
+	}
+	if employee.Email != "" {
+		user.Emails = []scimEmail{{Value: employee.Email, Type: "work", Primary: true}}
+	}
+	if employee.Department != "" || employee.ManagerID != "" {
+		user.Enterprise = &scimEnterprise{Department: employee.Department}
+		if employee.ManagerID != "" {
+			user.Enterprise.Manager = &scimManager{Value: employee.ManagerID}
+		}
+	}
+	return user
+}
+
+// applySCIMUser copies the attributes the identity provider sent onto the
+// employee record. Attributes missing from a full replacement are cleared.
+func applySCIMUser(employee *models.Employee, user scimUser) {
+	employee.ExternalID = user.ExternalID
+	employee.EmploymentType = user.UserType
+	employee.Active = user.Active == nil || *user.Active
+
+	employee.Email = ""
+	for _, email := range user.Emails {
+		if employee.Email == "" || email.Primary {
+			employee.Email = email.Value
+		}
+	}
+	if employee.Email == "" && strings.Contains(user.UserName, "@") {
+		employee.Email = user.UserName
+	}
+
+	employee.FullName = user.DisplayName
+	if employee.FullName == "" && user.Name != nil {
+		employee.FullName = user.Name.Formatted
+		if employee.FullName == "" {
+			employee.FullName = strings.TrimSpace(user.Name.GivenName + " " + user.Name.FamilyName)
+		}
+	}
+
+	employee.Department = ""
+	employee.ManagerID = ""
+	if user.Enterprise != nil {
+		employee.Department = user.Enterprise.Department
+		if user.Enterprise.Manager != nil {
+			employee.ManagerID = user.Enterprise.Manager.Value
+		}
+	}
+}
+
+// handleSCIMUsers serves /scim/v2/Users: GET lists or filters users, POST
+// provisions one.
+func (a *App) handleSCIMUsers(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		a.listSCIMUsers(w, r)
+
+	case http.MethodPost:
+		var user scimUser
+		if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
+			scimError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		employee := &models.Employee{}
+		applySCIMUser(employee, user)
+		if err := a.provisionEmployee(r.Context(), employee, ""); err != nil {
+			scimError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		logger.Info("SCIM provisioned %s (%s)", employee.Username, employee.SlackUserID)
+		writeSCIM(w, http.StatusCreated, toSCIMUser(employee))
+
+	default:
+		scimError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+var scimFilterPattern = regexp.MustCompile(`(?i)^\s*([\w.]+)\s+eq\s+"([^"]*)"\s*$`)
+
+// listSCIMUsers supports the equality filters identity providers use to
+// check whether a user already exists: userName, externalId and
+// emails.value.
+func (a *App) listSCIMUsers(w http.ResponseWriter, r *http.Request) {
+	employees, err := a.employeeRepo.List(false)
+	if err != nil {
+		scimError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if filter := r.URL.Query().Get("filter"); filter != "" {
+		match := scimFilterPattern.FindStringSubmatch(filter)
+		if match == nil {
+			scimError(w, http.StatusBadRequest, fmt.Sprintf("Unsupported filter %q", filter))
+			return
+		}
+		attribute, value := strings.ToLower(match[1]), match[2]
+
+		var matched []models.Employee
+		for _, employee := range employees {
+			var ok bool
+			switch attribute {
+			case "username":
+				ok = strings.EqualFold(employee.Email, value) || employee.Username == value
+			case "externalid":
+				ok = employee.ExternalID == value
+			case "emails.value", "emails":
+				ok = strings.EqualFold(employee.Email, value)
+			default:
+				scimError(w, http.StatusBadRequest, fmt.Sprintf("Unsupported filter attribute %q", match[1]))
+				return
+			}
+			if ok {
+				matched = append(matched, employee)
+			}
+		}
+		employees = matched
+	}
+
+	// Users without a Slack account have no SCIM id, so they can't be listed
+	resources := make([]scimUser, 0, len(employees))
+	for i := range employees {
+		if employees[i].SlackUserID != "" {
+			resources = append(resources, toSCIMUser(&employees[i]))
+		}
+	}
+	total := len(resources)
+
+	startIndex := 1
+	if n, err := strconv.Atoi(r.URL.Query().Get("startIndex")); err == nil && n > 1 {
+		startIndex = n
+	}
+	if startIndex > len(resources) {
+		resources = resources[:0]
+	} else {
+		resources = resources[startIndex-1:]
+	}
+	if n, err := strconv.Atoi(r.URL.Query().Get("count")); err == nil && n >= 0 && n < len(resources) {
+		resources = resources[:n]
+	}
+
+	writeSCIM(w, http.StatusOK, map[string]interface{}{
+		"schemas":      []string{scimListSchema},
+		"totalResults": total,
+		"startIndex":   startIndex,
+		"itemsPerPage": len(resources),
+		"Resources":    resources,
+	})
+}
+
+// handleSCIMUser serves /scim/v2/Users/{id}: GET, PUT, PATCH and DELETE.
+func (a *App) handleSCIMUser(w http.ResponseWriter, r *http.Request) {
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/scim/v2/Users/"), "/")
+	if id == "" {
+		a.handleSCIMUsers(w, r)
+		return
+	}
+
+	employee, err := a.employeeRepo.GetBySlackID(id)
+	if err != nil {
+		scimError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if employee == nil {
+		scimError(w, http.StatusNotFound, fmt.Sprintf("User %s not found", id))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeSCIM(w, http.StatusOK, toSCIMUser(employee))
+
+	case http.MethodPut:
+		var user scimUser
+		if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
+			scimError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		applySCIMUser(employee, user)
+		a.saveSCIMUser(w, r, employee)
+
+	case http.MethodPatch:
+		var patch scimPatchRequest
+		if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+			scimError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		for _, op := range patch.Operations {
+			if err := applySCIMPatch(employee, strings.ToLower(op.Op), op.Path, op.Value); err != nil {
+				scimError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+		a.saveSCIMUser(w, r, employee)
+
+	case http.MethodDelete:
+		if err := a.employeeRepo.Deactivate(employee.Username); err != nil {
+			scimError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		logger.Info("SCIM deprovisioned %s (%s)", employee.Username, employee.SlackUserID)
+		a.cancelFutureLeaves(r.Context(), employee.Username, employee.Email, "scim")
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		scimError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+func (a *App) saveSCIMUser(w http.ResponseWriter, r *http.Request, employee *models.Employee) {
+	if err := a.provisionEmployee(r.Context(), employee, ""); err != nil {
+		scimError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !employee.Active {
+		logger.Info("SCIM deactivated %s (%s)", employee.Username, employee.SlackUserID)
+		a.cancelFutureLeaves(r.Context(), employee.Username, employee.Email, "scim")
+	}
+	writeSCIM(w, http.StatusOK, toSCIMUser(employee))
+}
+
+// applySCIMPatch applies one PatchOp operation. Only the attributes this app
+// stores are supported; anything else is ignored so identity providers that
+// send their full attribute set don't fail.
+func applySCIMPatch(employee *models.Employee, op, path string, value json.RawMessage) error {
+	if op != "add" && op != "replace" && op != "remove" {
+		return fmt.Errorf("unsupported patch op %q", op)
+	}
+
+	// A patch without a path carries a map of attributes to set
+	if path == "" {
+		if op == "remove" {
+			return fmt.Errorf("remove requires a path")
+		}
+		var attributes map[string]json.RawMessage
+		if err := json.Unmarshal(value, &attributes); err != nil {
+			return fmt.Errorf("invalid patch value: %v", err)
+		}
+		for name, v := range attributes {
+			if name == scimEnterpriseSchema {
+				var nested map[string]json.RawMessage
+				if err := json.Unmarshal(v, &nested); err != nil {
+					return fmt.Errorf("invalid patch value: %v", err)
+				}
+				for nestedName, nestedValue := range nested {
+					if err := applySCIMPatch(employee, op, scimEnterpriseSchema+":"+nestedName, nestedValue); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if err := applySCIMPatch(employee, op, name, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var str string
+	if op != "remove" {
+		str = scimPatchString(value)
+	}
+
+	switch strings.ToLower(path) {
+	case "active":
+		if op == "remove" {
+			return nil
+		}
+		// Some identity providers send booleans as strings
+		active, err := strconv.ParseBool(str)
+		if err != nil {
+			return fmt.Errorf("invalid active value %s", value)
+		}
+		employee.Active = active
+	case "externalid":
+		employee.ExternalID = str
+	case "displayname", "name.formatted":
+		employee.FullName = str
+	case "usertype":
+		employee.EmploymentType = str
+	case "username":
+		if strings.Contains(str, "@") {
+			employee.Email = str
+		}
+	case `emails[type eq "work"].value`, "emails":
+		if strings.HasPrefix(strings.TrimSpace(string(value)), "[") {
+			var emails []scimEmail
+			if err := json.Unmarshal(value, &emails); err != nil {
+				return fmt.Errorf("invalid emails value: %v", err)
+			}
+			str = ""
+			for _, email := range emails {
+				if str == "" || email.Primary {
+					str = email.Value
+				}
+			}
+		}
+		employee.Email = str
+	case strings.ToLower(scimEnterpriseSchema + ":department"):
+		employee.Department = str
+	case strings.ToLower(scimEnterpriseSchema + ":manager"):
+		if op != "remove" && strings.HasPrefix(strings.TrimSpace(string(value)), "{") {
+			var manager scimManager
+			if err := json.Unmarshal(value, &manager); err != nil {
+				return fmt.Errorf("invalid manager value: %v", err)
+			}
+			str = manager.Value
+		}
+		employee.ManagerID = str
+	}
+	return nil
+}
+
+// scimPatchString decodes a patch value that may be a JSON string, boolean
+// or number into its string form.
+func scimPatchString(value json.RawMessage) string {
+	var str string
+	if err := json.Unmarshal(value, &str); err == nil {
+		return str
+	}
+	return strings.TrimSpace(string(value))
+}
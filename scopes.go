@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"slack-leaves-ai-agent/services"
+)
+
+// Bot token scopes that optional features depend on.
+const (
+	scopeFilesWrite     = "files:write"
+	scopeIMWrite        = "im:write"
+	scopeLinksWrite     = "links:write"
+	scopeUsersReadEmail = "users:read.email"
+)
+
+// coreScopes are needed for the bot to work at all.
+var coreScopes = []string{"chat:write", "commands", "users:read", "channels:history"}
+
+// featureScopes lists what is turned off when an optional scope is missing.
+var featureScopes = []struct {
+	scope    string
+	features string
+}{
+	{scopeFilesWrite, "CSV exports and the encashment report"},
+	{scopeIMWrite, "handover checklists and the encashment report, which are sent by DM"},
+	{scopeLinksWrite, "unfurling leave record links"},
+	{scopeUsersReadEmail, "matching roster and SCIM entries to Slack accounts by email"},
+}
+
+// loadSlackScopes looks up the bot token's scopes once at startup and warns
+// about every feature a missing scope turns off. If the lookup fails,
+// scopes stays nil and every feature is left on.
+func (a *App) loadSlackScopes(ctx context.Context) {
+	scopes, err := services.SlackScopes(ctx, a.config.SlackBotToken)
+	if err != nil {
+		logger.Error("Failed to look up Slack scopes, assuming all are granted: %v", err)
+		return
+	}
+	a.scopes = scopes
+
+	for _, scope := range coreScopes {
+		if !scopes[scope] {
+			logger.Error("Slack scope %s is missing; the bot won't work properly until it's added", scope)
+		}
+	}
+	for _, f := range featureScopes {
+		if !scopes[f.scope] {
+			logger.Error("Slack scope %s is missing, disabling %s", f.scope, f.features)
+		}
+	}
+}
+
+// hasScope reports whether the bot token was granted scope, or true when
+// the scopes couldn't be looked up.
+func (a *App) hasScope(scope string) bool {
+	return a.scopes == nil || a.scopes[scope]
+}
+
+// missingScopeText explains to a user why a feature isn't available.
+func missingScopeText(scope string) string {
+	return fmt.Sprintf("❌ This isn't available: the app is missing the Slack `%s` permission. Ask an admin to add it.", scope)
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// BatchMessage is one historical message to parse in a batch. PostedAt is
+// when the message was originally sent; relative dates ("tomorrow") are
+// resolved against it rather than against the current time, in the author's
+// Location (the default region when nil).
+type BatchMessage struct {
+	ID       string         `json:"id"`
+	Text     string         `json:"text"`
+	PostedAt time.Time      `json:"posted_at"`
+	Location *time.Location `json:"-"`
+}
+
+const maxBatchMessageLength = 1000
+
+type batchResult struct {
+	ID string `json:"id"`
+	LeaveResponse
+}
+
+type batchResponse struct {
+	Results []batchResult `json:"results"`
+}
+
+// ParseLeaveRequestBatch parses several historical messages in a single LLM
+// call. Results are keyed by BatchMessage.ID; messages the model skipped are
+// absent from the map so the caller can retry them individually.
+//
+// Unlike ParseLeaveRequest no past-date or 30-day window validation is
+// applied, since backfilled messages are by definition in the past.
+func (s *OpenAIService) ParseLeaveRequestBatch(ctx context.Context, messages []BatchMessage) (map[string]*LeaveResponse, error) {
+	if len(messages) == 0 {
+		return map[string]*LeaveResponse{}, nil
+	}
+
+	type promptItem struct {
+		ID       string `json:"id"`
+		Text     string `json:"text"`
+		PostedAt string `json:"posted_at"`
+	}
+	items := make([]promptItem, 0, len(messages))
+	locations := make(map[string]*time.Location, len(messages))
+	texts := make(map[string]string, len(messages))
+	for _, msg := range messages {
+		loc := msg.Location
+		if loc == nil {
+			loc = DefaultRegion().Timezone
+		}
+		locations[msg.ID] = loc
+		texts[msg.ID] = msg.Text
+
+		// Keep one rambling message from eating the whole context window
+		text := []rune(strings.TrimSpace(msg.Text))
+		if len(text) > maxBatchMessageLength {
+			text = text[:maxBatchMessageLength]
+		}
+		items = append(items, promptItem{
+			ID:       msg.ID,
+			Text:     string(text),
+			PostedAt: msg.PostedAt.In(loc).Format(time.RFC3339),
+		})
+	}
+	itemsJSON, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+
+	prompt := `Parse each of these historical Slack messages for leave/attendance details.
+
+	Messages (JSON array): ` + string(itemsJSON) + `
+
+	Context:
+	- Resolve relative dates ("today", "tomorrow", "next monday") against each message's own posted_at
+	- Default work hours: 9:00 AM to 6:00 PM
+	- Each posted_at is in the author's local timezone; use the same UTC offset for that message's dates
+
+	Rules for leave_type:
+	- "WFH" for working from home
+	- "IN_OFFICE" for working from the office (hybrid office days)
+	- "FULL_DAY" for full day leave
+	- "HALF_DAY" for half day leave
+	- "LATE_ARRIVAL" for coming late
+	- "EARLY_DEPARTURE" for leaving early
+	- "APPOINTMENT" for being away for a set window within the day (e.g. "out from 2:30 to 4 for a dentist appointment")
+	- "PARENTAL" for parental, maternity or paternity leave
+	- "LOSS_OF_PAY" for unpaid leave, leave without pay (LWP) or loss of pay (LOP)
+
+	- For full day, parental and loss-of-pay leave, WFH and IN_OFFICE: set time to 9:00 AM - 6:00 PM local time
+	- For half day leave: set time to either 9:00 AM - 1:00 PM or 2:00 PM - 6:00 PM local time
+	- For appointments: use the exact window to the minute (e.g. 14:30 - 16:00); never round it to a half day
+	- Set sick to true when the author is off because they're unwell (e.g. "down with fever", "sick leave tomorrow"), otherwise false
+	- If a message is not about attendance, set is_valid to false and start_time/end_time to null
+` + confidenceRules + explainRules + `
+
+	Return a JSON object with one result per message, echoing its id:
+	{
+		"results": [
+			{
+				"id": "message id",
+				"is_valid": true/false,
+				"leave_type": "WFH/IN_OFFICE/FULL_DAY/HALF_DAY/LATE_ARRIVAL/EARLY_DEPARTURE/APPOINTMENT/PARENTAL/LOSS_OF_PAY",
+				"start_time": "2024-03-01T09:00:00+05:30",
+				"end_time": "2024-03-01T18:00:00+05:30",
+				"duration": "9 hours",
+				"reason": "reason for leave, or empty if none is given",
+				"sick": false,
+				"confidence": 0.95,
+				"phrases": ["wfh tomorrow"],
+				"defaults": ["no time given, used the work day"],
+				"error": "why the message could not be parsed"
+			}
+		]
+	}`
+
+	var batchResp batchResponse
+	_, err = s.completeJSON(
+		ctx,
+		openai.ChatCompletionRequest{
+			Model: "gpt-4o-mini",
+			Messages: []openai.ChatCompletionMessage{
+				{
+					Role:    openai.ChatMessageRoleSystem,
+					Content: "You are a date-aware JSON response bot. Never use markdown." + untrustedRule,
+				},
+				{
+					Role:    openai.ChatMessageRoleUser,
+					Content: prompt,
+				},
+			},
+			ResponseFormat: &openai.ChatCompletionResponseFormat{
+				Type: openai.ChatCompletionResponseFormatTypeJSONObject,
+			},
+			Temperature: 0.1,
+		},
+		&batchResp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[string]*LeaveResponse, len(batchResp.Results))
+	for i := range batchResp.Results {
+		result := batchResp.Results[i]
+		loc, ok := locations[result.ID]
+		if !ok {
+			s.log.Printf("Ignoring batch result for unknown id %q", result.ID)
+			continue
+		}
+
+		leaveResp := result.LeaveResponse
+		if leaveResp.IsValid {
+			if LooksLikeInjection(texts[result.ID]) {
+				leaveResp.IsValid = false
+				leaveResp.Suspicious = true
+				leaveResp.Error = "Message reads like instructions to the parser, skipped"
+			} else if leaveResp.LeaveType == "" {
+				leaveResp.IsValid = false
+				leaveResp.Error = "leave_type is required for valid requests"
+			} else if leaveResp.EndTime.Before(leaveResp.StartTime) {
+				leaveResp.IsValid = false
+				leaveResp.Error = "End time must be after start time"
+			}
+		}
+		leaveResp.StartTime = leaveResp.StartTime.In(loc)
+		leaveResp.EndTime = leaveResp.EndTime.In(loc)
+		results[result.ID] = &leaveResp
+	}
+
+	if missing := len(messages) - len(results); missing > 0 {
+		s.log.Printf("Batch of %d returned %d results (%d missing)", len(messages), len(results), missing)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,249 @@
+// Package calendar keeps events on a Google Calendar through the Calendar
+// REST API, authorizing with an OAuth refresh token.
+package calendar
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTokenURL = "https://oauth2.googleapis.com/token"
+	defaultAPIURL   = "https://www.googleapis.com/calendar/v3"
+)
+
+// Config is what the client needs: an OAuth client and a refresh token
+// granted the calendar.events scope, and the calendar to write to.
+type Config struct {
+	ClientID     string
+	ClientSecret string
+	RefreshToken string
+	CalendarID   string
+}
+
+// TokenSource exchanges a refresh token for access tokens, keeping each
+// until shortly before it expires.
+type TokenSource struct {
+	config   Config
+	tokenURL string
+	client   *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// NewTokenSource returns a token source for config.
+func NewTokenSource(config Config, client *http.Client) *TokenSource {
+	return &TokenSource{config: config, tokenURL: defaultTokenURL, client: client}
+}
+
+// Token returns a valid access token, refreshing it when needed.
+func (s *TokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Leave a minute for clock skew and the request itself
+	if s.token != "" && time.Now().Add(time.Minute).Before(s.expires) {
+		return s.token, nil
+	}
+
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"client_id":     {s.config.ClientID},
+		"client_secret": {s.config.ClientSecret},
+		"refresh_token": {s.config.RefreshToken},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error refreshing the Google token: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		AccessToken      string `json:"access_token"`
+		ExpiresIn        int    `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("error reading the Google token response (%s): %v", resp.Status, err)
+	}
+	if resp.StatusCode != http.StatusOK || body.AccessToken == "" {
+		return "", fmt.Errorf("Google refused to refresh the token: %s %s", body.Error, body.ErrorDescription)
+	}
+
+	s.token = body.AccessToken
+	s.expires = time.Now().Add(time.Duration(body.ExpiresIn) * time.Second)
+	return s.token, nil
+}
+
+// Event is what the bot puts on the calendar. An all-day event covers the
+// dates of Start through End; otherwise Start and End are exact times.
+type Event struct {
+	Summary     string
+	Description string
+	Start       time.Time
+	End         time.Time
+	AllDay      bool
+}
+
+type eventTime struct {
+	Date     string `json:"date,omitempty"`
+	DateTime string `json:"dateTime,omitempty"`
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+type eventBody struct {
+	Summary      string    `json:"summary"`
+	Description  string    `json:"description,omitempty"`
+	Start        eventTime `json:"start"`
+	End          eventTime `json:"end"`
+	Transparency string    `json:"transparency"`
+}
+
+func (e Event) body() eventBody {
+	b := eventBody{
+		Summary:     e.Summary,
+		Description: e.Description,
+		// Others' absences shouldn't block the calendar's readers' time
+		Transparency: "transparent",
+	}
+	if e.AllDay {
+		// The end date of an all-day event is exclusive
+		b.Start = eventTime{Date: e.Start.Format("2006-01-02")}
+		b.End = eventTime{Date: e.End.AddDate(0, 0, 1).Format("2006-01-02")}
+	} else {
+		b.Start = eventTime{DateTime: e.Start.Format(time.RFC3339), TimeZone: e.Start.Location().String()}
+		b.End = eventTime{DateTime: e.End.Format(time.RFC3339), TimeZone: e.End.Location().String()}
+	}
+	// Only IANA names are valid time zones
+	if b.Start.TimeZone == "Local" || b.Start.TimeZone == "UTC" {
+		b.Start.TimeZone, b.End.TimeZone = "", ""
+	}
+	return b
+}
+
+// Client creates, updates and deletes events on one calendar.
+type Client struct {
+	calendarID string
+	apiURL     string
+	tokens     *TokenSource
+	client     *http.Client
+}
+
+// NewClient returns a client for the calendar in config.
+func NewClient(config Config) *Client {
+	client := &http.Client{Timeout: 15 * time.Second}
+	return &Client{
+		calendarID: config.CalendarID,
+		apiURL:     defaultAPIURL,
+		tokens:     NewTokenSource(config, client),
+		client:     client,
+	}
+}
+
+// Insert creates an event and returns its ID.
+func (c *Client) Insert(ctx context.Context, event Event) (string, error) {
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := c.do(ctx, http.MethodPost, "", event.body(), &created); err != nil {
+		return "", err
+	}
+	return created.ID, nil
+}
+
+// Update replaces the event with the given ID.
+func (c *Client) Update(ctx context.Context, eventID string, event Event) error {
+	return c.do(ctx, http.MethodPut, eventID, event.body(), nil)
+}
+
+// Delete removes the event with the given ID. An event that's already gone
+// isn't an error.
+func (c *Client) Delete(ctx context.Context, eventID string) error {
+	if err := c.do(ctx, http.MethodDelete, eventID, nil, nil); err != nil && !IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// APIError is a non-2xx answer from the Calendar API.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("Google Calendar returned %d: %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound reports whether err says the event doesn't exist, e.g. because
+// someone deleted it on the calendar.
+func IsNotFound(err error) bool {
+	apiErr, ok := err.(*APIError)
+	return ok && (apiErr.StatusCode == http.StatusNotFound || apiErr.StatusCode == http.StatusGone)
+}
+
+func (c *Client) do(ctx context.Context, method, eventID string, in, out interface{}) error {
+	token, err := c.tokens.Token(ctx)
+	if err != nil {
+		return err
+	}
+
+	endpoint := c.apiURL + "/calendars/" + url.PathEscape(c.calendarID) + "/events"
+	if eventID != "" {
+		endpoint += "/" + url.PathEscape(eventID)
+	}
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var failure struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&failure)
+		return &APIError{StatusCode: resp.StatusCode, Message: failure.Error.Message}
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule is a standard five-field cron expression: minute, hour, day
+// of month, month and day of week (0 or 7 is Sunday). Fields take "*",
+// numbers, ranges ("1-5"), lists ("1,15") and steps ("*/15", "9-17/2").
+// As in cron, when both day fields are restricted a time matches either.
+type CronSchedule struct {
+	spec     string
+	minutes  [60]bool
+	hours    [24]bool
+	days     [32]bool
+	months   [13]bool
+	weekdays [7]bool
+	anyDay   bool // day of month is "*"
+	anyWeek  bool // day of week is "*"
+}
+
+// ParseCron parses a cron expression such as "0 9 * * 1-5".
+func ParseCron(spec string) (*CronSchedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid schedule %q: want 5 fields (minute hour day month weekday)", spec)
+	}
+
+	s := &CronSchedule{spec: strings.Join(fields, " "), anyDay: fields[2] == "*", anyWeek: fields[4] == "*"}
+	for _, f := range []struct {
+		name     string
+		value    string
+		min, max int
+		set      func(int)
+	}{
+		{"minute", fields[0], 0, 59, func(n int) { s.minutes[n] = true }},
+		{"hour", fields[1], 0, 23, func(n int) { s.hours[n] = true }},
+		{"day", fields[2], 1, 31, func(n int) { s.days[n] = true }},
+		{"month", fields[3], 1, 12, func(n int) { s.months[n] = true }},
+		{"weekday", fields[4], 0, 7, func(n int) { s.weekdays[n%7] = true }},
+	} {
+		if err := parseCronField(f.value, f.min, f.max, f.set); err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %s %v", spec, f.name, err)
+		}
+	}
+	return s, nil
+}
+
+func parseCronField(value string, min, max int, set func(int)) error {
+	for _, part := range strings.Split(value, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n < 1 {
+				return fmt.Errorf("has an invalid step %q", stepPart)
+			}
+			step = n
+		}
+
+		lo, hi := min, max
+		if rangePart != "*" {
+			from, to, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = strconv.Atoi(from); err != nil {
+				return fmt.Errorf("has an invalid value %q", part)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(to); err != nil {
+					return fmt.Errorf("has an invalid value %q", part)
+				}
+			} else if hasStep {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return fmt.Errorf("%q is out of range %d-%d", part, min, max)
+		}
+
+		for n := lo; n <= hi; n += step {
+			set(n)
+		}
+	}
+	return nil
+}
+
+// Matches reports whether the schedule fires in t's minute, read in t's
+// location.
+func (s *CronSchedule) Matches(t time.Time) bool {
+	if !s.minutes[t.Minute()] || !s.hours[t.Hour()] || !s.months[t.Month()] {
+		return false
+	}
+	day, weekday := s.days[t.Day()], s.weekdays[t.Weekday()]
+	switch {
+	case s.anyDay && s.anyWeek:
+		return true
+	case s.anyDay:
+		return weekday
+	case s.anyWeek:
+		return day
+	}
+	return day || weekday
+}
+
+func (s *CronSchedule) String() string {
+	return s.spec
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultDeskBookingPayload is sent when no payload template is configured.
+const DefaultDeskBookingPayload = `{"username":{{json .Username}},"email":{{json .Email}},"date":{{json .Date}},"location":{{json .Location}}}`
+
+// DeskBooking is the data available to desk-booking URL and payload
+// templates.
+type DeskBooking struct {
+	Username string
+	Email    string
+	Date     string // YYYY-MM-DD in the office's timezone
+	Location string // office code, empty if the user has none
+	LeaveID  int64
+}
+
+// DeskBookingClient reserves and releases desks in an external booking system
+// through generic webhooks. URLs and payloads are text/template templates
+// rendered with a DeskBooking; the `json` function quotes a value as a JSON
+// string.
+type DeskBookingClient struct {
+	bookURL       *template.Template
+	bookPayload   *template.Template
+	cancelURL     *template.Template
+	cancelPayload *template.Template
+	cancelMethod  string
+	authHeader    string
+	client        *http.Client
+}
+
+// NewDeskBookingClient parses the templates. When cancelURL is empty,
+// cancellations are sent as a DELETE to the booking URL.
+func NewDeskBookingClient(bookURL, bookPayload, cancelURL, cancelPayload, authHeader string) (*DeskBookingClient, error) {
+	if bookPayload == "" {
+		bookPayload = DefaultDeskBookingPayload
+	}
+	if cancelPayload == "" {
+		cancelPayload = bookPayload
+	}
+	cancelMethod := http.MethodPost
+	if cancelURL == "" {
+		cancelURL = bookURL
+		cancelMethod = http.MethodDelete
+	}
+
+	c := &DeskBookingClient{
+		cancelMethod: cancelMethod,
+		authHeader:   authHeader,
+		client:       &http.Client{Timeout: 10 * time.Second},
+	}
+
+	var err error
+	if c.bookURL, err = parseDeskTemplate("book URL", bookURL); err != nil {
+		return nil, err
+	}
+	if c.bookPayload, err = parseDeskTemplate("book payload", bookPayload); err != nil {
+		return nil, err
+	}
+	if c.cancelURL, err = parseDeskTemplate("cancel URL", cancelURL); err != nil {
+		return nil, err
+	}
+	if c.cancelPayload, err = parseDeskTemplate("cancel payload", cancelPayload); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func parseDeskTemplate(name, text string) (*template.Template, error) {
+	t, err := template.New(name).Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid desk booking %s template: %v", name, err)
+	}
+	return t, nil
+}
+
+// Book reserves a desk for the booking's day.
+func (c *DeskBookingClient) Book(ctx context.Context, b DeskBooking) error {
+	return c.send(ctx, http.MethodPost, c.bookURL, c.bookPayload, b)
+}
+
+// Cancel releases the desk reserved for the booking's day.
+func (c *DeskBookingClient) Cancel(ctx context.Context, b DeskBooking) error {
+	return c.send(ctx, c.cancelMethod, c.cancelURL, c.cancelPayload, b)
+}
+
+func (c *DeskBookingClient) send(ctx context.Context, method string, urlTmpl, payloadTmpl *template.Template, b DeskBooking) error {
+	var url, body bytes.Buffer
+	if err := urlTmpl.Execute(&url, b); err != nil {
+		return err
+	}
+	if err := payloadTmpl.Execute(&body, b); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSpace(url.String()), &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.authHeader != "" {
+		req.Header.Set("Authorization", c.authHeader)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("desk booking system returned %s", resp.Status)
+	}
+	return nil
+}
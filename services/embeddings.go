@@ -0,0 +1,57 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// EmbeddingModel embeds leave texts and searches. Its vectors have
+// EmbeddingDimensions entries, the size of the leave_embeddings column.
+const (
+	EmbeddingModel      = "text-embedding-ada-002"
+	EmbeddingDimensions = 1536
+)
+
+// Embed returns an embedding of each text, in order.
+func (s *OpenAIService) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	// Newlines make embeddings worse, per OpenAI
+	input := make([]string, len(texts))
+	for i, text := range texts {
+		input[i] = strings.Join(strings.Fields(text), " ")
+	}
+
+	resp, err := s.client.CreateEmbeddings(ctx, openai.EmbeddingRequestStrings{
+		Input: input,
+		Model: openai.AdaEmbeddingV2,
+	})
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			s.log.Printf("Embedding request timed out after %s", s.timeout)
+			return nil, ErrTimeout
+		}
+		return nil, fmt.Errorf("OpenAI API error: %v", err)
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, data := range resp.Data {
+		if data.Index >= 0 && data.Index < len(vectors) {
+			vectors[data.Index] = data.Embedding
+		}
+	}
+	for i, vector := range vectors {
+		if len(vector) != EmbeddingDimensions {
+			return nil, fmt.Errorf("OpenAI API error: got %d dimensions for input %d, want %d", len(vector), i, EmbeddingDimensions)
+		}
+	}
+	return vectors, nil
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// OpenItem is a piece of unfinished work owned by the user, e.g. an open
+// ticket or pull request.
+type OpenItem struct {
+	Title  string `json:"title"`
+	URL    string `json:"url,omitempty"`
+	Status string `json:"status,omitempty"`
+	Source string `json:"source,omitempty"`
+}
+
+// OpenItemsSource looks up a user's open work in an external tool.
+type OpenItemsSource interface {
+	Name() string
+	OpenItems(ctx context.Context, email string) ([]OpenItem, error)
+}
+
+// HTTPOpenItemsSource fetches open items from an integration endpoint with
+// GET {url}?email=..., which must return a JSON array of OpenItem.
+type HTTPOpenItemsSource struct {
+	url        string
+	authHeader string
+	client     *http.Client
+}
+
+func NewHTTPOpenItemsSource(url, authHeader string) *HTTPOpenItemsSource {
+	return &HTTPOpenItemsSource{
+		url:        url,
+		authHeader: authHeader,
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *HTTPOpenItemsSource) Name() string {
+	return s.url
+}
+
+func (s *HTTPOpenItemsSource) OpenItems(ctx context.Context, email string) ([]OpenItem, error) {
+	u, err := url.Parse(s.url)
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	q.Set("email", email)
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if s.authHeader != "" {
+		req.Header.Set("Authorization", s.authHeader)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("open items endpoint returned %s", resp.Status)
+	}
+
+	var items []OpenItem
+	if err := json.NewDecoder(resp.Body).Decode(&items); err != nil {
+		return nil, fmt.Errorf("invalid open items response: %v", err)
+	}
+	return items, nil
+}
+
+// HandoverInput is what the handover checklist is drafted from.
+type HandoverInput struct {
+	Username  string
+	StartDate time.Time
+	EndDate   time.Time
+	Reason    string
+	Messages  []string // recent Slack messages by the user, oldest first
+	OpenItems []OpenItem
+}
+
+// GenerateHandoverChecklist drafts a handover checklist in Slack mrkdwn for
+// the user to edit before sharing.
+func (s *OpenAIService) GenerateHandoverChecklist(ctx context.Context, in HandoverInput) (string, error) {
+	messagesJSON, err := json.Marshal(in.Messages)
+	if err != nil {
+		return "", err
+	}
+	itemsJSON, err := json.Marshal(in.OpenItems)
+	if err != nil {
+		return "", err
+	}
+
+	prompt := `Draft a handover checklist for a colleague going on leave.
+
+	Person: ` + in.Username + `
+	Away: ` + in.StartDate.Format("Monday, January 2") + ` to ` + in.EndDate.Format("Monday, January 2, 2006") + `
+	Reason: ` + in.Reason + `
+
+	Their recent Slack messages (JSON array, oldest first): ` + string(messagesJSON) + `
+
+	Their open work items from other tools (JSON array): ` + string(itemsJSON) + `
+
+	Rules:
+	- Only include work that appears in the messages or open items, never invent tasks
+	- Group items under short bold headings (e.g. *In progress*, *Waiting on others*, *Recurring duties*)
+	- Each item is a Slack checkbox line starting with "☐ ", naming what to hand over and a blank "→ owner: ___"
+	- Include links from open items where available, formatted as <url|title>
+	- End with a short *Before you go* section: out-of-office message, calendar, access others may need
+	- Use Slack mrkdwn only, no markdown headers or code blocks
+	- Keep it under 25 lines`
+
+	content, err := s.complete(
+		ctx,
+		openai.ChatCompletionRequest{
+			Model: "gpt-4o-mini",
+			Messages: []openai.ChatCompletionMessage{
+				{
+					Role:    openai.ChatMessageRoleSystem,
+					Content: "You help people prepare concise, practical handover notes. Reply with the checklist only.",
+				},
+				{
+					Role:    openai.ChatMessageRoleUser,
+					Content: prompt,
+				},
+			},
+			Temperature: 0.3,
+		},
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Trim(content, "`"), nil
+}
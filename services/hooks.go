@@ -0,0 +1,183 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"plugin"
+	"strings"
+	"sync"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+)
+
+// Hook points, in the order a record meets them.
+const (
+	// HookPreValidate runs before a new record is checked and saved. A hook
+	// can refuse the record.
+	HookPreValidate = "pre_validate"
+	// HookPostCreate runs after a record is saved. Its result is ignored.
+	HookPostCreate = "post_create"
+	// HookPreNotify runs before a notification goes out. A hook can drop it
+	// or replace its text.
+	HookPreNotify = "pre_notify"
+)
+
+// IsHookPoint reports whether point is one of the hook points.
+func IsHookPoint(point string) bool {
+	return point == HookPreValidate || point == HookPostCreate || point == HookPreNotify
+}
+
+// HookEvent is what a hook is called with. Leave is set for record hooks
+// and Notification for HookPreNotify.
+type HookEvent struct {
+	Hook         string        `json:"hook"`
+	Actor        string        `json:"actor,omitempty"`
+	Leave        *models.Leave `json:"leave,omitempty"`
+	Notification *Notification `json:"notification,omitempty"`
+}
+
+// HookResult is what a hook decided. A nil result or the zero value lets
+// things go ahead unchanged.
+type HookResult struct {
+	Reject   bool   `json:"reject,omitempty"`   // HookPreValidate: refuse the record
+	Reason   string `json:"reason,omitempty"`   // why it was refused, shown to the user
+	Suppress bool   `json:"suppress,omitempty"` // HookPreNotify: don't send it
+	Text     string `json:"text,omitempty"`     // HookPreNotify: send this text instead
+}
+
+// HookFunc is custom logic run at a hook point.
+type HookFunc func(ctx context.Context, event HookEvent) (*HookResult, error)
+
+type namedHook struct {
+	name string
+	fn   HookFunc
+}
+
+// HookRegistry holds the hooks registered at each point, from Go code,
+// plugins or webhooks.
+type HookRegistry struct {
+	mu    sync.RWMutex
+	hooks map[string][]namedHook
+	log   *log.Logger
+}
+
+func NewHookRegistry() *HookRegistry {
+	return &HookRegistry{
+		hooks: make(map[string][]namedHook),
+		log:   log.New(os.Stdout, "🪝 HOOK    | ", log.Ltime),
+	}
+}
+
+// Register adds fn at point. Hooks at a point run in the order they were
+// registered.
+func (r *HookRegistry) Register(point, name string, fn HookFunc) error {
+	if !IsHookPoint(point) {
+		return fmt.Errorf("unknown hook point %q", point)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hooks[point] = append(r.hooks[point], namedHook{name: name, fn: fn})
+	r.log.Printf("Registered %s at %s", name, point)
+	return nil
+}
+
+// Run calls every hook at event.Hook. A hook that fails is logged and
+// skipped, so a broken hook can't stop leave from being recorded. The
+// first rejection ends the run; replaced notification text is passed on to
+// the hooks after.
+func (r *HookRegistry) Run(ctx context.Context, event HookEvent) HookResult {
+	r.mu.RLock()
+	hooks := r.hooks[event.Hook]
+	r.mu.RUnlock()
+
+	var result HookResult
+	for _, hook := range hooks {
+		hookResult, err := hook.fn(ctx, event)
+		if err != nil {
+			r.log.Printf("%s hook %s failed: %v", event.Hook, hook.name, err)
+			continue
+		}
+		if hookResult == nil {
+			continue
+		}
+		if hookResult.Reject {
+			result.Reject = true
+			result.Reason = hookResult.Reason
+			return result
+		}
+		if hookResult.Suppress {
+			result.Suppress = true
+		}
+		if hookResult.Text != "" && event.Notification != nil {
+			result.Text = hookResult.Text
+			n := *event.Notification
+			n.Text = hookResult.Text
+			event.Notification = &n
+		}
+	}
+	return result
+}
+
+// WebhookHook POSTs the event as JSON to url and reads a HookResult from
+// the response, if it has a body.
+func WebhookHook(url string) HookFunc {
+	client := &http.Client{Timeout: 5 * time.Second}
+	return func(ctx context.Context, event HookEvent) (*HookResult, error) {
+		body, err := json.Marshal(event)
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			return nil, fmt.Errorf("webhook returned %s", resp.Status)
+		}
+
+		data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(string(data)) == "" {
+			return nil, nil
+		}
+		var result HookResult
+		if err := json.Unmarshal(data, &result); err != nil {
+			return nil, fmt.Errorf("invalid hook response: %v", err)
+		}
+		return &result, nil
+	}
+}
+
+// LoadHookPlugin opens a Go plugin built with -buildmode=plugin against this
+// module and calls its exported RegisterHooks(*services.HookRegistry) error.
+func LoadHookPlugin(path string, registry *HookRegistry) error {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return err
+	}
+	symbol, err := p.Lookup("RegisterHooks")
+	if err != nil {
+		return err
+	}
+	register, ok := symbol.(func(*HookRegistry) error)
+	if !ok {
+		return fmt.Errorf("RegisterHooks in %s has type %T, want func(*services.HookRegistry) error", path, symbol)
+	}
+	return register(registry)
+}
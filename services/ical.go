@@ -0,0 +1,85 @@
+package services
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ICalEvent is one event of an iCalendar feed. An all-day event covers the
+// dates of Start through End; otherwise Start and End are exact times.
+type ICalEvent struct {
+	UID         string
+	Summary     string
+	Description string
+	Start       time.Time
+	End         time.Time
+	AllDay      bool
+	Updated     time.Time
+}
+
+// WriteICal writes events as an iCalendar (RFC 5545) feed named name.
+func WriteICal(w io.Writer, name string, events []ICalEvent) error {
+	b := bufio.NewWriter(w)
+	line := func(format string, args ...interface{}) {
+		b.WriteString(foldICalLine(fmt.Sprintf(format, args...)))
+		b.WriteString("\r\n")
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//latebot//leave schedule//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:%s", escapeICalText(name))
+	for _, event := range events {
+		line("BEGIN:VEVENT")
+		line("UID:%s", event.UID)
+		line("DTSTAMP:%s", event.Updated.UTC().Format("20060102T150405Z"))
+		if event.AllDay {
+			// The end date of an all-day event is exclusive
+			line("DTSTART;VALUE=DATE:%s", event.Start.Format("20060102"))
+			line("DTEND;VALUE=DATE:%s", event.End.AddDate(0, 0, 1).Format("20060102"))
+		} else {
+			line("DTSTART:%s", event.Start.UTC().Format("20060102T150405Z"))
+			line("DTEND:%s", event.End.UTC().Format("20060102T150405Z"))
+		}
+		line("SUMMARY:%s", escapeICalText(event.Summary))
+		if event.Description != "" {
+			line("DESCRIPTION:%s", escapeICalText(event.Description))
+		}
+		// Someone else's absence doesn't make the subscriber busy
+		line("TRANSP:TRANSPARENT")
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return b.Flush()
+}
+
+var icalTextEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escapeICalText(text string) string {
+	return icalTextEscaper.Replace(text)
+}
+
+// foldICalLine breaks a content line into lines of at most 75 octets, each
+// continuation starting with a space, without splitting a UTF-8 character.
+func foldICalLine(line string) string {
+	if len(line) <= 75 {
+		return line
+	}
+	var b strings.Builder
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > 75 {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	return b.String()
+}
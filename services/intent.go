@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Message intents returned by ClassifyIntent
+const (
+	IntentLeaveRequest = "LEAVE_REQUEST"
+	IntentCancellation = "CANCELLATION"
+	IntentAdjustment   = "ADJUSTMENT"
+	IntentQuery        = "QUERY"
+	IntentUnrelated    = "UNRELATED"
+)
+
+type IntentResponse struct {
+	Intent string `json:"intent"`
+}
+
+type CancellationResponse struct {
+	IsValid   bool   `json:"is_valid"`
+	Date      string `json:"date"`                 // YYYY-MM-DD of the leave being cancelled
+	LeaveType string `json:"leave_type,omitempty"` // Optional, narrows the match
+	Error     string `json:"error,omitempty"`
+}
+
+type AdjustmentResponse struct {
+	IsValid    bool   `json:"is_valid"`
+	Date       string `json:"date"`                 // YYYY-MM-DD of a day inside the leave being adjusted
+	NewEndDate string `json:"new_end_date"`         // YYYY-MM-DD of the leave's new last day
+	LeaveType  string `json:"leave_type,omitempty"` // Optional, narrows the match
+	Error      string `json:"error,omitempty"`
+}
+
+// ClassifyIntent is the first, cheap stage of message parsing. It only decides
+// what the message is about so the matching extraction prompt can be used.
+func (s *OpenAIService) ClassifyIntent(ctx context.Context, text string) (string, error) {
+	prompt := `Classify this Slack message from a workplace attendance channel.
+
+	Message: ` + quoteMessage(text) + `
+
+	Intents:
+	- "LEAVE_REQUEST": the author announces leave, WFH, an office day, arriving late or leaving early
+	- "CANCELLATION": the author cancels or withdraws a leave/WFH they announced earlier
+	- "ADJUSTMENT": the author shortens or extends a leave/WFH they're on or announced earlier, e.g. "back early, cutting my leave short" or "extending till Wednesday"
+	- "QUERY": the author asks a question about who is out, leave counts or statistics
+	- "UNRELATED": anything else
+
+	Return a JSON object only: {"intent": "LEAVE_REQUEST/CANCELLATION/ADJUSTMENT/QUERY/UNRELATED"}`
+
+	var intentResp IntentResponse
+	_, err := s.completeJSON(
+		ctx,
+		openai.ChatCompletionRequest{
+			Model: "gpt-4o-mini",
+			Messages: []openai.ChatCompletionMessage{
+				{
+					Role:    openai.ChatMessageRoleSystem,
+					Content: "You are an intent classifier. Reply with a single JSON object. Never use markdown." + untrustedRule,
+				},
+				{
+					Role:    openai.ChatMessageRoleUser,
+					Content: prompt,
+				},
+			},
+			Temperature: 0,
+			MaxTokens:   20,
+		},
+		&intentResp,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	switch intentResp.Intent {
+	case IntentLeaveRequest, IntentCancellation, IntentAdjustment, IntentQuery, IntentUnrelated:
+		return intentResp.Intent, nil
+	}
+	s.log.Printf("Unknown intent %q, treating as unrelated", intentResp.Intent)
+	return IntentUnrelated, nil
+}
+
+// ParseCancellation extracts which leave a cancellation message refers to,
+// resolving relative days in the sender's timezone.
+func (s *OpenAIService) ParseCancellation(ctx context.Context, text string, loc *time.Location) (*CancellationResponse, error) {
+	if loc == nil {
+		loc = DefaultRegion().Timezone
+	}
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+
+	prompt := `This message cancels a previously announced leave. Work out which day it refers to. Return a JSON object only.
+
+	Message: ` + quoteMessage(text) + `
+
+	Current context:
+	- Today's date: ` + today.Format("2006-01-02") + ` (` + today.Weekday().String() + `)
+	- Tomorrow's date: ` + today.AddDate(0, 0, 1).Format("2006-01-02") + `
+	- Timezone: ` + loc.String() + `
+
+	Rules:
+	- "date" is the day of the leave being cancelled, formatted YYYY-MM-DD
+	- "leave_type" is one of WFH/IN_OFFICE/FULL_DAY/HALF_DAY/LATE_ARRIVAL/EARLY_DEPARTURE/APPOINTMENT/PARENTAL/LOSS_OF_PAY if the message says which, otherwise empty
+	- If no day can be determined, set is_valid to false and explain in error
+
+	Return a JSON object with these fields:
+	{
+		"is_valid": true/false,
+		"date": "2024-03-01",
+		"leave_type": "",
+		"error": "error message if the day is unclear"
+	}`
+
+	var cancelResp CancellationResponse
+	_, err := s.completeJSON(
+		ctx,
+		openai.ChatCompletionRequest{
+			Model: "gpt-4o-mini",
+			Messages: []openai.ChatCompletionMessage{
+				{
+					Role:    openai.ChatMessageRoleSystem,
+					Content: "You are a date-aware JSON response bot. Use the current year for all dates. Never use markdown." + untrustedRule,
+				},
+				{
+					Role:    openai.ChatMessageRoleUser,
+					Content: prompt,
+				},
+			},
+			Temperature: 0.1,
+		},
+		&cancelResp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cancelResp, nil
+}
+
+// ParseAdjustment extracts which leave a shorten/extend message refers to and
+// its new last day, resolving relative days in the sender's timezone.
+func (s *OpenAIService) ParseAdjustment(ctx context.Context, text string, loc *time.Location) (*AdjustmentResponse, error) {
+	if loc == nil {
+		loc = DefaultRegion().Timezone
+	}
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+
+	prompt := `This message shortens or extends a leave the author is on or announced earlier. Work out which leave it refers to and its new last day. Return a JSON object only.
+
+	Message: ` + quoteMessage(text) + `
+
+	Current context:
+	- Today's date: ` + today.Format("2006-01-02") + ` (` + today.Weekday().String() + `)
+	- Tomorrow's date: ` + today.AddDate(0, 0, 1).Format("2006-01-02") + `
+	- Timezone: ` + loc.String() + `
+
+	Rules:
+	- "date" is any day inside the leave being changed, formatted YYYY-MM-DD; use today's date unless the message names the leave's dates
+	- "new_end_date" is the last day the leave should now cover, formatted YYYY-MM-DD
+	- "Back early"/"back tomorrow" means the leave now ends the day before the author is back; "back today" means it ended yesterday
+	- "Extending till Wednesday" means the leave now ends on Wednesday
+	- "leave_type" is one of WFH/FULL_DAY/PARENTAL/LOSS_OF_PAY if the message says which, otherwise empty
+	- If the new last day can't be determined, set is_valid to false and explain in error
+
+	Return a JSON object with these fields:
+	{
+		"is_valid": true/false,
+		"date": "2024-03-01",
+		"new_end_date": "2024-03-04",
+		"leave_type": "",
+		"error": "error message if the change is unclear"
+	}`
+
+	var adjustResp AdjustmentResponse
+	_, err := s.completeJSON(
+		ctx,
+		openai.ChatCompletionRequest{
+			Model: "gpt-4o-mini",
+			Messages: []openai.ChatCompletionMessage{
+				{
+					Role:    openai.ChatMessageRoleSystem,
+					Content: "You are a date-aware JSON response bot. Use the current year for all dates. Never use markdown." + untrustedRule,
+				},
+				{
+					Role:    openai.ChatMessageRoleUser,
+					Content: prompt,
+				},
+			},
+			Temperature: 0.1,
+		},
+		&adjustResp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &adjustResp, nil
+}
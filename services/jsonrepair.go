@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// RepairStats counts how the model's JSON replies were decoded since start.
+type RepairStats struct {
+	Valid    int64 `json:"valid"`    // decoded as returned
+	Repaired int64 `json:"repaired"` // decoded after stripping prose or trailing commas
+	Reasked  int64 `json:"reasked"`  // decoded after asking the model to fix its reply
+	Failed   int64 `json:"failed"`   // never decoded
+}
+
+// RepairRate is the share of replies that needed any repair, including those
+// that couldn't be repaired.
+func (r RepairStats) RepairRate() float64 {
+	total := r.Valid + r.Repaired + r.Reasked + r.Failed
+	if total == 0 {
+		return 0
+	}
+	return float64(total-r.Valid) / float64(total)
+}
+
+type repairCounters struct {
+	valid, repaired, reasked, failed atomic.Int64
+}
+
+// RepairStats returns the JSON repair counts so far.
+func (s *OpenAIService) RepairStats() RepairStats {
+	return RepairStats{
+		Valid:    s.repairs.valid.Load(),
+		Repaired: s.repairs.repaired.Load(),
+		Reasked:  s.repairs.reasked.Load(),
+		Failed:   s.repairs.failed.Load(),
+	}
+}
+
+// ErrInvalidJSON is returned when the model's reply couldn't be decoded,
+// even after asking it again.
+var ErrInvalidJSON = errors.New("JSON parse error")
+
+// completeJSON sends req and decodes the reply into v. A reply that isn't
+// valid JSON is repaired locally first; failing that, the model is shown the
+// parse error and asked once to re-emit it. It returns the reply that was
+// decoded.
+func (s *OpenAIService) completeJSON(ctx context.Context, req openai.ChatCompletionRequest, v interface{}) (string, error) {
+	content, err := s.complete(ctx, req)
+	if err != nil {
+		return "", err
+	}
+
+	parseErr := json.Unmarshal([]byte(content), v)
+	if parseErr == nil {
+		s.repairs.valid.Add(1)
+		return content, nil
+	}
+	if repaired := repairJSON(content); json.Unmarshal([]byte(repaired), v) == nil {
+		s.repairs.repaired.Add(1)
+		s.log.Printf("Repaired invalid JSON reply (%v)", parseErr)
+		return repaired, nil
+	}
+
+	s.log.Printf("Invalid JSON reply (%v), asking for it again", parseErr)
+	retry := req
+	retry.Messages = append(append([]openai.ChatCompletionMessage{}, req.Messages...),
+		openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: content},
+		openai.ChatCompletionMessage{
+			Role: openai.ChatMessageRoleUser,
+			Content: fmt.Sprintf("That reply isn't valid JSON: %v. Reply again with only the corrected JSON, "+
+				"no prose or markdown.", parseErr),
+		},
+	)
+	// A short MaxTokens may be what cut the first reply off
+	retry.MaxTokens = 0
+	again, err := s.complete(ctx, retry)
+	if err != nil {
+		s.repairs.failed.Add(1)
+		return "", err
+	}
+	again = repairJSON(again)
+	if err := json.Unmarshal([]byte(again), v); err != nil {
+		s.repairs.failed.Add(1)
+		return "", fmt.Errorf("%w: %v\nResponse: %s", ErrInvalidJSON, parseErr, content)
+	}
+	s.repairs.reasked.Add(1)
+	return again, nil
+}
+
+// repairJSON fixes the usual ways a model's JSON goes wrong: markdown fences,
+// prose around the object and trailing commas.
+func repairJSON(content string) string {
+	content = strings.ReplaceAll(content, "```json", "")
+	content = strings.ReplaceAll(content, "```", "")
+
+	start := strings.IndexAny(content, "{[")
+	end := strings.LastIndexAny(content, "}]")
+	if start >= 0 && end > start {
+		content = content[start : end+1]
+	}
+
+	return stripTrailingCommas(content)
+}
+
+// stripTrailingCommas drops commas directly before a closing brace or
+// bracket, leaving string contents alone.
+func stripTrailingCommas(content string) string {
+	var b strings.Builder
+	inString, escaped := false, false
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case inString:
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == ',':
+			next := strings.TrimLeft(content[i+1:], " \t\r\n")
+			if next != "" && (next[0] == '}' || next[0] == ']') {
+				continue
+			}
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// Recipient identifies a person on every channel we may reach them on. A
+// notifier skips recipients it has no address for.
+type Recipient struct {
+	Name    string `json:"name,omitempty"`
+	SlackID string `json:"slack_id,omitempty"`
+	Email   string `json:"email,omitempty"`
+}
+
+type Notification struct {
+	To      Recipient `json:"to"`
+	Subject string    `json:"subject"`
+	Text    string    `json:"text"`
+}
+
+// Notifier delivers a notification over one channel (Slack DM, email, ...).
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, n Notification) error
+}
+
+// MultiNotifier fans a notification out to every configured channel so people
+// who are away from Slack still hear about it.
+type MultiNotifier struct {
+	notifiers []Notifier
+	log       *log.Logger
+}
+
+func NewMultiNotifier(notifiers ...Notifier) *MultiNotifier {
+	return &MultiNotifier{
+		notifiers: notifiers,
+		log:       log.New(os.Stdout, "📣 NOTIFY  | ", log.Ltime),
+	}
+}
+
+func (m *MultiNotifier) Name() string {
+	return "multi"
+}
+
+// Notify succeeds if at least one channel delivered the notification.
+func (m *MultiNotifier) Notify(ctx context.Context, n Notification) error {
+	var errs []string
+	delivered := 0
+	for _, notifier := range m.notifiers {
+		if err := notifier.Notify(ctx, n); err != nil {
+			if errors.Is(err, ErrNoAddress) {
+				continue
+			}
+			m.log.Printf("%s notification to %s failed: %v", notifier.Name(), n.To.Name, err)
+			errs = append(errs, notifier.Name()+": "+err.Error())
+			continue
+		}
+		delivered++
+	}
+
+	if delivered == 0 && len(errs) > 0 {
+		return fmt.Errorf("notification not delivered: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// ErrNoAddress means the recipient has no address for this channel.
+var ErrNoAddress = errors.New("recipient has no address for this channel")
+
+type SlackNotifier struct {
+	client *slack.Client
+}
+
+func NewSlackNotifier(client *slack.Client) *SlackNotifier {
+	return &SlackNotifier{client: client}
+}
+
+func (s *SlackNotifier) Name() string {
+	return "slack"
+}
+
+func (s *SlackNotifier) Notify(ctx context.Context, n Notification) error {
+	if n.To.SlackID == "" {
+		return ErrNoAddress
+	}
+
+	text := n.Text
+	if n.Subject != "" {
+		text = "*" + n.Subject + "*\n" + text
+	}
+
+	_, _, err := s.client.PostMessageContext(ctx, n.To.SlackID, slack.MsgOptionText(text, false))
+	return err
+}
+
+type EmailNotifier struct {
+	host     string
+	port     string
+	username string
+	password string
+	from     string
+}
+
+func NewEmailNotifier(host, port, username, password, from string) *EmailNotifier {
+	return &EmailNotifier{
+		host:     host,
+		port:     port,
+		username: username,
+		password: password,
+		from:     from,
+	}
+}
+
+func (e *EmailNotifier) Name() string {
+	return "email"
+}
+
+func (e *EmailNotifier) Notify(ctx context.Context, n Notification) error {
+	if n.To.Email == "" {
+		return ErrNoAddress
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", e.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", n.To.Email)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", n.Subject)
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(n.Text, "\n", "\r\n"))
+
+	var auth smtp.Auth
+	if e.username != "" {
+		auth = smtp.PlainAuth("", e.username, e.password, e.host)
+	}
+
+	return smtp.SendMail(e.host+":"+e.port, auth, e.from, []string{n.To.Email}, msg.Bytes())
+}
+
+// WebhookNotifier POSTs the notification as JSON, for SMS/push gateways or
+// anything else that can take a webhook.
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (w *WebhookNotifier) Name() string {
+	return "webhook"
+}
+
+func (w *WebhookNotifier) Notify(ctx context.Context, n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -25,14 +25,71 @@ type QueryResponse struct {
 	EndDate         string   `json:"end_date"`           // Change to string for JSON response
 	Username        string   `json:"username,omitempty"` // Specific employee
 	Department      string   `json:"department,omitempty"`
+	EmploymentType  string   `json:"employment_type,omitempty"` // "EMPLOYEE" or "CONTRACTOR"
 	Limit           int      `json:"limit,omitempty"`
 	ComparisonType  string   `json:"comparison_type,omitempty"` // "greater_than", "less_than", etc.
 	ComparisonValue int      `json:"comparison_value,omitempty"`
-	LeaveTypes      []string `json:"leave_types,omitempty"` // Types: "WFH", "FULL_DAY", etc.
-	GroupBy         string   `json:"group_by,omitempty"`    // "day", "week", "month"
-	Metrics         Metrics  `json:"metrics,omitempty"`     // Update to use the new Metrics struct
-	Error           string   `json:"error,omitempty"`       // Error messages
-	Suggestion      string   `json:"suggestion,omitempty"`  // New field for suggestions
+	LeaveTypes      []string `json:"leave_types,omitempty"`   // Types: "WFH", "FULL_DAY", etc.
+	Subjects        []string `json:"subjects,omitempty"`      // the two users or teams of a "comparison"
+	GroupBy         string   `json:"group_by,omitempty"`      // "day", "week", "month", "employment_type"
+	ExcludeWFH      bool     `json:"exclude_wfh,omitempty"`   // count only time off, not remote days
+	Period          string   `json:"period,omitempty"`        // "fiscal_year", "quarter" or "sprint", instead of dates
+	PeriodOffset    int      `json:"period_offset,omitempty"` // 0 for the current period, -1 for the last one
+	Topic           string   `json:"topic,omitempty"`         // what records should be about, for similar: searches
+	Metrics         Metrics  `json:"metrics,omitempty"`       // Update to use the new Metrics struct
+	Error           string   `json:"error,omitempty"`         // Error messages
+	Suggestion      string   `json:"suggestion,omitempty"`    // New field for suggestions
+}
+
+// QueryTurn is an earlier question in the same conversation and how it was
+// understood, so a follow-up like "and just the backend team?" can build on
+// it.
+type QueryTurn struct {
+	Query  string
+	Parsed *QueryResponse
+}
+
+// conversationPrompt lists the earlier turns of a conversation for the
+// query prompt, or returns "" when there are none.
+func conversationPrompt(history []QueryTurn) string {
+	if len(history) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\n### 💬 Earlier in this conversation (oldest first):\n")
+	for _, turn := range history {
+		parsed, err := json.Marshal(turn.Parsed)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(&b, "- %s was understood as %s\n", quoteMessage(turn.Query), parsed)
+	}
+	b.WriteString("The query may be a follow-up to these, e.g. \"what about just the backend team?\" or \"and last month?\". " +
+		"If so, start from the last understood JSON and change only what the query changes.\n")
+	return b.String()
+}
+
+// LeaveTurn is an earlier message of a conversation about a leave, and the
+// question it was answered with because something needed was missing.
+type LeaveTurn struct {
+	Message  string
+	Question string
+}
+
+// leaveConversationPrompt lists the earlier turns of a leave conversation
+// for the parse prompt, or returns "" when there are none.
+func leaveConversationPrompt(history []LeaveTurn) string {
+	if len(history) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\n\tEarlier in this conversation (oldest first):\n")
+	for _, turn := range history {
+		fmt.Fprintf(&b, "\t- The author wrote %s and was asked %s\n", quoteMessage(turn.Message), quoteMessage(turn.Question))
+	}
+	b.WriteString("\tThe message answers the last question. Parse the earlier messages and the answer together as one request; " +
+		"the answer fills in or corrects what they left out.\n")
+	return b.String()
 }
 
 type Statistics struct {
@@ -50,72 +107,196 @@ type LeaveResponse struct {
 	EndTime   time.Time `json:"end_time"`
 	Duration  string    `json:"duration"`
 	Reason    string    `json:"reason"`
-	LeaveType string    `json:"leave_type"`      // WFH, FULL_DAY, HALF_DAY, LATE_ARRIVAL, EARLY_DEPARTURE
+	LeaveType string    `json:"leave_type"`      // WFH, IN_OFFICE, FULL_DAY, HALF_DAY, LATE_ARRIVAL, EARLY_DEPARTURE, APPOINTMENT, PARENTAL, LOSS_OF_PAY
 	Error     string    `json:"error,omitempty"` // Add error field for validation messages
+
+	// RRule is set when the item repeats ("WFH every other Friday"). It is an
+	// RFC 5545 rule, and StartTime/EndTime are the first occurrence.
+	RRule string `json:"rrule,omitempty"`
+
+	// Sick is set when the author is off because they're unwell, whatever
+	// the leave type.
+	Sick bool `json:"sick,omitempty"`
+
+	// Confidence is the model's own estimate, from 0 to 1, that the parse is
+	// what the author meant. A missing score reads as 0.
+	Confidence float64 `json:"confidence"`
+
+	// Phrases are the words of the message the item was read from, and
+	// Defaults what the model filled in because the message didn't say.
+	// Both explain the parse to the author.
+	Phrases  []string `json:"phrases,omitempty"`
+	Defaults []string `json:"defaults,omitempty"`
+
+	// Suspicious is set when the message reads like an attempt to instruct
+	// the parser. Such parses always need the author's confirmation.
+	Suspicious bool `json:"-"`
+
+	// Question is set on an invalid item the author should be asked about
+	// because it's missing something needed to record it, like its date.
+	Question string `json:"question,omitempty"`
+
+	// Outcome is how the parse turned out, one of the Outcome constants.
+	Outcome string `json:"-"`
 }
 
+// Parse outcomes, counted per leave type to see how well messages are
+// understood.
+const (
+	OutcomeValid           = "valid"
+	OutcomeInvalidPastDate = "invalid_past_date"
+	OutcomeInvalidTooFar   = "invalid_too_far"
+	OutcomeIncomplete      = "incomplete" // missing details the author was asked for
+	OutcomeInvalid         = "invalid"    // any other validation failure
+	OutcomeJSONError       = "json_error" // the model's reply couldn't be decoded
+	OutcomeUnrelated       = "unrelated"  // not about attendance
+)
+
+// confidenceRules tells the parser how to score its confidence.
+const confidenceRules = `
+	Rules for confidence (0 to 1):
+	- 0.9 or above when the type and dates are stated plainly
+	- 0.5 to 0.9 when you had to guess part of it, e.g. an ambiguous date ("next Friday" on a Thursday), an unclear type, or a missing time
+	- Below 0.5 when the message might not be about the author's own attendance at all
+`
+
+// explainRules asks the parser to show its work, for the "Why?" button.
+const explainRules = `
+	Rules for phrases and defaults:
+	- Set phrases to the exact words of the message the item comes from, e.g. ["wfh tomorrow"]
+	- Set defaults to what you filled in because the message didn't say, in a few words each, e.g. "no time given, used the 9:00 AM - 6:00 PM work day" or "'next Friday' read as the Friday of next week"; leave it empty when nothing was assumed
+`
+
+// ErrTimeout is returned when the OpenAI API doesn't answer within the
+// configured timeout.
+var ErrTimeout = errors.New("OpenAI request timed out")
+
 type OpenAIService struct {
-	client *openai.Client
-	log    *log.Logger
+	client   *openai.Client
+	log      *log.Logger
+	timeout  time.Duration
+	examples ExampleSource
+	repairs  repairCounters
+	parsers  parserCounters
 }
 
-func NewOpenAIService(apiKey string) *OpenAIService {
+// Example is a message and its correct parse, shown to the model as a
+// few-shot example. Times are office wall-clock times.
+type Example struct {
+	Message   string
+	PostedAt  time.Time
+	LeaveType string
+	StartTime time.Time
+	EndTime   time.Time
+	Reason    string
+}
+
+// ExampleSource supplies the few-shot examples for ParseLeaveRequest. It's
+// called for every message, so it should be cheap.
+type ExampleSource func() []Example
+
+// SetExampleSource makes ParseLeaveRequest include examples from source in
+// its prompt.
+func (s *OpenAIService) SetExampleSource(source ExampleSource) {
+	s.examples = source
+}
+
+// examplesPrompt renders the few-shot examples, or "" when there are none.
+func (s *OpenAIService) examplesPrompt(offset string) string {
+	if s.examples == nil {
+		return ""
+	}
+	examples := s.examples()
+	if len(examples) == 0 {
+		return ""
+	}
+
+	const layout = "2006-01-02T15:04:05"
+	var b strings.Builder
+	b.WriteString("\n\tExamples of messages from this workspace and their correct parse. " +
+		"Relative dates in each are resolved against when it was posted, not today:\n")
+	for _, e := range examples {
+		result, _ := json.Marshal(map[string][]map[string]string{"leaves": {{
+			"leave_type": e.LeaveType,
+			"start_time": e.StartTime.Format(layout) + offset,
+			"end_time":   e.EndTime.Format(layout) + offset,
+			"reason":     e.Reason,
+		}}})
+		fmt.Fprintf(&b, "\t- Posted %s: %q → %s\n", e.PostedAt.Format("Monday 2006-01-02 15:04"), e.Message, result)
+	}
+	return b.String()
+}
+
+func NewOpenAIService(apiKey string, timeout time.Duration) *OpenAIService {
 	return &OpenAIService{
-		client: openai.NewClient(apiKey),
-		log:    log.New(os.Stdout, "🤖 OPENAI  | ", log.Ltime),
+		client:  openai.NewClient(apiKey),
+		log:     log.New(os.Stdout, "🤖 OPENAI  | ", log.Ltime),
+		timeout: timeout,
 	}
 }
 
-func (s *OpenAIService) ParseQuery(query string) (*QueryResponse, error) {
-	loc, _ := time.LoadLocation("Asia/Kolkata")
+// complete sends a chat completion bounded by the service timeout and returns
+// the trimmed content of the first choice.
+func (s *OpenAIService) complete(ctx context.Context, req openai.ChatCompletionRequest) (string, error) {
+	reply, err := s.chat(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(reply.Content), nil
+}
+
+// ParseQuery turns a question about leave into a structured query. Relative
+// dates like "this month" are resolved in loc, the workspace's timezone.
+// history holds the earlier questions of the same conversation, if any, so
+// follow-ups can leave out what they don't change.
+func (s *OpenAIService) ParseQuery(ctx context.Context, query string, history []QueryTurn, loc *time.Location) (*QueryResponse, error) {
 	now := time.Now().In(loc)
 
 	// Updated prompt with better clarity and validation instructions
 	prompt := fmt.Sprintf(`
-Analyze this leave/attendance query and return a structured JSON response.
+Analyze this leave/attendance query and call describe_query with what it asks for.
 
-Query: "%s"
+Query: %s
 Current time: %s
-
+%s
 ### 🔍 Examples of Correct Queries:
 - "Who took the most leave this month?"
 - "How many people worked from home last week?"
 - "Show WFH trends over the past year."
 - "Which department has the most WFH employees?"
 
+### 📌 Query types:
+- "top_employee": who took the most leave
+- "employee_stats": totals for one employee (set username)
+- "period_stats": totals for everyone over a period (set start_date and end_date)
+- "type_ranking": who had the most of particular kinds of leave over a period, e.g. "who WFH'd the most last quarter?" or "most late arrivals this month" (set leave_types, start_date and end_date)
+- "trend": how leave changed over time, e.g. "show WFH trends over the past year" (set group_by to "day", "week" or "month", start_date and end_date, and leave_types if the query names kinds of leave)
+- "comparison": side-by-side stats for two people or teams, e.g. "how does priya compare to rahul this quarter?" or "compare engineering and sales last month" (set subjects, start_date and end_date)
+- "availability": who is out or away on one day, e.g. "who's out today?" or "is anyone away Friday afternoon?" (set start_date to the day)
+
 ### 📌 Important Rules:
-1. **Always return valid JSON** with all required fields.
-2. **Detect and correct misspellings** in queries where possible.
-3. If the query is **invalid or ambiguous**, return a **valid suggestion** in the 'suggestion' field.
-4. If a query references a **future date or untracked data**, return {"error": "Invalid query"} and a **possible fix**.
-5. **NEVER include markdown, bullet points, or extra text**, only return structured JSON.
-
-#### 📜 JSON Output Format:
-{
-	"query_type": REQUIRED,
-	"analysis_subtype": REQUIRED,
-	"start_date": optional,
-	"end_date": optional,
-	"username": optional,
-	"department": optional,
-	"limit": optional,
-	"comparison_type": optional,
-	"comparison_value": optional,
-	"leave_types": optional,
-	"group_by": optional,
-	"metrics": optional,
-	"error": optional,
-	"suggestion": optional
-}`, query, now.Format(time.RFC3339))
-
-	resp, err := s.client.CreateChatCompletion(
-		context.Background(),
+1. **Detect and correct misspellings** in queries where possible.
+2. If the query is **invalid or ambiguous**, give a **valid suggestion** in 'suggestion'.
+3. If a query references a **future date or untracked data**, set error to "Invalid query" and give a **possible fix**.
+
+### 📌 Fields:
+- employment_type: only when the query mentions employees vs contractors
+- comparison_type and comparison_value: with query_type "period_stats", keep people whose leave count compares to comparison_value, e.g. "who took more than 5 leaves this quarter?"
+- subjects: the two usernames or team names being compared
+- exclude_wfh: true when the query is about absences or time off only, e.g. "who was absent the most?", so working from home doesn't count
+- period: set it instead of start_date and end_date when the query names the fiscal or financial year, FY, a quarter or a sprint, as the company's fiscal year and sprints aren't calendar dates
+- period_offset: with period, 0 for the current one, -1 for the last one, -2 for the one before, and so on
+- topic: when the query looks for records about a subject, e.g. "leaves related to medical appointments last quarter", just the subject: "medical appointments"`, quoteMessage(query), now.Format(time.RFC3339), conversationPrompt(history))
+
+	var queryResp QueryResponse
+	content, err := s.callTool(
+		ctx,
 		openai.ChatCompletionRequest{
 			Model: "gpt-4o-mini",
 			Messages: []openai.ChatCompletionMessage{
 				{
 					Role:    openai.ChatMessageRoleSystem,
-					Content: "You are an AI trained to process attendance queries and return structured JSON. Never return markdown, code blocks, or plain text.",
+					Content: "You are an AI trained to understand attendance queries. Always answer by calling describe_query." + untrustedRule,
 				},
 				{
 					Role:    openai.ChatMessageRoleUser,
@@ -124,24 +305,15 @@ Current time: %s
 			},
 			Temperature: 0.3, // Lower temp for more consistent responses
 		},
+		describeQueryTool,
+		&queryResp,
 	)
 
 	if err != nil {
-		return nil, fmt.Errorf("OpenAI API error: %v", err)
+		return nil, err
 	}
 
-	// Clean response from AI
-	content := strings.TrimSpace(resp.Choices[0].Message.Content)
-	content = strings.ReplaceAll(content, "```json", "")
-	content = strings.ReplaceAll(content, "```", "")
-
-	s.log.Printf("Raw OpenAI response: %s", content)
-
-	// Parse JSON response
-	var queryResp QueryResponse
-	if err := json.Unmarshal([]byte(content), &queryResp); err != nil {
-		return nil, fmt.Errorf("JSON parse error: %v\nResponse: %s", err, content)
-	}
+	s.log.Printf("Raw OpenAI arguments: %s", content)
 
 	// If an error exists in the response, handle it properly
 	if queryResp.Error != "" {
@@ -200,69 +372,113 @@ func processStatistics(result []Statistics) Statistics {
 	}
 }
 
-func (s *OpenAIService) ParseLeaveRequest(text, timestamp string) (*LeaveResponse, error) {
-	// Set timezone to IST
-	loc, _ := time.LoadLocation("Asia/Kolkata")
+// ParseLeaveRequest parses a message expected to hold a single attendance
+// item, returning the first one found. Dates are resolved in the sender's
+// office timezone, and requests outside the booking window are rejected, as
+// are spans with an office holiday but no working day left to take off.
+func (s *OpenAIService) ParseLeaveRequest(ctx context.Context, text, timestamp string, region Region) (*LeaveResponse, error) {
+	responses, err := s.ParseLeaveRequests(ctx, text, timestamp, nil, region, ParseLeaveRules(text, time.Now(), region))
+	if err != nil {
+		return nil, err
+	}
+	if len(responses) == 0 {
+		return &LeaveResponse{IsValid: false}, nil
+	}
+	return responses[0], nil
+}
+
+// ParseLeaveRequests parses every attendance item in a message, so "WFH
+// tomorrow and on leave Friday" comes back as two responses, in the order
+// they were mentioned. Each is validated on its own. history holds the
+// earlier messages of the conversation when the message answers a
+// question about them. ruled is the caller's ParseLeaveRules result for
+// text: when it isn't nil it's returned as is without calling the model, so
+// common phrasings still work when OpenAI doesn't.
+func (s *OpenAIService) ParseLeaveRequests(ctx context.Context, text, timestamp string, history []LeaveTurn, region Region, ruled []*LeaveResponse) ([]*LeaveResponse, error) {
+	if ruled != nil {
+		s.parsers.rules.Add(1)
+		// Not the text: it may say why someone is off sick
+		s.log.Printf("Parsed a message with rules as %s", ruled[0].LeaveType)
+		return ruled, nil
+	}
+	s.parsers.model.Add(1)
+
+	loc := region.location()
+	maxAdvanceDays := region.maxAdvanceDays()
 	now := time.Now().In(loc)
+	offset := now.Format("-07:00")
 	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
 	tomorrow := today.AddDate(0, 0, 1)
-	maxFutureDate := today.AddDate(0, 0, 30)
+	maxFutureDate := today.AddDate(0, 0, maxAdvanceDays)
+	advance := fmt.Sprintf("%d", maxAdvanceDays)
+	workDay := region.workDayText()
 
-	prompt := `Parse this message for leave/attendance details. Return a JSON object only.
+	prompt := `Parse this message for leave/attendance details and call record_attendance with them.
 
-	Message: "` + text + `"
+	Message: ` + quoteMessage(text) + `
+` + leaveConversationPrompt(history) + `
 	Current time: ` + now.Format(time.RFC3339) + `
 
 	Current context:
 	- Today's date: ` + today.Format("2006-01-02") + `
 	- Tomorrow's date: ` + tomorrow.Format("2006-01-02") + `
 	- Maximum allowed date: ` + maxFutureDate.Format("2006-01-02") + `
-	- Default work hours: 9:00 AM to 6:00 PM
-	- Timezone: Asia/Kolkata (IST)
+	- Default work hours: ` + workDay + `
+	- Timezone: ` + loc.String() + ` (UTC` + offset + `)
 	- Current year: ` + fmt.Sprintf("%d", now.Year()) + `
 
 	Rules for leave_type:
 	- "WFH" for working from home
+	- "IN_OFFICE" for working from the office (hybrid office days)
 	- "FULL_DAY" for full day leave
 	- "HALF_DAY" for half day leave
 	- "LATE_ARRIVAL" for coming late
 	- "EARLY_DEPARTURE" for leaving early
-
+	- "APPOINTMENT" for being away for a set window within the day (e.g. "out from 2:30 to 4 for a dentist appointment")
+	- "PARENTAL" for parental, maternity or paternity leave
+	- "LOSS_OF_PAY" for unpaid leave, leave without pay (LWP) or loss of pay (LOP)
+` + region.longLeaveRules() + `
 	Important validation rules:
 	- Leave cannot be requested for past dates
-	- Leave cannot be requested for dates more than 30 days in advance
+	- Leave cannot be requested for dates more than ` + advance + ` days in advance
 	- Start time must be before end time
 	- If validation fails, set is_valid to false and include error message
-	- Use IST timezone (+05:30) for all dates
+	- Give start_time and end_time in RFC 3339, with UTC offset ` + offset + ` for all dates
 	- For "today", use ` + today.Format("2006-01-02") + `
 	- For "tomorrow", use ` + tomorrow.Format("2006-01-02") + `
 	- For specific dates (e.g. "march 10"):
 	  * If the date is in the past this year, set is_valid to false with error
-	  * If the date is in the future this year but more than 30 days away, set is_valid to false with error
-	  * If the date is within next 30 days, use that date
-	- For full day leave: set time to 9:00 AM - 6:00 PM IST
-	- For half day leave: set time to either 9:00 AM - 1:00 PM or 2:00 PM - 6:00 PM IST
-	- For WFH: set time to 9:00 AM - 6:00 PM IST
-
-	Return a JSON object with these fields:
-	{
-		"is_valid": true/false,
-		"leave_type": "WFH/FULL_DAY/HALF_DAY/LATE_ARRIVAL/EARLY_DEPARTURE",
-		"start_time": "2024-03-01T09:00:00+05:30",
-		"end_time": "2024-03-01T18:00:00+05:30",
-		"duration": "9 hours",
-		"reason": "reason for leave",
-		"error": "error message if validation fails"
-	}`
-
-	resp, err := s.client.CreateChatCompletion(
-		context.Background(),
+	  * If the date is in the future this year but more than ` + advance + ` days away, set is_valid to false with error
+	  * If the date is within next ` + advance + ` days, use that date
+	- For full day, parental and loss-of-pay leave: set time to ` + workDay + ` local time
+	- For half day leave: set time to either 9:00 AM - 1:00 PM or 2:00 PM - 6:00 PM local time
+	- For WFH and IN_OFFICE: set time to ` + workDay + ` local time
+	- For appointments: use the exact window to the minute (e.g. 14:30 - 16:00); never round it to a half day
+	- Set sick to true when the author is off because they're unwell (e.g. "down with fever", "sick leave tomorrow"), otherwise false
+	- If the message mentions several items (e.g. "WFH tomorrow and on leave Friday"), return one object per item, in the order mentioned
+	- Consecutive days of the same kind are one item (e.g. "off Monday to Wednesday")
+	- If the message isn't about attendance, return a single object with is_valid set to false
+	- If an item is about attendance but is missing something needed to record it (most often which day), set is_valid to false, set leave_type if you can tell it, and set question to one short, friendly question asking for what's missing, e.g. "Which day will you be out?"; leave question out for any other kind of invalid item
+	- If an item repeats, set rrule to an RFC 5545 recurrence rule and start_time/end_time to its first occurrence on or after today, e.g.:
+	  * "every Friday" → "FREQ=WEEKLY;BYDAY=FR"
+	  * "every other Friday" → "FREQ=WEEKLY;INTERVAL=2;BYDAY=FR"
+	  * "Mondays and Wednesdays" → "FREQ=WEEKLY;BYDAY=MO,WE"
+	  * "first Monday of the month" → "FREQ=MONTHLY;BYDAY=1MO"
+	  * "last Friday of every month" → "FREQ=MONTHLY;BYDAY=-1FR"
+	  * "every weekday until March 31" → "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR;UNTIL=20240331"
+	  * "every Tuesday for 6 weeks" → "FREQ=WEEKLY;BYDAY=TU;COUNT=6"
+	  Only use FREQ DAILY, WEEKLY or MONTHLY with INTERVAL, BYDAY, BYMONTHDAY, COUNT and UNTIL. Leave rrule out for one-off items
+` + confidenceRules + explainRules + s.examplesPrompt(offset)
+
+	var parsed recordAttendanceArgs
+	_, err := s.callTool(
+		ctx,
 		openai.ChatCompletionRequest{
 			Model: "gpt-4o-mini",
 			Messages: []openai.ChatCompletionMessage{
 				{
 					Role:    openai.ChatMessageRoleSystem,
-					Content: "You are a date-aware JSON response bot. Use the current year for all dates. Never use markdown.",
+					Content: "You are a date-aware attendance parser. Use the current year for all dates. Always answer by calling record_attendance." + untrustedRule,
 				},
 				{
 					Role:    openai.ChatMessageRoleUser,
@@ -271,61 +487,54 @@ func (s *OpenAIService) ParseLeaveRequest(text, timestamp string) (*LeaveRespons
 			},
 			Temperature: 0.1,
 		},
+		recordAttendanceTool,
+		&parsed,
 	)
 
 	if err != nil {
-		return nil, fmt.Errorf("OpenAI API error: %v", err)
+		return nil, err
 	}
 
-	content := resp.Choices[0].Message.Content
-	content = strings.TrimSpace(content)
-
-	var leaveResp LeaveResponse
-	err = json.Unmarshal([]byte(content), &leaveResp)
-	if err != nil {
-		return nil, fmt.Errorf("JSON parse error: %v\nResponse: %s", err, content)
-	}
-
-	if !leaveResp.IsValid {
-		return &leaveResp, nil
-	}
-
-	// Validate required fields
-	if leaveResp.LeaveType == "" {
-		return nil, fmt.Errorf("leave_type is required for valid requests")
-	}
-
-	// Convert response times to IST for comparison
-	startTimeIST := leaveResp.StartTime.In(loc)
-	endTimeIST := leaveResp.EndTime.In(loc)
-	nowIST := now.In(loc)
+	for _, leaveResp := range parsed.Leaves {
+		checkParsePolicy(leaveResp, text, region)
+		if !leaveResp.IsValid {
+			continue
+		}
 
-	// Compare dates only (ignore time) for validation
-	startDate := time.Date(startTimeIST.Year(), startTimeIST.Month(), startTimeIST.Day(), 0, 0, 0, 0, loc)
-	todayDate := time.Date(nowIST.Year(), nowIST.Month(), nowIST.Day(), 0, 0, 0, 0, loc)
-	maxDate := todayDate.AddDate(0, 0, 30)
+		if outcome, reason := region.ForLeaveType(leaveResp.LeaveType).validate(leaveResp.StartTime, leaveResp.EndTime, now); reason != "" {
+			leaveResp.IsValid = false
+			leaveResp.Error = reason
+			leaveResp.Outcome = outcome
+			continue
+		}
 
-	if startDate.Before(todayDate) {
-		leaveResp.IsValid = false
-		leaveResp.Error = "Cannot request leave for past dates"
-		return &leaveResp, nil
-	}
+		if leaveResp.RRule != "" {
+			rule, err := ParseRRule(leaveResp.RRule)
+			if err != nil {
+				leaveResp.IsValid = false
+				leaveResp.Error = fmt.Sprintf("I couldn't work out how that repeats (%v)", err)
+				continue
+			}
+			leaveResp.RRule = rule.String()
+		}
 
-	if startDate.After(maxDate) {
-		leaveResp.IsValid = false
-		leaveResp.Error = fmt.Sprintf("Cannot request leave more than 30 days in advance (maximum allowed date is %s)",
-			maxDate.Format("January 2, 2006"))
-		return &leaveResp, nil
+		leaveResp.StartTime = leaveResp.StartTime.In(loc)
+		leaveResp.EndTime = leaveResp.EndTime.In(loc)
 	}
 
-	if endTimeIST.Before(startTimeIST) {
-		leaveResp.IsValid = false
-		leaveResp.Error = "End time must be after start time"
-		return &leaveResp, nil
+	for _, leaveResp := range parsed.Leaves {
+		switch {
+		case leaveResp.Outcome != "":
+		case leaveResp.IsValid:
+			leaveResp.Outcome = OutcomeValid
+		case leaveResp.Question != "":
+			leaveResp.Outcome = OutcomeIncomplete
+		case leaveResp.LeaveType == "" && leaveResp.Error == "":
+			// The model's answer for a message that isn't about attendance
+			leaveResp.Outcome = OutcomeUnrelated
+		default:
+			leaveResp.Outcome = OutcomeInvalid
+		}
 	}
-
-	leaveResp.StartTime = leaveResp.StartTime.In(loc)
-	leaveResp.EndTime = leaveResp.EndTime.In(loc)
-
-	return &leaveResp, nil
+	return parsed.Leaves, nil
 }
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	attendanceKeywordPattern = regexp.MustCompile(`(?i)\b(` + strings.Join([]string{
+		`leave`, `leaving`, `off`, `ooo`, `out of office`, `out`, `absent`,
+		`wfh`, `work(ing)? from home`, `remote(ly)?`,
+		`wfo`, `in (the )?office`, `work(ing)? from (the )?office`,
+		`late`, `delayed`, `running behind`, `coming in`, `reach(ing)?`,
+		`early`, `half[- ]?day`, `full[- ]?day`,
+		`sick`, `unwell`, `fever`, `doctor`, `appointment`, `hospital`,
+		`vacation`, `holiday`, `pto`, `personal day`, `day off`,
+	}, `|`) + `)\b`)
+
+	urlOnlyPattern   = regexp.MustCompile(`^(<https?://[^>]+>\s*)+$`)
+	emojiOnlyPattern = regexp.MustCompile(`^(:[a-z0-9_+\-]+:\s*)+$`)
+)
+
+// IsObviouslyIrrelevant reports messages that can never be an attendance
+// update no matter how a channel is configured: links, code, emoji.
+func IsObviouslyIrrelevant(text string) bool {
+	text = strings.TrimSpace(text)
+	if len(text) < 3 {
+		return true
+	}
+	if strings.Contains(text, "```") {
+		return true
+	}
+	return urlOnlyPattern.MatchString(text) || emojiOnlyPattern.MatchString(text)
+}
+
+// HasAttendanceKeyword reports whether text mentions anything leave,
+// lateness or WFH related.
+func HasAttendanceKeyword(text string) bool {
+	return attendanceKeywordPattern.MatchString(text)
+}
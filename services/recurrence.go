@@ -0,0 +1,339 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RRule is the subset of an RFC 5545 recurrence rule the bot understands:
+// daily, weekly and monthly repeats with an interval, weekdays (with an
+// ordinal such as 1MO or -1FR for monthly rules), month days, and an end
+// given by COUNT or UNTIL.
+type RRule struct {
+	Freq       string // DAILY, WEEKLY or MONTHLY
+	Interval   int
+	ByDay      []RRuleDay
+	ByMonthDay []int
+	Count      int
+	Until      time.Time // date only; zero when open-ended
+}
+
+// RRuleDay is a BYDAY entry. Ordinal is 0 for "every", 1 for the first, -1
+// for the last, and so on; it only applies to monthly rules.
+type RRuleDay struct {
+	Ordinal int
+	Weekday time.Weekday
+}
+
+var rruleWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// maxRRuleOccurrences bounds how many occurrences are generated from one
+// rule in one call, as a guard against pathological rules.
+const maxRRuleOccurrences = 1000
+
+// ParseRRule parses a rule such as "FREQ=WEEKLY;INTERVAL=2;BYDAY=FR", with or
+// without the "RRULE:" prefix.
+func ParseRRule(value string) (*RRule, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "RRULE:")
+	rule := &RRule{Interval: 1}
+
+	for _, part := range strings.Split(value, ";") {
+		if part == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rule part %q", part)
+		}
+
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rule.Freq = strings.ToUpper(val)
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid INTERVAL %q", val)
+			}
+			rule.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid COUNT %q", val)
+			}
+			rule.Count = n
+		case "UNTIL":
+			until, err := time.Parse("20060102", val[:min(len(val), 8)])
+			if err != nil {
+				return nil, fmt.Errorf("invalid UNTIL %q", val)
+			}
+			rule.Until = until
+		case "BYDAY":
+			for _, day := range strings.Split(strings.ToUpper(val), ",") {
+				if len(day) < 2 {
+					return nil, fmt.Errorf("invalid BYDAY %q", day)
+				}
+				weekday, ok := rruleWeekdays[day[len(day)-2:]]
+				if !ok {
+					return nil, fmt.Errorf("invalid BYDAY %q", day)
+				}
+				ordinal := 0
+				if prefix := day[:len(day)-2]; prefix != "" {
+					n, err := strconv.Atoi(prefix)
+					if err != nil || n == 0 || n < -5 || n > 5 {
+						return nil, fmt.Errorf("invalid BYDAY %q", day)
+					}
+					ordinal = n
+				}
+				rule.ByDay = append(rule.ByDay, RRuleDay{Ordinal: ordinal, Weekday: weekday})
+			}
+		case "BYMONTHDAY":
+			for _, day := range strings.Split(val, ",") {
+				n, err := strconv.Atoi(day)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return nil, fmt.Errorf("invalid BYMONTHDAY %q", day)
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, n)
+			}
+		case "WKST":
+			if strings.ToUpper(val) != "MO" {
+				return nil, fmt.Errorf("only WKST=MO is supported")
+			}
+		default:
+			return nil, fmt.Errorf("unsupported rule part %s", key)
+		}
+	}
+
+	switch rule.Freq {
+	case "DAILY", "WEEKLY", "MONTHLY":
+	case "":
+		return nil, fmt.Errorf("FREQ is required")
+	default:
+		return nil, fmt.Errorf("unsupported FREQ %s", rule.Freq)
+	}
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return nil, fmt.Errorf("COUNT and UNTIL can't be combined")
+	}
+	for _, day := range rule.ByDay {
+		if day.Ordinal != 0 && rule.Freq != "MONTHLY" {
+			return nil, fmt.Errorf("BYDAY ordinals are only supported for monthly rules")
+		}
+	}
+	if len(rule.ByMonthDay) > 0 && rule.Freq != "MONTHLY" {
+		return nil, fmt.Errorf("BYMONTHDAY is only supported for monthly rules")
+	}
+	return rule, nil
+}
+
+// String renders the rule in RFC 5545 form, without the "RRULE:" prefix.
+func (r *RRule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, 0, len(r.ByDay))
+		for _, day := range r.ByDay {
+			code := strings.ToUpper(day.Weekday.String()[:2])
+			if day.Ordinal != 0 {
+				code = strconv.Itoa(day.Ordinal) + code
+			}
+			days = append(days, code)
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, 0, len(r.ByMonthDay))
+		for _, day := range r.ByMonthDay {
+			days = append(days, strconv.Itoa(day))
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Describe renders the rule in plain English, e.g. "every 2 weeks on Friday"
+// or "every month on the first Monday".
+func (r *RRule) Describe() string {
+	var b strings.Builder
+	unit := map[string]string{"DAILY": "day", "WEEKLY": "week", "MONTHLY": "month"}[r.Freq]
+	if r.Interval > 1 {
+		fmt.Fprintf(&b, "every %d %ss", r.Interval, unit)
+	} else {
+		fmt.Fprintf(&b, "every %s", unit)
+	}
+
+	if len(r.ByDay) > 0 {
+		days := make([]string, 0, len(r.ByDay))
+		for _, day := range r.ByDay {
+			days = append(days, ordinalName(day.Ordinal)+day.Weekday.String())
+		}
+		b.WriteString(" on " + strings.Join(days, ", "))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, 0, len(r.ByMonthDay))
+		for _, day := range r.ByMonthDay {
+			if day == -1 {
+				days = append(days, "the last day")
+			} else {
+				days = append(days, "day "+strconv.Itoa(day))
+			}
+		}
+		b.WriteString(" on " + strings.Join(days, ", "))
+	}
+
+	if r.Count > 0 {
+		fmt.Fprintf(&b, ", %d times", r.Count)
+	}
+	if !r.Until.IsZero() {
+		b.WriteString(" until " + r.Until.Format("Jan 2, 2006"))
+	}
+	return b.String()
+}
+
+func ordinalName(ordinal int) string {
+	switch ordinal {
+	case 0:
+		return ""
+	case -1:
+		return "the last "
+	case -2:
+		return "the second to last "
+	case 1:
+		return "the first "
+	case 2:
+		return "the second "
+	case 3:
+		return "the third "
+	case 4:
+		return "the fourth "
+	case 5:
+		return "the fifth "
+	}
+	return fmt.Sprintf("number %d from the end: ", -ordinal)
+}
+
+// Between returns the starts of the occurrences of a series beginning at
+// dtstart that fall after from and no later than to, in order. Occurrences
+// keep dtstart's time of day and location. COUNT is counted from dtstart, so
+// later windows of the same series line up with earlier ones.
+func (r *RRule) Between(dtstart, from, to time.Time) []time.Time {
+	var out []time.Time
+	seen := 0
+	for period := 0; ; period++ {
+		candidates := r.periodDays(dtstart, period)
+		if candidates == nil {
+			return out
+		}
+		for _, day := range candidates {
+			start := time.Date(day.Year(), day.Month(), day.Day(),
+				dtstart.Hour(), dtstart.Minute(), dtstart.Second(), 0, dtstart.Location())
+			if start.Before(dtstart) {
+				continue
+			}
+			if !r.Until.IsZero() && start.Format("2006-01-02") > r.Until.Format("2006-01-02") {
+				return out
+			}
+			if start.After(to) {
+				return out
+			}
+			seen++
+			if r.Count > 0 && seen > r.Count {
+				return out
+			}
+			if start.After(from) {
+				out = append(out, start)
+			}
+			if seen >= maxRRuleOccurrences {
+				return out
+			}
+		}
+	}
+}
+
+// periodDays returns the candidate days, in order, of the period-th
+// interval of the series. It returns an empty, non-nil slice for a period
+// without matches and nil once the series can produce no more.
+func (r *RRule) periodDays(dtstart time.Time, period int) []time.Time {
+	if period > maxRRuleOccurrences {
+		return nil
+	}
+	loc := dtstart.Location()
+	base := time.Date(dtstart.Year(), dtstart.Month(), dtstart.Day(), 0, 0, 0, 0, loc)
+	days := []time.Time{}
+
+	switch r.Freq {
+	case "DAILY":
+		return append(days, base.AddDate(0, 0, period*r.Interval))
+
+	case "WEEKLY":
+		// Weeks start on Monday
+		monday := base.AddDate(0, 0, -((int(base.Weekday())+6)%7)).AddDate(0, 0, 7*period*r.Interval)
+		if len(r.ByDay) == 0 {
+			return append(days, monday.AddDate(0, 0, (int(dtstart.Weekday())+6)%7))
+		}
+		for offset := 0; offset < 7; offset++ {
+			day := monday.AddDate(0, 0, offset)
+			for _, byDay := range r.ByDay {
+				if byDay.Weekday == day.Weekday() {
+					days = append(days, day)
+					break
+				}
+			}
+		}
+		return days
+
+	case "MONTHLY":
+		first := time.Date(base.Year(), base.Month()+time.Month(period*r.Interval), 1, 0, 0, 0, 0, loc)
+		daysInMonth := first.AddDate(0, 1, -1).Day()
+		match := make(map[int]bool)
+
+		for _, byDay := range r.ByDay {
+			var matching []int
+			for d := 1; d <= daysInMonth; d++ {
+				if first.AddDate(0, 0, d-1).Weekday() == byDay.Weekday {
+					matching = append(matching, d)
+				}
+			}
+			switch {
+			case byDay.Ordinal == 0:
+				for _, d := range matching {
+					match[d] = true
+				}
+			case byDay.Ordinal > 0 && byDay.Ordinal <= len(matching):
+				match[matching[byDay.Ordinal-1]] = true
+			case byDay.Ordinal < 0 && -byDay.Ordinal <= len(matching):
+				match[matching[len(matching)+byDay.Ordinal]] = true
+			}
+		}
+		for _, d := range r.ByMonthDay {
+			if d < 0 {
+				d = daysInMonth + d + 1
+			}
+			if d >= 1 && d <= daysInMonth {
+				match[d] = true
+			}
+		}
+		if len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 && dtstart.Day() <= daysInMonth {
+			match[dtstart.Day()] = true
+		}
+
+		for d := 1; d <= daysInMonth; d++ {
+			if match[d] {
+				days = append(days, first.AddDate(0, 0, d-1))
+			}
+		}
+		return days
+	}
+	return nil
+}
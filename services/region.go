@@ -0,0 +1,180 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultMaxAdvanceDays is how far ahead leave can be requested when the
+// user's office doesn't set its own window.
+const DefaultMaxAdvanceDays = 30
+
+// Region is the office-specific context a leave request is parsed and
+// validated in: the user's timezone, how far ahead they may book, their
+// working hours, and the public holidays of their office.
+type Region struct {
+	Timezone       *time.Location
+	MaxAdvanceDays int
+	Holidays       map[string]string // "2006-01-02" -> holiday name
+
+	// WorkStartHour and WorkEndHour bound the work day a record without a
+	// time fills; 0 means 9 and 18.
+	WorkStartHour int
+	WorkEndHour   int
+
+	// LongLeaveTypes are leave types, such as parental leave, that may be
+	// booked up to LongLeaveAdvanceDays ahead and last weeks or months.
+	LongLeaveTypes       map[string]bool
+	LongLeaveAdvanceDays int
+}
+
+// DefaultRegion is used for users without an assigned office.
+func DefaultRegion() Region {
+	loc, _ := time.LoadLocation("Asia/Kolkata")
+	return Region{Timezone: loc, MaxAdvanceDays: DefaultMaxAdvanceDays}
+}
+
+func (r Region) location() *time.Location {
+	if r.Timezone == nil {
+		return DefaultRegion().Timezone
+	}
+	return r.Timezone
+}
+
+// workDay returns the start and end of the work day as offsets into a day.
+func (r Region) workDay() (time.Duration, time.Duration) {
+	start, end := r.WorkStartHour, r.WorkEndHour
+	if start <= 0 || end <= start || end > 24 {
+		start, end = 9, 18
+	}
+	return time.Duration(start) * time.Hour, time.Duration(end) * time.Hour
+}
+
+// workDayText is the work day as "9:00 AM - 6:00 PM".
+func (r Region) workDayText() string {
+	start, end := r.workDay()
+	var midnight time.Time
+	return midnight.Add(start).Format("3:04 PM") + " - " + midnight.Add(end).Format("3:04 PM")
+}
+
+func (r Region) maxAdvanceDays() int {
+	if r.MaxAdvanceDays <= 0 {
+		return DefaultMaxAdvanceDays
+	}
+	return r.MaxAdvanceDays
+}
+
+// ForLeaveType returns the region with the booking window that applies to
+// leaveType: the long-leave window for long-leave types, the usual one
+// otherwise.
+func (r Region) ForLeaveType(leaveType string) Region {
+	if r.LongLeaveTypes[leaveType] && r.LongLeaveAdvanceDays > r.maxAdvanceDays() {
+		r.MaxAdvanceDays = r.LongLeaveAdvanceDays
+	}
+	return r
+}
+
+// Validate applies the region's booking rules to a request spanning start to
+// end, returning a user-facing reason when it isn't allowed or "" when it is.
+func (r Region) Validate(start, end, now time.Time) string {
+	_, reason := r.validate(start, end, now)
+	return reason
+}
+
+// validate is Validate that also returns which parse outcome a refusal is.
+func (r Region) validate(start, end, now time.Time) (string, string) {
+	loc := r.location()
+	maxAdvanceDays := r.maxAdvanceDays()
+
+	// Compare dates only (ignore time)
+	startLocal := start.In(loc)
+	nowLocal := now.In(loc)
+	startDate := time.Date(startLocal.Year(), startLocal.Month(), startLocal.Day(), 0, 0, 0, 0, loc)
+	today := time.Date(nowLocal.Year(), nowLocal.Month(), nowLocal.Day(), 0, 0, 0, 0, loc)
+	maxDate := today.AddDate(0, 0, maxAdvanceDays)
+
+	if startDate.Before(today) {
+		return OutcomeInvalidPastDate, "Cannot request leave for past dates"
+	}
+
+	if startDate.After(maxDate) {
+		return OutcomeInvalidTooFar, fmt.Sprintf("Cannot request leave more than %d days in advance (maximum allowed date is %s)",
+			maxAdvanceDays, maxDate.Format("January 2, 2006"))
+	}
+
+	if end.Before(start) {
+		return OutcomeInvalid, "End time must be after start time"
+	}
+
+	// A span with holidays in it is fine, since only its working days are
+	// charged, unless it has no working days left to take off
+	endLocal := end.In(loc)
+	if holidays := r.holidaysBetween(startLocal, endLocal); len(holidays) > 0 && len(r.WorkingDays(startLocal, endLocal)) == 0 {
+		if startLocal.Format("2006-01-02") == endLocal.Format("2006-01-02") {
+			return OutcomeInvalid, fmt.Sprintf("%s is a public holiday in your office (%s), no leave needed",
+				startDate.Format("January 2, 2006"), holidays[0])
+		}
+		return OutcomeInvalid, fmt.Sprintf("%s to %s has no working days in your office (%s), no leave needed",
+			startDate.Format("January 2"), endLocal.Format("January 2, 2006"), strings.Join(holidays, ", "))
+	}
+
+	return OutcomeValid, ""
+}
+
+// IsWorkingDay reports whether day's date is in the work week, Monday to
+// Friday, and isn't one of the region's public holidays. Like working_days
+// in the database, it reads the date as stored, in office wall-clock time.
+func (r Region) IsWorkingDay(day time.Time) bool {
+	if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+		return false
+	}
+	_, holiday := r.Holidays[day.Format("2006-01-02")]
+	return !holiday
+}
+
+// holidaysBetween returns the names of the region's public holidays from
+// start's date to end's date, in date order.
+func (r Region) holidaysBetween(start, end time.Time) []string {
+	var names []string
+	last := end.Format("2006-01-02")
+	for day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location()); day.Format("2006-01-02") <= last; day = day.AddDate(0, 0, 1) {
+		if name, ok := r.Holidays[day.Format("2006-01-02")]; ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// WorkingDays returns the working days from start's date to end's date,
+// inclusive.
+func (r Region) WorkingDays(start, end time.Time) []time.Time {
+	var days []time.Time
+	last := end.Format("2006-01-02")
+	for day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location()); day.Format("2006-01-02") <= last; day = day.AddDate(0, 0, 1) {
+		if r.IsWorkingDay(day) {
+			days = append(days, day)
+		}
+	}
+	return days
+}
+
+// longLeaveRules tells the parser which types are exempt from the usual
+// booking window, or is empty when none are.
+func (r Region) longLeaveRules() string {
+	var types []string
+	for leaveType, ok := range r.LongLeaveTypes {
+		if ok {
+			types = append(types, leaveType)
+		}
+	}
+	if len(types) == 0 || r.LongLeaveAdvanceDays <= r.maxAdvanceDays() {
+		return ""
+	}
+	sort.Strings(types)
+	return fmt.Sprintf("\n\tRules for long leave:\n"+
+		"\t- %s leave may be requested up to %d days in advance instead of the usual limit below\n"+
+		"\t- It may last several weeks or months; use the exact first and last day\n",
+		strings.Join(types, "/"), r.LongLeaveAdvanceDays)
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRegionWorkingDays(t *testing.T) {
+	region := Region{Holidays: map[string]string{"2024-03-08": "Maha Shivaratri"}}
+	at := func(d, hour int) time.Time { return time.Date(2024, time.March, d, hour, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name       string
+		start, end time.Time
+		want       []string
+	}{
+		{"one weekday", at(4, 9), at(4, 18), []string{"2024-03-04"}},
+		{"over a weekend", at(7, 9), at(11, 18), []string{"2024-03-07", "2024-03-11"}}, // Friday is a holiday
+		{"weekend only", at(9, 9), at(10, 18), nil},
+		{"holiday only", at(8, 9), at(8, 18), nil},
+		{"late start, early end", at(11, 14), at(12, 10), []string{"2024-03-11", "2024-03-12"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, day := range region.WorkingDays(tt.start, tt.end) {
+			got = append(got, day.Format("2006-01-02"))
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: WorkingDays = %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: WorkingDays = %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	"slack-leaves-ai-agent/models"
+)
+
+// ruleConfidence is the confidence of a rule parse: the message said the
+// type and day plainly, or it wouldn't have matched.
+const ruleConfidence = 0.95
+
+// ruleTypes maps the phrasings the rule parser knows to their leave type.
+// A "sick" entry is a full day off flagged as sick.
+var ruleTypes = map[string]string{
+	"wfh":                       "WFH",
+	"working from home":         "WFH",
+	"work from home":            "WFH",
+	"working remotely":          "WFH",
+	"on leave":                  "FULL_DAY",
+	"leave":                     "FULL_DAY",
+	"taking leave":              "FULL_DAY",
+	"taking a leave":            "FULL_DAY",
+	"off":                       "FULL_DAY",
+	"day off":                   "FULL_DAY",
+	"taking the day off":        "FULL_DAY",
+	"out of office":             "FULL_DAY",
+	"ooo":                       "FULL_DAY",
+	"on pto":                    "FULL_DAY",
+	"pto":                       "FULL_DAY",
+	"sick":                      "sick",
+	"off sick":                  "sick",
+	"out sick":                  "sick",
+	"sick leave":                "sick",
+	"on sick leave":             "sick",
+	"taking sick leave":         "sick",
+	"wfo":                       "IN_OFFICE",
+	"in office":                 "IN_OFFICE",
+	"in the office":             "IN_OFFICE",
+	"working from office":       "IN_OFFICE",
+	"working from the office":   "IN_OFFICE",
+	"coming to office":          "IN_OFFICE",
+	"coming to the office":      "IN_OFFICE",
+	"coming into the office":    "IN_OFFICE",
+	"going to be in the office": "IN_OFFICE",
+}
+
+var ruleWeekdays = map[string]time.Weekday{
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+}
+
+var (
+	ruleNoise   = regexp.MustCompile(`<[^>]*>|:[a-z0-9_+-]+:`)
+	rulePrefix  = regexp.MustCompile(`^(?:(?:hi|hey|hello|good morning|morning)(?: (?:all|team|everyone|folks))? )?(?:(?:i'm|im|i am|i'll be|ill be|i will be|will be|i'll|i will) )?`)
+	ruleDay     = `today|tomorrow|tmrw|tmr|(?:on )?(?:` + ruleAlternation(ruleWeekdays) + `)`
+	ruleTime    = `(\d{1,2})(?:[:.](\d{2}))? ?(am|pm)?`
+	ruleTypeDay = regexp.MustCompile(`^(` + ruleAlternation(ruleTypes) + `) (` + ruleDay + `)$`)
+	ruleDayType = regexp.MustCompile(`^(today|tomorrow|tmrw|tmr) (` + ruleAlternation(ruleTypes) + `)$`)
+	// ruleLate only takes "late" or coming-in phrasings: "in office by 10"
+	// is as likely an office day as a late arrival, so it goes to the model
+	ruleLate = regexp.MustCompile(`^(?:(?:(?:running|a bit|a little) )*late (?:coming in|reaching office|reaching|in)|coming in|reaching office|reaching) (?:at|by) ` +
+		ruleTime + `(?: (` + ruleDay + `))?$`)
+)
+
+// ruleAlternation is a regexp alternation of the keys of m, longest first so
+// "on leave" wins over "leave".
+func ruleAlternation[V any](m map[string]V) string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, regexp.QuoteMeta(key))
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	return strings.Join(keys, "|")
+}
+
+// normalizeRuleText lowercases a message and strips what doesn't change its
+// meaning: mentions, emoji, punctuation and extra whitespace.
+func normalizeRuleText(text string) string {
+	text = strings.ToLower(strings.ReplaceAll(text, "’", "'"))
+	text = ruleNoise.ReplaceAllString(text, " ")
+	text = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == '\'', r == ':', r == '.':
+			return r
+		case r == '-', r == ',', unicode.IsSpace(r):
+			return ' '
+		}
+		return -1
+	}, text)
+	text = strings.Join(strings.Fields(text), " ")
+	text = strings.TrimRight(text, ".")
+	return rulePrefix.ReplaceAllString(text, "")
+}
+
+// ParseLeaveRules parses the most common phrasings of a single item without
+// the model: "WFH today", "on leave tomorrow", "off Friday", "coming in at
+// 11". It returns nil for anything else, including a weekday that could
+// mean today or next week and any question ("off tomorrow?"), so those go
+// to the model. Matches are validated against region like the model's
+// parses.
+func ParseLeaveRules(text string, now time.Time, region Region) []*LeaveResponse {
+	if strings.ContainsAny(text, "?？") {
+		return nil
+	}
+	loc := region.location()
+	now = now.In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	normalized := normalizeRuleText(text)
+	workStart, workEnd := region.workDay()
+
+	resp := &LeaveResponse{IsValid: true, Confidence: ruleConfidence, Phrases: []string{strings.TrimSpace(text)}}
+	var day time.Time
+	var ok bool
+	switch {
+	case ruleTypeDay.MatchString(normalized):
+		m := ruleTypeDay.FindStringSubmatch(normalized)
+		resp.LeaveType = ruleTypes[m[1]]
+		day, ok = ruleDate(m[2], today, resp)
+	case ruleDayType.MatchString(normalized):
+		m := ruleDayType.FindStringSubmatch(normalized)
+		resp.LeaveType = ruleTypes[m[2]]
+		day, ok = ruleDate(m[1], today, resp)
+	case ruleLate.MatchString(normalized):
+		m := ruleLate.FindStringSubmatch(normalized)
+		resp.LeaveType = "LATE_ARRIVAL"
+		day, ok = today, true
+		if m[4] != "" {
+			day, ok = ruleDate(m[4], today, resp)
+		}
+		arrival, inWorkDay := ruleArrival(m[1], m[2], m[3], workStart, workEnd)
+		if !ok || !inWorkDay {
+			return nil
+		}
+		resp.StartTime = ruleClock(day, workStart)
+		resp.EndTime = ruleClock(day, arrival)
+		resp.Duration = models.FormatDuration(resp.StartTime, resp.EndTime)
+		resp.Defaults = append(resp.Defaults, "the day taken to start at "+resp.StartTime.Format("3:04 PM"))
+		return []*LeaveResponse{ruleValidate(resp, now, region)}
+	default:
+		return nil
+	}
+	if !ok {
+		return nil
+	}
+
+	if resp.LeaveType == "sick" {
+		resp.LeaveType, resp.Sick, resp.Reason = "FULL_DAY", true, "sick"
+	}
+	resp.StartTime = ruleClock(day, workStart)
+	resp.EndTime = ruleClock(day, workEnd)
+	resp.Duration = models.FormatDuration(resp.StartTime, resp.EndTime)
+	resp.Defaults = append(resp.Defaults, "no time given, used the "+region.workDayText()+" work day")
+	return []*LeaveResponse{ruleValidate(resp, now, region)}
+}
+
+// ruleDate resolves a day phrase. A weekday is the next one after today;
+// today's own weekday is ambiguous and doesn't resolve.
+func ruleDate(phrase string, today time.Time, resp *LeaveResponse) (time.Time, bool) {
+	switch phrase {
+	case "today":
+		return today, true
+	case "tomorrow", "tmrw", "tmr":
+		return today.AddDate(0, 0, 1), true
+	}
+	weekday, ok := ruleWeekdays[strings.TrimPrefix(phrase, "on ")]
+	if !ok {
+		return time.Time{}, false
+	}
+	ahead := (int(weekday) - int(today.Weekday()) + 7) % 7
+	if ahead == 0 {
+		return time.Time{}, false
+	}
+	day := today.AddDate(0, 0, ahead)
+	resp.Defaults = append(resp.Defaults, fmt.Sprintf("%q read as %s", phrase, day.Format("Monday, Jan 2")))
+	return day, true
+}
+
+// ruleArrival returns an arrival time as an offset into the day. Without am
+// or pm, 1 to 6 are afternoon hours. It must fall inside the work day
+// between workStart and workEnd.
+func ruleArrival(hourText, minuteText, meridiem string, workStart, workEnd time.Duration) (time.Duration, bool) {
+	hour, _ := strconv.Atoi(hourText)
+	minute := 0
+	if minuteText != "" {
+		minute, _ = strconv.Atoi(minuteText)
+	}
+	if hour < 1 || hour > 12 || minute > 59 {
+		return 0, false
+	}
+	switch {
+	case meridiem == "am" && hour == 12:
+		hour = 0
+	case meridiem == "pm" && hour < 12, meridiem == "" && hour <= 6:
+		hour += 12
+	}
+	arrival := time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute
+	if arrival <= workStart || arrival >= workEnd {
+		return 0, false
+	}
+	return arrival, true
+}
+
+// ruleClock is the wall-clock time of day on day, in day's location.
+func ruleClock(day time.Time, of time.Duration) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), int(of.Hours()), int(of.Minutes())%60, 0, 0, day.Location())
+}
+
+// ruleValidate applies the region's booking rules to a rule parse and sets
+// its outcome.
+func ruleValidate(resp *LeaveResponse, now time.Time, region Region) *LeaveResponse {
+	outcome, reason := region.ForLeaveType(resp.LeaveType).validate(resp.StartTime, resp.EndTime, now)
+	if reason != "" {
+		resp.IsValid = false
+		resp.Error = reason
+	}
+	resp.Outcome = outcome
+	return resp
+}
+
+// ParserStats counts which parser read each leave message since start.
+type ParserStats struct {
+	Rules int64 `json:"rules"` // a common phrasing, read without the model
+	Model int64 `json:"model"`
+}
+
+type parserCounters struct {
+	rules, model atomic.Int64
+}
+
+// ParserStats returns the parser counts so far.
+func (s *OpenAIService) ParserStats() ParserStats {
+	return ParserStats{Rules: s.parsers.rules.Load(), Model: s.parsers.model.Load()}
+}
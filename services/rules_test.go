@@ -0,0 +1,211 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func TestParseLeaveRulesPhrasings(t *testing.T) {
+	loc := mustLoadLocation(t, "Asia/Kolkata")
+	region := Region{Timezone: loc}
+	now := time.Date(2024, time.March, 4, 10, 0, 0, 0, loc) // a Monday
+	tomorrow := time.Date(2024, time.March, 5, 0, 0, 0, 0, loc)
+
+	tests := []struct {
+		text      string
+		leaveType string // "" when the rules shouldn't read it
+		sick      bool
+		day       time.Time
+	}{
+		{"WFH today", "WFH", false, now},
+		{"wfh tomorrow", "WFH", false, tomorrow},
+		{"Hi team, I'm working from home tomorrow.", "WFH", false, tomorrow},
+		{"<@U123> :house: wfh tmrw!", "WFH", false, tomorrow},
+		{"on leave tomorrow", "FULL_DAY", false, tomorrow},
+		{"tomorrow off", "FULL_DAY", false, tomorrow},
+		{"Good morning all, I’ll be OOO today", "FULL_DAY", false, now},
+		{"out sick today", "FULL_DAY", true, now},
+		{"sick leave tomorrow", "FULL_DAY", true, tomorrow},
+		{"in the office tomorrow", "IN_OFFICE", false, tomorrow},
+		{"WFO on Wednesday", "IN_OFFICE", false, time.Date(2024, time.March, 6, 0, 0, 0, 0, loc)},
+
+		{"WFH tomorrow and off Friday", "", false, time.Time{}},
+		{"might be WFH tomorrow", "", false, time.Time{}},
+		{"off next week", "", false, time.Time{}},
+		{"WFH tomorrow afternoon", "", false, time.Time{}},
+		{"in office by 10", "", false, time.Time{}},
+		{"what's the leave policy?", "", false, time.Time{}},
+		{"off tomorrow?", "", false, time.Time{}},
+		{"wfh today?", "", false, time.Time{}},
+		{"leave tomorrow?", "", false, time.Time{}},
+		{"on leave tomorrow ?", "", false, time.Time{}},
+		{"<@U123> WFH tomorrow??", "", false, time.Time{}},
+	}
+	for _, tt := range tests {
+		got := ParseLeaveRules(tt.text, now, region)
+		if tt.leaveType == "" {
+			if got != nil {
+				t.Errorf("%q: parsed as %+v, want nil", tt.text, got[0])
+			}
+			continue
+		}
+		if len(got) != 1 {
+			t.Errorf("%q: got %d items, want 1", tt.text, len(got))
+			continue
+		}
+		resp := got[0]
+		if !resp.IsValid || resp.LeaveType != tt.leaveType || resp.Sick != tt.sick {
+			t.Errorf("%q: got valid %v, type %s, sick %v; want %s, sick %v",
+				tt.text, resp.IsValid, resp.LeaveType, resp.Sick, tt.leaveType, tt.sick)
+		}
+		if resp.StartTime.Format("2006-01-02") != tt.day.Format("2006-01-02") {
+			t.Errorf("%q: starts %s, want %s", tt.text, resp.StartTime.Format("2006-01-02"), tt.day.Format("2006-01-02"))
+		}
+		if resp.StartTime.Hour() != 9 || resp.EndTime.Hour() != 18 {
+			t.Errorf("%q: %s to %s, want the 9 to 6 work day", tt.text, resp.StartTime.Format("15:04"), resp.EndTime.Format("15:04"))
+		}
+		if resp.Confidence != ruleConfidence || resp.Outcome != OutcomeValid {
+			t.Errorf("%q: confidence %v, outcome %s", tt.text, resp.Confidence, resp.Outcome)
+		}
+	}
+}
+
+func TestParseLeaveRulesWeekdays(t *testing.T) {
+	loc := mustLoadLocation(t, "Asia/Kolkata")
+	region := Region{Timezone: loc}
+	wednesday := time.Date(2024, time.March, 6, 10, 0, 0, 0, loc)
+
+	tests := []struct {
+		text string
+		want string // "" when it's ambiguous
+	}{
+		{"off thursday", "2024-03-07"},
+		{"off on Fri", "2024-03-08"},
+		{"wfh monday", "2024-03-11"}, // the next Monday, not the past one
+		{"wfh tues", "2024-03-12"},
+		{"off wednesday", ""}, // today, or a week from now
+	}
+	for _, tt := range tests {
+		got := ParseLeaveRules(tt.text, wednesday, region)
+		if tt.want == "" {
+			if got != nil {
+				t.Errorf("%q: parsed as %s, want nil", tt.text, got[0].StartTime.Format("2006-01-02"))
+			}
+			continue
+		}
+		if got == nil {
+			t.Errorf("%q: not parsed, want %s", tt.text, tt.want)
+			continue
+		}
+		if day := got[0].StartTime.Format("2006-01-02"); day != tt.want {
+			t.Errorf("%q: %s, want %s", tt.text, day, tt.want)
+		}
+		if len(got[0].Defaults) == 0 {
+			t.Errorf("%q: the weekday reading isn't explained", tt.text)
+		}
+	}
+}
+
+// "today" is the day in the author's office, whatever the server's clock.
+func TestParseLeaveRulesTimezones(t *testing.T) {
+	now := time.Date(2024, time.March, 4, 20, 0, 0, 0, time.UTC)
+	tests := []struct {
+		timezone        string
+		today, tomorrow string
+	}{
+		{"Asia/Kolkata", "2024-03-05", "2024-03-06"}, // already 1:30 AM on the 5th
+		{"America/Los_Angeles", "2024-03-04", "2024-03-05"},
+		{"Pacific/Auckland", "2024-03-05", "2024-03-06"},
+		{"UTC", "2024-03-04", "2024-03-05"},
+	}
+	for _, tt := range tests {
+		loc := mustLoadLocation(t, tt.timezone)
+		region := Region{Timezone: loc}
+		for text, want := range map[string]string{"wfh today": tt.today, "wfh tomorrow": tt.tomorrow} {
+			got := ParseLeaveRules(text, now, region)
+			if len(got) != 1 {
+				t.Errorf("%s, %q: got %d items, want 1", tt.timezone, text, len(got))
+				continue
+			}
+			if got[0].StartTime.Location() != loc {
+				t.Errorf("%s, %q: start in %s", tt.timezone, text, got[0].StartTime.Location())
+			}
+			if day := got[0].StartTime.Format("2006-01-02"); day != want {
+				t.Errorf("%s, %q: %s, want %s", tt.timezone, text, day, want)
+			}
+		}
+	}
+}
+
+func TestParseLeaveRulesLateArrival(t *testing.T) {
+	loc := mustLoadLocation(t, "Europe/London")
+	now := time.Date(2024, time.March, 4, 8, 0, 0, 0, loc)
+	region := Region{Timezone: loc, WorkStartHour: 10, WorkEndHour: 19}
+
+	tests := []struct {
+		text    string
+		arrival string // "" when the rules shouldn't read it
+		day     string
+	}{
+		{"coming in at 11", "11:00", "2024-03-04"},
+		{"running late, in by 10:30", "10:30", "2024-03-04"},
+		{"a bit late reaching by 10:45", "10:45", "2024-03-04"},
+		{"late coming in at 12.15 pm tomorrow", "12:15", "2024-03-05"},
+		{"coming in at 3", "15:00", "2024-03-04"},    // 1 to 6 are afternoon hours
+		{"coming in at 6:30", "18:30", "2024-03-04"}, // still inside a work day ending at 7
+		{"coming in at 10", "", ""},                  // the work day's start isn't late
+		{"coming in at 9:30 am", "", ""},             // before the work day
+		{"coming in at 7 pm", "", ""},                // the work day's end
+		{"coming in at 13", "", ""},
+		{"coming in at 11:75", "", ""},
+	}
+	for _, tt := range tests {
+		got := ParseLeaveRules(tt.text, now, region)
+		if tt.arrival == "" {
+			if got != nil {
+				t.Errorf("%q: parsed as %s to %s, want nil", tt.text, got[0].StartTime.Format("15:04"), got[0].EndTime.Format("15:04"))
+			}
+			continue
+		}
+		if len(got) != 1 {
+			t.Errorf("%q: got %d items, want 1", tt.text, len(got))
+			continue
+		}
+		resp := got[0]
+		if resp.LeaveType != "LATE_ARRIVAL" || !resp.IsValid {
+			t.Errorf("%q: type %s, valid %v", tt.text, resp.LeaveType, resp.IsValid)
+		}
+		if start := resp.StartTime.Format("15:04"); start != "10:00" {
+			t.Errorf("%q: starts %s, want the 10:00 work day start", tt.text, start)
+		}
+		if end := resp.EndTime.Format("15:04"); end != tt.arrival {
+			t.Errorf("%q: arrives %s, want %s", tt.text, end, tt.arrival)
+		}
+		if day := resp.StartTime.Format("2006-01-02"); day != tt.day {
+			t.Errorf("%q: on %s, want %s", tt.text, day, tt.day)
+		}
+	}
+}
+
+func TestParseLeaveRulesValidates(t *testing.T) {
+	loc := mustLoadLocation(t, "Asia/Kolkata")
+	now := time.Date(2024, time.March, 7, 10, 0, 0, 0, loc)
+	region := Region{Timezone: loc, Holidays: map[string]string{"2024-03-08": "Maha Shivaratri"}}
+
+	got := ParseLeaveRules("off tomorrow", now, region)
+	if len(got) != 1 {
+		t.Fatalf("got %d items, want 1", len(got))
+	}
+	if got[0].IsValid || got[0].Error == "" {
+		t.Errorf("leave on a public holiday: valid %v, error %q", got[0].IsValid, got[0].Error)
+	}
+}
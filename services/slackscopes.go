@@ -0,0 +1,35 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// SlackScopes returns the OAuth scopes granted to a Slack token. auth.test
+// only reports them in its X-OAuth-Scopes response header, which the Slack
+// client doesn't expose.
+func SlackScopes(ctx context.Context, token string) (map[string]bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://slack.com/api/auth.test", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	scopes := make(map[string]bool)
+	for _, scope := range strings.Split(resp.Header.Get("X-OAuth-Scopes"), ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes[scope] = true
+		}
+	}
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("Slack didn't report the token's scopes")
+	}
+	return scopes, nil
+}
@@ -0,0 +1,244 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/sashabaranov/go-openai/jsonschema"
+)
+
+// leaveTypes are the leave_type values the parser may return.
+var leaveTypes = models.LeaveTypeNames(nil)
+
+// argsValidator is implemented by tool arguments that can be wrong in ways
+// the schema can't express. A validation error is shown to the model like a
+// decoding error.
+type argsValidator interface {
+	validateArgs() error
+}
+
+// chat sends a chat completion bounded by the service timeout and returns
+// the first choice's message.
+func (s *OpenAIService) chat(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionMessage, error) {
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	resp, err := s.client.CreateChatCompletion(ctx, req)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			s.log.Printf("Request timed out after %s", s.timeout)
+			return openai.ChatCompletionMessage{}, ErrTimeout
+		}
+		return openai.ChatCompletionMessage{}, fmt.Errorf("OpenAI API error: %v", err)
+	}
+	if len(resp.Choices) == 0 {
+		return openai.ChatCompletionMessage{}, fmt.Errorf("OpenAI API error: empty response")
+	}
+	return resp.Choices[0].Message, nil
+}
+
+// callTool makes the model answer req by calling fn, and decodes the call's
+// arguments into v. Arguments that don't decode, or that v's validateArgs
+// rejects, are sent back to the model with the error, once, for it to call
+// fn again. It returns the arguments that were decoded.
+func (s *OpenAIService) callTool(ctx context.Context, req openai.ChatCompletionRequest, fn openai.FunctionDefinition, v interface{}) (string, error) {
+	req.Tools = []openai.Tool{{Type: openai.ToolTypeFunction, Function: fn}}
+	req.ToolChoice = openai.ToolChoice{Type: openai.ToolTypeFunction, Function: openai.ToolFunction{Name: fn.Name}}
+
+	reply, err := s.chat(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	args, callErr := decodeToolCall(reply, fn.Name, v)
+	if callErr == nil {
+		s.repairs.valid.Add(1)
+		return args, nil
+	}
+
+	s.log.Printf("Invalid %s call (%v), asking for it again", fn.Name, callErr)
+	retry := req
+	retry.Messages = append(append([]openai.ChatCompletionMessage{}, req.Messages...), reply)
+	feedback := fmt.Sprintf("Those arguments are invalid: %v. Call %s again with corrected arguments.", callErr, fn.Name)
+	if len(reply.ToolCalls) > 0 {
+		// Every tool call must be answered before the model is asked again
+		for _, call := range reply.ToolCalls {
+			retry.Messages = append(retry.Messages, openai.ChatCompletionMessage{
+				Role: openai.ChatMessageRoleTool, ToolCallID: call.ID, Content: feedback,
+			})
+		}
+	} else {
+		retry.Messages = append(retry.Messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: feedback})
+	}
+	// A short MaxTokens may be what cut the first call off
+	retry.MaxTokens = 0
+
+	again, err := s.chat(ctx, retry)
+	if err != nil {
+		s.repairs.failed.Add(1)
+		return "", err
+	}
+	// Don't let what the first call set leak into the second
+	reflect.ValueOf(v).Elem().Set(reflect.Zero(reflect.TypeOf(v).Elem()))
+	args, err = decodeToolCall(again, fn.Name, v)
+	if err != nil {
+		s.repairs.failed.Add(1)
+		return "", fmt.Errorf("%w: %v\nArguments: %s", ErrInvalidJSON, callErr, toolArguments(reply, fn.Name))
+	}
+	s.repairs.reasked.Add(1)
+	return args, nil
+}
+
+// decodeToolCall decodes the arguments of reply's call to name into v.
+func decodeToolCall(reply openai.ChatCompletionMessage, name string, v interface{}) (string, error) {
+	args := toolArguments(reply, name)
+	if args == "" {
+		return "", fmt.Errorf("no call to %s", name)
+	}
+	if err := json.Unmarshal([]byte(args), v); err != nil {
+		return args, err
+	}
+	if validator, ok := v.(argsValidator); ok {
+		if err := validator.validateArgs(); err != nil {
+			return args, err
+		}
+	}
+	return args, nil
+}
+
+func toolArguments(reply openai.ChatCompletionMessage, name string) string {
+	for _, call := range reply.ToolCalls {
+		if call.Function.Name == name {
+			return call.Function.Arguments
+		}
+	}
+	return ""
+}
+
+// recordAttendanceTool is the function ParseLeaveRequests has the model
+// call with the items it found.
+var recordAttendanceTool = openai.FunctionDefinition{
+	Name:        "record_attendance",
+	Description: "Record the attendance items (leave, WFH, office days, late arrivals and so on) found in a message.",
+	Parameters: jsonschema.Definition{
+		Type: jsonschema.Object,
+		Properties: map[string]jsonschema.Definition{
+			"leaves": {
+				Type:        jsonschema.Array,
+				Description: "One entry per item, in the order mentioned. A message that isn't about attendance has a single entry with is_valid false.",
+				Items: &jsonschema.Definition{
+					Type: jsonschema.Object,
+					Properties: map[string]jsonschema.Definition{
+						"is_valid":   {Type: jsonschema.Boolean},
+						"leave_type": {Type: jsonschema.String, Enum: leaveTypes, Description: "Left out when the message isn't about attendance."},
+						"start_time": {Type: jsonschema.String, Description: "RFC 3339 with the office's UTC offset, e.g. 2024-03-01T09:00:00+05:30. Left out when unknown."},
+						"end_time":   {Type: jsonschema.String, Description: "RFC 3339 with the office's UTC offset. Left out when unknown."},
+						"duration":   {Type: jsonschema.String, Description: "e.g. \"9 hours\" or \"3 days\""},
+						"reason":     {Type: jsonschema.String, Description: "The reason given, or empty."},
+						"rrule":      {Type: jsonschema.String, Description: "RFC 5545 recurrence rule, only when the item repeats."},
+						"sick":       {Type: jsonschema.Boolean},
+						"confidence": {Type: jsonschema.Number},
+						"phrases":    {Type: jsonschema.Array, Items: &jsonschema.Definition{Type: jsonschema.String}},
+						"defaults":   {Type: jsonschema.Array, Items: &jsonschema.Definition{Type: jsonschema.String}},
+						"error":      {Type: jsonschema.String, Description: "Why the item is invalid."},
+						"question":   {Type: jsonschema.String, Description: "What to ask the author when something needed is missing."},
+					},
+					Required: []string{"is_valid", "confidence"},
+				},
+			},
+		},
+		Required: []string{"leaves"},
+	},
+}
+
+// recordAttendanceArgs are the arguments of a record_attendance call.
+type recordAttendanceArgs struct {
+	Leaves []*LeaveResponse `json:"leaves"`
+}
+
+func (r *recordAttendanceArgs) validateArgs() error {
+	if len(r.Leaves) == 0 {
+		return errors.New("leaves must have at least one entry")
+	}
+	for i, leave := range r.Leaves {
+		if leave == nil {
+			return fmt.Errorf("leaves[%d] is null", i)
+		}
+		if !leave.IsValid {
+			continue
+		}
+		if leave.StartTime.IsZero() || leave.EndTime.IsZero() {
+			return fmt.Errorf("leaves[%d] is valid but start_time or end_time is missing", i)
+		}
+	}
+	return nil
+}
+
+// queryTypes are the query_type values ParseQuery understands.
+var queryTypes = []string{"top_employee", "employee_stats", "period_stats", "type_ranking", "trend", "comparison", "availability"}
+
+// describeQueryTool is the function ParseQuery has the model call with its
+// reading of the question.
+var describeQueryTool = openai.FunctionDefinition{
+	Name:        "describe_query",
+	Description: "Describe what a question about leave or attendance asks for.",
+	Parameters: jsonschema.Definition{
+		Type: jsonschema.Object,
+		Properties: map[string]jsonschema.Definition{
+			"query_type":       {Type: jsonschema.String, Enum: queryTypes},
+			"analysis_subtype": {Type: jsonschema.String, Description: "e.g. \"most_leaves\" or \"late_arrival_trend\""},
+			"start_date":       {Type: jsonschema.String, Description: "YYYY-MM-DD"},
+			"end_date":         {Type: jsonschema.String, Description: "YYYY-MM-DD"},
+			"username":         {Type: jsonschema.String},
+			"department":       {Type: jsonschema.String},
+			"employment_type":  {Type: jsonschema.String, Enum: []string{"EMPLOYEE", "CONTRACTOR"}},
+			"limit":            {Type: jsonschema.Integer},
+			"comparison_type":  {Type: jsonschema.String, Enum: []string{"greater_than", "less_than", "at_least", "at_most", "equal", "not_equal"}},
+			"comparison_value": {Type: jsonschema.Integer},
+			"subjects":         {Type: jsonschema.Array, Items: &jsonschema.Definition{Type: jsonschema.String}},
+			"leave_types":      {Type: jsonschema.Array, Items: &jsonschema.Definition{Type: jsonschema.String, Enum: leaveTypes}},
+			"group_by":         {Type: jsonschema.String, Enum: []string{"day", "week", "month", "employment_type"}},
+			"exclude_wfh":      {Type: jsonschema.Boolean},
+			"period":           {Type: jsonschema.String, Enum: []string{"fiscal_year", "quarter", "sprint"}},
+			"period_offset":    {Type: jsonschema.Integer},
+			"topic":            {Type: jsonschema.String},
+			"metrics": {
+				Type: jsonschema.Object,
+				Properties: map[string]jsonschema.Definition{
+					"count":     {Type: jsonschema.String},
+					"frequency": {Type: jsonschema.String},
+				},
+			},
+			"error":      {Type: jsonschema.String},
+			"suggestion": {Type: jsonschema.String},
+		},
+		Required: []string{"query_type", "analysis_subtype"},
+	},
+}
+
+func (q *QueryResponse) validateArgs() error {
+	if q.Error != "" {
+		return nil
+	}
+	if q.QueryType == "" {
+		return errors.New("query_type is required")
+	}
+	for _, date := range []string{q.StartDate, q.EndDate} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			return fmt.Errorf("dates must be YYYY-MM-DD, got %q", date)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"slack-leaves-ai-agent/db/migrations"
+	"slack-leaves-ai-agent/models"
+
+	"github.com/slack-go/slack"
+)
+
+// Keys of the settings store. Settings set in the environment take
+// precedence over these.
+const (
+	settingAdminChannel   = "admin_channel"
+	settingApprover       = "approver_id"
+	settingSetupCompleted = "setup_completed_at"
+)
+
+// Action IDs of the setup wizard.
+const (
+	setupChannelActionID  = "setup_admin_channel"
+	setupApproverActionID = "setup_approver"
+	setupDoneActionID     = "setup_done"
+)
+
+// adminChannel is where announcements and admin notices go: ADMIN_CHANNEL_ID,
+// or the channel picked during setup. It's "" when neither is set.
+func (a *App) adminChannel() string {
+	if a.config.AdminChannelID != "" {
+		return a.config.AdminChannelID
+	}
+	return a.setting(settingAdminChannel)
+}
+
+// fallbackApprover approves leave for people with no manager on file:
+// APPROVER_ID, or the person picked during setup.
+func (a *App) fallbackApprover() string {
+	if a.config.ApproverID != "" {
+		return a.config.ApproverID
+	}
+	return a.setting(settingApprover)
+}
+
+func (a *App) setting(key string) string {
+	value, err := a.settingsRepo.Get(key)
+	if err != nil {
+		logger.Error("Failed to load setting %s: %v", key, err)
+	}
+	return value
+}
+
+// runFirstRunSetup walks the installing admin through setup in a DM the
+// first time the bot starts: on an empty database, or on an install that
+// never had an admin channel configured. Installs configured through the
+// environment are marked as set up without asking.
+func (a *App) runFirstRunSetup(ctx context.Context, createdTables bool) {
+	completed, err := a.settingsRepo.Get(settingSetupCompleted)
+	if err != nil {
+		logger.Error("Failed to check whether setup ran: %v", err)
+		return
+	}
+	if completed != "" {
+		return
+	}
+	if !createdTables && a.config.AdminChannelID != "" {
+		a.completeSetup("system")
+		return
+	}
+
+	adminID, err := a.installingAdmin(ctx)
+	if err != nil {
+		logger.Error("Skipping first-run setup: %v", err)
+		return
+	}
+	if err := a.sendSetupWizard(ctx, adminID, createdTables); err != nil {
+		logger.Error("Failed to send setup wizard to %s: %v", adminID, err)
+		return
+	}
+	logger.Info("Sent first-run setup to %s", adminID)
+}
+
+// installingAdmin picks who to run setup with: the first of ADMIN_USER_IDS,
+// or else the workspace's primary owner, who is made an admin.
+func (a *App) installingAdmin(ctx context.Context) (string, error) {
+	if len(a.config.AdminUserIDs) > 0 {
+		return a.config.AdminUserIDs[0], nil
+	}
+
+	users, err := a.slackClient.GetUsersContext(ctx)
+	if err != nil {
+		return "", fmt.Errorf("error listing users to find the workspace owner: %v", err)
+	}
+	for _, user := range users {
+		if user.IsPrimaryOwner && !user.Deleted {
+			if err := a.roleRepo.Grant(user.ID, models.RoleAdmin); err != nil {
+				return "", fmt.Errorf("error making %s an admin: %v", user.Name, err)
+			}
+			a.audit("system", "grant_role", 0, nil, map[string]string{"user_id": user.ID, "role": models.RoleAdmin})
+			return user.ID, nil
+		}
+	}
+	return "", fmt.Errorf("no ADMIN_USER_IDS and no workspace owner found")
+}
+
+func (a *App) sendSetupWizard(ctx context.Context, userID string, createdTables bool) error {
+	if !a.hasScope(scopeIMWrite) {
+		return fmt.Errorf("missing %s scope", scopeIMWrite)
+	}
+	channel, _, _, err := a.slackClient.OpenConversationContext(ctx, &slack.OpenConversationParameters{Users: []string{userID}})
+	if err != nil {
+		return err
+	}
+	_, _, err = a.slackClient.PostMessageContext(ctx, channel.ID,
+		slack.MsgOptionText("Let's set up the leave bot", false),
+		slack.MsgOptionBlocks(a.setupBlocks(ctx, createdTables)...))
+	return err
+}
+
+// setupBlocks is the wizard: what was checked, then a picker for each
+// setting and a Done button. Settings that come from the environment are
+// shown but can't be changed here.
+func (a *App) setupBlocks(ctx context.Context, createdTables bool) []slack.Block {
+	checks := []string{fmt.Sprintf("✅ Database is at schema version %d", migrations.Version())}
+	if createdTables {
+		checks[0] = fmt.Sprintf("✅ Created the database tables (schema version %d)", migrations.Version())
+	}
+	if auth, err := a.slackClient.AuthTestContext(ctx); err != nil {
+		checks = append(checks, "❌ Slack bot token check failed: "+err.Error())
+	} else {
+		checks = append(checks, fmt.Sprintf("✅ Connected to *%s* as <@%s>", auth.Team, auth.UserID))
+	}
+	var missing []string
+	for _, scope := range coreScopes {
+		if !a.hasScope(scope) {
+			missing = append(missing, "`"+scope+"`")
+		}
+	}
+	for _, f := range featureScopes {
+		if !a.hasScope(f.scope) {
+			missing = append(missing, "`"+f.scope+"`")
+		}
+	}
+	if len(missing) > 0 {
+		checks = append(checks, "⚠️ Missing Slack scopes: "+strings.Join(missing, ", "))
+	} else {
+		checks = append(checks, "✅ All Slack scopes granted")
+	}
+
+	blocks := []slack.Block{
+		slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn",
+			"👋 *Let's set up the leave bot*\n"+strings.Join(checks, "\n"), false, false), nil, nil),
+	}
+
+	channelText := "*Announcement channel*\nWhere should I post admin notices, digests and departures? Invite me to it too."
+	if a.config.AdminChannelID != "" {
+		blocks = append(blocks, slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn",
+			fmt.Sprintf("*Announcement channel*\n<#%s>, set by `ADMIN_CHANNEL_ID`", a.config.AdminChannelID), false, false), nil, nil))
+	} else {
+		picker := slack.NewOptionsSelectBlockElement(slack.OptTypeConversations,
+			slack.NewTextBlockObject("plain_text", "Choose a channel", false, false), setupChannelActionID)
+		picker.InitialConversation = a.setting(settingAdminChannel)
+		blocks = append(blocks, slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", channelText, false, false),
+			nil, slack.NewAccessory(picker)))
+	}
+
+	approverText := "*Fallback approver*\nWho approves leave for people with no manager on file?"
+	if a.config.ApproverID != "" {
+		blocks = append(blocks, slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn",
+			fmt.Sprintf("*Fallback approver*\n<@%s>, set by `APPROVER_ID`", a.config.ApproverID), false, false), nil, nil))
+	} else {
+		picker := slack.NewOptionsSelectBlockElement(slack.OptTypeUser,
+			slack.NewTextBlockObject("plain_text", "Choose a person", false, false), setupApproverActionID)
+		picker.InitialUser = a.setting(settingApprover)
+		blocks = append(blocks, slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", approverText, false, false),
+			nil, slack.NewAccessory(picker)))
+	}
+
+	done := slack.NewButtonBlockElement(setupDoneActionID, "done",
+		slack.NewTextBlockObject("plain_text", "Done", false, false)).WithStyle(slack.StylePrimary)
+	return append(blocks, slack.NewActionBlock("setup_actions", done))
+}
+
+func setupInteraction(callback slack.InteractionCallback) bool {
+	for _, action := range callback.ActionCallback.BlockActions {
+		switch action.ActionID {
+		case setupChannelActionID, setupApproverActionID, setupDoneActionID:
+			return true
+		}
+	}
+	return false
+}
+
+// handleSetupAction saves what the admin picked in the wizard. Done marks
+// setup as completed and replaces the wizard with a summary; it can be run
+// again with `/admin-leave setup`.
+func (a *App) handleSetupAction(callback slack.InteractionCallback) {
+	if !a.isAdmin(callback.User.ID) {
+		a.replyFeedback(callback, "❌ Only admins can change the bot's setup.")
+		return
+	}
+	actor := "slack:" + callback.User.ID
+
+	for _, action := range callback.ActionCallback.BlockActions {
+		switch action.ActionID {
+		case setupChannelActionID:
+			a.saveSetting(actor, settingAdminChannel, action.SelectedConversation)
+		case setupApproverActionID:
+			a.saveSetting(actor, settingApprover, action.SelectedUser)
+		case setupDoneActionID:
+			a.completeSetup(actor)
+			_, _, err := a.slackClient.PostMessage(callback.Channel.ID,
+				slack.MsgOptionReplaceOriginal(callback.ResponseURL),
+				slack.MsgOptionText(a.setupSummary(), false))
+			if err != nil {
+				logger.Error("Failed to update setup wizard: %v", err)
+			}
+		}
+	}
+}
+
+func (a *App) saveSetting(actor, key, value string) {
+	before := a.setting(key)
+	if err := a.settingsRepo.Set(key, value, actor); err != nil {
+		logger.Error("Failed to save setting %s: %v", key, err)
+		return
+	}
+	a.audit(actor, "setting_update", 0, map[string]string{key: before}, map[string]string{key: value})
+}
+
+func (a *App) completeSetup(actor string) {
+	if err := a.settingsRepo.Set(settingSetupCompleted, time.Now().UTC().Format(time.RFC3339), actor); err != nil {
+		logger.Error("Failed to mark setup as completed: %v", err)
+	}
+}
+
+func (a *App) setupSummary() string {
+	channel, approver := "none, notices are only logged", "none, leave of people with no manager isn't held for approval"
+	if id := a.adminChannel(); id != "" {
+		channel = "<#" + id + ">"
+	}
+	if id := a.fallbackApprover(); id != "" {
+		approver = "<@" + id + ">"
+	}
+	return fmt.Sprintf("✅ *Setup done*\nAnnouncement channel: %s\nFallback approver: %s\nRun `/admin-leave setup` to change these.",
+		channel, approver)
+}
+
+// runSetupCommand handles `/admin-leave setup`, which sends the wizard to
+// the admin who ran it.
+func (a *App) runSetupCommand(actor string, args []string) (string, error) {
+	if len(args) != 0 {
+		return adminLeaveUsage, nil
+	}
+	if err := a.sendSetupWizard(context.Background(), strings.TrimPrefix(actor, "slack:"), false); err != nil {
+		return "", fmt.Errorf("error sending the setup wizard: %v", err)
+	}
+	return "📬 Sent you the setup wizard in a DM.", nil
+}
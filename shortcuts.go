@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+	"slack-leaves-ai-agent/services"
+
+	"github.com/slack-go/slack"
+)
+
+// logAsLeaveCallbackID is the callback ID of the "Log as leave" message
+// shortcut configured in the Slack app.
+const logAsLeaveCallbackID = "log_as_leave"
+
+// handleLogAsLeaveShortcut sends an existing message through the parser after
+// the fact, recording the leave for the message's author. Relative dates are
+// resolved against when the message was posted, not when the shortcut is used.
+func (a *App) handleLogAsLeaveShortcut(ctx context.Context, callback slack.InteractionCallback) {
+	clickerID := callback.User.ID
+	channelID := callback.Channel.ID
+	msg := callback.Message
+
+	reply := func(text string) {
+		_, err := a.slackClient.PostEphemeral(channelID, clickerID, slack.MsgOptionText(text, false))
+		if err != nil {
+			logger.Error("Failed to post shortcut reply: %v", err)
+		}
+	}
+
+	if msg.User == "" || msg.BotID != "" {
+		reply("❌ Only messages written by people can be logged as leave.")
+		return
+	}
+
+	if msg.User != clickerID && !a.canLogForOthers(clickerID) {
+		reply("❌ You can only log your own messages as leave.")
+		return
+	}
+
+	author, err := a.slackClient.GetUserInfoContext(ctx, msg.User)
+	if err != nil {
+		logger.Error("Error getting user info: %v", err)
+		reply("❌ Couldn't look up the author of that message.")
+		return
+	}
+
+	postedAt := parseSlackTimestamp(msg.Timestamp)
+	results, err := a.openAI.ParseLeaveRequestBatch(ctx, []services.BatchMessage{{
+		ID:       msg.Timestamp,
+		Text:     msg.Text,
+		PostedAt: postedAt,
+		Location: a.regionFor(author.Name).Timezone,
+	}})
+	if err != nil {
+		logger.Error("Error parsing shortcut message: %v", err)
+		a.notifyIfTimeout(err, channelID, clickerID)
+		reply("❌ Couldn't parse that message, please try again.")
+		return
+	}
+
+	response, ok := results[msg.Timestamp]
+	if !ok || !response.IsValid {
+		reason := "it doesn't look like an attendance update"
+		if ok && response.Error != "" {
+			reason = response.Error
+		}
+		reply(fmt.Sprintf("❌ Unable to log that message as leave: %s", reason))
+		return
+	}
+
+	leave := &models.Leave{
+		Username:     author.Name,
+		OriginalText: msg.Text,
+		StartTime:    response.StartTime,
+		EndTime:      response.EndTime,
+		Duration:     response.Duration,
+		Reason:       response.Reason,
+		LeaveType:    response.LeaveType,
+
+		ParseConfidence: response.Confidence,
+		Trace:           newParseTrace("shortcut", response, a.regionFor(author.Name).Timezone),
+		Sick:            response.Sick,
+	}
+
+	violations, err := a.recordLeave(ctx, "slack:"+clickerID, "shortcut_create", leave, author.Profile.Email)
+	var locked *ErrPeriodLocked
+	if errors.As(err, &locked) {
+		reply(fmt.Sprintf("🔒 Can't log that message: %v. Ask an admin to use `/admin-leave create ... --override`.", err))
+		return
+	}
+	var rejected *ErrHookRejected
+	if errors.As(err, &rejected) {
+		reply(fmt.Sprintf("🚫 Can't log that message: %v", rejected))
+		return
+	}
+	if err != nil {
+		logger.Error("Error saving leave: %v", err)
+		reply("❌ Failed to save the leave record.")
+		return
+	}
+
+	_, _, err = a.slackClient.PostMessage(
+		channelID,
+		append(confirmationOptions(leave), slack.MsgOptionTS(msg.Timestamp))...,
+	)
+	if err != nil {
+		logger.Error("Error sending confirmation: %v", err)
+	}
+
+	if warning := policyWarningText(violations); warning != "" {
+		_, _, err = a.slackClient.PostMessage(channelID, slack.MsgOptionText(warning, false), slack.MsgOptionTS(msg.Timestamp))
+		if err != nil {
+			logger.Error("Error sending policy warning: %v", err)
+		}
+	}
+
+	a.maybeSendHandover(ctx, leave, msg.User, author.Profile.Email)
+}
+
+// canLogForOthers reports whether userID may log someone else's message, i.e.
+// is an admin or HR.
+func (a *App) canLogForOthers(userID string) bool {
+	if a.isAdmin(userID) {
+		return true
+	}
+	ok, err := a.roleRepo.HasRole(userID, models.RoleHR)
+	if err != nil {
+		logger.Error("Failed to check roles for %s: %v", userID, err)
+		return false
+	}
+	return ok
+}
+
+// parseSlackTimestamp converts a message ts ("1700000000.000100") to a time.
+func parseSlackTimestamp(ts string) time.Time {
+	secs, _, _ := strings.Cut(ts, ".")
+	n, err := strconv.ParseInt(secs, 10, 64)
+	if err != nil {
+		return time.Now()
+	}
+	return time.Unix(n, 0)
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"slack-leaves-ai-agent/services"
+
+	"github.com/slack-go/slack"
+)
+
+// similarPrefix switches /query to semantic search over reasons and
+// messages, e.g. `/query similar: medical appointments last quarter`.
+const similarPrefix = "similar:"
+
+// embeddingBatchSize is how many records are embedded per API call.
+const embeddingBatchSize = 100
+
+// embeddingInterval is how often records that changed are re-embedded.
+const embeddingInterval = 10 * time.Minute
+
+// maxSimilarResults keeps the answer within one Slack message.
+const maxSimilarResults = 20
+
+// similarTopic returns what a `similar:` query searches for.
+func similarTopic(text string) (string, bool) {
+	text = strings.TrimSpace(text)
+	if len(text) < len(similarPrefix) || !strings.EqualFold(text[:len(similarPrefix)], similarPrefix) {
+		return "", false
+	}
+	return strings.TrimSpace(text[len(similarPrefix):]), true
+}
+
+// semanticSearchReady reports whether embeddings are turned on and the
+// database has pgvector.
+func (a *App) semanticSearchReady() bool {
+	if !a.config.EmbeddingsEnabled {
+		return false
+	}
+	available, err := a.embeddingRepo.Available()
+	if err != nil {
+		logger.Error("Failed to check for leave embeddings: %v", err)
+		return false
+	}
+	return available
+}
+
+// runEmbeddings keeps every record's embedding up to date, when
+// EMBEDDINGS_ENABLED is on and pgvector is installed.
+func (a *App) runEmbeddings(ctx context.Context) {
+	if !a.semanticSearchReady() {
+		if a.config.EmbeddingsEnabled {
+			logger.Error("Semantic search disabled: the database has no pgvector extension")
+		}
+		return
+	}
+
+	a.embedStaleLeaves(ctx)
+	ticker := time.NewTicker(embeddingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			a.embedStaleLeaves(ctx)
+		}
+	}
+}
+
+func (a *App) embedStaleLeaves(ctx context.Context) {
+	embedded := 0
+	for {
+		texts, err := a.embeddingRepo.ListStale(embeddingBatchSize)
+		if err != nil {
+			logger.Error("Failed to list records to embed: %v", err)
+			return
+		}
+		if len(texts) == 0 {
+			break
+		}
+
+		inputs := make([]string, len(texts))
+		for i, text := range texts {
+			inputs[i] = text.Text
+		}
+		vectors, err := a.openAI.Embed(ctx, inputs)
+		if err != nil {
+			logger.Error("Failed to embed records: %v", err)
+			return
+		}
+		for i, text := range texts {
+			if err := a.embeddingRepo.Save(text.LeaveID, services.EmbeddingModel, vectors[i]); err != nil {
+				logger.Error("Failed to save embedding of leave %d: %v", text.LeaveID, err)
+				return
+			}
+		}
+		embedded += len(texts)
+	}
+	if embedded > 0 {
+		logger.Info("Embedded %d records for semantic search", embedded)
+	}
+}
+
+// handleSimilarQuery answers `/query similar: ...` with the records whose
+// reasons and messages are closest in meaning. Reasons can be personal, so
+// it's for HR and admins, and the answer only goes to whoever asked.
+func (a *App) handleSimilarQuery(ctx context.Context, cmd slack.SlashCommand, text string) {
+	reply := func(text string) {
+		_, err := a.slackClient.PostEphemeral(cmd.ChannelID, cmd.UserID, slack.MsgOptionText(text, false))
+		if err != nil {
+			logger.Error("Failed to post similar query reply: %v", err)
+		}
+	}
+
+	if !a.canViewReports(cmd.UserID) {
+		reply("❌ Searching reasons is only available to HR and admins.")
+		return
+	}
+	if !a.semanticSearchReady() {
+		reply("❌ Semantic search isn't turned on here.")
+		return
+	}
+	if text == "" {
+		reply("Say what to look for, e.g. `/query similar: medical appointments last quarter`")
+		return
+	}
+
+	answer, err := a.similarText(ctx, text)
+	if errors.Is(err, services.ErrTimeout) {
+		a.notifyIfTimeout(err, cmd.ChannelID, cmd.UserID)
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to run similar query: %v", err)
+		reply("❌ " + err.Error())
+		return
+	}
+	reply(answer)
+}
+
+func (a *App) similarText(ctx context.Context, text string) (string, error) {
+	// The parser pulls the period and the subject apart
+	queryResp, err := a.openAI.ParseQuery(ctx, text, nil, a.config.Timezone)
+	if err != nil {
+		return "", err
+	}
+	if queryResp.Period != "" {
+		if err := a.applyReportingPeriod(queryResp); err != nil {
+			return "", err
+		}
+	}
+
+	var start, end time.Time
+	period := "all time"
+	if queryResp.StartDate != "" || queryResp.EndDate != "" {
+		if start, end, err = queryPeriod(queryResp, a.today()); err != nil {
+			return "", err
+		}
+		period = fmt.Sprintf("%s to %s", start.Format("Jan 2, 2006"), end.Format("Jan 2, 2006"))
+		end = end.AddDate(0, 0, 1)
+	}
+
+	topic := queryResp.Topic
+	if topic == "" {
+		topic = text
+	}
+	vectors, err := a.openAI.Embed(ctx, []string{topic})
+	if err != nil {
+		return "", err
+	}
+	results, err := a.embeddingRepo.Search(vectors[0], start, end, maxSimilarResults)
+	if err != nil {
+		return "", fmt.Errorf("search failed: %v", err)
+	}
+	if len(results) == 0 {
+		return fmt.Sprintf("No records found for %s.", period), nil
+	}
+
+	lines := []string{fmt.Sprintf("🔎 *Records most like \"%s\"*, %s", topic, period)}
+	for _, result := range results {
+		what := publicReason(&result.Leave)
+		if message := strings.Join(strings.Fields(result.OriginalText), " "); message != "" {
+			if runes := []rune(message); len(runes) > 100 {
+				message = string(runes[:100]) + "…"
+			}
+			what += " · _" + message + "_"
+		}
+		lines = append(lines, fmt.Sprintf("• *%s* – %s, %s (%.0f%%): %s", result.Username, result.LeaveType,
+			result.StartTime.Format("Mon Jan 2, 2006"), result.Similarity*100, what))
+	}
+	return strings.Join(lines, "\n"), nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+// How the bot receives events from Slack (SLACK_MODE): over a socket mode
+// connection it opens, or as requests Slack sends to /slack/events, which
+// needs a public URL but no app-level token.
+const (
+	slackModeSocket = "socket"
+	slackModeHTTP   = "http"
+)
+
+// maxSlackRequestBytes bounds the body of a request from Slack.
+const maxSlackRequestBytes = 1 << 20
+
+// handleSlackEvents serves /slack/events in HTTP mode. It takes Events API
+// callbacks, slash commands and interactions alike, so the app's Event
+// Subscriptions, Slash Commands and Interactivity URLs can all point at it.
+// Every request must carry a valid signature made with SLACK_SIGNING_SECRET.
+// Slack is answered right away and the work queued, as in socket mode.
+func (a *App) handleSlackEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxSlackRequestBytes))
+	if err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	verifier, err := slack.NewSecretsVerifier(r.Header, a.config.SlackSigningSecret)
+	if err != nil {
+		logger.Error("Rejected Slack request: %v", err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	verifier.Write(body)
+	if err := verifier.Ensure(); err != nil {
+		logger.Error("Rejected Slack request with a bad signature")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		a.serveSlackEvent(w, r, body)
+		return
+	}
+
+	// Slash commands and interactions are form posts
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	if payload := r.PostForm.Get("payload"); payload != "" {
+		var callback slack.InteractionCallback
+		if err := json.Unmarshal([]byte(payload), &callback); err != nil {
+			logger.Error("Failed to decode interaction: %v", err)
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+		writeSlackAck(w, a.dispatchInteraction(callback))
+		return
+	}
+	if r.PostForm.Get("command") != "" {
+		cmd, err := slack.SlashCommandParse(r)
+		if err != nil {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+		writeSlackAck(w, a.dispatchSlashCommand(cmd))
+		return
+	}
+	http.Error(w, "Unknown request", http.StatusBadRequest)
+}
+
+// serveSlackEvent answers an Events API request: the URL verification
+// challenge, or a callback to dispatch.
+func (a *App) serveSlackEvent(w http.ResponseWriter, r *http.Request, body []byte) {
+	// The signature has been checked, which supersedes the verification token
+	event, err := slackevents.ParseEvent(json.RawMessage(body), slackevents.OptionNoVerifyToken())
+	if err != nil {
+		// Events slack-go doesn't know still reach their handlers
+		if !a.dispatchUndecodedEvent(body) {
+			logger.Debug("Unhandled Slack event: %v", err)
+		}
+		// Anything but a 2xx makes Slack retry, which wouldn't help
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if event.Type == slackevents.URLVerification {
+		challenge, ok := event.Data.(*slackevents.EventsAPIURLVerificationEvent)
+		if !ok {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(challenge.Challenge))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if retry := r.Header.Get("X-Slack-Retry-Num"); retry != "" {
+		logger.Debug("Slack redelivered an event (attempt %s, %s)", retry, r.Header.Get("X-Slack-Retry-Reason"))
+	}
+	a.dispatchEvent(event)
+}
+
+// writeSlackAck acknowledges a slash command or interaction, with resp as
+// the JSON body when there is one.
+func writeSlackAck(w http.ResponseWriter, resp interface{}) {
+	if resp == nil {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
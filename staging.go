@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// Values of APP_ENV. A staging instance runs against the production Slack
+// workspace, so it only listens and posts in STAGING_CHANNELS, marks its
+// posts, and keeps its tables in a schema of their own.
+const (
+	envProduction = "production"
+	envStaging    = "staging"
+)
+
+// stagingPrefix marks everything a staging instance posts.
+const stagingPrefix = "[STAGING]"
+
+// stagingPostMethods are the Web API methods that post to a channel, with
+// the form field naming it.
+var stagingPostMethods = map[string]string{
+	"chat.postMessage":             "channel",
+	"chat.postEphemeral":           "channel",
+	"chat.scheduleMessage":         "channel",
+	"chat.meMessage":               "channel",
+	"chat.update":                  "channel",
+	"files.completeUploadExternal": "channel_id",
+}
+
+// stagingHTTPClient sits under the Slack client of a staging instance. Posts
+// to channels outside STAGING_CHANNELS, DMs included, never leave the
+// process: they fail with channel_not_allowed_in_staging, as a Slack error
+// would. Posts that are allowed get stagingPrefix on their text and blocks.
+type stagingHTTPClient struct {
+	next     *http.Client
+	channels []string
+}
+
+func newStagingHTTPClient(channels []string) *stagingHTTPClient {
+	return &stagingHTTPClient{next: &http.Client{Timeout: 30 * time.Second}, channels: channels}
+}
+
+func (c *stagingHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	field, ok := stagingPostMethods[path.Base(req.URL.Path)]
+	if !ok || req.Body == nil || !strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		return c.next.Do(req)
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, err
+	}
+
+	if channel := values.Get(field); !containsString(c.channels, channel) {
+		logger.Debug("Staging: not posting to %s, which isn't in STAGING_CHANNELS", channel)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"ok":false,"error":"channel_not_allowed_in_staging"}`)),
+			Request:    req,
+		}, nil
+	}
+
+	if text := values.Get("text"); text != "" && !strings.HasPrefix(text, stagingPrefix) {
+		values.Set("text", stagingPrefix+" "+text)
+	}
+	if comment := values.Get("initial_comment"); comment != "" {
+		values.Set("initial_comment", stagingPrefix+" "+comment)
+	}
+	if blocks := values.Get("blocks"); blocks != "" {
+		if marked, err := stagingBlocks(blocks); err == nil {
+			values.Set("blocks", marked)
+		}
+	}
+
+	encoded := values.Encode()
+	req.Body = io.NopCloser(strings.NewReader(encoded))
+	req.ContentLength = int64(len(encoded))
+	return c.next.Do(req)
+}
+
+// stagingBlocks puts a context block with stagingPrefix ahead of blocks, a
+// JSON array, since Slack shows blocks instead of the text.
+func stagingBlocks(blocks string) (string, error) {
+	var list []json.RawMessage
+	if err := json.Unmarshal([]byte(blocks), &list); err != nil {
+		return "", err
+	}
+	label, err := json.Marshal(map[string]interface{}{
+		"type":     "context",
+		"elements": []map[string]string{{"type": "mrkdwn", "text": "*" + stagingPrefix + "*"}},
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(list) > 0 && bytes.Contains(list[0], []byte(stagingPrefix)) {
+		return blocks, nil
+	}
+	marked, err := json.Marshal(append([]json.RawMessage{label}, list...))
+	return string(marked), err
+}
+
+// channelAllowed reports whether the bot should act on events from
+// channel: always in production, only in STAGING_CHANNELS in staging.
+func (a *App) channelAllowed(channel string) bool {
+	return a.config.Environment != envStaging || containsString(a.config.StagingChannels, channel)
+}
+
+// stagingNotice answers a slash command used outside STAGING_CHANNELS.
+func (a *App) stagingNotice() string {
+	channels := make([]string, 0, len(a.config.StagingChannels))
+	for _, channel := range a.config.StagingChannels {
+		channels = append(channels, "<#"+channel+">")
+	}
+	return fmt.Sprintf("%s This is the staging bot; it only works in %s.", stagingPrefix, strings.Join(channels, ", "))
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+
+	"github.com/slack-go/slack"
+)
+
+// runLeaveStatements DMs every active employee their leave statement for the
+// previous month on the 1st of each month, when ACCRUAL_STATEMENTS is on.
+func (a *App) runLeaveStatements(ctx context.Context) {
+	if !a.config.AccrualStatements {
+		return
+	}
+
+	loc := a.config.Timezone
+	lastRun := ""
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			now = now.In(loc)
+			thisMonth := now.Format("2006-01")
+			if lastRun == thisMonth || now.Day() != 1 || now.Hour() < 9 {
+				continue
+			}
+			lastRun = thisMonth
+			if !a.featureEnabled(flagBalances) {
+				continue
+			}
+			a.sendLeaveStatements(ctx, now.AddDate(0, -1, 0))
+		}
+	}
+}
+
+func (a *App) sendLeaveStatements(ctx context.Context, month time.Time) {
+	employees, err := a.employeeRepo.List(true)
+	if err != nil {
+		logger.Error("Failed to load employees for leave statements: %v", err)
+		return
+	}
+
+	sent := 0
+	for _, employee := range employees {
+		if employee.SlackUserID == "" {
+			continue
+		}
+		statement, err := a.leaveStatement(employee.Username, month)
+		if err != nil {
+			logger.Error("Failed to build leave statement for %s: %v", employee.Username, err)
+			continue
+		}
+		if statement == nil {
+			continue
+		}
+
+		_, _, err = a.slackClient.PostMessageContext(ctx, employee.SlackUserID,
+			slack.MsgOptionText(fmt.Sprintf("🧾 Your leave statement for %s", month.Format("January 2006")), false),
+			slack.MsgOptionBlocks(statementBlocks(statement)...),
+		)
+		if err != nil {
+			logger.Error("Failed to send leave statement to %s: %v", employee.Username, err)
+			continue
+		}
+		sent++
+	}
+	logger.Info("Sent %d leave statements for %s", sent, month.Format("January 2006"))
+}
+
+// leaveStatement builds the user's statement for the month from the balance
+// ledger. It returns nil for users whose policy doesn't accrue leave.
+func (a *App) leaveStatement(username string, month time.Time) (*models.LeaveStatement, error) {
+	policy := a.userPolicy(username)
+	if !policy.AccruesLeave {
+		return nil, nil
+	}
+
+	loc := a.config.Timezone
+	monthStart := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, loc)
+	monthEnd := monthStart.AddDate(0, 1, 0)
+	yearStart := time.Date(month.Year(), time.January, 1, 0, 0, 0, 0, loc)
+
+	totals, err := a.ledgerRepo.Totals(username, models.LedgerAnnual, yearStart, monthEnd)
+	if err != nil {
+		return nil, fmt.Errorf("error loading balance ledger: %v", err)
+	}
+	entries, err := a.ledgerRepo.List(username, monthStart, monthEnd)
+	if err != nil {
+		return nil, fmt.Errorf("error loading balance ledger: %v", err)
+	}
+	balance := balanceFromTotals(username, month.Year(), policy.AnnualLeaveDays, totals)
+
+	statement := &models.LeaveStatement{
+		Username:    username,
+		Month:       monthStart,
+		Entitlement: balance.Entitlement,
+		Accrued:     balance.Accrued,
+		Granted:     balance.Granted,
+		Used:        balance.Used,
+		Available:   balance.Available,
+		Remaining:   math.Max(balance.Entitlement+balance.Granted-balance.Used, 0),
+		Entries:     entries,
+	}
+	for _, entry := range entries {
+		if entry.EntryType == models.LedgerDebit && entry.Category == models.LedgerAnnual {
+			statement.UsedThisMonth -= entry.Days
+		}
+	}
+
+	lossOfPay, err := a.leaveRepo.GetLossOfPayDays(
+		time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC),
+		time.Date(month.Year(), month.Month()+1, 1, 0, 0, 0, 0, time.UTC),
+		[]string{username})
+	if err != nil {
+		return nil, fmt.Errorf("error loading loss of pay: %v", err)
+	}
+	for _, days := range lossOfPay {
+		statement.LossOfPay += days.WorkingDays
+	}
+
+	yearUsage, err := a.leaveRepo.GetUsageByType(username, yearStart, monthEnd)
+	if err != nil {
+		return nil, fmt.Errorf("error loading leave usage: %v", err)
+	}
+	monthUsage, err := a.leaveRepo.GetUsageByType(username, monthStart, monthEnd)
+	if err != nil {
+		return nil, fmt.Errorf("error loading leave usage: %v", err)
+	}
+	for _, u := range yearUsage {
+		statement.Categories = append(statement.Categories, models.StatementCategory{LeaveType: u.LeaveType, Records: u.Records, Days: u.Days})
+	}
+	for _, u := range monthUsage {
+		for i := range statement.Categories {
+			if statement.Categories[i].LeaveType == u.LeaveType {
+				statement.Categories[i].RecordsThisMonth = u.Records
+				statement.Categories[i].DaysThisMonth = u.Days
+			}
+		}
+	}
+	return statement, nil
+}
+
+func statementBlocks(statement *models.LeaveStatement) []slack.Block {
+	balance := fmt.Sprintf("*🌴 Annual leave*\n"+
+		"Entitlement: %s days a year\n"+
+		"Accrued so far: %s days · granted: %s days\n"+
+		"Used this year: %s days (%s in %s)\n"+
+		"Available now: *%s days* · Remaining for the year: %s days",
+		formatDays(statement.Entitlement),
+		formatDays(statement.Accrued), formatDays(statement.Granted),
+		formatDays(statement.Used), formatDays(statement.UsedThisMonth), statement.Month.Format("January"),
+		formatDays(statement.Available), formatDays(statement.Remaining))
+
+	blocks := []slack.Block{
+		slack.NewHeaderBlock(slack.NewTextBlockObject("plain_text",
+			"🧾 Leave statement — "+statement.Month.Format("January 2006"), true, false)),
+		slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", balance, false, false), nil, nil),
+	}
+
+	if statement.LossOfPay > 0 {
+		blocks = append(blocks, slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn",
+			fmt.Sprintf("*💸 Loss of pay*\n%s working days of unpaid leave in %s, deducted from your pay rather than your balance",
+				formatDays(statement.LossOfPay), statement.Month.Format("January")), false, false), nil, nil))
+	}
+
+	if len(statement.Entries) > 0 {
+		lines := make([]string, 0, len(statement.Entries))
+		for i := range statement.Entries {
+			lines = append(lines, "• "+formatLedgerEntry(&statement.Entries[i]))
+		}
+		blocks = append(blocks,
+			slack.NewDividerBlock(),
+			slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn",
+				"*Balance changes in "+statement.Month.Format("January")+"*\n"+strings.Join(lines, "\n"), false, false), nil, nil),
+		)
+	}
+
+	if len(statement.Categories) > 0 {
+		lines := make([]string, 0, len(statement.Categories))
+		for _, category := range statement.Categories {
+			lines = append(lines, statementCategoryLine(category, statement.Month))
+		}
+		blocks = append(blocks,
+			slack.NewDividerBlock(),
+			slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn",
+				"*By category, this year*\n"+strings.Join(lines, "\n"), false, false), nil, nil),
+		)
+	}
+
+	blocks = append(blocks, slack.NewContextBlock("", slack.NewTextBlockObject("mrkdwn",
+		"Annual leave accrues on the 1st of each month. Half days count as 0.5; WFH, office days and partial-day records don't use your balance.",
+		false, false)))
+	return blocks
+}
+
+func statementCategoryLine(category models.StatementCategory, month time.Time) string {
+	name := "`" + category.LeaveType + "`"
+	if category.Days > 0 {
+		return fmt.Sprintf("• %s: %s days (%s in %s)", name,
+			formatDays(category.Days), formatDays(category.DaysThisMonth), month.Format("January"))
+	}
+	return fmt.Sprintf("• %s: %d times (%d in %s)", name,
+		category.Records, category.RecordsThisMonth, month.Format("January"))
+}
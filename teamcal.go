@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+
+	"github.com/slack-go/slack"
+)
+
+const calendarLegend = "`O` out · `h` half day · `W` WFH · `L` late/early · `a` appointment · `I` in office · `.` nothing logged"
+
+// parseCalendarMonth accepts "", "next", "last", "YYYY-MM", or a month name
+// with an optional year ("march", "mar 2026").
+func parseCalendarMonth(value string, now time.Time) (time.Time, error) {
+	current := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	value = strings.ToLower(strings.TrimSpace(value))
+
+	switch value {
+	case "", "this", "current":
+		return current, nil
+	case "next":
+		return current.AddDate(0, 1, 0), nil
+	case "last", "prev", "previous":
+		return current.AddDate(0, -1, 0), nil
+	}
+
+	if month, err := time.Parse("2006-01", value); err == nil {
+		return month, nil
+	}
+	for _, layout := range []string{"January 2006", "Jan 2006", "January", "Jan"} {
+		month, err := time.Parse(layout, value)
+		if err != nil {
+			continue
+		}
+		year := month.Year()
+		if year == 0 {
+			year = now.Year()
+		}
+		return time.Date(year, month.Month(), 1, 0, 0, 0, 0, time.UTC), nil
+	}
+	return time.Time{}, fmt.Errorf("couldn't understand month %q (try `march`, `next` or `2026-03`)", value)
+}
+
+// renderTeamCalendar lays out one row per user and one column per day of
+// month. When usernames is empty everyone with a record that month is shown.
+func (a *App) renderTeamCalendar(month time.Time, usernames []string) (string, error) {
+	start := month
+	end := month.AddDate(0, 1, 0)
+
+	statuses, err := a.leaveRepo.GetDailyStatuses(start, end)
+	if err != nil {
+		return "", fmt.Errorf("error loading calendar: %v", err)
+	}
+
+	cells := make(map[string]map[int]string)
+	ranks := make(map[string]map[int]int)
+	for _, status := range statuses {
+		info, ok := models.FindLeaveType(status.LeaveType)
+		if !ok {
+			continue
+		}
+		if cells[status.Username] == nil {
+			cells[status.Username] = make(map[int]string)
+			ranks[status.Username] = make(map[int]int)
+		}
+		day := status.Day.Day()
+		if info.CalendarRank > ranks[status.Username][day] {
+			cells[status.Username][day] = info.CalendarSymbol
+			ranks[status.Username][day] = info.CalendarRank
+		}
+	}
+
+	if len(usernames) == 0 {
+		for username := range cells {
+			usernames = append(usernames, username)
+		}
+	}
+	if len(usernames) == 0 {
+		return fmt.Sprintf("Nothing recorded for %s yet.", month.Format("January 2006")), nil
+	}
+	sort.Strings(usernames)
+
+	nameWidth := 4
+	for _, username := range usernames {
+		if len(username) > nameWidth {
+			nameWidth = len(username)
+		}
+	}
+	if nameWidth > 14 {
+		nameWidth = 14
+	}
+
+	days := end.AddDate(0, 0, -1).Day()
+	var tens, units strings.Builder
+	for d := 1; d <= days; d++ {
+		if d >= 10 {
+			tens.WriteString(fmt.Sprintf("%d", d/10))
+		} else {
+			tens.WriteString(" ")
+		}
+		units.WriteString(fmt.Sprintf("%d", d%10))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "🗓️ *Team calendar — %s*\n```\n", month.Format("January 2006"))
+	fmt.Fprintf(&b, "%-*s %s\n", nameWidth, "", tens.String())
+	fmt.Fprintf(&b, "%-*s %s\n", nameWidth, "", units.String())
+	for _, username := range usernames {
+		name := username
+		if len(name) > nameWidth {
+			name = name[:nameWidth]
+		}
+		var row strings.Builder
+		for d := 1; d <= days; d++ {
+			date := time.Date(month.Year(), month.Month(), d, 0, 0, 0, 0, time.UTC)
+			switch {
+			case cells[username][d] != "":
+				row.WriteString(cells[username][d])
+			case date.Weekday() == time.Saturday || date.Weekday() == time.Sunday:
+				row.WriteString(" ")
+			default:
+				row.WriteString(".")
+			}
+		}
+		fmt.Fprintf(&b, "%-*s %s\n", nameWidth, name, row.String())
+	}
+	b.WriteString("```\n" + calendarLegend)
+
+	return b.String(), nil
+}
+
+// handleTeamCalCommand handles `/teamcal [month]`. Managers see their direct
+// reports; everyone else sees all users with a record that month.
+func handleTeamCalCommand(app *App, cmd slack.SlashCommand) {
+	reply := func(text string) {
+		_, err := app.slackClient.PostEphemeral(cmd.ChannelID, cmd.UserID, slack.MsgOptionText(text, false))
+		if err != nil {
+			logger.Error("Failed to post team calendar: %v", err)
+		}
+	}
+
+	month, err := parseCalendarMonth(cmd.Text, app.today())
+	if err != nil {
+		reply("❌ " + err.Error())
+		return
+	}
+
+	team, err := app.employeeRepo.GetReports(cmd.UserID)
+	if err != nil {
+		logger.Error("Failed to load reports for %s: %v", cmd.UserID, err)
+	}
+	if len(team) > 0 {
+		team = append(team, cmd.UserName)
+	}
+
+	text, err := app.renderTeamCalendar(month, team)
+	if err != nil {
+		logger.Error("Failed to render team calendar: %v", err)
+		reply("❌ " + err.Error())
+		return
+	}
+	reply(text)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"slack-leaves-ai-agent/repository"
+	"slack-leaves-ai-agent/services"
+
+	"github.com/slack-go/slack"
+)
+
+// maxTrendBuckets keeps a trend within one Slack message; longer periods
+// have to be grouped more coarsely.
+const maxTrendBuckets = 60
+
+// trendBarWidth is how many characters the longest bar takes.
+const trendBarWidth = 20
+
+// isTrendBucket reports whether group_by asks for a time series.
+func isTrendBucket(groupBy string) bool {
+	return groupBy == "day" || groupBy == "week" || groupBy == "month"
+}
+
+// trendBlocks answers a trend question with one row per day, week or month
+// of the query's period.
+func (a *App) trendBlocks(queryResp *services.QueryResponse) ([]slack.Block, error) {
+	if !a.featureEnabled(flagAnalytics) {
+		return nil, errAnalyticsOff
+	}
+	bucket := queryResp.GroupBy
+	if !isTrendBucket(bucket) {
+		bucket = "week"
+	}
+
+	leaveTypes, err := queryLeaveTypes(queryResp)
+	if err != nil {
+		return nil, err
+	}
+
+	startDate, endDate, err := queryPeriod(queryResp, a.today())
+	if err != nil {
+		return nil, err
+	}
+	if buckets := trendBucketCount(startDate, endDate, bucket); buckets > maxTrendBuckets {
+		return nil, fmt.Errorf("that's %d %ss, too many to show; try grouping by a longer period", buckets, bucket)
+	}
+
+	points, err := a.leaveRepo.GetLeaveTrend(startDate, endDate.AddDate(0, 0, 1), bucket, leaveTypes,
+		strings.ToUpper(queryResp.EmploymentType))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get leave trend: %v", err)
+	}
+
+	what := "Leave"
+	if len(leaveTypes) > 0 {
+		what = strings.Join(leaveTypes, ", ")
+	}
+	title := fmt.Sprintf("📈 *%s by %s, %s to %s*", what, bucket,
+		startDate.Format("Jan 2, 2006"), endDate.Format("Jan 2, 2006"))
+
+	blocks := []slack.Block{
+		slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", title+"\n"+trendTable(points, bucket), false, false), nil, nil),
+	}
+	return withDetails(blocks, repository.LeaveFilter{
+		Start:          startDate,
+		End:            endDate.AddDate(0, 0, 1),
+		LeaveTypes:     leaveTypes,
+		EmploymentType: strings.ToUpper(queryResp.EmploymentType),
+	}), nil
+}
+
+// trendBucketCount is how many buckets the period from start to end,
+// inclusive, spans.
+func trendBucketCount(start, end time.Time, bucket string) int {
+	switch bucket {
+	case "day":
+		return int(end.Sub(start).Hours()/24) + 1
+	case "week":
+		return int(end.Sub(start).Hours()/24/7) + 2
+	default:
+		return (end.Year()-start.Year())*12 + int(end.Month()-start.Month()) + 1
+	}
+}
+
+// trendTable renders the series as a fixed-width table with a bar per row,
+// scaled to the busiest bucket.
+func trendTable(points []repository.TrendPoint, bucket string) string {
+	most := 0
+	for _, point := range points {
+		most = max(most, point.LeaveCount)
+	}
+
+	var b strings.Builder
+	b.WriteString("```\n")
+	fmt.Fprintf(&b, "%-12s %7s %6s %7s %5s\n", trendBucketHeading(bucket), "Records", "People", "Hours", "Days")
+	for _, point := range points {
+		bar := ""
+		if most > 0 {
+			bar = strings.Repeat("█", (point.LeaveCount*trendBarWidth+most-1)/most)
+		}
+		fmt.Fprintf(&b, "%-12s %7d %6d %7.1f %5.1f %s\n",
+			trendBucketLabel(point.Bucket, bucket), point.LeaveCount, point.UserCount, point.TotalHours, point.WorkingDays, bar)
+	}
+	b.WriteString("```")
+	return b.String()
+}
+
+func trendBucketHeading(bucket string) string {
+	switch bucket {
+	case "day":
+		return "Day"
+	case "week":
+		return "Week of"
+	default:
+		return "Month"
+	}
+}
+
+func trendBucketLabel(t time.Time, bucket string) string {
+	switch bucket {
+	case "day":
+		return t.Format("Mon Jan 2")
+	case "week":
+		return t.Format("Jan 2")
+	default:
+		return t.Format("Jan 2006")
+	}
+}
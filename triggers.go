@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"slack-leaves-ai-agent/services"
+)
+
+// Channel trigger modes control which messages in a channel reach the parser.
+const (
+	TriggerMention  = "mention"  // only messages that @-mention the bot
+	TriggerKeywords = "keywords" // only messages with an attendance keyword
+	TriggerAll      = "all"      // everything except links, code and emoji
+	TriggerPrefix   = "prefix"   // only messages starting with TRIGGER_PREFIX or an @-mention of the bot
+)
+
+// parseChannelTriggers reads CHANNEL_TRIGGERS entries of the form
+// "C0123:mention,C0456:all".
+func parseChannelTriggers(value string) (map[string]string, error) {
+	triggers := make(map[string]string)
+	for _, entry := range splitList(value) {
+		channel, mode, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid CHANNEL_TRIGGERS entry %q (expected CHANNEL:MODE)", entry)
+		}
+		mode = strings.ToLower(strings.TrimSpace(mode))
+		if !isValidTriggerMode(mode) {
+			return nil, fmt.Errorf("invalid trigger mode %q for channel %s", mode, channel)
+		}
+		triggers[strings.TrimSpace(channel)] = mode
+	}
+	return triggers, nil
+}
+
+func isValidTriggerMode(mode string) bool {
+	switch mode {
+	case TriggerMention, TriggerKeywords, TriggerAll, TriggerPrefix:
+		return true
+	}
+	return false
+}
+
+func (a *App) triggerModeFor(channelID string) string {
+	if mode, ok := a.config.ChannelTriggers[channelID]; ok {
+		return mode
+	}
+	// Direct messages are always meant for the bot
+	if strings.HasPrefix(channelID, "D") {
+		return TriggerAll
+	}
+	return a.config.DefaultTrigger
+}
+
+// shouldParse applies the channel's trigger requirement to a message before
+// it is sent to the LLM.
+func (a *App) shouldParse(channelID, text string) bool {
+	if services.IsObviouslyIrrelevant(text) {
+		return false
+	}
+
+	switch a.triggerModeFor(channelID) {
+	case TriggerAll:
+		return true
+	case TriggerMention:
+		botID := a.botUserID()
+		return botID != "" && strings.Contains(text, "<@"+botID+">")
+	case TriggerPrefix:
+		_, ok := a.cutTriggerPrefix(text)
+		return ok
+	default:
+		return services.HasAttendanceKeyword(text)
+	}
+}
+
+// cutTriggerPrefix removes a leading TRIGGER_PREFIX or @-mention of the bot,
+// reporting whether text had one. Meant for shared channels, where anyone
+// from the other organisation could otherwise be recorded by accident.
+func (a *App) cutTriggerPrefix(text string) (string, bool) {
+	trimmed := strings.TrimSpace(text)
+	if botID := a.botUserID(); botID != "" && strings.HasPrefix(trimmed, "<@"+botID+">") {
+		return strings.TrimSpace(trimmed[len("<@"+botID+">"):]), true
+	}
+	prefix := a.config.TriggerPrefix
+	if prefix == "" || len(trimmed) < len(prefix) || !strings.EqualFold(trimmed[:len(prefix)], prefix) {
+		return text, false
+	}
+	rest := trimmed[len(prefix):]
+	// "latebot wfh" and "latebot: wfh", but not "latebots"
+	if rest != "" && !strings.ContainsAny(rest[:1], " :,\n") {
+		return text, false
+	}
+	return strings.TrimSpace(strings.TrimLeft(rest, ":,")), true
+}
+
+// triggerText is the part of a message the parser should see: in prefix
+// channels, what follows the prefix.
+func (a *App) triggerText(channelID, text string) string {
+	if a.triggerModeFor(channelID) != TriggerPrefix {
+		return text
+	}
+	if rest, ok := a.cutTriggerPrefix(text); ok {
+		return rest
+	}
+	return text
+}
+
+// botUserID returns the bot's own user ID, looked up via auth.test and cached
+// after the first successful call.
+func (a *App) botUserID() string {
+	a.botIDMu.Lock()
+	defer a.botIDMu.Unlock()
+
+	if a.botID == "" {
+		authTest, err := a.slackClient.AuthTest()
+		if err != nil {
+			logger.Error("Failed to look up bot user: %v", err)
+			return ""
+		}
+		a.botID = authTest.UserID
+		a.botTeamID = authTest.TeamID
+	}
+	return a.botID
+}
+
+// teamID returns the ID of the workspace the bot is installed in, or "" if
+// it couldn't be looked up.
+func (a *App) teamID() string {
+	a.botUserID()
+	a.botIDMu.Lock()
+	defer a.botIDMu.Unlock()
+	return a.botTeamID
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"slack-leaves-ai-agent/models"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+// leaveURL is the dashboard URL of a leave record, or "" when no dashboard
+// is configured. The dashboard is a separate app at DASHBOARD_URL, which
+// serves /leaves/{id} behind its own login; the bot doesn't serve it.
+func (a *App) leaveURL(id int64) string {
+	if a.config.DashboardURL == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/leaves/%d", strings.TrimSuffix(a.config.DashboardURL, "/"), id)
+}
+
+// leaveIDFromURL extracts the record ID from a dashboard leave URL.
+func (a *App) leaveIDFromURL(rawURL string) (int64, bool) {
+	if a.config.DashboardURL == "" {
+		return 0, false
+	}
+
+	base, err := url.Parse(a.config.DashboardURL)
+	if err != nil {
+		return 0, false
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host != base.Host {
+		return 0, false
+	}
+
+	prefix := strings.TrimSuffix(base.Path, "/") + "/leaves/"
+	if !strings.HasPrefix(u.Path, prefix) {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(u.Path, prefix), "/"), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return id, true
+}
+
+// handleLinkShared unfurls leave record URLs pasted in Slack into a preview
+// card via chat.unfurl. Anyone in the channel sees the card, so records
+// with private details aren't unfurled.
+func (a *App) handleLinkShared(ctx context.Context, ev *slackevents.LinkSharedEvent) {
+	if !a.hasScope(scopeLinksWrite) {
+		return
+	}
+	unfurls := make(map[string]slack.Attachment)
+
+	for _, link := range ev.Links {
+		id, ok := a.leaveIDFromURL(link.URL)
+		if !ok {
+			continue
+		}
+
+		leave, err := a.leaveRepo.GetByID(id)
+		if err != nil {
+			logger.Debug("Not unfurling %s: %v", link.URL, err)
+			continue
+		}
+		if a.isPrivateLeave(leave) {
+			logger.Debug("Not unfurling %s: the record is private", link.URL)
+			continue
+		}
+
+		fields := []*slack.TextBlockObject{
+			slack.NewTextBlockObject("mrkdwn", "*User:*\n"+leave.Username, false, false),
+			slack.NewTextBlockObject("mrkdwn", "*Type:*\n"+leave.LeaveType, false, false),
+			slack.NewTextBlockObject("mrkdwn", "*From:*\n"+leave.StartTime.Format("Jan 2, 2006 3:04 PM"), false, false),
+			slack.NewTextBlockObject("mrkdwn", "*To:*\n"+leave.EndTime.Format("Jan 2, 2006 3:04 PM"), false, false),
+			slack.NewTextBlockObject("mrkdwn", "*Status:*\n"+getStatusMessage(leave.LeaveType), false, false),
+		}
+
+		unfurls[link.URL] = slack.Attachment{
+			Blocks: slack.Blocks{BlockSet: []slack.Block{
+				slack.NewSectionBlock(
+					slack.NewTextBlockObject("mrkdwn", fmt.Sprintf("*Leave record #%d*", leave.ID), false, false),
+					fields, nil,
+				),
+			}},
+		}
+	}
+
+	if len(unfurls) == 0 {
+		return
+	}
+
+	_, _, _, err := a.slackClient.UnfurlMessageContext(ctx, ev.Channel, ev.MessageTimeStamp, unfurls)
+	if err != nil {
+		logger.Error("Failed to unfurl leave links: %v", err)
+	}
+}
+
+// isPrivateLeave reports whether a record has details only admins may see:
+// a private reason, or a sick day kept private by its owner's policy.
+func (a *App) isPrivateLeave(leave *models.Leave) bool {
+	if leave.PrivateReason != "" {
+		return true
+	}
+	return leave.Reason == "sick" && a.userPolicy(leave.Username).SickNoQuestions
+}
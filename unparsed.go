@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+	"slack-leaves-ai-agent/services"
+
+	"github.com/slack-go/slack"
+)
+
+// unparsedDigestSchedule posts the weekly digest of messages the parser
+// missed on Monday mornings, next to the ops digest.
+const unparsedDigestSchedule = "0 9 * * 1"
+
+// maxUnparsedDigest is how many messages one digest lists.
+const maxUnparsedDigest = 20
+
+// Action and callback IDs of the unparsed message digest and its modal.
+const (
+	unparsedExampleActionID = "unparsed_example"
+	unparsedDismissActionID = "unparsed_dismiss"
+	unparsedModalCallbackID = "unparsed_example"
+)
+
+// Block IDs of the example modal's inputs. They take reviewInputActionID.
+const (
+	unparsedTypeBlockID   = "unparsed_type"
+	unparsedStartBlockID  = "unparsed_start"
+	unparsedEndBlockID    = "unparsed_end"
+	unparsedReasonBlockID = "unparsed_reason"
+)
+
+// unparsedReason says why the parser got nothing out of a message classified
+// as attendance, or returns "" when responses hold something it understood.
+// Items refused for their dates were understood, just not allowed.
+func unparsedReason(responses []*services.LeaveResponse) string {
+	if len(responses) == 0 {
+		return "no items found"
+	}
+	var reasons []string
+	for _, response := range responses {
+		switch response.Outcome {
+		case services.OutcomeUnrelated:
+			reasons = append(reasons, "parsed as unrelated")
+		case services.OutcomeInvalid:
+			if response.Error != "" {
+				reasons = append(reasons, response.Error)
+			} else {
+				reasons = append(reasons, "invalid item")
+			}
+		default:
+			return ""
+		}
+	}
+	return strings.Join(reasons, "; ")
+}
+
+// recordUnparsed keeps ev for the weekly digest. A failure to record is
+// logged and otherwise ignored.
+func (a *App) recordUnparsed(ev *slack.MessageEvent, reason string) {
+	err := a.unparsedRepo.Record(&models.UnparsedMessage{
+		Channel:   ev.Channel,
+		MessageTS: ev.Timestamp,
+		UserID:    ev.User,
+		Text:      ev.Text,
+		Reason:    reason,
+	})
+	if err != nil {
+		logger.Error("Failed to record unparsed message %s: %v", ev.Timestamp, err)
+	}
+}
+
+// unparsedDigestJob posts the week's unparsed messages to the admin channel.
+func (a *App) unparsedDigestJob() (scheduledJob, error) {
+	schedule, err := services.ParseCron(unparsedDigestSchedule)
+	if err != nil {
+		return scheduledJob{}, err
+	}
+	return scheduledJob{
+		name:     "unparsedDigest",
+		schedule: schedule,
+		loc:      a.config.Timezone,
+		run:      a.postUnparsedDigest,
+	}, nil
+}
+
+func (a *App) postUnparsedDigest(ctx context.Context, now time.Time) {
+	adminChannel := a.adminChannel()
+	if adminChannel == "" || !a.featureEnabled(flagDigests) {
+		return
+	}
+
+	messages, err := a.unparsedRepo.ListPending(now.AddDate(0, 0, -7), maxUnparsedDigest)
+	if err != nil {
+		logger.Error("Failed to load unparsed messages: %v", err)
+		return
+	}
+	if len(messages) == 0 {
+		return
+	}
+
+	text := fmt.Sprintf("🧐 %d messages this week looked like attendance but I couldn't parse them", len(messages))
+	_, _, err = a.slackClient.PostMessageContext(ctx, adminChannel,
+		slack.MsgOptionText(text, false),
+		slack.MsgOptionBlocks(unparsedDigestBlocks(text, messages, a.config.Timezone)...))
+	if err != nil {
+		logger.Error("Failed to post unparsed message digest: %v", err)
+	}
+}
+
+// unparsedDigestBlocks lists each message with buttons to turn it into a
+// parse example or dismiss it.
+func unparsedDigestBlocks(title string, messages []*models.UnparsedMessage, loc *time.Location) []slack.Block {
+	blocks := []slack.Block{
+		slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", "*"+title+".*\n"+
+			"Add the ones with phrasing worth learning to the parser's examples.", false, false), nil, nil),
+	}
+	for _, message := range messages {
+		value := strconv.FormatInt(message.ID, 10)
+		text := fmt.Sprintf("<@%s> in <#%s>, %s\n>%s\n_%s_",
+			message.UserID, message.Channel, message.CreatedAt.In(loc).Format("Mon Jan 2 3:04 PM"),
+			strings.ReplaceAll(message.Text, "\n", "\n>"), message.Reason)
+		blocks = append(blocks,
+			slack.NewDividerBlock(),
+			slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", text, false, false), nil, nil),
+			slack.NewActionBlock("unparsed_"+value,
+				slack.NewButtonBlockElement(unparsedExampleActionID, value,
+					slack.NewTextBlockObject("plain_text", "Add example", false, false)).WithStyle(slack.StylePrimary),
+				slack.NewButtonBlockElement(unparsedDismissActionID, value,
+					slack.NewTextBlockObject("plain_text", "Dismiss", false, false)),
+			),
+		)
+	}
+	return blocks
+}
+
+func unparsedInteraction(callback slack.InteractionCallback) bool {
+	for _, action := range callback.ActionCallback.BlockActions {
+		if action.ActionID == unparsedExampleActionID || action.ActionID == unparsedDismissActionID {
+			return true
+		}
+	}
+	return false
+}
+
+// handleUnparsedAction opens the example modal or dismisses a message.
+func (a *App) handleUnparsedAction(callback slack.InteractionCallback) {
+	reply := func(text string) {
+		_, err := a.slackClient.PostEphemeral(callback.Channel.ID, callback.User.ID, slack.MsgOptionText(text, false))
+		if err != nil {
+			logger.Error("Failed to post unparsed message reply: %v", err)
+		}
+	}
+	if !a.isAdmin(callback.User.ID) {
+		reply("❌ Only admins can add parse examples.")
+		return
+	}
+
+	for _, action := range callback.ActionCallback.BlockActions {
+		id, err := strconv.ParseInt(action.Value, 10, 64)
+		if err != nil {
+			continue
+		}
+		message, err := a.unparsedRepo.Get(id)
+		if err != nil {
+			logger.Error("Failed to load unparsed message %d: %v", id, err)
+			continue
+		}
+		if message == nil || message.Status != models.UnparsedPending {
+			reply("That message has already been handled.")
+			continue
+		}
+
+		switch action.ActionID {
+		case unparsedExampleActionID:
+			loc := a.unparsedTimezone(message.UserID)
+			if _, err := a.slackClient.OpenView(callback.TriggerID, unparsedModal(message, loc)); err != nil {
+				logger.Error("Failed to open example modal: %v", err)
+			}
+
+		case unparsedDismissActionID:
+			ok, err := a.unparsedRepo.Resolve(id, models.UnparsedDismissed, "slack:"+callback.User.ID)
+			if err != nil {
+				logger.Error("Failed to dismiss unparsed message %d: %v", id, err)
+				reply("❌ Failed to dismiss the message.")
+				continue
+			}
+			if ok {
+				reply("👍 Dismissed.")
+			}
+		}
+	}
+}
+
+// unparsedTimezone is the timezone of a message's author, by their office
+// when they're in the employees table.
+func (a *App) unparsedTimezone(userID string) *time.Location {
+	employee, err := a.employeeRepo.GetBySlackID(userID)
+	if err != nil || employee == nil {
+		return a.config.Timezone
+	}
+	return a.regionFor(employee.Username).Timezone
+}
+
+func unparsedModal(message *models.UnparsedMessage, loc *time.Location) slack.ModalViewRequest {
+	typeOptions := make([]*slack.OptionBlockObject, 0, len(models.LeaveTypes))
+	for _, info := range models.LeaveTypes {
+		typeOptions = append(typeOptions,
+			slack.NewOptionBlockObject(info.Name, slack.NewTextBlockObject("plain_text", info.Name, false, false), nil))
+	}
+	typeSelect := slack.NewOptionsSelectBlockElement(slack.OptTypeStatic, nil, reviewInputActionID, typeOptions...)
+
+	textInput := func(value string) *slack.PlainTextInputBlockElement {
+		input := slack.NewPlainTextInputBlockElement(nil, reviewInputActionID)
+		input.InitialValue = value
+		return input
+	}
+	timeHint := slack.NewTextBlockObject("plain_text", "YYYY-MM-DD or YYYY-MM-DDTHH:MM, in the author's office time", false, false)
+	posted := message.CreatedAt.In(loc).Format("2006-01-02")
+
+	reason := slack.NewInputBlock(unparsedReasonBlockID, slack.NewTextBlockObject("plain_text", "Reason", false, false), nil, textInput(""))
+	reason.Optional = true
+
+	return slack.ModalViewRequest{
+		Type:            slack.VTModal,
+		CallbackID:      unparsedModalCallbackID,
+		PrivateMetadata: strconv.FormatInt(message.ID, 10),
+		Title:           slack.NewTextBlockObject("plain_text", "Add parse example", false, false),
+		Submit:          slack.NewTextBlockObject("plain_text", "Add", false, false),
+		Close:           slack.NewTextBlockObject("plain_text", "Cancel", false, false),
+		Blocks: slack.Blocks{BlockSet: []slack.Block{
+			slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn",
+				fmt.Sprintf("<@%s> wrote on %s:\n>%s", message.UserID, message.CreatedAt.In(loc).Format("Mon Jan 2 3:04 PM"),
+					strings.ReplaceAll(message.Text, "\n", "\n>")),
+				false, false), nil, nil),
+			slack.NewInputBlock(unparsedTypeBlockID, slack.NewTextBlockObject("plain_text", "Type", false, false), nil, typeSelect),
+			slack.NewInputBlock(unparsedStartBlockID, slack.NewTextBlockObject("plain_text", "Start", false, false), timeHint,
+				textInput(posted)),
+			slack.NewInputBlock(unparsedEndBlockID, slack.NewTextBlockObject("plain_text", "End", false, false), timeHint,
+				textInput(posted)),
+			reason,
+			slack.NewContextBlock("", slack.NewTextBlockObject("mrkdwn",
+				"Nothing is recorded for the author; the message and this parse only go into the parser's examples.", false, false)),
+		}},
+	}
+}
+
+// submitUnparsedExample adds the parse from the example modal to the example
+// store. It runs inline because validation errors go back in the
+// acknowledgement; a nil response closes the modal.
+func (a *App) submitUnparsedExample(callback slack.InteractionCallback) *slack.ViewSubmissionResponse {
+	if !a.isAdmin(callback.User.ID) {
+		return slack.NewErrorsViewSubmissionResponse(map[string]string{
+			unparsedTypeBlockID: "You are not allowed to add parse examples.",
+		})
+	}
+
+	id, err := strconv.ParseInt(callback.View.PrivateMetadata, 10, 64)
+	if err != nil {
+		logger.Error("Invalid example modal metadata %q", callback.View.PrivateMetadata)
+		return nil
+	}
+	message, err := a.unparsedRepo.Get(id)
+	if err != nil || message == nil {
+		logger.Error("Failed to load unparsed message %d: %v", id, err)
+		return slack.NewErrorsViewSubmissionResponse(map[string]string{
+			unparsedTypeBlockID: "Couldn't load the message. Please try again.",
+		})
+	}
+
+	values := callback.View.State.Values
+	loc := a.unparsedTimezone(message.UserID)
+	errs := map[string]string{}
+	start, err := parseAdminTime(strings.TrimSpace(values[unparsedStartBlockID][reviewInputActionID].Value), false, loc)
+	if err != nil {
+		errs[unparsedStartBlockID] = err.Error()
+	}
+	end, err := parseAdminTime(strings.TrimSpace(values[unparsedEndBlockID][reviewInputActionID].Value), true, loc)
+	if err != nil {
+		errs[unparsedEndBlockID] = err.Error()
+	}
+	if len(errs) == 0 && !end.After(start) {
+		errs[unparsedEndBlockID] = "end time must be after start time"
+	}
+	if len(errs) > 0 {
+		return slack.NewErrorsViewSubmissionResponse(errs)
+	}
+
+	if message.Status != models.UnparsedPending {
+		return slack.NewErrorsViewSubmissionResponse(map[string]string{
+			unparsedTypeBlockID: "Someone has already handled this message.",
+		})
+	}
+
+	actor := "slack:" + callback.User.ID
+	example := &models.ParseExample{
+		Message:   message.Text,
+		PostedAt:  message.CreatedAt,
+		LeaveType: values[unparsedTypeBlockID][reviewInputActionID].SelectedOption.Value,
+		StartTime: start,
+		EndTime:   end,
+		Reason:    strings.TrimSpace(values[unparsedReasonBlockID][reviewInputActionID].Value),
+		Source:    models.ExampleUnparsed,
+		CreatedBy: actor,
+	}
+	if err := a.feedbackRepo.AddExample(example); err != nil {
+		logger.Error("Failed to store example from unparsed message %d: %v", id, err)
+		return slack.NewErrorsViewSubmissionResponse(map[string]string{
+			unparsedTypeBlockID: "Couldn't save the example. Please try again.",
+		})
+	}
+	if _, err := a.unparsedRepo.Resolve(id, models.UnparsedExample, actor); err != nil {
+		logger.Error("Failed to resolve unparsed message %d: %v", id, err)
+	}
+	logger.Info("%s added unparsed message %d to the parse examples", actor, id)
+	return nil
+}
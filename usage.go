@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"slack-leaves-ai-agent/repository"
+)
+
+// Features counted for the adoption report.
+const (
+	usageMessageParse = "message_parse" // a message parsed as attendance
+	usageQuery        = "query"         // /query or a question in a message
+	usageModal        = "modal"         // a modal submitted
+	usageAppHome      = "app_home"      // the App Home opened
+	usageShortcut     = "shortcut"      // "Log as leave" used on a message
+	usageLeaveCommand = "leave_command" // /leave
+)
+
+// usageFeatures labels each feature in the adoption report, in order.
+var usageFeatures = []struct{ feature, label string }{
+	{usageMessageParse, "💬 Messages parsed"},
+	{usageQuery, "🔎 Questions"},
+	{usageModal, "🪟 Modals"},
+	{usageAppHome, "🏠 App Home"},
+	{usageShortcut, "📎 Log as leave"},
+	{usageLeaveCommand, "🗂️ /leave"},
+}
+
+// maxUsageWeeks bounds the adoption report.
+const maxUsageWeeks = 26
+
+// trackUsage counts a use of feature by userID in this week. A failure to
+// count is logged and otherwise ignored.
+func (a *App) trackUsage(feature, userID string) {
+	if userID == "" {
+		return
+	}
+	if err := a.usageRepo.Record(usageWeek(time.Now().In(a.config.Timezone)), feature, userID); err != nil {
+		logger.Error("Failed to record %s use by %s: %v", feature, userID, err)
+	}
+}
+
+// usageWeek returns the Monday starting t's week.
+func usageWeek(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, time.UTC)
+}
+
+// usageReport is what the adoption report covers.
+type usageReport struct {
+	Since       time.Time                       `json:"since"`
+	Weekly      []repository.WeeklyUsage        `json:"weekly"`
+	ActiveUsers int                             `json:"active_users"`
+	Departments []repository.DepartmentAdoption `json:"departments"`
+}
+
+func (a *App) loadUsageReport(weeks int) (*usageReport, error) {
+	since := usageWeek(time.Now().In(a.config.Timezone)).AddDate(0, 0, -7*(weeks-1))
+	report := &usageReport{Since: since}
+
+	var err error
+	if report.Weekly, err = a.usageRepo.Weekly(since); err != nil {
+		return nil, fmt.Errorf("error loading usage: %v", err)
+	}
+	if report.ActiveUsers, err = a.usageRepo.ActiveUsers(since); err != nil {
+		return nil, fmt.Errorf("error counting active users: %v", err)
+	}
+	if report.Departments, err = a.usageRepo.DepartmentAdoption(since); err != nil {
+		return nil, fmt.Errorf("error loading adoption by department: %v", err)
+	}
+	return report, nil
+}
+
+// usageReportText shows, per feature, distinct users and uses in each week,
+// then adoption by department, least adopted first.
+func usageReportText(report *usageReport, weeks int) string {
+	lines := []string{fmt.Sprintf("📊 *Adoption, last %d weeks* (since %s)", weeks, report.Since.Format("Jan 2"))}
+	if len(report.Weekly) == 0 {
+		return lines[0] + "\nNo use recorded yet."
+	}
+
+	byFeature := make(map[string][]repository.WeeklyUsage)
+	for _, u := range report.Weekly {
+		byFeature[u.Feature] = append(byFeature[u.Feature], u)
+	}
+	for _, f := range usageFeatures {
+		usage := byFeature[f.feature]
+		if len(usage) == 0 {
+			continue
+		}
+		parts := make([]string, 0, len(usage))
+		for _, u := range usage {
+			parts = append(parts, fmt.Sprintf("%s: %d users, %d uses", u.Week.Format("Jan 2"), u.Users, u.Uses))
+		}
+		lines = append(lines, fmt.Sprintf("• %s — %s", f.label, strings.Join(parts, " · ")))
+	}
+	lines = append(lines, fmt.Sprintf("People who used the bot: *%d*", report.ActiveUsers))
+
+	if len(report.Departments) > 0 {
+		lines = append(lines, "", "*By department*")
+		for _, d := range report.Departments {
+			name := d.Department
+			if name == "" {
+				name = "_no department_"
+			}
+			line := fmt.Sprintf("• %s: %d of %d (%.0f%%)", name, d.ActiveUsers, d.Employees,
+				100*float64(d.ActiveUsers)/float64(d.Employees))
+			if d.ActiveUsers == 0 {
+				line += " ⚠️ not using the bot"
+			}
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// runUsageCommand handles `/admin-leave usage [WEEKS]`.
+func (a *App) runUsageCommand(args []string) (string, error) {
+	weeks := 4
+	if len(args) > 1 {
+		return adminLeaveUsage, nil
+	}
+	if len(args) == 1 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n <= 0 || n > maxUsageWeeks {
+			return "", fmt.Errorf("weeks must be between 1 and %d", maxUsageWeeks)
+		}
+		weeks = n
+	}
+	report, err := a.loadUsageReport(weeks)
+	if err != nil {
+		return "", err
+	}
+	return usageReportText(report, weeks), nil
+}
+
+// handleUsageReport serves GET /api/admin/reports/usage?weeks=N.
+func (a *App) handleUsageReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	weeks := 4
+	if v := r.URL.Query().Get("weeks"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxUsageWeeks {
+			http.Error(w, "Invalid weeks", http.StatusBadRequest)
+			return
+		}
+		weeks = n
+	}
+
+	report, err := a.loadUsageReport(weeks)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+)
+
+// maxViewerTokenDays caps how long an auditor's token stays valid.
+const maxViewerTokenDays = 90
+
+const viewerTokenPrefix = "lvw_"
+
+func hashViewerToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func newViewerTokenSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return viewerTokenPrefix + hex.EncodeToString(b), nil
+}
+
+// runViewerCommand handles `/admin-leave viewer create|list|revoke`.
+func (a *App) runViewerCommand(actor string, args []string) (string, error) {
+	if len(args) == 0 {
+		return adminLeaveUsage, nil
+	}
+
+	switch args[0] {
+	case "create":
+		if len(args) < 4 {
+			return adminLeaveUsage, nil
+		}
+		start, err := time.Parse("2006-01-02", args[1])
+		if err != nil {
+			return "", fmt.Errorf("invalid start date %q (use YYYY-MM-DD)", args[1])
+		}
+		end, err := time.Parse("2006-01-02", args[2])
+		if err != nil {
+			return "", fmt.Errorf("invalid end date %q (use YYYY-MM-DD)", args[2])
+		}
+		if end.Before(start) {
+			return "", fmt.Errorf("end date is before start date")
+		}
+		days, err := strconv.Atoi(args[3])
+		if err != nil || days <= 0 || days > maxViewerTokenDays {
+			return "", fmt.Errorf("validity must be between 1 and %d days", maxViewerTokenDays)
+		}
+		label := strings.Join(args[4:], " ")
+		if label == "" {
+			label = "auditor"
+		}
+
+		secret, err := newViewerTokenSecret()
+		if err != nil {
+			return "", fmt.Errorf("error generating token: %v", err)
+		}
+		token := &models.ViewerToken{
+			Label:      label,
+			ScopeStart: start,
+			ScopeEnd:   end,
+			ExpiresAt:  time.Now().AddDate(0, 0, days),
+			CreatedBy:  actor,
+		}
+		if err := a.viewerTokenRepo.Create(token, hashViewerToken(secret)); err != nil {
+			return "", fmt.Errorf("error saving token: %v", err)
+		}
+		a.audit(actor, "viewer_token_create", 0, nil, token)
+
+		return fmt.Sprintf("🔑 Viewer token #%d for *%s* (%s → %s, expires %s):\n`%s`\n"+
+			"Send it as `Authorization: Bearer <token>` to `/api/export/leaves` and `/api/leave/query`. "+
+			"It won't be shown again.",
+			token.ID, label, args[1], args[2], token.ExpiresAt.Format("Jan 2, 2006"), secret), nil
+
+	case "list":
+		tokens, err := a.viewerTokenRepo.List()
+		if err != nil {
+			return "", err
+		}
+		if len(tokens) == 0 {
+			return "No viewer tokens have been created.", nil
+		}
+		now := time.Now()
+		lines := make([]string, 0, len(tokens))
+		for _, token := range tokens {
+			state := "active until " + token.ExpiresAt.Format("Jan 2, 2006")
+			switch {
+			case token.RevokedAt != nil:
+				state = "revoked " + token.RevokedAt.Format("Jan 2, 2006")
+			case !token.Usable(now):
+				state = "expired " + token.ExpiresAt.Format("Jan 2, 2006")
+			}
+			lines = append(lines, fmt.Sprintf("#%d *%s* %s → %s, %s (by %s)",
+				token.ID, token.Label, token.ScopeStart.Format("2006-01-02"), token.ScopeEnd.Format("2006-01-02"),
+				state, token.CreatedBy))
+		}
+		return strings.Join(lines, "\n"), nil
+
+	case "revoke":
+		if len(args) != 2 {
+			return adminLeaveUsage, nil
+		}
+		id, err := strconv.ParseInt(strings.TrimPrefix(args[1], "#"), 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid token id %q", args[1])
+		}
+		if err := a.viewerTokenRepo.Revoke(id); err != nil {
+			return "", err
+		}
+		a.audit(actor, "viewer_token_revoke", 0, nil, map[string]int64{"token_id": id})
+		return fmt.Sprintf("🚫 Revoked viewer token #%d", id), nil
+
+	default:
+		return adminLeaveUsage, nil
+	}
+}
+
+type viewerTokenKey struct{}
+
+// requireViewerAccess guards the read-only export and query endpoints. The
+// admin API key grants unrestricted access; a viewer token grants access to
+// its date range only, which handlers enforce with requestRange.
+func (a *App) requireViewerAccess(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("X-Admin-Key")
+		if a.config.AdminAPIKey != "" && subtle.ConstantTimeCompare([]byte(key), []byte(a.config.AdminAPIKey)) == 1 {
+			next(w, r)
+			return
+		}
+
+		secret := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !strings.HasPrefix(secret, viewerTokenPrefix) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		token, err := a.viewerTokenRepo.GetByHash(hashViewerToken(secret))
+		if err != nil {
+			logger.Error("Failed to look up viewer token: %v", err)
+			http.Error(w, "Internal error", http.StatusInternalServerError)
+			return
+		}
+		if token == nil || !token.Usable(time.Now()) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		logger.Info("Viewer token #%d (%s) used: %s %s", token.ID, token.Label, r.Method, r.URL.RequestURI())
+		next(w, r.WithContext(context.WithValue(r.Context(), viewerTokenKey{}, token)))
+	}
+}
+
+// requestRange resolves the inclusive date range a read-only request asks
+// for. Missing dates default to def for admin requests and to the token's
+// scope for viewers, who get an error if they ask for anything outside it.
+func requestRange(r *http.Request, startValue, endValue string, defStart, defEnd time.Time) (time.Time, time.Time, error) {
+	token, _ := r.Context().Value(viewerTokenKey{}).(*models.ViewerToken)
+	if token != nil {
+		defStart, defEnd = token.ScopeStart, token.ScopeEnd
+	}
+
+	start, end := defStart, defEnd
+	var err error
+	if startValue != "" {
+		if start, err = time.Parse("2006-01-02", startValue); err != nil {
+			return start, end, fmt.Errorf("invalid start date %q", startValue)
+		}
+	}
+	if endValue != "" {
+		if end, err = time.Parse("2006-01-02", endValue); err != nil {
+			return start, end, fmt.Errorf("invalid end date %q", endValue)
+		}
+	}
+	if end.Before(start) {
+		return start, end, fmt.Errorf("end date is before start date")
+	}
+
+	if token != nil && (start.Format("2006-01-02") < token.ScopeStart.Format("2006-01-02") ||
+		end.Format("2006-01-02") > token.ScopeEnd.Format("2006-01-02")) {
+		return start, end, fmt.Errorf("this token only covers %s to %s",
+			token.ScopeStart.Format("2006-01-02"), token.ScopeEnd.Format("2006-01-02"))
+	}
+	return start, end, nil
+}
+
+// handleLeaveExport serves /api/export/leaves?start=&end=&format=csv|json,
+// exporting every record overlapping the date range.
+func (a *App) handleLeaveExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	today := a.today()
+	thisMonth := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+	start, end, err := requestRange(r, r.URL.Query().Get("start"), r.URL.Query().Get("end"),
+		thisMonth, thisMonth.AddDate(0, 1, -1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	// Stored times are office wall-clock times, so the dates are used as-is
+	leaves, err := a.leaveRepo.ListBetween(start, end.AddDate(0, 0, 1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Private details are for admins, not dashboards
+	for i := range leaves {
+		leaves[i].PrivateReason = ""
+	}
+
+	if r.URL.Query().Get("format") == "json" {
+		writeJSON(w, http.StatusOK, leaves)
+		return
+	}
+
+	data, err := leavesCSV(leaves)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=leaves-%s-%s.csv",
+		start.Format("20060102"), end.Format("20060102")))
+	w.Write(data)
+}
+
+// leaveExportHeader is the header row of record exports.
+var leaveExportHeader = []string{"id", "username", "leave_type", "start_time", "end_time", "duration", "reason", "created_at", "loss_of_pay"}
+
+// leaveExportRow is a record's row in an export. Only the public reason is
+// exported. loss_of_pay flags unpaid leave for payroll to deduct.
+func leaveExportRow(leave *models.Leave) []string {
+	return []string{
+		strconv.FormatInt(leave.ID, 10),
+		leave.Username,
+		leave.LeaveType,
+		leave.StartTime.Format("2006-01-02 15:04"),
+		leave.EndTime.Format("2006-01-02 15:04"),
+		leave.Duration,
+		leave.Reason,
+		leave.CreatedAt.Format("2006-01-02 15:04"),
+		strconv.FormatBool(isLossOfPay(leave.LeaveType)),
+	}
+}
+
+func leavesCSV(leaves []models.Leave) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(leaveExportHeader)
+	for i := range leaves {
+		w.Write(leaveExportRow(&leaves[i]))
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
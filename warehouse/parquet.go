@@ -0,0 +1,215 @@
+// Package warehouse writes attendance data in formats analytics tools load
+// directly and uploads it to object storage.
+package warehouse
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// FieldType is the type of a Parquet column.
+type FieldType string
+
+const (
+	String    FieldType = "string"    // BYTE_ARRAY, UTF8
+	Int64     FieldType = "int64"     // INT64
+	Timestamp FieldType = "timestamp" // INT64, TIMESTAMP_MILLIS
+)
+
+// Field is one column of a table. Every column is optional: a nil value is
+// written as null.
+type Field struct {
+	Name string    `json:"name"`
+	Type FieldType `json:"type"`
+}
+
+// Parquet enum values used below.
+const (
+	parquetInt64     = 2
+	parquetByteArray = 6
+
+	parquetOptional = 1
+
+	convertedUTF8            = 0
+	convertedTimestampMillis = 9
+
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	codecUncompressed = 0
+
+	pageData = 0
+)
+
+var parquetMagic = []byte("PAR1")
+
+// EncodeParquet writes rows as an uncompressed Parquet file with one row
+// group and one data page per column. Each row has a value per field: a
+// string, an int64 or a time.Time according to the field's type, or nil.
+func EncodeParquet(fields []Field, rows [][]interface{}) ([]byte, error) {
+	for i, row := range rows {
+		if len(row) != len(fields) {
+			return nil, fmt.Errorf("row %d has %d values, want %d", i, len(row), len(fields))
+		}
+	}
+
+	var file bytes.Buffer
+	file.Write(parquetMagic)
+
+	type chunk struct {
+		offset, size int64
+	}
+	chunks := make([]chunk, len(fields))
+	for col, field := range fields {
+		page, err := dataPage(field, col, rows)
+		if err != nil {
+			return nil, err
+		}
+
+		var header thriftWriter
+		header.beginStruct()
+		header.i32(1, pageData)
+		header.i32(2, int32(len(page)))
+		header.i32(3, int32(len(page)))
+		header.structField(5)
+		header.i32(1, int32(len(rows)))
+		header.i32(2, encodingPlain)
+		header.i32(3, encodingRLE)
+		header.i32(4, encodingRLE)
+		header.endStruct()
+		header.endStruct()
+
+		chunks[col] = chunk{offset: int64(file.Len()), size: int64(header.Len() + len(page))}
+		file.Write(header.Bytes())
+		file.Write(page)
+	}
+
+	var footer thriftWriter
+	footer.beginStruct()
+	footer.i32(1, 1)
+	footer.list(2, thriftStruct, len(fields)+1)
+	footer.beginStruct()
+	footer.string(4, "schema")
+	footer.i32(5, int32(len(fields)))
+	footer.endStruct()
+	for _, field := range fields {
+		physical, converted := parquetTypes(field.Type)
+		footer.beginStruct()
+		footer.i32(1, physical)
+		footer.i32(3, parquetOptional)
+		footer.string(4, field.Name)
+		if converted >= 0 {
+			footer.i32(6, converted)
+		}
+		footer.endStruct()
+	}
+	footer.i64(3, int64(len(rows)))
+
+	var total int64
+	for _, c := range chunks {
+		total += c.size
+	}
+	footer.list(4, thriftStruct, 1)
+	footer.beginStruct()
+	footer.list(1, thriftStruct, len(fields))
+	for col, field := range fields {
+		physical, _ := parquetTypes(field.Type)
+		footer.beginStruct()
+		footer.i64(2, chunks[col].offset)
+		footer.structField(3)
+		footer.i32(1, physical)
+		footer.list(2, thriftI32, 2)
+		footer.i32Elem(encodingPlain)
+		footer.i32Elem(encodingRLE)
+		footer.list(3, thriftBinary, 1)
+		footer.stringElem(field.Name)
+		footer.i32(4, codecUncompressed)
+		footer.i64(5, int64(len(rows)))
+		footer.i64(6, chunks[col].size)
+		footer.i64(7, chunks[col].size)
+		footer.i64(9, chunks[col].offset)
+		footer.endStruct()
+		footer.endStruct()
+	}
+	footer.i64(2, total)
+	footer.i64(3, int64(len(rows)))
+	footer.endStruct()
+	footer.string(6, "slack-leaves-ai-agent")
+	footer.endStruct()
+
+	file.Write(footer.Bytes())
+	binary.Write(&file, binary.LittleEndian, uint32(footer.Len()))
+	file.Write(parquetMagic)
+	return file.Bytes(), nil
+}
+
+// parquetTypes maps a field type to its physical and converted type; -1 for
+// no converted type.
+func parquetTypes(t FieldType) (int32, int32) {
+	switch t {
+	case String:
+		return parquetByteArray, convertedUTF8
+	case Timestamp:
+		return parquetInt64, convertedTimestampMillis
+	default:
+		return parquetInt64, -1
+	}
+}
+
+// dataPage encodes column col: the definition levels, RLE-encoded behind
+// their length, then the non-null values PLAIN-encoded.
+func dataPage(field Field, col int, rows [][]interface{}) ([]byte, error) {
+	var levels, values bytes.Buffer
+	defined := make([]bool, len(rows))
+	for i, row := range rows {
+		switch v := row[col].(type) {
+		case nil:
+			continue
+		case string:
+			if field.Type != String {
+				return nil, fmt.Errorf("column %s: got a string in row %d", field.Name, i)
+			}
+			binary.Write(&values, binary.LittleEndian, uint32(len(v)))
+			values.WriteString(v)
+		case int64:
+			if field.Type != Int64 {
+				return nil, fmt.Errorf("column %s: got an integer in row %d", field.Name, i)
+			}
+			binary.Write(&values, binary.LittleEndian, v)
+		case time.Time:
+			if field.Type != Timestamp {
+				return nil, fmt.Errorf("column %s: got a time in row %d", field.Name, i)
+			}
+			binary.Write(&values, binary.LittleEndian, v.UnixMilli())
+		default:
+			return nil, fmt.Errorf("column %s: unsupported value %T in row %d", field.Name, v, i)
+		}
+		defined[i] = true
+	}
+
+	// One RLE run per stretch of equal levels; with a bit width of 1 each
+	// run's value takes one byte
+	var buf [binary.MaxVarintLen64]byte
+	for start := 0; start < len(defined); {
+		end := start
+		for end < len(defined) && defined[end] == defined[start] {
+			end++
+		}
+		n := binary.PutUvarint(buf[:], uint64(end-start)<<1)
+		levels.Write(buf[:n])
+		if defined[start] {
+			levels.WriteByte(1)
+		} else {
+			levels.WriteByte(0)
+		}
+		start = end
+	}
+
+	var page bytes.Buffer
+	binary.Write(&page, binary.LittleEndian, uint32(levels.Len()))
+	page.Write(levels.Bytes())
+	page.Write(values.Bytes())
+	return page.Bytes(), nil
+}
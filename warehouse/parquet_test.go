@@ -0,0 +1,260 @@
+package warehouse
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// thriftReader decodes the Thrift compact protocol into maps of field id to
+// value, lists into slices and binaries into strings, independently of
+// thriftWriter.
+type thriftReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *thriftReader) byte() byte {
+	b := r.data[r.pos]
+	r.pos++
+	return b
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.data[r.pos:])
+	if n <= 0 {
+		panic(fmt.Sprintf("bad varint at %d", r.pos))
+	}
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) zigzag() int64 {
+	v := r.uvarint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *thriftReader) value(typ byte) interface{} {
+	switch typ {
+	case thriftI32, thriftI64:
+		return r.zigzag()
+	case thriftBinary:
+		n := int(r.uvarint())
+		s := string(r.data[r.pos : r.pos+n])
+		r.pos += n
+		return s
+	case thriftList:
+		header := r.byte()
+		n, elem := int(header>>4), header&0x0F
+		if n == 15 {
+			n = int(r.uvarint())
+		}
+		list := make([]interface{}, n)
+		for i := range list {
+			list[i] = r.value(elem)
+		}
+		return list
+	case thriftStruct:
+		return r.structValue()
+	}
+	panic(fmt.Sprintf("unexpected thrift type %d at %d", typ, r.pos))
+}
+
+func (r *thriftReader) structValue() map[int16]interface{} {
+	fields := map[int16]interface{}{}
+	var last int16
+	for {
+		header := r.byte()
+		if header == 0 {
+			return fields
+		}
+		id := last + int16(header>>4)
+		if header>>4 == 0 {
+			id = int16(r.zigzag())
+		}
+		fields[id] = r.value(header & 0x0F)
+		last = id
+	}
+}
+
+func decodeStruct(t *testing.T, data []byte) (map[int16]interface{}, int) {
+	t.Helper()
+	r := &thriftReader{data: data}
+	return r.structValue(), r.pos
+}
+
+func TestEncodeParquet(t *testing.T) {
+	fields := []Field{
+		{Name: "id", Type: Int64},
+		{Name: "username", Type: String},
+		{Name: "start_time", Type: Timestamp},
+		{Name: "deleted_at", Type: Timestamp},
+	}
+	start := time.Date(2024, time.March, 4, 9, 0, 0, 0, time.UTC)
+	deleted := time.Date(2024, time.March, 5, 12, 30, 0, 0, time.UTC)
+	rows := [][]interface{}{
+		{int64(1), "asha", start, nil},
+		{int64(2), nil, start.Add(24 * time.Hour), deleted},
+		{int64(3), "ravi", nil, nil},
+	}
+
+	data, err := EncodeParquet(fields, rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, parquetMagic) || !bytes.HasSuffix(data, parquetMagic) {
+		t.Fatal("file doesn't start and end with PAR1")
+	}
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footerStart := len(data) - 8 - footerLen
+	meta, n := decodeStruct(t, data[footerStart:len(data)-8])
+	if n != footerLen {
+		t.Fatalf("footer decoded %d bytes, length says %d", n, footerLen)
+	}
+
+	if meta[1] != int64(1) {
+		t.Errorf("version = %v, want 1", meta[1])
+	}
+	if meta[3] != int64(len(rows)) {
+		t.Errorf("num_rows = %v, want %d", meta[3], len(rows))
+	}
+	schema := meta[2].([]interface{})
+	if len(schema) != len(fields)+1 {
+		t.Fatalf("schema has %d elements, want %d", len(schema), len(fields)+1)
+	}
+	if root := schema[0].(map[int16]interface{}); root[5] != int64(len(fields)) {
+		t.Errorf("root num_children = %v, want %d", root[5], len(fields))
+	}
+	for i, field := range fields {
+		element := schema[i+1].(map[int16]interface{})
+		physical, converted := parquetTypes(field.Type)
+		if element[4] != field.Name || element[1] != int64(physical) || element[3] != int64(parquetOptional) {
+			t.Errorf("schema element %d = %v, want optional %s of type %d", i+1, element, field.Name, physical)
+		}
+		if got, ok := element[6]; converted >= 0 && got != int64(converted) || converted < 0 && ok {
+			t.Errorf("%s: converted type = %v, want %d", field.Name, got, converted)
+		}
+	}
+
+	rowGroups := meta[4].([]interface{})
+	if len(rowGroups) != 1 {
+		t.Fatalf("%d row groups, want 1", len(rowGroups))
+	}
+	columns := rowGroups[0].(map[int16]interface{})[1].([]interface{})
+	for col, field := range fields {
+		chunk := columns[col].(map[int16]interface{})
+		columnMeta := chunk[3].(map[int16]interface{})
+		if path := columnMeta[3].([]interface{}); len(path) != 1 || path[0] != field.Name {
+			t.Errorf("%s: path_in_schema = %v", field.Name, path)
+		}
+		if columnMeta[5] != int64(len(rows)) {
+			t.Errorf("%s: num_values = %v, want %d", field.Name, columnMeta[5], len(rows))
+		}
+		offset, size := int(columnMeta[9].(int64)), int(columnMeta[7].(int64))
+		if offset+size > footerStart {
+			t.Fatalf("%s: chunk [%d, %d) runs into the footer at %d", field.Name, offset, offset+size, footerStart)
+		}
+
+		header, n := decodeStruct(t, data[offset:offset+size])
+		if header[1] != int64(pageData) {
+			t.Errorf("%s: page type = %v, want data page", field.Name, header[1])
+		}
+		pageSize := int(header[3].(int64))
+		if n+pageSize != size {
+			t.Errorf("%s: header %d + page %d bytes, chunk is %d", field.Name, n, pageSize, size)
+		}
+		if dataHeader := header[5].(map[int16]interface{}); dataHeader[1] != int64(len(rows)) {
+			t.Errorf("%s: page num_values = %v, want %d", field.Name, dataHeader[1], len(rows))
+		}
+
+		got := decodePage(t, field, data[offset+n:offset+size], len(rows))
+		var want []interface{}
+		for _, row := range rows {
+			value := row[col]
+			if ts, ok := value.(time.Time); ok {
+				value = ts.UnixMilli()
+			}
+			want = append(want, value)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: values = %v, want %v", field.Name, got, want)
+		}
+	}
+}
+
+// decodePage reads a data page of an optional column: RLE definition levels
+// behind their length, then the PLAIN values of the defined rows.
+func decodePage(t *testing.T, field Field, page []byte, rows int) []interface{} {
+	t.Helper()
+	levelsLen := int(binary.LittleEndian.Uint32(page))
+	levels := &thriftReader{data: page[4 : 4+levelsLen]}
+	var defined []bool
+	for levels.pos < len(levels.data) {
+		header := levels.uvarint()
+		if header&1 != 0 {
+			t.Fatalf("%s: unexpected bit-packed run", field.Name)
+		}
+		value := levels.byte() == 1
+		for i := uint64(0); i < header>>1; i++ {
+			defined = append(defined, value)
+		}
+	}
+	if len(defined) != rows {
+		t.Fatalf("%s: %d definition levels, want %d", field.Name, len(defined), rows)
+	}
+
+	values := page[4+levelsLen:]
+	var out []interface{}
+	for _, ok := range defined {
+		switch {
+		case !ok:
+			out = append(out, nil)
+		case field.Type == String:
+			n := int(binary.LittleEndian.Uint32(values))
+			out = append(out, string(values[4:4+n]))
+			values = values[4+n:]
+		default:
+			out = append(out, int64(binary.LittleEndian.Uint64(values)))
+			values = values[8:]
+		}
+	}
+	if len(values) != 0 {
+		t.Errorf("%s: %d bytes left after the values", field.Name, len(values))
+	}
+	return out
+}
+
+func TestEncodeParquetRejectsMismatchedRows(t *testing.T) {
+	fields := []Field{{Name: "id", Type: Int64}, {Name: "username", Type: String}}
+	tests := []struct {
+		name string
+		rows [][]interface{}
+	}{
+		{"short row", [][]interface{}{{int64(1)}}},
+		{"wrong type", [][]interface{}{{"1", "asha"}}},
+		{"unsupported value", [][]interface{}{{1, "asha"}}},
+	}
+	for _, tt := range tests {
+		if _, err := EncodeParquet(fields, tt.rows); err == nil {
+			t.Errorf("%s: no error", tt.name)
+		}
+	}
+}
+
+func TestThriftWriterFieldDeltas(t *testing.T) {
+	var w thriftWriter
+	w.beginStruct()
+	w.i32(1, -3)
+	w.i64(20, 1<<40) // too far for a delta
+	w.string(21, "x")
+	w.endStruct()
+
+	got, _ := decodeStruct(t, w.Bytes())
+	want := map[int16]interface{}{1: int64(-3), 20: int64(1 << 40), 21: "x"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded %v, want %v", got, want)
+	}
+}
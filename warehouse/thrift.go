@@ -0,0 +1,97 @@
+package warehouse
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Thrift compact protocol type ids, as used in Parquet's metadata.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter writes the Thrift compact protocol, just enough of it for a
+// Parquet footer and page headers. Fields must be written in increasing id
+// order within a struct.
+type thriftWriter struct {
+	bytes.Buffer
+	lastField []int16 // last field id written, per open struct
+}
+
+func (w *thriftWriter) varint(v uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	w.Write(buf[:n])
+}
+
+func zigzag(v int64) uint64 {
+	return uint64((v << 1) ^ (v >> 63))
+}
+
+func (w *thriftWriter) field(id int16, typ byte) {
+	last := &w.lastField[len(w.lastField)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		w.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		w.WriteByte(typ)
+		w.varint(zigzag(int64(id)))
+	}
+	*last = id
+}
+
+func (w *thriftWriter) i32(id int16, v int32) {
+	w.field(id, thriftI32)
+	w.varint(zigzag(int64(v)))
+}
+
+func (w *thriftWriter) i64(id int16, v int64) {
+	w.field(id, thriftI64)
+	w.varint(zigzag(v))
+}
+
+func (w *thriftWriter) string(id int16, v string) {
+	w.field(id, thriftBinary)
+	w.varint(uint64(len(v)))
+	w.WriteString(v)
+}
+
+// list starts a list field of n elements. Struct elements are then written
+// between beginStruct and endStruct; plain elements with the element
+// methods below.
+func (w *thriftWriter) list(id int16, elemType byte, n int) {
+	w.field(id, thriftList)
+	if n < 15 {
+		w.WriteByte(byte(n)<<4 | elemType)
+	} else {
+		w.WriteByte(0xF0 | elemType)
+		w.varint(uint64(n))
+	}
+}
+
+func (w *thriftWriter) i32Elem(v int32) {
+	w.varint(zigzag(int64(v)))
+}
+
+func (w *thriftWriter) stringElem(v string) {
+	w.varint(uint64(len(v)))
+	w.WriteString(v)
+}
+
+// structField starts a struct-valued field; close it with endStruct.
+func (w *thriftWriter) structField(id int16) {
+	w.field(id, thriftStruct)
+	w.beginStruct()
+}
+
+func (w *thriftWriter) beginStruct() {
+	w.lastField = append(w.lastField, 0)
+}
+
+func (w *thriftWriter) endStruct() {
+	w.WriteByte(0)
+	w.lastField = w.lastField[:len(w.lastField)-1]
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+	"slack-leaves-ai-agent/warehouse"
+)
+
+// warehouseExportHour is when, in the workspace's timezone, the nightly
+// warehouse export runs.
+const warehouseExportHour = 2
+
+// warehouseSettleTime keeps the export from reading records whose
+// transaction may still be committing with an earlier updated_at.
+const warehouseSettleTime = time.Minute
+
+// warehouseLeaveFields are the columns of the leaves table. Cancelled and
+// rejected records are exported too, so a loader sees the change; status and
+// deleted_at tell them apart from live ones.
+var warehouseLeaveFields = []warehouse.Field{
+	{Name: "id", Type: warehouse.Int64},
+	{Name: "username", Type: warehouse.String},
+	{Name: "leave_type", Type: warehouse.String},
+	{Name: "start_time", Type: warehouse.Timestamp},
+	{Name: "end_time", Type: warehouse.Timestamp},
+	{Name: "duration", Type: warehouse.String},
+	{Name: "reason", Type: warehouse.String},
+	{Name: "recurrence_id", Type: warehouse.Int64},
+	{Name: "status", Type: warehouse.String},
+	{Name: "created_at", Type: warehouse.Timestamp},
+	{Name: "updated_at", Type: warehouse.Timestamp},
+	{Name: "deleted_at", Type: warehouse.Timestamp},
+}
+
+var warehouseAuditFields = []warehouse.Field{
+	{Name: "id", Type: warehouse.Int64},
+	{Name: "actor", Type: warehouse.String},
+	{Name: "action", Type: warehouse.String},
+	{Name: "leave_id", Type: warehouse.Int64},
+	{Name: "before", Type: warehouse.String},
+	{Name: "after", Type: warehouse.String},
+	{Name: "created_at", Type: warehouse.Timestamp},
+	{Name: "hash", Type: warehouse.String},
+}
+
+// warehouseManifest is written after a run's data files and lists them, so
+// a loader only picks up complete runs.
+type warehouseManifest struct {
+	RunID      string                   `json:"run_id"`
+	ExportedAt time.Time                `json:"exported_at"`
+	Tables     []warehouseManifestTable `json:"tables"`
+}
+
+type warehouseManifestTable struct {
+	Table   string            `json:"table"`
+	Key     string            `json:"key,omitempty"` // empty when nothing changed
+	Format  string            `json:"format"`
+	Rows    int               `json:"rows"`
+	After   string            `json:"after"`   // exclusive lower bound: updated_at for leaves, id for the audit log
+	Through string            `json:"through"` // inclusive upper bound
+	Fields  []warehouse.Field `json:"fields"`
+}
+
+func buildWarehouseBucket(config *Config) *warehouse.Bucket {
+	if config.WarehouseURL == "" {
+		return nil
+	}
+	bucket, err := warehouse.NewBucket(config.WarehouseURL, config.WarehouseRegion,
+		config.WarehouseAccessKey, config.WarehouseSecretKey)
+	if err != nil {
+		logger.Error("Warehouse export disabled: %v", err)
+		return nil
+	}
+	return bucket
+}
+
+// runWarehouseExport exports what changed since the last run every night at
+// warehouseExportHour, when WAREHOUSE_EXPORT_URL is set.
+func (a *App) runWarehouseExport(ctx context.Context) {
+	if a.warehouse == nil {
+		return
+	}
+
+	loc := a.config.Timezone
+	lastRun := ""
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			now = now.In(loc)
+			today := now.Format("2006-01-02")
+			if lastRun == today || now.Hour() < warehouseExportHour {
+				continue
+			}
+			lastRun = today
+			export, err := a.exportToWarehouse(ctx, time.Now())
+			if err != nil {
+				logger.Error("Warehouse export failed: %v", err)
+				continue
+			}
+			logger.Info("Exported %d leaves and %d audit entries to the warehouse (%s)",
+				export.LeaveRows, export.AuditRows, export.ManifestKey)
+		}
+	}
+}
+
+// exportToWarehouse writes the leaves changed and audit entries added since
+// the last export as Parquet files, then a manifest listing them. The run
+// is only recorded once the manifest is up, so a failed run is retried in
+// full by the next one. Cancelled and rejected leaves are exported with
+// their status and deleted_at; deleted ones show up as "delete" audit
+// entries.
+func (a *App) exportToWarehouse(ctx context.Context, now time.Time) (*models.WarehouseExport, error) {
+	last, err := a.warehouseRepo.Latest()
+	if err != nil {
+		return nil, fmt.Errorf("error loading the last export: %v", err)
+	}
+	if last == nil {
+		last = &models.WarehouseExport{}
+	}
+
+	export := &models.WarehouseExport{
+		StartedAt:     now,
+		LeavesThrough: now.Add(-warehouseSettleTime),
+		AuditThrough:  last.AuditThrough,
+	}
+	runID := now.UTC().Format("20060102T150405Z")
+	partition := "dt=" + now.In(a.config.Timezone).Format("2006-01-02")
+
+	leaves, err := a.leaveRepo.ListUpdatedBetween(last.LeavesThrough, export.LeavesThrough)
+	if err != nil {
+		return nil, fmt.Errorf("error listing changed leaves: %v", err)
+	}
+	leaveRows := make([][]interface{}, 0, len(leaves))
+	for _, leave := range leaves {
+		// Private reasons are for admins only and stay out of the warehouse
+		leaveRows = append(leaveRows, []interface{}{
+			leave.ID, leave.Username, leave.LeaveType, leave.StartTime, leave.EndTime, leave.Duration,
+			nullableString(leave.Reason), nullableInt(leave.RecurrenceID), leave.Status,
+			leave.CreatedAt, leave.UpdatedAt, nullableTime(leave.DeletedAt),
+		})
+	}
+
+	entries, err := a.auditRepo.ListAfter(last.AuditThrough)
+	if err != nil {
+		return nil, fmt.Errorf("error listing audit entries: %v", err)
+	}
+	auditRows := make([][]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		auditRows = append(auditRows, []interface{}{
+			entry.ID, entry.Actor, entry.Action, nullableInt(entry.LeaveID),
+			nullableString(withoutPrivateReason(entry.Before)), nullableString(withoutPrivateReason(entry.After)),
+			entry.CreatedAt, nullableString(entry.Hash),
+		})
+		export.AuditThrough = entry.ID
+	}
+
+	manifest := warehouseManifest{RunID: runID, ExportedAt: now.UTC()}
+	tables := []struct {
+		name           string
+		fields         []warehouse.Field
+		rows           [][]interface{}
+		after, through string
+	}{
+		{"leaves", warehouseLeaveFields, leaveRows,
+			last.LeavesThrough.Format(time.RFC3339), export.LeavesThrough.Format(time.RFC3339)},
+		{"audit_log", warehouseAuditFields, auditRows,
+			fmt.Sprintf("%d", last.AuditThrough), fmt.Sprintf("%d", export.AuditThrough)},
+	}
+	for _, table := range tables {
+		entry := warehouseManifestTable{
+			Table:   table.name,
+			Format:  "parquet",
+			Rows:    len(table.rows),
+			After:   table.after,
+			Through: table.through,
+			Fields:  table.fields,
+		}
+		if len(table.rows) > 0 {
+			data, err := warehouse.EncodeParquet(table.fields, table.rows)
+			if err != nil {
+				return nil, fmt.Errorf("error encoding %s: %v", table.name, err)
+			}
+			entry.Key = fmt.Sprintf("%s/%s/%s-%s.parquet", table.name, partition, table.name, runID)
+			if err := a.warehouse.Put(ctx, entry.Key, data, "application/vnd.apache.parquet"); err != nil {
+				return nil, err
+			}
+		}
+		manifest.Tables = append(manifest.Tables, entry)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	export.ManifestKey = fmt.Sprintf("manifests/%s.json", runID)
+	if err := a.warehouse.Put(ctx, export.ManifestKey, data, "application/json"); err != nil {
+		return nil, err
+	}
+
+	export.LeaveRows, export.AuditRows = len(leaveRows), len(auditRows)
+	if err := a.warehouseRepo.Record(export); err != nil {
+		return nil, fmt.Errorf("error recording the export: %v", err)
+	}
+	return export, nil
+}
+
+// withoutPrivateReason drops private reasons from an audit entry's JSON
+// snapshot of one or more leaves. Snapshots without one are left as they
+// are, so their audit hash still checks out.
+func withoutPrivateReason(snapshot string) string {
+	var value interface{}
+	if json.Unmarshal([]byte(snapshot), &value) != nil || !stripPrivateReason(value) {
+		return snapshot
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return snapshot
+	}
+	return string(data)
+}
+
+func stripPrivateReason(value interface{}) bool {
+	stripped := false
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if _, ok := v["private_reason"]; ok {
+			delete(v, "private_reason")
+			stripped = true
+		}
+		for _, child := range v {
+			stripped = stripPrivateReason(child) || stripped
+		}
+	case []interface{}:
+		for _, child := range v {
+			stripped = stripPrivateReason(child) || stripped
+		}
+	}
+	return stripped
+}
+
+func nullableString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func nullableInt(n int64) interface{} {
+	if n == 0 {
+		return nil
+	}
+	return n
+}
+
+func nullableTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return *t
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// activityTracker remembers when each user was last seen posting anything in
+// a channel the bot can see and, when handover checklists are on, the start
+// of their last few messages. Nothing here is persisted.
+type activityTracker struct {
+	mu       sync.Mutex
+	lastSeen map[string]time.Time
+	recent   map[string][]recentMessage
+}
+
+type recentMessage struct {
+	Text string
+	At   time.Time
+}
+
+// maxRecentMessages is how many messages are kept per user.
+const maxRecentMessages = 50
+
+// maxRecentMessageLength caps how much of a message is kept, in characters:
+// enough for a checklist to name the work it mentions.
+const maxRecentMessageLength = 200
+
+func newActivityTracker() *activityTracker {
+	return &activityTracker{
+		lastSeen: make(map[string]time.Time),
+		recent:   make(map[string][]recentMessage),
+	}
+}
+
+func (t *activityTracker) Touch(userID string, at time.Time) {
+	if userID == "" {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if at.After(t.lastSeen[userID]) {
+		t.lastSeen[userID] = at
+	}
+}
+
+// Remember stores the start of a message the user posted, dropping messages
+// older than handoverLookback and the oldest once maxRecentMessages is
+// reached.
+func (t *activityTracker) Remember(userID, text string, at time.Time) {
+	text = strings.TrimSpace(text)
+	if userID == "" || text == "" {
+		return
+	}
+	if runes := []rune(text); len(runes) > maxRecentMessageLength {
+		text = string(runes[:maxRecentMessageLength])
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var msgs []recentMessage
+	for _, msg := range t.recent[userID] {
+		if msg.At.After(at.Add(-handoverLookback)) {
+			msgs = append(msgs, msg)
+		}
+	}
+	msgs = append(msgs, recentMessage{Text: text, At: at})
+	if len(msgs) > maxRecentMessages {
+		msgs = msgs[len(msgs)-maxRecentMessages:]
+	}
+	t.recent[userID] = msgs
+}
+
+// Recent returns the user's remembered messages posted after since, oldest
+// first.
+func (t *activityTracker) Recent(userID string, since time.Time) []recentMessage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var msgs []recentMessage
+	for _, msg := range t.recent[userID] {
+		if msg.At.After(since) {
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs
+}
+
+func (t *activityTracker) LastSeen(userID string) time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.lastSeen[userID]
+}
+
+// parseWellnessChecks reads WELLNESS_CHECK_USERS entries of the form
+// "U_EMPLOYEE:U_MANAGER". Only users listed here are ever checked.
+func parseWellnessChecks(value string) (map[string]string, error) {
+	checks := make(map[string]string)
+	for _, entry := range splitList(value) {
+		userID, managerID, ok := strings.Cut(entry, ":")
+		if !ok || userID == "" || managerID == "" {
+			return nil, fmt.Errorf("invalid WELLNESS_CHECK_USERS entry %q (expected USER:MANAGER)", entry)
+		}
+		checks[strings.TrimSpace(userID)] = strings.TrimSpace(managerID)
+	}
+	return checks, nil
+}
+
+// runWellnessChecks waits for the configured check time on each working day
+// and alerts the managers of opted-in users who have neither posted on Slack
+// nor recorded any leave. It never contacts the user or anyone outside the
+// company; the manager decides whether to follow up. Activity is only
+// tracked in memory, so the day the bot starts isn't checked: it can't know
+// who posted before then.
+func (a *App) runWellnessChecks(ctx context.Context) {
+	if len(a.config.WellnessChecks) == 0 {
+		return
+	}
+
+	loc := a.config.Timezone
+	hour, minute, err := parseClock(a.config.WellnessCheckTime)
+	if err != nil {
+		logger.Error("Wellness checks disabled: %v", err)
+		return
+	}
+
+	logger.Info("Wellness checks enabled for %d users at %02d:%02d %s", len(a.config.WellnessChecks), hour, minute, loc)
+	watchingSince := time.Now().In(loc)
+
+	lastRun := ""
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			now = now.In(loc)
+			today := now.Format("2006-01-02")
+			if lastRun == today || now.Weekday() == time.Saturday || now.Weekday() == time.Sunday {
+				continue
+			}
+			checkAt := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, loc)
+			if now.Before(checkAt) {
+				continue
+			}
+			lastRun = today
+			if dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc); watchingSince.After(dayStart) {
+				logger.Info("Skipping today's wellness check: activity has only been tracked since %s", watchingSince.Format("3:04 PM"))
+				continue
+			}
+			a.checkUnexplainedAbsences(ctx, now)
+		}
+	}
+}
+
+func (a *App) checkUnexplainedAbsences(ctx context.Context, now time.Time) {
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	for userID, managerID := range a.config.WellnessChecks {
+		if a.activity.LastSeen(userID).After(dayStart) {
+			continue
+		}
+
+		user, err := a.slackClient.GetUserInfoContext(ctx, userID)
+		if err != nil {
+			logger.Error("Wellness check: failed to look up %s: %v", userID, err)
+			continue
+		}
+
+		leaves, err := a.leaveRepo.FindByUserAndDate(user.Name, now)
+		if err != nil {
+			logger.Error("Wellness check: failed to load leaves for %s: %v", user.Name, err)
+			continue
+		}
+		if len(leaves) > 0 {
+			continue
+		}
+
+		// Being online without posting still counts as activity
+		if presence, err := a.slackClient.GetUserPresenceContext(ctx, userID); err == nil && presence.Presence == "active" {
+			continue
+		}
+
+		logger.Info("Wellness check: no activity or leave for %s, alerting manager", user.Name)
+		a.notifyUser(ctx, managerID, "Wellness check",
+			fmt.Sprintf("<@%s> hasn't posted on Slack or recorded any leave today (as of %s). "+
+				"You may want to check in with them.", userID, now.Format("3:04 PM")))
+	}
+}
+
+// parseClock parses "HH:MM" in 24-hour format.
+func parseClock(value string) (int, int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid time %q (expected HH:MM)", value)
+	}
+	return t.Hour(), t.Minute(), nil
+}
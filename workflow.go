@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"slack-leaves-ai-agent/models"
+
+	"github.com/slack-go/slack"
+)
+
+// recordLeaveFunctionID is the callback_id of the "Record leave" custom
+// workflow step declared in the app manifest. Inputs:
+//
+//	user_id     slack#/types/user_id (required)
+//	message     string, parsed like a channel message, or instead:
+//	leave_type  string (WFH, IN_OFFICE, FULL_DAY, HALF_DAY, ...)
+//	start_date  slack#/types/date
+//	end_date    slack#/types/date (defaults to start_date)
+//	reason      string
+//
+// Outputs: leave_id and summary, both strings.
+const recordLeaveFunctionID = "record_leave"
+
+// slackAPIURL is where function completions are sent.
+const slackAPIURL = "https://slack.com/api/"
+
+type functionExecutedEvent struct {
+	Type     string `json:"type"`
+	Function struct {
+		CallbackID string `json:"callback_id"`
+	} `json:"function"`
+	Inputs              map[string]interface{} `json:"inputs"`
+	FunctionExecutionID string                 `json:"function_execution_id"`
+	BotAccessToken      string                 `json:"bot_access_token"`
+}
+
+// parseFunctionExecuted picks function_executed events out of Events API
+// payloads. The slack-go version we're on doesn't know this event type and
+// can't decode it, so it's decoded here.
+func parseFunctionExecuted(raw json.RawMessage) (*functionExecutedEvent, bool) {
+	var payload struct {
+		Event functionExecutedEvent `json:"event"`
+	}
+	if err := json.Unmarshal(raw, &payload); err != nil || payload.Event.Type != "function_executed" {
+		return nil, false
+	}
+	return &payload.Event, true
+}
+
+func (ev *functionExecutedEvent) input(name string) string {
+	value, _ := ev.Inputs[name].(string)
+	return strings.TrimSpace(value)
+}
+
+// handleFunctionExecuted runs a custom workflow step. Only "record_leave" is
+// provided; it writes through the same pipeline as channel messages.
+func (a *App) handleFunctionExecuted(ctx context.Context, ev *functionExecutedEvent) {
+	if ev.Function.CallbackID != recordLeaveFunctionID {
+		logger.Debug("Ignoring unknown workflow function %q", ev.Function.CallbackID)
+		return
+	}
+
+	leave, summary, err := a.runRecordLeaveStep(ctx, ev)
+	if err != nil {
+		logger.Error("Workflow step %s failed: %v", ev.FunctionExecutionID, err)
+		a.completeFunction(ctx, ev, nil, err.Error())
+		return
+	}
+
+	a.completeFunction(ctx, ev, map[string]string{
+		"leave_id": strconv.FormatInt(leave.ID, 10),
+		"summary":  summary,
+	}, "")
+}
+
+func (a *App) runRecordLeaveStep(ctx context.Context, ev *functionExecutedEvent) (*models.Leave, string, error) {
+	userID := ev.input("user_id")
+	if userID == "" {
+		return nil, "", fmt.Errorf("user_id is required")
+	}
+	user, err := a.slackClient.GetUserInfoContext(ctx, userID)
+	if err != nil {
+		return nil, "", fmt.Errorf("unknown user %s: %v", userID, err)
+	}
+
+	region := a.regionFor(user.Name)
+	leave := &models.Leave{Username: user.Name, Reason: ev.input("reason"), PrivateReason: ev.input("private_reason")}
+
+	if message := ev.input("message"); message != "" {
+		response, err := a.openAI.ParseLeaveRequest(ctx, message, fmt.Sprintf("%d", time.Now().Unix()), region)
+		if err != nil {
+			return nil, "", err
+		}
+		if !response.IsValid {
+			return nil, "", fmt.Errorf("unable to process leave request: %s", response.Error)
+		}
+		leave.OriginalText = message
+		leave.StartTime = response.StartTime
+		leave.EndTime = response.EndTime
+		leave.Duration = response.Duration
+		leave.Reason = response.Reason
+		leave.LeaveType = response.LeaveType
+		leave.ParseConfidence = response.Confidence
+		leave.Sick = response.Sick
+	} else {
+		leave.LeaveType = strings.ToUpper(ev.input("leave_type"))
+		startDate := ev.input("start_date")
+		endDate := ev.input("end_date")
+		if endDate == "" {
+			endDate = startDate
+		}
+		if leave.StartTime, err = parseAdminTime(startDate, false, region.Timezone); err != nil {
+			return nil, "", err
+		}
+		if leave.EndTime, err = parseAdminTime(endDate, true, region.Timezone); err != nil {
+			return nil, "", err
+		}
+		if leave.LeaveType == "HALF_DAY" && endDate == startDate {
+			leave.EndTime = leave.StartTime.Add(4 * time.Hour)
+		}
+		leave.Duration = models.FormatDuration(leave.StartTime, leave.EndTime)
+		leave.OriginalText = fmt.Sprintf("Workflow: %s %s to %s %s", leave.LeaveType, startDate, endDate, leave.Reason)
+
+		if err := validateAdminLeave(leave); err != nil {
+			return nil, "", err
+		}
+		if reason := region.Validate(leave.StartTime, leave.EndTime, time.Now()); reason != "" {
+			return nil, "", fmt.Errorf("%s", reason)
+		}
+	}
+
+	violations, err := a.recordLeave(ctx, "workflow:"+userID, "workflow_create", leave, user.Profile.Email)
+	if err != nil {
+		return nil, "", err
+	}
+
+	summary := fmt.Sprintf("%s for %s, %s – %s", leave.LeaveType, leave.Username,
+		leave.StartTime.Format("Jan 2, 2006 3:04 PM"), leave.EndTime.Format("Jan 2, 2006 3:04 PM"))
+	if warning := policyWarningText(violations); warning != "" {
+		summary += "\n" + warning
+	}
+	return leave, summary, nil
+}
+
+// completeFunction reports the step's outcome with functions.completeSuccess
+// or, when errMsg is set, functions.completeError.
+func (a *App) completeFunction(ctx context.Context, ev *functionExecutedEvent, outputs map[string]string, errMsg string) {
+	method := "functions.completeSuccess"
+	body := map[string]interface{}{"function_execution_id": ev.FunctionExecutionID}
+	if errMsg != "" {
+		method = "functions.completeError"
+		body["error"] = errMsg
+	} else {
+		body["outputs"] = outputs
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		logger.Error("Failed to encode %s: %v", method, err)
+		return
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, slackAPIURL+method, bytes.NewReader(data))
+	if err != nil {
+		logger.Error("Failed to build %s request: %v", method, err)
+		return
+	}
+
+	// The per-execution token is preferred; the bot token works too
+	token := ev.BotAccessToken
+	if token == "" {
+		token = a.config.SlackBotToken
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		logger.Error("Failed to call %s: %v", method, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	var result slack.SlackResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		logger.Error("Invalid %s response: %v", method, err)
+		return
+	}
+	if !result.Ok {
+		logger.Error("%s failed: %s", method, result.Error)
+	}
+}